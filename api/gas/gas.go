@@ -0,0 +1,24 @@
+// Package gas defines the Gas API. All chains that require fees to be paid
+// for transactions should implement this API. The Gas API is used to estimate
+// the fees that are needed in order to confirm transactions in a timely
+// manner.
+package gas
+
+import (
+	"context"
+
+	"github.com/renproject/pack"
+)
+
+// The Estimator interface defines the functionality required to know the
+// current recommended gas price per unit of gas (for UTXO-based chains, this
+// is the fee per byte of the transaction), and the maximum gas price that
+// should be paid. In distributed networks that collectively build, sign, and
+// submit transactions, it is important that all nodes in the network have
+// reached consensus on these values.
+type Estimator interface {
+	// EstimateGas returns the gas price, and the gas cap, that is needed in
+	// order to confirm transactions with an estimated maximum delay of one
+	// block.
+	EstimateGas(context.Context) (pack.U256, pack.U256, error)
+}
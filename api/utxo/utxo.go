@@ -36,9 +36,15 @@ type Input struct {
 // A Recipient specifies an address, and an amount, for which a transaction will
 // produce an output. Depending on the output, the address can take on different
 // formats (e.g. in Bitcoin, addresses can be P2PK, P2PKH, or P2SH).
+//
+// Instead of an address, a recipient can specify the pubkey script of the
+// output directly, or data that will be committed to by a null-data
+// (OP_RETURN) output. Exactly one of the address, script, or data must be set.
 type Recipient struct {
-	To    address.Address `json:"to"`
-	Value pack.U256       `json:"value"`
+	To     address.Address `json:"to"`
+	Value  pack.U256       `json:"value"`
+	Script pack.Bytes      `json:"script,omitempty"`
+	Data   pack.Bytes      `json:"data,omitempty"`
 }
 
 // The Tx interfaces defines the functionality that must be exposed by
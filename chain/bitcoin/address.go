@@ -2,11 +2,15 @@ package bitcoin
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/bech32"
 	"github.com/pranav292gpt/zecutil/address"
+	"golang.org/x/crypto/ripemd160"
 )
 
 // AddressEncodeDecoder implements the address.EncodeDecoder interface
@@ -67,6 +71,13 @@ func (encoder AddressEncoder) encodeBech32(rawAddr address.RawAddress) (address.
 		}
 		return address.Address(addr.EncodeAddress()), nil
 	case 33:
+		if rawAddr[0] == TaprootWitnessVersion {
+			addr, err := NewAddressTaproot(rawAddr[1:], encoder.params)
+			if err != nil {
+				return address.Address(""), fmt.Errorf("new address taproot: %v", err)
+			}
+			return address.Address(addr.EncodeAddress()), nil
+		}
 		addr, err := btcutil.NewAddressWitnessScriptHash(rawAddr[1:], encoder.params)
 		if err != nil {
 			return address.Address(""), fmt.Errorf("new address witness script hash: %v", err)
@@ -98,7 +109,7 @@ func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAd
 			return nil, fmt.Errorf("invalid address: bad character %v", c)
 		}
 	}
-	decodedAddr, err := btcutil.DecodeAddress(string(addr), decoder.params)
+	decodedAddr, err := decodeAddress(string(addr), decoder.params)
 	if err != nil {
 		return nil, fmt.Errorf("decode address: %v", err)
 	}
@@ -112,7 +123,96 @@ func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAd
 	case *btcutil.AddressWitnessScriptHash:
 		rawAddr := append([]byte{a.WitnessVersion()}, a.WitnessProgram()...)
 		return address.RawAddress(rawAddr), nil
+	case *AddressTaproot:
+		rawAddr := append([]byte{a.WitnessVersion()}, a.WitnessProgram()...)
+		return address.RawAddress(rawAddr), nil
 	default:
 		return nil, fmt.Errorf("non-exhaustive pattern: address %T", a)
 	}
 }
+
+// decodeAddress decodes a Bitcoin address, including P2TR addresses, which are
+// not supported by btcutil.
+func decodeAddress(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	decodedAddr, err := btcutil.DecodeAddress(addr, params)
+	if err != nil {
+		if taprootAddr, taprootErr := DecodeAddressTaproot(addr, params); taprootErr == nil {
+			return taprootAddr, nil
+		}
+		// The error message of btcutil.UnsupportedWitnessVerError formats
+		// itself recursively, so it is replaced.
+		if version, ok := err.(btcutil.UnsupportedWitnessVerError); ok {
+			return nil, fmt.Errorf("unsupported witness version %v", byte(version))
+		}
+		return nil, err
+	}
+	return decodedAddr, nil
+}
+
+// DecodeAddressForNet decodes a P2PKH, P2SH, P2WPKH, P2WSH, or P2TR address
+// that is encoded using the prefixes of the given chain configuration. Unlike
+// btcutil.DecodeAddress, the chain configuration does not need to be
+// registered with chaincfg, and addresses for other networks are rejected.
+// This allows forks of Bitcoin, whose chain configurations can conflict with
+// those of Bitcoin, to reuse its address types.
+func DecodeAddressForNet(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	hrp := strings.ToLower(params.Bech32HRPSegwit)
+	if hrp != "" && strings.HasPrefix(strings.ToLower(addr), hrp+"1") {
+		return decodeSegWitAddressForNet(addr, params)
+	}
+
+	hash, netID, err := base58.CheckDecode(addr)
+	if err != nil {
+		return nil, fmt.Errorf("bad address: %v", err)
+	}
+	if len(hash) != ripemd160.Size {
+		return nil, fmt.Errorf("bad address: expected %v byte hash, got %v bytes", ripemd160.Size, len(hash))
+	}
+	switch netID {
+	case params.PubKeyHashAddrID:
+		return btcutil.NewAddressPubKeyHash(hash, params)
+	case params.ScriptHashAddrID:
+		return btcutil.NewAddressScriptHashFromHash(hash, params)
+	default:
+		return nil, fmt.Errorf("bad address: unknown version %v for %v", netID, params.Name)
+	}
+}
+
+// decodeSegWitAddressForNet decodes a P2WPKH, P2WSH, or P2TR address that is
+// encoded using the human readable part of the given chain configuration.
+func decodeSegWitAddressForNet(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	if taprootAddr, err := DecodeAddressTaproot(addr, params); err == nil {
+		return taprootAddr, nil
+	}
+	hrp, values, err := bech32.Decode(addr)
+	if err != nil {
+		return nil, fmt.Errorf("bad address: %v", err)
+	}
+	if hrp != strings.ToLower(params.Bech32HRPSegwit) {
+		return nil, fmt.Errorf("bad address: expected hrp %v, got hrp %v", params.Bech32HRPSegwit, hrp)
+	}
+	if len(values) < 1 || values[0] != 0 {
+		return nil, fmt.Errorf("bad address: unsupported witness version")
+	}
+	program, err := bech32.ConvertBits(values[1:], 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("bad address: %v", err)
+	}
+	switch len(program) {
+	case 20:
+		return btcutil.NewAddressWitnessPubKeyHash(program, params)
+	case 32:
+		return btcutil.NewAddressWitnessScriptHash(program, params)
+	default:
+		return nil, fmt.Errorf("bad address: invalid witness program length %v", len(program))
+	}
+}
+
+// PayToAddrScript returns the pubkey script that pays to the address,
+// including P2TR addresses, which are not supported by txscript.
+func PayToAddrScript(addr btcutil.Address) ([]byte, error) {
+	if taprootAddr, ok := addr.(*AddressTaproot); ok {
+		return PayToTaprootScript(taprootAddr.WitnessProgram())
+	}
+	return txscript.PayToAddrScript(addr)
+}
@@ -19,4 +19,33 @@ var _ = Describe("Bitcoin", func() {
 			}).ToNot(Panic())
 		})
 	})
+
+	Context("when decoding addresses for a network", func() {
+		It("should decode addresses of the network", func() {
+			for _, addr := range []string{
+				"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+				"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+				"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+				"bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3",
+				"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+			} {
+				decoded, err := bitcoin.DecodeAddressForNet(addr, &chaincfg.MainNetParams)
+				Expect(err).ToNot(HaveOccurred(), addr)
+				Expect(decoded.EncodeAddress()).To(Equal(addr))
+				Expect(decoded.IsForNet(&chaincfg.MainNetParams)).To(BeTrue())
+			}
+		})
+
+		It("should reject addresses of other networks", func() {
+			for _, addr := range []string{
+				"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+				"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+				"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",
+				"",
+			} {
+				_, err := bitcoin.DecodeAddressForNet(addr, &chaincfg.MainNetParams)
+				Expect(err).To(HaveOccurred(), addr)
+			}
+		})
+	})
 })
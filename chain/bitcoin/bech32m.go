@@ -0,0 +1,67 @@
+package bitcoin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+	bech32x "github.com/pranav292gpt/zecutil/internal/bech32"
+)
+
+// bech32MaxLength is the maximum length of a bech32m segwit address.
+const bech32MaxLength = 90
+
+// encodeBech32m encodes 5-bit values as a bech32m string (as defined by
+// BIP350).
+func encodeBech32m(hrp string, values []byte) string {
+	return bech32x.Encode(hrp, values, bech32x.Bech32m)
+}
+
+// decodeBech32m decodes a bech32m string (as defined by BIP350) into its human
+// readable part, and its 5-bit values (without the checksum).
+func decodeBech32m(s string) (string, []byte, error) {
+	return bech32x.Decode(s, bech32x.Bech32m, bech32MaxLength)
+}
+
+// encodeSegWitAddress encodes a witness program as a segwit address. Witness
+// version 0 uses bech32 (as defined by BIP173), and all other versions use
+// bech32m (as defined by BIP350).
+func encodeSegWitAddress(hrp string, version byte, program []byte) (string, error) {
+	converted, err := bech32.ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	values := append([]byte{version}, converted...)
+	if version == 0 {
+		return bech32.Encode(hrp, values)
+	}
+	return encodeBech32m(hrp, values), nil
+}
+
+// decodeSegWitAddress decodes a segwit address, with witness version 1 or
+// higher, into its witness version and witness program. The address must use
+// bech32m (as defined by BIP350), and have the given human readable part.
+func decodeSegWitAddress(hrp string, addr string) (byte, []byte, error) {
+	decodedHRP, values, err := decodeBech32m(addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if decodedHRP != strings.ToLower(hrp) {
+		return 0, nil, fmt.Errorf("bad address: expected hrp %v, got hrp %v", hrp, decodedHRP)
+	}
+	if len(values) < 1 {
+		return 0, nil, fmt.Errorf("bad address: empty data")
+	}
+	version := values[0]
+	if version < 1 || version > 16 {
+		return 0, nil, fmt.Errorf("bad address: invalid witness version %v", version)
+	}
+	program, err := bech32.ConvertBits(values[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, fmt.Errorf("bad address: %v", err)
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return 0, nil, fmt.Errorf("bad address: invalid witness program length %v", len(program))
+	}
+	return version, program, nil
+}
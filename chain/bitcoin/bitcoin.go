@@ -195,7 +195,7 @@ func (client *client) SubmitTx(ctx context.Context, tx utxo.Tx) error {
 	}
 	resp := ""
 	if err := client.send(ctx, &resp, "sendrawtransaction", hex.EncodeToString(serial)); err != nil {
-		return fmt.Errorf("bad \"sendrawtransaction\": %v", err)
+		return fmt.Errorf("bad \"sendrawtransaction\": %w", err)
 	}
 	return nil
 }
@@ -316,7 +316,7 @@ func (client *client) send(ctx context.Context, resp interface{}, method string,
 		}
 		defer res.Body.Close()
 		if err := decodeResponse(resp, res.Body); err != nil {
-			return fmt.Errorf("decoding http response: %v", err)
+			return fmt.Errorf("decoding http response: %w", err)
 		}
 		return nil
 	})
@@ -356,7 +356,13 @@ func decodeResponse(resp interface{}, r io.Reader) error {
 		return fmt.Errorf("decoding response: %v", err)
 	}
 	if res.Error != nil {
-		return fmt.Errorf("decoding response: %v", string(*res.Error))
+		// RPC errors are returned as a *btcjson.RPCError, so that callers
+		// can check their code.
+		rpcErr := new(btcjson.RPCError)
+		if err := json.Unmarshal(*res.Error, rpcErr); err != nil {
+			return fmt.Errorf("decoding response: %v", string(*res.Error))
+		}
+		return fmt.Errorf("decoding response: %w", rpcErr)
 	}
 	if res.Result == nil {
 		return fmt.Errorf("decoding result: result is nil")
@@ -374,7 +380,7 @@ func retry(ctx context.Context, dur time.Duration, f func() error) error {
 		log.Printf("retrying: %v", err)
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("%v: %v", ctx.Err(), err)
+			return fmt.Errorf("%v: %w", ctx.Err(), err)
 		case <-ticker.C:
 			err = f()
 		}
@@ -2,16 +2,16 @@ package bitcoin_test
 
 import (
 	"context"
-	"log"
-	"os"
 	"reflect"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/pranav292gpt/zecutil/address"
 	"github.com/pranav292gpt/zecutil/api/utxo"
 	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/testutil"
 	"github.com/renproject/id"
 	"github.com/renproject/pack"
 
@@ -21,36 +21,41 @@ import (
 
 var _ = Describe("Bitcoin", func() {
 	Context("when submitting transactions", func() {
+		var server *testutil.Server
+
+		BeforeEach(func() {
+			server = testutil.NewServer(testutil.BitcoinChain(&chaincfg.RegressionNetParams))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
 		Context("when sending BTC to multiple addresses", func() {
 			It("should work", func() {
-				// Load private key, and assume that the associated address has
-				// funds to spend. You can do this by setting BITCOIN_PK to the
-				// value specified in the `./multichaindeploy/.env` file.
-				pkEnv := os.Getenv("BITCOIN_PK")
-				if pkEnv == "" {
-					panic("BITCOIN_PK is undefined")
-				}
-				wif, err := btcutil.DecodeWIF(pkEnv)
-				Expect(err).ToNot(HaveOccurred())
+				// Generate a private key, and fund the associated address.
+				privKey := id.NewPrivKey()
+				pubKey := (*btcec.PrivateKey)(privKey).PubKey()
 
 				// PKH
-				pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.PrivKey.PubKey().SerializeCompressed()), &chaincfg.RegressionNetParams)
+				pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), &chaincfg.RegressionNetParams)
 				Expect(err).ToNot(HaveOccurred())
-				pkhAddrUncompressed, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.PrivKey.PubKey().SerializeUncompressed()), &chaincfg.RegressionNetParams)
+				pkhAddrUncompressed, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeUncompressed()), &chaincfg.RegressionNetParams)
 				Expect(err).ToNot(HaveOccurred())
-				log.Printf("PKH                %v", pkhAddr.EncodeAddress())
-				log.Printf("PKH (uncompressed) %v", pkhAddrUncompressed.EncodeAddress())
 
 				// WPKH
 				wpkAddr, err := btcutil.NewAddressWitnessPubKeyHash([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19}, &chaincfg.RegressionNetParams)
 				Expect(err).ToNot(HaveOccurred())
-				log.Printf("WPKH               %v", wpkAddr.EncodeAddress())
+
+				funded, err := server.Fund(pkhAddr.EncodeAddress(), 100000000)
+				Expect(err).ToNot(HaveOccurred())
+				server.Mine(1)
 
 				// Setup the client and load the unspent transaction outputs.
-				client := bitcoin.NewClient(bitcoin.DefaultClientOptions())
+				client := bitcoin.NewClient(server.ClientOptions())
 				outputs, err := client.UnspentOutputs(context.Background(), 0, 999999999, address.Address(pkhAddr.EncodeAddress()))
 				Expect(err).ToNot(HaveOccurred())
-				Expect(len(outputs)).To(BeNumerically(">", 0))
+				Expect(outputs).To(Equal([]utxo.Output{funded}))
 				output := outputs[0]
 
 				// Check that we can load the output and that it is equal.
@@ -97,40 +102,32 @@ var _ = Describe("Bitcoin", func() {
 				// MPC algorithm, but for the purposes of this test, using an
 				// explicit privkey is ok.
 				sighashes, err := tx.Sighashes()
-				signatures := make([]pack.Bytes65, len(sighashes))
 				Expect(err).ToNot(HaveOccurred())
+				signatures := make([]pack.Bytes65, len(sighashes))
 				for i := range sighashes {
 					hash := id.Hash(sighashes[i])
-					privKey := (*id.PrivKey)(wif.PrivKey)
 					signature, err := privKey.Sign(&hash)
 					Expect(err).ToNot(HaveOccurred())
 					signatures[i] = pack.NewBytes65(signature)
 				}
-				Expect(tx.Sign(signatures, pack.NewBytes(wif.SerializePubKey()))).To(Succeed())
+				Expect(tx.Sign(signatures, pack.NewBytes(pubKey.SerializeCompressed()))).To(Succeed())
 
-				// Submit the transaction to the Bitcoin node. Again, this
-				// should be running a la `./multichaindeploy`.
+				// Submit the transaction, mine it, and check that it is
+				// confirmed.
 				txHash, err := tx.Hash()
 				Expect(err).ToNot(HaveOccurred())
-				err = client.SubmitTx(context.Background(), tx)
+				Expect(client.SubmitTx(context.Background(), tx)).To(Succeed())
+				confs, err := client.Confirmations(context.Background(), txHash)
 				Expect(err).ToNot(HaveOccurred())
-				log.Printf("TXID               %v", txHash)
-
-				for {
-					// Loop until the transaction has at least a few
-					// confirmations. This implies that the transaction is
-					// definitely valid, and the test has passed. We were
-					// successfully able to use the multichain to construct and
-					// submit a Bitcoin transaction!
-					confs, err := client.Confirmations(context.Background(), txHash)
-					Expect(err).ToNot(HaveOccurred())
-					log.Printf("                   %v/3 confirmations", confs)
-					if confs >= 1 {
-						break
-					}
-					time.Sleep(10 * time.Second)
-				}
-				ctxWithTimeout, cancelCtxWithTimeout := context.WithTimeout(context.Background(), time.Second)
+				Expect(confs).To(Equal(int64(0)))
+				server.Mine(1)
+				confs, err = client.Confirmations(context.Background(), txHash)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(confs).To(Equal(int64(1)))
+
+				// The spent output can no longer be loaded as unspent. The
+				// client retries until the context is done.
+				ctxWithTimeout, cancelCtxWithTimeout := context.WithTimeout(context.Background(), 100*time.Millisecond)
 				defer cancelCtxWithTimeout()
 				_, _, err = client.UnspentOutput(ctxWithTimeout, output.Outpoint)
 				Expect(err).To(HaveOccurred())
@@ -0,0 +1,214 @@
+package bitcoin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// DefaultIncrementalRelayFeeRate is the default minimum fee rate (in
+// SATs-per-byte) that a replacement transaction must pay for its own relay, in
+// addition to the fee of the transaction that it replaces (as defined by
+// BIP125). This is the same as the default -incrementalrelayfee of Bitcoin
+// Core. It is also used as the minimum fee rate of child transactions.
+const DefaultIncrementalRelayFeeRate = 1
+
+// A FeeBumper increases the fee of transactions that have been submitted to
+// the Bitcoin network, but have not been confirmed. It can replace the
+// transaction with one that pays a higher fee (as defined by BIP125), or it
+// can build a child transaction that spends the change of the transaction and
+// pays a fee high enough for both transactions to be confirmed (known as
+// child-pays-for-parent).
+type FeeBumper struct {
+	txBuilder               TxBuilder
+	gasEstimator            GasEstimator
+	incrementalRelayFeeRate pack.U256
+}
+
+// NewFeeBumper returns a fee bumper that builds transactions using the
+// transaction builder, and that targets the fee rate returned by the gas
+// estimator.
+func NewFeeBumper(txBuilder TxBuilder, gasEstimator GasEstimator) FeeBumper {
+	return FeeBumper{
+		txBuilder:               txBuilder,
+		gasEstimator:            gasEstimator,
+		incrementalRelayFeeRate: pack.NewU256FromUint64(DefaultIncrementalRelayFeeRate),
+	}
+}
+
+// WithIncrementalRelayFeeRate returns a copy of the fee bumper that uses the
+// given incremental relay fee rate (in SATs-per-byte).
+func (bumper FeeBumper) WithIncrementalRelayFeeRate(feeRate pack.U256) FeeBumper {
+	bumper.incrementalRelayFeeRate = feeRate
+	return bumper
+}
+
+// Replace returns an unsigned transaction that replaces the given transaction,
+// and pays the fee rate returned by the gas estimator. The transaction must
+// signal replaceability (see SequenceRBF). The fee is increased by shrinking
+// the change output at the given index. If the change output is too small,
+// then the extra inputs are added, in order, until the fee can be paid. Extra
+// inputs must be confirmed, because BIP125 does not allow a replacement to
+// spend new unconfirmed outputs. If the change output becomes dust, then it is
+// dropped.
+//
+// The replacement always pays a higher absolute fee, and a higher fee rate,
+// than the transaction that it replaces, and the additional fee pays for its
+// relay at the incremental relay fee rate (as required by BIP125). Inputs of
+// the original transaction keep their sequence numbers, and extra inputs are
+// given the SequenceRBF sequence number so that the replacement can itself be
+// replaced.
+func (bumper FeeBumper) Replace(ctx context.Context, tx *Tx, changeIndex int, extraInputs []utxo.Input) (*Tx, error) {
+	if changeIndex < 0 || changeIndex >= len(tx.recipients) {
+		return nil, fmt.Errorf("bad change: expected index < %v, got index %v", len(tx.recipients), changeIndex)
+	}
+	replaceable := false
+	sequences := make([]uint32, 0, len(tx.msgTx.TxIn)+len(extraInputs))
+	for _, txIn := range tx.msgTx.TxIn {
+		replaceable = replaceable || SignalsRBF(txIn.Sequence)
+		sequences = append(sequences, txIn.Sequence)
+	}
+	if !replaceable {
+		return nil, fmt.Errorf("bad tx: does not signal replaceability")
+	}
+	feeRate, _, err := bumper.gasEstimator.EstimateGas(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("estimating fee rate: %v", err)
+	}
+
+	inputs := append([]utxo.Input{}, tx.inputs...)
+	inputValue := pack.NewU256FromUint64(0)
+	for _, input := range inputs {
+		inputValue = inputValue.Add(input.Value)
+	}
+	recipients := append([]utxo.Recipient{}, tx.recipients...)
+	outputValue := pack.NewU256FromUint64(0)
+	for i, recipient := range recipients {
+		if i != changeIndex {
+			outputValue = outputValue.Add(recipient.Value)
+		}
+	}
+	withoutChange := append(append([]utxo.Recipient{}, recipients[:changeIndex]...), recipients[changeIndex+1:]...)
+
+	for i := 0; ; i++ {
+		txBuilder := bumper.txBuilder.WithLockTime(tx.msgTx.LockTime).WithInputSequences(sequences)
+
+		// The size of the transaction does not depend on the value of the
+		// change, so a placeholder value is used to estimate it.
+		recipients[changeIndex].Value = pack.NewU256FromUint64(0)
+		size, err := bumper.estimateSize(txBuilder, inputs, recipients)
+		if err != nil {
+			return nil, err
+		}
+		fee := bumper.replacementFee(tx, feeRate, size)
+		if inputValue.GreaterThanEqual(outputValue.Add(fee)) {
+			change := inputValue.Sub(outputValue).Sub(fee)
+			if change.GreaterThanEqual(bumper.txBuilder.feePolicy.OutputDustThreshold(tx.msgTx.TxOut[changeIndex].PkScript)) {
+				recipients[changeIndex].Value = change
+				return buildTx(txBuilder, inputs, recipients)
+			}
+		}
+
+		// Dropping the change output pays the remainder as a fee.
+		if len(withoutChange) > 0 {
+			size, err = bumper.estimateSize(txBuilder, inputs, withoutChange)
+			if err != nil {
+				return nil, err
+			}
+			if inputValue.GreaterThanEqual(outputValue.Add(bumper.replacementFee(tx, feeRate, size))) {
+				return buildTx(txBuilder, inputs, withoutChange)
+			}
+		}
+
+		if i >= len(extraInputs) {
+			return nil, ErrInsufficientInputs{InputValue: inputValue, OutputValue: outputValue.Add(fee)}
+		}
+		inputs = append(inputs, extraInputs[i])
+		inputValue = inputValue.Add(extraInputs[i].Value)
+		sequences = append(sequences, SequenceRBF)
+	}
+}
+
+// Child returns an unsigned transaction that spends the change output at the
+// given index of the parent transaction, and sends it to the given address.
+// The child pays a fee such that the fee rate of the parent and child together
+// (known as the package fee rate) is the fee rate returned by the gas
+// estimator. The child pays at least the estimated fee rate for its own size,
+// even if the parent already pays enough.
+func (bumper FeeBumper) Child(ctx context.Context, parent *Tx, changeIndex int, to address.Address) (*Tx, error) {
+	outputs, err := parent.Outputs()
+	if err != nil {
+		return nil, fmt.Errorf("bad parent: %v", err)
+	}
+	if changeIndex < 0 || changeIndex >= len(outputs) {
+		return nil, fmt.Errorf("bad change: expected index < %v, got index %v", len(outputs), changeIndex)
+	}
+	feeRate, _, err := bumper.gasEstimator.EstimateGas(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("estimating fee rate: %v", err)
+	}
+
+	inputs := []utxo.Input{{Output: outputs[changeIndex]}}
+	recipients := []utxo.Recipient{{To: to, Value: pack.NewU256FromUint64(0)}}
+	txBuilder := bumper.txBuilder.WithSequence(SequenceRBF)
+	child, err := txBuilder.build(inputs, recipients)
+	if err != nil {
+		return nil, err
+	}
+	size := child.EstimateSize()
+
+	fee := feeRate.Mul(pack.NewU256FromUint64(uint64(size)))
+	packageFee := feeRate.Mul(pack.NewU256FromUint64(uint64(parent.EstimateSize() + size)))
+	if packageFee.GreaterThan(parent.Fee().Add(fee)) {
+		fee = packageFee.Sub(parent.Fee())
+	}
+	if minFee := bumper.incrementalRelayFeeRate.Mul(pack.NewU256FromUint64(uint64(size))); fee.LessThan(minFee) {
+		fee = minFee
+	}
+
+	value := outputs[changeIndex].Value
+	dustThreshold := bumper.txBuilder.feePolicy.OutputDustThreshold(child.msgTx.TxOut[0].PkScript)
+	if value.LessThan(fee.Add(dustThreshold)) {
+		return nil, ErrInsufficientInputs{InputValue: value, OutputValue: fee.Add(dustThreshold)}
+	}
+	recipients[0].Value = value.Sub(fee)
+	return buildTx(txBuilder, inputs, recipients)
+}
+
+// replacementFee returns the minimum fee that a replacement of the given size
+// must pay: at least the estimated fee rate, a higher fee rate than the
+// original, and enough additional fee to pay for its relay.
+func (bumper FeeBumper) replacementFee(tx *Tx, feeRate pack.U256, size int) pack.U256 {
+	n := pack.NewU256FromUint64(uint64(size))
+	fee := feeRate.Mul(n)
+	if minFee := tx.fee.Add(bumper.incrementalRelayFeeRate.Mul(n)); fee.LessThan(minFee) {
+		fee = minFee
+	}
+	// The fee rate must be strictly higher than the fee rate of the original.
+	originalSize := pack.NewU256FromUint64(uint64(tx.EstimateSize()))
+	if minFee := tx.fee.Mul(n).Div(originalSize).Add(pack.NewU256FromUint64(1)); fee.LessThan(minFee) {
+		fee = minFee
+	}
+	return fee
+}
+
+// estimateSize returns the estimated size of the transaction that would be
+// built for the inputs and recipients, without checking the fee policy.
+func (bumper FeeBumper) estimateSize(txBuilder TxBuilder, inputs []utxo.Input, recipients []utxo.Recipient) (int, error) {
+	tx, err := txBuilder.build(inputs, recipients)
+	if err != nil {
+		return 0, err
+	}
+	return tx.EstimateSize(), nil
+}
+
+func buildTx(txBuilder TxBuilder, inputs []utxo.Input, recipients []utxo.Recipient) (*Tx, error) {
+	tx, err := txBuilder.BuildTx(inputs, recipients)
+	if err != nil {
+		return nil, err
+	}
+	return tx.(*Tx), nil
+}
@@ -0,0 +1,202 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// feeRateClient is a client that only estimates fees, and estimates a fixed
+// number of SATs-per-byte.
+type feeRateClient struct {
+	bitcoin.Client
+	satsPerByte uint64
+}
+
+func (client feeRateClient) EstimateSmartFee(ctx context.Context, numBlocks int64) (float64, error) {
+	if client.satsPerByte == 0 {
+		return 0, fmt.Errorf("insufficient data")
+	}
+	return float64(client.satsPerByte) * 1024 / 1e8, nil
+}
+
+var _ = Describe("Fee bumping", func() {
+	params := &chaincfg.RegressionNetParams
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(make([]byte, 20), params)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		panic(err)
+	}
+	to := address.Address(addr.EncodeAddress())
+	input := func(index uint32, value uint64) utxo.Input {
+		return utxo.Input{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(index)}, Value: pack.NewU256FromUint64(value), PubKeyScript: pubKeyScript}}
+	}
+	txBuilder := bitcoin.NewTxBuilder(params).WithSequence(bitcoin.SequenceRBF)
+	bumper := func(satsPerByte uint64) bitcoin.FeeBumper {
+		gasEstimator := bitcoin.NewGasEstimator(feeRateClient{satsPerByte: satsPerByte}, 1, pack.NewU256FromUint64(0))
+		return bitcoin.NewFeeBumper(txBuilder, gasEstimator)
+	}
+
+	// build returns a transaction that pays 50000 SATs, and the rest of the
+	// inputs (less the fee) as change.
+	build := func(txBuilder bitcoin.TxBuilder, inputs []utxo.Input, fee uint64) *bitcoin.Tx {
+		total := uint64(0)
+		for _, input := range inputs {
+			total += input.Value.Int().Uint64()
+		}
+		tx, err := txBuilder.BuildTx(inputs, []utxo.Recipient{
+			{To: to, Value: pack.NewU256FromUint64(50000)},
+			{To: to, Value: pack.NewU256FromUint64(total - 50000 - fee)},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*bitcoin.Tx)
+	}
+	outputValues := func(tx *bitcoin.Tx) []uint64 {
+		outputs, err := tx.Outputs()
+		Expect(err).ToNot(HaveOccurred())
+		values := make([]uint64, len(outputs))
+		for i, output := range outputs {
+			values[i] = output.Value.Int().Uint64()
+		}
+		return values
+	}
+	// expectReplacement checks that the replacement follows the BIP125 rules
+	// for the given incremental relay fee rate.
+	expectReplacement := func(original, replacement *bitcoin.Tx, satsPerByte uint64) {
+		fee := replacement.Fee().Int().Uint64()
+		size := uint64(replacement.EstimateSize())
+		originalFee := original.Fee().Int().Uint64()
+		Expect(fee).To(BeNumerically(">=", satsPerByte*size))
+		Expect(fee).To(BeNumerically(">=", originalFee+bitcoin.DefaultIncrementalRelayFeeRate*size))
+		Expect(fee * uint64(original.EstimateSize())).To(BeNumerically(">", originalFee*size))
+		Expect(outputValues(replacement)[0]).To(Equal(uint64(50000)))
+	}
+
+	Context("when replacing transactions", func() {
+		It("should shrink the change", func() {
+			original := build(txBuilder, []utxo.Input{input(0, 100000)}, 200)
+			replacement, err := bumper(20).Replace(context.Background(), original, 1, nil)
+			Expect(err).ToNot(HaveOccurred())
+			expectReplacement(original, replacement, 20)
+
+			inputs, err := replacement.Inputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inputs).To(HaveLen(1))
+			Expect(outputValues(replacement)[1]).To(Equal(100000 - 50000 - replacement.Fee().Int().Uint64()))
+		})
+
+		It("should pay for relay when the estimated fee rate is lower", func() {
+			original := build(txBuilder, []utxo.Input{input(0, 100000)}, 5000)
+			replacement, err := bumper(1).Replace(context.Background(), original, 1, nil)
+			Expect(err).ToNot(HaveOccurred())
+			expectReplacement(original, replacement, 1)
+		})
+
+		It("should add inputs when the change is too small", func() {
+			original := build(txBuilder, []utxo.Input{input(0, 51000)}, 200)
+			extraInputs := []utxo.Input{input(1, 1000), input(2, 100000), input(3, 100000)}
+			replacement, err := bumper(20).Replace(context.Background(), original, 1, extraInputs)
+			Expect(err).ToNot(HaveOccurred())
+			expectReplacement(original, replacement, 20)
+
+			inputs, err := replacement.Inputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inputs).To(Equal(append([]utxo.Input{input(0, 51000)}, extraInputs[:2]...)))
+			Expect(outputValues(replacement)).To(HaveLen(2))
+		})
+
+		It("should drop the change when it becomes dust", func() {
+			original := build(txBuilder, []utxo.Input{input(0, 55000)}, 1000)
+			replacement, err := bumper(40).Replace(context.Background(), original, 1, nil)
+			Expect(err).ToNot(HaveOccurred())
+			expectReplacement(original, replacement, 40)
+			Expect(outputValues(replacement)).To(Equal([]uint64{50000}))
+			Expect(replacement.Fee()).To(Equal(pack.NewU256FromUint64(5000)))
+		})
+
+		It("should keep the sequence numbers and lock time", func() {
+			original := build(txBuilder.WithLockTime(100).WithInputSequences([]uint32{bitcoin.SequenceRBF, bitcoin.SequenceFinal}), []utxo.Input{input(0, 30000), input(1, 21000)}, 200)
+			replacement, err := bumper(20).Replace(context.Background(), original, 1, []utxo.Input{input(2, 100000)})
+			Expect(err).ToNot(HaveOccurred())
+
+			serialized, err := replacement.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(bitcoin.Version)
+			Expect(msgTx.DeserializeNoWitness(bytes.NewReader(serialized))).To(Succeed())
+			Expect(msgTx.LockTime).To(Equal(uint32(100)))
+			Expect(msgTx.TxIn).To(HaveLen(3))
+			Expect(msgTx.TxIn[0].Sequence).To(Equal(uint32(bitcoin.SequenceRBF)))
+			Expect(msgTx.TxIn[1].Sequence).To(Equal(uint32(bitcoin.SequenceFinal)))
+			Expect(msgTx.TxIn[2].Sequence).To(Equal(uint32(bitcoin.SequenceRBF)))
+		})
+
+		It("should reject transactions that do not signal replaceability", func() {
+			original := build(bitcoin.NewTxBuilder(params), []utxo.Input{input(0, 100000)}, 200)
+			_, err := bumper(20).Replace(context.Background(), original, 1, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return an error when the inputs are insufficient", func() {
+			original := build(txBuilder, []utxo.Input{input(0, 51000)}, 200)
+			_, err := bumper(20).Replace(context.Background(), original, 1, nil)
+			Expect(err).To(BeAssignableToTypeOf(bitcoin.ErrInsufficientInputs{}))
+		})
+
+		It("should return an error when the fee rate cannot be estimated", func() {
+			original := build(txBuilder, []utxo.Input{input(0, 100000)}, 200)
+			_, err := bumper(0).Replace(context.Background(), original, 1, nil)
+			Expect(err).To(HaveOccurred())
+			_, err = bumper(20).Replace(context.Background(), original, 2, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building child transactions", func() {
+		It("should pay the package fee rate", func() {
+			parent := build(txBuilder, []utxo.Input{input(0, 100000)}, 200)
+			child, err := bumper(20).Child(context.Background(), parent, 1, to)
+			Expect(err).ToNot(HaveOccurred())
+
+			parentHash, err := parent.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			inputs, err := child.Inputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inputs).To(HaveLen(1))
+			Expect(inputs[0].Hash).To(Equal(parentHash))
+			Expect(inputs[0].Index).To(Equal(pack.NewU32(1)))
+
+			fee := parent.Fee().Int().Uint64() + child.Fee().Int().Uint64()
+			size := uint64(parent.EstimateSize() + child.EstimateSize())
+			Expect(fee).To(Equal(20 * size))
+			Expect(outputValues(child)).To(Equal([]uint64{100000 - 50000 - fee}))
+		})
+
+		It("should pay for itself when the parent pays enough", func() {
+			parent := build(txBuilder, []utxo.Input{input(0, 100000)}, 20000)
+			child, err := bumper(20).Child(context.Background(), parent, 1, to)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(child.Fee()).To(Equal(pack.NewU256FromUint64(20 * uint64(child.EstimateSize()))))
+		})
+
+		It("should return an error when the change is too small", func() {
+			parent := build(txBuilder, []utxo.Input{input(0, 52000)}, 200)
+			_, err := bumper(20).Child(context.Background(), parent, 1, to)
+			Expect(err).To(BeAssignableToTypeOf(bitcoin.ErrInsufficientInputs{}))
+		})
+	})
+})
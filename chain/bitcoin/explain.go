@@ -0,0 +1,251 @@
+package bitcoin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// An Explanation is a structured, human-readable view of a transaction. It
+// follows the layout of the result of the `decoderawtransaction` RPC call, and
+// its JSON encoding is stable. Values are in satoshis.
+type Explanation struct {
+	TxID     string            `json:"txid"`
+	Hash     string            `json:"hash"`
+	Version  int32             `json:"version"`
+	Size     int               `json:"size"`
+	VSize    int               `json:"vsize"`
+	LockTime uint32            `json:"locktime"`
+	Inputs   []ExplainedInput  `json:"vin"`
+	Outputs  []ExplainedOutput `json:"vout"`
+	// Fee is only known if the outputs spent by all inputs are known.
+	Fee *int64 `json:"fee,omitempty"`
+}
+
+// An ExplainedInput is a structured view of a transaction input. The value and
+// the sighash are only known if the output spent by the input is known.
+type ExplainedInput struct {
+	TxID      string          `json:"txid"`
+	Vout      uint32          `json:"vout"`
+	ScriptSig ExplainedScript `json:"scriptSig"`
+	Witness   []string        `json:"txinwitness,omitempty"`
+	Sequence  uint32          `json:"sequence"`
+	Value     *int64          `json:"value,omitempty"`
+	Sighash   string          `json:"sighash,omitempty"`
+}
+
+// An ExplainedOutput is a structured view of a transaction output. The
+// address is empty if the pubkey script does not pay to an address.
+type ExplainedOutput struct {
+	Value        int64           `json:"value"`
+	N            uint32          `json:"n"`
+	ScriptPubKey ExplainedScript `json:"scriptPubKey"`
+}
+
+// An ExplainedScript is a disassembled script. The type and address are only
+// set for pubkey scripts.
+type ExplainedScript struct {
+	Asm     string `json:"asm"`
+	Hex     string `json:"hex"`
+	Type    string `json:"type,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// DecodeTx deserializes a transaction, with or without witness data.
+func DecodeTx(data []byte) (*wire.MsgTx, error) {
+	msgTx := new(wire.MsgTx)
+	r := bytes.NewReader(data)
+	if err := msgTx.Deserialize(r); err != nil {
+		return nil, fmt.Errorf("bad tx: %v", err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("bad tx: %v trailing bytes", r.Len())
+	}
+	return msgTx, nil
+}
+
+// ExplainRawTx decodes a serialized transaction and explains it. See Explain.
+func ExplainRawTx(data []byte, inputs []utxo.Input, params *chaincfg.Params, encoder address.Encoder) (Explanation, error) {
+	msgTx, err := DecodeTx(data)
+	if err != nil {
+		return Explanation{}, err
+	}
+	return Explain(msgTx, inputs, params, encoder)
+}
+
+// Explain returns a structured view of the transaction. The inputs are the
+// inputs of the transaction, in order, with the outputs that they spend, as
+// they would be passed to the TxBuilder. If they are nil, the values of the
+// inputs, the fee, and the sighashes are omitted. Addresses of outputs are
+// encoded using the address encoder, so that chains that re-use this package
+// can explain outputs using their own address format.
+func Explain(msgTx *wire.MsgTx, inputs []utxo.Input, params *chaincfg.Params, encoder address.Encoder) (Explanation, error) {
+	var sighashes []pack.Bytes32
+	if inputs != nil {
+		if err := CheckExplainedInputs(msgTx, inputs); err != nil {
+			return Explanation{}, err
+		}
+		var err error
+		if sighashes, err = (&Tx{inputs: inputs, msgTx: msgTx}).Sighashes(); err != nil {
+			return Explanation{}, fmt.Errorf("bad sighashes: %v", err)
+		}
+	}
+
+	fee, err := ExplainFee(inputs, msgTx.TxOut)
+	if err != nil {
+		return Explanation{}, err
+	}
+	baseSize := msgTx.SerializeSizeStripped()
+	size := msgTx.SerializeSize()
+	return Explanation{
+		TxID:     msgTx.TxHash().String(),
+		Hash:     msgTx.WitnessHash().String(),
+		Version:  msgTx.Version,
+		Size:     size,
+		VSize:    virtualSize(baseSize, size-baseSize),
+		LockTime: msgTx.LockTime,
+		Inputs:   ExplainInputs(msgTx.TxIn, inputs, sighashes),
+		Outputs: ExplainOutputs(msgTx.TxOut, func(script []byte) (address.RawAddress, bool) {
+			return RawAddressFromScript(script, params)
+		}, encoder),
+		Fee: fee,
+	}, nil
+}
+
+// Explain returns a structured view of the transaction. See Explain.
+func (tx *Tx) Explain(params *chaincfg.Params, encoder address.Encoder) (Explanation, error) {
+	return Explain(tx.msgTx, tx.inputs, params, encoder)
+}
+
+// CheckExplainedInputs returns an error if the inputs do not spend the same
+// outpoints as the inputs of the transaction.
+func CheckExplainedInputs(msgTx *wire.MsgTx, inputs []utxo.Input) error {
+	if len(inputs) != len(msgTx.TxIn) {
+		return fmt.Errorf("expected %v inputs, got %v inputs", len(msgTx.TxIn), len(inputs))
+	}
+	for i, input := range inputs {
+		outpoint := msgTx.TxIn[i].PreviousOutPoint
+		if !bytes.Equal(input.Outpoint.Hash, outpoint.Hash[:]) || input.Outpoint.Index.Uint32() != outpoint.Index {
+			return fmt.Errorf("bad input %v: expected outpoint %v", i, outpoint)
+		}
+	}
+	return nil
+}
+
+// ExplainInputs returns structured views of the transaction inputs. The inputs
+// and the sighashes can be nil if they are not known.
+func ExplainInputs(txIns []*wire.TxIn, inputs []utxo.Input, sighashes []pack.Bytes32) []ExplainedInput {
+	explained := make([]ExplainedInput, len(txIns))
+	for i, txIn := range txIns {
+		explained[i] = ExplainedInput{
+			TxID:      txIn.PreviousOutPoint.Hash.String(),
+			Vout:      txIn.PreviousOutPoint.Index,
+			ScriptSig: ExplainScript(txIn.SignatureScript),
+			Sequence:  txIn.Sequence,
+		}
+		for _, item := range txIn.Witness {
+			explained[i].Witness = append(explained[i].Witness, hex.EncodeToString(item))
+		}
+		if inputs != nil {
+			value := inputs[i].Value.Int().Int64()
+			explained[i].Value = &value
+		}
+		if sighashes != nil {
+			explained[i].Sighash = hex.EncodeToString(sighashes[i][:])
+		}
+	}
+	return explained
+}
+
+// ExplainOutputs returns structured views of the transaction outputs. The
+// rawAddress function returns the raw address that is paid by a pubkey script,
+// which is then encoded using the address encoder.
+func ExplainOutputs(txOuts []*wire.TxOut, rawAddress func(script []byte) (address.RawAddress, bool), encoder address.Encoder) []ExplainedOutput {
+	explained := make([]ExplainedOutput, len(txOuts))
+	for i, txOut := range txOuts {
+		script := ExplainScript(txOut.PkScript)
+		script.Type = ScriptType(txOut.PkScript)
+		if rawAddr, ok := rawAddress(txOut.PkScript); ok {
+			if addr, err := encoder.EncodeAddress(rawAddr); err == nil {
+				script.Address = string(addr)
+			}
+		}
+		explained[i] = ExplainedOutput{
+			Value:        txOut.Value,
+			N:            uint32(i),
+			ScriptPubKey: script,
+		}
+	}
+	return explained
+}
+
+// ExplainFee returns the implied fee of a transaction that spends the inputs,
+// and produces the outputs. It returns nil if the inputs are nil.
+func ExplainFee(inputs []utxo.Input, txOuts []*wire.TxOut) (*int64, error) {
+	if inputs == nil {
+		return nil, nil
+	}
+	fee := int64(0)
+	for _, input := range inputs {
+		fee += input.Value.Int().Int64()
+	}
+	for _, txOut := range txOuts {
+		fee -= txOut.Value
+	}
+	if fee < 0 {
+		return nil, fmt.Errorf("bad fee: outputs exceed inputs by %v", -fee)
+	}
+	return &fee, nil
+}
+
+// ExplainScript disassembles the script. Scripts that cannot be parsed are
+// disassembled up to the first error, followed by "[error]".
+func ExplainScript(script []byte) ExplainedScript {
+	asm, err := txscript.DisasmString(script)
+	if err != nil {
+		asm += "[error]"
+	}
+	return ExplainedScript{Asm: asm, Hex: hex.EncodeToString(script)}
+}
+
+// ScriptType returns the name of the type of the pubkey script, using the same
+// names as Bitcoin Core.
+func ScriptType(script []byte) string {
+	switch {
+	case IsPayToTaproot(script):
+		return "witness_v1_taproot"
+	case IsNullData(script):
+		return "nulldata"
+	default:
+		return txscript.GetScriptClass(script).String()
+	}
+}
+
+// RawAddressFromScript returns the raw address, in the format expected by the
+// AddressEncoder, that is paid by a P2PKH, P2SH, P2WPKH, P2WSH, or P2TR pubkey
+// script. It returns false for all other scripts.
+func RawAddressFromScript(script []byte, params *chaincfg.Params) (address.RawAddress, bool) {
+	if IsPayToTaproot(script) {
+		return address.RawAddress(append([]byte{TaprootWitnessVersion}, script[2:]...)), true
+	}
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(script, params)
+	if err != nil || len(addrs) != 1 {
+		return nil, false
+	}
+	switch class {
+	case txscript.PubKeyHashTy, txscript.ScriptHashTy:
+		return address.RawAddress(base58.Decode(addrs[0].EncodeAddress())), true
+	case txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy:
+		return address.RawAddress(append([]byte{0}, addrs[0].ScriptAddress()...)), true
+	default:
+		return nil, false
+	}
+}
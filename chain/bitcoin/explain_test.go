@@ -0,0 +1,167 @@
+package bitcoin_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Explain", func() {
+	params := &chaincfg.RegressionNetParams
+	encoder := bitcoin.NewAddressEncoder(params)
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		panic(err)
+	}
+	key := signer.NewKeySigner(privKey, true)
+	pubKeyHash := btcutil.Hash160(key.PubKey())
+	p2pkhAddr, err := btcutil.NewAddressPubKeyHash(pubKeyHash, params)
+	if err != nil {
+		panic(err)
+	}
+	p2pkhScript, err := txscript.PayToAddrScript(p2pkhAddr)
+	if err != nil {
+		panic(err)
+	}
+	p2wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+	if err != nil {
+		panic(err)
+	}
+	p2wpkhScript, err := txscript.PayToAddrScript(p2wpkhAddr)
+	if err != nil {
+		panic(err)
+	}
+	p2trAddr, err := bitcoin.NewAddressTaproot(make([]byte, 32), params)
+	if err != nil {
+		panic(err)
+	}
+
+	inputs := []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: p2pkhScript}},
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(1)}, Value: pack.NewU256FromUint64(50000), PubKeyScript: p2wpkhScript}},
+	}
+	recipients := []utxo.Recipient{
+		{To: address.Address(p2pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(60000)},
+		{To: address.Address(p2wpkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(50000)},
+		{To: address.Address(p2trAddr.EncodeAddress()), Value: pack.NewU256FromUint64(30000)},
+		{Data: pack.Bytes("memo"), Value: pack.NewU256FromUint64(0)},
+	}
+
+	Context("when explaining built transactions", func() {
+		It("should explain the inputs, outputs, fee, and sighashes", func() {
+			tx, err := bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+
+			explanation, err := tx.(*bitcoin.Tx).Explain(params, encoder)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(explanation.Version).To(Equal(int32(bitcoin.Version)))
+			Expect(*explanation.Fee).To(Equal(int64(10000)))
+			Expect(explanation.TxID).To(Equal(explanation.Hash))
+			for i, input := range explanation.Inputs {
+				Expect(*input.Value).To(Equal(inputs[i].Value.Int().Int64()))
+				Expect(input.Sighash).To(Equal(hex.EncodeToString(sighashes[i][:])))
+			}
+
+			Expect(explanation.Outputs).To(HaveLen(4))
+			for i, expected := range []struct {
+				scriptType string
+				address    string
+			}{
+				{"pubkeyhash", p2pkhAddr.EncodeAddress()},
+				{"witness_v0_keyhash", p2wpkhAddr.EncodeAddress()},
+				{"witness_v1_taproot", p2trAddr.EncodeAddress()},
+				{"nulldata", ""},
+			} {
+				Expect(explanation.Outputs[i].N).To(Equal(uint32(i)))
+				Expect(explanation.Outputs[i].Value).To(Equal(recipients[i].Value.Int().Int64()))
+				Expect(explanation.Outputs[i].ScriptPubKey.Type).To(Equal(expected.scriptType))
+				Expect(explanation.Outputs[i].ScriptPubKey.Address).To(Equal(expected.address))
+			}
+
+			// After signing, the witness is explained, and the witness hash
+			// differs from the transaction hash.
+			Expect(signer.SignTx(context.Background(), key, tx)).To(Succeed())
+			signed, err := tx.(*bitcoin.Tx).Explain(params, encoder)
+			Expect(err).ToNot(HaveOccurred())
+			hash, err := tx.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signed.TxID).To(Equal(hex.EncodeToString(reverse(hash))))
+			Expect(signed.Hash).ToNot(Equal(signed.TxID))
+			Expect(signed.VSize).To(BeNumerically("<", signed.Size))
+			Expect(signed.Inputs[0].ScriptSig.Asm).To(HaveSuffix(hex.EncodeToString(key.PubKey())))
+			Expect(signed.Inputs[0].Witness).To(BeEmpty())
+			Expect(signed.Inputs[1].Witness).To(HaveLen(2))
+			Expect(signed.Inputs[1].Witness[1]).To(Equal(hex.EncodeToString(key.PubKey())))
+			for i, input := range signed.Inputs {
+				Expect(input.Sighash).To(Equal(explanation.Inputs[i].Sighash))
+			}
+
+			// Explaining the raw transaction gives the same result.
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			raw, err := bitcoin.ExplainRawTx(serialized, inputs, params, encoder)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(raw).To(Equal(signed))
+		})
+	})
+
+	Context("when explaining raw transactions", func() {
+		It("should omit unknown values and produce stable JSON", func() {
+			tx, err := bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+
+			explanation, err := bitcoin.ExplainRawTx(serialized, nil, params, encoder)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(explanation.Fee).To(BeNil())
+			data, err := json.Marshal(explanation)
+			Expect(err).ToNot(HaveOccurred())
+			var fields map[string]interface{}
+			Expect(json.Unmarshal(data, &fields)).To(Succeed())
+			Expect(fields).ToNot(HaveKey("fee"))
+			Expect(fields["vin"].([]interface{})[0]).ToNot(HaveKey("sighash"))
+
+			var decoded bitcoin.Explanation
+			Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(explanation))
+		})
+
+		It("should reject malformed transactions", func() {
+			tx, err := bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = bitcoin.DecodeTx(serialized[:len(serialized)-1])
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.DecodeTx(append(serialized, 0))
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.ExplainRawTx(serialized, inputs[1:], params, encoder)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+func reverse(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i := range data {
+		reversed[len(data)-1-i] = data[i]
+	}
+	return reversed
+}
@@ -0,0 +1,239 @@
+package bitcoin
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultMaxFee is the default maximum absolute fee (in SATs) of a
+	// transaction. This is the same as the default -maxtxfee of Bitcoin Core.
+	DefaultMaxFee = 10000000
+	// DefaultMaxFeeRate is the default maximum fee rate (in SATs-per-byte) of
+	// a transaction. This is the same as the default maxfeerate of Bitcoin
+	// Core.
+	DefaultMaxFeeRate = 10000
+	// DefaultDustRelayFeeRate is the default fee rate (in SATs-per-kilobyte)
+	// used to compute the dust threshold of each output. This is the same as
+	// the default -dustrelayfee of Bitcoin Core.
+	DefaultDustRelayFeeRate = 3000
+	// DefaultDustThreshold is the dust threshold (in SATs) of P2PKH outputs at
+	// the default dust relay fee rate. Other kinds of outputs have other
+	// thresholds (for example, 294 SATs for P2WPKH, and 330 SATs for P2TR), so
+	// it is only a default for outputs that are known to be P2PKH, such as
+	// change. See DustThreshold.
+	DefaultDustThreshold = 546
+)
+
+// FeePolicy defines the bounds that are enforced on the implicit fee of a
+// transaction (the difference in the sum value of the inputs and the sum value
+// of the outputs), and on the values of its outputs. A zero value for any of
+// the bounds disables the respective check.
+//
+// An output is dust if its value is less than the DustThreshold, or less than
+// the dust threshold of its pubkey script at the DustRelayFeeRate (in
+// SATs-per-kilobyte, see DustThreshold).
+type FeePolicy struct {
+	MaxFee           pack.U256
+	MaxFeeRate       pack.U256
+	DustThreshold    pack.U256
+	DustRelayFeeRate pack.U256
+}
+
+// DefaultFeePolicy returns a FeePolicy with the default settings. These
+// settings match the default relay policy of Bitcoin Core, which computes the
+// dust threshold of each output from its pubkey script.
+func DefaultFeePolicy() FeePolicy {
+	return FeePolicy{
+		MaxFee:           pack.NewU256FromUint64(DefaultMaxFee),
+		MaxFeeRate:       pack.NewU256FromUint64(DefaultMaxFeeRate),
+		DustRelayFeeRate: pack.NewU256FromUint64(DefaultDustRelayFeeRate),
+	}
+}
+
+// WithMaxFee sets the maximum absolute fee (in SATs) that a transaction is
+// allowed to pay.
+func (policy FeePolicy) WithMaxFee(maxFee pack.U256) FeePolicy {
+	policy.MaxFee = maxFee
+	return policy
+}
+
+// WithMaxFeeRate sets the maximum fee rate (in SATs-per-byte) that a
+// transaction is allowed to pay.
+func (policy FeePolicy) WithMaxFeeRate(maxFeeRate pack.U256) FeePolicy {
+	policy.MaxFeeRate = maxFeeRate
+	return policy
+}
+
+// WithDustThreshold sets the minimum value (in SATs) that an output is allowed
+// to have, whatever its pubkey script.
+func (policy FeePolicy) WithDustThreshold(dustThreshold pack.U256) FeePolicy {
+	policy.DustThreshold = dustThreshold
+	return policy
+}
+
+// WithDustRelayFeeRate sets the fee rate (in SATs-per-kilobyte) used to
+// compute the dust threshold of each output from its pubkey script.
+func (policy FeePolicy) WithDustRelayFeeRate(dustRelayFeeRate pack.U256) FeePolicy {
+	policy.DustRelayFeeRate = dustRelayFeeRate
+	return policy
+}
+
+// OutputDustThreshold returns the minimum value (in SATs) of an output with
+// the pubkey script: the greater of the DustThreshold, and the dust threshold
+// of the script at the DustRelayFeeRate.
+func (policy FeePolicy) OutputDustThreshold(script []byte) pack.U256 {
+	threshold := DustThreshold(script, policy.DustRelayFeeRate)
+	if threshold.LessThan(policy.DustThreshold) {
+		return policy.DustThreshold
+	}
+	return threshold
+}
+
+// DustThreshold returns the minimum value (in SATs) of an output with the
+// pubkey script, at the dust relay fee rate (in SATs-per-kilobyte). It is the
+// fee of the output and of an input that spends it, which is
+// GetDustThreshold in Bitcoin Core. Null-data outputs are provably unspendable,
+// and have no dust threshold.
+func DustThreshold(script []byte, dustRelayFeeRate pack.U256) pack.U256 {
+	if IsNullData(script) || len(script) > txscript.MaxScriptSize || dustRelayFeeRate.Equal(pack.NewU256FromUint64(0)) {
+		return pack.NewU256FromUint64(0)
+	}
+	// The serialized size of the output, and of an input that spends it. The
+	// signature and pubkey of inputs that spend witness programs are
+	// discounted.
+	size := 8 + wire.VarIntSerializeSize(uint64(len(script))) + len(script)
+	if txscript.IsWitnessProgram(script) {
+		size += 32 + 4 + 1 + 107/4 + 4
+	} else {
+		size += 32 + 4 + 1 + 107 + 4
+	}
+	fee := dustRelayFeeRate.Mul(pack.NewU256FromUint64(uint64(size))).Div(pack.NewU256FromUint64(1000))
+	if fee.Equal(pack.NewU256FromUint64(0)) {
+		return pack.NewU256FromUint64(1)
+	}
+	return fee
+}
+
+// Check the inputs and outputs of a transaction against the policy, and return
+// the implicit fee of the transaction. The size is the number of bytes that
+// the transaction will have once it has been signed, and is used to check the
+// fee rate.
+func (policy FeePolicy) Check(inputs []utxo.Input, outputs []*wire.TxOut, size int) (pack.U256, error) {
+	seen := make(map[wire.OutPoint]struct{}, len(inputs))
+	inputValue := pack.NewU256FromUint64(0)
+	for i, input := range inputs {
+		hash := chainhash.Hash{}
+		copy(hash[:], input.Hash)
+		outpoint := wire.OutPoint{Hash: hash, Index: input.Index.Uint32()}
+		if _, ok := seen[outpoint]; ok {
+			return pack.U256{}, ErrDuplicateInput{Index: i, Outpoint: input.Outpoint}
+		}
+		seen[outpoint] = struct{}{}
+		inputValue = inputValue.Add(input.Value)
+	}
+
+	outputValue := pack.NewU256FromUint64(0)
+	for i, output := range outputs {
+		if output.Value < 0 {
+			return pack.U256{}, fmt.Errorf("bad output %v: value is less than zero", i)
+		}
+		value := pack.NewU256FromUint64(uint64(output.Value))
+		// Null-data outputs are provably unspendable, and are expected to
+		// have no value.
+		if IsNullData(output.PkScript) {
+			outputValue = outputValue.Add(value)
+			continue
+		}
+		if dustThreshold := policy.OutputDustThreshold(output.PkScript); value.LessThan(dustThreshold) {
+			return pack.U256{}, ErrDustOutput{Index: i, Value: value, DustThreshold: dustThreshold}
+		}
+		outputValue = outputValue.Add(value)
+	}
+
+	if outputValue.GreaterThan(inputValue) {
+		return pack.U256{}, ErrInsufficientInputs{InputValue: inputValue, OutputValue: outputValue}
+	}
+	fee := inputValue.Sub(outputValue)
+
+	zero := pack.NewU256FromUint64(0)
+	if !policy.MaxFee.Equal(zero) && fee.GreaterThan(policy.MaxFee) {
+		return pack.U256{}, ErrFeeTooHigh{Fee: fee, MaxFee: policy.MaxFee}
+	}
+	if !policy.MaxFeeRate.Equal(zero) && size > 0 {
+		maxFee := policy.MaxFeeRate.Mul(pack.NewU256FromUint64(uint64(size)))
+		if fee.GreaterThan(maxFee) {
+			return pack.U256{}, ErrFeeRateTooHigh{Fee: fee, Size: size, MaxFeeRate: policy.MaxFeeRate}
+		}
+	}
+	return fee, nil
+}
+
+// ErrDuplicateInput is returned when a transaction consumes the same outpoint
+// more than once.
+type ErrDuplicateInput struct {
+	Index    int
+	Outpoint utxo.Outpoint
+}
+
+// Error implements the error interface.
+func (err ErrDuplicateInput) Error() string {
+	hash := chainhash.Hash{}
+	copy(hash[:], err.Outpoint.Hash)
+	return fmt.Sprintf("duplicate input %v: outpoint %v:%v is already spent by the transaction", err.Index, hash, err.Outpoint.Index)
+}
+
+// ErrDustOutput is returned when a transaction produces an output with a
+// value below the dust threshold.
+type ErrDustOutput struct {
+	Index         int
+	Value         pack.U256
+	DustThreshold pack.U256
+}
+
+// Error implements the error interface.
+func (err ErrDustOutput) Error() string {
+	return fmt.Sprintf("dust output %v: value %v is less than the dust threshold %v", err.Index, err.Value, err.DustThreshold)
+}
+
+// ErrInsufficientInputs is returned when the sum value of the outputs of a
+// transaction is greater than the sum value of its inputs.
+type ErrInsufficientInputs struct {
+	InputValue  pack.U256
+	OutputValue pack.U256
+}
+
+// Error implements the error interface.
+func (err ErrInsufficientInputs) Error() string {
+	return fmt.Sprintf("insufficient inputs: input value %v is less than output value %v", err.InputValue, err.OutputValue)
+}
+
+// ErrFeeTooHigh is returned when the implicit fee of a transaction is greater
+// than the maximum absolute fee.
+type ErrFeeTooHigh struct {
+	Fee    pack.U256
+	MaxFee pack.U256
+}
+
+// Error implements the error interface.
+func (err ErrFeeTooHigh) Error() string {
+	return fmt.Sprintf("fee too high: fee %v is greater than max fee %v", err.Fee, err.MaxFee)
+}
+
+// ErrFeeRateTooHigh is returned when the implicit fee of a transaction,
+// divided by its size, is greater than the maximum fee rate.
+type ErrFeeRateTooHigh struct {
+	Fee        pack.U256
+	Size       int
+	MaxFeeRate pack.U256
+}
+
+// Error implements the error interface.
+func (err ErrFeeRateTooHigh) Error() string {
+	return fmt.Sprintf("fee rate too high: fee %v for %v bytes is greater than max fee rate %v per byte", err.Fee, err.Size, err.MaxFeeRate)
+}
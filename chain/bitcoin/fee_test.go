@@ -0,0 +1,156 @@
+package bitcoin_test
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fee", func() {
+	pk := id.NewPrivKey()
+	pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160((*btcec.PrivateKey)(pk).PubKey().SerializeCompressed()), &chaincfg.RegressionNetParams)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(pkhAddr)
+	if err != nil {
+		panic(err)
+	}
+
+	input := func(index uint32, value uint64) utxo.Input {
+		return utxo.Input{Output: utxo.Output{
+			Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(index)},
+			Value:        pack.NewU256FromUint64(value),
+			PubKeyScript: pack.NewBytes(pubKeyScript),
+		}}
+	}
+	recipient := func(value uint64) utxo.Recipient {
+		return utxo.Recipient{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(value)}
+	}
+	builder := bitcoin.NewTxBuilder(&chaincfg.RegressionNetParams)
+
+	Context("when building transactions", func() {
+		It("should expose the implicit fee", func() {
+			tx, err := builder.BuildTx([]utxo.Input{input(0, 100000), input(1, 50000)}, []utxo.Recipient{recipient(60000), recipient(80000)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.(*bitcoin.Tx).Fee()).To(Equal(pack.NewU256FromUint64(10000)))
+		})
+
+		It("should reject outputs that are more than the inputs", func() {
+			_, err := builder.BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(100001)})
+			Expect(errors.As(err, &bitcoin.ErrInsufficientInputs{})).To(BeTrue())
+		})
+
+		It("should reject duplicate inputs", func() {
+			_, err := builder.BuildTx([]utxo.Input{input(0, 100000), input(0, 100000)}, []utxo.Recipient{recipient(100000)})
+			dupErr := bitcoin.ErrDuplicateInput{}
+			Expect(errors.As(err, &dupErr)).To(BeTrue())
+			Expect(dupErr.Index).To(Equal(1))
+		})
+
+		It("should reject dust outputs", func() {
+			_, err := builder.BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(90000), recipient(545)})
+			dustErr := bitcoin.ErrDustOutput{}
+			Expect(errors.As(err, &dustErr)).To(BeTrue())
+			Expect(dustErr.Index).To(Equal(1))
+			Expect(dustErr.DustThreshold).To(Equal(pack.NewU256FromUint64(bitcoin.DefaultDustThreshold)))
+		})
+
+		It("should compute the dust threshold of each kind of output", func() {
+			p2wpkh := append([]byte{txscript.OP_0, txscript.OP_DATA_20}, make([]byte, 20)...)
+			p2wsh := append([]byte{txscript.OP_0, txscript.OP_DATA_32}, make([]byte, 32)...)
+			p2tr := append([]byte{txscript.OP_1, txscript.OP_DATA_32}, make([]byte, 32)...)
+			for _, test := range []struct {
+				script        []byte
+				dustThreshold uint64
+			}{
+				{pubKeyScript, 546},
+				{p2wpkh, 294},
+				{p2wsh, 330},
+				{p2tr, 330},
+			} {
+				rate := pack.NewU256FromUint64(bitcoin.DefaultDustRelayFeeRate)
+				Expect(bitcoin.DustThreshold(test.script, rate)).To(Equal(pack.NewU256FromUint64(test.dustThreshold)))
+
+				dust := utxo.Recipient{Script: pack.NewBytes(test.script), Value: pack.NewU256FromUint64(test.dustThreshold - 1)}
+				_, err := builder.BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(90000), dust})
+				dustErr := bitcoin.ErrDustOutput{}
+				Expect(errors.As(err, &dustErr)).To(BeTrue())
+				Expect(dustErr.DustThreshold).To(Equal(pack.NewU256FromUint64(test.dustThreshold)))
+
+				notDust := utxo.Recipient{Script: pack.NewBytes(test.script), Value: pack.NewU256FromUint64(test.dustThreshold)}
+				_, err = builder.BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(90000), notDust})
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			// A fixed dust threshold applies to all outputs.
+			policy := bitcoin.DefaultFeePolicy().WithDustThreshold(pack.NewU256FromUint64(1000))
+			Expect(policy.OutputDustThreshold(p2wpkh)).To(Equal(pack.NewU256FromUint64(1000)))
+			policy = bitcoin.FeePolicy{}.WithDustRelayFeeRate(pack.NewU256FromUint64(1000))
+			Expect(policy.OutputDustThreshold(p2wpkh)).To(Equal(pack.NewU256FromUint64(98)))
+		})
+
+		It("should reject fees above the absolute cap", func() {
+			_, err := builder.WithFeePolicy(bitcoin.DefaultFeePolicy().WithMaxFee(pack.NewU256FromUint64(999))).
+				BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(99000)})
+			Expect(errors.As(err, &bitcoin.ErrFeeTooHigh{})).To(BeTrue())
+
+			_, err = builder.WithFeePolicy(bitcoin.DefaultFeePolicy().WithMaxFee(pack.NewU256FromUint64(1000))).
+				BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(99000)})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should reject fees above the per-byte cap", func() {
+			// A missing change output sends everything to the miners.
+			_, err := builder.WithFeePolicy(bitcoin.DefaultFeePolicy().WithMaxFee(pack.NewU256FromUint64(0))).
+				BuildTx([]utxo.Input{input(0, 100000000)}, []utxo.Recipient{recipient(1000)})
+			Expect(errors.As(err, &bitcoin.ErrFeeRateTooHigh{})).To(BeTrue())
+
+			_, err = builder.WithFeePolicy(bitcoin.DefaultFeePolicy().WithMaxFeeRate(pack.NewU256FromUint64(10))).
+				BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(90000)})
+			Expect(errors.As(err, &bitcoin.ErrFeeRateTooHigh{})).To(BeTrue())
+		})
+
+		It("should allow checks to be disabled", func() {
+			_, err := builder.WithFeePolicy(bitcoin.FeePolicy{}).
+				BuildTx([]utxo.Input{input(0, 100000000)}, []utxo.Recipient{recipient(1)})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when estimating the size of transactions", func() {
+		It("should not underestimate the signed size", func() {
+			tx, err := builder.BuildTx([]utxo.Input{input(0, 100000), input(1, 100000)}, []utxo.Recipient{recipient(190000)})
+			Expect(err).ToNot(HaveOccurred())
+			estimate := tx.(*bitcoin.Tx).EstimateSize()
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			signatures := make([]pack.Bytes65, len(sighashes))
+			for i := range sighashes {
+				hash := id.Hash(sighashes[i])
+				signature, err := pk.Sign(&hash)
+				Expect(err).ToNot(HaveOccurred())
+				signatures[i] = pack.NewBytes65(signature)
+			}
+			Expect(tx.Sign(signatures, pack.NewBytes((*btcec.PrivateKey)(pk).PubKey().SerializeCompressed()))).To(Succeed())
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(estimate).To(BeNumerically(">=", len(serial)))
+			Expect(estimate).To(BeNumerically("<=", len(serial)+4))
+			Expect(tx.(*bitcoin.Tx).EstimateSize()).To(Equal(len(serial)))
+			Expect(bitcoin.EstimateP2PKHSize(2, 1)).To(Equal(estimate))
+		})
+	})
+})
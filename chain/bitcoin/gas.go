@@ -22,10 +22,12 @@ type GasEstimator struct {
 	client      Client
 	numBlocks   int64
 	fallbackGas pack.U256
+	legacy      bool
 }
 
-// NewGasEstimator returns a simple gas estimator that always returns the given
-// number of SATs-per-byte.
+// NewGasEstimator returns a gas estimator that uses the `estimatesmartfee` RPC
+// call to estimate the SATs-per-byte needed to confirm transactions within
+// `numBlocks` blocks. The fallback gas is returned if estimation fails.
 func NewGasEstimator(client Client, numBlocks int64, fallbackGas pack.U256) GasEstimator {
 	return GasEstimator{
 		client:      client,
@@ -34,6 +36,19 @@ func NewGasEstimator(client Client, numBlocks int64, fallbackGas pack.U256) GasE
 	}
 }
 
+// NewLegacyGasEstimator returns a gas estimator like NewGasEstimator, but that
+// uses the `estimatefee` RPC call instead, for nodes that do not support
+// `estimatesmartfee`. If `numBlocks` is zero, then `estimatefee` is called
+// without arguments, which is required by Bitcoin Cash Node.
+func NewLegacyGasEstimator(client Client, numBlocks int64, fallbackGas pack.U256) GasEstimator {
+	return GasEstimator{
+		client:      client,
+		numBlocks:   numBlocks,
+		fallbackGas: fallbackGas,
+		legacy:      true,
+	}
+}
+
 // EstimateGas returns the number of SATs-per-byte (for both price and cap) that
 // is needed in order to confirm transactions with an estimated maximum delay of
 // `numBlocks` block. It is the responsibility of the caller to know the number
@@ -41,9 +56,14 @@ func NewGasEstimator(client Client, numBlocks int64, fallbackGas pack.U256) GasE
 // call to the node, which based on a conservative (considering longer history)
 // strategy returns the estimated BTC per kilobyte of data in the transaction.
 // An error will be returned if the bitcoin node hasn't observed enough blocks
-// to make an estimate for the provided target `numBlocks`.
+// to make an estimate for the provided target `numBlocks`. Legacy estimators
+// call `estimatefee` instead.
 func (gasEstimator GasEstimator) EstimateGas(ctx context.Context) (pack.U256, pack.U256, error) {
-	feeRate, err := gasEstimator.client.EstimateSmartFee(ctx, gasEstimator.numBlocks)
+	estimateFee := gasEstimator.client.EstimateSmartFee
+	if gasEstimator.legacy {
+		estimateFee = gasEstimator.client.EstimateFeeLegacy
+	}
+	feeRate, err := estimateFee(ctx, gasEstimator.numBlocks)
 	if err != nil {
 		return gasEstimator.fallbackGas, gasEstimator.fallbackGas, err
 	}
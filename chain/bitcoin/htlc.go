@@ -0,0 +1,233 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/pack"
+)
+
+// SecretSize is the size of the secret that must be revealed to claim the
+// funds locked in an HTLC. The HTLC script enforces this size, so that the
+// same secret can be used on chains with different script size limits.
+const SecretSize = 32
+
+// A SecretHashType defines the hash function used to lock an HTLC.
+type SecretHashType uint8
+
+const (
+	// SecretHashSHA256 locks an HTLC using SHA256(secret).
+	SecretHashSHA256 = SecretHashType(0)
+	// SecretHashHASH160 locks an HTLC using RIPEMD160(SHA256(secret)).
+	SecretHashHASH160 = SecretHashType(1)
+)
+
+// Size returns the size of the hash produced by the hash function.
+func (hashType SecretHashType) Size() int {
+	if hashType == SecretHashHASH160 {
+		return 20
+	}
+	return 32
+}
+
+// Hash returns the hash of the secret.
+func (hashType SecretHashType) Hash(secret []byte) []byte {
+	if hashType == SecretHashHASH160 {
+		return btcutil.Hash160(secret)
+	}
+	hash := sha256.Sum256(secret)
+	return hash[:]
+}
+
+// An HTLC is a hash-time-locked contract. The funds locked in an HTLC can be
+// claimed by the recipient by revealing the secret, or refunded to the sender
+// once the lock time has passed. The redeem script of the HTLC is:
+//
+//	OP_IF
+//	  OP_SIZE 32 OP_EQUALVERIFY
+//	  OP_SHA256 <secret hash> OP_EQUALVERIFY
+//	  OP_DUP OP_HASH160 <recipient pubkey hash>
+//	OP_ELSE
+//	  <lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP
+//	  OP_DUP OP_HASH160 <refund pubkey hash>
+//	OP_ENDIF
+//	OP_EQUALVERIFY OP_CHECKSIG
+//
+// where OP_SHA256 is replaced by OP_HASH160 when the secret hash type is
+// SecretHashHASH160.
+type HTLC struct {
+	SecretHashType      SecretHashType
+	SecretHash          []byte
+	RecipientPubKeyHash []byte
+	RefundPubKeyHash    []byte
+	LockTime            uint32
+}
+
+// Script returns the redeem script of the HTLC.
+func (htlc HTLC) Script() ([]byte, error) {
+	hashOp := byte(txscript.OP_SHA256)
+	switch htlc.SecretHashType {
+	case SecretHashSHA256:
+	case SecretHashHASH160:
+		hashOp = txscript.OP_HASH160
+	default:
+		return nil, fmt.Errorf("bad secret hash type: %v", htlc.SecretHashType)
+	}
+	if len(htlc.SecretHash) != htlc.SecretHashType.Size() {
+		return nil, fmt.Errorf("bad secret hash: expected %v bytes, got %v bytes", htlc.SecretHashType.Size(), len(htlc.SecretHash))
+	}
+	if len(htlc.RecipientPubKeyHash) != 20 {
+		return nil, fmt.Errorf("bad recipient pubkey hash: expected 20 bytes, got %v bytes", len(htlc.RecipientPubKeyHash))
+	}
+	if len(htlc.RefundPubKeyHash) != 20 {
+		return nil, fmt.Errorf("bad refund pubkey hash: expected 20 bytes, got %v bytes", len(htlc.RefundPubKeyHash))
+	}
+	if htlc.LockTime == 0 {
+		return nil, fmt.Errorf("bad lock time: expected greater than zero")
+	}
+
+	builder := txscript.NewScriptBuilder()
+	builder.AddOp(txscript.OP_IF)
+	builder.AddOp(txscript.OP_SIZE)
+	builder.AddInt64(SecretSize)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(hashOp)
+	builder.AddData(htlc.SecretHash)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(txscript.OP_DUP)
+	builder.AddOp(txscript.OP_HASH160)
+	builder.AddData(htlc.RecipientPubKeyHash)
+	builder.AddOp(txscript.OP_ELSE)
+	builder.AddInt64(int64(htlc.LockTime))
+	builder.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddOp(txscript.OP_DUP)
+	builder.AddOp(txscript.OP_HASH160)
+	builder.AddData(htlc.RefundPubKeyHash)
+	builder.AddOp(txscript.OP_ENDIF)
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(txscript.OP_CHECKSIG)
+	return builder.Script()
+}
+
+// Address returns the P2SH address of the HTLC.
+func (htlc HTLC) Address(params *chaincfg.Params) (*btcutil.AddressScriptHash, error) {
+	script, err := htlc.Script()
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.NewAddressScriptHash(script, params)
+}
+
+// ParseHTLC parses a redeem script into an HTLC. An error is returned if the
+// script is not exactly the redeem script of an HTLC. This can be used to
+// check the HTLC published by the counterparty of an atomic swap.
+func ParseHTLC(script []byte) (HTLC, error) {
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		return HTLC{}, fmt.Errorf("bad script: %v", err)
+	}
+
+	// The pushes are the secret size, the secret hash, the recipient pubkey
+	// hash, the lock time (unless it is a small integer), and the refund
+	// pubkey hash.
+	if len(pushes) != 4 && len(pushes) != 5 {
+		return HTLC{}, fmt.Errorf("bad script: not an htlc")
+	}
+	htlc := HTLC{
+		SecretHashType:      SecretHashSHA256,
+		SecretHash:          pushes[1],
+		RecipientPubKeyHash: pushes[2],
+		RefundPubKeyHash:    pushes[len(pushes)-1],
+	}
+	if len(htlc.SecretHash) == SecretHashHASH160.Size() {
+		htlc.SecretHashType = SecretHashHASH160
+	}
+	if len(pushes) == 5 {
+		lockTime, err := decodeLockTime(pushes[3])
+		if err != nil {
+			return HTLC{}, fmt.Errorf("bad script: %v", err)
+		}
+		htlc.LockTime = lockTime
+	} else {
+		// The lock time is a small integer, encoded by the opcode that
+		// immediately follows OP_ELSE.
+		i := 32 + len(htlc.SecretHash)
+		if i >= len(script) || script[i] < txscript.OP_1 || script[i] > txscript.OP_16 {
+			return HTLC{}, fmt.Errorf("bad script: not an htlc")
+		}
+		htlc.LockTime = uint32(script[i] - (txscript.OP_1 - 1))
+	}
+
+	expected, err := htlc.Script()
+	if err != nil || !bytes.Equal(expected, script) {
+		return HTLC{}, fmt.Errorf("bad script: not an htlc")
+	}
+	return htlc, nil
+}
+
+// HTLCClaimScript returns the signature script that claims the funds locked in
+// an HTLC, by revealing the secret. The signature must be over the sighash of
+// the input (computed using the redeem script as the sig script of the input),
+// and the pubkey must hash to the recipient pubkey hash of the HTLC.
+func HTLCClaimScript(redeemScript []byte, signature pack.Bytes65, pubKey pack.Bytes, secret []byte) ([]byte, error) {
+	if len(secret) != SecretSize {
+		return nil, fmt.Errorf("bad secret: expected %v bytes, got %v bytes", SecretSize, len(secret))
+	}
+	builder := txscript.NewScriptBuilder()
+	builder.AddData(serializeSignature(signature))
+	builder.AddData(pubKey)
+	builder.AddData(secret)
+	builder.AddOp(txscript.OP_TRUE)
+	builder.AddData(redeemScript)
+	return builder.Script()
+}
+
+// HTLCRefundScript returns the signature script that refunds the funds locked
+// in an HTLC. The transaction must have a lock time that is no less than the
+// lock time of the HTLC, and the input must have a non-final sequence number,
+// otherwise the refund is invalid. The signature must be over the sighash of
+// the input (computed using the redeem script as the sig script of the input),
+// and the pubkey must hash to the refund pubkey hash of the HTLC.
+func HTLCRefundScript(redeemScript []byte, signature pack.Bytes65, pubKey pack.Bytes) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	builder.AddData(serializeSignature(signature))
+	builder.AddData(pubKey)
+	builder.AddOp(txscript.OP_FALSE)
+	builder.AddData(redeemScript)
+	return builder.Script()
+}
+
+// serializeSignature encodes a signature in the [R || S || V] format as a DER
+// signature, with the SIGHASH_ALL type appended.
+func serializeSignature(rsv pack.Bytes65) []byte {
+	signature := btcec.Signature{
+		R: new(big.Int).SetBytes(rsv[:32]),
+		S: new(big.Int).SetBytes(rsv[32:64]),
+	}
+	return append(signature.Serialize(), byte(txscript.SigHashAll))
+}
+
+// decodeLockTime decodes a lock time that was pushed as a script number.
+func decodeLockTime(data []byte) (uint32, error) {
+	if len(data) == 0 || len(data) > 5 {
+		return 0, fmt.Errorf("bad lock time: expected 1 to 5 bytes, got %v bytes", len(data))
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		return 0, fmt.Errorf("bad lock time: negative")
+	}
+	lockTime := uint64(0)
+	for i, b := range data {
+		lockTime |= uint64(b) << (8 * uint(i))
+	}
+	if lockTime > 0xffffffff {
+		return 0, fmt.Errorf("bad lock time: %v overflows", lockTime)
+	}
+	return uint32(lockTime), nil
+}
@@ -0,0 +1,227 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTLC", func() {
+	params := &chaincfg.RegressionNetParams
+	lockTime := uint32(700000)
+
+	newKey := func() signer.KeySigner {
+		return signer.NewKeySigner((*btcec.PrivateKey)(id.NewPrivKey()), true)
+	}
+	secret := bytes.Repeat([]byte{0x42}, bitcoin.SecretSize)
+
+	newHTLC := func(hashType bitcoin.SecretHashType, recipient, refund signer.KeySigner) bitcoin.HTLC {
+		return bitcoin.HTLC{
+			SecretHashType:      hashType,
+			SecretHash:          hashType.Hash(secret),
+			RecipientPubKeyHash: btcutil.Hash160(recipient.PubKey()),
+			RefundPubKeyHash:    btcutil.Hash160(refund.PubKey()),
+			LockTime:            lockTime,
+		}
+	}
+
+	// spend builds a transaction that spends the HTLC, and returns it along
+	// with the sighash of its input.
+	spend := func(builder bitcoin.TxBuilder, htlc bitcoin.HTLC) (*bitcoin.Tx, []byte, pack.Bytes32) {
+		redeemScript, err := htlc.Script()
+		Expect(err).ToNot(HaveOccurred())
+		addr, err := htlc.Address(params)
+		Expect(err).ToNot(HaveOccurred())
+		pubKeyScript, err := txscript.PayToAddrScript(addr)
+		Expect(err).ToNot(HaveOccurred())
+
+		input := utxo.Input{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)},
+				Value:        pack.NewU256FromUint64(100000),
+				PubKeyScript: pubKeyScript,
+			},
+			SigScript: redeemScript,
+		}
+		to, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+		Expect(err).ToNot(HaveOccurred())
+		tx, err := builder.BuildTx([]utxo.Input{input}, []utxo.Recipient{{To: address.Address(to.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}})
+		Expect(err).ToNot(HaveOccurred())
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*bitcoin.Tx), pubKeyScript, sighashes[0]
+	}
+
+	sign := func(key signer.KeySigner, sighash pack.Bytes32) pack.Bytes65 {
+		signatures, _, err := key.Sign(context.Background(), []pack.Bytes32{sighash})
+		Expect(err).ToNot(HaveOccurred())
+		return signatures[0]
+	}
+
+	// execute runs the btcd script engine against the first input of the
+	// transaction.
+	execute := func(tx *bitcoin.Tx, pubKeyScript []byte) error {
+		serialized, err := tx.Serialize()
+		Expect(err).ToNot(HaveOccurred())
+		msgTx := wire.NewMsgTx(bitcoin.Version)
+		Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+		engine, err := txscript.NewEngine(pubKeyScript, msgTx, 0, txscript.StandardVerifyFlags, nil, nil, 100000)
+		Expect(err).ToNot(HaveOccurred())
+		return engine.Execute()
+	}
+
+	for _, hashType := range []bitcoin.SecretHashType{bitcoin.SecretHashSHA256, bitcoin.SecretHashHASH160} {
+		hashType := hashType
+
+		Context("when claiming", func() {
+			It("should accept the secret and the recipient signature", func() {
+				recipient, refund := newKey(), newKey()
+				htlc := newHTLC(hashType, recipient, refund)
+				redeemScript, err := htlc.Script()
+				Expect(err).ToNot(HaveOccurred())
+
+				tx, pubKeyScript, sighash := spend(bitcoin.NewTxBuilder(params), htlc)
+				sigScript, err := bitcoin.HTLCClaimScript(redeemScript, sign(recipient, sighash), recipient.PubKey(), secret)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+				Expect(execute(tx, pubKeyScript)).To(Succeed())
+
+				// The size estimate should cover the actual claim.
+				serialized, err := tx.Serialize()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.EstimateSize()).To(BeNumerically(">=", len(serialized)))
+			})
+
+			It("should reject the wrong secret", func() {
+				recipient, refund := newKey(), newKey()
+				htlc := newHTLC(hashType, recipient, refund)
+				redeemScript, err := htlc.Script()
+				Expect(err).ToNot(HaveOccurred())
+
+				tx, pubKeyScript, sighash := spend(bitcoin.NewTxBuilder(params), htlc)
+				sigScript, err := bitcoin.HTLCClaimScript(redeemScript, sign(recipient, sighash), recipient.PubKey(), bytes.Repeat([]byte{0x43}, bitcoin.SecretSize))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+				Expect(execute(tx, pubKeyScript)).ToNot(Succeed())
+			})
+
+			It("should reject the refund signature", func() {
+				recipient, refund := newKey(), newKey()
+				htlc := newHTLC(hashType, recipient, refund)
+				redeemScript, err := htlc.Script()
+				Expect(err).ToNot(HaveOccurred())
+
+				tx, pubKeyScript, sighash := spend(bitcoin.NewTxBuilder(params), htlc)
+				sigScript, err := bitcoin.HTLCClaimScript(redeemScript, sign(refund, sighash), refund.PubKey(), secret)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+				Expect(execute(tx, pubKeyScript)).ToNot(Succeed())
+			})
+		})
+
+		Context("when refunding", func() {
+			It("should accept the refund signature after the lock time", func() {
+				recipient, refund := newKey(), newKey()
+				htlc := newHTLC(hashType, recipient, refund)
+				redeemScript, err := htlc.Script()
+				Expect(err).ToNot(HaveOccurred())
+
+				builder := bitcoin.NewTxBuilder(params).WithLockTime(lockTime).WithSequence(wire.MaxTxInSequenceNum - 1)
+				tx, pubKeyScript, sighash := spend(builder, htlc)
+				sigScript, err := bitcoin.HTLCRefundScript(redeemScript, sign(refund, sighash), refund.PubKey())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+				Expect(execute(tx, pubKeyScript)).To(Succeed())
+			})
+
+			It("should reject a refund before the lock time", func() {
+				recipient, refund := newKey(), newKey()
+				htlc := newHTLC(hashType, recipient, refund)
+				redeemScript, err := htlc.Script()
+				Expect(err).ToNot(HaveOccurred())
+
+				builder := bitcoin.NewTxBuilder(params).WithLockTime(lockTime - 1).WithSequence(wire.MaxTxInSequenceNum - 1)
+				tx, pubKeyScript, sighash := spend(builder, htlc)
+				sigScript, err := bitcoin.HTLCRefundScript(redeemScript, sign(refund, sighash), refund.PubKey())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+				Expect(execute(tx, pubKeyScript)).ToNot(Succeed())
+			})
+
+			It("should reject a refund with a final sequence number", func() {
+				recipient, refund := newKey(), newKey()
+				htlc := newHTLC(hashType, recipient, refund)
+				redeemScript, err := htlc.Script()
+				Expect(err).ToNot(HaveOccurred())
+
+				tx, pubKeyScript, sighash := spend(bitcoin.NewTxBuilder(params).WithLockTime(lockTime), htlc)
+				sigScript, err := bitcoin.HTLCRefundScript(redeemScript, sign(refund, sighash), refund.PubKey())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+				Expect(execute(tx, pubKeyScript)).ToNot(Succeed())
+			})
+		})
+	}
+
+	Context("when parsing", func() {
+		It("should round-trip", func() {
+			for _, hashType := range []bitcoin.SecretHashType{bitcoin.SecretHashSHA256, bitcoin.SecretHashHASH160} {
+				for _, lockTime := range []uint32{1, 16, 17, 500000, 1600000000, 0xffffffff} {
+					htlc := newHTLC(hashType, newKey(), newKey())
+					htlc.LockTime = lockTime
+					script, err := htlc.Script()
+					Expect(err).ToNot(HaveOccurred())
+					parsed, err := bitcoin.ParseHTLC(script)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(parsed).To(Equal(htlc))
+				}
+			}
+		})
+
+		It("should reject other scripts", func() {
+			htlc := newHTLC(bitcoin.SecretHashSHA256, newKey(), newKey())
+			script, err := htlc.Script()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = bitcoin.ParseHTLC(script[:len(script)-1])
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.ParseHTLC(append(script, txscript.OP_NOP))
+			Expect(err).To(HaveOccurred())
+			pkhAddr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+			Expect(err).ToNot(HaveOccurred())
+			pkhScript, err := txscript.PayToAddrScript(pkhAddr)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = bitcoin.ParseHTLC(pkhScript)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject invalid parameters", func() {
+			htlc := newHTLC(bitcoin.SecretHashSHA256, newKey(), newKey())
+			htlc.SecretHash = htlc.SecretHash[:20]
+			_, err := htlc.Script()
+			Expect(err).To(HaveOccurred())
+
+			htlc = newHTLC(bitcoin.SecretHashSHA256, newKey(), newKey())
+			htlc.LockTime = 0
+			_, err = htlc.Script()
+			Expect(err).To(HaveOccurred())
+
+			_, err = bitcoin.HTLCClaimScript([]byte{}, pack.Bytes65{}, pack.Bytes{}, secret[:31])
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,85 @@
+package bitcoin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// LockTimeThreshold is the value below which a lock time is interpreted
+	// as a block height. At, or above, this value it is interpreted as a UNIX
+	// timestamp.
+	LockTimeThreshold = txscript.LockTimeThreshold
+
+	// SequenceFinal is the default sequence number of an input. If all inputs
+	// of a transaction have this sequence number, then the lock time of the
+	// transaction is not enforced.
+	SequenceFinal = wire.MaxTxInSequenceNum
+	// SequenceNoRBF is the largest sequence number that enables the lock time
+	// of a transaction, without signalling replaceability.
+	SequenceNoRBF = wire.MaxTxInSequenceNum - 1
+	// SequenceRBF is the largest sequence number that signals that a
+	// transaction can be replaced by a transaction paying a higher fee (as
+	// defined by BIP125). It also enables the lock time of the transaction.
+	SequenceRBF = wire.MaxTxInSequenceNum - 2
+
+	// sequenceLockTimeGranularity is the number of seconds in one unit of a
+	// time-based relative lock time (as defined by BIP68).
+	sequenceLockTimeGranularity = 1 << wire.SequenceLockTimeGranularity
+)
+
+// LockTimeFromHeight returns a lock time that prevents a transaction from
+// being mined until the chain has reached the given block height.
+func LockTimeFromHeight(height uint32) (uint32, error) {
+	if height >= LockTimeThreshold {
+		return 0, fmt.Errorf("bad lock time: expected height < %v, got height %v", LockTimeThreshold, height)
+	}
+	return height, nil
+}
+
+// LockTimeFromTime returns a lock time that prevents a transaction from being
+// mined until the median time of the chain has passed the given time.
+func LockTimeFromTime(t time.Time) (uint32, error) {
+	unix := t.Unix()
+	if unix < LockTimeThreshold || unix > int64(wire.MaxTxInSequenceNum) {
+		return 0, fmt.Errorf("bad lock time: expected timestamp between %v and %v, got timestamp %v", int64(LockTimeThreshold), wire.MaxTxInSequenceNum, unix)
+	}
+	return uint32(unix), nil
+}
+
+// IsLockTimeHeight returns true if the lock time is interpreted as a block
+// height, and false if it is interpreted as a UNIX timestamp.
+func IsLockTimeHeight(lockTime uint32) bool {
+	return lockTime < LockTimeThreshold
+}
+
+// RelativeLockHeight returns a sequence number that prevents an input from
+// being mined until the output that it spends has the given number of
+// confirmations (as defined by BIP68). It also signals replaceability.
+func RelativeLockHeight(blocks uint16) uint32 {
+	return uint32(blocks)
+}
+
+// RelativeLockTime returns a sequence number that prevents an input from being
+// mined until the given duration has passed since the output that it spends
+// was mined (as defined by BIP68). The duration is rounded up to a multiple of
+// 512 seconds. It also signals replaceability.
+func RelativeLockTime(d time.Duration) (uint32, error) {
+	if d < 0 {
+		return 0, fmt.Errorf("bad relative lock time: expected duration >= 0, got duration %v", d)
+	}
+	seconds := (int64(d/time.Second) + sequenceLockTimeGranularity - 1) / sequenceLockTimeGranularity
+	if seconds > wire.SequenceLockTimeMask {
+		return 0, fmt.Errorf("bad relative lock time: expected duration <= %v, got duration %v", time.Duration(wire.SequenceLockTimeMask*sequenceLockTimeGranularity)*time.Second, d)
+	}
+	return wire.SequenceLockTimeIsSeconds | uint32(seconds), nil
+}
+
+// SignalsRBF returns true if the sequence number signals that the transaction
+// spending the input can be replaced (as defined by BIP125).
+func SignalsRBF(sequence uint32) bool {
+	return sequence <= SequenceRBF
+}
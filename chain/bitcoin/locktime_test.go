@@ -0,0 +1,167 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock time", func() {
+	params := &chaincfg.RegressionNetParams
+
+	Context("when encoding lock times", func() {
+		It("should distinguish heights from timestamps", func() {
+			lockTime, err := bitcoin.LockTimeFromHeight(700000)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lockTime).To(Equal(uint32(700000)))
+			Expect(bitcoin.IsLockTimeHeight(lockTime)).To(BeTrue())
+
+			lockTime, err = bitcoin.LockTimeFromTime(time.Unix(1600000000, 0))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lockTime).To(Equal(uint32(1600000000)))
+			Expect(bitcoin.IsLockTimeHeight(lockTime)).To(BeFalse())
+
+			_, err = bitcoin.LockTimeFromHeight(bitcoin.LockTimeThreshold)
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.LockTimeFromTime(time.Unix(bitcoin.LockTimeThreshold-1, 0))
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.LockTimeFromTime(time.Unix(1<<32, 0))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when encoding sequence numbers", func() {
+		It("should encode relative lock times", func() {
+			Expect(bitcoin.RelativeLockHeight(144)).To(Equal(uint32(144)))
+
+			sequence, err := bitcoin.RelativeLockTime(1024 * time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sequence).To(Equal(uint32(wire.SequenceLockTimeIsSeconds | 2)))
+
+			// Durations are rounded up.
+			sequence, err = bitcoin.RelativeLockTime(1025 * time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sequence).To(Equal(uint32(wire.SequenceLockTimeIsSeconds | 3)))
+
+			_, err = bitcoin.RelativeLockTime(-time.Second)
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.RelativeLockTime(512 * 0x10000 * time.Second)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should signal replaceability", func() {
+			Expect(bitcoin.SignalsRBF(bitcoin.SequenceFinal)).To(BeFalse())
+			Expect(bitcoin.SignalsRBF(bitcoin.SequenceNoRBF)).To(BeFalse())
+			Expect(bitcoin.SignalsRBF(bitcoin.SequenceRBF)).To(BeTrue())
+			Expect(bitcoin.SignalsRBF(bitcoin.RelativeLockHeight(1))).To(BeTrue())
+		})
+	})
+
+	Context("when building transactions", func() {
+		// A script that can only be spent after a relative lock of 10 blocks.
+		redeemScript, err := txscript.NewScriptBuilder().
+			AddInt64(10).
+			AddOp(txscript.OP_CHECKSEQUENCEVERIFY).
+			AddOp(txscript.OP_DROP).
+			AddOp(txscript.OP_TRUE).
+			Script()
+		if err != nil {
+			panic(err)
+		}
+		p2shAddr, err := btcutil.NewAddressScriptHash(redeemScript, params)
+		if err != nil {
+			panic(err)
+		}
+		pubKeyScript, err := txscript.PayToAddrScript(p2shAddr)
+		if err != nil {
+			panic(err)
+		}
+		inputs := func(n int) []utxo.Input {
+			inputs := make([]utxo.Input, n)
+			for i := range inputs {
+				inputs[i] = utxo.Input{
+					Output: utxo.Output{
+						Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(uint32(i))},
+						Value:        pack.NewU256FromUint64(100000),
+						PubKeyScript: pubKeyScript,
+					},
+					SigScript: redeemScript,
+				}
+			}
+			return inputs
+		}
+		recipients := []utxo.Recipient{{To: address.Address(p2shAddr.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}}
+
+		build := func(builder bitcoin.TxBuilder, n int) *wire.MsgTx {
+			tx, err := builder.BuildTx(inputs(n), recipients)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < n; i++ {
+				sigScript, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.(*bitcoin.Tx).SetSignatureScript(i, sigScript)).To(Succeed())
+			}
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(bitcoin.Version)
+			Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+			return msgTx
+		}
+		execute := func(msgTx *wire.MsgTx, i int) error {
+			engine, err := txscript.NewEngine(pubKeyScript, msgTx, i, txscript.StandardVerifyFlags, nil, nil, 100000)
+			Expect(err).ToNot(HaveOccurred())
+			return engine.Execute()
+		}
+
+		It("should set the lock time and the sequence of each input", func() {
+			lockTime, err := bitcoin.LockTimeFromHeight(700000)
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := build(bitcoin.NewTxBuilder(params).WithLockTime(lockTime).WithInputSequences([]uint32{bitcoin.SequenceRBF, bitcoin.RelativeLockHeight(10)}), 2)
+			Expect(msgTx.LockTime).To(Equal(lockTime))
+			Expect(msgTx.TxIn[0].Sequence).To(Equal(bitcoin.SequenceRBF))
+			Expect(msgTx.TxIn[1].Sequence).To(Equal(uint32(10)))
+
+			msgTx = build(bitcoin.NewTxBuilder(params).WithSequence(bitcoin.SequenceNoRBF), 2)
+			Expect(msgTx.TxIn[0].Sequence).To(Equal(bitcoin.SequenceNoRBF))
+			Expect(msgTx.TxIn[1].Sequence).To(Equal(bitcoin.SequenceNoRBF))
+
+			msgTx = build(bitcoin.NewTxBuilder(params), 1)
+			Expect(msgTx.LockTime).To(Equal(uint32(0)))
+			Expect(msgTx.TxIn[0].Sequence).To(Equal(bitcoin.SequenceFinal))
+		})
+
+		It("should enforce relative lock times", func() {
+			msgTx := build(bitcoin.NewTxBuilder(params).WithInputSequences([]uint32{bitcoin.RelativeLockHeight(10), bitcoin.RelativeLockHeight(9)}), 2)
+			Expect(execute(msgTx, 0)).To(Succeed())
+			Expect(execute(msgTx, 1)).ToNot(Succeed())
+		})
+
+		It("should reject the wrong number of sequences", func() {
+			_, err := bitcoin.NewTxBuilder(params).WithInputSequences([]uint32{0}).BuildTx(inputs(2), recipients)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should commit to the lock time and sequences in the sighash", func() {
+			sighashes := func(builder bitcoin.TxBuilder) []pack.Bytes32 {
+				tx, err := builder.BuildTx(inputs(2), recipients)
+				Expect(err).ToNot(HaveOccurred())
+				sighashes, err := tx.Sighashes()
+				Expect(err).ToNot(HaveOccurred())
+				return sighashes
+			}
+			base := sighashes(bitcoin.NewTxBuilder(params))
+			Expect(sighashes(bitcoin.NewTxBuilder(params).WithLockTime(1))).ToNot(ContainElement(base[0]))
+			Expect(sighashes(bitcoin.NewTxBuilder(params).WithInputSequences([]uint32{bitcoin.SequenceFinal, bitcoin.SequenceRBF}))[1]).ToNot(Equal(base[1]))
+		})
+	})
+})
@@ -0,0 +1,159 @@
+package bitcoin
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// MaxDataCarrierSize is the maximum number of bytes that can be committed
+	// to by a null-data output, under the default relay policy of Bitcoin
+	// Core.
+	MaxDataCarrierSize = txscript.MaxDataCarrierSize
+	// MaxNullDataOutputs is the maximum number of null-data outputs in a
+	// transaction, under the default relay policy of Bitcoin Core.
+	MaxNullDataOutputs = 1
+)
+
+// NullDataScript returns a null-data (OP_RETURN) pubkey script that commits to
+// the data. The data must be no larger than maxDataCarrierSize.
+func NullDataScript(data []byte, maxDataCarrierSize int) ([]byte, error) {
+	if len(data) > maxDataCarrierSize {
+		return nil, fmt.Errorf("bad data: expected <= %v bytes, got %v bytes", maxDataCarrierSize, len(data))
+	}
+	return txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(data).Script()
+}
+
+// ExtractNullData returns the data committed to by a null-data (OP_RETURN)
+// pubkey script. It returns false if the script is not a null-data script with
+// a single data push.
+func ExtractNullData(script []byte) ([]byte, bool) {
+	if len(script) < 2 || script[0] != txscript.OP_RETURN {
+		return nil, false
+	}
+	pushes, err := txscript.PushedData(script[1:])
+	if err != nil || len(pushes) != 1 {
+		return nil, false
+	}
+	// The data must be pushed canonically, so that the script round-trips.
+	expected, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddData(pushes[0]).Script()
+	if err != nil || !bytes.Equal(expected, script) {
+		return nil, false
+	}
+	if pushes[0] == nil {
+		return []byte{}, true
+	}
+	return pushes[0], true
+}
+
+// IsNullData returns true if the pubkey script is a null-data (OP_RETURN)
+// script. Outputs with null-data scripts are provably unspendable.
+func IsNullData(script []byte) bool {
+	return len(script) > 0 && script[0] == txscript.OP_RETURN
+}
+
+// RecipientScript returns the pubkey script of the output that will be produced
+// for the recipient. If the recipient has data (which is non-nil, but can be
+// empty), then a null-data script is returned, and the recipient must have a
+// zero value (to avoid burning funds). If the recipient has a script, then it
+// must be a standard pubkey script, or a bare OP_RETURN with a zero value.
+// Otherwise, the address of the recipient is converted to a pubkey script using
+// payToAddr.
+func RecipientScript(recipient utxo.Recipient, maxDataCarrierSize int, payToAddr func(address.Address) ([]byte, error)) ([]byte, error) {
+	set := 0
+	for _, ok := range []bool{recipient.To != "", len(recipient.Script) > 0, recipient.Data != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("expected exactly one of address, script, or data")
+	}
+
+	switch {
+	case recipient.Data != nil:
+		if !recipient.Value.Equal(pack.NewU256FromUint64(0)) {
+			return nil, fmt.Errorf("bad data: expected value 0, got value %v", recipient.Value)
+		}
+		return NullDataScript(recipient.Data, maxDataCarrierSize)
+	case len(recipient.Script) > 0:
+		// A bare OP_RETURN commits to no data, so it cannot be produced from
+		// data, and is accepted as a script.
+		if bytes.Equal(recipient.Script, []byte{txscript.OP_RETURN}) {
+			if !recipient.Value.Equal(pack.NewU256FromUint64(0)) {
+				return nil, fmt.Errorf("bad script: expected value 0, got value %v", recipient.Value)
+			}
+			return []byte(recipient.Script), nil
+		}
+		if IsNullData(recipient.Script) {
+			return nil, fmt.Errorf("bad script: use data for null-data outputs")
+		}
+		if IsPayToTaproot(recipient.Script) {
+			return []byte(recipient.Script), nil
+		}
+		switch txscript.GetScriptClass(recipient.Script) {
+		case txscript.PubKeyHashTy, txscript.ScriptHashTy, txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy, txscript.MultiSigTy, txscript.PubKeyTy:
+			return []byte(recipient.Script), nil
+		default:
+			return nil, fmt.Errorf("bad script: non-standard")
+		}
+	default:
+		return payToAddr(recipient.To)
+	}
+}
+
+// CheckNullDataOutputs returns an error if there are more null-data outputs
+// than are allowed by the relay policy.
+func CheckNullDataOutputs(outputs []*wire.TxOut) error {
+	n := 0
+	for _, output := range outputs {
+		if IsNullData(output.PkScript) {
+			n++
+		}
+	}
+	if n > MaxNullDataOutputs {
+		return fmt.Errorf("bad recipients: expected <= %v null-data outputs, got %v", MaxNullDataOutputs, n)
+	}
+	return nil
+}
+
+// RecipientFromOutput returns the recipient for which the output was produced.
+// Null-data outputs are returned as recipients with data (except for a bare
+// OP_RETURN, which is returned as a script), outputs that pay to an address
+// are returned as recipients with an address, and all other outputs are
+// returned as recipients with a script. This is the inverse of
+// RecipientScript.
+func RecipientFromOutput(output utxo.Output, params *chaincfg.Params) utxo.Recipient {
+	if data, ok := ExtractNullData(output.PubKeyScript); ok {
+		return utxo.Recipient{Value: output.Value, Data: pack.Bytes(data)}
+	}
+	if IsPayToTaproot(output.PubKeyScript) {
+		if addr, err := NewAddressTaproot(output.PubKeyScript[2:], params); err == nil {
+			return utxo.Recipient{Value: output.Value, To: address.Address(addr.EncodeAddress())}
+		}
+	}
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(output.PubKeyScript, params)
+	if err == nil && len(addrs) == 1 && class != txscript.PubKeyTy && class != txscript.MultiSigTy {
+		return utxo.Recipient{Value: output.Value, To: address.Address(addrs[0].EncodeAddress())}
+	}
+	return utxo.Recipient{Value: output.Value, Script: output.PubKeyScript}
+}
+
+// payToAddrFunc returns a function that converts Bitcoin addresses to pubkey
+// scripts.
+func payToAddrFunc(params *chaincfg.Params) func(address.Address) ([]byte, error) {
+	return func(addr address.Address) ([]byte, error) {
+		decoded, err := decodeAddress(string(addr), params)
+		if err != nil {
+			return nil, err
+		}
+		return PayToAddrScript(decoded)
+	}
+}
@@ -0,0 +1,193 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recipients", func() {
+	params := &chaincfg.RegressionNetParams
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		panic(err)
+	}
+	witnessAddr, err := btcutil.NewAddressWitnessScriptHash(make([]byte, 32), params)
+	if err != nil {
+		panic(err)
+	}
+	witnessScript, err := txscript.PayToAddrScript(witnessAddr)
+	if err != nil {
+		panic(err)
+	}
+	inputs := []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pubKeyScript}},
+	}
+	data := []byte("hello, world")
+
+	build := func(recipients []utxo.Recipient) (utxo.Tx, error) {
+		return bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+	}
+
+	Context("when encoding null-data scripts", func() {
+		It("should round-trip the data", func() {
+			for _, n := range []int{0, 1, 75, 76, bitcoin.MaxDataCarrierSize} {
+				data := bytes.Repeat([]byte{0xab}, n)
+				script, err := bitcoin.NullDataScript(data, bitcoin.MaxDataCarrierSize)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(txscript.GetScriptClass(script)).To(Equal(txscript.NullDataTy))
+				Expect(bitcoin.IsNullData(script)).To(BeTrue())
+				extracted, ok := bitcoin.ExtractNullData(script)
+				Expect(ok).To(BeTrue())
+				Expect(extracted).To(Equal(data))
+			}
+		})
+
+		It("should reject data that is too large", func() {
+			_, err := bitcoin.NullDataScript(make([]byte, bitcoin.MaxDataCarrierSize+1), bitcoin.MaxDataCarrierSize)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should not extract data from other scripts", func() {
+			_, ok := bitcoin.ExtractNullData(pubKeyScript)
+			Expect(ok).To(BeFalse())
+			_, ok = bitcoin.ExtractNullData([]byte{txscript.OP_RETURN})
+			Expect(ok).To(BeFalse())
+			// Non-canonical pushes do not round-trip.
+			_, ok = bitcoin.ExtractNullData([]byte{txscript.OP_RETURN, txscript.OP_PUSHDATA1, 1, 0xab})
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("when building transactions", func() {
+		It("should round-trip recipients through the outputs", func() {
+			recipients := []utxo.Recipient{
+				{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(40000)},
+				{Script: witnessScript, Value: pack.NewU256FromUint64(40000)},
+				{Data: data, Value: pack.NewU256FromUint64(0)},
+			}
+			tx, err := build(recipients)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.(*bitcoin.Tx).Fee()).To(Equal(pack.NewU256FromUint64(20000)))
+
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(3))
+			Expect(txscript.GetScriptClass(outputs[2].PubKeyScript)).To(Equal(txscript.NullDataTy))
+			// Scripts that pay to an address are returned as the address.
+			expected := append([]utxo.Recipient{}, recipients...)
+			expected[1] = utxo.Recipient{To: address.Address(witnessAddr.EncodeAddress()), Value: recipients[1].Value}
+			for i, output := range outputs {
+				Expect(bitcoin.RecipientFromOutput(output, params)).To(Equal(expected[i]))
+			}
+		})
+
+		It("should round-trip null-data outputs without data", func() {
+			for _, script := range [][]byte{
+				{txscript.OP_RETURN},
+				{txscript.OP_RETURN, txscript.OP_0},
+			} {
+				recipient := bitcoin.RecipientFromOutput(utxo.Output{PubKeyScript: script, Value: pack.NewU256FromUint64(0)}, params)
+				tx, err := build([]utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}, recipient})
+				Expect(err).ToNot(HaveOccurred(), "%x", script)
+				outputs, err := tx.Outputs()
+				Expect(err).ToNot(HaveOccurred())
+				Expect([]byte(outputs[1].PubKeyScript)).To(Equal(script))
+				Expect(bitcoin.RecipientFromOutput(outputs[1], params)).To(Equal(recipient))
+			}
+
+			// Empty data is committed to by pushing nothing.
+			tx, err := build([]utxo.Recipient{{Data: []byte{}, Value: pack.NewU256FromUint64(0)}})
+			Expect(err).ToNot(HaveOccurred())
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(outputs[0].PubKeyScript)).To(Equal([]byte{txscript.OP_RETURN, txscript.OP_0}))
+
+			_, err = build([]utxo.Recipient{{Script: []byte{txscript.OP_RETURN}, Value: pack.NewU256FromUint64(1000)}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return non-address scripts as scripts", func() {
+			script, err := txscript.MultiSigScript([]*btcutil.AddressPubKey{}, 0)
+			Expect(err).ToNot(HaveOccurred())
+			recipient := bitcoin.RecipientFromOutput(utxo.Output{PubKeyScript: script}, params)
+			Expect(recipient.To).To(BeEmpty())
+			Expect([]byte(recipient.Script)).To(Equal(script))
+		})
+
+		It("should reject recipients that are not exactly one kind", func() {
+			_, err := build([]utxo.Recipient{{Value: pack.NewU256FromUint64(40000)}})
+			Expect(err).To(HaveOccurred())
+			_, err = build([]utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Script: pubKeyScript, Value: pack.NewU256FromUint64(40000)}})
+			Expect(err).To(HaveOccurred())
+			_, err = build([]utxo.Recipient{{Script: pubKeyScript, Data: data, Value: pack.NewU256FromUint64(0)}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject data with a value", func() {
+			_, err := build([]utxo.Recipient{{Data: data, Value: pack.NewU256FromUint64(1000)}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject data that is too large", func() {
+			_, err := build([]utxo.Recipient{{Data: make([]byte, bitcoin.MaxDataCarrierSize+1), Value: pack.NewU256FromUint64(0)}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject more than one null-data output", func() {
+			_, err := build([]utxo.Recipient{
+				{Data: data, Value: pack.NewU256FromUint64(0)},
+				{Data: data, Value: pack.NewU256FromUint64(0)},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject non-standard scripts", func() {
+			_, err := build([]utxo.Recipient{{Script: []byte{txscript.OP_TRUE}, Value: pack.NewU256FromUint64(40000)}})
+			Expect(err).To(HaveOccurred())
+			nullData, err := bitcoin.NullDataScript(data, bitcoin.MaxDataCarrierSize)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = build([]utxo.Recipient{{Script: nullData, Value: pack.NewU256FromUint64(0)}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should still reject dust outputs that are not null-data", func() {
+			_, err := build([]utxo.Recipient{{Script: pubKeyScript, Value: pack.NewU256FromUint64(1)}})
+			Expect(err).To(BeAssignableToTypeOf(bitcoin.ErrDustOutput{}))
+		})
+	})
+
+	Context("when overriding the recipient script", func() {
+		It("should use the given function for all recipients", func() {
+			recipientScript := func(recipient utxo.Recipient) ([]byte, error) {
+				if len(recipient.Script) > 0 && txscript.IsWitnessProgram(recipient.Script) {
+					return nil, fmt.Errorf("witness programs are not supported")
+				}
+				return pubKeyScript, nil
+			}
+			txBuilder := bitcoin.NewTxBuilder(params).WithRecipientScript(recipientScript)
+			tx, err := txBuilder.BuildTx(inputs, []utxo.Recipient{{To: "not an address", Value: pack.NewU256FromUint64(40000)}})
+			Expect(err).ToNot(HaveOccurred())
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(outputs[0].PubKeyScript)).To(Equal(pubKeyScript))
+
+			_, err = txBuilder.BuildTx(inputs, []utxo.Recipient{{Script: witnessScript, Value: pack.NewU256FromUint64(40000)}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
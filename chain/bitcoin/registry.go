@@ -0,0 +1,56 @@
+package bitcoin
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/pranav292gpt/zecutil/api/gas"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/registry"
+	"github.com/renproject/pack"
+)
+
+func init() {
+	for network, config := range map[registry.Network]struct {
+		params *chaincfg.Params
+		host   string
+	}{
+		registry.Mainnet: {&chaincfg.MainNetParams, "http://127.0.0.1:8332"},
+		registry.Testnet: {&chaincfg.TestNet3Params, "http://127.0.0.1:18332"},
+		registry.Regtest: {&chaincfg.RegressionNetParams, DefaultClientOptions().Host},
+	} {
+		registry.Register(registry.Bundle{
+			Chain:                registry.Bitcoin,
+			Network:              network,
+			AddressEncodeDecoder: NewAddressEncodeDecoder(config.params),
+			TxBuilder:            NewTxBuilder(config.params),
+			DefaultClientOptions: registry.ClientOptions(DefaultClientOptions().WithHost(config.host)),
+			NewClient:            NewRegistryClient,
+			NewGasEstimator: func(client utxo.Client, numBlocks int64, fallbackGas pack.U256) (gas.Estimator, error) {
+				bitcoinClient, err := RegistryClient(client)
+				if err != nil {
+					return nil, err
+				}
+				return NewGasEstimator(bitcoinClient, numBlocks, fallbackGas), nil
+			},
+		})
+	}
+}
+
+// NewRegistryClient returns a client using client options from the registry.
+// It can be used as the client constructor of registry bundles for chains
+// that re-use the Bitcoin client.
+func NewRegistryClient(opts registry.ClientOptions) utxo.Client {
+	return NewClient(ClientOptions(opts))
+}
+
+// RegistryClient converts a client that was returned by a registry bundle back
+// into a Client. An error is returned if the client was not returned by
+// NewRegistryClient.
+func RegistryClient(client utxo.Client) (Client, error) {
+	bitcoinClient, ok := client.(Client)
+	if !ok {
+		return nil, fmt.Errorf("bad client: expected bitcoin client, got %T", client)
+	}
+	return bitcoinClient, nil
+}
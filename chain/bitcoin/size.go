@@ -0,0 +1,155 @@
+package bitcoin
+
+import (
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// maxSigSize is the maximum size of a DER encoded ECDSA signature, with
+	// the sighash type appended.
+	maxSigSize = 73
+	// maxPubKeySize is the size of a compressed public key. Uncompressed
+	// public keys are larger, but are not expected to be used when building
+	// new transactions.
+	maxPubKeySize = 33
+	// witnessScaleFactor is the discount applied to witness data when
+	// computing the virtual size of a transaction.
+	witnessScaleFactor = 4
+)
+
+const (
+	// TxOverheadSize is the size (in bytes) of the version, the input and
+	// output counts, and the lock time of a transaction without witnesses, and
+	// with fewer than 253 inputs and outputs.
+	TxOverheadSize = 4 + 1 + 1 + 4
+	// P2PKHInputSize is the maximum size (in bytes) of a signed input that
+	// spends a P2PKH output with a compressed public key.
+	P2PKHInputSize = 32 + 4 + 1 + 1 + maxSigSize + 1 + maxPubKeySize + 4
+	// P2PKHOutputSize is the size (in bytes) of a P2PKH output.
+	P2PKHOutputSize = 8 + 1 + 25
+)
+
+// EstimateP2PKHSize returns the estimated size (in bytes) of a signed
+// transaction with the given number of P2PKH inputs and outputs. It can be
+// used to estimate fees before the inputs of a transaction are selected.
+func EstimateP2PKHSize(numInputs, numOutputs int) int {
+	return TxOverheadSize + P2PKHInputSize*numInputs + P2PKHOutputSize*numOutputs
+}
+
+// A FeeFunc returns the fee of a transaction with the given number of P2PKH
+// inputs and outputs, at the given fee rate (per byte). It is used to estimate
+// fees before the inputs of a transaction are selected.
+type FeeFunc func(feeRate pack.U256, numInputs, numOutputs int) pack.U256
+
+// LinearFee is a FeeFunc that pays the fee rate for the estimated size of a
+// transaction with P2PKH inputs and outputs (see EstimateP2PKHSize).
+func LinearFee(feeRate pack.U256, numInputs, numOutputs int) pack.U256 {
+	return feeRate.Mul(pack.NewU256FromUint64(uint64(EstimateP2PKHSize(numInputs, numOutputs))))
+}
+
+// EstimateInputSize returns the estimated size of the signature script, and
+// the estimated size of the witness, that will be needed to spend the given
+// input once it has been signed. The estimate assumes that the input will be
+// spent using a single signature and a compressed public key, which is what
+// Tx.Sign produces, or that an HTLC will be claimed. P2TR inputs are assumed to
+// be spent using the key path.
+func EstimateInputSize(input utxo.Input) (int, int) {
+	sigScript := []byte(input.SigScript)
+
+	kind, err := ClassifyInput(input)
+	if err != nil {
+		// Assume that the input spends a P2SH output, which is the largest
+		// non-segwit input.
+		kind = SpendP2SH
+		if sigScript == nil {
+			kind = SpendP2PKH
+		}
+	}
+	switch kind {
+	case SpendP2TR:
+		return 0, witnessSize(SchnorrSignatureSize)
+	case SpendP2WPKH:
+		return 0, witnessSize(maxSigSize, maxPubKeySize)
+	case SpendP2WSH:
+		return 0, witnessSize(maxSigSize, maxPubKeySize, len(sigScript))
+	case SpendP2SHP2WPKH:
+		return pushSize(len(sigScript)), witnessSize(maxSigSize, maxPubKeySize)
+	case SpendP2SHP2WSH:
+		return pushSize(len(payToWitnessScriptHashScript(sigScript))), witnessSize(maxSigSize, maxPubKeySize, len(sigScript))
+	case SpendP2PKH:
+		return pushSize(maxSigSize) + pushSize(maxPubKeySize), 0
+	}
+	if isHTLC(sigScript) {
+		// Claiming an HTLC also reveals the secret and selects the branch,
+		// which is larger than refunding it.
+		return pushSize(maxSigSize) + pushSize(maxPubKeySize) + pushSize(SecretSize) + 1 + pushSize(len(sigScript)), 0
+	}
+	return pushSize(maxSigSize) + pushSize(maxPubKeySize) + pushSize(len(sigScript)), 0
+}
+
+// isHTLC returns true if the script is the redeem script of an HTLC.
+func isHTLC(script []byte) bool {
+	_, err := ParseHTLC(script)
+	return err == nil
+}
+
+// EstimateSize returns the estimated virtual size of the transaction (in
+// bytes) once all of its inputs have been signed. If the transaction has
+// already been signed, then its actual virtual size is returned.
+func (tx *Tx) EstimateSize() int {
+	if tx.signed {
+		return virtualSize(tx.msgTx.SerializeSizeStripped(), tx.msgTx.SerializeSize()-tx.msgTx.SerializeSizeStripped())
+	}
+
+	baseSize := tx.msgTx.SerializeSizeStripped()
+	witness := 0
+	for i, input := range tx.inputs {
+		sigScriptSize, witnessSize := EstimateInputSize(input)
+		current := len(tx.msgTx.TxIn[i].SignatureScript)
+		baseSize += sigScriptSize - current + wire.VarIntSerializeSize(uint64(sigScriptSize)) - wire.VarIntSerializeSize(uint64(current))
+		witness += witnessSize
+	}
+	if witness > 0 {
+		// Inputs without a witness still need to encode an empty witness, and
+		// the transaction needs to encode the segwit marker and flag.
+		for _, input := range tx.inputs {
+			if _, witnessSize := EstimateInputSize(input); witnessSize == 0 {
+				witness++
+			}
+		}
+		witness += 2
+	}
+	return virtualSize(baseSize, witness)
+}
+
+func virtualSize(baseSize, witnessSize int) int {
+	return baseSize + (witnessSize+witnessScaleFactor-1)/witnessScaleFactor
+}
+
+// pushSize returns the number of bytes needed to push data of the given length
+// onto the stack in a script.
+func pushSize(n int) int {
+	switch {
+	case n < txscript.OP_PUSHDATA1:
+		return 1 + n
+	case n <= 0xff:
+		return 2 + n
+	case n <= 0xffff:
+		return 3 + n
+	default:
+		return 5 + n
+	}
+}
+
+// witnessSize returns the number of bytes needed to encode a witness with
+// items of the given lengths.
+func witnessSize(items ...int) int {
+	size := wire.VarIntSerializeSize(uint64(len(items)))
+	for _, item := range items {
+		size += wire.VarIntSerializeSize(uint64(item)) + item
+	}
+	return size
+}
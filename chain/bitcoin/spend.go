@@ -0,0 +1,146 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+)
+
+// A SpendKind defines how an input spends the output that it consumes, and
+// therefore how its sighash is computed, and how its signature script and
+// witness are built.
+type SpendKind uint8
+
+const (
+	// SpendP2PKH spends a P2PKH output. The sig script of the input is nil.
+	SpendP2PKH = SpendKind(iota)
+	// SpendP2SH spends a P2SH output. The sig script of the input is the
+	// redeem script.
+	SpendP2SH
+	// SpendP2WPKH spends a P2WPKH output. The sig script of the input is nil.
+	SpendP2WPKH
+	// SpendP2WSH spends a P2WSH output. The sig script of the input is the
+	// witness script.
+	SpendP2WSH
+	// SpendP2SHP2WPKH spends a P2WPKH output nested in a P2SH output. The sig
+	// script of the input is the P2WPKH pubkey script, which is also the redeem
+	// script.
+	SpendP2SHP2WPKH
+	// SpendP2SHP2WSH spends a P2WSH output nested in a P2SH output. The sig
+	// script of the input is the witness script. The redeem script is the
+	// P2WSH pubkey script of the witness script.
+	SpendP2SHP2WSH
+	// SpendP2TR spends a P2TR output using the key path. The sig script of the
+	// input is nil.
+	SpendP2TR
+)
+
+// String implements the fmt.Stringer interface.
+func (kind SpendKind) String() string {
+	switch kind {
+	case SpendP2PKH:
+		return "p2pkh"
+	case SpendP2SH:
+		return "p2sh"
+	case SpendP2WPKH:
+		return "p2wpkh"
+	case SpendP2WSH:
+		return "p2wsh"
+	case SpendP2SHP2WPKH:
+		return "p2sh-p2wpkh"
+	case SpendP2SHP2WSH:
+		return "p2sh-p2wsh"
+	case SpendP2TR:
+		return "p2tr"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(kind))
+	}
+}
+
+// IsWitness returns true if the input spends a segwit output, and its
+// signature is in the witness.
+func (kind SpendKind) IsWitness() bool {
+	return kind != SpendP2PKH && kind != SpendP2SH
+}
+
+// ClassifyInput returns how the input spends the output that it consumes,
+// based on the pubkey script of the output, and the sig script of the input.
+// An error is returned if the sig script does not match the pubkey script.
+func ClassifyInput(input utxo.Input) (SpendKind, error) {
+	pubKeyScript := []byte(input.PubKeyScript)
+	sigScript := []byte(input.SigScript)
+
+	switch {
+	case IsPayToTaproot(pubKeyScript):
+		if sigScript != nil {
+			return 0, fmt.Errorf("bad sig script: expected nil for p2tr")
+		}
+		return SpendP2TR, nil
+	case txscript.IsPayToWitnessPubKeyHash(pubKeyScript):
+		if sigScript != nil {
+			return 0, fmt.Errorf("bad sig script: expected nil for p2wpkh")
+		}
+		return SpendP2WPKH, nil
+	case txscript.IsPayToWitnessScriptHash(pubKeyScript):
+		hash := sha256.Sum256(sigScript)
+		if sigScript == nil || !bytes.Equal(hash[:], pubKeyScript[2:]) {
+			return 0, fmt.Errorf("bad sig script: expected witness script for p2wsh")
+		}
+		return SpendP2WSH, nil
+	case txscript.IsPayToScriptHash(pubKeyScript):
+		scriptHash := pubKeyScript[2:22]
+		if sigScript != nil && bytes.Equal(btcutil.Hash160(sigScript), scriptHash) {
+			if txscript.IsPayToWitnessPubKeyHash(sigScript) {
+				return SpendP2SHP2WPKH, nil
+			}
+			return SpendP2SH, nil
+		}
+		if sigScript != nil && bytes.Equal(btcutil.Hash160(payToWitnessScriptHashScript(sigScript)), scriptHash) {
+			return SpendP2SHP2WSH, nil
+		}
+		return 0, fmt.Errorf("bad sig script: expected redeem script, or witness script, for p2sh")
+	default:
+		if sigScript != nil {
+			return 0, fmt.Errorf("bad sig script: expected nil for %v", txscript.GetScriptClass(pubKeyScript))
+		}
+		return SpendP2PKH, nil
+	}
+}
+
+// payToWitnessScriptHashScript returns the P2WSH pubkey script of the witness
+// script.
+func payToWitnessScriptHashScript(witnessScript []byte) []byte {
+	hash := sha256.Sum256(witnessScript)
+	return append([]byte{txscript.OP_0, txscript.OP_DATA_32}, hash[:]...)
+}
+
+// signInput sets the signature script and witness of the input, so that it
+// spends the output that it consumes using the signature and pubkey.
+func signInput(txIn *wire.TxIn, kind SpendKind, input utxo.Input, sig []byte, pubKey []byte) error {
+	sigScript := []byte(input.SigScript)
+	var err error
+	switch kind {
+	case SpendP2TR:
+		txIn.Witness = wire.TxWitness{sig}
+	case SpendP2WPKH:
+		txIn.Witness = wire.TxWitness{sig, pubKey}
+	case SpendP2WSH:
+		txIn.Witness = wire.TxWitness{sig, pubKey, sigScript}
+	case SpendP2SHP2WPKH:
+		txIn.Witness = wire.TxWitness{sig, pubKey}
+		txIn.SignatureScript, err = txscript.NewScriptBuilder().AddData(sigScript).Script()
+	case SpendP2SHP2WSH:
+		txIn.Witness = wire.TxWitness{sig, pubKey, sigScript}
+		txIn.SignatureScript, err = txscript.NewScriptBuilder().AddData(payToWitnessScriptHashScript(sigScript)).Script()
+	case SpendP2SH:
+		txIn.SignatureScript, err = txscript.NewScriptBuilder().AddData(sig).AddData(pubKey).AddData(sigScript).Script()
+	default:
+		txIn.SignatureScript, err = txscript.NewScriptBuilder().AddData(sig).AddData(pubKey).Script()
+	}
+	return err
+}
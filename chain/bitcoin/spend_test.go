@@ -0,0 +1,145 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spending", func() {
+	params := &chaincfg.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		panic(err)
+	}
+	key := signer.NewKeySigner(privKey, true)
+	pubKeyHash := btcutil.Hash160(key.PubKey())
+
+	// The script that is used as the redeem script, and the witness script.
+	// It is spent by a signature and a pubkey.
+	script, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(pubKeyHash).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		panic(err)
+	}
+	scriptHash := sha256.Sum256(script)
+	p2wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+	if err != nil {
+		panic(err)
+	}
+	p2wpkhScript, err := txscript.PayToAddrScript(p2wpkhAddr)
+	if err != nil {
+		panic(err)
+	}
+	p2wshAddr, err := btcutil.NewAddressWitnessScriptHash(scriptHash[:], params)
+	if err != nil {
+		panic(err)
+	}
+	p2wshScript, err := txscript.PayToAddrScript(p2wshAddr)
+	if err != nil {
+		panic(err)
+	}
+
+	newAddr := func(f func() (btcutil.Address, error)) btcutil.Address {
+		addr, err := f()
+		if err != nil {
+			panic(err)
+		}
+		return addr
+	}
+	type spend struct {
+		kind      bitcoin.SpendKind
+		addr      btcutil.Address
+		sigScript []byte
+	}
+	spends := []spend{
+		{bitcoin.SpendP2PKH, newAddr(func() (btcutil.Address, error) { return btcutil.NewAddressPubKeyHash(pubKeyHash, params) }), nil},
+		{bitcoin.SpendP2SH, newAddr(func() (btcutil.Address, error) { return btcutil.NewAddressScriptHash(script, params) }), script},
+		{bitcoin.SpendP2WPKH, p2wpkhAddr, nil},
+		{bitcoin.SpendP2WSH, p2wshAddr, script},
+		{bitcoin.SpendP2SHP2WPKH, newAddr(func() (btcutil.Address, error) { return btcutil.NewAddressScriptHash(p2wpkhScript, params) }), p2wpkhScript},
+		{bitcoin.SpendP2SHP2WSH, newAddr(func() (btcutil.Address, error) { return btcutil.NewAddressScriptHash(p2wshScript, params) }), script},
+	}
+
+	for _, spend := range spends {
+		spend := spend
+		Context(spend.kind.String(), func() {
+			pubKeyScript, err := txscript.PayToAddrScript(spend.addr)
+			if err != nil {
+				panic(err)
+			}
+			inputs := []utxo.Input{
+				{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pubKeyScript}, SigScript: spend.sigScript},
+				{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(1)}, Value: pack.NewU256FromUint64(50000), PubKeyScript: pubKeyScript}, SigScript: spend.sigScript},
+			}
+
+			It("should classify the input", func() {
+				kind, err := bitcoin.ClassifyInput(inputs[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(kind).To(Equal(spend.kind))
+			})
+
+			It("should build, sign, and verify transactions", func() {
+				// Pay to the same kind of address that is being spent.
+				recipients := []utxo.Recipient{{To: address.Address(spend.addr.EncodeAddress()), Value: pack.NewU256FromUint64(140000)}}
+				tx, err := bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+				Expect(err).ToNot(HaveOccurred())
+				outputs, err := tx.Outputs()
+				Expect(err).ToNot(HaveOccurred())
+				Expect([]byte(outputs[0].PubKeyScript)).To(Equal(pubKeyScript))
+				estimated := tx.(*bitcoin.Tx).EstimateSize()
+
+				Expect(signer.SignTx(context.Background(), key, tx)).To(Succeed())
+				actual := tx.(*bitcoin.Tx).EstimateSize()
+				Expect(actual).To(BeNumerically("<=", estimated))
+				// DER signatures can be up to 2 bytes smaller than the maximum size.
+				Expect(actual).To(BeNumerically(">=", estimated-2*len(inputs)))
+
+				serialized, err := tx.Serialize()
+				Expect(err).ToNot(HaveOccurred())
+				msgTx := wire.NewMsgTx(bitcoin.Version)
+				Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+				for i, txIn := range msgTx.TxIn {
+					Expect(len(txIn.Witness) > 0).To(Equal(spend.kind.IsWitness()))
+					engine, err := txscript.NewEngine(pubKeyScript, msgTx, i, txscript.StandardVerifyFlags, nil, nil, inputs[i].Value.Int().Int64())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(engine.Execute()).To(Succeed())
+				}
+			})
+		})
+	}
+
+	It("should reject sig scripts that do not match the pubkey script", func() {
+		p2shScript, err := txscript.PayToAddrScript(spends[1].addr)
+		Expect(err).ToNot(HaveOccurred())
+		for _, input := range []utxo.Input{
+			{Output: utxo.Output{PubKeyScript: p2wpkhScript}, SigScript: script},
+			{Output: utxo.Output{PubKeyScript: p2wshScript}},
+			{Output: utxo.Output{PubKeyScript: p2wshScript}, SigScript: p2wpkhScript},
+			{Output: utxo.Output{PubKeyScript: p2shScript}},
+			{Output: utxo.Output{PubKeyScript: p2shScript}, SigScript: p2wpkhScript},
+		} {
+			_, err := bitcoin.ClassifyInput(input)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
@@ -0,0 +1,353 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// TaprootWitnessVersion is the witness version of pay-to-taproot (P2TR)
+	// outputs (as defined by BIP341).
+	TaprootWitnessVersion = 1
+	// SigHashDefault is the sighash type of taproot signatures that commit to
+	// all inputs and outputs (as defined by BIP341). It is equivalent to
+	// SIGHASH_ALL, but the sighash type is omitted from the signature.
+	SigHashDefault = txscript.SigHashType(0x00)
+	// SchnorrSignatureSize is the size of a Schnorr signature (as defined by
+	// BIP340).
+	SchnorrSignatureSize = 64
+)
+
+// An AddressTaproot is a pay-to-taproot (P2TR) address (as defined by BIP341).
+// It is encoded using bech32m (as defined by BIP350). It implements the
+// btcutil.Address interface.
+type AddressTaproot struct {
+	hrp       string
+	outputKey [32]byte
+}
+
+// NewAddressTaproot returns a P2TR address for the given x-only output key.
+// The output key is usually computed using TaprootOutputKey.
+func NewAddressTaproot(outputKey []byte, params *chaincfg.Params) (*AddressTaproot, error) {
+	if len(outputKey) != 32 {
+		return nil, fmt.Errorf("bad output key: expected 32 bytes, got %v bytes", len(outputKey))
+	}
+	addr := &AddressTaproot{hrp: params.Bech32HRPSegwit}
+	copy(addr.outputKey[:], outputKey)
+	return addr, nil
+}
+
+// DecodeAddressTaproot decodes a bech32m encoded P2TR address.
+func DecodeAddressTaproot(addr string, params *chaincfg.Params) (*AddressTaproot, error) {
+	version, program, err := decodeSegWitAddress(params.Bech32HRPSegwit, addr)
+	if err != nil {
+		return nil, err
+	}
+	if version != TaprootWitnessVersion || len(program) != 32 {
+		return nil, fmt.Errorf("bad address: expected witness version %v with 32 byte program, got witness version %v with %v byte program", TaprootWitnessVersion, version, len(program))
+	}
+	return NewAddressTaproot(program, params)
+}
+
+// EncodeAddress returns the bech32m encoding of the address.
+func (addr *AddressTaproot) EncodeAddress() string {
+	encoded, err := encodeSegWitAddress(addr.hrp, TaprootWitnessVersion, addr.outputKey[:])
+	if err != nil {
+		return ""
+	}
+	return encoded
+}
+
+// String returns the bech32m encoding of the address.
+func (addr *AddressTaproot) String() string {
+	return addr.EncodeAddress()
+}
+
+// ScriptAddress returns the x-only output key of the address.
+func (addr *AddressTaproot) ScriptAddress() []byte {
+	return addr.outputKey[:]
+}
+
+// IsForNet returns whether or not the address is associated with the passed
+// bitcoin network.
+func (addr *AddressTaproot) IsForNet(params *chaincfg.Params) bool {
+	return addr.hrp == params.Bech32HRPSegwit
+}
+
+// WitnessVersion returns the witness version of the address.
+func (addr *AddressTaproot) WitnessVersion() byte {
+	return TaprootWitnessVersion
+}
+
+// WitnessProgram returns the witness program of the address.
+func (addr *AddressTaproot) WitnessProgram() []byte {
+	return addr.outputKey[:]
+}
+
+// PayToTaprootScript returns the pubkey script of a P2TR output for the given
+// x-only output key.
+func PayToTaprootScript(outputKey []byte) ([]byte, error) {
+	if len(outputKey) != 32 {
+		return nil, fmt.Errorf("bad output key: expected 32 bytes, got %v bytes", len(outputKey))
+	}
+	return txscript.NewScriptBuilder().AddOp(txscript.OP_1).AddData(outputKey).Script()
+}
+
+// IsPayToTaproot returns true if the pubkey script is a P2TR script.
+func IsPayToTaproot(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_1 && script[1] == txscript.OP_DATA_32
+}
+
+// TaprootOutputKey returns the x-only output key that commits to the x-only
+// internal key, and to the merkle root of the script tree (as defined by
+// BIP341). The merkle root is nil if the output can only be spent using the
+// key path.
+func TaprootOutputKey(internalKey []byte, merkleRoot []byte) ([]byte, error) {
+	p, err := liftX(internalKey)
+	if err != nil {
+		return nil, fmt.Errorf("bad internal key: %v", err)
+	}
+	t, err := taprootTweak(internalKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	curve := btcec.S256()
+	tx, ty := curve.ScalarBaseMult(t.Bytes())
+	qx, _ := curve.Add(p.X, p.Y, tx, ty)
+	return padScalar(qx), nil
+}
+
+// TaprootTweakPrivKey returns the private key that can sign for the output key
+// that commits to the public key of the given private key, and to the merkle
+// root of the script tree (as defined by BIP341). Signatures for key path
+// spends must be produced using the tweaked private key.
+func TaprootTweakPrivKey(privKey *btcec.PrivateKey, merkleRoot []byte) (*btcec.PrivateKey, error) {
+	n := btcec.S256().N
+	d := new(big.Int).Set(privKey.D)
+	if privKey.PubKey().Y.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+	t, err := taprootTweak(padScalar(privKey.PubKey().X), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t).Mod(d, n)
+	if d.Sign() == 0 {
+		return nil, fmt.Errorf("bad tweak: tweaked private key is zero")
+	}
+	tweaked, _ := btcec.PrivKeyFromBytes(btcec.S256(), padScalar(d))
+	return tweaked, nil
+}
+
+// CalcTaprootSigHash returns the sighash of the input at the given index, for
+// a key path spend (as defined by BIP341). The previous outputs must be the
+// outputs spent by each input of the transaction, in order. All sighash types
+// are supported, but annexes are not.
+func CalcTaprootSigHash(tx *wire.MsgTx, prevOuts []*wire.TxOut, hashType txscript.SigHashType, idx int) ([]byte, error) {
+	if len(prevOuts) != len(tx.TxIn) {
+		return nil, fmt.Errorf("bad previous outputs: expected %v, got %v", len(tx.TxIn), len(prevOuts))
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("bad input %v: expected index < %v", idx, len(tx.TxIn))
+	}
+	switch hashType {
+	case SigHashDefault, txscript.SigHashAll, txscript.SigHashNone, txscript.SigHashSingle,
+		txscript.SigHashAll | txscript.SigHashAnyOneCanPay, txscript.SigHashNone | txscript.SigHashAnyOneCanPay, txscript.SigHashSingle | txscript.SigHashAnyOneCanPay:
+	default:
+		return nil, fmt.Errorf("bad sighash type: %v", hashType)
+	}
+	anyoneCanPay := hashType&txscript.SigHashAnyOneCanPay != 0
+	outputType := hashType & 0x03
+	if outputType == SigHashDefault {
+		outputType = txscript.SigHashAll
+	}
+	if outputType == txscript.SigHashSingle && idx >= len(tx.TxOut) {
+		return nil, fmt.Errorf("bad input %v: no corresponding output for sighash single", idx)
+	}
+
+	msg := new(bytes.Buffer)
+	// The sighash epoch.
+	msg.WriteByte(0x00)
+	msg.WriteByte(byte(hashType))
+	binary.Write(msg, binary.LittleEndian, tx.Version)
+	binary.Write(msg, binary.LittleEndian, tx.LockTime)
+	if !anyoneCanPay {
+		prevouts, amounts, scriptPubKeys, sequences := sha256.New(), sha256.New(), sha256.New(), sha256.New()
+		for i, txIn := range tx.TxIn {
+			writeOutPoint(prevouts, txIn.PreviousOutPoint)
+			binary.Write(amounts, binary.LittleEndian, prevOuts[i].Value)
+			if err := wire.WriteVarBytes(scriptPubKeys, 0, prevOuts[i].PkScript); err != nil {
+				return nil, err
+			}
+			binary.Write(sequences, binary.LittleEndian, txIn.Sequence)
+		}
+		msg.Write(prevouts.Sum(nil))
+		msg.Write(amounts.Sum(nil))
+		msg.Write(scriptPubKeys.Sum(nil))
+		msg.Write(sequences.Sum(nil))
+	}
+	if outputType == txscript.SigHashAll {
+		outputs := sha256.New()
+		for _, txOut := range tx.TxOut {
+			if err := wire.WriteTxOut(outputs, 0, 0, txOut); err != nil {
+				return nil, err
+			}
+		}
+		msg.Write(outputs.Sum(nil))
+	}
+	// The spend type is zero for key path spends without an annex.
+	msg.WriteByte(0x00)
+	if anyoneCanPay {
+		txIn := tx.TxIn[idx]
+		writeOutPoint(msg, txIn.PreviousOutPoint)
+		binary.Write(msg, binary.LittleEndian, prevOuts[idx].Value)
+		if err := wire.WriteVarBytes(msg, 0, prevOuts[idx].PkScript); err != nil {
+			return nil, err
+		}
+		binary.Write(msg, binary.LittleEndian, txIn.Sequence)
+	} else {
+		binary.Write(msg, binary.LittleEndian, uint32(idx))
+	}
+	if outputType == txscript.SigHashSingle {
+		output := sha256.New()
+		if err := wire.WriteTxOut(output, 0, 0, tx.TxOut[idx]); err != nil {
+			return nil, err
+		}
+		msg.Write(output.Sum(nil))
+	}
+	return taggedHash("TapSighash", msg.Bytes()), nil
+}
+
+// SchnorrSign returns the Schnorr signature of the hash (as defined by
+// BIP340). The auxiliary randomness must be 32 bytes, and should be fresh for
+// every signature, but signatures are still secure if it is all zeros.
+func SchnorrSign(privKey *btcec.PrivateKey, hash []byte, auxRand []byte) ([SchnorrSignatureSize]byte, error) {
+	sig := [SchnorrSignatureSize]byte{}
+	if len(hash) != 32 {
+		return sig, fmt.Errorf("bad hash: expected 32 bytes, got %v bytes", len(hash))
+	}
+	if len(auxRand) != 32 {
+		return sig, fmt.Errorf("bad auxiliary randomness: expected 32 bytes, got %v bytes", len(auxRand))
+	}
+	curve := btcec.S256()
+	n := curve.N
+	d := new(big.Int).Set(privKey.D)
+	if d.Sign() == 0 || d.Cmp(n) >= 0 {
+		return sig, fmt.Errorf("bad private key")
+	}
+	px, py := curve.ScalarBaseMult(padScalar(d))
+	if py.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+	pubKey := padScalar(px)
+
+	t := taggedHash("BIP0340/aux", auxRand)
+	for i, b := range padScalar(d) {
+		t[i] ^= b
+	}
+	k := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, pubKey, hash))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return sig, fmt.Errorf("bad nonce: zero")
+	}
+	rx, ry := curve.ScalarBaseMult(padScalar(k))
+	if ry.Bit(0) == 1 {
+		k.Sub(n, k)
+	}
+	r := padScalar(rx)
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", r, pubKey, hash))
+	e.Mod(e, n)
+	s := e.Mul(e, d)
+	s.Add(s, k).Mod(s, n)
+
+	copy(sig[:32], r)
+	copy(sig[32:], padScalar(s))
+	return sig, nil
+}
+
+// SchnorrVerify returns true if the signature is a valid Schnorr signature of
+// the hash, for the x-only public key (as defined by BIP340).
+func SchnorrVerify(pubKey []byte, hash []byte, sig []byte) bool {
+	if len(hash) != 32 || len(sig) != SchnorrSignatureSize {
+		return false
+	}
+	p, err := liftX(pubKey)
+	if err != nil {
+		return false
+	}
+	curve := btcec.S256()
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", sig[:32], pubKey, hash))
+	e.Mod(e, curve.N)
+	e.Sub(curve.N, e)
+
+	// R = s*G - e*P
+	sx, sy := curve.ScalarBaseMult(padScalar(s))
+	ex, ey := curve.ScalarMult(p.X, p.Y, padScalar(e))
+	rx, ry := curve.Add(sx, sy, ex, ey)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false
+	}
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
+
+// writeOutPoint writes the serialization of the outpoint.
+func writeOutPoint(w io.Writer, outpoint wire.OutPoint) {
+	w.Write(outpoint.Hash[:])
+	binary.Write(w, binary.LittleEndian, outpoint.Index)
+}
+
+// taprootTweak returns the tweak that commits the internal key to the merkle
+// root.
+func taprootTweak(internalKey []byte, merkleRoot []byte) (*big.Int, error) {
+	if merkleRoot != nil && len(merkleRoot) != 32 {
+		return nil, fmt.Errorf("bad merkle root: expected 32 bytes, got %v bytes", len(merkleRoot))
+	}
+	t := new(big.Int).SetBytes(taggedHash("TapTweak", internalKey, merkleRoot))
+	if t.Cmp(btcec.S256().N) >= 0 {
+		return nil, fmt.Errorf("bad tweak: exceeds curve order")
+	}
+	return t, nil
+}
+
+// liftX returns the point with the given x-coordinate, and an even
+// y-coordinate.
+func liftX(x []byte) (*btcec.PublicKey, error) {
+	if len(x) != 32 {
+		return nil, fmt.Errorf("expected 32 bytes, got %v bytes", len(x))
+	}
+	return btcec.ParsePubKey(append([]byte{0x02}, x...), btcec.S256())
+}
+
+// taggedHash returns the tagged hash of the data (as defined by BIP340).
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// padScalar returns the 32 byte big-endian encoding of the integer.
+func padScalar(x *big.Int) []byte {
+	b := make([]byte, 32)
+	xBytes := x.Bytes()
+	copy(b[32-len(xBytes):], xBytes)
+	return b
+}
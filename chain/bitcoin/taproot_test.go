@@ -0,0 +1,265 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type taprootVectors struct {
+	ScriptPubKey []struct {
+		Given struct {
+			InternalPubkey string `json:"internalPubkey"`
+		} `json:"given"`
+		Intermediary struct {
+			MerkleRoot    *string `json:"merkleRoot"`
+			TweakedPubkey string  `json:"tweakedPubkey"`
+		} `json:"intermediary"`
+		Expected struct {
+			ScriptPubKey  string `json:"scriptPubKey"`
+			Bip350Address string `json:"bip350Address"`
+		} `json:"expected"`
+	} `json:"scriptPubKey"`
+	KeyPathSpending []struct {
+		Given struct {
+			RawUnsignedTx string `json:"rawUnsignedTx"`
+			UtxosSpent    []struct {
+				ScriptPubKey string `json:"scriptPubKey"`
+				AmountSats   int64  `json:"amountSats"`
+			} `json:"utxosSpent"`
+		} `json:"given"`
+		InputSpending []struct {
+			Given struct {
+				TxinIndex       int     `json:"txinIndex"`
+				InternalPrivkey string  `json:"internalPrivkey"`
+				MerkleRoot      *string `json:"merkleRoot"`
+				HashType        byte    `json:"hashType"`
+			} `json:"given"`
+			Intermediary struct {
+				TweakedPrivkey string `json:"tweakedPrivkey"`
+				SigHash        string `json:"sigHash"`
+			} `json:"intermediary"`
+			Expected struct {
+				Witness []string `json:"witness"`
+			} `json:"expected"`
+		} `json:"inputSpending"`
+	} `json:"keyPathSpending"`
+}
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func decodeMerkleRoot(s *string) []byte {
+	if s == nil {
+		return nil
+	}
+	return decodeHex(*s)
+}
+
+var _ = Describe("Taproot", func() {
+	data, err := os.ReadFile("testdata/bip_0341.json")
+	if err != nil {
+		panic(err)
+	}
+	vectors := taprootVectors{}
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		panic(err)
+	}
+	zero := make([]byte, 32)
+
+	Context("when computing output keys", func() {
+		It("should match the BIP341 vectors", func() {
+			encodeDecoder := bitcoin.NewAddressEncodeDecoder(&chaincfg.MainNetParams)
+			for _, vector := range vectors.ScriptPubKey {
+				outputKey, err := bitcoin.TaprootOutputKey(decodeHex(vector.Given.InternalPubkey), decodeMerkleRoot(vector.Intermediary.MerkleRoot))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(outputKey)).To(Equal(vector.Intermediary.TweakedPubkey))
+
+				script, err := bitcoin.PayToTaprootScript(outputKey)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(script)).To(Equal(vector.Expected.ScriptPubKey))
+				Expect(bitcoin.IsPayToTaproot(script)).To(BeTrue())
+
+				addr, err := bitcoin.NewAddressTaproot(outputKey, &chaincfg.MainNetParams)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(addr.EncodeAddress()).To(Equal(vector.Expected.Bip350Address))
+
+				rawAddr, err := encodeDecoder.DecodeAddress(address.Address(vector.Expected.Bip350Address))
+				Expect(err).ToNot(HaveOccurred())
+				Expect([]byte(rawAddr)).To(Equal(append([]byte{bitcoin.TaprootWitnessVersion}, outputKey...)))
+				encoded, err := encodeDecoder.EncodeAddress(rawAddr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(encoded).To(Equal(address.Address(vector.Expected.Bip350Address)))
+			}
+		})
+	})
+
+	Context("when decoding addresses", func() {
+		It("should decode valid BIP350 addresses", func() {
+			for addr, outputKey := range map[string]string{
+				"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0": "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+				"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c": "000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433",
+			} {
+				params := &chaincfg.MainNetParams
+				if addr[0] == 't' {
+					params = &chaincfg.TestNet3Params
+				}
+				decoded, err := bitcoin.DecodeAddressTaproot(addr, params)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(decoded.ScriptAddress())).To(Equal(outputKey))
+				Expect(decoded.IsForNet(params)).To(BeTrue())
+			}
+		})
+
+		It("should reject invalid BIP350 addresses", func() {
+			decoder := bitcoin.NewAddressDecoder(&chaincfg.MainNetParams)
+			for _, addr := range []string{
+				"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut",
+				"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+				"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL",
+				"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+				"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",
+				"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R",
+				"bc1pw5dgrnzv",
+				"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav",
+				"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",
+				"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf",
+				"bc1gmk9yu",
+				// Valid, but not taproot.
+				"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y",
+				"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs",
+			} {
+				_, err := decoder.DecodeAddress(address.Address(addr))
+				Expect(err).To(HaveOccurred(), addr)
+			}
+			_, err := bitcoin.DecodeAddressTaproot("tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", &chaincfg.TestNet3Params)
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.DecodeAddressTaproot("tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j", &chaincfg.TestNet3Params)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when spending using the key path", func() {
+		It("should match the BIP341 vectors", func() {
+			for _, vector := range vectors.KeyPathSpending {
+				msgTx := wire.NewMsgTx(bitcoin.Version)
+				Expect(msgTx.Deserialize(bytes.NewReader(decodeHex(vector.Given.RawUnsignedTx)))).To(Succeed())
+				prevOuts := make([]*wire.TxOut, len(vector.Given.UtxosSpent))
+				for i, utxo := range vector.Given.UtxosSpent {
+					prevOuts[i] = wire.NewTxOut(utxo.AmountSats, decodeHex(utxo.ScriptPubKey))
+				}
+
+				for _, input := range vector.InputSpending {
+					hashType := txscript.SigHashType(input.Given.HashType)
+					sighash, err := bitcoin.CalcTaprootSigHash(msgTx, prevOuts, hashType, input.Given.TxinIndex)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hex.EncodeToString(sighash)).To(Equal(input.Intermediary.SigHash))
+
+					privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), decodeHex(input.Given.InternalPrivkey))
+					tweaked, err := bitcoin.TaprootTweakPrivKey(privKey, decodeMerkleRoot(input.Given.MerkleRoot))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hex.EncodeToString(tweaked.Serialize())).To(Equal(input.Intermediary.TweakedPrivkey))
+
+					sig, err := bitcoin.SchnorrSign(tweaked, sighash, zero)
+					Expect(err).ToNot(HaveOccurred())
+					witness := sig[:]
+					if hashType != bitcoin.SigHashDefault {
+						witness = append(witness, byte(hashType))
+					}
+					Expect(input.Expected.Witness).To(Equal([]string{hex.EncodeToString(witness)}))
+
+					outputKey := prevOuts[input.Given.TxinIndex].PkScript[2:]
+					Expect(bitcoin.SchnorrVerify(outputKey, sighash, sig[:])).To(BeTrue())
+					sig[0] ^= 1
+					Expect(bitcoin.SchnorrVerify(outputKey, sighash, sig[:])).To(BeFalse())
+				}
+			}
+		})
+
+		It("should reject invalid sighash types", func() {
+			vector := vectors.KeyPathSpending[0]
+			msgTx := wire.NewMsgTx(bitcoin.Version)
+			Expect(msgTx.Deserialize(bytes.NewReader(decodeHex(vector.Given.RawUnsignedTx)))).To(Succeed())
+			prevOuts := make([]*wire.TxOut, len(vector.Given.UtxosSpent))
+			for i, utxo := range vector.Given.UtxosSpent {
+				prevOuts[i] = wire.NewTxOut(utxo.AmountSats, decodeHex(utxo.ScriptPubKey))
+			}
+			_, err := bitcoin.CalcTaprootSigHash(msgTx, prevOuts, 0x04, 0)
+			Expect(err).To(HaveOccurred())
+			_, err = bitcoin.CalcTaprootSigHash(msgTx, prevOuts[1:], bitcoin.SigHashDefault, 0)
+			Expect(err).To(HaveOccurred())
+			// There is no output corresponding to the last input.
+			_, err = bitcoin.CalcTaprootSigHash(msgTx, prevOuts, txscript.SigHashSingle, len(prevOuts)-1)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should build, sign, and estimate the size of transactions", func() {
+			params := &chaincfg.RegressionNetParams
+			privKey, err := btcec.NewPrivateKey(btcec.S256())
+			Expect(err).ToNot(HaveOccurred())
+			outputKey, err := bitcoin.TaprootOutputKey(privKey.PubKey().SerializeCompressed()[1:], nil)
+			Expect(err).ToNot(HaveOccurred())
+			addr, err := bitcoin.NewAddressTaproot(outputKey, params)
+			Expect(err).ToNot(HaveOccurred())
+			pubKeyScript, err := bitcoin.PayToTaprootScript(outputKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			inputs := []utxo.Input{
+				{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pubKeyScript}},
+				{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(1)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pubKeyScript}},
+			}
+			recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(190000)}}
+			tx, err := bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(outputs[0].PubKeyScript)).To(Equal(pubKeyScript))
+			Expect(bitcoin.RecipientFromOutput(outputs[0], params)).To(Equal(recipients[0]))
+			estimated := tx.(*bitcoin.Tx).EstimateSize()
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			tweaked, err := bitcoin.TaprootTweakPrivKey(privKey, nil)
+			Expect(err).ToNot(HaveOccurred())
+			signatures := make([]pack.Bytes65, len(sighashes))
+			for i, sighash := range sighashes {
+				sig, err := bitcoin.SchnorrSign(tweaked, sighash[:], zero)
+				Expect(err).ToNot(HaveOccurred())
+				copy(signatures[i][:], sig[:])
+			}
+			Expect(tx.Sign(signatures, nil)).To(Succeed())
+			Expect(tx.(*bitcoin.Tx).EstimateSize()).To(Equal(estimated))
+
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(bitcoin.Version)
+			Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+			prevOuts := []*wire.TxOut{wire.NewTxOut(100000, pubKeyScript), wire.NewTxOut(100000, pubKeyScript)}
+			for i, txIn := range msgTx.TxIn {
+				Expect(txIn.Witness).To(HaveLen(1))
+				Expect(txIn.Witness[0]).To(HaveLen(bitcoin.SchnorrSignatureSize))
+				sighash, err := bitcoin.CalcTaprootSigHash(msgTx, prevOuts, bitcoin.SigHashDefault, i)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bitcoin.SchnorrVerify(outputKey, sighash, txIn.Witness[0])).To(BeTrue())
+			}
+		})
+	})
+})
@@ -3,14 +3,11 @@ package bitcoin
 import (
 	"bytes"
 	"fmt"
-	"math/big"
 
-	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
-	"github.com/btcsuite/btcutil"
 	"github.com/pranav292gpt/zecutil/api/utxo"
 	"github.com/renproject/pack"
 )
@@ -21,7 +18,13 @@ const Version int32 = 2
 // The TxBuilder is an implementation of a UTXO-compatible transaction builder
 // for Bitcoin.
 type TxBuilder struct {
-	params *chaincfg.Params
+	params    *chaincfg.Params
+	feePolicy FeePolicy
+	lockTime  uint32
+	sequence  uint32
+	sequences []uint32
+
+	recipientScript func(utxo.Recipient) ([]byte, error)
 }
 
 // NewTxBuilder returns a transaction builder that builds UTXO-compatible
@@ -29,36 +32,114 @@ type TxBuilder struct {
 // can be used for regnet, testnet, and mainnet, but also for networks that are
 // minimally modified forks of the Bitcoin network).
 func NewTxBuilder(params *chaincfg.Params) TxBuilder {
-	return TxBuilder{params: params}
+	return TxBuilder{params: params, feePolicy: DefaultFeePolicy(), sequence: SequenceFinal}
+}
+
+// WithFeePolicy returns a copy of the transaction builder that checks built
+// transactions against the given fee policy.
+func (txBuilder TxBuilder) WithFeePolicy(feePolicy FeePolicy) TxBuilder {
+	txBuilder.feePolicy = feePolicy
+	return txBuilder
+}
+
+// WithLockTime returns a copy of the transaction builder that sets the lock
+// time (nLockTime) of built transactions. The lock time is either a block
+// height or a UNIX timestamp (see LockTimeFromHeight and LockTimeFromTime). It
+// is only enforced if at least one input has a non-final sequence number (see
+// WithSequence), and it must be set when refunding an HTLC.
+func (txBuilder TxBuilder) WithLockTime(lockTime uint32) TxBuilder {
+	txBuilder.lockTime = lockTime
+	return txBuilder
+}
+
+// WithSequence returns a copy of the transaction builder that sets the
+// sequence number (nSequence) of all inputs of built transactions. By default,
+// inputs have the final sequence number (see SequenceFinal).
+func (txBuilder TxBuilder) WithSequence(sequence uint32) TxBuilder {
+	txBuilder.sequence = sequence
+	return txBuilder
+}
+
+// WithInputSequences returns a copy of the transaction builder that sets the
+// sequence number (nSequence) of each input of built transactions
+// individually. The number of sequence numbers must match the number of inputs
+// passed to BuildTx. This overrides WithSequence. It can be used to set
+// relative lock times (see RelativeLockHeight and RelativeLockTime), or to
+// signal replaceability (see SequenceRBF).
+func (txBuilder TxBuilder) WithInputSequences(sequences []uint32) TxBuilder {
+	txBuilder.sequences = append([]uint32{}, sequences...)
+	return txBuilder
+}
+
+// WithRecipientScript returns a copy of the transaction builder that uses the
+// given function to produce the pubkey script of the output for each recipient,
+// instead of RecipientScript with the address format of the chain
+// configuration. This allows forks of Bitcoin that use different address
+// formats, or that do not support all kinds of pubkey scripts, to reuse the
+// builder.
+func (txBuilder TxBuilder) WithRecipientScript(recipientScript func(utxo.Recipient) ([]byte, error)) TxBuilder {
+	txBuilder.recipientScript = recipientScript
+	return txBuilder
 }
 
 // BuildTx returns a Bitcoin transaction that consumes funds from the given
 // inputs, and sends them to the given recipients. The difference in the sum
 // value of the inputs and the sum value of the recipients is paid as a fee to
 // the Bitcoin network. This fee must be calculated independently of this
-// function. Outputs produced for recipients will use P2PKH, P2SH, P2WPKH, or
-// P2WSH scripts as the pubkey script, based on the format of the recipient
-// address.
+// function, but it is checked against the fee policy of the builder, and an
+// error is returned if it is out of bounds. Outputs produced for recipients
+// will use P2PKH, P2SH, P2WPKH, or P2WSH scripts as the pubkey script, based on
+// the format of the recipient address. Recipients can instead specify a
+// standard pubkey script, or data to be committed to by a null-data output
+// (see RecipientScript).
 func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipient) (utxo.Tx, error) {
+	tx, err := txBuilder.build(inputs, recipients)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := txBuilder.feePolicy.Check(inputs, tx.msgTx.TxOut, tx.EstimateSize())
+	if err != nil {
+		return nil, err
+	}
+	tx.fee = fee
+	return tx, nil
+}
+
+// build returns a Bitcoin transaction that consumes funds from the given
+// inputs, and sends them to the given recipients, without checking it against
+// the fee policy of the builder.
+func (txBuilder TxBuilder) build(inputs []utxo.Input, recipients []utxo.Recipient) (*Tx, error) {
 	msgTx := wire.NewMsgTx(Version)
 
 	// Inputs
-	for _, input := range inputs {
+	if txBuilder.sequences != nil && len(txBuilder.sequences) != len(inputs) {
+		return nil, fmt.Errorf("expected %v sequences, got %v sequences", len(inputs), len(txBuilder.sequences))
+	}
+	for i, input := range inputs {
 		hash := chainhash.Hash{}
 		copy(hash[:], input.Hash)
 		index := input.Index.Uint32()
-		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&hash, index), nil, nil))
+		txIn := wire.NewTxIn(wire.NewOutPoint(&hash, index), nil, nil)
+		txIn.Sequence = txBuilder.sequence
+		if txBuilder.sequences != nil {
+			txIn.Sequence = txBuilder.sequences[i]
+		}
+		msgTx.AddTxIn(txIn)
 	}
+	msgTx.LockTime = txBuilder.lockTime
 
 	// Outputs
-	for _, recipient := range recipients {
-		addr, err := btcutil.DecodeAddress(string(recipient.To), txBuilder.params)
-		if err != nil {
-			return nil, err
+	recipientScript := txBuilder.recipientScript
+	if recipientScript == nil {
+		payToAddr := payToAddrFunc(txBuilder.params)
+		recipientScript = func(recipient utxo.Recipient) ([]byte, error) {
+			return RecipientScript(recipient, MaxDataCarrierSize, payToAddr)
 		}
-		script, err := txscript.PayToAddrScript(addr)
+	}
+	for i, recipient := range recipients {
+		script, err := recipientScript(recipient)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("bad recipient %v: %v", i, err)
 		}
 		value := recipient.Value.Int().Int64()
 		if value < 0 {
@@ -66,6 +147,9 @@ func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipi
 		}
 		msgTx.AddTxOut(wire.NewTxOut(value, script))
 	}
+	if err := CheckNullDataOutputs(msgTx.TxOut); err != nil {
+		return nil, err
+	}
 
 	return &Tx{inputs: inputs, recipients: recipients, msgTx: msgTx, signed: false}, nil
 }
@@ -77,6 +161,7 @@ type Tx struct {
 	recipients []utxo.Recipient
 
 	msgTx *wire.MsgTx
+	fee   pack.U256
 
 	signed bool
 }
@@ -87,6 +172,12 @@ func (tx *Tx) Hash() (pack.Bytes, error) {
 	return pack.NewBytes(txhash[:]), nil
 }
 
+// Fee returns the implicit fee of the transaction: the difference in the sum
+// value of the inputs and the sum value of the outputs.
+func (tx *Tx) Fee() pack.U256 {
+	return tx.fee
+}
+
 // Inputs returns the UTXO inputs in the underlying transaction.
 func (tx *Tx) Inputs() ([]utxo.Input, error) {
 	return tx.inputs, nil
@@ -114,9 +205,15 @@ func (tx *Tx) Outputs() ([]utxo.Output, error) {
 }
 
 // Sighashes returns the digests that must be signed before the transaction
-// can be submitted by the client.
+// can be submitted by the client. The digest of each input depends on how it
+// spends the output that it consumes (see ClassifyInput). Inputs that spend
+// segwit outputs, including nested segwit outputs, use the BIP143 digest.
+// Inputs that spend P2TR outputs use the BIP341 digest with SIGHASH_DEFAULT,
+// and must be signed using Schnorr signatures (see SchnorrSign and
+// TaprootTweakPrivKey).
 func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 	sighashes := make([]pack.Bytes32, len(tx.inputs))
+	txSigHashes := txscript.NewTxSigHashes(tx.msgTx)
 
 	for i, txin := range tx.inputs {
 		pubKeyScript := txin.PubKeyScript
@@ -125,21 +222,23 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 		if value < 0 {
 			return []pack.Bytes32{}, fmt.Errorf("expected value >= 0, got value %v", value)
 		}
+		kind, err := ClassifyInput(txin)
+		if err != nil {
+			return []pack.Bytes32{}, fmt.Errorf("bad input %v: %v", i, err)
+		}
 
 		var hash []byte
-		var err error
-		if sigScript == nil {
-			if txscript.IsPayToWitnessPubKeyHash(pubKeyScript) {
-				hash, err = txscript.CalcWitnessSigHash(pubKeyScript, txscript.NewTxSigHashes(tx.msgTx), txscript.SigHashAll, tx.msgTx, i, value)
-			} else {
-				hash, err = txscript.CalcSignatureHash(pubKeyScript, txscript.SigHashAll, tx.msgTx, i)
-			}
-		} else {
-			if txscript.IsPayToWitnessScriptHash(pubKeyScript) {
-				hash, err = txscript.CalcWitnessSigHash(sigScript, txscript.NewTxSigHashes(tx.msgTx), txscript.SigHashAll, tx.msgTx, i, value)
-			} else {
-				hash, err = txscript.CalcSignatureHash(sigScript, txscript.SigHashAll, tx.msgTx, i)
-			}
+		switch kind {
+		case SpendP2TR:
+			hash, err = CalcTaprootSigHash(tx.msgTx, tx.prevOuts(), SigHashDefault, i)
+		case SpendP2WPKH:
+			hash, err = txscript.CalcWitnessSigHash(pubKeyScript, txSigHashes, txscript.SigHashAll, tx.msgTx, i, value)
+		case SpendP2WSH, SpendP2SHP2WPKH, SpendP2SHP2WSH:
+			hash, err = txscript.CalcWitnessSigHash(sigScript, txSigHashes, txscript.SigHashAll, tx.msgTx, i, value)
+		case SpendP2SH:
+			hash, err = txscript.CalcSignatureHash(sigScript, txscript.SigHashAll, tx.msgTx, i)
+		default:
+			hash, err = txscript.CalcSignatureHash(pubKeyScript, txscript.SigHashAll, tx.msgTx, i)
 		}
 		if err != nil {
 			return []pack.Bytes32{}, err
@@ -154,7 +253,10 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 }
 
 // Sign consumes a list of signatures, and adds them to the list of UTXOs in
-// the underlying transactions.
+// the underlying transactions. The signature script and witness of each input
+// are built based on how it spends the output that it consumes (see
+// SpendKind). For inputs that spend P2TR outputs, the first 64 bytes of the
+// signature must be a Schnorr signature, and the last byte is ignored.
 func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	if tx.signed {
 		return fmt.Errorf("already signed")
@@ -164,40 +266,19 @@ func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	}
 
 	for i, rsv := range signatures {
-		var err error
-
-		// Decode the signature and the pubkey script.
-		r := new(big.Int).SetBytes(rsv[:32])
-		s := new(big.Int).SetBytes(rsv[32:64])
-		signature := btcec.Signature{
-			R: r,
-			S: s,
-		}
-		pubKeyScript := tx.inputs[i].Output.PubKeyScript
-		sigScript := tx.inputs[i].SigScript
-
-		// Support segwit.
-		if sigScript == nil {
-			if txscript.IsPayToWitnessPubKeyHash(pubKeyScript) || txscript.IsPayToWitnessScriptHash(pubKeyScript) {
-				tx.msgTx.TxIn[i].Witness = wire.TxWitness([][]byte{append(signature.Serialize(), byte(txscript.SigHashAll)), pubKey})
-				continue
-			}
-		} else {
-			if txscript.IsPayToWitnessScriptHash(sigScript) || txscript.IsPayToWitnessScriptHash(sigScript) {
-				tx.msgTx.TxIn[i].Witness = wire.TxWitness([][]byte{append(signature.Serialize(), byte(txscript.SigHashAll)), pubKey, sigScript})
-				continue
-			}
+		kind, err := ClassifyInput(tx.inputs[i])
+		if err != nil {
+			return fmt.Errorf("bad input %v: %v", i, err)
 		}
 
-		// Support non-segwit
-		builder := txscript.NewScriptBuilder()
-		builder.AddData(append(signature.Serialize(), byte(txscript.SigHashAll)))
-		builder.AddData(pubKey)
-		if sigScript != nil {
-			builder.AddData(sigScript)
+		// Decode the signature.
+		var sig []byte
+		if kind == SpendP2TR {
+			sig = append([]byte{}, rsv[:SchnorrSignatureSize]...)
+		} else {
+			sig = serializeSignature(rsv)
 		}
-		tx.msgTx.TxIn[i].SignatureScript, err = builder.Script()
-		if err != nil {
+		if err := signInput(tx.msgTx.TxIn[i], kind, tx.inputs[i], sig, pubKey); err != nil {
 			return err
 		}
 	}
@@ -206,6 +287,26 @@ func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	return nil
 }
 
+// prevOuts returns the outputs spent by the inputs of the transaction.
+func (tx *Tx) prevOuts() []*wire.TxOut {
+	prevOuts := make([]*wire.TxOut, len(tx.inputs))
+	for i, input := range tx.inputs {
+		prevOuts[i] = wire.NewTxOut(input.Value.Int().Int64(), input.PubKeyScript)
+	}
+	return prevOuts
+}
+
+// SetSignatureScript sets the signature script of an input directly. This can
+// be used to spend outputs that cannot be signed by Sign, such as HTLC outputs,
+// using signatures over the sighashes returned by Sighashes.
+func (tx *Tx) SetSignatureScript(idx int, sigScript []byte) error {
+	if idx < 0 || idx >= len(tx.msgTx.TxIn) {
+		return fmt.Errorf("bad input %v: expected index < %v", idx, len(tx.msgTx.TxIn))
+	}
+	tx.msgTx.TxIn[idx].SignatureScript = sigScript
+	return nil
+}
+
 // Serialize serializes the UTXO transaction to bytes
 func (tx *Tx) Serialize() (pack.Bytes, error) {
 	buf := new(bytes.Buffer)
@@ -0,0 +1,345 @@
+package bitcoincash
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/pranav292gpt/zecutil/address"
+)
+
+const (
+	// cashAddrCharset is the charset used by CashAddr addresses. It is the
+	// same as the charset used by Bech32 addresses.
+	cashAddrCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	// cashAddrChecksumSize is the number of 5-bit groups in the checksum.
+	cashAddrChecksumSize = 8
+
+	// cashAddrTypeP2PKH and cashAddrTypeP2SH are the address types encoded
+	// in the version byte of a CashAddr address.
+	cashAddrTypeP2PKH = 0
+	cashAddrTypeP2SH  = 1
+	// cashAddrSize160 is the hash size encoded in the version byte of a
+	// CashAddr address with a 160-bit hash.
+	cashAddrSize160 = 0
+)
+
+// AddressEncodeDecoder implements the address.EncodeDecoder interface
+type AddressEncodeDecoder struct {
+	AddressEncoder
+	AddressDecoder
+}
+
+// NewAddressEncodeDecoder constructs a new AddressEncodeDecoder with the
+// chain specific configurations
+func NewAddressEncodeDecoder(params *Params) AddressEncodeDecoder {
+	return AddressEncodeDecoder{
+		AddressEncoder: NewAddressEncoder(params),
+		AddressDecoder: NewAddressDecoder(params),
+	}
+}
+
+// AddressEncoder encapsulates the chain specific configurations and implements
+// the address.Encoder interface
+type AddressEncoder struct {
+	params *Params
+}
+
+// NewAddressEncoder constructs a new AddressEncoder with the chain specific
+// configurations
+func NewAddressEncoder(params *Params) AddressEncoder {
+	return AddressEncoder{params: params}
+}
+
+// EncodeAddress implements the address.Encoder interface. Raw addresses are
+// the base58 decoding of legacy addresses (which is the same as for Bitcoin),
+// and are always encoded as CashAddr addresses.
+func (encoder AddressEncoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	if len(rawAddr) != 25 {
+		return address.Address(""), fmt.Errorf("non-exhaustive pattern: address length %v", len(rawAddr))
+	}
+	addr, err := decodeLegacyAddress(base58.Encode(rawAddr), encoder.params)
+	if err != nil {
+		return address.Address(""), err
+	}
+	return address.Address(addr.EncodeAddress()), nil
+}
+
+// AddressDecoder encapsulates the chain specific configurations and implements
+// the address.Decoder interface
+type AddressDecoder struct {
+	params *Params
+}
+
+// NewAddressDecoder constructs a new AddressDecoder with the chain specific
+// configurations
+func NewAddressDecoder(params *Params) AddressDecoder {
+	return AddressDecoder{params: params}
+}
+
+// DecodeAddress implements the address.Decoder interface. Both CashAddr and
+// legacy addresses are accepted.
+func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	decodedAddr, err := DecodeAddress(string(addr), decoder.params)
+	if err != nil {
+		return nil, fmt.Errorf("decode address: %v", err)
+	}
+	return address.RawAddress(base58.Decode(decodedAddr.BitcoinAddress().EncodeAddress())), nil
+}
+
+// An Address represents a Bitcoin Cash address.
+type Address interface {
+	btcutil.Address
+	BitcoinAddress() btcutil.Address
+}
+
+// AddressPubKeyHash represents an address for P2PKH transactions for Bitcoin
+// Cash that is compatible with the Bitcoin Compat API.
+type AddressPubKeyHash struct {
+	*btcutil.AddressPubKeyHash
+	params *Params
+}
+
+// NewAddressPubKeyHash returns a new AddressPubKeyHash that is compatible with
+// the Bitcoin Compat API.
+func NewAddressPubKeyHash(pkh []byte, params *Params) (AddressPubKeyHash, error) {
+	addr, err := btcutil.NewAddressPubKeyHash(pkh, params.Params)
+	return AddressPubKeyHash{AddressPubKeyHash: addr, params: params}, err
+}
+
+// String returns the CashAddr encoding of the address.
+func (addr AddressPubKeyHash) String() string {
+	return addr.EncodeAddress()
+}
+
+// EncodeAddress returns the CashAddr encoding of the address, including the
+// prefix.
+func (addr AddressPubKeyHash) EncodeAddress() string {
+	return encodeCashAddr(addr.params.CashAddrPrefix, cashAddrTypeP2PKH<<3|cashAddrSize160, addr.AddressPubKeyHash.ScriptAddress())
+}
+
+// EncodeLegacyAddress returns the legacy base58 encoding of the address.
+func (addr AddressPubKeyHash) EncodeLegacyAddress() string {
+	return addr.AddressPubKeyHash.EncodeAddress()
+}
+
+// ScriptAddress returns the raw bytes of the address to be used when inserting
+// the address into a txout's script.
+func (addr AddressPubKeyHash) ScriptAddress() []byte {
+	return addr.AddressPubKeyHash.ScriptAddress()
+}
+
+// IsForNet returns whether or not the address is associated with the passed
+// bitcoin network.
+func (addr AddressPubKeyHash) IsForNet(params *chaincfg.Params) bool {
+	return addr.AddressPubKeyHash.IsForNet(params)
+}
+
+// BitcoinAddress returns the address as if it was a Bitcoin address.
+func (addr AddressPubKeyHash) BitcoinAddress() btcutil.Address {
+	return addr.AddressPubKeyHash
+}
+
+// AddressScriptHash represents an address for P2SH transactions for Bitcoin
+// Cash that is compatible with the Bitcoin Compat API.
+type AddressScriptHash struct {
+	*btcutil.AddressScriptHash
+	params *Params
+}
+
+// NewAddressScriptHash returns a new AddressScriptHash that is compatible with
+// the Bitcoin Compat API.
+func NewAddressScriptHash(script []byte, params *Params) (AddressScriptHash, error) {
+	addr, err := btcutil.NewAddressScriptHash(script, params.Params)
+	return AddressScriptHash{AddressScriptHash: addr, params: params}, err
+}
+
+// NewAddressScriptHashFromHash returns a new AddressScriptHash that is
+// compatible with the Bitcoin Compat API.
+func NewAddressScriptHashFromHash(scriptHash []byte, params *Params) (AddressScriptHash, error) {
+	addr, err := btcutil.NewAddressScriptHashFromHash(scriptHash, params.Params)
+	return AddressScriptHash{AddressScriptHash: addr, params: params}, err
+}
+
+// String returns the CashAddr encoding of the address.
+func (addr AddressScriptHash) String() string {
+	return addr.EncodeAddress()
+}
+
+// EncodeAddress returns the CashAddr encoding of the address, including the
+// prefix.
+func (addr AddressScriptHash) EncodeAddress() string {
+	return encodeCashAddr(addr.params.CashAddrPrefix, cashAddrTypeP2SH<<3|cashAddrSize160, addr.AddressScriptHash.ScriptAddress())
+}
+
+// EncodeLegacyAddress returns the legacy base58 encoding of the address.
+func (addr AddressScriptHash) EncodeLegacyAddress() string {
+	return addr.AddressScriptHash.EncodeAddress()
+}
+
+// ScriptAddress returns the raw bytes of the address to be used when inserting
+// the address into a txout's script.
+func (addr AddressScriptHash) ScriptAddress() []byte {
+	return addr.AddressScriptHash.ScriptAddress()
+}
+
+// IsForNet returns whether or not the address is associated with the passed
+// bitcoin network.
+func (addr AddressScriptHash) IsForNet(params *chaincfg.Params) bool {
+	return addr.AddressScriptHash.IsForNet(params)
+}
+
+// BitcoinAddress returns the address as if it was a Bitcoin address.
+func (addr AddressScriptHash) BitcoinAddress() btcutil.Address {
+	return addr.AddressScriptHash
+}
+
+// DecodeAddress decodes a CashAddr address, or a legacy base58 address, for
+// the given network. The prefix of a CashAddr address can be omitted, in which
+// case the prefix of the network is assumed.
+func DecodeAddress(addr string, params *Params) (Address, error) {
+	cashAddr, cashAddrErr := decodeCashAddrAddress(addr, params)
+	if cashAddrErr == nil {
+		return cashAddr, nil
+	}
+	if strings.Contains(addr, ":") {
+		return nil, cashAddrErr
+	}
+	legacyAddr, err := decodeLegacyAddress(addr, params)
+	if err != nil {
+		return nil, fmt.Errorf("bad address: expected cashaddr (%v) or legacy (%v)", cashAddrErr, err)
+	}
+	return legacyAddr, nil
+}
+
+// decodeCashAddrAddress decodes a CashAddr address for the given network.
+func decodeCashAddrAddress(addr string, params *Params) (Address, error) {
+	version, hash, err := decodeCashAddr(params.CashAddrPrefix, addr)
+	if err != nil {
+		return nil, err
+	}
+	if version&0x07 != cashAddrSize160 || len(hash) != 20 {
+		return nil, fmt.Errorf("unsupported hash size: version %v with %v bytes", version, len(hash))
+	}
+	switch version >> 3 {
+	case cashAddrTypeP2PKH:
+		return NewAddressPubKeyHash(hash, params)
+	case cashAddrTypeP2SH:
+		return NewAddressScriptHashFromHash(hash, params)
+	default:
+		return nil, fmt.Errorf("unsupported address type %v", version>>3)
+	}
+}
+
+// decodeLegacyAddress decodes a legacy base58 address for the given network.
+func decodeLegacyAddress(addr string, params *Params) (Address, error) {
+	decodedAddr, err := btcutil.DecodeAddress(addr, params.Params)
+	if err != nil {
+		return nil, err
+	}
+	if !decodedAddr.IsForNet(params.Params) {
+		return nil, fmt.Errorf("address %v is not for %v", addr, params.Name)
+	}
+	switch a := decodedAddr.(type) {
+	case *btcutil.AddressPubKeyHash:
+		return AddressPubKeyHash{AddressPubKeyHash: a, params: params}, nil
+	case *btcutil.AddressScriptHash:
+		return AddressScriptHash{AddressScriptHash: a, params: params}, nil
+	default:
+		return nil, fmt.Errorf("non-exhaustive pattern: address %T", a)
+	}
+}
+
+// cashAddrPolymod computes the CashAddr checksum of a sequence of 5-bit
+// groups. See
+// https://github.com/bitcoincashorg/bitcoincash.org/blob/master/spec/cashaddr.md.
+func cashAddrPolymod(values []byte) uint64 {
+	generators := [5]uint64{0x98f2bc8e61, 0x79b76d99e2, 0xf33e5fb3c4, 0xae2eabe2a8, 0x1e4f43e470}
+	chk := uint64(1)
+	for _, v := range values {
+		top := chk >> 35
+		chk = ((chk & 0x07ffffffff) << 5) ^ uint64(v)
+		for i, g := range generators {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk ^ 1
+}
+
+// cashAddrPrefixValues returns the lower 5 bits of each character of the
+// prefix, followed by a zero for the separator.
+func cashAddrPrefixValues(prefix string) []byte {
+	values := make([]byte, 0, len(prefix)+1)
+	for i := 0; i < len(prefix); i++ {
+		values = append(values, prefix[i]&0x1f)
+	}
+	return append(values, 0)
+}
+
+// encodeCashAddr encodes the version byte and hash as a CashAddr address with
+// the given prefix.
+func encodeCashAddr(prefix string, version byte, hash []byte) string {
+	payload, err := bech32.ConvertBits(append([]byte{version}, hash...), 8, 5, true)
+	if err != nil {
+		// Converting from 8-bit groups with padding cannot fail.
+		panic(err)
+	}
+	values := append(cashAddrPrefixValues(prefix), payload...)
+	mod := cashAddrPolymod(append(values, make([]byte, cashAddrChecksumSize)...))
+	for i := 0; i < cashAddrChecksumSize; i++ {
+		payload = append(payload, byte(mod>>uint(5*(cashAddrChecksumSize-1-i)))&0x1f)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(prefix)
+	builder.WriteByte(':')
+	for _, v := range payload {
+		builder.WriteByte(cashAddrCharset[v])
+	}
+	return builder.String()
+}
+
+// decodeCashAddr decodes a CashAddr address, and returns its version byte and
+// hash. The prefix of the address must match the given prefix, but can be
+// omitted.
+func decodeCashAddr(prefix string, addr string) (byte, []byte, error) {
+	if strings.ToLower(addr) != addr && strings.ToUpper(addr) != addr {
+		return 0, nil, fmt.Errorf("bad cashaddr: mixed case")
+	}
+	addr = strings.ToLower(addr)
+	if i := strings.LastIndexByte(addr, ':'); i >= 0 {
+		if addr[:i] != prefix {
+			return 0, nil, fmt.Errorf("bad cashaddr: expected prefix %v, got prefix %v", prefix, addr[:i])
+		}
+		addr = addr[i+1:]
+	}
+	if len(addr) <= cashAddrChecksumSize {
+		return 0, nil, fmt.Errorf("bad cashaddr: too short")
+	}
+
+	payload := make([]byte, len(addr))
+	for i := 0; i < len(addr); i++ {
+		v := strings.IndexByte(cashAddrCharset, addr[i])
+		if v < 0 {
+			return 0, nil, fmt.Errorf("bad cashaddr: bad character %q", addr[i])
+		}
+		payload[i] = byte(v)
+	}
+	if cashAddrPolymod(append(cashAddrPrefixValues(prefix), payload...)) != 0 {
+		return 0, nil, fmt.Errorf("bad cashaddr: bad checksum")
+	}
+
+	data, err := bech32.ConvertBits(payload[:len(payload)-cashAddrChecksumSize], 5, 8, false)
+	if err != nil {
+		return 0, nil, fmt.Errorf("bad cashaddr: %v", err)
+	}
+	if len(data) == 0 {
+		return 0, nil, fmt.Errorf("bad cashaddr: missing version")
+	}
+	return data[0], data[1:], nil
+}
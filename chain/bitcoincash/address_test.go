@@ -0,0 +1,93 @@
+package bitcoincash_test
+
+import (
+	"strings"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/bitcoincash"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bitcoin Cash Address", func() {
+	Context("when encoding and decoding addresses", func() {
+		// Test vectors from
+		// https://github.com/bitcoincashorg/bitcoincash.org/blob/master/spec/cashaddr.md.
+		vectors := []struct {
+			legacy   string
+			cashAddr string
+		}{
+			{"1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"},
+			{"1KXrWXciRDZUpQwQmuM1DbwsKDLYAYsVLR", "bitcoincash:qr95sy3j9xwd2ap32xkykttr4cvcu7as4y0qverfuy"},
+			{"16w1D5WRVKJuZUsSRzdLp9w3YGcgoxDXb", "bitcoincash:qqq3728yw0y47sqn6l2na30mcw6zm78dzqre909m2r"},
+			{"3CWFddi6m4ndiGyKqzYvsFYagqDLPVMTzC", "bitcoincash:ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq"},
+			{"3LDsS579y7sruadqu11beEJoTjdFiFCdX4", "bitcoincash:pr95sy3j9xwd2ap32xkykttr4cvcu7as4yc93ky28e"},
+			{"31nwvkZwyPdgzjBJZXfDmSWsC4ZLKpYyUw", "bitcoincash:pqq3728yw0y47sqn6l2na30mcw6zm78dzq5ucqzc37"},
+		}
+		addrEncodeDecoder := bitcoincash.NewAddressEncodeDecoder(&bitcoincash.MainNetParams)
+
+		for _, vector := range vectors {
+			vector := vector
+			It("should convert "+vector.legacy, func() {
+				rawAddr, err := addrEncodeDecoder.DecodeAddress(address.Address(vector.cashAddr))
+				Expect(err).ToNot(HaveOccurred())
+				legacyRawAddr, err := addrEncodeDecoder.DecodeAddress(address.Address(vector.legacy))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rawAddr).To(Equal(legacyRawAddr))
+
+				encodedAddr, err := addrEncodeDecoder.EncodeAddress(rawAddr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(encodedAddr).To(Equal(address.Address(vector.cashAddr)))
+
+				// The prefix is optional, and upper case is allowed.
+				withoutPrefix := strings.TrimPrefix(vector.cashAddr, "bitcoincash:")
+				for _, addr := range []string{withoutPrefix, strings.ToUpper(vector.cashAddr)} {
+					decoded, err := bitcoincash.DecodeAddress(addr, &bitcoincash.MainNetParams)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(decoded.EncodeAddress()).To(Equal(vector.cashAddr))
+					Expect(decoded.BitcoinAddress().EncodeAddress()).To(Equal(vector.legacy))
+				}
+			})
+		}
+
+		It("should use the prefix of the network", func() {
+			hash := btcutil.Hash160([]byte("pubkey"))
+			for _, params := range []*bitcoincash.Params{&bitcoincash.TestNet3Params, &bitcoincash.RegressionNetParams} {
+				addr, err := bitcoincash.NewAddressPubKeyHash(hash, params)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(addr.EncodeAddress()).To(HavePrefix(params.CashAddrPrefix + ":q"))
+
+				addrEncodeDecoder := bitcoincash.NewAddressEncodeDecoder(params)
+				rawAddr, err := addrEncodeDecoder.DecodeAddress(address.Address(addr.EncodeAddress()))
+				Expect(err).ToNot(HaveOccurred())
+				encodedAddr, err := addrEncodeDecoder.EncodeAddress(rawAddr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(encodedAddr).To(Equal(address.Address(addr.EncodeAddress())))
+				Expect(addr.EncodeLegacyAddress()).To(Equal(addr.BitcoinAddress().EncodeAddress()))
+			}
+		})
+
+		It("should reject invalid addresses", func() {
+			for _, addr := range []string{
+				// Bad checksum.
+				"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6b",
+				// Mixed case.
+				"bitcoincash:Qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a",
+				// Wrong prefix.
+				"bchtest:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a",
+				// Bad character.
+				"bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6o",
+				// Legacy address for another network.
+				"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+				// Segwit address.
+				"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+				"",
+			} {
+				_, err := addrEncodeDecoder.DecodeAddress(address.Address(addr))
+				Expect(err).To(HaveOccurred(), addr)
+			}
+		})
+	})
+})
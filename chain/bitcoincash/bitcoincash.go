@@ -0,0 +1,53 @@
+package bitcoincash
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// SigHashForkID is the sighash flag that Bitcoin Cash requires all signatures
+// to set. Signatures with this flag commit to a BIP143-style digest, which
+// includes the value of the output being spent, and are invalid on the Bitcoin
+// network (and vice versa), which protects against replay attacks.
+const SigHashForkID = txscript.SigHashType(0x40)
+
+// Params signifies the chain specific parameters of the Bitcoin Cash network.
+type Params struct {
+	// The embedded chaincfg params define the base58 prefixes of legacy
+	// addresses, which are the same as those used by Bitcoin.
+	*chaincfg.Params
+
+	// CashAddrPrefix is the human-readable prefix of CashAddr addresses.
+	CashAddrPrefix string
+
+	// HDCoinType is the BIP44 coin type used when deriving hierarchical
+	// deterministic keys. It shadows the coin type of the embedded chaincfg
+	// params, which belongs to Bitcoin.
+	HDCoinType uint32
+}
+
+var (
+	// MainNetParams defines the mainnet configuration.
+	MainNetParams = Params{
+		Params: &chaincfg.MainNetParams,
+
+		CashAddrPrefix: "bitcoincash",
+		HDCoinType:     145,
+	}
+
+	// TestNet3Params defines the testnet configuration.
+	TestNet3Params = Params{
+		Params: &chaincfg.TestNet3Params,
+
+		CashAddrPrefix: "bchtest",
+		HDCoinType:     1,
+	}
+
+	// RegressionNetParams defines a devet/regnet configuration.
+	RegressionNetParams = Params{
+		Params: &chaincfg.RegressionNetParams,
+
+		CashAddrPrefix: "bchreg",
+		HDCoinType:     1,
+	}
+)
@@ -0,0 +1,60 @@
+package bitcoincash_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pranav292gpt/zecutil/chain/bitcoincash"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// feeClient is a client that only implements fee estimation. All other methods
+// panic.
+type feeClient struct {
+	bitcoincash.Client
+
+	feeRate   float64
+	err       error
+	numBlocks int64
+}
+
+func (client *feeClient) EstimateFeeLegacy(ctx context.Context, numBlocks int64) (float64, error) {
+	client.numBlocks = numBlocks
+	return client.feeRate, client.err
+}
+
+var _ = Describe("Gas", func() {
+	Context("when estimating bitcoin cash network fee", func() {
+		fallback := pack.NewU256FromUint64(123)
+
+		It("should convert BCH-per-kilobyte to SATs-per-byte", func() {
+			client := &feeClient{feeRate: 0.00001}
+			gasPrice, gasCap, err := bitcoincash.NewGasEstimator(client, 0, fallback).EstimateGas(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.numBlocks).To(Equal(int64(0)))
+			Expect(gasPrice).To(Equal(pack.NewU256FromUint64(1)))
+			Expect(gasCap).To(Equal(gasPrice))
+
+			client = &feeClient{feeRate: 0.0005}
+			gasPrice, _, err = bitcoincash.NewGasEstimator(client, 6, fallback).EstimateGas(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.numBlocks).To(Equal(int64(6)))
+			Expect(gasPrice).To(Equal(pack.NewU256FromUint64(49)))
+		})
+
+		It("should return the fallback gas if estimation fails", func() {
+			for _, client := range []*feeClient{
+				{err: fmt.Errorf("insufficient data")},
+				{feeRate: -1},
+			} {
+				gasPrice, gasCap, err := bitcoincash.NewGasEstimator(client, 1, fallback).EstimateGas(context.Background())
+				Expect(err).To(HaveOccurred())
+				Expect(gasPrice).To(Equal(fallback))
+				Expect(gasCap).To(Equal(fallback))
+			}
+		})
+	})
+})
@@ -0,0 +1,65 @@
+package bitcoincash
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+// MaxDataCarrierSize is the maximum number of bytes that can be committed to
+// by a null-data output. The relay policy of Bitcoin Cash nodes allows
+// null-data scripts of up to 223 bytes, which leaves 220 bytes for the data
+// after the OP_RETURN and OP_PUSHDATA1 opcodes.
+const MaxDataCarrierSize = 220
+
+// NullDataScript returns a null-data (OP_RETURN) pubkey script that commits to
+// the data. The data must be no larger than MaxDataCarrierSize.
+func NullDataScript(data []byte) ([]byte, error) {
+	return bitcoin.NullDataScript(data, MaxDataCarrierSize)
+}
+
+// RecipientScript returns the pubkey script of the output that will be produced
+// for the recipient. Bitcoin Cash does not support segregated witness, so
+// scripts with witness programs are rejected. See bitcoin.RecipientScript.
+func RecipientScript(recipient utxo.Recipient, params *Params) ([]byte, error) {
+	script, err := bitcoin.RecipientScript(recipient, MaxDataCarrierSize, payToAddrFunc(params))
+	if err != nil {
+		return nil, err
+	}
+	if txscript.IsWitnessProgram(script) {
+		return nil, fmt.Errorf("bad script: witness programs are not supported")
+	}
+	return script, nil
+}
+
+// RecipientFromOutput returns the recipient for which the output was produced.
+// Addresses are returned in the CashAddr format. This is the inverse of
+// RecipientScript.
+func RecipientFromOutput(output utxo.Output, params *Params) utxo.Recipient {
+	if data, ok := bitcoin.ExtractNullData(output.PubKeyScript); ok {
+		return utxo.Recipient{Value: output.Value, Data: pack.Bytes(data)}
+	}
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(output.PubKeyScript, params.Params)
+	if err == nil && (class == txscript.PubKeyHashTy || class == txscript.ScriptHashTy) && len(addrs) == 1 {
+		if addr, err := DecodeAddress(addrs[0].EncodeAddress(), params); err == nil {
+			return utxo.Recipient{Value: output.Value, To: address.Address(addr.EncodeAddress())}
+		}
+	}
+	return utxo.Recipient{Value: output.Value, Script: output.PubKeyScript}
+}
+
+// payToAddrFunc returns a function that converts CashAddr and legacy addresses
+// to pubkey scripts.
+func payToAddrFunc(params *Params) func(address.Address) ([]byte, error) {
+	return func(to address.Address) ([]byte, error) {
+		addr, err := DecodeAddress(string(to), params)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.PayToAddrScript(addr.BitcoinAddress())
+	}
+}
@@ -0,0 +1,291 @@
+package bitcoincash
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+// Version of Bitcoin Cash transactions supported by the multichain.
+const Version int32 = 2
+
+// ClientOptions are used to parameterise the behaviour of the Client.
+type ClientOptions = bitcoin.ClientOptions
+
+// DefaultClientOptions returns ClientOptions with the default settings. These
+// settings are valid for use with the default local deployment of the
+// multichain. In production, the host, user, and password should be changed.
+func DefaultClientOptions() ClientOptions {
+	return bitcoin.DefaultClientOptions().WithHost("http://127.0.0.1:18443")
+}
+
+// Client re-exports bitcoin.Client.
+type Client = bitcoin.Client
+
+// NewClient re-exports bitcoin.NewClient.
+var NewClient = bitcoin.NewClient
+
+// GasEstimator re-exports bitcoin.GasEstimator.
+type GasEstimator = bitcoin.GasEstimator
+
+// NewGasEstimator re-exports bitcoin.NewLegacyGasEstimator. Bitcoin Cash nodes
+// do not support the `estimatesmartfee` RPC call, so the estimator calls
+// `estimatefee`. If `numBlocks` is zero, then `estimatefee` is called without
+// arguments, which is required by Bitcoin Cash Node.
+var NewGasEstimator = bitcoin.NewLegacyGasEstimator
+
+// FeePolicy re-exports bitcoin.FeePolicy.
+type FeePolicy = bitcoin.FeePolicy
+
+// DefaultFeePolicy re-exports bitcoin.DefaultFeePolicy.
+var DefaultFeePolicy = bitcoin.DefaultFeePolicy
+
+// The TxBuilder is an implementation of a UTXO-compatible transaction builder
+// for Bitcoin Cash.
+type TxBuilder struct {
+	params    *Params
+	feePolicy FeePolicy
+	lockTime  uint32
+	sequence  uint32
+}
+
+// NewTxBuilder returns a transaction builder that builds UTXO-compatible
+// Bitcoin Cash transactions for the given chain configuration.
+func NewTxBuilder(params *Params) TxBuilder {
+	return TxBuilder{params: params, feePolicy: DefaultFeePolicy(), sequence: bitcoin.SequenceFinal}
+}
+
+// WithFeePolicy returns a copy of the transaction builder that checks built
+// transactions against the given fee policy.
+func (txBuilder TxBuilder) WithFeePolicy(feePolicy FeePolicy) TxBuilder {
+	txBuilder.feePolicy = feePolicy
+	return txBuilder
+}
+
+// WithLockTime returns a copy of the transaction builder that sets the lock
+// time (nLockTime) of built transactions. It is only enforced if at least one
+// input has a non-final sequence number (see WithSequence).
+func (txBuilder TxBuilder) WithLockTime(lockTime uint32) TxBuilder {
+	txBuilder.lockTime = lockTime
+	return txBuilder
+}
+
+// WithSequence returns a copy of the transaction builder that sets the
+// sequence number (nSequence) of all inputs of built transactions. By default,
+// inputs have the final sequence number.
+func (txBuilder TxBuilder) WithSequence(sequence uint32) TxBuilder {
+	txBuilder.sequence = sequence
+	return txBuilder
+}
+
+// BuildTx returns a Bitcoin Cash transaction that consumes funds from the
+// given inputs, and sends them to the given recipients. The difference in the
+// sum value of the inputs and the sum value of the recipients is paid as a fee
+// to the Bitcoin Cash network. This fee is checked against the fee policy of
+// the builder, and an error is returned if it is out of bounds.
+//
+// Inputs must spend P2PKH outputs, or P2SH outputs with the redeem script as
+// the sig script. Outputs produced for recipients will use P2PKH, or P2SH
+// scripts as the pubkey script, based on the recipient address, which can be a
+// CashAddr or legacy address. Recipients can instead specify a standard pubkey
+// script, or data to be committed to by a null-data output (see
+// RecipientScript).
+func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipient) (utxo.Tx, error) {
+	msgTx := wire.NewMsgTx(Version)
+
+	// Inputs
+	for _, input := range inputs {
+		hash := chainhash.Hash{}
+		copy(hash[:], input.Hash)
+		index := input.Index.Uint32()
+		txIn := wire.NewTxIn(wire.NewOutPoint(&hash, index), nil, nil)
+		txIn.Sequence = txBuilder.sequence
+		msgTx.AddTxIn(txIn)
+	}
+	msgTx.LockTime = txBuilder.lockTime
+
+	// Outputs
+	for i, recipient := range recipients {
+		script, err := RecipientScript(recipient, txBuilder.params)
+		if err != nil {
+			return nil, fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+		value := recipient.Value.Int().Int64()
+		if value < 0 {
+			return nil, fmt.Errorf("expected value >= 0, got value %v", value)
+		}
+		msgTx.AddTxOut(wire.NewTxOut(value, script))
+	}
+	if err := bitcoin.CheckNullDataOutputs(msgTx.TxOut); err != nil {
+		return nil, err
+	}
+
+	tx := &Tx{inputs: inputs, recipients: recipients, msgTx: msgTx, signed: false}
+	fee, err := txBuilder.feePolicy.Check(inputs, msgTx.TxOut, tx.EstimateSize())
+	if err != nil {
+		return nil, err
+	}
+	tx.fee = fee
+	return tx, nil
+}
+
+// Tx represents a simple Bitcoin Cash transaction that implements the Bitcoin
+// Compat API.
+type Tx struct {
+	inputs     []utxo.Input
+	recipients []utxo.Recipient
+
+	msgTx *wire.MsgTx
+	fee   pack.U256
+
+	signed bool
+}
+
+// Hash returns the transaction hash of the given underlying transaction.
+func (tx *Tx) Hash() (pack.Bytes, error) {
+	txhash := tx.msgTx.TxHash()
+	return pack.NewBytes(txhash[:]), nil
+}
+
+// Fee returns the implicit fee of the transaction: the difference in the sum
+// value of the inputs and the sum value of the outputs.
+func (tx *Tx) Fee() pack.U256 {
+	return tx.fee
+}
+
+// EstimateSize returns the estimated size of the transaction (in bytes) once
+// all of its inputs have been signed. If the transaction has already been
+// signed, then its actual size is returned.
+func (tx *Tx) EstimateSize() int {
+	size := tx.msgTx.SerializeSize()
+	if tx.signed {
+		return size
+	}
+	for i, input := range tx.inputs {
+		sigScriptSize, _ := bitcoin.EstimateInputSize(input)
+		current := len(tx.msgTx.TxIn[i].SignatureScript)
+		size += sigScriptSize - current + wire.VarIntSerializeSize(uint64(sigScriptSize)) - wire.VarIntSerializeSize(uint64(current))
+	}
+	return size
+}
+
+// Inputs returns the UTXO inputs in the underlying transaction.
+func (tx *Tx) Inputs() ([]utxo.Input, error) {
+	return tx.inputs, nil
+}
+
+// Outputs returns the UTXO outputs in the underlying transaction.
+func (tx *Tx) Outputs() ([]utxo.Output, error) {
+	hash, err := tx.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("bad hash: %v", err)
+	}
+	outputs := make([]utxo.Output, len(tx.msgTx.TxOut))
+	for i := range outputs {
+		outputs[i].Outpoint = utxo.Outpoint{
+			Hash:  hash,
+			Index: pack.NewU32(uint32(i)),
+		}
+		outputs[i].PubKeyScript = pack.Bytes(tx.msgTx.TxOut[i].PkScript)
+		if tx.msgTx.TxOut[i].Value < 0 {
+			return nil, fmt.Errorf("bad output %v: value is less than zero", i)
+		}
+		outputs[i].Value = pack.NewU256FromU64(pack.NewU64(uint64(tx.msgTx.TxOut[i].Value)))
+	}
+	return outputs, nil
+}
+
+// Sighashes returns the digests that must be signed before the transaction
+// can be submitted by the client. Bitcoin Cash signatures must set
+// SigHashForkID, so the digests are computed using the BIP143 algorithm (even
+// though Bitcoin Cash does not support segregated witness), which commits to
+// the value of each input. The script code is the sig script of the input, if
+// there is one, or the pubkey script of the input.
+func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
+	sighashes := make([]pack.Bytes32, len(tx.inputs))
+	txSigHashes := txscript.NewTxSigHashes(tx.msgTx)
+
+	for i, txin := range tx.inputs {
+		scriptCode := []byte(txin.PubKeyScript)
+		if txin.SigScript != nil {
+			scriptCode = txin.SigScript
+		}
+		value := txin.Value.Int().Int64()
+		if value < 0 {
+			return []pack.Bytes32{}, fmt.Errorf("expected value >= 0, got value %v", value)
+		}
+
+		hash, err := txscript.CalcWitnessSigHash(scriptCode, txSigHashes, txscript.SigHashAll|SigHashForkID, tx.msgTx, i, value)
+		if err != nil {
+			return []pack.Bytes32{}, err
+		}
+
+		sighash := [32]byte{}
+		copy(sighash[:], hash)
+		sighashes[i] = pack.NewBytes32(sighash)
+	}
+	return sighashes, nil
+}
+
+// Sign consumes a list of signatures, and adds them to the list of UTXOs in
+// the underlying transactions. The sighash type appended to each signature is
+// SIGHASH_ALL|SIGHASH_FORKID.
+func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
+	if tx.signed {
+		return fmt.Errorf("already signed")
+	}
+	if len(signatures) != len(tx.msgTx.TxIn) {
+		return fmt.Errorf("expected %v signatures, got %v signatures", len(tx.msgTx.TxIn), len(signatures))
+	}
+
+	for i, rsv := range signatures {
+		r := new(big.Int).SetBytes(rsv[:32])
+		s := new(big.Int).SetBytes(rsv[32:64])
+		signature := btcec.Signature{
+			R: r,
+			S: s,
+		}
+
+		builder := txscript.NewScriptBuilder()
+		builder.AddData(append(signature.Serialize(), byte(txscript.SigHashAll|SigHashForkID)))
+		builder.AddData(pubKey)
+		if tx.inputs[i].SigScript != nil {
+			builder.AddData(tx.inputs[i].SigScript)
+		}
+		signatureScript, err := builder.Script()
+		if err != nil {
+			return err
+		}
+		tx.msgTx.TxIn[i].SignatureScript = signatureScript
+	}
+	tx.signed = true
+	return nil
+}
+
+// SetSignatureScript sets the signature script of an input directly. This can
+// be used to spend outputs that cannot be signed by Sign, such as HTLC outputs,
+// using signatures over the sighashes returned by Sighashes.
+func (tx *Tx) SetSignatureScript(idx int, sigScript []byte) error {
+	if idx < 0 || idx >= len(tx.msgTx.TxIn) {
+		return fmt.Errorf("bad input %v: expected index < %v", idx, len(tx.msgTx.TxIn))
+	}
+	tx.msgTx.TxIn[idx].SignatureScript = sigScript
+	return nil
+}
+
+// Serialize serializes the UTXO transaction to bytes
+func (tx *Tx) Serialize() (pack.Bytes, error) {
+	buf := new(bytes.Buffer)
+	if err := tx.msgTx.SerializeNoWitness(buf); err != nil {
+		return pack.Bytes{}, err
+	}
+	return pack.NewBytes(buf.Bytes()), nil
+}
@@ -0,0 +1,164 @@
+package bitcoincash_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoincash"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// forkIDSighash computes the BIP143-style digest with SIGHASH_ALL|FORKID
+// directly from its specification, for a transaction with one input.
+func forkIDSighash(msgTx *wire.MsgTx, scriptCode []byte, value int64) []byte {
+	Expect(msgTx.TxIn).To(HaveLen(1))
+	txIn := msgTx.TxIn[0]
+
+	outpoint := new(bytes.Buffer)
+	outpoint.Write(txIn.PreviousOutPoint.Hash[:])
+	Expect(binary.Write(outpoint, binary.LittleEndian, txIn.PreviousOutPoint.Index)).To(Succeed())
+	sequence := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sequence, txIn.Sequence)
+	outputs := new(bytes.Buffer)
+	for _, txOut := range msgTx.TxOut {
+		Expect(wire.WriteTxOut(outputs, 0, 0, txOut)).To(Succeed())
+	}
+
+	preimage := new(bytes.Buffer)
+	Expect(binary.Write(preimage, binary.LittleEndian, msgTx.Version)).To(Succeed())
+	preimage.Write(chainhash.DoubleHashB(outpoint.Bytes()))
+	preimage.Write(chainhash.DoubleHashB(sequence))
+	preimage.Write(outpoint.Bytes())
+	Expect(wire.WriteVarBytes(preimage, 0, scriptCode)).To(Succeed())
+	Expect(binary.Write(preimage, binary.LittleEndian, value)).To(Succeed())
+	preimage.Write(sequence)
+	preimage.Write(chainhash.DoubleHashB(outputs.Bytes()))
+	Expect(binary.Write(preimage, binary.LittleEndian, msgTx.LockTime)).To(Succeed())
+	Expect(binary.Write(preimage, binary.LittleEndian, uint32(0x41))).To(Succeed())
+	return chainhash.DoubleHashB(preimage.Bytes())
+}
+
+var _ = Describe("Bitcoin Cash Tx", func() {
+	params := &bitcoincash.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		panic(err)
+	}
+	key := signer.NewKeySigner(privKey, true)
+	pubKeyHash := btcutil.Hash160(key.PubKey())
+	pkhAddr, err := bitcoincash.NewAddressPubKeyHash(pubKeyHash, params)
+	if err != nil {
+		panic(err)
+	}
+	pkhScript, err := txscript.PayToAddrScript(pkhAddr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+	// The redeem script of the P2SH output is spent by a signature and a
+	// pubkey.
+	redeemScript := pkhScript
+	shAddr, err := bitcoincash.NewAddressScriptHash(redeemScript, params)
+	if err != nil {
+		panic(err)
+	}
+	shScript, err := txscript.PayToAddrScript(shAddr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+
+	for _, input := range []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(1)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pkhScript}},
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(2)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: shScript}, SigScript: redeemScript},
+	} {
+		input := input
+		scriptCode := []byte(input.PubKeyScript)
+		if input.SigScript != nil {
+			scriptCode = input.SigScript
+		}
+
+		Context("when spending "+txscript.GetScriptClass(input.PubKeyScript).String(), func() {
+			It("should build, sign, and serialize transactions", func() {
+				recipients := []utxo.Recipient{
+					{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(50000)},
+					{To: address.Address(shAddr.EncodeLegacyAddress()), Value: pack.NewU256FromUint64(40000)},
+					{Data: pack.Bytes("memo"), Value: pack.NewU256FromUint64(0)},
+				}
+				tx, err := bitcoincash.NewTxBuilder(params).WithLockTime(100).BuildTx([]utxo.Input{input}, recipients)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.(*bitcoincash.Tx).Fee()).To(Equal(pack.NewU256FromUint64(10000)))
+
+				// Outputs are returned to CashAddr recipients.
+				outputs, err := tx.Outputs()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(outputs).To(HaveLen(3))
+				for i, output := range outputs {
+					recipient := bitcoincash.RecipientFromOutput(output, params)
+					if i == 1 {
+						Expect(recipient.To).To(Equal(address.Address(shAddr.EncodeAddress())))
+						continue
+					}
+					Expect(recipient).To(Equal(recipients[i]))
+				}
+
+				// The sighash commits to the value of the input.
+				serialized, err := tx.Serialize()
+				Expect(err).ToNot(HaveOccurred())
+				msgTx := wire.NewMsgTx(bitcoincash.Version)
+				Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+				sighashes, err := tx.Sighashes()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sighashes).To(HaveLen(1))
+				Expect(sighashes[0][:]).To(Equal(forkIDSighash(msgTx, scriptCode, 100000)))
+				Expect(sighashes[0][:]).ToNot(Equal(forkIDSighash(msgTx, scriptCode, 100001)))
+				estimated := tx.(*bitcoincash.Tx).EstimateSize()
+
+				// Sign the transaction, and check the signature script.
+				Expect(signer.SignTx(context.Background(), key, tx)).To(Succeed())
+				Expect(tx.(*bitcoincash.Tx).EstimateSize()).To(BeNumerically("<=", estimated))
+				serialized, err = tx.Serialize()
+				Expect(err).ToNot(HaveOccurred())
+				msgTx = wire.NewMsgTx(bitcoincash.Version)
+				Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+				Expect(msgTx.LockTime).To(Equal(uint32(100)))
+				Expect(msgTx.HasWitness()).To(BeFalse())
+
+				pushes, err := txscript.PushedData(msgTx.TxIn[0].SignatureScript)
+				Expect(err).ToNot(HaveOccurred())
+				if input.SigScript != nil {
+					Expect(pushes).To(HaveLen(3))
+					Expect(pushes[2]).To(Equal(redeemScript))
+				} else {
+					Expect(pushes).To(HaveLen(2))
+				}
+				sig := pushes[0]
+				Expect(sig[len(sig)-1]).To(Equal(byte(txscript.SigHashAll | bitcoincash.SigHashForkID)))
+				Expect(pushes[1]).To(Equal([]byte(key.PubKey())))
+				parsedSig, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsedSig.Verify(sighashes[0][:], privKey.PubKey())).To(BeTrue())
+
+				Expect(tx.Sign([]pack.Bytes65{{}}, key.PubKey())).ToNot(Succeed())
+			})
+		})
+	}
+
+	It("should reject segwit recipients", func() {
+		witnessScript := append([]byte{txscript.OP_0, txscript.OP_DATA_20}, pubKeyHash...)
+		_, err := bitcoincash.RecipientScript(utxo.Recipient{Script: witnessScript, Value: pack.NewU256FromUint64(1000)}, params)
+		Expect(err).To(HaveOccurred())
+		_, err = bitcoincash.RecipientScript(utxo.Recipient{To: "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", Value: pack.NewU256FromUint64(1000)}, params)
+		Expect(err).To(HaveOccurred())
+	})
+})
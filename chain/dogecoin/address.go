@@ -0,0 +1,87 @@
+package dogecoin
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+)
+
+// AddressEncodeDecoder implements the address.EncodeDecoder interface
+type AddressEncodeDecoder struct {
+	AddressEncoder
+	AddressDecoder
+}
+
+// NewAddressEncodeDecoder constructs a new AddressEncodeDecoder with the
+// chain specific configurations
+func NewAddressEncodeDecoder(params *chaincfg.Params) AddressEncodeDecoder {
+	return AddressEncodeDecoder{
+		AddressEncoder: NewAddressEncoder(params),
+		AddressDecoder: NewAddressDecoder(params),
+	}
+}
+
+// AddressEncoder encapsulates the chain specific configurations and implements
+// the address.Encoder interface
+type AddressEncoder struct {
+	params *chaincfg.Params
+}
+
+// NewAddressEncoder constructs a new AddressEncoder with the chain specific
+// configurations
+func NewAddressEncoder(params *chaincfg.Params) AddressEncoder {
+	return AddressEncoder{params: params}
+}
+
+// EncodeAddress implements the address.Encoder interface. Raw addresses are
+// the base58 decoding of P2PKH and P2SH addresses.
+func (encoder AddressEncoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	if len(rawAddr) != 25 {
+		return address.Address(""), fmt.Errorf("non-exhaustive pattern: address length %v", len(rawAddr))
+	}
+	// Validate that the base58 address is in fact in correct format.
+	encodedAddr := base58.Encode([]byte(rawAddr))
+	if _, err := DecodeAddress(encodedAddr, encoder.params); err != nil {
+		return address.Address(""), err
+	}
+	return address.Address(encodedAddr), nil
+}
+
+// AddressDecoder encapsulates the chain specific configurations and implements
+// the address.Decoder interface
+type AddressDecoder struct {
+	params *chaincfg.Params
+}
+
+// NewAddressDecoder constructs a new AddressDecoder with the chain specific
+// configurations
+func NewAddressDecoder(params *chaincfg.Params) AddressDecoder {
+	return AddressDecoder{params: params}
+}
+
+// DecodeAddress implements the address.Decoder interface
+func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	if _, err := DecodeAddress(string(addr), decoder.params); err != nil {
+		return nil, fmt.Errorf("decode address: %v", err)
+	}
+	return address.RawAddress(base58.Decode(string(addr))), nil
+}
+
+// DecodeAddress decodes a P2PKH or P2SH address for the network. Addresses for
+// other networks (including Bitcoin) are rejected.
+func DecodeAddress(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	decodedAddr, err := bitcoin.DecodeAddressForNet(addr, params)
+	if err != nil {
+		return nil, err
+	}
+	switch decodedAddr.(type) {
+	case *btcutil.AddressPubKeyHash, *btcutil.AddressScriptHash:
+		return decodedAddr, nil
+	default:
+		return nil, fmt.Errorf("non-exhaustive pattern: address %T", decodedAddr)
+	}
+}
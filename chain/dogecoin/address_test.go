@@ -0,0 +1,60 @@
+package dogecoin_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/dogecoin"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dogecoin Address", func() {
+	hash := btcutil.Hash160([]byte("dogecoin"))
+
+	for _, params := range []*chaincfg.Params{&dogecoin.MainNetParams, &dogecoin.TestNet3Params, &dogecoin.RegressionNetParams} {
+		params := params
+		Context("when using "+params.Name, func() {
+			It("should encode and decode addresses", func() {
+				addrEncodeDecoder := dogecoin.NewAddressEncodeDecoder(params)
+				pkhAddr, err := btcutil.NewAddressPubKeyHash(hash, params)
+				Expect(err).ToNot(HaveOccurred())
+				shAddr, err := btcutil.NewAddressScriptHashFromHash(hash, params)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pkhAddr.EncodeAddress()).To(Equal(base58.CheckEncode(hash, params.PubKeyHashAddrID)))
+				Expect(shAddr.EncodeAddress()).To(Equal(base58.CheckEncode(hash, params.ScriptHashAddrID)))
+
+				for _, addr := range []btcutil.Address{pkhAddr, shAddr} {
+					rawAddr, err := addrEncodeDecoder.DecodeAddress(address.Address(addr.EncodeAddress()))
+					Expect(err).ToNot(HaveOccurred())
+					encodedAddr, err := addrEncodeDecoder.EncodeAddress(rawAddr)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(encodedAddr).To(Equal(address.Address(addr.EncodeAddress())))
+				}
+			})
+		})
+	}
+
+	It("should use the dogecoin prefixes on mainnet", func() {
+		pkhAddr, err := btcutil.NewAddressPubKeyHash(hash, &dogecoin.MainNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pkhAddr.EncodeAddress()).To(HavePrefix("D"))
+	})
+
+	It("should reject bitcoin and segwit addresses", func() {
+		addrEncodeDecoder := dogecoin.NewAddressEncodeDecoder(&dogecoin.MainNetParams)
+		for _, addr := range []string{
+			"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			"",
+		} {
+			_, err := addrEncodeDecoder.DecodeAddress(address.Address(addr))
+			Expect(err).To(HaveOccurred(), addr)
+		}
+		_, err := addrEncodeDecoder.EncodeAddress(address.RawAddress(append([]byte{0}, hash...)))
+		Expect(err).To(HaveOccurred())
+	})
+})
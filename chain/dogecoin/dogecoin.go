@@ -0,0 +1,52 @@
+package dogecoin
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Only the fields of the chaincfg params that are needed to encode addresses,
+// keys, and scripts are set. The params are not registered with chaincfg,
+// because the network magic of the Dogecoin regtest conflicts with that of the
+// Bitcoin regtest. Dogecoin does not support segregated witness, so the params
+// have no bech32 human-readable part.
+var (
+	// MainNetParams defines the mainnet configuration.
+	MainNetParams = chaincfg.Params{
+		Name:             "dogecoin-mainnet",
+		Net:              wire.BitcoinNet(0xc0c0c0c0),
+		DefaultPort:      "22556",
+		PubKeyHashAddrID: 0x1e, // starts with D
+		ScriptHashAddrID: 0x16, // starts with 9 or A
+		PrivateKeyID:     0x9e,
+		HDPrivateKeyID:   [4]byte{0x02, 0xfa, 0xc3, 0x98},
+		HDPublicKeyID:    [4]byte{0x02, 0xfa, 0xca, 0xfd},
+		HDCoinType:       3,
+	}
+
+	// TestNet3Params defines the testnet configuration.
+	TestNet3Params = chaincfg.Params{
+		Name:             "dogecoin-testnet3",
+		Net:              wire.BitcoinNet(0xdcb7c1fc),
+		DefaultPort:      "44556",
+		PubKeyHashAddrID: 0x71, // starts with n
+		ScriptHashAddrID: 0xc4, // starts with 2
+		PrivateKeyID:     0xf1,
+		HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+		HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+		HDCoinType:       1,
+	}
+
+	// RegressionNetParams defines a devet/regnet configuration.
+	RegressionNetParams = chaincfg.Params{
+		Name:             "dogecoin-regtest",
+		Net:              wire.BitcoinNet(0xdab5bffa),
+		DefaultPort:      "18444",
+		PubKeyHashAddrID: 0x6f, // starts with m or n
+		ScriptHashAddrID: 0xc4, // starts with 2
+		PrivateKeyID:     0xef,
+		HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+		HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+		HDCoinType:       1,
+	}
+)
@@ -0,0 +1,95 @@
+package dogecoin
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultFeeRate is the default fee rate (in koinu per 1000 bytes) of a
+	// transaction. This is the same as the recommended minimum fee rate of
+	// Dogecoin Core (0.01 DOGE per kilobyte).
+	DefaultFeeRate = 1000000
+	// DefaultMinRelayFeeRate is the minimum fee rate (in koinu per 1000
+	// bytes) that is relayed by the default relay policy of Dogecoin Core
+	// (0.001 DOGE per kilobyte).
+	DefaultMinRelayFeeRate = 100000
+	// DefaultSoftDustLimit is the default soft dust limit (in koinu) of
+	// Dogecoin Core. Every output below this value must pay an additional fee
+	// of this value.
+	DefaultSoftDustLimit = 1000000
+	// DefaultHardDustLimit is the default hard dust limit (in koinu) of
+	// Dogecoin Core. Outputs below this value are rejected by its relay policy.
+	DefaultHardDustLimit = 100000
+
+	// DefaultMaxFee is the default maximum absolute fee (in koinu) of a
+	// transaction. This is the same as the default -maxtxfee of Dogecoin Core.
+	DefaultMaxFee = 10000000000
+	// DefaultMaxFeeRate is the default maximum fee rate (in koinu-per-byte) of
+	// a transaction. Dogecoin fees are rounded up to whole kilobytes, and
+	// include a fee for every output below the soft dust limit, so small
+	// transactions pay a high rate per byte.
+	DefaultMaxFeeRate = 100000
+)
+
+// FeePolicy re-exports bitcoin.FeePolicy.
+type FeePolicy = bitcoin.FeePolicy
+
+// DefaultFeePolicy returns a FeePolicy with the default settings. These
+// settings match the default relay policy of Dogecoin Core, and reject outputs
+// below the hard dust limit.
+func DefaultFeePolicy() FeePolicy {
+	return FeePolicy{
+		MaxFee:        pack.NewU256FromUint64(DefaultMaxFee),
+		MaxFeeRate:    pack.NewU256FromUint64(DefaultMaxFeeRate),
+		DustThreshold: pack.NewU256FromUint64(DefaultHardDustLimit),
+	}
+}
+
+// A FeeModel computes the fee that a Dogecoin transaction must pay. Unlike
+// Bitcoin, the fee rate is charged for every started kilobyte of the
+// transaction, and every output below the soft dust limit must pay an
+// additional fee of the soft dust limit.
+type FeeModel struct {
+	FeeRate       pack.U256
+	SoftDustLimit pack.U256
+}
+
+// DefaultFeeModel returns a FeeModel with the default settings. These settings
+// match the recommended fees of Dogecoin Core.
+func DefaultFeeModel() FeeModel {
+	return FeeModel{
+		FeeRate:       pack.NewU256FromUint64(DefaultFeeRate),
+		SoftDustLimit: pack.NewU256FromUint64(DefaultSoftDustLimit),
+	}
+}
+
+// WithFeeRate sets the fee rate (in koinu per 1000 bytes).
+func (model FeeModel) WithFeeRate(feeRate pack.U256) FeeModel {
+	model.FeeRate = feeRate
+	return model
+}
+
+// WithSoftDustLimit sets the soft dust limit (in koinu).
+func (model FeeModel) WithSoftDustLimit(softDustLimit pack.U256) FeeModel {
+	model.SoftDustLimit = softDustLimit
+	return model
+}
+
+// Fee returns the fee (in koinu) that must be paid by a transaction with the
+// given size (in bytes) and outputs. Null-data outputs do not pay the dust
+// fee.
+func (model FeeModel) Fee(size int, outputs []*wire.TxOut) pack.U256 {
+	kilobytes := uint64((size + 999) / 1000)
+	fee := model.FeeRate.Mul(pack.NewU256FromUint64(kilobytes))
+	for _, output := range outputs {
+		if output.Value < 0 || bitcoin.IsNullData(output.PkScript) {
+			continue
+		}
+		if pack.NewU256FromUint64(uint64(output.Value)).LessThan(model.SoftDustLimit) {
+			fee = fee.Add(model.SoftDustLimit)
+		}
+	}
+	return fee
+}
@@ -0,0 +1,44 @@
+package dogecoin_test
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/chain/dogecoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dogecoin Fees", func() {
+	pubKeyScript := make([]byte, 25)
+	nullDataScript := []byte{0x6a, 0x01, 0x01}
+
+	Context("when computing fees with the default fee model", func() {
+		model := dogecoin.DefaultFeeModel()
+
+		It("should charge for every started kilobyte", func() {
+			outputs := []*wire.TxOut{wire.NewTxOut(dogecoin.DefaultSoftDustLimit, pubKeyScript)}
+			Expect(model.Fee(1, outputs)).To(Equal(pack.NewU256FromUint64(dogecoin.DefaultFeeRate)))
+			Expect(model.Fee(1000, outputs)).To(Equal(pack.NewU256FromUint64(dogecoin.DefaultFeeRate)))
+			Expect(model.Fee(1001, outputs)).To(Equal(pack.NewU256FromUint64(2 * dogecoin.DefaultFeeRate)))
+		})
+
+		It("should charge for every output below the soft dust limit", func() {
+			outputs := []*wire.TxOut{
+				wire.NewTxOut(dogecoin.DefaultSoftDustLimit-1, pubKeyScript),
+				wire.NewTxOut(dogecoin.DefaultHardDustLimit, pubKeyScript),
+				wire.NewTxOut(dogecoin.DefaultSoftDustLimit, pubKeyScript),
+				wire.NewTxOut(0, nullDataScript),
+			}
+			Expect(model.Fee(500, outputs)).To(Equal(pack.NewU256FromUint64(dogecoin.DefaultFeeRate + 2*dogecoin.DefaultSoftDustLimit)))
+		})
+	})
+
+	It("should use the given fee rate and soft dust limit", func() {
+		model := dogecoin.DefaultFeeModel().
+			WithFeeRate(pack.NewU256FromUint64(dogecoin.DefaultMinRelayFeeRate)).
+			WithSoftDustLimit(pack.NewU256FromUint64(0))
+		outputs := []*wire.TxOut{wire.NewTxOut(1, pubKeyScript)}
+		Expect(model.Fee(2500, outputs)).To(Equal(pack.NewU256FromUint64(3 * dogecoin.DefaultMinRelayFeeRate)))
+	})
+})
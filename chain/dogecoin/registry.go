@@ -0,0 +1,37 @@
+package dogecoin
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/pranav292gpt/zecutil/api/gas"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/registry"
+	"github.com/renproject/pack"
+)
+
+func init() {
+	for network, config := range map[registry.Network]struct {
+		params *chaincfg.Params
+		host   string
+	}{
+		registry.Mainnet: {&MainNetParams, "http://127.0.0.1:22555"},
+		registry.Testnet: {&TestNet3Params, "http://127.0.0.1:44555"},
+		registry.Regtest: {&RegressionNetParams, DefaultClientOptions().Host},
+	} {
+		registry.Register(registry.Bundle{
+			Chain:                registry.Dogecoin,
+			Network:              network,
+			AddressEncodeDecoder: NewAddressEncodeDecoder(config.params),
+			TxBuilder:            NewTxBuilder(config.params),
+			DefaultClientOptions: registry.ClientOptions(DefaultClientOptions().WithHost(config.host)),
+			NewClient:            bitcoin.NewRegistryClient,
+			NewGasEstimator: func(client utxo.Client, numBlocks int64, fallbackGas pack.U256) (gas.Estimator, error) {
+				dogecoinClient, err := bitcoin.RegistryClient(client)
+				if err != nil {
+					return nil, err
+				}
+				return NewGasEstimator(dogecoinClient, numBlocks, fallbackGas), nil
+			},
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package dogecoin
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+)
+
+// ClientOptions are used to parameterise the behaviour of the Client.
+type ClientOptions = bitcoin.ClientOptions
+
+// DefaultClientOptions returns ClientOptions with the default settings. These
+// settings are valid for use with the default local deployment of the
+// multichain. In production, the host, user, and password should be changed.
+func DefaultClientOptions() ClientOptions {
+	return bitcoin.DefaultClientOptions().WithHost("http://127.0.0.1:18332")
+}
+
+// Client re-exports bitcoin.Client.
+type Client = bitcoin.Client
+
+// NewClient re-exports bitcoin.NewClient.
+var NewClient = bitcoin.NewClient
+
+// GasEstimator re-exports bitcoin.GasEstimator. The estimated fee rate does not
+// include the additional fees of the Dogecoin fee model (see FeeModel).
+type GasEstimator = bitcoin.GasEstimator
+
+// NewGasEstimator re-exports bitcoin.NewGasEstimator.
+var NewGasEstimator = bitcoin.NewGasEstimator
+
+// TxBuilder re-exports bitcoin.TxBuilder.
+type TxBuilder = bitcoin.TxBuilder
+
+// Tx re-exports bitcoin.Tx. Dogecoin transactions have the same format, and
+// the same sighashes, as Bitcoin transactions without segregated witness.
+type Tx = bitcoin.Tx
+
+// NewTxBuilder returns a transaction builder that builds UTXO-compatible
+// Dogecoin transactions for the given chain configuration. Outputs produced for
+// recipients use the Dogecoin address format (see RecipientScript). The fee
+// must be computed using the Dogecoin fee model (see FeeModel).
+func NewTxBuilder(params *chaincfg.Params) TxBuilder {
+	return bitcoin.NewTxBuilder(params).
+		WithFeePolicy(DefaultFeePolicy()).
+		WithRecipientScript(func(recipient utxo.Recipient) ([]byte, error) {
+			return RecipientScript(recipient, params)
+		})
+}
+
+// RecipientScript returns the pubkey script of the output that will be produced
+// for the recipient. Addresses must be Dogecoin P2PKH or P2SH addresses for the
+// network. Dogecoin does not support segregated witness, so scripts with
+// witness programs are rejected. See bitcoin.RecipientScript.
+func RecipientScript(recipient utxo.Recipient, params *chaincfg.Params) ([]byte, error) {
+	script, err := bitcoin.RecipientScript(recipient, bitcoin.MaxDataCarrierSize, func(to address.Address) ([]byte, error) {
+		addr, err := DecodeAddress(string(to), params)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.PayToAddrScript(addr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txscript.IsWitnessProgram(script) {
+		return nil, fmt.Errorf("bad script: witness programs are not supported")
+	}
+	return script, nil
+}
+
+// RecipientFromOutput returns the recipient for which the output was produced.
+// This is the inverse of RecipientScript.
+func RecipientFromOutput(output utxo.Output, params *chaincfg.Params) utxo.Recipient {
+	return bitcoin.RecipientFromOutput(output, params)
+}
@@ -0,0 +1,83 @@
+package dogecoin_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/dogecoin"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dogecoin Tx", func() {
+	params := &dogecoin.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		panic(err)
+	}
+	key := signer.NewKeySigner(privKey, true)
+	pubKeyHash := btcutil.Hash160(key.PubKey())
+	pkhAddr, err := btcutil.NewAddressPubKeyHash(pubKeyHash, params)
+	if err != nil {
+		panic(err)
+	}
+	pkhScript, err := txscript.PayToAddrScript(pkhAddr)
+	if err != nil {
+		panic(err)
+	}
+	inputs := []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(1000000000), PubKeyScript: pkhScript}},
+	}
+
+	It("should build, sign, and verify transactions that pay the model fee", func() {
+		// Build the transaction once to compute its fee.
+		value := uint64(1000000000)
+		recipients := []utxo.Recipient{{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(value)}}
+		tx, err := dogecoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+		outputs, err := tx.Outputs()
+		Expect(err).ToNot(HaveOccurred())
+		txOuts := []*wire.TxOut{wire.NewTxOut(int64(value), outputs[0].PubKeyScript)}
+		fee := dogecoin.DefaultFeeModel().Fee(tx.(*dogecoin.Tx).EstimateSize(), txOuts)
+		Expect(fee).To(Equal(pack.NewU256FromUint64(dogecoin.DefaultFeeRate)))
+
+		recipients[0].Value = pack.NewU256FromUint64(value).Sub(fee)
+		tx, err = dogecoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.(*dogecoin.Tx).Fee()).To(Equal(fee))
+
+		Expect(signer.SignTx(context.Background(), key, tx)).To(Succeed())
+		serialized, err := tx.Serialize()
+		Expect(err).ToNot(HaveOccurred())
+		msgTx := wire.NewMsgTx(bitcoin.Version)
+		Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+		Expect(msgTx.HasWitness()).To(BeFalse())
+		engine, err := txscript.NewEngine(pkhScript, msgTx, 0, txscript.StandardVerifyFlags, nil, nil, 1000000000)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(engine.Execute()).To(Succeed())
+	})
+
+	It("should reject segwit recipients", func() {
+		wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+		Expect(err).ToNot(HaveOccurred())
+		wpkhScript, err := txscript.PayToAddrScript(wpkhAddr)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = dogecoin.NewTxBuilder(params).BuildTx(inputs, []utxo.Recipient{{Script: wpkhScript, Value: pack.NewU256FromUint64(500000000)}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject outputs below the hard dust limit", func() {
+		_, err := dogecoin.NewTxBuilder(params).BuildTx(inputs, []utxo.Recipient{{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(dogecoin.DefaultHardDustLimit - 1)}})
+		Expect(err).To(BeAssignableToTypeOf(bitcoin.ErrDustOutput{}))
+	})
+})
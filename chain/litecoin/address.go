@@ -0,0 +1,109 @@
+package litecoin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+)
+
+// ErrMWEBAddress is returned when decoding an MWEB address. Outputs cannot be
+// sent to MWEB addresses using transparent transactions.
+var ErrMWEBAddress = errors.New("mweb addresses are not supported")
+
+// AddressEncodeDecoder implements the address.EncodeDecoder interface
+type AddressEncodeDecoder struct {
+	AddressEncoder
+	AddressDecoder
+}
+
+// NewAddressEncodeDecoder constructs a new AddressEncodeDecoder with the
+// chain specific configurations
+func NewAddressEncodeDecoder(params *Params) AddressEncodeDecoder {
+	return AddressEncodeDecoder{
+		AddressEncoder: NewAddressEncoder(params),
+		AddressDecoder: NewAddressDecoder(params),
+	}
+}
+
+// AddressEncoder encapsulates the chain specific configurations and implements
+// the address.Encoder interface
+type AddressEncoder struct {
+	params *Params
+}
+
+// NewAddressEncoder constructs a new AddressEncoder with the chain specific
+// configurations
+func NewAddressEncoder(params *Params) AddressEncoder {
+	return AddressEncoder{params: params}
+}
+
+// EncodeAddress implements the address.Encoder interface. Raw addresses have
+// the same format as for Bitcoin.
+func (encoder AddressEncoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	switch len(rawAddr) {
+	case 25:
+		// Validate that the base58 address is in fact in correct format.
+		encodedAddr := base58.Encode([]byte(rawAddr))
+		if _, err := DecodeAddress(encodedAddr, encoder.params); err != nil {
+			return address.Address(""), err
+		}
+		return address.Address(encodedAddr), nil
+	default:
+		return bitcoin.NewAddressEncoder(encoder.params.Params).EncodeAddress(rawAddr)
+	}
+}
+
+// AddressDecoder encapsulates the chain specific configurations and implements
+// the address.Decoder interface
+type AddressDecoder struct {
+	params *Params
+}
+
+// NewAddressDecoder constructs a new AddressDecoder with the chain specific
+// configurations
+func NewAddressDecoder(params *Params) AddressDecoder {
+	return AddressDecoder{params: params}
+}
+
+// DecodeAddress implements the address.Decoder interface. MWEB addresses are
+// rejected with ErrMWEBAddress.
+func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	decodedAddr, err := DecodeAddress(string(addr), decoder.params)
+	if err != nil {
+		return nil, fmt.Errorf("decode address: %v", err)
+	}
+
+	switch a := decodedAddr.(type) {
+	case *btcutil.AddressPubKeyHash, *btcutil.AddressScriptHash:
+		return address.RawAddress(base58.Decode(string(addr))), nil
+	case *btcutil.AddressWitnessPubKeyHash:
+		return address.RawAddress(append([]byte{a.WitnessVersion()}, a.WitnessProgram()...)), nil
+	case *btcutil.AddressWitnessScriptHash:
+		return address.RawAddress(append([]byte{a.WitnessVersion()}, a.WitnessProgram()...)), nil
+	case *bitcoin.AddressTaproot:
+		return address.RawAddress(append([]byte{a.WitnessVersion()}, a.WitnessProgram()...)), nil
+	default:
+		return nil, fmt.Errorf("non-exhaustive pattern: address %T", a)
+	}
+}
+
+// IsMWEBAddress returns true if the address has the human-readable part of MWEB
+// addresses for the network. It does not check that the address is valid.
+func IsMWEBAddress(addr string, params *Params) bool {
+	return params.MWEBHRP != "" && strings.HasPrefix(strings.ToLower(addr), params.MWEBHRP+"1")
+}
+
+// DecodeAddress decodes a P2PKH, P2SH, P2WPKH, P2WSH, or P2TR address for the
+// network. MWEB addresses are rejected with ErrMWEBAddress, and addresses for
+// other networks (including Bitcoin) are rejected.
+func DecodeAddress(addr string, params *Params) (btcutil.Address, error) {
+	if IsMWEBAddress(addr, params) {
+		return nil, ErrMWEBAddress
+	}
+	return bitcoin.DecodeAddressForNet(addr, params.Params)
+}
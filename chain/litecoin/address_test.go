@@ -0,0 +1,82 @@
+package litecoin_test
+
+import (
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/litecoin"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Litecoin Address", func() {
+	hash := btcutil.Hash160([]byte("litecoin"))
+	program := make([]byte, 32)
+	copy(program, hash)
+
+	for _, params := range []*litecoin.Params{&litecoin.MainNetParams, &litecoin.TestNet4Params, &litecoin.RegressionNetParams} {
+		params := params
+		Context("when using "+params.Name, func() {
+			addrEncodeDecoder := litecoin.NewAddressEncodeDecoder(params)
+
+			It("should encode and decode addresses", func() {
+				pkhAddr, err := btcutil.NewAddressPubKeyHash(hash, params.Params)
+				Expect(err).ToNot(HaveOccurred())
+				shAddr, err := btcutil.NewAddressScriptHashFromHash(hash, params.Params)
+				Expect(err).ToNot(HaveOccurred())
+				wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(hash, params.Params)
+				Expect(err).ToNot(HaveOccurred())
+				wshAddr, err := btcutil.NewAddressWitnessScriptHash(program, params.Params)
+				Expect(err).ToNot(HaveOccurred())
+				trAddr, err := bitcoin.NewAddressTaproot(program, params.Params)
+				Expect(err).ToNot(HaveOccurred())
+
+				// Legacy addresses use the Litecoin version bytes, and segwit
+				// addresses use the Litecoin human-readable part.
+				Expect(pkhAddr.EncodeAddress()).To(Equal(base58.CheckEncode(hash, params.PubKeyHashAddrID)))
+				Expect(shAddr.EncodeAddress()).To(Equal(base58.CheckEncode(hash, params.ScriptHashAddrID)))
+				for _, addr := range []btcutil.Address{wpkhAddr, wshAddr, trAddr} {
+					Expect(addr.EncodeAddress()).To(HavePrefix(params.Bech32HRPSegwit + "1"))
+				}
+
+				for _, addr := range []btcutil.Address{pkhAddr, shAddr, wpkhAddr, wshAddr, trAddr} {
+					rawAddr, err := addrEncodeDecoder.DecodeAddress(address.Address(addr.EncodeAddress()))
+					Expect(err).ToNot(HaveOccurred())
+					encodedAddr, err := addrEncodeDecoder.EncodeAddress(rawAddr)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(encodedAddr).To(Equal(address.Address(addr.EncodeAddress())))
+				}
+			})
+
+			It("should reject mweb addresses", func() {
+				// MWEB addresses encode a scan pubkey and a spend pubkey, and
+				// are longer than segwit addresses.
+				values, err := bech32.ConvertBits(make([]byte, 66), 8, 5, true)
+				Expect(err).ToNot(HaveOccurred())
+				mwebAddr, err := bech32.Encode(params.MWEBHRP, append([]byte{0}, values...))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(litecoin.IsMWEBAddress(mwebAddr, params)).To(BeTrue())
+
+				_, err = litecoin.DecodeAddress(mwebAddr, params)
+				Expect(err).To(Equal(litecoin.ErrMWEBAddress))
+				_, err = addrEncodeDecoder.DecodeAddress(address.Address(mwebAddr))
+				Expect(err).To(MatchError(ContainSubstring(litecoin.ErrMWEBAddress.Error())))
+			})
+		})
+	}
+
+	It("should reject bitcoin addresses", func() {
+		addrEncodeDecoder := litecoin.NewAddressEncodeDecoder(&litecoin.MainNetParams)
+		for _, addr := range []string{
+			"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		} {
+			_, err := addrEncodeDecoder.DecodeAddress(address.Address(addr))
+			Expect(err).To(HaveOccurred(), addr)
+		}
+	})
+})
@@ -0,0 +1,73 @@
+package litecoin
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Params signifies the chain specific parameters of the Litecoin network.
+type Params struct {
+	// Only the fields of the embedded chaincfg params that are needed to
+	// encode addresses, keys, and scripts are set. The params are not
+	// registered with chaincfg, because the network magic of the Litecoin
+	// regtest conflicts with that of the Bitcoin regtest.
+	*chaincfg.Params
+
+	// MWEBHRP is the human-readable part of MWEB (MimbleWimble Extension
+	// Block) addresses. Outputs cannot be sent to MWEB addresses using
+	// transparent transactions, so these addresses are rejected.
+	MWEBHRP string
+}
+
+var (
+	// MainNetParams defines the mainnet configuration.
+	MainNetParams = Params{
+		Params: &chaincfg.Params{
+			Name:             "litecoin-mainnet",
+			Net:              wire.BitcoinNet(0xdbb6c0fb),
+			DefaultPort:      "9333",
+			PubKeyHashAddrID: 0x30, // starts with L
+			ScriptHashAddrID: 0x32, // starts with M
+			PrivateKeyID:     0xb0,
+			Bech32HRPSegwit:  "ltc",
+			HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4},
+			HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e},
+			HDCoinType:       2,
+		},
+		MWEBHRP: "ltcmweb",
+	}
+
+	// TestNet4Params defines the testnet configuration.
+	TestNet4Params = Params{
+		Params: &chaincfg.Params{
+			Name:             "litecoin-testnet4",
+			Net:              wire.BitcoinNet(0xf1c8d2fd),
+			DefaultPort:      "19335",
+			PubKeyHashAddrID: 0x6f, // starts with m or n
+			ScriptHashAddrID: 0x3a, // starts with Q
+			PrivateKeyID:     0xef,
+			Bech32HRPSegwit:  "tltc",
+			HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+			HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+			HDCoinType:       1,
+		},
+		MWEBHRP: "tmweb",
+	}
+
+	// RegressionNetParams defines a devet/regnet configuration.
+	RegressionNetParams = Params{
+		Params: &chaincfg.Params{
+			Name:             "litecoin-regtest",
+			Net:              wire.BitcoinNet(0xdab5bffa),
+			DefaultPort:      "19444",
+			PubKeyHashAddrID: 0x6f, // starts with m or n
+			ScriptHashAddrID: 0x3a, // starts with Q
+			PrivateKeyID:     0xef,
+			Bech32HRPSegwit:  "rltc",
+			HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+			HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+			HDCoinType:       1,
+		},
+		MWEBHRP: "tmweb",
+	}
+)
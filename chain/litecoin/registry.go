@@ -0,0 +1,36 @@
+package litecoin
+
+import (
+	"github.com/pranav292gpt/zecutil/api/gas"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/registry"
+	"github.com/renproject/pack"
+)
+
+func init() {
+	for network, config := range map[registry.Network]struct {
+		params *Params
+		host   string
+	}{
+		registry.Mainnet: {&MainNetParams, "http://127.0.0.1:9332"},
+		registry.Testnet: {&TestNet4Params, "http://127.0.0.1:19332"},
+		registry.Regtest: {&RegressionNetParams, DefaultClientOptions().Host},
+	} {
+		registry.Register(registry.Bundle{
+			Chain:                registry.Litecoin,
+			Network:              network,
+			AddressEncodeDecoder: NewAddressEncodeDecoder(config.params),
+			TxBuilder:            NewTxBuilder(config.params),
+			DefaultClientOptions: registry.ClientOptions(DefaultClientOptions().WithHost(config.host)),
+			NewClient:            bitcoin.NewRegistryClient,
+			NewGasEstimator: func(client utxo.Client, numBlocks int64, fallbackGas pack.U256) (gas.Estimator, error) {
+				litecoinClient, err := bitcoin.RegistryClient(client)
+				if err != nil {
+					return nil, err
+				}
+				return NewGasEstimator(litecoinClient, numBlocks, fallbackGas), nil
+			},
+		})
+	}
+}
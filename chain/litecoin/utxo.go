@@ -0,0 +1,95 @@
+package litecoin
+
+import (
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultMaxFee is the default maximum absolute fee (in litoshis) of a
+	// transaction. This is the same as the default -maxtxfee of Litecoin
+	// Core.
+	DefaultMaxFee = 10000000
+	// DefaultMaxFeeRate is the default maximum fee rate (in
+	// litoshis-per-byte) of a transaction.
+	DefaultMaxFeeRate = 10000
+	// DefaultDustThreshold is the default minimum value (in litoshis) of an
+	// output. Outputs below this value are rejected by the relay policy of
+	// Litecoin Core, which uses a dust relay fee ten times higher than that of
+	// Bitcoin Core.
+	DefaultDustThreshold = 5460
+)
+
+// ClientOptions are used to parameterise the behaviour of the Client.
+type ClientOptions = bitcoin.ClientOptions
+
+// DefaultClientOptions returns ClientOptions with the default settings. These
+// settings are valid for use with the default local deployment of the
+// multichain. In production, the host, user, and password should be changed.
+func DefaultClientOptions() ClientOptions {
+	return bitcoin.DefaultClientOptions().WithHost("http://127.0.0.1:19443")
+}
+
+// Client re-exports bitcoin.Client.
+type Client = bitcoin.Client
+
+// NewClient re-exports bitcoin.NewClient.
+var NewClient = bitcoin.NewClient
+
+// GasEstimator re-exports bitcoin.GasEstimator.
+type GasEstimator = bitcoin.GasEstimator
+
+// NewGasEstimator re-exports bitcoin.NewGasEstimator.
+var NewGasEstimator = bitcoin.NewGasEstimator
+
+// FeePolicy re-exports bitcoin.FeePolicy.
+type FeePolicy = bitcoin.FeePolicy
+
+// DefaultFeePolicy returns a FeePolicy with the default settings. These
+// settings match the default relay policy of Litecoin Core.
+func DefaultFeePolicy() FeePolicy {
+	return FeePolicy{
+		MaxFee:        pack.NewU256FromUint64(DefaultMaxFee),
+		MaxFeeRate:    pack.NewU256FromUint64(DefaultMaxFeeRate),
+		DustThreshold: pack.NewU256FromUint64(DefaultDustThreshold),
+	}
+}
+
+// TxBuilder re-exports bitcoin.TxBuilder.
+type TxBuilder = bitcoin.TxBuilder
+
+// Tx re-exports bitcoin.Tx. Litecoin transactions have the same format, and
+// the same sighashes, as Bitcoin transactions.
+type Tx = bitcoin.Tx
+
+// NewTxBuilder returns a transaction builder that builds UTXO-compatible
+// Litecoin transactions for the given chain configuration. Outputs produced for
+// recipients use the Litecoin address format (see RecipientScript).
+func NewTxBuilder(params *Params) TxBuilder {
+	return bitcoin.NewTxBuilder(params.Params).
+		WithFeePolicy(DefaultFeePolicy()).
+		WithRecipientScript(func(recipient utxo.Recipient) ([]byte, error) {
+			return RecipientScript(recipient, params)
+		})
+}
+
+// RecipientScript returns the pubkey script of the output that will be produced
+// for the recipient. Addresses must be Litecoin addresses for the network, and
+// MWEB addresses are rejected. See bitcoin.RecipientScript.
+func RecipientScript(recipient utxo.Recipient, params *Params) ([]byte, error) {
+	return bitcoin.RecipientScript(recipient, bitcoin.MaxDataCarrierSize, func(to address.Address) ([]byte, error) {
+		addr, err := DecodeAddress(string(to), params)
+		if err != nil {
+			return nil, err
+		}
+		return bitcoin.PayToAddrScript(addr)
+	})
+}
+
+// RecipientFromOutput returns the recipient for which the output was produced.
+// This is the inverse of RecipientScript.
+func RecipientFromOutput(output utxo.Output, params *Params) utxo.Recipient {
+	return bitcoin.RecipientFromOutput(output, params.Params)
+}
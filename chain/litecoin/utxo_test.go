@@ -0,0 +1,83 @@
+package litecoin_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/litecoin"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Litecoin Tx", func() {
+	params := &litecoin.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		panic(err)
+	}
+	key := signer.NewKeySigner(privKey, true)
+	pubKeyHash := btcutil.Hash160(key.PubKey())
+	wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params.Params)
+	if err != nil {
+		panic(err)
+	}
+	wpkhScript, err := txscript.PayToAddrScript(wpkhAddr)
+	if err != nil {
+		panic(err)
+	}
+	pkhAddr, err := btcutil.NewAddressPubKeyHash(pubKeyHash, params.Params)
+	if err != nil {
+		panic(err)
+	}
+	inputs := []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(1000000), PubKeyScript: wpkhScript}},
+	}
+
+	It("should build, sign, and verify transactions", func() {
+		recipients := []utxo.Recipient{
+			{To: address.Address(wpkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(500000)},
+			{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(400000)},
+		}
+		tx, err := litecoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+		outputs, err := tx.Outputs()
+		Expect(err).ToNot(HaveOccurred())
+		for i, output := range outputs {
+			Expect(litecoin.RecipientFromOutput(output, params)).To(Equal(recipients[i]))
+		}
+
+		Expect(signer.SignTx(context.Background(), key, tx)).To(Succeed())
+		serialized, err := tx.Serialize()
+		Expect(err).ToNot(HaveOccurred())
+		msgTx := wire.NewMsgTx(bitcoin.Version)
+		Expect(msgTx.Deserialize(bytes.NewReader(serialized))).To(Succeed())
+		engine, err := txscript.NewEngine(wpkhScript, msgTx, 0, txscript.StandardVerifyFlags, nil, nil, 1000000)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(engine.Execute()).To(Succeed())
+	})
+
+	It("should reject recipients that are not litecoin addresses", func() {
+		btcAddr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, &chaincfg.RegressionNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		for _, to := range []string{btcAddr.EncodeAddress(), "tmweb1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq"} {
+			_, err := litecoin.NewTxBuilder(params).BuildTx(inputs, []utxo.Recipient{{To: address.Address(to), Value: pack.NewU256FromUint64(500000)}})
+			Expect(err).To(HaveOccurred(), to)
+		}
+	})
+
+	It("should reject dust outputs", func() {
+		_, err := litecoin.NewTxBuilder(params).BuildTx(inputs, []utxo.Recipient{{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(litecoin.DefaultDustThreshold - 1)}})
+		Expect(err).To(BeAssignableToTypeOf(bitcoin.ErrDustOutput{}))
+	})
+})
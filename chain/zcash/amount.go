@@ -2,13 +2,80 @@ package zcash
 
 import (
 	"errors"
+	"fmt"
 	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/renproject/pack"
+)
+
+const (
+	// ZatoshiPerZecash is the number of zatoshi in one zcash (1 ZEC).
+	ZatoshiPerZecash = 1e8
+
+	// MaxZatoshi is the maximum amount of zatoshi that can ever exist, and the
+	// maximum value that a transaction output (or the sum of all outputs) is
+	// allowed to have. This mirrors MAX_MONEY in zcashd.
+	MaxZatoshi = 21e6 * ZatoshiPerZecash
+)
+
+var (
+	// ErrAmountOverflow is returned when an arithmetic operation on amounts
+	// would overflow the underlying int64.
+	ErrAmountOverflow = errors.New("amount overflow")
+
+	// ErrAmountOutOfRange is returned when an amount is negative, or greater
+	// than MaxZatoshi.
+	ErrAmountOutOfRange = errors.New("amount out of range")
+)
+
+// AmountUnit describes a method of converting an Amount to something other
+// than the base unit of zcash. The value of the AmountUnit is the exponent
+// component of the decadic multiple to convert from an amount in zcash to an
+// amount counted in units.
+type AmountUnit int
+
+// These constants define various units used when describing a zcash monetary
+// amount.
+const (
+	AmountZEC      AmountUnit = 0
+	AmountMilliZEC AmountUnit = -3
+	AmountMicroZEC AmountUnit = -6
+	AmountZatoshi  AmountUnit = -8
 )
 
-const ZatoshiPerZecash = 1e8
+// String returns the unit as a string. For recognized units, the SI prefix is
+// used, or "zat" for the base unit. For all unrecognized units, "1eN ZEC" is
+// returned, where N is the AmountUnit.
+func (u AmountUnit) String() string {
+	switch u {
+	case AmountZEC:
+		return "ZEC"
+	case AmountMilliZEC:
+		return "mZEC"
+	case AmountMicroZEC:
+		return "μZEC"
+	case AmountZatoshi:
+		return "zat"
+	default:
+		return "1e" + strconv.FormatInt(int64(u), 10) + " ZEC"
+	}
+}
 
-// Amount represents the base bitcoin monetary unit (colloquially referred
-// to as a `Zatoshi').  A single Amount is equal to 1e-8 of a bitcoin.
+// decimals returns the number of decimal places that are needed to represent
+// one zatoshi in the unit.
+func (u AmountUnit) decimals() (int, error) {
+	d := int(u) + 8
+	if d < 0 || d > 18 {
+		return 0, fmt.Errorf("unsupported unit: %v", u)
+	}
+	return d, nil
+}
+
+// Amount represents the base zcash monetary unit (colloquially referred
+// to as a `Zatoshi').  A single Amount is equal to 1e-8 of a zcash.
 type Amount int64
 
 // round converts a floating point number, which may or may not be representable
@@ -23,7 +90,7 @@ func round(f float64) Amount {
 }
 
 // NewAmount creates an Amount from a floating point value representing
-// some value in bitcoin.  NewAmount errors if f is NaN or +-Infinity, but
+// some value in zcash.  NewAmount errors if f is NaN or +-Infinity, but
 // does not check that the amount is within the total amount of zecash
 // producible as f may not refer to an amount at a single moment in time.
 //
@@ -40,8 +107,193 @@ func NewAmount(f float64) (Amount, error) {
 	case math.IsInf(f, 1):
 		fallthrough
 	case math.IsInf(f, -1):
-		return 0, errors.New("invalid bitcoin amount")
+		return 0, errors.New("invalid zcash amount")
 	}
 
 	return round(f * ZatoshiPerZecash), nil
 }
+
+// NewAmountFromU256 converts a value counted in zatoshi, as used by
+// utxo.Output and utxo.Recipient, into an Amount. An error is returned if the
+// value is greater than MaxZatoshi.
+func NewAmountFromU256(value pack.U256) (Amount, error) {
+	if value.GreaterThan(pack.NewU256FromUint64(MaxZatoshi)) {
+		return 0, fmt.Errorf("%w: %v is greater than %v", ErrAmountOutOfRange, value, uint64(MaxZatoshi))
+	}
+	return Amount(value.Int().Int64()), nil
+}
+
+// ParseAmount parses a decimal string representing some value in zcash (for
+// example, "1.23456789") into an Amount. Unlike NewAmount, the string is
+// parsed exactly, without going through a floating point representation. An
+// error is returned if the string is malformed, has more than eight decimal
+// places, or represents more than MaxZatoshi.
+func ParseAmount(s string) (Amount, error) {
+	return ParseAmountUnit(s, AmountZEC)
+}
+
+// ParseAmountUnit parses a decimal string representing some value in the given
+// unit into an Amount. See ParseAmount for more details.
+func ParseAmountUnit(s string, u AmountUnit) (Amount, error) {
+	decimals, err := u.decimals()
+	if err != nil {
+		return 0, err
+	}
+	str := s
+	neg := false
+	if strings.HasPrefix(str, "-") {
+		neg = true
+		str = str[1:]
+	}
+
+	whole, frac := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		whole, frac = str[:i], str[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q: no digits", s)
+	}
+	if len(frac) > decimals {
+		return 0, fmt.Errorf("invalid amount %q: more than %v decimal places", s, decimals)
+	}
+	for _, digits := range []string{whole, frac} {
+		for _, c := range digits {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("invalid amount %q: unexpected character %q", s, c)
+			}
+		}
+	}
+
+	// Shift the decimal point so that the value is counted in zatoshi, and
+	// then check the range using arbitrary precision arithmetic to avoid
+	// overflowing while parsing.
+	digits := whole + frac + strings.Repeat("0", decimals-len(frac))
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if value.Cmp(big.NewInt(MaxZatoshi)) > 0 {
+		return 0, fmt.Errorf("%w: %v is greater than %v", ErrAmountOutOfRange, s, Amount(MaxZatoshi))
+	}
+	if neg {
+		value.Neg(value)
+	}
+	return Amount(value.Int64()), nil
+}
+
+// ToUnit converts a monetary amount counted in zcash base units to a floating
+// point value representing an amount of zcash.
+func (a Amount) ToUnit(u AmountUnit) float64 {
+	return float64(a) / math.Pow10(int(u+8))
+}
+
+// ToZEC is the equivalent of calling ToUnit with AmountZEC.
+func (a Amount) ToZEC() float64 {
+	return a.ToUnit(AmountZEC)
+}
+
+// Format formats a monetary amount counted in zcash base units as a string for
+// a given unit. The formatting is exact, and known units will be formatted
+// with an appended label describing the units with SI notation, or "zat" for
+// the base unit.
+func (a Amount) Format(u AmountUnit) string {
+	return a.formatDecimal(u) + " " + u.String()
+}
+
+// String is the equivalent of calling Format with AmountZEC.
+func (a Amount) String() string {
+	return a.Format(AmountZEC)
+}
+
+// formatDecimal returns the amount as a decimal string in the given unit,
+// without a label.
+func (a Amount) formatDecimal(u AmountUnit) string {
+	decimals, err := u.decimals()
+	if err != nil {
+		return strconv.FormatFloat(a.ToUnit(u), 'f', -1, 64)
+	}
+	value := new(big.Int).Abs(big.NewInt(int64(a))).String()
+	if len(value) <= decimals {
+		value = strings.Repeat("0", decimals-len(value)+1) + value
+	}
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	if decimals == 0 {
+		return sign + value
+	}
+	return sign + value[:len(value)-decimals] + "." + value[len(value)-decimals:]
+}
+
+// Validate returns an error if the amount is negative, or greater than
+// MaxZatoshi. This is equivalent to MoneyRange in zcashd.
+func (a Amount) Validate() error {
+	if a < 0 || a > MaxZatoshi {
+		return fmt.Errorf("%w: %v", ErrAmountOutOfRange, int64(a))
+	}
+	return nil
+}
+
+// Add returns the sum of two amounts, or an error if the sum overflows.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, fmt.Errorf("%w: %v + %v", ErrAmountOverflow, int64(a), int64(b))
+	}
+	return a + b, nil
+}
+
+// Sub returns the difference of two amounts, or an error if the difference
+// overflows.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, fmt.Errorf("%w: %v - %v", ErrAmountOverflow, int64(a), int64(b))
+	}
+	return a - b, nil
+}
+
+// Mul returns the amount multiplied by n, or an error if the product
+// overflows.
+func (a Amount) Mul(n int64) (Amount, error) {
+	if a == 0 || n == 0 {
+		return 0, nil
+	}
+	product := int64(a) * n
+	if product/n != int64(a) || (int64(a) == -1 && n == math.MinInt64) || (n == -1 && int64(a) == math.MinInt64) {
+		return 0, fmt.Errorf("%w: %v * %v", ErrAmountOverflow, int64(a), n)
+	}
+	return Amount(product), nil
+}
+
+// U256 converts the amount into a value counted in zatoshi, as used by
+// utxo.Output and utxo.Recipient. An error is returned if the amount is
+// negative.
+func (a Amount) U256() (pack.U256, error) {
+	if a < 0 {
+		return pack.U256{}, fmt.Errorf("%w: %v is negative", ErrAmountOutOfRange, int64(a))
+	}
+	return pack.NewU256FromUint64(uint64(a)), nil
+}
+
+// MarshalJSON implements the json.Marshaler interface. The amount is encoded
+// as a JSON number counted in zcash, with exactly eight decimal places, in the
+// same format that is used by zcashd.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.formatDecimal(AmountZEC)), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The amount can be
+// encoded as a JSON number, or a JSON string, counted in zcash. In both cases,
+// the amount is parsed exactly.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if len(str) >= 2 && str[0] == '"' && str[len(str)-1] == '"' {
+		str = str[1 : len(str)-1]
+	}
+	amount, err := ParseAmount(str)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
@@ -0,0 +1,174 @@
+package zcash_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Amount", func() {
+	Context("when parsing amounts", func() {
+		It("should parse decimal strings exactly", func() {
+			cases := map[string]zcash.Amount{
+				"0":                 0,
+				"1":                 100000000,
+				"1.23456789":        123456789,
+				"0.1":               10000000,
+				".00000001":         1,
+				"21000000":          zcash.MaxZatoshi,
+				"-0.5":              -50000000,
+				"20999999.99999999": zcash.MaxZatoshi - 1,
+			}
+			for str, expected := range cases {
+				amount, err := zcash.ParseAmount(str)
+				Expect(err).ToNot(HaveOccurred(), str)
+				Expect(amount).To(Equal(expected), str)
+			}
+		})
+
+		It("should reject malformed strings", func() {
+			for _, str := range []string{"", "-", ".", "1.000000001", "1e8", "0x10", "1,5", " 1", "21000000.00000001", "99999999999999999999"} {
+				_, err := zcash.ParseAmount(str)
+				Expect(err).To(HaveOccurred(), str)
+			}
+		})
+
+		It("should parse amounts in other units", func() {
+			amount, err := zcash.ParseAmountUnit("1.5", zcash.AmountMilliZEC)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amount).To(Equal(zcash.Amount(150000)))
+
+			amount, err = zcash.ParseAmountUnit("42", zcash.AmountZatoshi)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amount).To(Equal(zcash.Amount(42)))
+
+			_, err = zcash.ParseAmountUnit("4.2", zcash.AmountZatoshi)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when formatting amounts", func() {
+		It("should format amounts exactly in all units", func() {
+			amount := zcash.Amount(123456789)
+			Expect(amount.String()).To(Equal("1.23456789 ZEC"))
+			Expect(amount.Format(zcash.AmountMilliZEC)).To(Equal("1234.56789 mZEC"))
+			Expect(amount.Format(zcash.AmountMicroZEC)).To(Equal("1234567.89 μZEC"))
+			Expect(amount.Format(zcash.AmountZatoshi)).To(Equal("123456789 zat"))
+			Expect(zcash.Amount(1).String()).To(Equal("0.00000001 ZEC"))
+			Expect(zcash.Amount(-1).String()).To(Equal("-0.00000001 ZEC"))
+			Expect(zcash.Amount(0).String()).To(Equal("0.00000000 ZEC"))
+		})
+
+		It("should round-trip through parsing", func() {
+			for _, amount := range []zcash.Amount{0, 1, 99, 100000000, 123456789, zcash.MaxZatoshi, -42} {
+				for _, unit := range []zcash.AmountUnit{zcash.AmountZEC, zcash.AmountMilliZEC, zcash.AmountMicroZEC, zcash.AmountZatoshi} {
+					str := amount.Format(unit)
+					parsed, err := zcash.ParseAmountUnit(str[:len(str)-len(unit.String())-1], unit)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(parsed).To(Equal(amount))
+				}
+			}
+		})
+	})
+
+	Context("when doing arithmetic", func() {
+		It("should detect overflows", func() {
+			_, err := zcash.Amount(math.MaxInt64).Add(1)
+			Expect(errors.Is(err, zcash.ErrAmountOverflow)).To(BeTrue())
+			_, err = zcash.Amount(math.MinInt64).Sub(1)
+			Expect(errors.Is(err, zcash.ErrAmountOverflow)).To(BeTrue())
+			_, err = zcash.Amount(math.MaxInt64 / 2).Mul(3)
+			Expect(errors.Is(err, zcash.ErrAmountOverflow)).To(BeTrue())
+			_, err = zcash.Amount(math.MinInt64).Mul(-1)
+			Expect(errors.Is(err, zcash.ErrAmountOverflow)).To(BeTrue())
+
+			sum, err := zcash.Amount(1).Add(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sum).To(Equal(zcash.Amount(3)))
+			diff, err := zcash.Amount(1).Sub(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diff).To(Equal(zcash.Amount(-1)))
+			product, err := zcash.Amount(-7).Mul(6)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(product).To(Equal(zcash.Amount(-42)))
+		})
+
+		It("should validate the money range", func() {
+			Expect(zcash.Amount(0).Validate()).To(Succeed())
+			Expect(zcash.Amount(zcash.MaxZatoshi).Validate()).To(Succeed())
+			Expect(errors.Is(zcash.Amount(-1).Validate(), zcash.ErrAmountOutOfRange)).To(BeTrue())
+			Expect(errors.Is(zcash.Amount(zcash.MaxZatoshi+1).Validate(), zcash.ErrAmountOutOfRange)).To(BeTrue())
+		})
+	})
+
+	Context("when converting amounts", func() {
+		It("should convert to and from U256", func() {
+			value, err := zcash.Amount(123).U256()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(pack.NewU256FromUint64(123)))
+			amount, err := zcash.NewAmountFromU256(value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amount).To(Equal(zcash.Amount(123)))
+
+			_, err = zcash.Amount(-1).U256()
+			Expect(err).To(HaveOccurred())
+			_, err = zcash.NewAmountFromU256(pack.NewU256FromUint64(zcash.MaxZatoshi + 1))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should marshal to and from JSON", func() {
+			data, err := json.Marshal(struct {
+				Amount zcash.Amount `json:"amount"`
+			}{zcash.Amount(123456789)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"amount":1.23456789}`))
+
+			var amount zcash.Amount
+			Expect(json.Unmarshal([]byte(`1.23456789`), &amount)).To(Succeed())
+			Expect(amount).To(Equal(zcash.Amount(123456789)))
+			Expect(json.Unmarshal([]byte(`"0.00000001"`), &amount)).To(Succeed())
+			Expect(amount).To(Equal(zcash.Amount(1)))
+			Expect(json.Unmarshal([]byte(`0.000000001`), &amount)).ToNot(Succeed())
+		})
+	})
+
+	Context("when building transactions", func() {
+		It("should reject values outside of the money range", func() {
+			pk := id.NewPrivKey()
+			addr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160((*btcec.PrivateKey)(pk).PubKey().SerializeCompressed()), &zcash.RegressionNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			builder := zcash.NewTxBuilder(&zcash.RegressionNetParams, 1000000)
+			inputs := []utxo.Input{{Output: utxo.Output{
+				Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)},
+				Value:    pack.NewU256FromUint64(zcash.MaxZatoshi),
+			}}}
+
+			_, err = builder.BuildTx(inputs, []utxo.Recipient{
+				{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(zcash.MaxZatoshi + 1)},
+			})
+			Expect(err).To(HaveOccurred())
+
+			_, err = builder.BuildTx(inputs, []utxo.Recipient{
+				{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(zcash.MaxZatoshi)},
+				{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(1)},
+			})
+			Expect(err).To(HaveOccurred())
+
+			_, err = builder.BuildTx(inputs, []utxo.Recipient{
+				{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(zcash.MaxZatoshi)},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,497 @@
+package zcash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	versionNU5        int32  = 5
+	versionNU5GroupID uint32 = 0x26A7270A
+
+	// maxDecodeCount bounds the number of elements of each vector of a
+	// decoded transaction, so that malformed transactions cannot cause large
+	// allocations. It is larger than the number of elements that fit in a
+	// block.
+	maxDecodeCount = 1 << 16
+
+	// Sizes of the elements of the vectors of a transaction, used to bound
+	// their number. Inputs and outputs have scripts of variable size, so their
+	// sizes are the minimum sizes.
+	minTxInSize        = 32 + 4 + 1 + 4
+	minTxOutSize       = 8 + 1
+	saplingSpendV4Size = 4*32 + 192 + 64
+	saplingSpendV5Size = 3*32 + 192 + 64
+	saplingOutputSize  = 3*32 + 580 + 80 + 192
+	orchardActionSize  = 5*32 + 580 + 80 + 64
+)
+
+// A SaplingSpend is a Sapling spend description of a decoded transaction.
+type SaplingSpend struct {
+	CV           [32]byte
+	Anchor       [32]byte
+	Nullifier    [32]byte
+	RK           [32]byte
+	Proof        [192]byte
+	SpendAuthSig [64]byte
+}
+
+// A SaplingOutput is a Sapling output description of a decoded transaction.
+type SaplingOutput struct {
+	CV            [32]byte
+	CMU           [32]byte
+	EphemeralKey  [32]byte
+	EncCiphertext [580]byte
+	OutCiphertext [80]byte
+	Proof         [192]byte
+}
+
+// An OrchardAction is an Orchard action description of a decoded transaction.
+type OrchardAction struct {
+	CV            [32]byte
+	Nullifier     [32]byte
+	RK            [32]byte
+	CMX           [32]byte
+	EphemeralKey  [32]byte
+	EncCiphertext [580]byte
+	OutCiphertext [80]byte
+	SpendAuthSig  [64]byte
+}
+
+// A JoinSplit is a Sprout JoinSplit description of a decoded transaction. Only
+// the transparent values are decoded; the rest of the description is kept as
+// it was serialized.
+type JoinSplit struct {
+	VPubOld uint64
+	VPubNew uint64
+	Data    []byte
+}
+
+// A DecodedTx is a Zcash transaction that has been decoded from its serialized
+// form. Transactions of version 3 (Overwinter), 4 (Sapling), and 5 (NU5) are
+// supported. The transparent components are decoded into a wire.MsgTx, and the
+// shielded components are decoded into their descriptions, without verifying
+// any proofs or signatures.
+type DecodedTx struct {
+	// MsgTx holds the version, the transparent inputs and outputs, and the
+	// lock time.
+	MsgTx          *wire.MsgTx
+	VersionGroupID uint32
+	// ConsensusBranchID is only serialized in version 5 transactions. It is
+	// zero for other versions.
+	ConsensusBranchID uint32
+	ExpiryHeight      uint32
+
+	SaplingValueBalance int64
+	SaplingSpends       []SaplingSpend
+	SaplingOutputs      []SaplingOutput
+	SaplingBindingSig   [64]byte
+
+	JoinSplits      []JoinSplit
+	JoinSplitPubKey [32]byte
+	JoinSplitSig    [64]byte
+
+	OrchardActions      []OrchardAction
+	OrchardFlags        byte
+	OrchardValueBalance int64
+	OrchardAnchor       [32]byte
+	OrchardProof        []byte
+	OrchardBindingSig   [64]byte
+
+	serialized []byte
+}
+
+// DecodeTx decodes a serialized Zcash transaction. An error is returned if the
+// transaction is malformed, if it has an unsupported version, or if it is
+// followed by trailing bytes.
+func DecodeTx(data []byte) (*DecodedTx, error) {
+	r := bytes.NewReader(data)
+	tx, err := decodeTx(r)
+	if err != nil {
+		return nil, fmt.Errorf("bad tx: %v", err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("bad tx: %v trailing bytes", r.Len())
+	}
+	tx.serialized = append([]byte{}, data...)
+	return tx, nil
+}
+
+// Serialize returns the serialized transaction that was decoded.
+func (tx *DecodedTx) Serialize() []byte {
+	return append([]byte{}, tx.serialized...)
+}
+
+// IsTransparent returns true if the transaction has no shielded components.
+func (tx *DecodedTx) IsTransparent() bool {
+	return len(tx.SaplingSpends) == 0 && len(tx.SaplingOutputs) == 0 && len(tx.JoinSplits) == 0 && len(tx.OrchardActions) == 0
+}
+
+// TxID returns the transaction ID. For version 5 transactions, this is the
+// ZIP-244 transaction digest. For earlier versions, this is the double SHA-256
+// hash of the serialized transaction.
+func (tx *DecodedTx) TxID() (chainhash.Hash, error) {
+	if tx.MsgTx.Version >= versionNU5 {
+		return tx.txIDDigest()
+	}
+	return chainhash.DoubleHashH(tx.serialized), nil
+}
+
+func decodeTx(r *bytes.Reader) (*DecodedTx, error) {
+	header, err := readUint32(r)
+	if err != nil {
+		return nil, fmt.Errorf("bad header: %v", err)
+	}
+	if header&(1<<31) == 0 {
+		return nil, fmt.Errorf("bad header: expected overwintered transaction")
+	}
+	version := int32(header &^ (1 << 31))
+
+	tx := &DecodedTx{MsgTx: wire.NewMsgTx(version)}
+	if tx.VersionGroupID, err = readUint32(r); err != nil {
+		return nil, fmt.Errorf("bad version group id: %v", err)
+	}
+	var expectedGroupID uint32
+	switch version {
+	case versionOverwinter:
+		expectedGroupID = versionOverwinterGroupID
+	case versionSapling:
+		expectedGroupID = versionSaplingGroupID
+	case versionNU5:
+		expectedGroupID = versionNU5GroupID
+	default:
+		return nil, fmt.Errorf("bad version: expected %v, %v, or %v, got %v", versionOverwinter, versionSapling, versionNU5, version)
+	}
+	if tx.VersionGroupID != expectedGroupID {
+		return nil, fmt.Errorf("bad version group id: expected %08x, got %08x", expectedGroupID, tx.VersionGroupID)
+	}
+
+	if version == versionNU5 {
+		if tx.ConsensusBranchID, err = readUint32(r); err != nil {
+			return nil, fmt.Errorf("bad consensus branch id: %v", err)
+		}
+		if tx.MsgTx.LockTime, err = readUint32(r); err != nil {
+			return nil, fmt.Errorf("bad lock time: %v", err)
+		}
+		if tx.ExpiryHeight, err = readUint32(r); err != nil {
+			return nil, fmt.Errorf("bad expiry height: %v", err)
+		}
+		if err := decodeTransparent(r, tx.MsgTx); err != nil {
+			return nil, err
+		}
+		if err := tx.decodeSaplingV5(r); err != nil {
+			return nil, fmt.Errorf("bad sapling bundle: %v", err)
+		}
+		if err := tx.decodeOrchard(r); err != nil {
+			return nil, fmt.Errorf("bad orchard bundle: %v", err)
+		}
+		return tx, nil
+	}
+
+	if err := decodeTransparent(r, tx.MsgTx); err != nil {
+		return nil, err
+	}
+	if tx.MsgTx.LockTime, err = readUint32(r); err != nil {
+		return nil, fmt.Errorf("bad lock time: %v", err)
+	}
+	if tx.ExpiryHeight, err = readUint32(r); err != nil {
+		return nil, fmt.Errorf("bad expiry height: %v", err)
+	}
+	if version == versionSapling {
+		if err := tx.decodeSaplingV4(r); err != nil {
+			return nil, fmt.Errorf("bad sapling bundle: %v", err)
+		}
+	}
+	if err := tx.decodeJoinSplits(r, version); err != nil {
+		return nil, fmt.Errorf("bad joinsplits: %v", err)
+	}
+	if version == versionSapling && (len(tx.SaplingSpends) > 0 || len(tx.SaplingOutputs) > 0) {
+		if err := readBytes(r, tx.SaplingBindingSig[:]); err != nil {
+			return nil, fmt.Errorf("bad sapling binding sig: %v", err)
+		}
+	}
+	return tx, nil
+}
+
+func decodeTransparent(r *bytes.Reader, msgTx *wire.MsgTx) error {
+	numTxIns, err := readCount(r, minTxInSize)
+	if err != nil {
+		return fmt.Errorf("bad inputs: %v", err)
+	}
+	for i := uint64(0); i < numTxIns; i++ {
+		txIn := new(wire.TxIn)
+		if err := readBytes(r, txIn.PreviousOutPoint.Hash[:]); err != nil {
+			return fmt.Errorf("bad input %v: %v", i, err)
+		}
+		if txIn.PreviousOutPoint.Index, err = readUint32(r); err != nil {
+			return fmt.Errorf("bad input %v: %v", i, err)
+		}
+		if txIn.SignatureScript, err = readVarBytes(r); err != nil {
+			return fmt.Errorf("bad input %v: %v", i, err)
+		}
+		if txIn.Sequence, err = readUint32(r); err != nil {
+			return fmt.Errorf("bad input %v: %v", i, err)
+		}
+		msgTx.AddTxIn(txIn)
+	}
+
+	numTxOuts, err := readCount(r, minTxOutSize)
+	if err != nil {
+		return fmt.Errorf("bad outputs: %v", err)
+	}
+	for i := uint64(0); i < numTxOuts; i++ {
+		txOut := new(wire.TxOut)
+		value, err := readUint64(r)
+		if err != nil {
+			return fmt.Errorf("bad output %v: %v", i, err)
+		}
+		if value > math.MaxInt64 {
+			return fmt.Errorf("bad output %v: value overflows", i)
+		}
+		txOut.Value = int64(value)
+		if txOut.PkScript, err = readVarBytes(r); err != nil {
+			return fmt.Errorf("bad output %v: %v", i, err)
+		}
+		msgTx.AddTxOut(txOut)
+	}
+	return nil
+}
+
+func (tx *DecodedTx) decodeSaplingV4(r *bytes.Reader) error {
+	var err error
+	if tx.SaplingValueBalance, err = readInt64(r); err != nil {
+		return fmt.Errorf("bad value balance: %v", err)
+	}
+	numSpends, err := readCount(r, saplingSpendV4Size)
+	if err != nil {
+		return fmt.Errorf("bad spends: %v", err)
+	}
+	tx.SaplingSpends = make([]SaplingSpend, numSpends)
+	for i := range tx.SaplingSpends {
+		spend := &tx.SaplingSpends[i]
+		if err := readBytes(r, spend.CV[:], spend.Anchor[:], spend.Nullifier[:], spend.RK[:], spend.Proof[:], spend.SpendAuthSig[:]); err != nil {
+			return fmt.Errorf("bad spend %v: %v", i, err)
+		}
+	}
+	numOutputs, err := readCount(r, saplingOutputSize)
+	if err != nil {
+		return fmt.Errorf("bad outputs: %v", err)
+	}
+	tx.SaplingOutputs = make([]SaplingOutput, numOutputs)
+	for i := range tx.SaplingOutputs {
+		output := &tx.SaplingOutputs[i]
+		if err := readBytes(r, output.CV[:], output.CMU[:], output.EphemeralKey[:], output.EncCiphertext[:], output.OutCiphertext[:], output.Proof[:]); err != nil {
+			return fmt.Errorf("bad output %v: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (tx *DecodedTx) decodeSaplingV5(r *bytes.Reader) error {
+	numSpends, err := readCount(r, saplingSpendV5Size)
+	if err != nil {
+		return fmt.Errorf("bad spends: %v", err)
+	}
+	tx.SaplingSpends = make([]SaplingSpend, numSpends)
+	for i := range tx.SaplingSpends {
+		spend := &tx.SaplingSpends[i]
+		if err := readBytes(r, spend.CV[:], spend.Nullifier[:], spend.RK[:]); err != nil {
+			return fmt.Errorf("bad spend %v: %v", i, err)
+		}
+	}
+	numOutputs, err := readCount(r, saplingOutputSize)
+	if err != nil {
+		return fmt.Errorf("bad outputs: %v", err)
+	}
+	tx.SaplingOutputs = make([]SaplingOutput, numOutputs)
+	for i := range tx.SaplingOutputs {
+		output := &tx.SaplingOutputs[i]
+		if err := readBytes(r, output.CV[:], output.CMU[:], output.EphemeralKey[:], output.EncCiphertext[:], output.OutCiphertext[:]); err != nil {
+			return fmt.Errorf("bad output %v: %v", i, err)
+		}
+	}
+	if numSpends+numOutputs > 0 {
+		if tx.SaplingValueBalance, err = readInt64(r); err != nil {
+			return fmt.Errorf("bad value balance: %v", err)
+		}
+	}
+	if numSpends > 0 {
+		// The anchor is shared by all spends.
+		var anchor [32]byte
+		if err := readBytes(r, anchor[:]); err != nil {
+			return fmt.Errorf("bad anchor: %v", err)
+		}
+		for i := range tx.SaplingSpends {
+			tx.SaplingSpends[i].Anchor = anchor
+		}
+	}
+	for i := range tx.SaplingSpends {
+		if err := readBytes(r, tx.SaplingSpends[i].Proof[:]); err != nil {
+			return fmt.Errorf("bad spend %v: %v", i, err)
+		}
+	}
+	for i := range tx.SaplingSpends {
+		if err := readBytes(r, tx.SaplingSpends[i].SpendAuthSig[:]); err != nil {
+			return fmt.Errorf("bad spend %v: %v", i, err)
+		}
+	}
+	for i := range tx.SaplingOutputs {
+		if err := readBytes(r, tx.SaplingOutputs[i].Proof[:]); err != nil {
+			return fmt.Errorf("bad output %v: %v", i, err)
+		}
+	}
+	if numSpends+numOutputs > 0 {
+		if err := readBytes(r, tx.SaplingBindingSig[:]); err != nil {
+			return fmt.Errorf("bad binding sig: %v", err)
+		}
+	}
+	return nil
+}
+
+func (tx *DecodedTx) decodeOrchard(r *bytes.Reader) error {
+	numActions, err := readCount(r, orchardActionSize)
+	if err != nil {
+		return fmt.Errorf("bad actions: %v", err)
+	}
+	tx.OrchardActions = make([]OrchardAction, numActions)
+	for i := range tx.OrchardActions {
+		action := &tx.OrchardActions[i]
+		if err := readBytes(r, action.CV[:], action.Nullifier[:], action.RK[:], action.CMX[:], action.EphemeralKey[:], action.EncCiphertext[:], action.OutCiphertext[:]); err != nil {
+			return fmt.Errorf("bad action %v: %v", i, err)
+		}
+	}
+	if numActions == 0 {
+		return nil
+	}
+	if tx.OrchardFlags, err = r.ReadByte(); err != nil {
+		return fmt.Errorf("bad flags: %v", err)
+	}
+	if tx.OrchardValueBalance, err = readInt64(r); err != nil {
+		return fmt.Errorf("bad value balance: %v", err)
+	}
+	if err := readBytes(r, tx.OrchardAnchor[:]); err != nil {
+		return fmt.Errorf("bad anchor: %v", err)
+	}
+	if tx.OrchardProof, err = readVarBytes(r); err != nil {
+		return fmt.Errorf("bad proof: %v", err)
+	}
+	for i := range tx.OrchardActions {
+		if err := readBytes(r, tx.OrchardActions[i].SpendAuthSig[:]); err != nil {
+			return fmt.Errorf("bad action %v: %v", i, err)
+		}
+	}
+	if err := readBytes(r, tx.OrchardBindingSig[:]); err != nil {
+		return fmt.Errorf("bad binding sig: %v", err)
+	}
+	return nil
+}
+
+func (tx *DecodedTx) decodeJoinSplits(r *bytes.Reader, version int32) error {
+	numJoinSplits, err := readCount(r, 16+joinSplitDataSize(version))
+	if err != nil {
+		return err
+	}
+	dataSize := joinSplitDataSize(version)
+	tx.JoinSplits = make([]JoinSplit, numJoinSplits)
+	for i := range tx.JoinSplits {
+		joinSplit := &tx.JoinSplits[i]
+		if joinSplit.VPubOld, err = readUint64(r); err != nil {
+			return fmt.Errorf("bad joinsplit %v: %v", i, err)
+		}
+		if joinSplit.VPubNew, err = readUint64(r); err != nil {
+			return fmt.Errorf("bad joinsplit %v: %v", i, err)
+		}
+		joinSplit.Data = make([]byte, dataSize)
+		if err := readBytes(r, joinSplit.Data); err != nil {
+			return fmt.Errorf("bad joinsplit %v: %v", i, err)
+		}
+	}
+	if numJoinSplits > 0 {
+		if err := readBytes(r, tx.JoinSplitPubKey[:], tx.JoinSplitSig[:]); err != nil {
+			return fmt.Errorf("bad joinsplit signature: %v", err)
+		}
+	}
+	return nil
+}
+
+// joinSplitDataSize returns the size of a JoinSplit description, apart from
+// its two values. JoinSplits of Sapling transactions use Groth16 proofs, and
+// those of Overwinter transactions use PHGR13 proofs. Each description has an
+// anchor, two nullifiers, two commitments, an ephemeral key, a random seed, two
+// MACs, a proof, and two ciphertexts.
+func joinSplitDataSize(version int32) int {
+	proofSize := 296
+	if version == versionSapling {
+		proofSize = 192
+	}
+	return 32 + 2*32 + 2*32 + 32 + 32 + 2*32 + proofSize + 2*601
+}
+
+// readCount reads a compact size that is the number of elements of a vector.
+// The remaining data must be large enough to hold that many elements of at
+// least the given size, so that malformed transactions cannot cause large
+// allocations.
+func readCount(r *bytes.Reader, minSize int) (uint64, error) {
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, err
+	}
+	if count > maxDecodeCount || count > uint64(r.Len()/minSize) {
+		return 0, fmt.Errorf("count %v is too large", count)
+	}
+	return count, nil
+}
+
+// readVarBytes reads a compact size that is a number of bytes, followed by the
+// bytes. Unlike wire.ReadVarBytes, it does not allocate more bytes than are
+// remaining.
+func readVarBytes(r *bytes.Reader) ([]byte, error) {
+	size, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(r.Len()) {
+		return nil, fmt.Errorf("size %v is too large", size)
+	}
+	data := make([]byte, size)
+	if err := readBytes(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func readBytes(r io.Reader, fields ...[]byte) error {
+	for _, field := range fields {
+		if _, err := io.ReadFull(r, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	var buf [4]byte
+	if err := readBytes(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+func readUint64(r io.Reader) (uint64, error) {
+	var buf [8]byte
+	if err := readBytes(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+func readInt64(r io.Reader) (int64, error) {
+	value, err := readUint64(r)
+	return int64(value), err
+}
@@ -0,0 +1,176 @@
+package zcash_test
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// v5Vector is a version 5 transaction from the test vectors of
+// zcash-test-vectors (transaction_v5.py).
+type v5Vector struct {
+	tx                  []byte
+	txID                string
+	consensusBranchID   uint32
+	lockTime            uint32
+	expiryHeight        uint32
+	numTxIns            int
+	numTxOuts           int
+	numSaplingSpends    int
+	numSaplingOutputs   int
+	saplingValueBalance int64
+	numOrchardActions   int
+	orchardValueBalance int64
+}
+
+func loadV5Vectors() []v5Vector {
+	data, err := os.ReadFile("testdata/tx_v5.json")
+	Expect(err).ToNot(HaveOccurred())
+	var rows [][]interface{}
+	Expect(json.Unmarshal(data, &rows)).To(Succeed())
+
+	// The first two rows are the source, and the names of the columns.
+	vectors := make([]v5Vector, 0, len(rows)-2)
+	for _, row := range rows[2:] {
+		tx, err := hex.DecodeString(row[0].(string))
+		Expect(err).ToNot(HaveOccurred())
+		number := func(i int) int64 {
+			if row[i] == nil {
+				return 0
+			}
+			return int64(row[i].(float64))
+		}
+		vectors = append(vectors, v5Vector{
+			tx:                  tx,
+			txID:                row[1].(string),
+			consensusBranchID:   uint32(number(4)),
+			lockTime:            uint32(number(5)),
+			expiryHeight:        uint32(number(6)),
+			numTxIns:            int(number(7)),
+			numTxOuts:           int(number(8)),
+			numSaplingSpends:    int(number(9)),
+			numSaplingOutputs:   int(number(10)),
+			saplingValueBalance: number(11),
+			numOrchardActions:   int(number(14)),
+			orchardValueBalance: number(16),
+		})
+	}
+	return vectors
+}
+
+// loadRawTxs returns the transactions in a file with one hex-encoded
+// transaction per line. Lines starting with "#" are ignored.
+func loadRawTxs(path string) [][]byte {
+	f, err := os.Open(path)
+	Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+
+	var txs [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tx, err := hex.DecodeString(line)
+		Expect(err).ToNot(HaveOccurred())
+		txs = append(txs, tx)
+	}
+	Expect(scanner.Err()).ToNot(HaveOccurred())
+	return txs
+}
+
+var _ = Describe("DecodeTx", func() {
+	Context("when decoding version 5 transactions", func() {
+		It("should decode the test vectors", func() {
+			vectors := loadV5Vectors()
+			Expect(vectors).ToNot(BeEmpty())
+			for i, vector := range vectors {
+				tx, err := zcash.DecodeTx(vector.tx)
+				Expect(err).ToNot(HaveOccurred(), "vector %v", i)
+				Expect(tx.MsgTx.Version).To(Equal(int32(5)))
+				Expect(tx.ConsensusBranchID).To(Equal(vector.consensusBranchID))
+				Expect(tx.MsgTx.LockTime).To(Equal(vector.lockTime))
+				Expect(tx.ExpiryHeight).To(Equal(vector.expiryHeight))
+				Expect(tx.MsgTx.TxIn).To(HaveLen(vector.numTxIns))
+				Expect(tx.MsgTx.TxOut).To(HaveLen(vector.numTxOuts))
+				Expect(tx.SaplingSpends).To(HaveLen(vector.numSaplingSpends))
+				Expect(tx.SaplingOutputs).To(HaveLen(vector.numSaplingOutputs))
+				Expect(tx.OrchardActions).To(HaveLen(vector.numOrchardActions))
+				Expect(tx.Serialize()).To(Equal(vector.tx))
+
+				txID, err := tx.TxID()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(txID.String()).To(Equal(vector.txID), "vector %v", i)
+			}
+		})
+	})
+
+	Context("when decoding version 3 and 4 transactions", func() {
+		// Test vectors from ZIP-143 and ZIP-243, which have JoinSplits, and
+		// Sapling spends and outputs.
+		for _, test := range []struct {
+			path    string
+			version int32
+		}{
+			{"testdata/zip143_raw_tx", 3},
+			{"testdata/zip243_raw_tx", 4},
+		} {
+			test := test
+			It("should decode "+test.path, func() {
+				txs := loadRawTxs(test.path)
+				Expect(txs).To(HaveLen(2))
+				for _, data := range txs {
+					tx, err := zcash.DecodeTx(data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(tx.MsgTx.Version).To(Equal(test.version))
+					Expect(tx.ConsensusBranchID).To(BeZero())
+					Expect(tx.Serialize()).To(Equal(data))
+
+					txID, err := tx.TxID()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(txID).To(Equal(chainhash.DoubleHashH(data)))
+				}
+			})
+		}
+	})
+
+	Context("when decoding malformed transactions", func() {
+		It("should return an error", func() {
+			txs := append(loadRawTxs("testdata/zip243_raw_tx"), loadRawTxs("testdata/zip143_raw_tx")...)
+			for _, vector := range loadV5Vectors() {
+				txs = append(txs, vector.tx)
+			}
+			for _, data := range txs {
+				// Truncated transactions.
+				for _, n := range []int{0, 3, 4, 8, 9, 20, len(data) / 2, len(data) - 1} {
+					_, err := zcash.DecodeTx(data[:n])
+					Expect(err).To(HaveOccurred())
+				}
+				// Trailing bytes.
+				_, err := zcash.DecodeTx(append(append([]byte{}, data...), 0))
+				Expect(err).To(HaveOccurred())
+			}
+
+			// Transactions that are not overwintered, or that have an
+			// unknown version group id.
+			data := append([]byte{}, txs[0]...)
+			data[3] = 0x00
+			_, err := zcash.DecodeTx(data)
+			Expect(err).To(HaveOccurred())
+			data = append([]byte{}, txs[0]...)
+			data[4] ^= 0xff
+			_, err = zcash.DecodeTx(data)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
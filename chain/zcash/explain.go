@@ -0,0 +1,273 @@
+package zcash
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+// ExplainedInput re-exports bitcoin.ExplainedInput.
+type ExplainedInput = bitcoin.ExplainedInput
+
+// ExplainedOutput re-exports bitcoin.ExplainedOutput.
+type ExplainedOutput = bitcoin.ExplainedOutput
+
+// ExplainedScript re-exports bitcoin.ExplainedScript.
+type ExplainedScript = bitcoin.ExplainedScript
+
+// An Explanation is a structured, human-readable view of a Zcash transaction.
+// It follows the layout of the result of the `decoderawtransaction` RPC call
+// of zcashd, and its JSON encoding is stable. Values are in zatoshis. Shielded
+// components are summarised by their number and their value balance.
+type Explanation struct {
+	TxID              string            `json:"txid"`
+	Version           int32             `json:"version"`
+	Overwintered      bool              `json:"overwintered"`
+	VersionGroupID    string            `json:"versiongroupid"`
+	ConsensusBranchID string            `json:"consensusbranchid"`
+	Size              int               `json:"size"`
+	LockTime          uint32            `json:"locktime"`
+	ExpiryHeight      uint32            `json:"expiryheight"`
+	Inputs            []ExplainedInput  `json:"vin"`
+	Outputs           []ExplainedOutput `json:"vout"`
+
+	SaplingSpends       int   `json:"saplingSpends"`
+	SaplingOutputs      int   `json:"saplingOutputs"`
+	SaplingValueBalance int64 `json:"valueBalanceSapling"`
+	JoinSplits          int   `json:"joinSplits"`
+	OrchardActions      int   `json:"orchardActions"`
+	OrchardValueBalance int64 `json:"valueBalanceOrchard"`
+
+	// Fee is only known if the outputs spent by all inputs are known.
+	Fee *int64 `json:"fee,omitempty"`
+}
+
+// Sighashes returns the SIGHASH_ALL digests of the transparent inputs of the
+// transaction. The inputs must be the inputs of the transaction, in order,
+// with the outputs that they spend, as they would be passed to the TxBuilder.
+// Version 5 transactions use the ZIP-244 digest. Earlier versions use the
+// ZIP-243 digest (or the ZIP-143 digest for version 3), with the consensus
+// branch that is active at the expiry height.
+func (tx *DecodedTx) Sighashes(inputs []utxo.Input, params *Params) ([]pack.Bytes32, error) {
+	values, err := tx.inputValues(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return tx.sighashes(inputs, values, params)
+}
+
+// sighashes returns the sighashes of the transparent inputs, which must have
+// been checked by inputValues.
+func (tx *DecodedTx) sighashes(inputs []utxo.Input, values []int64, params *Params) ([]pack.Bytes32, error) {
+	shielded, err := tx.shieldedDigests()
+	if err != nil {
+		return nil, err
+	}
+
+	sighashes := make([]pack.Bytes32, len(inputs))
+	for i, input := range inputs {
+		if tx.MsgTx.Version >= versionNU5 {
+			hash, err := tx.signatureDigest(inputs, i)
+			if err != nil {
+				return nil, err
+			}
+			sighashes[i] = pack.NewBytes32(hash)
+			continue
+		}
+
+		scriptCode := []byte(input.PubKeyScript)
+		if input.SigScript != nil {
+			scriptCode = input.SigScript
+		}
+		hash, err := calculateSighash(params, scriptCode, txscript.SigHashAll, tx.MsgTx, i, values[i], tx.ExpiryHeight, shielded)
+		if err != nil {
+			return nil, err
+		}
+		copy(sighashes[i][:], hash)
+	}
+	return sighashes, nil
+}
+
+// Verify executes the signature script of each transparent input of the
+// transaction against the pubkey script of the output that it spends (see
+// Tx.Verify). The inputs must be the inputs of the transaction, in order, with
+// the outputs that they spend. Only version 4 transactions with no shielded
+// components are supported.
+func (tx *DecodedTx) Verify(inputs []utxo.Input, params *Params) ([]error, error) {
+	if err := bitcoin.CheckExplainedInputs(tx.MsgTx, inputs); err != nil {
+		return nil, err
+	}
+	if tx.MsgTx.Version != versionSapling || !tx.IsTransparent() {
+		return nil, fmt.Errorf("expected a transparent version %v transaction, got version %v", versionSapling, tx.MsgTx.Version)
+	}
+	return (&Tx{inputs: inputs, msgTx: tx.MsgTx, params: params, expiryHeight: tx.ExpiryHeight, signed: true}).Verify()
+}
+
+// ExplainRawTx decodes a serialized transaction and explains it. See Explain.
+func ExplainRawTx(data []byte, inputs []utxo.Input, params *Params) (Explanation, error) {
+	tx, err := DecodeTx(data)
+	if err != nil {
+		return Explanation{}, err
+	}
+	return Explain(tx, inputs, params)
+}
+
+// Explain returns a structured view of the decoded transaction. The inputs are
+// the inputs of the transaction, in order, with the outputs that they spend,
+// as they would be passed to the TxBuilder. If they are nil, the values of the
+// inputs, the fee, and the sighashes are omitted.
+// Addresses of outputs are encoded using the AddressEncoder for the network.
+func Explain(tx *DecodedTx, inputs []utxo.Input, params *Params) (Explanation, error) {
+	var sighashes []pack.Bytes32
+	var fee *int64
+	if inputs != nil {
+		values, err := tx.inputValues(inputs)
+		if err != nil {
+			return Explanation{}, err
+		}
+		if sighashes, err = tx.sighashes(inputs, values, params); err != nil {
+			return Explanation{}, fmt.Errorf("bad sighashes: %v", err)
+		}
+		if fee, err = tx.fee(values); err != nil {
+			return Explanation{}, err
+		}
+	}
+
+	txID, err := tx.TxID()
+	if err != nil {
+		return Explanation{}, fmt.Errorf("bad txid: %v", err)
+	}
+	consensusBranchID := tx.ConsensusBranchID
+	if tx.MsgTx.Version < versionNU5 {
+		consensusBranchID = binary.LittleEndian.Uint32(branchID(tx.ExpiryHeight, params))
+	}
+	encoder := NewAddressEncoder(params)
+	return Explanation{
+		TxID:              txID.String(),
+		Version:           tx.MsgTx.Version,
+		Overwintered:      true,
+		VersionGroupID:    fmt.Sprintf("%08x", tx.VersionGroupID),
+		ConsensusBranchID: fmt.Sprintf("%08x", consensusBranchID),
+		Size:              len(tx.serialized),
+		LockTime:          tx.MsgTx.LockTime,
+		ExpiryHeight:      tx.ExpiryHeight,
+		Inputs:            bitcoin.ExplainInputs(tx.MsgTx.TxIn, inputs, sighashes),
+		Outputs: bitcoin.ExplainOutputs(tx.MsgTx.TxOut, func(script []byte) (address.RawAddress, bool) {
+			return RawAddressFromScript(script, params)
+		}, encoder),
+
+		SaplingSpends:       len(tx.SaplingSpends),
+		SaplingOutputs:      len(tx.SaplingOutputs),
+		SaplingValueBalance: tx.SaplingValueBalance,
+		JoinSplits:          len(tx.JoinSplits),
+		OrchardActions:      len(tx.OrchardActions),
+		OrchardValueBalance: tx.OrchardValueBalance,
+
+		Fee: fee,
+	}, nil
+}
+
+// Explain returns a structured view of the transaction. See Explain.
+func (tx *Tx) Explain() (Explanation, error) {
+	serialized, err := tx.Serialize()
+	if err != nil {
+		return Explanation{}, err
+	}
+	decoded, err := DecodeTx(serialized)
+	if err != nil {
+		return Explanation{}, err
+	}
+	return Explain(decoded, tx.inputs, tx.params)
+}
+
+// RawAddressFromScript returns the raw address, in the format expected by the
+// AddressEncoder, that is paid by a P2PKH or P2SH pubkey script. It returns
+// false for all other scripts.
+func RawAddressFromScript(script []byte, params *Params) (address.RawAddress, bool) {
+	addr, err := ExtractPkScriptAddrs(script, params)
+	if err != nil {
+		return nil, false
+	}
+	return address.RawAddress(base58.Decode(addr.EncodeAddress())), true
+}
+
+// inputValues checks that the inputs are the inputs of the transaction, in
+// order, and returns their values. An error is returned if a value is greater
+// than MaxZatoshi.
+func (tx *DecodedTx) inputValues(inputs []utxo.Input) ([]int64, error) {
+	if err := bitcoin.CheckExplainedInputs(tx.MsgTx, inputs); err != nil {
+		return nil, err
+	}
+	values := make([]int64, len(inputs))
+	for i, input := range inputs {
+		value, err := NewAmountFromU256(input.Value)
+		if err != nil {
+			return nil, fmt.Errorf("bad input %v: %w", i, err)
+		}
+		values[i] = int64(value)
+	}
+	return values, nil
+}
+
+// fee returns the implied fee of the transaction: the value that flows into
+// its transparent and shielded pools, minus the value that flows out of them.
+// The values are the values of the inputs, as returned by inputValues. The
+// values of the outputs, the value balances, and the values of JoinSplits are
+// decoded from the transaction, so they are checked to be in range (as
+// MoneyRange does in zcashd), and the sum is checked for overflow.
+func (tx *DecodedTx) fee(values []int64) (*int64, error) {
+	in := make([]Amount, 0, len(values)+2+len(tx.JoinSplits))
+	out := make([]Amount, 0, len(tx.MsgTx.TxOut)+len(tx.JoinSplits))
+	for _, value := range values {
+		in = append(in, Amount(value))
+	}
+	for i, txOut := range tx.MsgTx.TxOut {
+		if err := Amount(txOut.Value).Validate(); err != nil {
+			return nil, fmt.Errorf("bad output %v: %w", i, err)
+		}
+		out = append(out, Amount(txOut.Value))
+	}
+	for _, balance := range []struct {
+		name  string
+		value int64
+	}{
+		{"sapling value balance", tx.SaplingValueBalance},
+		{"orchard value balance", tx.OrchardValueBalance},
+	} {
+		if balance.value < -MaxZatoshi || balance.value > MaxZatoshi {
+			return nil, fmt.Errorf("bad %v: %w: %v", balance.name, ErrAmountOutOfRange, balance.value)
+		}
+		in = append(in, Amount(balance.value))
+	}
+	for i, joinSplit := range tx.JoinSplits {
+		if joinSplit.VPubOld > MaxZatoshi || joinSplit.VPubNew > MaxZatoshi {
+			return nil, fmt.Errorf("bad joinsplit %v: %w: vpub_old %v, vpub_new %v", i, ErrAmountOutOfRange, joinSplit.VPubOld, joinSplit.VPubNew)
+		}
+		in = append(in, Amount(joinSplit.VPubNew))
+		out = append(out, Amount(joinSplit.VPubOld))
+	}
+
+	fee := Amount(0)
+	var err error
+	for _, value := range in {
+		if fee, err = fee.Add(value); err != nil {
+			return nil, fmt.Errorf("bad fee: %w", err)
+		}
+	}
+	for _, value := range out {
+		if fee, err = fee.Sub(value); err != nil {
+			return nil, fmt.Errorf("bad fee: %w", err)
+		}
+	}
+	if fee < 0 {
+		return nil, fmt.Errorf("bad fee: outputs exceed inputs by %v", -int64(fee))
+	}
+	feeValue := int64(fee)
+	return &feeValue, nil
+}
@@ -0,0 +1,267 @@
+package zcash_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Explain", func() {
+	params := &zcash.RegressionNetParams
+	pk := id.NewPrivKey()
+	pubKey := (*btcec.PrivateKey)(pk).PubKey().SerializeCompressed()
+	pkhAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey), params)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(pkhAddr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+	inputs := []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pubKeyScript}},
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(1)}, Value: pack.NewU256FromUint64(50000), PubKeyScript: pubKeyScript}},
+	}
+	recipients := []utxo.Recipient{
+		{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(140000)},
+		{Data: pack.Bytes("memo"), Value: pack.NewU256FromUint64(0)},
+	}
+
+	Context("when explaining built transactions", func() {
+		It("should explain the inputs, outputs, fee, and sighashes", func() {
+			tx, err := zcash.NewConfigurableTxBuilder(params, 1000).WithLockTime(10).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			hash, err := tx.Hash()
+			Expect(err).ToNot(HaveOccurred())
+
+			explanation, err := tx.(*zcash.Tx).Explain()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(explanation.Version).To(Equal(int32(4)))
+			Expect(explanation.VersionGroupID).To(Equal("892f2085"))
+			Expect(explanation.ConsensusBranchID).To(Equal("e9ff75a6"))
+			Expect(explanation.LockTime).To(Equal(uint32(10)))
+			Expect(explanation.ExpiryHeight).To(Equal(uint32(1000)))
+			Expect(explanation.TxID).To(Equal(hex.EncodeToString(reverse(hash))))
+			Expect(*explanation.Fee).To(Equal(int64(10000)))
+
+			Expect(explanation.Inputs).To(HaveLen(2))
+			for i, input := range explanation.Inputs {
+				Expect(input.Vout).To(Equal(uint32(i)))
+				Expect(*input.Value).To(Equal(inputs[i].Value.Int().Int64()))
+				Expect(input.Sighash).To(Equal(hex.EncodeToString(sighashes[i][:])))
+				Expect(input.ScriptSig.Hex).To(BeEmpty())
+			}
+
+			Expect(explanation.Outputs).To(HaveLen(2))
+			Expect(explanation.Outputs[0].Value).To(Equal(int64(140000)))
+			Expect(explanation.Outputs[0].ScriptPubKey.Type).To(Equal("pubkeyhash"))
+			Expect(explanation.Outputs[0].ScriptPubKey.Address).To(Equal(pkhAddr.EncodeAddress()))
+			Expect(explanation.Outputs[0].ScriptPubKey.Asm).To(HavePrefix("OP_DUP OP_HASH160"))
+			Expect(explanation.Outputs[1].N).To(Equal(uint32(1)))
+			Expect(explanation.Outputs[1].ScriptPubKey.Type).To(Equal("nulldata"))
+			Expect(explanation.Outputs[1].ScriptPubKey.Address).To(BeEmpty())
+
+			// Signing does not change the sighashes, but does change the
+			// scriptSigs.
+			signatures := make([]pack.Bytes65, len(sighashes))
+			for i := range sighashes {
+				hash := id.Hash(sighashes[i])
+				signature, err := pk.Sign(&hash)
+				Expect(err).ToNot(HaveOccurred())
+				signatures[i] = pack.NewBytes65(signature)
+			}
+			Expect(tx.Sign(signatures, pack.NewBytes(pubKey))).To(Succeed())
+			signed, err := tx.(*zcash.Tx).Explain()
+			Expect(err).ToNot(HaveOccurred())
+			for i, input := range signed.Inputs {
+				Expect(input.Sighash).To(Equal(explanation.Inputs[i].Sighash))
+				Expect(input.ScriptSig.Asm).To(HaveSuffix(hex.EncodeToString(pubKey)))
+			}
+
+			// The signatures can be verified after decoding, but only against
+			// the outputs that were signed.
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			decoded, err := zcash.DecodeTx(serialized)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = decoded.Verify(inputs, params)
+			Expect(err).ToNot(HaveOccurred())
+			wrongInputs := append([]utxo.Input{}, inputs...)
+			wrongInputs[1].Value = pack.NewU256FromUint64(50001)
+			results, err := decoded.Verify(wrongInputs, params)
+			Expect(err).To(HaveOccurred())
+			Expect(results[0]).ToNot(HaveOccurred())
+			Expect(results[1]).To(HaveOccurred())
+		})
+
+		It("should produce stable JSON", func() {
+			tx, err := zcash.NewTxBuilder(params, 1000).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+
+			explanation, err := zcash.ExplainRawTx(serialized, nil, params)
+			Expect(err).ToNot(HaveOccurred())
+			data, err := json.Marshal(explanation)
+			Expect(err).ToNot(HaveOccurred())
+			again, err := json.Marshal(explanation)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(again))
+
+			// Without the inputs, the values, fee, and sighashes are omitted.
+			var fields map[string]interface{}
+			Expect(json.Unmarshal(data, &fields)).To(Succeed())
+			Expect(fields).ToNot(HaveKey("fee"))
+			Expect(fields).To(HaveKeyWithValue("txid", explanation.TxID))
+			Expect(fields).To(HaveKeyWithValue("versiongroupid", "892f2085"))
+			vin := fields["vin"].([]interface{})
+			Expect(vin).To(HaveLen(2))
+			Expect(vin[0]).ToNot(HaveKey("value"))
+			Expect(vin[0]).ToNot(HaveKey("sighash"))
+			Expect(vin[0]).To(HaveKey("scriptSig"))
+			vout := fields["vout"].([]interface{})
+			Expect(vout[0]).To(HaveKeyWithValue("value", float64(140000)))
+
+			var decoded zcash.Explanation
+			Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(explanation))
+		})
+
+		It("should reject inputs that do not match the transaction", func() {
+			tx, err := zcash.NewTxBuilder(params, 1000).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = zcash.ExplainRawTx(serialized, inputs[:1], params)
+			Expect(err).To(HaveOccurred())
+			_, err = zcash.ExplainRawTx(serialized, []utxo.Input{inputs[1], inputs[0]}, params)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject input values that are out of range", func() {
+			tx, err := zcash.NewTxBuilder(params, 1000).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			decoded, err := zcash.DecodeTx(serialized)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Values that do not fit in an int64 must not wrap around.
+			for _, value := range []pack.U256{
+				pack.NewU256FromUint64(zcash.MaxZatoshi + 1),
+				pack.NewU256FromUint64(1 << 63),
+				pack.MaxU256,
+			} {
+				badInputs := append([]utxo.Input{}, inputs...)
+				badInputs[0].Value = value
+				_, err = zcash.Explain(decoded, badInputs, params)
+				Expect(err).To(MatchError(zcash.ErrAmountOutOfRange))
+				_, err = decoded.Sighashes(badInputs, params)
+				Expect(err).To(MatchError(zcash.ErrAmountOutOfRange))
+			}
+		})
+
+		It("should reject decoded values that are out of range", func() {
+			tx, err := zcash.NewTxBuilder(params, 1000).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+
+			// Values that would overflow the sum, and show a plausible fee,
+			// must be rejected.
+			for _, mutate := range []func(*zcash.DecodedTx){
+				func(decoded *zcash.DecodedTx) { decoded.MsgTx.TxOut[1].Value = -(1 << 62) },
+				func(decoded *zcash.DecodedTx) { decoded.MsgTx.TxOut[1].Value = zcash.MaxZatoshi + 1 },
+				func(decoded *zcash.DecodedTx) { decoded.SaplingValueBalance = 1 << 62 },
+				func(decoded *zcash.DecodedTx) { decoded.OrchardValueBalance = -zcash.MaxZatoshi - 1 },
+				func(decoded *zcash.DecodedTx) {
+					decoded.JoinSplits = []zcash.JoinSplit{{VPubNew: 1<<63 + 10000}}
+				},
+			} {
+				decoded, err := zcash.DecodeTx(serialized)
+				Expect(err).ToNot(HaveOccurred())
+				mutate(decoded)
+				_, err = zcash.Explain(decoded, inputs, params)
+				Expect(err).To(MatchError(zcash.ErrAmountOutOfRange))
+			}
+
+			// Values in range are summed.
+			decoded, err := zcash.DecodeTx(serialized)
+			Expect(err).ToNot(HaveOccurred())
+			decoded.SaplingValueBalance = zcash.MaxZatoshi
+			decoded.JoinSplits = []zcash.JoinSplit{{VPubOld: zcash.MaxZatoshi}}
+			explanation, err := zcash.Explain(decoded, inputs, params)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*explanation.Fee).To(Equal(int64(10000)))
+		})
+	})
+
+	Context("when explaining version 5 transactions", func() {
+		It("should explain the shielded components", func() {
+			for _, vector := range loadV5Vectors() {
+				explanation, err := zcash.ExplainRawTx(vector.tx, nil, params)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(explanation.TxID).To(Equal(vector.txID))
+				Expect(explanation.Version).To(Equal(int32(5)))
+				Expect(explanation.Size).To(Equal(len(vector.tx)))
+				Expect(explanation.SaplingSpends).To(Equal(vector.numSaplingSpends))
+				Expect(explanation.SaplingOutputs).To(Equal(vector.numSaplingOutputs))
+				Expect(explanation.SaplingValueBalance).To(Equal(vector.saplingValueBalance))
+				Expect(explanation.OrchardActions).To(Equal(vector.numOrchardActions))
+				Expect(explanation.OrchardValueBalance).To(Equal(vector.orchardValueBalance))
+				Expect(explanation.Inputs).To(HaveLen(vector.numTxIns))
+				Expect(explanation.Outputs).To(HaveLen(vector.numTxOuts))
+			}
+		})
+
+		It("should compute sighashes that commit to the spent outputs", func() {
+			for _, vector := range loadV5Vectors() {
+				tx, err := zcash.DecodeTx(vector.tx)
+				Expect(err).ToNot(HaveOccurred())
+				if len(tx.MsgTx.TxIn) == 0 {
+					continue
+				}
+				spent := make([]utxo.Input, len(tx.MsgTx.TxIn))
+				for i, txIn := range tx.MsgTx.TxIn {
+					spent[i] = utxo.Input{Output: utxo.Output{
+						Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(txIn.PreviousOutPoint.Hash[:]), Index: pack.NewU32(txIn.PreviousOutPoint.Index)},
+						Value:        pack.NewU256FromUint64(100000),
+						PubKeyScript: pubKeyScript,
+					}}
+				}
+				sighashes, err := tx.Sighashes(spent, params)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sighashes).To(HaveLen(len(spent)))
+
+				spent[0].Value = pack.NewU256FromUint64(100001)
+				changed, err := tx.Sighashes(spent, params)
+				Expect(err).ToNot(HaveOccurred())
+				for i := range sighashes {
+					Expect(changed[i]).ToNot(Equal(sighashes[i]))
+				}
+			}
+		})
+	})
+})
+
+func reverse(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i := range data {
+		reversed[len(data)-1-i] = data[i]
+	}
+	return reversed
+}
@@ -0,0 +1,61 @@
+package zcash
+
+import (
+	"github.com/btcsuite/btcd/txscript"
+	btcbech32 "github.com/btcsuite/btcutil/bech32"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/internal/bech32"
+)
+
+// This file exports internals of the package to its tests.
+
+// AddressFromRawBytes exports addressFromRawBytes.
+var AddressFromRawBytes = addressFromRawBytes
+
+// SignatureHash returns the ZIP-143 or ZIP-243 sighash of an input of the
+// transaction, using any sighash type, and the consensus branch that is active
+// at the expiry height of the transaction. The index is -1 for the sighash
+// that commits to no input.
+func (tx *DecodedTx) SignatureHash(params *Params, scriptCode []byte, hashType txscript.SigHashType, idx int, amount int64) ([]byte, error) {
+	shielded, err := tx.shieldedDigests()
+	if err != nil {
+		return nil, err
+	}
+	if idx < 0 {
+		idx = notAnInput
+	}
+	return calculateSighash(params, scriptCode, hashType, tx.MsgTx, idx, amount, tx.ExpiryHeight, shielded)
+}
+
+// Tx returns the transaction as it would be built by the TxBuilder. The
+// transaction must not have shielded components.
+func (tx *DecodedTx) Tx(params *Params) *Tx {
+	return &Tx{msgTx: tx.MsgTx, params: params, expiryHeight: tx.ExpiryHeight, signed: true}
+}
+
+// EncodeUnifiedAddress returns the unified address with the human readable
+// part, and the encoding of its receivers. The receivers are not checked.
+func EncodeUnifiedAddress(hrp string, receivers []byte) address.Address {
+	padding := make([]byte, unifiedPaddingSize)
+	copy(padding, hrp)
+	values, err := btcbech32.ConvertBits(f4Jumble(append(append([]byte{}, receivers...), padding...)), 8, 5, true)
+	if err != nil {
+		panic(err)
+	}
+	return address.Address(bech32.Encode(hrp, values, bech32.Bech32m))
+}
+
+// f4Jumble applies the F4Jumble permutation to a message (as defined by
+// ZIP-316). Only the inverse is needed to decode addresses.
+func f4Jumble(m []byte) []byte {
+	leftSize := len(m) / 2
+	if leftSize > f4JumbleHashSize {
+		leftSize = f4JumbleHashSize
+	}
+	a, b := m[:leftSize], m[leftSize:]
+	x := xorBytes(b, f4JumbleG(0, a, len(b)))
+	y := xorBytes(a, f4JumbleH(0, x, len(a)))
+	d := xorBytes(x, f4JumbleG(1, y, len(x)))
+	c := xorBytes(y, f4JumbleH(1, d, len(y)))
+	return append(c, d...)
+}
@@ -0,0 +1,91 @@
+package zcash
+
+import (
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultMaxFee is the default maximum absolute fee (in zatoshi) of a
+	// transaction. This is the same as the default -maxtxfee of zcashd.
+	DefaultMaxFee = 10000000
+	// DefaultMaxFeeRate is the default maximum fee rate (in
+	// zatoshi-per-byte) of a transaction.
+	DefaultMaxFeeRate = 1000
+	// DefaultDustThreshold is the default minimum value (in zatoshi) of an
+	// output. Outputs below this value are rejected by the relay policy of
+	// zcashd.
+	DefaultDustThreshold = 54
+
+	// MarginalFee is the ZIP-317 fee (in zatoshi) of each logical action of
+	// a transaction.
+	MarginalFee = 5000
+	// GraceActions is the ZIP-317 number of logical actions that every
+	// transaction pays for, regardless of how many it has.
+	GraceActions = 2
+	// P2PKHStandardInputSize is the ZIP-317 size (in bytes) of a transparent
+	// input. Transparent inputs count as one logical action per this many
+	// bytes.
+	P2PKHStandardInputSize = 150
+	// P2PKHStandardOutputSize is the ZIP-317 size (in bytes) of a
+	// transparent output. Transparent outputs count as one logical action per
+	// this many bytes.
+	P2PKHStandardOutputSize = 34
+)
+
+// LogicalActions returns the ZIP-317 number of logical actions of a
+// transaction whose transparent inputs and outputs have the given total sizes
+// (in bytes), and which has the given number of shielded logical actions.
+func LogicalActions(txInTotalSize, txOutTotalSize, shieldedActions int) int {
+	inActions := (txInTotalSize + P2PKHStandardInputSize - 1) / P2PKHStandardInputSize
+	outActions := (txOutTotalSize + P2PKHStandardOutputSize - 1) / P2PKHStandardOutputSize
+	if inActions > outActions {
+		return inActions + shieldedActions
+	}
+	return outActions + shieldedActions
+}
+
+// ConventionalFee returns the ZIP-317 conventional fee (in zatoshi) of a
+// transaction with the given number of logical actions.
+func ConventionalFee(logicalActions int) pack.U256 {
+	if logicalActions < GraceActions {
+		logicalActions = GraceActions
+	}
+	return pack.NewU256FromUint64(MarginalFee * uint64(logicalActions))
+}
+
+// P2PKHConventionalFee is a bitcoin.FeeFunc that pays the ZIP-317
+// conventional fee of a transaction with P2PKH inputs and outputs. Each input
+// is counted as a standard input, which is never smaller than a signed P2PKH
+// input, so every input adds the marginal fee. The fee rate is ignored.
+func P2PKHConventionalFee(feeRate pack.U256, numInputs, numOutputs int) pack.U256 {
+	return ConventionalFee(LogicalActions(P2PKHStandardInputSize*numInputs, P2PKHStandardOutputSize*numOutputs, 0))
+}
+
+// FeePolicy re-exports bitcoin.FeePolicy.
+type FeePolicy = bitcoin.FeePolicy
+
+// DefaultFeePolicy returns a FeePolicy with the default settings. These
+// settings match the default relay policy of zcashd.
+func DefaultFeePolicy() FeePolicy {
+	return FeePolicy{
+		MaxFee:        pack.NewU256FromUint64(DefaultMaxFee),
+		MaxFeeRate:    pack.NewU256FromUint64(DefaultMaxFeeRate),
+		DustThreshold: pack.NewU256FromUint64(DefaultDustThreshold),
+	}
+}
+
+// ErrDuplicateInput re-exports bitcoin.ErrDuplicateInput.
+type ErrDuplicateInput = bitcoin.ErrDuplicateInput
+
+// ErrDustOutput re-exports bitcoin.ErrDustOutput.
+type ErrDustOutput = bitcoin.ErrDustOutput
+
+// ErrInsufficientInputs re-exports bitcoin.ErrInsufficientInputs.
+type ErrInsufficientInputs = bitcoin.ErrInsufficientInputs
+
+// ErrFeeTooHigh re-exports bitcoin.ErrFeeTooHigh.
+type ErrFeeTooHigh = bitcoin.ErrFeeTooHigh
+
+// ErrFeeRateTooHigh re-exports bitcoin.ErrFeeRateTooHigh.
+type ErrFeeRateTooHigh = bitcoin.ErrFeeRateTooHigh
@@ -0,0 +1,122 @@
+package zcash_test
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fee", func() {
+	pk := id.NewPrivKey()
+	pkhAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160((*btcec.PrivateKey)(pk).PubKey().SerializeCompressed()), &zcash.RegressionNetParams)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(pkhAddr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+
+	input := func(index uint32, value uint64) utxo.Input {
+		return utxo.Input{Output: utxo.Output{
+			Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(index)},
+			Value:        pack.NewU256FromUint64(value),
+			PubKeyScript: pack.NewBytes(pubKeyScript),
+		}}
+	}
+	recipient := func(value uint64) utxo.Recipient {
+		return utxo.Recipient{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(value)}
+	}
+	builder := zcash.NewTxBuilder(&zcash.RegressionNetParams, 1000000)
+
+	Context("when building transactions", func() {
+		It("should return the configurable builder as the interface", func() {
+			configurable, ok := builder.(zcash.TxBuilder)
+			Expect(ok).To(BeTrue())
+			Expect(configurable).To(Equal(zcash.NewConfigurableTxBuilder(&zcash.RegressionNetParams, 1000000)))
+		})
+
+		It("should expose the implicit fee", func() {
+			tx, err := builder.BuildTx([]utxo.Input{input(0, 100000), input(1, 50000)}, []utxo.Recipient{recipient(60000), recipient(80000)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.(*zcash.Tx).Fee()).To(Equal(pack.NewU256FromUint64(10000)))
+		})
+
+		It("should enforce the zcashd relay policy by default", func() {
+			_, err := builder.BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(100001)})
+			Expect(errors.As(err, &zcash.ErrInsufficientInputs{})).To(BeTrue())
+
+			_, err = builder.BuildTx([]utxo.Input{input(0, 100000), input(0, 100000)}, []utxo.Recipient{recipient(100000)})
+			Expect(errors.As(err, &zcash.ErrDuplicateInput{})).To(BeTrue())
+
+			_, err = builder.BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(90000), recipient(53)})
+			Expect(errors.As(err, &zcash.ErrDustOutput{})).To(BeTrue())
+			_, err = builder.BuildTx([]utxo.Input{input(0, 100000)}, []utxo.Recipient{recipient(90000), recipient(54)})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = builder.BuildTx([]utxo.Input{input(0, 100000000)}, []utxo.Recipient{recipient(1000)})
+			Expect(errors.As(err, &zcash.ErrFeeTooHigh{})).To(BeTrue())
+
+			_, err = builder.BuildTx([]utxo.Input{input(0, 1000000)}, []utxo.Recipient{recipient(1000)})
+			Expect(errors.As(err, &zcash.ErrFeeRateTooHigh{})).To(BeTrue())
+		})
+
+		It("should not underestimate the signed size", func() {
+			tx, err := builder.BuildTx([]utxo.Input{input(0, 100000), input(1, 100000)}, []utxo.Recipient{recipient(190000)})
+			Expect(err).ToNot(HaveOccurred())
+			estimate, err := tx.(*zcash.Tx).EstimateSize()
+			Expect(err).ToNot(HaveOccurred())
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			signatures := make([]pack.Bytes65, len(sighashes))
+			for i := range sighashes {
+				hash := id.Hash(sighashes[i])
+				signature, err := pk.Sign(&hash)
+				Expect(err).ToNot(HaveOccurred())
+				signatures[i] = pack.NewBytes65(signature)
+			}
+			Expect(tx.Sign(signatures, pack.NewBytes((*btcec.PrivateKey)(pk).PubKey().SerializeCompressed()))).To(Succeed())
+			serial, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(estimate).To(BeNumerically(">=", len(serial)))
+			Expect(estimate).To(BeNumerically("<=", len(serial)+4))
+		})
+	})
+
+	Context("when computing the ZIP-317 conventional fee", func() {
+		It("should count transparent inputs and outputs by size", func() {
+			Expect(zcash.LogicalActions(0, 0, 0)).To(Equal(0))
+			Expect(zcash.LogicalActions(148, 34, 0)).To(Equal(1))
+			Expect(zcash.LogicalActions(3*148, 2*34, 0)).To(Equal(3))
+			Expect(zcash.LogicalActions(148, 3*34, 0)).To(Equal(3))
+			Expect(zcash.LogicalActions(151, 34, 0)).To(Equal(2))
+			Expect(zcash.LogicalActions(148, 34, 4)).To(Equal(5))
+		})
+
+		It("should charge the marginal fee for each action beyond the grace actions", func() {
+			Expect(zcash.ConventionalFee(0)).To(Equal(pack.NewU256FromUint64(10000)))
+			Expect(zcash.ConventionalFee(2)).To(Equal(pack.NewU256FromUint64(10000)))
+			Expect(zcash.ConventionalFee(3)).To(Equal(pack.NewU256FromUint64(15000)))
+			Expect(zcash.ConventionalFee(100)).To(Equal(pack.NewU256FromUint64(500000)))
+		})
+
+		It("should estimate the fee of transactions with P2PKH inputs and outputs", func() {
+			var fee bitcoin.FeeFunc = zcash.P2PKHConventionalFee
+			Expect(fee(pack.NewU256FromUint64(10), 1, 1)).To(Equal(pack.NewU256FromUint64(10000)))
+			Expect(fee(pack.NewU256FromUint64(10), 3, 2)).To(Equal(pack.NewU256FromUint64(15000)))
+			Expect(fee(pack.NewU256FromUint64(0), 1, 4)).To(Equal(pack.NewU256FromUint64(20000)))
+		})
+	})
+})
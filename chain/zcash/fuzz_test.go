@@ -0,0 +1,126 @@
+//go:build go1.18
+// +build go1.18
+
+package zcash_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+
+	. "github.com/onsi/gomega"
+)
+
+// The fuzz targets are seeded with the test vectors, and run as regular tests
+// by `go test`. Run them with `go test -fuzz=<target>`.
+
+var fuzzParams = []*zcash.Params{&zcash.MainNetParams, &zcash.TestNet3Params, &zcash.RegressionNetParams}
+
+func FuzzDecodeTx(f *testing.F) {
+	// The test vectors are loaded using Gomega.
+	RegisterTestingT(f)
+	for _, path := range []string{"testdata/zip_0143.json", "testdata/zip_0243.json"} {
+		for _, vector := range loadSighashVectors(path) {
+			f.Add(vector.tx)
+		}
+	}
+	for _, vector := range loadZIP244Vectors() {
+		f.Add(vector.tx)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx, err := zcash.DecodeTx(data)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(tx.Serialize(), data) {
+			t.Fatalf("expected the decoded tx to serialize to its input")
+		}
+		if _, err := tx.TxID(); err != nil {
+			t.Fatalf("bad txid: %v", err)
+		}
+		if _, err := zcash.Explain(tx, nil, &zcash.MainNetParams); err != nil {
+			t.Fatalf("bad explanation: %v", err)
+		}
+
+		// Transactions with no shielded components are serialized in the same
+		// way by the TxBuilder.
+		if tx.IsTransparent() && tx.MsgTx.Version < 5 {
+			serialized, err := tx.Tx(&zcash.MainNetParams).Serialize()
+			if err != nil {
+				t.Fatalf("bad serialization: %v", err)
+			}
+			if !bytes.Equal(serialized, data) {
+				t.Fatalf("expected the tx to serialize to its input, got %x", serialized)
+			}
+		}
+	})
+}
+
+func FuzzDecodeAddress(f *testing.F) {
+	for _, params := range fuzzParams {
+		hash := make([]byte, 20)
+		pkhAddr, err := zcash.NewAddressPubKeyHash(hash, params)
+		if err != nil {
+			f.Fatal(err)
+		}
+		shAddr, err := zcash.NewAddressScriptHashFromHash(hash, params)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(pkhAddr.EncodeAddress())
+		f.Add(shAddr.EncodeAddress())
+	}
+	f.Add("")
+	f.Add("t1")
+
+	f.Fuzz(func(t *testing.T, addr string) {
+		for _, params := range fuzzParams {
+			decoded, err := zcash.DecodeAddress(addr, params)
+			rawAddr, rawErr := zcash.NewAddressDecoder(params).DecodeAddress(address.Address(addr))
+			if (err == nil) != (rawErr == nil) {
+				t.Fatalf("expected DecodeAddress and AddressDecoder to agree, got %v and %v", err, rawErr)
+			}
+			if err != nil {
+				continue
+			}
+			if decoded.EncodeAddress() != addr {
+				t.Fatalf("expected %v to round-trip, got %v", addr, decoded.EncodeAddress())
+			}
+			encoded, err := zcash.NewAddressEncoder(params).EncodeAddress(rawAddr)
+			if err != nil || string(encoded) != addr {
+				t.Fatalf("expected %v to round-trip, got %v (%v)", addr, encoded, err)
+			}
+		}
+	})
+}
+
+func FuzzAddressFromRawBytes(f *testing.F) {
+	for _, params := range fuzzParams {
+		hash := make([]byte, 20)
+		pkhAddr, err := zcash.NewAddressPubKeyHash(hash, params)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(base58.Decode(pkhAddr.EncodeAddress()))
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, params := range fuzzParams {
+			addr, err := zcash.AddressFromRawBytes(data, params)
+			if err != nil {
+				continue
+			}
+			if len(addr.ScriptAddress()) != 20 {
+				t.Fatalf("expected a 20 byte hash, got %x", addr.ScriptAddress())
+			}
+			if encoded := addr.EncodeAddress(); encoded != base58.Encode(data) {
+				t.Fatalf("expected %x to round-trip, got %v", data, encoded)
+			}
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/testutil"
 	"github.com/renproject/pack"
 
 	. "github.com/onsi/ginkgo"
@@ -12,41 +13,58 @@ import (
 
 var _ = Describe("Gas", func() {
 	Context("when estimating zcash network fee", func() {
+		var server *testutil.Server
+
+		BeforeEach(func() {
+			server = testutil.NewServer(testutil.ZcashChain(&zcash.RegressionNetParams))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
 		It("should work", func() {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			client := zcash.NewClient(zcash.DefaultClientOptions())
+			client := zcash.NewClient(server.ClientOptions())
+			server.SetFeeRate(1, 0.0001024)
+			server.SetFeeRate(10, 0.0000512)
 
 			// estimate fee to include tx within 1 block.
 			fallback1 := uint64(123)
 			gasEstimator1 := zcash.NewGasEstimator(client, 1, pack.NewU256FromUint64(fallback1))
 			gasPrice1, _, err := gasEstimator1.EstimateGas(ctx)
-			if err != nil {
-				Expect(gasPrice1).To(Equal(pack.NewU256FromUint64(fallback1)))
-			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gasPrice1).To(Equal(pack.NewU256FromUint64(10)))
 
 			// estimate fee to include tx within 10 blocks.
 			fallback2 := uint64(234)
 			gasEstimator2 := zcash.NewGasEstimator(client, 10, pack.NewU256FromUint64(fallback2))
 			gasPrice2, _, err := gasEstimator2.EstimateGas(ctx)
-			if err != nil {
-				Expect(gasPrice2).To(Equal(pack.NewU256FromUint64(fallback2)))
-			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gasPrice2).To(Equal(pack.NewU256FromUint64(5)))
 
 			// estimate fee to include tx within 100 blocks.
 			fallback3 := uint64(345)
 			gasEstimator3 := zcash.NewGasEstimator(client, 100, pack.NewU256FromUint64(fallback3))
 			gasPrice3, _, err := gasEstimator3.EstimateGas(ctx)
-			if err != nil {
-				Expect(gasPrice3).To(Equal(pack.NewU256FromUint64(fallback3)))
-			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gasPrice3).To(Equal(pack.NewU256FromUint64(5)))
 
 			// expect fees in this order at the very least.
-			if err == nil {
-				Expect(gasPrice1.GreaterThanEqual(gasPrice2)).To(BeTrue())
-				Expect(gasPrice2.GreaterThanEqual(gasPrice3)).To(BeTrue())
-			}
+			Expect(gasPrice1.GreaterThanEqual(gasPrice2)).To(BeTrue())
+			Expect(gasPrice2.GreaterThanEqual(gasPrice3)).To(BeTrue())
+		})
+
+		It("should return the fallback when the node has no estimate", func() {
+			client := zcash.NewClient(server.ClientOptions())
+			fallback := pack.NewU256FromUint64(123)
+			gasPrice, gasCap, err := zcash.NewGasEstimator(client, 1, fallback).EstimateGas(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(gasPrice).To(Equal(fallback))
+			Expect(gasCap).To(Equal(fallback))
+			Expect(server.Calls("estimatefee")).To(Equal(1))
 		})
 	})
 })
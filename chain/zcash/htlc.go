@@ -0,0 +1,43 @@
+package zcash
+
+import (
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+)
+
+// SecretSize re-exports bitcoin.SecretSize.
+const SecretSize = bitcoin.SecretSize
+
+// SecretHashType re-exports bitcoin.SecretHashType.
+type SecretHashType = bitcoin.SecretHashType
+
+const (
+	// SecretHashSHA256 re-exports bitcoin.SecretHashSHA256.
+	SecretHashSHA256 = bitcoin.SecretHashSHA256
+	// SecretHashHASH160 re-exports bitcoin.SecretHashHASH160.
+	SecretHashHASH160 = bitcoin.SecretHashHASH160
+)
+
+// HTLC re-exports bitcoin.HTLC. The redeem script of an HTLC is the same on
+// Zcash and Bitcoin, which allows a secret revealed on one chain to be used to
+// claim funds on the other.
+type HTLC = bitcoin.HTLC
+
+// ParseHTLC re-exports bitcoin.ParseHTLC.
+var ParseHTLC = bitcoin.ParseHTLC
+
+// HTLCClaimScript re-exports bitcoin.HTLCClaimScript. The signature must be
+// over the Zcash sighash of the input.
+var HTLCClaimScript = bitcoin.HTLCClaimScript
+
+// HTLCRefundScript re-exports bitcoin.HTLCRefundScript. The signature must be
+// over the Zcash sighash of the input.
+var HTLCRefundScript = bitcoin.HTLCRefundScript
+
+// NewHTLCAddress returns the P2SH address of the HTLC.
+func NewHTLCAddress(htlc HTLC, params *Params) (AddressScriptHash, error) {
+	script, err := htlc.Script()
+	if err != nil {
+		return AddressScriptHash{}, err
+	}
+	return NewAddressScriptHash(script, params)
+}
@@ -0,0 +1,150 @@
+package zcash_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTLC", func() {
+	params := &zcash.RegressionNetParams
+	lockTime := uint32(700000)
+	secret := bytes.Repeat([]byte{0x42}, zcash.SecretSize)
+
+	newKey := func() signer.KeySigner {
+		return signer.NewKeySigner((*btcec.PrivateKey)(id.NewPrivKey()), true)
+	}
+	newHTLC := func(recipient, refund signer.KeySigner) zcash.HTLC {
+		return zcash.HTLC{
+			SecretHashType:      zcash.SecretHashSHA256,
+			SecretHash:          zcash.SecretHashSHA256.Hash(secret),
+			RecipientPubKeyHash: btcutil.Hash160(recipient.PubKey()),
+			RefundPubKeyHash:    btcutil.Hash160(refund.PubKey()),
+			LockTime:            lockTime,
+		}
+	}
+
+	// spend builds a transaction that spends the HTLC, and returns it along
+	// with the sighash of its input.
+	spend := func(builder zcash.TxBuilder, htlc zcash.HTLC) (*zcash.Tx, pack.Bytes32) {
+		redeemScript, err := htlc.Script()
+		Expect(err).ToNot(HaveOccurred())
+		addr, err := zcash.NewHTLCAddress(htlc, params)
+		Expect(err).ToNot(HaveOccurred())
+		pubKeyScript, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+		Expect(err).ToNot(HaveOccurred())
+
+		input := utxo.Input{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)},
+				Value:        pack.NewU256FromUint64(100000),
+				PubKeyScript: pubKeyScript,
+			},
+			SigScript: redeemScript,
+		}
+		to, err := zcash.NewAddressPubKeyHash(make([]byte, 20), params)
+		Expect(err).ToNot(HaveOccurred())
+		tx, err := builder.BuildTx([]utxo.Input{input}, []utxo.Recipient{{To: address.Address(to.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}})
+		Expect(err).ToNot(HaveOccurred())
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*zcash.Tx), sighashes[0]
+	}
+
+	sign := func(key signer.KeySigner, sighash pack.Bytes32) pack.Bytes65 {
+		signatures, _, err := key.Sign(context.Background(), []pack.Bytes32{sighash})
+		Expect(err).ToNot(HaveOccurred())
+		return signatures[0]
+	}
+
+	It("should produce a P2SH address", func() {
+		htlc := newHTLC(newKey(), newKey())
+		addr, err := zcash.NewHTLCAddress(htlc, &zcash.MainNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(addr.EncodeAddress()).To(HavePrefix("t3"))
+
+		script, err := htlc.Script()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(addr.ScriptAddress()).To(Equal(btcutil.Hash160(script)))
+	})
+
+	It("should claim with the secret", func() {
+		recipient, refund := newKey(), newKey()
+		htlc := newHTLC(recipient, refund)
+		redeemScript, err := htlc.Script()
+		Expect(err).ToNot(HaveOccurred())
+
+		tx, sighash := spend(zcash.NewConfigurableTxBuilder(params, 1000000), htlc)
+		sigScript, err := zcash.HTLCClaimScript(redeemScript, sign(recipient, sighash), recipient.PubKey(), secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+		_, err = tx.Verify()
+		Expect(err).ToNot(HaveOccurred())
+
+		// A claim with the wrong secret is invalid.
+		sigScript, err = zcash.HTLCClaimScript(redeemScript, sign(recipient, sighash), recipient.PubKey(), bytes.Repeat([]byte{0x43}, zcash.SecretSize))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+		_, err = tx.Verify()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should refund after the lock time", func() {
+		recipient, refund := newKey(), newKey()
+		htlc := newHTLC(recipient, refund)
+		redeemScript, err := htlc.Script()
+		Expect(err).ToNot(HaveOccurred())
+
+		builder := zcash.NewConfigurableTxBuilder(params, 1000000).WithLockTime(lockTime).WithSequence(wire.MaxTxInSequenceNum - 1)
+		tx, sighash := spend(builder, htlc)
+		sigScript, err := zcash.HTLCRefundScript(redeemScript, sign(refund, sighash), refund.PubKey())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+		_, err = tx.Verify()
+		Expect(err).ToNot(HaveOccurred())
+
+		// The recipient cannot use the refund branch.
+		sigScript, err = zcash.HTLCRefundScript(redeemScript, sign(recipient, sighash), recipient.PubKey())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+		_, err = tx.Verify()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not refund before the lock time", func() {
+		recipient, refund := newKey(), newKey()
+		htlc := newHTLC(recipient, refund)
+		redeemScript, err := htlc.Script()
+		Expect(err).ToNot(HaveOccurred())
+
+		builder := zcash.NewConfigurableTxBuilder(params, 1000000).WithLockTime(lockTime - 1).WithSequence(wire.MaxTxInSequenceNum - 1)
+		tx, sighash := spend(builder, htlc)
+		sigScript, err := zcash.HTLCRefundScript(redeemScript, sign(refund, sighash), refund.PubKey())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+		_, err = tx.Verify()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should commit to the lock time and sequence in the sighash", func() {
+		htlc := newHTLC(newKey(), newKey())
+		_, sighash := spend(zcash.NewConfigurableTxBuilder(params, 1000000), htlc)
+		_, sighashWithLockTime := spend(zcash.NewConfigurableTxBuilder(params, 1000000).WithLockTime(lockTime), htlc)
+		_, sighashWithSequence := spend(zcash.NewConfigurableTxBuilder(params, 1000000).WithSequence(0), htlc)
+		Expect(sighashWithLockTime).ToNot(Equal(sighash))
+		Expect(sighashWithSequence).ToNot(Equal(sighash))
+	})
+})
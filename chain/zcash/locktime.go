@@ -0,0 +1,40 @@
+package zcash
+
+import (
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+)
+
+const (
+	// LockTimeThreshold re-exports bitcoin.LockTimeThreshold.
+	LockTimeThreshold = bitcoin.LockTimeThreshold
+	// SequenceFinal re-exports bitcoin.SequenceFinal.
+	SequenceFinal = bitcoin.SequenceFinal
+	// SequenceNonFinal is the largest sequence number that enables the lock
+	// time of a transaction. Zcash does not support replace-by-fee, or
+	// relative lock times, so other sequence numbers have no special meaning.
+	SequenceNonFinal = bitcoin.SequenceNoRBF
+
+	// MaxExpiryHeight is the maximum expiry height of a transaction (as
+	// defined by ZIP-203).
+	MaxExpiryHeight = 499999999
+	// DefaultExpiryDelta is the number of blocks after which a transaction
+	// expires, if it has not been mined. This is the same as the default
+	// -txexpirydelta of zcashd.
+	DefaultExpiryDelta = 40
+)
+
+// LockTimeFromHeight re-exports bitcoin.LockTimeFromHeight.
+var LockTimeFromHeight = bitcoin.LockTimeFromHeight
+
+// LockTimeFromTime re-exports bitcoin.LockTimeFromTime.
+var LockTimeFromTime = bitcoin.LockTimeFromTime
+
+// IsLockTimeHeight re-exports bitcoin.IsLockTimeHeight.
+var IsLockTimeHeight = bitcoin.IsLockTimeHeight
+
+// ExpiryHeightFromHeight returns the expiry height of a transaction that is
+// built when the chain is at the given block height, using the default expiry
+// delta.
+func ExpiryHeightFromHeight(height uint32) uint32 {
+	return height + DefaultExpiryDelta
+}
@@ -0,0 +1,101 @@
+package zcash_test
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock time and expiry", func() {
+	params := &zcash.RegressionNetParams
+	key := signer.NewKeySigner((*btcec.PrivateKey)(id.NewPrivKey()), true)
+	addr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey()), params)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+	inputs := []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(50000), PubKeyScript: pubKeyScript}},
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(1)}, Value: pack.NewU256FromUint64(50000), PubKeyScript: pubKeyScript}},
+	}
+	recipients := []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}}
+
+	build := func(builder zcash.TxBuilder) *zcash.Tx {
+		tx, err := builder.BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*zcash.Tx)
+	}
+	sighashes := func(builder zcash.TxBuilder) []pack.Bytes32 {
+		sighashes, err := build(builder).Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		return sighashes
+	}
+
+	It("should serialize the lock time, sequences and expiry height", func() {
+		lockTime, err := zcash.LockTimeFromHeight(123456)
+		Expect(err).ToNot(HaveOccurred())
+		builder := zcash.NewConfigurableTxBuilder(params, 1000000).
+			WithLockTime(lockTime).
+			WithInputSequences([]uint32{zcash.SequenceNonFinal, 7}).
+			WithExpiryHeight(zcash.ExpiryHeightFromHeight(1000))
+		serialized, err := build(builder).Serialize()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Header (8 bytes) and the input count (1 byte), followed by each
+		// input (36 byte outpoint, an empty script, and a 4 byte sequence).
+		Expect(binary.LittleEndian.Uint32(serialized[9+37 : 9+41])).To(Equal(uint32(zcash.SequenceNonFinal)))
+		Expect(binary.LittleEndian.Uint32(serialized[9+41+37 : 9+41+41])).To(Equal(uint32(7)))
+
+		// The lock time and expiry height are followed by the value balance
+		// and three empty vectors.
+		tail := serialized[len(serialized)-(4+4+8+3):]
+		Expect(binary.LittleEndian.Uint32(tail[0:4])).To(Equal(lockTime))
+		Expect(binary.LittleEndian.Uint32(tail[4:8])).To(Equal(uint32(1040)))
+	})
+
+	It("should commit to the lock time, sequences and expiry height in the sighash", func() {
+		base := sighashes(zcash.NewConfigurableTxBuilder(params, 1000000))
+		Expect(sighashes(zcash.NewConfigurableTxBuilder(params, 1000000).WithLockTime(1))).ToNot(ContainElement(base[0]))
+		Expect(sighashes(zcash.NewConfigurableTxBuilder(params, 1000000).WithExpiryHeight(1000001))).ToNot(ContainElement(base[0]))
+		withSequences := sighashes(zcash.NewConfigurableTxBuilder(params, 1000000).WithInputSequences([]uint32{zcash.SequenceFinal, 0}))
+		Expect(withSequences[0]).ToNot(Equal(base[0]))
+		Expect(withSequences[1]).ToNot(Equal(base[1]))
+	})
+
+	It("should use the latest consensus branch when expiry is disabled", func() {
+		noExpiry := sighashes(zcash.NewConfigurableTxBuilder(params, 1000000).WithExpiryHeight(0))
+		Expect(noExpiry).ToNot(Equal(sighashes(zcash.NewConfigurableTxBuilder(params, 1000000).WithExpiryHeight(1))))
+
+		tx := build(zcash.NewConfigurableTxBuilder(params, 0))
+		Expect(signer.SignTx(context.Background(), key, tx)).To(Succeed())
+		_, err := tx.Verify()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reject an expiry height that is too large", func() {
+		_, err := zcash.NewConfigurableTxBuilder(params, 1000000).WithExpiryHeight(zcash.MaxExpiryHeight+1).BuildTx(inputs, recipients)
+		Expect(err).To(HaveOccurred())
+		_, err = zcash.NewTxBuilder(params, zcash.MaxExpiryHeight).BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reject the wrong number of sequences", func() {
+		_, err := zcash.NewConfigurableTxBuilder(params, 1000000).WithInputSequences([]uint32{0}).BuildTx(inputs, recipients)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,202 @@
+package zcash
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MemoSize is the size (in bytes) of the memo of a shielded output.
+	MemoSize = 512
+
+	// memoMaxText is the largest first byte of a text memo. Every UTF-8
+	// string starts with a byte that is not greater than this.
+	memoMaxText = 0xF4
+	// memoNone is the first byte of the "no memo" marker.
+	memoNone = 0xF6
+	// memoData is the first byte of a memo that holds arbitrary data.
+	memoData = 0xFF
+)
+
+// MemoFormat is the format of a memo, as defined by ZIP-302.
+type MemoFormat int
+
+// These constants define the memo formats of ZIP-302.
+const (
+	// MemoText is a UTF-8 string, padded with zero bytes.
+	MemoText MemoFormat = iota
+	// MemoEmpty is the "no memo" marker: 0xF6 followed by zero bytes.
+	MemoEmpty
+	// MemoData is 0xFF followed by arbitrary data.
+	MemoData
+	// MemoReserved is reserved for future formats, and should not be shown
+	// to users.
+	MemoReserved
+)
+
+// String returns the name of the format.
+func (format MemoFormat) String() string {
+	switch format {
+	case MemoText:
+		return "text"
+	case MemoEmpty:
+		return "empty"
+	case MemoData:
+		return "data"
+	case MemoReserved:
+		return "reserved"
+	default:
+		return fmt.Sprintf("MemoFormat(%d)", int(format))
+	}
+}
+
+// Memo is the 512-byte memo of a shielded output. Memos are encoded as
+// hexadecimal by the RPCs of zcashd.
+type Memo [MemoSize]byte
+
+// NoMemo returns the "no memo" marker, which is used when a shielded output
+// has no memo.
+func NoMemo() Memo {
+	var memo Memo
+	memo[0] = memoNone
+	return memo
+}
+
+// NewTextMemo returns a memo that holds the UTF-8 text. An empty text is
+// encoded as the "no memo" marker. An error is returned if the text is not
+// valid UTF-8, is longer than MemoSize bytes, or ends with a zero byte (which
+// would be removed as padding).
+func NewTextMemo(text string) (Memo, error) {
+	if text == "" {
+		return NoMemo(), nil
+	}
+	if len(text) > MemoSize {
+		return Memo{}, fmt.Errorf("bad memo: expected at most %v bytes, got %v bytes", MemoSize, len(text))
+	}
+	if !utf8.ValidString(text) {
+		return Memo{}, fmt.Errorf("bad memo: expected UTF-8 text")
+	}
+	if strings.HasSuffix(text, "\x00") {
+		return Memo{}, fmt.Errorf("bad memo: unexpected trailing zero byte")
+	}
+	var memo Memo
+	copy(memo[:], text)
+	return memo, nil
+}
+
+// NewDataMemo returns a memo that holds arbitrary data. The data is padded with
+// zero bytes. An error is returned if the data is longer than MemoSize-1
+// bytes.
+func NewDataMemo(data []byte) (Memo, error) {
+	if len(data) > MemoSize-1 {
+		return Memo{}, fmt.Errorf("bad memo: expected at most %v bytes of data, got %v bytes", MemoSize-1, len(data))
+	}
+	var memo Memo
+	memo[0] = memoData
+	copy(memo[1:], data)
+	return memo, nil
+}
+
+// ParseMemo returns the memo with the given bytes. Memos that are shorter than
+// MemoSize bytes are padded with zero bytes, in the same way as the memos
+// passed to zcashd. Memos of every format are accepted, including the reserved
+// formats.
+func ParseMemo(b []byte) (Memo, error) {
+	if len(b) > MemoSize {
+		return Memo{}, fmt.Errorf("bad memo: expected at most %v bytes, got %v bytes", MemoSize, len(b))
+	}
+	var memo Memo
+	copy(memo[:], b)
+	return memo, nil
+}
+
+// DecodeMemoHex returns the memo encoded as hexadecimal. See ParseMemo for more
+// details.
+func DecodeMemoHex(s string) (Memo, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return Memo{}, fmt.Errorf("bad memo: %v", err)
+	}
+	return ParseMemo(b)
+}
+
+// Format returns the ZIP-302 format of the memo.
+func (memo Memo) Format() MemoFormat {
+	switch {
+	case memo[0] <= memoMaxText:
+		return MemoText
+	case memo[0] == memoNone && isZero(memo[1:]):
+		return MemoEmpty
+	case memo[0] == memoData:
+		return MemoData
+	default:
+		return MemoReserved
+	}
+}
+
+// Text returns the text of a text memo, without its padding. An empty string
+// is returned for the "no memo" marker. An error is returned for other
+// formats, and for text that is not valid UTF-8.
+func (memo Memo) Text() (string, error) {
+	switch memo.Format() {
+	case MemoText:
+		text := bytes.TrimRight(memo[:], "\x00")
+		if !utf8.Valid(text) {
+			return "", fmt.Errorf("bad memo: expected UTF-8 text")
+		}
+		return string(text), nil
+	case MemoEmpty:
+		return "", nil
+	default:
+		return "", fmt.Errorf("bad memo: expected text, got %v", memo.Format())
+	}
+}
+
+// Data returns the MemoSize-1 bytes of data held by a data memo, including
+// its padding. An error is returned for other formats.
+func (memo Memo) Data() ([]byte, error) {
+	if memo.Format() != MemoData {
+		return nil, fmt.Errorf("bad memo: expected data, got %v", memo.Format())
+	}
+	return append([]byte(nil), memo[1:]...), nil
+}
+
+// Bytes returns the memo without its trailing zero bytes. Since memos are
+// padded with zero bytes, ParseMemo returns the same memo for these bytes.
+func (memo Memo) Bytes() []byte {
+	return append([]byte(nil), bytes.TrimRight(memo[:], "\x00")...)
+}
+
+// Hex returns the memo encoded as hexadecimal.
+func (memo Memo) Hex() string {
+	return hex.EncodeToString(memo[:])
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. Memos are
+// encoded as hexadecimal.
+func (memo Memo) MarshalText() ([]byte, error) {
+	return []byte(memo.Hex()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. See
+// DecodeMemoHex for more details.
+func (memo *Memo) UnmarshalText(text []byte) error {
+	decoded, err := DecodeMemoHex(string(text))
+	if err != nil {
+		return err
+	}
+	*memo = decoded
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
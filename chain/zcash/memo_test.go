@@ -0,0 +1,156 @@
+package zcash_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memo", func() {
+	Context("when creating text memos", func() {
+		It("should pad the text with zero bytes", func() {
+			memo, err := zcash.NewTextMemo("Thank you ✨")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(memo.Format()).To(Equal(zcash.MemoText))
+			Expect(memo[:len("Thank you ✨")]).To(Equal([]byte("Thank you ✨")))
+			Expect(memo[len("Thank you ✨"):]).To(Equal(make([]byte, zcash.MemoSize-len("Thank you ✨"))))
+			text, err := memo.Text()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(text).To(Equal("Thank you ✨"))
+			Expect(memo.Bytes()).To(Equal([]byte("Thank you ✨")))
+		})
+
+		It("should fill the memo", func() {
+			memo, err := zcash.NewTextMemo(strings.Repeat("a", zcash.MemoSize))
+			Expect(err).ToNot(HaveOccurred())
+			text, err := memo.Text()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(text).To(HaveLen(zcash.MemoSize))
+		})
+
+		It("should encode empty text as no memo", func() {
+			memo, err := zcash.NewTextMemo("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(memo).To(Equal(zcash.NoMemo()))
+		})
+
+		It("should return an error for invalid text", func() {
+			_, err := zcash.NewTextMemo(strings.Repeat("a", zcash.MemoSize+1))
+			Expect(err).To(HaveOccurred())
+			_, err = zcash.NewTextMemo("\xff")
+			Expect(err).To(HaveOccurred())
+			_, err = zcash.NewTextMemo("a\x00")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when creating data memos", func() {
+		It("should prefix the data with 0xFF", func() {
+			memo, err := zcash.NewDataMemo([]byte{1, 2, 3})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(memo.Format()).To(Equal(zcash.MemoData))
+			Expect(memo.Bytes()).To(Equal([]byte{0xFF, 1, 2, 3}))
+			data, err := memo.Data()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(HaveLen(zcash.MemoSize - 1))
+			Expect(data[:3]).To(Equal([]byte{1, 2, 3}))
+			_, err = memo.Text()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return an error for too much data", func() {
+			_, err := zcash.NewDataMemo(make([]byte, zcash.MemoSize-1))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = zcash.NewDataMemo(make([]byte, zcash.MemoSize))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when parsing memos", func() {
+		It("should return the format of the memo", func() {
+			for _, test := range []struct {
+				b      []byte
+				format zcash.MemoFormat
+			}{
+				{[]byte{}, zcash.MemoText},
+				{[]byte("hello"), zcash.MemoText},
+				{[]byte{0xF4, 0x8F, 0xBF, 0xBF}, zcash.MemoText},
+				{[]byte{0xF5}, zcash.MemoReserved},
+				{[]byte{0xF6}, zcash.MemoEmpty},
+				{[]byte{0xF6, 0, 1}, zcash.MemoReserved},
+				{[]byte{0xF7}, zcash.MemoReserved},
+				{[]byte{0xFE}, zcash.MemoReserved},
+				{[]byte{0xFF}, zcash.MemoData},
+			} {
+				memo, err := zcash.ParseMemo(test.b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(memo.Format()).To(Equal(test.format), "%x", test.b)
+			}
+		})
+
+		It("should return no text for the no memo marker", func() {
+			text, err := zcash.NoMemo().Text()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(text).To(BeEmpty())
+		})
+
+		It("should return an error for text memos that are not UTF-8", func() {
+			memo, err := zcash.ParseMemo([]byte{'a', 0xC0})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(memo.Format()).To(Equal(zcash.MemoText))
+			_, err = memo.Text()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return an error for oversized memos", func() {
+			_, err := zcash.ParseMemo(make([]byte, zcash.MemoSize+1))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when encoding memos as hex", func() {
+		It("should round-trip the memo", func() {
+			memo, err := zcash.NewTextMemo("hello")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(memo.Hex()).To(HaveLen(2 * zcash.MemoSize))
+			Expect(memo.Hex()).To(HavePrefix("68656c6c6f00"))
+			decoded, err := zcash.DecodeMemoHex(memo.Hex())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal(memo))
+		})
+
+		It("should pad short hex", func() {
+			memo, err := zcash.DecodeMemoHex("f6")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(memo).To(Equal(zcash.NoMemo()))
+		})
+
+		It("should return an error for bad hex", func() {
+			_, err := zcash.DecodeMemoHex("f")
+			Expect(err).To(HaveOccurred())
+			_, err = zcash.DecodeMemoHex(strings.Repeat("00", zcash.MemoSize+1))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should marshal to and from JSON", func() {
+			memo, err := zcash.NewDataMemo([]byte{1, 2, 3})
+			Expect(err).ToNot(HaveOccurred())
+			data, err := json.Marshal(struct {
+				Memo zcash.Memo `json:"memo"`
+			}{memo})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"memo":"ff010203` + strings.Repeat("00", zcash.MemoSize-4) + `"}`))
+
+			var decoded struct {
+				Memo zcash.Memo `json:"memo"`
+			}
+			Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+			Expect(decoded.Memo).To(Equal(memo))
+			Expect(json.Unmarshal([]byte(`{"memo":"zz"}`), &decoded)).ToNot(Succeed())
+		})
+	})
+})
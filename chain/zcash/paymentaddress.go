@@ -0,0 +1,271 @@
+package zcash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	btcbech32 "github.com/btcsuite/btcutil/bech32"
+	blake2 "github.com/dchest/blake2b"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/internal/bech32"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	// SaplingAddressSize is the size (in bytes) of a Sapling payment address:
+	// the 11 byte diversifier, and the 32 byte diversified transmission key.
+	SaplingAddressSize = 11 + 32
+	// orchardAddressSize is the size (in bytes) of an Orchard receiver.
+	orchardAddressSize = 11 + 32
+
+	// The typecodes of the receivers of unified addresses (as defined by
+	// ZIP-316).
+	typecodeP2PKH   = 0x00
+	typecodeP2SH    = 0x01
+	typecodeSapling = 0x02
+	typecodeOrchard = 0x03
+
+	// unifiedPaddingSize is the size (in bytes) of the padding that ends the
+	// encoding of a unified address. It holds the human readable part.
+	unifiedPaddingSize = 16
+	// minF4JumbleSize and maxF4JumbleSize are the bounds on the size (in bytes)
+	// of the messages that can be encoded by F4Jumble.
+	minF4JumbleSize = 48
+	maxF4JumbleSize = 4194368
+
+	f4JumbleHashSize         = 64
+	f4JumbleHPersonalization = "UA_F4Jumble_H"
+	f4JumbleGPersonalization = "UA_F4Jumble_G"
+)
+
+// An AddressKind is the kind of a Zcash payment address.
+type AddressKind uint8
+
+const (
+	// AddressTransparent is the kind of base58 P2PKH and P2SH addresses.
+	AddressTransparent = AddressKind(iota)
+	// AddressTEX is the kind of TEX addresses (as defined by ZIP-320). They
+	// are P2PKH addresses that must only be paid from transparent inputs.
+	AddressTEX
+	// AddressSapling is the kind of Sapling payment addresses (as defined by
+	// ZIP-173).
+	AddressSapling
+	// AddressUnified is the kind of unified addresses (as defined by ZIP-316).
+	// They have at least one shielded receiver.
+	AddressUnified
+)
+
+// String returns the name of the kind.
+func (kind AddressKind) String() string {
+	switch kind {
+	case AddressTransparent:
+		return "transparent"
+	case AddressTEX:
+		return "tex"
+	case AddressSapling:
+		return "sapling"
+	case AddressUnified:
+		return "unified"
+	default:
+		return fmt.Sprintf("AddressKind(%d)", uint8(kind))
+	}
+}
+
+// IsShielded returns true if addresses of the kind can receive shielded
+// funds, and therefore memos.
+func (kind AddressKind) IsShielded() bool {
+	return kind == AddressSapling || kind == AddressUnified
+}
+
+// PaymentAddressDecoder decodes all of the payment addresses of a network:
+// transparent, TEX, Sapling, and unified addresses. It implements the
+// address.Decoder interface. Use AddressDecoder for addresses that must be
+// paid by transparent transactions.
+//
+// The encodings of addresses are checked, but shielded receivers are not
+// checked to be valid points, since that needs the Jubjub and Pallas curves.
+type PaymentAddressDecoder struct {
+	params *Params
+}
+
+// NewPaymentAddressDecoder constructs a new PaymentAddressDecoder with the
+// chain specific configurations
+func NewPaymentAddressDecoder(params *Params) PaymentAddressDecoder {
+	return PaymentAddressDecoder{params: params}
+}
+
+// DecodeAddress implements the address.Decoder interface. The raw address of a
+// transparent address is its base58 payload (as returned by AddressDecoder),
+// of a TEX address it is the pubkey hash, of a Sapling address it is the
+// diversifier and the diversified transmission key, and of a unified address
+// it is the encoding of the receivers, without the padding.
+func (decoder PaymentAddressDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	_, raw, err := decoder.decode(addr)
+	return raw, err
+}
+
+// AddressKind returns the kind of the address. An error is returned if the
+// address cannot be decoded.
+func (decoder PaymentAddressDecoder) AddressKind(addr address.Address) (AddressKind, error) {
+	kind, _, err := decoder.decode(addr)
+	return kind, err
+}
+
+func (decoder PaymentAddressDecoder) decode(addr address.Address) (AddressKind, address.RawAddress, error) {
+	s := string(addr)
+	if sep := strings.LastIndexByte(s, '1'); sep > 0 {
+		switch hrp := strings.ToLower(s[:sep]); hrp {
+		case decoder.params.TEXHRP:
+			raw, err := decodeBech32Address(s, bech32.Bech32m, ripemd160.Size)
+			return AddressTEX, raw, err
+		case decoder.params.SaplingHRP:
+			raw, err := decodeBech32Address(s, bech32.Bech32, SaplingAddressSize)
+			return AddressSapling, raw, err
+		case decoder.params.UnifiedHRP:
+			raw, err := decodeUnifiedAddress(s, hrp)
+			return AddressUnified, raw, err
+		}
+	}
+	raw, err := NewAddressDecoder(decoder.params).DecodeAddress(addr)
+	return AddressTransparent, raw, err
+}
+
+// decodeBech32Address returns the payload of a TEX or Sapling address, which
+// must have the given size.
+func decodeBech32Address(addr string, variant bech32.Variant, size int) (address.RawAddress, error) {
+	_, values, err := bech32.Decode(addr, variant, 0)
+	if err != nil {
+		return nil, err
+	}
+	data, err := btcbech32.ConvertBits(values, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("bad %v: %v", variant, err)
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("validating address length: expected %v, got %v", size, len(data))
+	}
+	return address.RawAddress(data), nil
+}
+
+// decodeUnifiedAddress returns the encoding of the receivers of a unified
+// address. The receivers must have distinct typecodes, must not include both
+// a P2PKH and a P2SH receiver, and must include a shielded receiver. Receivers
+// with unknown typecodes are allowed.
+func decodeUnifiedAddress(addr, hrp string) (address.RawAddress, error) {
+	_, values, err := bech32.Decode(addr, bech32.Bech32m, 0)
+	if err != nil {
+		return nil, err
+	}
+	data, err := btcbech32.ConvertBits(values, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("bad bech32m: %v", err)
+	}
+	if len(data) < minF4JumbleSize || len(data) > maxF4JumbleSize {
+		return nil, fmt.Errorf("validating address length: expected between %v and %v, got %v", minF4JumbleSize, maxF4JumbleSize, len(data))
+	}
+	data = f4JumbleInv(data)
+	padding := make([]byte, unifiedPaddingSize)
+	copy(padding, hrp)
+	if !bytes.Equal(data[len(data)-unifiedPaddingSize:], padding) {
+		return nil, fmt.Errorf("bad padding: expected %x, got %x", padding, data[len(data)-unifiedPaddingSize:])
+	}
+	receivers := data[:len(data)-unifiedPaddingSize]
+
+	seen := map[uint64]bool{}
+	shielded := false
+	for r := bytes.NewReader(receivers); r.Len() > 0; {
+		typecode, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad typecode: %v", err)
+		}
+		size, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad receiver size: %v", err)
+		}
+		if size > uint64(r.Len()) {
+			return nil, fmt.Errorf("bad receiver size: expected at most %v, got %v", r.Len(), size)
+		}
+		if _, err := r.Seek(int64(size), 1); err != nil {
+			return nil, fmt.Errorf("bad receiver: %v", err)
+		}
+		if seen[typecode] {
+			return nil, fmt.Errorf("bad receivers: duplicate typecode %v", typecode)
+		}
+		seen[typecode] = true
+
+		expectedSize := uint64(0)
+		switch typecode {
+		case typecodeP2PKH, typecodeP2SH:
+			expectedSize = ripemd160.Size
+		case typecodeSapling:
+			expectedSize, shielded = SaplingAddressSize, true
+		case typecodeOrchard:
+			expectedSize, shielded = orchardAddressSize, true
+		default:
+			continue
+		}
+		if size != expectedSize {
+			return nil, fmt.Errorf("bad receiver %v: expected %v bytes, got %v bytes", typecode, expectedSize, size)
+		}
+	}
+	if seen[typecodeP2PKH] && seen[typecodeP2SH] {
+		return nil, fmt.Errorf("bad receivers: expected at most one transparent receiver")
+	}
+	if !shielded {
+		return nil, fmt.Errorf("bad receivers: expected a shielded receiver")
+	}
+	return address.RawAddress(receivers), nil
+}
+
+// f4JumbleInv inverts the F4Jumble permutation of a message (as defined by
+// ZIP-316). The size of the message must be within the bounds of F4Jumble.
+func f4JumbleInv(m []byte) []byte {
+	leftSize := len(m) / 2
+	if leftSize > f4JumbleHashSize {
+		leftSize = f4JumbleHashSize
+	}
+	c, d := m[:leftSize], m[leftSize:]
+	y := xorBytes(c, f4JumbleH(1, d, len(c)))
+	x := xorBytes(d, f4JumbleG(1, y, len(d)))
+	a := xorBytes(y, f4JumbleH(0, x, len(y)))
+	b := xorBytes(x, f4JumbleG(0, a, len(x)))
+	return append(a, b...)
+}
+
+// f4JumbleH returns the H_i round function of F4Jumble, which hashes u into
+// the given number of bytes.
+func f4JumbleH(i byte, u []byte, size int) []byte {
+	return f4JumbleHash(append([]byte(f4JumbleHPersonalization), i, 0, 0), u, size)
+}
+
+// f4JumbleG returns the G_i round function of F4Jumble, which expands u into
+// the given number of bytes.
+func f4JumbleG(i byte, u []byte, size int) []byte {
+	out := make([]byte, 0, size+f4JumbleHashSize)
+	for j := uint16(0); len(out) < size; j++ {
+		person := append([]byte(f4JumbleGPersonalization), i, 0, 0)
+		binary.LittleEndian.PutUint16(person[len(person)-2:], j)
+		out = append(out, f4JumbleHash(person, u, f4JumbleHashSize)...)
+	}
+	return out[:size]
+}
+
+func f4JumbleHash(person, u []byte, size int) []byte {
+	hash, err := blake2.New(&blake2.Config{Person: person, Size: uint8(size)})
+	if err != nil {
+		panic(err)
+	}
+	hash.Write(u)
+	return hash.Sum(nil)
+}
+
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package zcash_test
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Payment addresses", func() {
+	// unifiedAddr is a mainnet unified address with P2PKH, Sapling, and
+	// Orchard receivers.
+	const unifiedAddr = "u1pg2aaph7jp8rpf6yhsza25722sg5fcn3vaca6ze27hqjw7jvvhhuxkpcg0ge9xh6drsgdkda8qjq5chpehkcpxf87rnjryjqwymdheptpvnljqqrjqzjwkc2ma6hcq666kgwfytxwac8eyex6ndgr6ezte66706e3vaqrd25dzvzkc69kw0jgywtd0cmq52q5lkw6uh7hyvzjse8ksx"
+	// saplingAddr is the testnet Sapling address of the ZIP-321 examples.
+	const saplingAddr = "ztestsapling10yy2ex5dcqkclhc7z7yrnjq2z6feyjad56ptwlfgmy77dmaqqrl9gyhprdx59qgmsnyfska2kez"
+	// texAddr is the TEX address of the ZIP-320 example, and transparentAddr
+	// is the P2PKH address with the same pubkey hash.
+	const texAddr = "tex1s2rt77ggv6q989lr49rkgzmh5slsksa9khdgte"
+	const transparentAddr = "t1VmmGiyjVNeCjxDZzg7vZmd99WyzVby9yC"
+
+	mainnet := zcash.NewPaymentAddressDecoder(&zcash.MainNetParams)
+	testnet := zcash.NewPaymentAddressDecoder(&zcash.TestNet3Params)
+
+	receiver := func(typecode byte, size int) []byte {
+		return append([]byte{typecode, byte(size)}, make([]byte, size)...)
+	}
+	unified := func(receivers ...[]byte) address.Address {
+		encoded := []byte{}
+		for _, r := range receivers {
+			encoded = append(encoded, r...)
+		}
+		return zcash.EncodeUnifiedAddress(zcash.MainNetParams.UnifiedHRP, encoded)
+	}
+
+	Context("when decoding addresses", func() {
+		It("should decode each kind of address", func() {
+			for _, test := range []struct {
+				decoder zcash.PaymentAddressDecoder
+				addr    address.Address
+				kind    zcash.AddressKind
+			}{
+				{mainnet, transparentAddr, zcash.AddressTransparent},
+				{mainnet, texAddr, zcash.AddressTEX},
+				{mainnet, address.Address(strings.ToUpper(texAddr)), zcash.AddressTEX},
+				{testnet, saplingAddr, zcash.AddressSapling},
+				{mainnet, unifiedAddr, zcash.AddressUnified},
+			} {
+				kind, err := test.decoder.AddressKind(test.addr)
+				Expect(err).ToNot(HaveOccurred(), "%v", test.addr)
+				Expect(kind).To(Equal(test.kind))
+				Expect(kind.IsShielded()).To(Equal(test.kind == zcash.AddressSapling || test.kind == zcash.AddressUnified))
+			}
+		})
+
+		It("should decode the pubkey hash of TEX addresses", func() {
+			raw, err := mainnet.DecodeAddress(texAddr)
+			Expect(err).ToNot(HaveOccurred())
+			transparent, err := zcash.NewAddressDecoder(&zcash.MainNetParams).DecodeAddress(transparentAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(raw).To(Equal(transparent[2:22]))
+		})
+
+		It("should decode the payload of Sapling addresses", func() {
+			raw, err := testnet.DecodeAddress(saplingAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(raw).To(HaveLen(zcash.SaplingAddressSize))
+		})
+
+		It("should decode the receivers of unified addresses", func() {
+			raw, err := mainnet.DecodeAddress(unifiedAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hex.EncodeToString(raw[:2])).To(Equal("0014"))
+			Expect(hex.EncodeToString(raw[22:24])).To(Equal("022b"))
+			Expect(hex.EncodeToString(raw[67:69])).To(Equal("032b"))
+			Expect(raw).To(HaveLen(69 + 43))
+		})
+
+		It("should decode unified addresses of any size", func() {
+			for _, receivers := range [][][]byte{
+				{receiver(0x02, 43)},
+				{receiver(0x00, 20), receiver(0x02, 43), receiver(0x03, 43)},
+				{receiver(0x03, 43), receiver(0x10, 200)},
+			} {
+				encoded := []byte{}
+				for _, r := range receivers {
+					encoded = append(encoded, r...)
+				}
+				raw, err := mainnet.DecodeAddress(unified(receivers...))
+				Expect(err).ToNot(HaveOccurred())
+				Expect([]byte(raw)).To(Equal(encoded))
+			}
+		})
+
+		It("should return an error for addresses of other networks", func() {
+			for _, addr := range []address.Address{transparentAddr, texAddr, unifiedAddr} {
+				_, err := testnet.DecodeAddress(addr)
+				Expect(err).To(HaveOccurred(), "%v", addr)
+			}
+			_, err := mainnet.DecodeAddress(saplingAddr)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return an error for bad addresses", func() {
+			for _, addr := range []address.Address{
+				// Bad checksums.
+				address.Address(texAddr[:len(texAddr)-1] + "q"),
+				address.Address(saplingAddr[:len(saplingAddr)-1] + "q"),
+				address.Address(unifiedAddr[:len(unifiedAddr)-1] + "q"),
+				// Bad receivers.
+				unified(receiver(0x00, 20)),
+				unified(receiver(0x02, 42)),
+				unified(receiver(0x02, 43), receiver(0x02, 43)),
+				unified(receiver(0x00, 20), receiver(0x01, 20), receiver(0x02, 43)),
+				unified(append(receiver(0x02, 43), 0x03)),
+			} {
+				_, err := mainnet.DecodeAddress(addr)
+				Expect(err).To(HaveOccurred(), "%v", addr)
+			}
+			_, err := testnet.DecodeAddress(address.Address(strings.Replace(saplingAddr, "ztestsapling", "zs", 1)))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
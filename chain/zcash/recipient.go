@@ -0,0 +1,79 @@
+package zcash
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+const (
+	// MaxDataCarrierSize is the maximum number of bytes that can be committed
+	// to by a null-data output. The relay policy of zcashd allows null-data
+	// scripts of up to 223 bytes, which leaves 220 bytes for the data after
+	// the OP_RETURN and OP_PUSHDATA1 opcodes.
+	MaxDataCarrierSize = 220
+	// MaxNullDataOutputs re-exports bitcoin.MaxNullDataOutputs.
+	MaxNullDataOutputs = bitcoin.MaxNullDataOutputs
+)
+
+// NullDataScript returns a null-data (OP_RETURN) pubkey script that commits to
+// the data. The data must be no larger than MaxDataCarrierSize.
+func NullDataScript(data []byte) ([]byte, error) {
+	return bitcoin.NullDataScript(data, MaxDataCarrierSize)
+}
+
+// ExtractNullData re-exports bitcoin.ExtractNullData.
+var ExtractNullData = bitcoin.ExtractNullData
+
+// IsNullData re-exports bitcoin.IsNullData.
+var IsNullData = bitcoin.IsNullData
+
+// CheckNullDataOutputs re-exports bitcoin.CheckNullDataOutputs.
+var CheckNullDataOutputs = bitcoin.CheckNullDataOutputs
+
+// RecipientScript returns the pubkey script of the output that will be produced
+// for the recipient. Zcash does not support segregated witness, so scripts
+// with witness programs are rejected. See bitcoin.RecipientScript.
+func RecipientScript(recipient utxo.Recipient, params *Params) ([]byte, error) {
+	script, err := bitcoin.RecipientScript(recipient, MaxDataCarrierSize, payToAddrFunc(params))
+	if err != nil {
+		return nil, err
+	}
+	if txscript.IsWitnessProgram(script) {
+		return nil, fmt.Errorf("bad script: witness programs are not supported")
+	}
+	return script, nil
+}
+
+// RecipientFromOutput returns the recipient for which the output was produced.
+// This is the inverse of RecipientScript.
+func RecipientFromOutput(output utxo.Output, params *Params) utxo.Recipient {
+	if data, ok := ExtractNullData(output.PubKeyScript); ok {
+		return utxo.Recipient{Value: output.Value, Data: pack.Bytes(data)}
+	}
+	if addr, err := ExtractPkScriptAddrs(output.PubKeyScript, params); err == nil {
+		return utxo.Recipient{Value: output.Value, To: address.Address(addr.EncodeAddress())}
+	}
+	return utxo.Recipient{Value: output.Value, Script: output.PubKeyScript}
+}
+
+// payToAddrFunc returns a function that converts Zcash transparent addresses to
+// pubkey scripts.
+func payToAddrFunc(params *Params) func(address.Address) ([]byte, error) {
+	addrDecoder := NewAddressDecoder(params)
+	return func(to address.Address) ([]byte, error) {
+		addrBytes, err := addrDecoder.DecodeAddress(to)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := addressFromRawBytes(addrBytes, params)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.PayToAddrScript(addr.BitcoinAddress())
+	}
+}
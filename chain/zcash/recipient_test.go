@@ -0,0 +1,98 @@
+package zcash_test
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recipients", func() {
+	params := &zcash.RegressionNetParams
+	key := signer.NewKeySigner((*btcec.PrivateKey)(id.NewPrivKey()), true)
+	addr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey()), params)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+	scriptAddr, err := zcash.NewAddressScriptHash([]byte{txscript.OP_TRUE}, params)
+	if err != nil {
+		panic(err)
+	}
+	scriptHashScript, err := txscript.PayToAddrScript(scriptAddr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+	inputs := []utxo.Input{
+		{Output: utxo.Output{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pubKeyScript}},
+	}
+	data := []byte("hello, world")
+
+	build := func(recipients []utxo.Recipient) (utxo.Tx, error) {
+		return zcash.NewTxBuilder(params, 1000000).BuildTx(inputs, recipients)
+	}
+
+	It("should allow more data than bitcoin", func() {
+		script, err := zcash.NullDataScript(make([]byte, zcash.MaxDataCarrierSize))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(script).To(HaveLen(223))
+		_, err = zcash.NullDataScript(make([]byte, zcash.MaxDataCarrierSize+1))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should build, sign, and round-trip transactions with data", func() {
+		recipients := []utxo.Recipient{
+			{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(40000)},
+			{Script: scriptHashScript, Value: pack.NewU256FromUint64(40000)},
+			{Data: make([]byte, zcash.MaxDataCarrierSize), Value: pack.NewU256FromUint64(0)},
+		}
+		tx, err := build(recipients)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer.SignTx(context.Background(), key, tx)).To(Succeed())
+		_, err = tx.(*zcash.Tx).Verify()
+		Expect(err).ToNot(HaveOccurred())
+
+		// Scripts that pay to an address are returned as the address.
+		expected := append([]utxo.Recipient{}, recipients...)
+		expected[1] = utxo.Recipient{To: address.Address(scriptAddr.EncodeAddress()), Value: recipients[1].Value}
+		outputs, err := tx.Outputs()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(outputs).To(HaveLen(3))
+		for i, output := range outputs {
+			Expect(zcash.RecipientFromOutput(output, params)).To(Equal(expected[i]))
+		}
+	})
+
+	It("should reject invalid recipients", func() {
+		witnessAddr, err := btcutil.NewAddressWitnessPubKeyHash(make([]byte, 20), params.Params)
+		Expect(err).ToNot(HaveOccurred())
+		witnessScript, err := txscript.PayToAddrScript(witnessAddr)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, recipients := range [][]utxo.Recipient{
+			{{Value: pack.NewU256FromUint64(40000)}},
+			{{To: address.Address(addr.EncodeAddress()), Data: data, Value: pack.NewU256FromUint64(0)}},
+			{{Data: data, Value: pack.NewU256FromUint64(1000)}},
+			{{Data: make([]byte, zcash.MaxDataCarrierSize+1), Value: pack.NewU256FromUint64(0)}},
+			{{Data: data, Value: pack.NewU256FromUint64(0)}, {Data: data, Value: pack.NewU256FromUint64(0)}},
+			{{Script: witnessScript, Value: pack.NewU256FromUint64(40000)}},
+			{{Script: []byte{txscript.OP_TRUE}, Value: pack.NewU256FromUint64(40000)}},
+		} {
+			_, err := build(recipients)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
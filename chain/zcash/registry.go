@@ -0,0 +1,38 @@
+package zcash
+
+import (
+	"github.com/pranav292gpt/zecutil/api/gas"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/registry"
+	"github.com/renproject/pack"
+)
+
+func init() {
+	for network, config := range map[registry.Network]struct {
+		params *Params
+		host   string
+	}{
+		registry.Mainnet: {&MainNetParams, "http://127.0.0.1:8232"},
+		registry.Testnet: {&TestNet3Params, "http://127.0.0.1:18232"},
+		registry.Regtest: {&RegressionNetParams, DefaultClientOptions().Host},
+	} {
+		registry.Register(registry.Bundle{
+			Chain:                registry.Zcash,
+			Network:              network,
+			AddressEncodeDecoder: NewAddressEncodeDecoder(config.params),
+			// An expiry height of zero disables expiry, because the bundle
+			// cannot know the current block height.
+			TxBuilder:            NewTxBuilder(config.params, 0),
+			DefaultClientOptions: registry.ClientOptions(DefaultClientOptions().WithHost(config.host)),
+			NewClient:            bitcoin.NewRegistryClient,
+			NewGasEstimator: func(client utxo.Client, numBlocks int64, fallbackGas pack.U256) (gas.Estimator, error) {
+				zcashClient, err := bitcoin.RegistryClient(client)
+				if err != nil {
+					return nil, err
+				}
+				return NewGasEstimator(zcashClient, numBlocks, fallbackGas), nil
+			},
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package rpcclient
 
+import "github.com/pranav292gpt/zecutil/chain/zcash"
+
 type zcashConf struct {
 	testNet     bool
 	rpcUser     string
@@ -164,19 +166,91 @@ func (b Block) TransactionTypes() (tTXs, sTXs int) {
 	return tTXs, sTXs
 }
 
-// Transaction describes a zcash tranaction
+// Transaction describes a zcash transaction, as returned by the verbose
+// `getrawtransaction`. Hashes and keys are hex strings, in the same byte order
+// as zcashd. The Sapling fields are set for v4 and v5 transactions, and the
+// Orchard field only for v5 transactions. Values in zcash are decoded exactly
+// as a zcash.Amount, and are also given in zatoshi by the fields with the Zat
+// suffix. The shielded fields have not yet been checked against the output of
+// a node (see testdata/README.md).
+// https://zcash.github.io/rpc/getrawtransaction.html
 type Transaction struct {
-	Hex             string                   `json:"hex"`
-	Txid            string                   `json:"txid"`
-	Version         int                      `json:"version"`
-	LockTime        int                      `json:"locktime"`
-	ExpiryHeight    int                      `json:"expirtheight"`
-	VIn             []VIn                    `json:"vin"`
-	VOut            []VOut                   `json:"vout"`
-	VJoinSplit      []VJoinSplitTX           `json:"vjoinsplit"`
-	ValueBalance    float64                  `json:"valueBalance"`
-	VShieldedSpend  []map[string]interface{} `json:"vShieldedSpend"`
-	VShieldedOutput []map[string]interface{} `json:"vShieldedOutput"`
+	Hex             string           `json:"hex"`
+	Txid            string           `json:"txid"`
+	AuthDigest      string           `json:"authdigest"`
+	Size            int              `json:"size"`
+	Overwintered    bool             `json:"overwintered"`
+	Version         int              `json:"version"`
+	VersionGroupID  string           `json:"versiongroupid"`
+	LockTime        int              `json:"locktime"`
+	ExpiryHeight    int              `json:"expiryheight"`
+	VIn             []VIn            `json:"vin"`
+	VOut            []VOut           `json:"vout"`
+	VJoinSplit      []VJoinSplitTX   `json:"vjoinsplit"`
+	ValueBalance    zcash.Amount     `json:"valueBalance"`
+	ValueBalanceZat int64            `json:"valueBalanceZat"`
+	VShieldedSpend  []ShieldedSpend  `json:"vShieldedSpend"`
+	VShieldedOutput []ShieldedOutput `json:"vShieldedOutput"`
+	BindingSig      string           `json:"bindingSig"`
+	Orchard         *Orchard         `json:"orchard"`
+	BlockHash       string           `json:"blockhash"`
+	Height          int              `json:"height"`
+	Confirmations   int              `json:"confirmations"`
+	Time            int64            `json:"time"`
+	BlockTime       int64            `json:"blocktime"`
+}
+
+// ShieldedSpend is a Sapling spend description. The cv, anchor, nullifier and
+// rk are encoded by zcashd in reverse byte order, like hashes.
+type ShieldedSpend struct {
+	CV           string `json:"cv"`
+	Anchor       string `json:"anchor"`
+	Nullifier    string `json:"nullifier"`
+	RK           string `json:"rk"`
+	Proof        string `json:"proof"`
+	SpendAuthSig string `json:"spendAuthSig"`
+}
+
+// ShieldedOutput is a Sapling output description. The cv, cmu and
+// ephemeralKey are encoded by zcashd in reverse byte order, like hashes.
+type ShieldedOutput struct {
+	CV            string `json:"cv"`
+	CMU           string `json:"cmu"`
+	EphemeralKey  string `json:"ephemeralKey"`
+	EncCiphertext string `json:"encCiphertext"`
+	OutCiphertext string `json:"outCiphertext"`
+	Proof         string `json:"proof"`
+}
+
+// Orchard is the Orchard bundle of a v5 transaction. The flags, anchor, proof
+// and binding signature are only set if there are actions.
+type Orchard struct {
+	Actions         []OrchardAction `json:"actions"`
+	ValueBalance    zcash.Amount    `json:"valueBalance"`
+	ValueBalanceZat int64           `json:"valueBalanceZat"`
+	Flags           *OrchardFlags   `json:"flags"`
+	Anchor          string          `json:"anchor"`
+	Proof           string          `json:"proof"`
+	BindingSig      string          `json:"bindingSig"`
+}
+
+// OrchardFlags are the flags of an Orchard bundle.
+type OrchardFlags struct {
+	EnableSpends  bool `json:"enableSpends"`
+	EnableOutputs bool `json:"enableOutputs"`
+}
+
+// OrchardAction is an Orchard action description. Unlike the Sapling fields,
+// the fields are encoded by zcashd in their serialized byte order.
+type OrchardAction struct {
+	CV            string `json:"cv"`
+	Nullifier     string `json:"nullifier"`
+	RK            string `json:"rk"`
+	CMX           string `json:"cmx"`
+	EphemeralKey  string `json:"ephemeralKey"`
+	EncCiphertext string `json:"encCiphertext"`
+	SpendAuthSig  string `json:"spendAuthSig"`
+	OutCiphertext string `json:"outCiphertext"`
 }
 
 // TransparentInAndOut return if there are transparent
@@ -192,7 +266,8 @@ func (t Transaction) IsTransparent() bool {
 		len(t.VJoinSplit) == 0 &&
 		t.ValueBalance == 0 &&
 		len(t.VShieldedSpend) == 0 &&
-		len(t.VShieldedSpend) == 0
+		len(t.VShieldedOutput) == 0 &&
+		!t.ContainsOrchard()
 }
 
 // ContainsSprout returns if a transaction contains
@@ -210,11 +285,17 @@ func (t Transaction) ContainsSapling() bool {
 		len(t.VShieldedOutput) > 0)
 }
 
+// ContainsOrchard returns if a transaction contains
+// orchard actions
+func (t Transaction) ContainsOrchard() bool {
+	return t.Orchard != nil && len(t.Orchard.Actions) > 0
+}
+
 // IsShielded returns if the transaction contains
 // no transparent addresses
 func (t Transaction) IsShielded() bool {
 	return !t.TransparentInAndOut() &&
-		(t.ContainsSprout() || t.ContainsSapling())
+		(t.ContainsSprout() || t.ContainsSapling() || t.ContainsOrchard())
 }
 
 // IsMixed returns if the transaction contains
@@ -222,15 +303,15 @@ func (t Transaction) IsShielded() bool {
 func (t Transaction) IsMixed() bool {
 	tInOrOut := len(t.VIn) > 0 || len(t.VOut) > 0
 	return tInOrOut &&
-		(t.ContainsSprout() || t.ContainsSapling())
+		(t.ContainsSprout() || t.ContainsSapling() || t.ContainsOrchard())
 }
 
 type VIn struct {
-	Coinbase  string `json:"coinbase"`
-	TxID      string `json:"txid"`
-	VOut      int    `json:"vout"`
-	ScriptSig ScriptSig
-	Sequence  int `json:"sequemce"`
+	Coinbase  string    `json:"coinbase"`
+	TxID      string    `json:"txid"`
+	VOut      int       `json:"vout"`
+	ScriptSig ScriptSig `json:"scriptSig"`
+	Sequence  uint32    `json:"sequence"`
 }
 
 // IsCoinBase returns a bool to show if a Vin is a Coinbase one or not.
@@ -243,20 +324,31 @@ type ScriptSig struct {
 	Hex string `json:"hex"`
 }
 type VOut struct {
-	Value        float64
-	N            int
-	ScriptPubKey ScriptPubKey
+	Value        zcash.Amount `json:"value"`
+	ValueZat     int64        `json:"valueZat"`
+	N            int          `json:"n"`
+	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
 }
 type ScriptPubKey struct {
 	Asm       string   `json:"asm"`
 	Hex       string   `json:"hex"`
-	ReqSigs   int      `json:"reqSigs`
+	ReqSigs   int      `json:"reqSigs"`
 	Type      string   `json:"type"`
 	Addresses []string `json:"addresses"`
 }
 type VJoinSplitTX struct {
-	VPubOld float64 `json:"vpub_old"`
-	VPubNew float64 `json:"vpub_new"`
+	VPubOld       zcash.Amount `json:"vpub_old"`
+	VPubOldZat    int64        `json:"vpub_oldZat"`
+	VPubNew       zcash.Amount `json:"vpub_new"`
+	VPubNewZat    int64        `json:"vpub_newZat"`
+	Anchor        string       `json:"anchor"`
+	Nullifiers    []string     `json:"nullifiers"`
+	Commitments   []string     `json:"commitments"`
+	OnetimePubKey string       `json:"onetimePubKey"`
+	RandomSeed    string       `json:"randomSeed"`
+	Macs          []string     `json:"macs"`
+	Proof         string       `json:"proof"`
+	Ciphertexts   []string     `json:"ciphertexts"`
 }
 type ValuePool struct {
 	ID            string  `json:"id"`
@@ -0,0 +1,202 @@
+package rpcclient_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/chain/zcash/rpcclient"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The fixtures are synthetic: they were written by hand in the format of the
+// verbose `getrawtransaction` of zcashd, and were not captured from a node.
+// The shielded fixtures are built from the ZIP-243 and ZIP-244 test vector
+// transactions, which were never mined, so the fixtures have no block fields.
+// See testdata/README.md.
+func loadTransaction(filename string) (rpcclient.Transaction, *zcash.DecodedTx) {
+	data, err := os.ReadFile(filename)
+	Expect(err).ToNot(HaveOccurred())
+	var tx rpcclient.Transaction
+	Expect(json.Unmarshal(data, &tx)).To(Succeed())
+
+	raw, err := hex.DecodeString(tx.Hex)
+	Expect(err).ToNot(HaveOccurred())
+	decoded, err := zcash.DecodeTx(raw)
+	Expect(err).ToNot(HaveOccurred())
+	txID, err := decoded.TxID()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(tx.Txid).To(Equal(txID.String()))
+	return tx, decoded
+}
+
+// reversed returns the hex encoding of the bytes in reverse order, which is
+// how zcashd encodes hashes and most Sapling fields.
+func reversed(b []byte) string {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return hex.EncodeToString(r)
+}
+
+var _ = Describe("Transaction", func() {
+	Context("when decoding transparent transactions", func() {
+		It("should decode every field", func() {
+			tx, decoded := loadTransaction("testdata/getrawtransaction_v4_transparent.json")
+			Expect(tx.Size).To(Equal(len(decoded.Serialize())))
+			Expect(tx.Overwintered).To(BeTrue())
+			Expect(tx.Version).To(Equal(4))
+			Expect(tx.VersionGroupID).To(Equal("892f2085"))
+			Expect(tx.ExpiryHeight).To(Equal(2000040))
+			// The fixture is not a mined transaction.
+			Expect(tx.BlockHash).To(BeEmpty())
+			Expect(tx.Confirmations).To(Equal(0))
+
+			Expect(tx.VIn).To(HaveLen(1))
+			Expect(tx.VIn[0].TxID).To(Equal(decoded.MsgTx.TxIn[0].PreviousOutPoint.Hash.String()))
+			Expect(tx.VIn[0].VOut).To(Equal(1))
+			Expect(tx.VIn[0].ScriptSig.Hex).To(Equal(hex.EncodeToString(decoded.MsgTx.TxIn[0].SignatureScript)))
+			Expect(tx.VIn[0].Sequence).To(Equal(uint32(0xfffffffe)))
+			Expect(tx.VIn[0].IsCoinBase()).To(BeFalse())
+
+			Expect(tx.VOut).To(HaveLen(2))
+			Expect(tx.VOut[0].Value).To(Equal(zcash.Amount(123456789)))
+			Expect(tx.VOut[0].ValueZat).To(Equal(int64(123456789)))
+			Expect(tx.VOut[0].N).To(Equal(0))
+			Expect(tx.VOut[0].ScriptPubKey.ReqSigs).To(Equal(1))
+			Expect(tx.VOut[0].ScriptPubKey.Type).To(Equal("pubkeyhash"))
+			Expect(tx.VOut[0].ScriptPubKey.Addresses).To(Equal([]string{"t1NYUFkLP3nBGeh7Bb75ZBp5BfZB5NXrkvx"}))
+			Expect(tx.VOut[1].N).To(Equal(1))
+			Expect(tx.VOut[1].ScriptPubKey.Type).To(Equal("scripthash"))
+
+			Expect(tx.VShieldedSpend).To(BeEmpty())
+			Expect(tx.VShieldedOutput).To(BeEmpty())
+			Expect(tx.BindingSig).To(BeEmpty())
+			Expect(tx.Orchard).To(BeNil())
+			Expect(tx.IsTransparent()).To(BeTrue())
+			Expect(tx.IsShielded()).To(BeFalse())
+			Expect(tx.IsMixed()).To(BeFalse())
+		})
+	})
+
+	Context("when decoding mined transactions", func() {
+		It("should decode the block fields", func() {
+			// The values are placeholders, which only check the JSON tags.
+			var tx rpcclient.Transaction
+			Expect(json.Unmarshal([]byte(`{
+				"blockhash": "00000000000000000000000000000000000000000000000000000000000000ff",
+				"height": 1,
+				"confirmations": 2,
+				"time": 3,
+				"blocktime": 4
+			}`), &tx)).To(Succeed())
+			Expect(tx.BlockHash).To(Equal("00000000000000000000000000000000000000000000000000000000000000ff"))
+			Expect(tx.Height).To(Equal(1))
+			Expect(tx.Confirmations).To(Equal(2))
+			Expect(tx.Time).To(Equal(int64(3)))
+			Expect(tx.BlockTime).To(Equal(int64(4)))
+		})
+	})
+
+	Context("when decoding v4 transactions", func() {
+		It("should decode the Sapling spends and outputs", func() {
+			tx, decoded := loadTransaction("testdata/getrawtransaction_v4_sapling.json")
+			Expect(tx.ExpiryHeight).To(Equal(int(decoded.ExpiryHeight)))
+			Expect(tx.ValueBalanceZat).To(Equal(decoded.SaplingValueBalance))
+			Expect(tx.ValueBalance).To(Equal(zcash.Amount(decoded.SaplingValueBalance)))
+			Expect(tx.BindingSig).To(Equal(hex.EncodeToString(decoded.SaplingBindingSig[:])))
+
+			Expect(tx.VShieldedSpend).To(HaveLen(len(decoded.SaplingSpends)))
+			for i, spend := range decoded.SaplingSpends {
+				Expect(tx.VShieldedSpend[i]).To(Equal(rpcclient.ShieldedSpend{
+					CV:           reversed(spend.CV[:]),
+					Anchor:       reversed(spend.Anchor[:]),
+					Nullifier:    reversed(spend.Nullifier[:]),
+					RK:           reversed(spend.RK[:]),
+					Proof:        hex.EncodeToString(spend.Proof[:]),
+					SpendAuthSig: hex.EncodeToString(spend.SpendAuthSig[:]),
+				}))
+			}
+			Expect(tx.VShieldedOutput).To(HaveLen(len(decoded.SaplingOutputs)))
+			for i, output := range decoded.SaplingOutputs {
+				Expect(tx.VShieldedOutput[i]).To(Equal(rpcclient.ShieldedOutput{
+					CV:            reversed(output.CV[:]),
+					CMU:           reversed(output.CMU[:]),
+					EphemeralKey:  reversed(output.EphemeralKey[:]),
+					EncCiphertext: hex.EncodeToString(output.EncCiphertext[:]),
+					OutCiphertext: hex.EncodeToString(output.OutCiphertext[:]),
+					Proof:         hex.EncodeToString(output.Proof[:]),
+				}))
+			}
+
+			Expect(tx.Orchard).To(BeNil())
+			Expect(tx.IsTransparent()).To(BeFalse())
+			Expect(tx.ContainsSapling()).To(BeTrue())
+			Expect(tx.ContainsOrchard()).To(BeFalse())
+			Expect(tx.IsMixed()).To(BeTrue())
+		})
+	})
+
+	Context("when decoding v5 transactions", func() {
+		It("should decode the Orchard actions", func() {
+			tx, decoded := loadTransaction("testdata/getrawtransaction_v5_orchard.json")
+			Expect(tx.Version).To(Equal(5))
+			Expect(tx.VersionGroupID).To(Equal("26a7270a"))
+			Expect(tx.AuthDigest).To(HaveLen(64))
+			Expect(tx.VShieldedSpend).To(HaveLen(1))
+			Expect(tx.VShieldedOutput).To(HaveLen(1))
+			Expect(tx.VShieldedOutput[0].CMU).To(Equal(reversed(decoded.SaplingOutputs[0].CMU[:])))
+
+			Expect(tx.Orchard).ToNot(BeNil())
+			Expect(tx.Orchard.ValueBalanceZat).To(Equal(decoded.OrchardValueBalance))
+			Expect(tx.Orchard.ValueBalance).To(Equal(zcash.Amount(decoded.OrchardValueBalance)))
+			Expect(tx.Orchard.Flags).To(Equal(&rpcclient.OrchardFlags{
+				EnableSpends:  decoded.OrchardFlags&1 != 0,
+				EnableOutputs: decoded.OrchardFlags&2 != 0,
+			}))
+			Expect(tx.Orchard.Anchor).To(Equal(hex.EncodeToString(decoded.OrchardAnchor[:])))
+			Expect(tx.Orchard.Proof).To(Equal(hex.EncodeToString(decoded.OrchardProof)))
+			Expect(tx.Orchard.BindingSig).To(Equal(hex.EncodeToString(decoded.OrchardBindingSig[:])))
+			Expect(tx.Orchard.Actions).To(HaveLen(len(decoded.OrchardActions)))
+			for i, action := range decoded.OrchardActions {
+				Expect(tx.Orchard.Actions[i]).To(Equal(rpcclient.OrchardAction{
+					CV:            hex.EncodeToString(action.CV[:]),
+					Nullifier:     hex.EncodeToString(action.Nullifier[:]),
+					RK:            hex.EncodeToString(action.RK[:]),
+					CMX:           hex.EncodeToString(action.CMX[:]),
+					EphemeralKey:  hex.EncodeToString(action.EphemeralKey[:]),
+					EncCiphertext: hex.EncodeToString(action.EncCiphertext[:]),
+					SpendAuthSig:  hex.EncodeToString(action.SpendAuthSig[:]),
+					OutCiphertext: hex.EncodeToString(action.OutCiphertext[:]),
+				}))
+			}
+
+			Expect(tx.ContainsOrchard()).To(BeTrue())
+			Expect(tx.IsTransparent()).To(BeFalse())
+			Expect(tx.IsShielded()).To(BeTrue())
+		})
+
+		It("should treat transactions with no Orchard actions as transparent", func() {
+			var tx rpcclient.Transaction
+			Expect(json.Unmarshal([]byte(`{
+				"version": 5,
+				"vin": [{"txid": "00", "vout": 0, "scriptSig": {"asm": "", "hex": ""}, "sequence": 4294967295}],
+				"vout": [{"value": 1.0, "valueZat": 100000000, "n": 0, "scriptPubKey": {"type": "nonstandard"}}],
+				"vjoinsplit": [],
+				"valueBalance": 0.0,
+				"valueBalanceZat": 0,
+				"vShieldedSpend": [],
+				"vShieldedOutput": [],
+				"orchard": {"actions": [], "valueBalance": 0.0, "valueBalanceZat": 0}
+			}`), &tx)).To(Succeed())
+			Expect(tx.Orchard).ToNot(BeNil())
+			Expect(tx.Orchard.Flags).To(BeNil())
+			Expect(tx.ContainsOrchard()).To(BeFalse())
+			Expect(tx.IsTransparent()).To(BeTrue())
+		})
+	})
+})
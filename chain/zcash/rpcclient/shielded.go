@@ -0,0 +1,149 @@
+package rpcclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+)
+
+// ZSendManyRecipient is an amount sent by `z_sendmany`. The memo can only be
+// set for shielded addresses (Sapling and unified addresses).
+// https://zcash.github.io/rpc/z_sendmany.html
+type ZSendManyRecipient struct {
+	Address string
+	Amount  zcash.Amount
+	Memo    *zcash.Memo
+}
+
+// MarshalJSON encodes the recipient in the format expected by zcashd. The
+// amount is encoded exactly, as a decimal number of ZEC. An error is returned
+// if the amount is out of range, or if there is a memo and the address is not
+// a shielded address of any network.
+func (recipient ZSendManyRecipient) MarshalJSON() ([]byte, error) {
+	if err := recipient.validate(); err != nil {
+		return nil, err
+	}
+	amount := strings.TrimSuffix(recipient.Amount.Format(zcash.AmountZEC), " "+zcash.AmountZEC.String())
+	return json.Marshal(struct {
+		Address string      `json:"address"`
+		Amount  json.Number `json:"amount"`
+		Memo    *zcash.Memo `json:"memo,omitempty"`
+	}{recipient.Address, json.Number(amount), recipient.Memo})
+}
+
+func (recipient ZSendManyRecipient) validate() error {
+	if err := recipient.Amount.Validate(); err != nil {
+		return fmt.Errorf("bad amount: %v", err)
+	}
+	if recipient.Memo != nil {
+		kind, err := addressKind(address.Address(recipient.Address))
+		if err != nil {
+			return fmt.Errorf("bad address: %v", err)
+		}
+		if !kind.IsShielded() {
+			return fmt.Errorf("bad memo: cannot send a memo to %v address %v", kind, recipient.Address)
+		}
+	}
+	return nil
+}
+
+// addressKind returns the kind of an address of any network, since the client
+// does not know the network of the node.
+func addressKind(addr address.Address) (zcash.AddressKind, error) {
+	var err error
+	for _, params := range []*zcash.Params{&zcash.MainNetParams, &zcash.TestNet3Params, &zcash.RegressionNetParams} {
+		var kind zcash.AddressKind
+		if kind, err = zcash.NewPaymentAddressDecoder(params).AddressKind(addr); err == nil {
+			return kind, nil
+		}
+	}
+	return 0, err
+}
+
+// ZReceived is a note received by an address, as returned by
+// `z_listreceivedbyaddress`. The amount is decoded exactly, and is also given
+// in zatoshi by AmountZat.
+// https://zcash.github.io/rpc/z_listreceivedbyaddress.html
+type ZReceived struct {
+	TxID          string       `json:"txid"`
+	Pool          string       `json:"pool"`
+	Amount        zcash.Amount `json:"amount"`
+	AmountZat     int64        `json:"amountZat"`
+	Memo          zcash.Memo   `json:"memo"`
+	OutIndex      int          `json:"outindex"`
+	JSIndex       int          `json:"jsindex"`
+	JSOutIndex    int          `json:"jsoutindex"`
+	Confirmations int          `json:"confirmations"`
+	BlockHeight   int64        `json:"blockheight"`
+	BlockIndex    int          `json:"blockindex"`
+	BlockTime     int64        `json:"blocktime"`
+	Change        bool         `json:"change"`
+}
+
+// OperationStatus is the status of an asynchronous operation, such as
+// `z_sendmany`, as returned by `z_getoperationstatus`.
+// https://zcash.github.io/rpc/z_getoperationstatus.html
+type OperationStatus struct {
+	ID           string           `json:"id"`
+	Status       string           `json:"status"`
+	CreationTime int64            `json:"creation_time"`
+	Method       string           `json:"method"`
+	Result       *OperationResult `json:"result,omitempty"`
+	Error        *OperationError  `json:"error,omitempty"`
+}
+
+// OperationResult is the result of a successful operation.
+type OperationResult struct {
+	TxID string `json:"txid"`
+}
+
+// OperationError is the error of a failed operation.
+type OperationError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ZSendMany sends the amounts from the address, spending notes and outputs
+// with at least minconf confirmations. It returns the ID of the operation,
+// which can be passed to ZGetOperationStatus.
+func (c *Client) ZSendMany(from string, recipients []ZSendManyRecipient, minconf int) (string, error) {
+	// The recipients are validated before the call, because jsonrpc panics
+	// if the params cannot be encoded.
+	for i, recipient := range recipients {
+		if err := recipient.validate(); err != nil {
+			return "", fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+	}
+	var opID string
+	err := c.rpcClient.CallFor(&opID, "z_sendmany", from, recipients, minconf)
+	return opID, err
+}
+
+// ZListReceivedByAddress returns the notes received by the shielded address
+// with at least minconf confirmations. The memos are decoded from hex.
+func (c *Client) ZListReceivedByAddress(address string, minconf int) ([]ZReceived, error) {
+	received := []ZReceived{}
+	if err := c.rpcClient.CallFor(&received, "z_listreceivedbyaddress", address, minconf); err != nil {
+		return nil, err
+	}
+	return received, nil
+}
+
+// ZGetOperationStatus returns the status of the operations. All operations are
+// returned if no IDs are given.
+func (c *Client) ZGetOperationStatus(opIDs ...string) ([]OperationStatus, error) {
+	statuses := []OperationStatus{}
+	// A single slice is sent as the params array, so the IDs are wrapped to
+	// be sent as the first param.
+	params := []interface{}{}
+	if len(opIDs) > 0 {
+		params = append(params, []interface{}{opIDs})
+	}
+	if err := c.rpcClient.CallFor(&statuses, "z_getoperationstatus", params...); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
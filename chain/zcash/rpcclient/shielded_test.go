@@ -0,0 +1,159 @@
+package rpcclient_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	btcbech32 "github.com/btcsuite/btcutil/bech32"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/chain/zcash/rpcclient"
+	"github.com/pranav292gpt/zecutil/internal/bech32"
+	"github.com/pranav292gpt/zecutil/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shielded RPCs", func() {
+	params := &zcash.RegressionNetParams
+
+	// saplingAddress returns a regtest Sapling address with the given payload.
+	// The payload is not a valid point, which the node does not check.
+	saplingAddress := func(seed byte) string {
+		payload := make([]byte, zcash.SaplingAddressSize)
+		payload[0] = seed
+		values, err := btcbech32.ConvertBits(payload, 8, 5, true)
+		Expect(err).ToNot(HaveOccurred())
+		return bech32.Encode(params.SaplingHRP, values, bech32.Bech32)
+	}
+
+	var server *testutil.Server
+	var client *rpcclient.Client
+	var from, to, taddr string
+
+	BeforeEach(func() {
+		server = testutil.NewServer(testutil.ZcashChain(params))
+		var err error
+		client, err = rpcclient.New(&rpcclient.ConnConfig{Host: strings.TrimPrefix(server.URL(), "http://")})
+		Expect(err).ToNot(HaveOccurred())
+
+		from, to = saplingAddress(1), saplingAddress(2)
+		pkhAddr, err := zcash.NewAddressPubKeyHash(make([]byte, 20), params)
+		Expect(err).ToNot(HaveOccurred())
+		taddr = pkhAddr.EncodeAddress()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("when encoding recipients", func() {
+		It("should encode the amount as an exact number", func() {
+			memo, err := zcash.NewTextMemo("hello")
+			Expect(err).ToNot(HaveOccurred())
+			data, err := json.Marshal(rpcclient.ZSendManyRecipient{Address: to, Amount: 1, Memo: &memo})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"address":"` + to + `","amount":0.00000001,"memo":"` + memo.Hex() + `"}`))
+
+			data, err = json.Marshal(rpcclient.ZSendManyRecipient{Address: taddr, Amount: 123456789})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"address":"` + taddr + `","amount":1.23456789}`))
+		})
+
+		It("should return an error for bad recipients", func() {
+			memo := zcash.NoMemo()
+			for _, recipient := range []rpcclient.ZSendManyRecipient{
+				{Address: to, Amount: -1},
+				{Address: to, Amount: zcash.MaxZatoshi + 1},
+				{Address: taddr, Amount: 1, Memo: &memo},
+				{Address: "bad", Amount: 1, Memo: &memo},
+			} {
+				_, err := json.Marshal(recipient)
+				Expect(err).To(HaveOccurred(), "%v", recipient)
+			}
+		})
+	})
+
+	Context("when sending to shielded addresses", func() {
+		It("should list the notes that were sent once they are confirmed", func() {
+			memo, err := zcash.NewTextMemo("invoice 42")
+			Expect(err).ToNot(HaveOccurred())
+			opID, err := client.ZSendMany(from, []rpcclient.ZSendManyRecipient{
+				{Address: to, Amount: 1, Memo: &memo},
+				{Address: taddr, Amount: 150000000},
+				{Address: to, Amount: 123456789},
+			}, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.Calls("z_sendmany")).To(Equal(1))
+
+			received, err := client.ZListReceivedByAddress(to, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(received).To(BeEmpty())
+			received, err = client.ZListReceivedByAddress(to, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(received).To(HaveLen(2))
+
+			server.Mine(1)
+			received, err = client.ZListReceivedByAddress(to, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(received).To(HaveLen(2))
+			Expect(received[0].Pool).To(Equal("sapling"))
+			Expect(received[0].AmountZat).To(Equal(int64(1)))
+			Expect(received[0].Amount).To(Equal(zcash.Amount(1)))
+			Expect(received[0].Memo).To(Equal(memo))
+			Expect(received[0].OutIndex).To(Equal(0))
+			Expect(received[0].Confirmations).To(Equal(1))
+			Expect(received[0].BlockHeight).To(Equal(server.Height()))
+			Expect(received[1].AmountZat).To(Equal(int64(123456789)))
+			Expect(received[1].Memo).To(Equal(zcash.NoMemo()))
+			Expect(received[1].OutIndex).To(Equal(2))
+
+			statuses, err := client.ZGetOperationStatus(opID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statuses).To(HaveLen(1))
+			Expect(statuses[0].ID).To(Equal(opID))
+			Expect(statuses[0].Status).To(Equal("success"))
+			Expect(statuses[0].Method).To(Equal("z_sendmany"))
+			Expect(statuses[0].Result).ToNot(BeNil())
+			Expect(statuses[0].Result.TxID).To(Equal(received[0].TxID))
+			Expect(statuses[0].Error).To(BeNil())
+		})
+
+		It("should return the status of each of the given operations", func() {
+			opIDs := []string{}
+			for i := 0; i < 3; i++ {
+				opID, err := client.ZSendMany(from, []rpcclient.ZSendManyRecipient{{Address: to, Amount: 1}}, 1)
+				Expect(err).ToNot(HaveOccurred())
+				opIDs = append(opIDs, opID)
+			}
+
+			// The IDs are sent as a single array param, so the node only
+			// returns the given operations.
+			statuses, err := client.ZGetOperationStatus(opIDs[0], opIDs[2])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statuses).To(HaveLen(2))
+			Expect(statuses[0].ID).To(Equal(opIDs[0]))
+			Expect(statuses[1].ID).To(Equal(opIDs[2]))
+
+			statuses, err = client.ZGetOperationStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statuses).To(HaveLen(3))
+		})
+
+		It("should not send bad recipients to the node", func() {
+			memo := zcash.NoMemo()
+			_, err := client.ZSendMany(from, []rpcclient.ZSendManyRecipient{{Address: to, Amount: -1}}, 1)
+			Expect(err).To(HaveOccurred())
+			_, err = client.ZSendMany(from, []rpcclient.ZSendManyRecipient{{Address: taddr, Amount: 1, Memo: &memo}}, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(server.Calls("z_sendmany")).To(Equal(0))
+		})
+
+		It("should return the errors of the node", func() {
+			_, err := client.ZListReceivedByAddress(taddr, 1)
+			Expect(err).To(HaveOccurred())
+			_, err = client.ZSendMany(from, []rpcclient.ZSendManyRecipient{{Address: "bad", Amount: 1}}, 1)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
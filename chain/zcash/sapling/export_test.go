@@ -0,0 +1,66 @@
+package sapling
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// This file exports internals of the package to its tests.
+
+// Blake2s256 exports blake2s256.
+var Blake2s256 = blake2s256
+
+// PedersenBases returns the encodings of the Pedersen hash generators.
+func PedersenBases() [][32]byte {
+	bases := make([][32]byte, len(pedersenBases))
+	for i, base := range pedersenBases {
+		bases[i] = base.bytes()
+	}
+	return bases
+}
+
+// PedersenRandomnessBase returns the encoding of the generator used for the
+// randomness of the windowed Pedersen commitment.
+func PedersenRandomnessBase() [32]byte {
+	return pedersenRandomnessBase.bytes()
+}
+
+// EncryptNote returns an output that sends a note to the default address of
+// the ivk with the diversifier, using a ZIP-212 note plaintext. The ivk must
+// be valid for the diversifier.
+func EncryptNote(ivk IncomingViewingKey, diversifier [11]byte, value uint64, rseed [32]byte, memo [512]byte) Output {
+	gd, ok := diversifyHash(diversifier)
+	if !ok {
+		panic("bad diversifier")
+	}
+	pkd := gd.mul(leInt(ivk[:]))
+	rcm := scalar(prfExpand(rseed[:], 4))
+	esk := scalar(prfExpand(rseed[:], 5))
+
+	var output Output
+	output.CMU = noteCommitment(rcm, gd.bytes(), pkd.bytes(), value).uBytes()
+	output.EphemeralKey = gd.mul(esk).bytes()
+	key := kdf(pkd.mul(esk).mulByCofactor().bytes(), output.EphemeralKey)
+
+	plaintext := make([]byte, notePlaintextSize)
+	plaintext[0] = leadByteZIP212
+	copy(plaintext[1:12], diversifier[:])
+	binary.LittleEndian.PutUint64(plaintext[12:20], value)
+	copy(plaintext[20:52], rseed[:])
+	copy(plaintext[52:], memo[:])
+	aead, err := chacha20poly1305.New(key[:])
+	if err != nil {
+		panic(err)
+	}
+	output.EncCiphertext = aead.Seal(nil, make([]byte, aead.NonceSize()), plaintext, nil)
+	return output
+}
+
+// Rcm returns the rcm derived from a ZIP-212 rseed.
+func Rcm(rseed [32]byte) [32]byte {
+	var rcm [32]byte
+	scalar(prfExpand(rseed[:], 4)).FillBytes(rcm[:])
+	reverse(rcm[:])
+	return rcm
+}
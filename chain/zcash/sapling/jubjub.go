@@ -0,0 +1,171 @@
+package sapling
+
+import (
+	"math/big"
+)
+
+var (
+	// q is the order of the base field of Jubjub, which is the order of the
+	// scalar field of BLS12-381.
+	q, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+	// r is the order of the prime-order subgroup of Jubjub.
+	r, _ = new(big.Int).SetString("0e7db4ea6533afa906673b0101343b00a6682093ccc81082d0970e5ed6f72cb7", 16)
+
+	// d is the Jubjub curve parameter -(10240/10241), and d2 is 2*d. The
+	// other parameter, a, is -1.
+	d  = fqMul(fqNeg(big.NewInt(10240)), new(big.Int).ModInverse(big.NewInt(10241), q))
+	d2 = fqAdd(d, d)
+
+	one = big.NewInt(1)
+)
+
+func fqAdd(a, b *big.Int) *big.Int {
+	z := new(big.Int).Add(a, b)
+	if z.Cmp(q) >= 0 {
+		z.Sub(z, q)
+	}
+	return z
+}
+
+func fqSub(a, b *big.Int) *big.Int {
+	z := new(big.Int).Sub(a, b)
+	if z.Sign() < 0 {
+		z.Add(z, q)
+	}
+	return z
+}
+
+func fqMul(a, b *big.Int) *big.Int {
+	z := new(big.Int).Mul(a, b)
+	return z.Mod(z, q)
+}
+
+func fqNeg(a *big.Int) *big.Int {
+	if a.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Sub(q, a)
+}
+
+// point is a point on Jubjub in extended twisted Edwards coordinates, where the
+// affine coordinates are (u, v) = (x/z, y/z), and t = x*y/z.
+type point struct {
+	x, y, z, t *big.Int
+}
+
+// identity returns the identity of Jubjub, which is (0, 1).
+func identity() point {
+	return point{x: new(big.Int), y: big.NewInt(1), z: big.NewInt(1), t: new(big.Int)}
+}
+
+// add returns p + o. The addition formula is complete for Jubjub, so it is
+// also used for doubling.
+func (p point) add(o point) point {
+	a := fqMul(fqSub(p.y, p.x), fqSub(o.y, o.x))
+	b := fqMul(fqAdd(p.y, p.x), fqAdd(o.y, o.x))
+	c := fqMul(fqMul(p.t, d2), o.t)
+	dd := fqMul(fqAdd(p.z, p.z), o.z)
+	e, f, g, h := fqSub(b, a), fqSub(dd, c), fqAdd(dd, c), fqAdd(b, a)
+	return point{x: fqMul(e, f), y: fqMul(g, h), z: fqMul(f, g), t: fqMul(e, h)}
+}
+
+// mul returns [k] p, where k is a non-negative integer. It is not
+// constant-time: the time taken depends on the bits of k, which is the secret
+// ivk when decrypting notes.
+func (p point) mul(k *big.Int) point {
+	result := identity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.add(result)
+		if k.Bit(i) == 1 {
+			result = result.add(p)
+		}
+	}
+	return result
+}
+
+// mulByCofactor returns [8] p.
+func (p point) mulByCofactor() point {
+	p2 := p.add(p)
+	p4 := p2.add(p2)
+	return p4.add(p4)
+}
+
+// affine returns the affine coordinates (u, v) of the point.
+func (p point) affine() (*big.Int, *big.Int) {
+	zInv := new(big.Int).ModInverse(p.z, q)
+	return fqMul(p.x, zInv), fqMul(p.y, zInv)
+}
+
+// isIdentity returns true if the point is the identity.
+func (p point) isIdentity() bool {
+	u, v := p.affine()
+	return u.Sign() == 0 && v.Cmp(one) == 0
+}
+
+// bytes returns repr_J of the point: the little-endian encoding of v, with the
+// sign of u in the highest bit.
+func (p point) bytes() [32]byte {
+	u, v := p.affine()
+	var b [32]byte
+	v.FillBytes(b[:])
+	reverse(b[:])
+	b[31] |= byte(u.Bit(0)) << 7
+	return b
+}
+
+// uBytes returns Extract_J(r) of the point: the little-endian encoding of u.
+func (p point) uBytes() [32]byte {
+	u, _ := p.affine()
+	var b [32]byte
+	u.FillBytes(b[:])
+	reverse(b[:])
+	return b
+}
+
+// decodePoint returns the point encoded by repr_J, which is abst_J in the
+// protocol specification. It returns false if the encoding is not canonical,
+// or is not a point on Jubjub.
+func decodePoint(b [32]byte) (point, bool) {
+	sign := uint(b[31] >> 7)
+	b[31] &= 0x7f
+	v := leInt(b[:])
+	if v.Cmp(q) >= 0 {
+		return point{}, false
+	}
+
+	// From -u^2 + v^2 = 1 + d*u^2*v^2, u^2 = (v^2 - 1) / (d*v^2 + 1). The
+	// denominator is never zero, because d is not a square.
+	vv := fqMul(v, v)
+	u2 := fqMul(fqSub(vv, one), new(big.Int).ModInverse(fqAdd(fqMul(d, vv), one), q))
+	u := new(big.Int).ModSqrt(u2, q)
+	if u == nil {
+		return point{}, false
+	}
+	if u.Bit(0) != sign {
+		if u.Sign() == 0 {
+			return point{}, false
+		}
+		u = fqNeg(u)
+	}
+	return point{x: u, y: v, z: big.NewInt(1), t: fqMul(u, v)}, true
+}
+
+// leInt returns the integer encoded in little-endian by b.
+func leInt(b []byte) *big.Int {
+	be := append([]byte(nil), b...)
+	reverse(be)
+	return new(big.Int).SetBytes(be)
+}
+
+// scalar returns the integer encoded in little-endian by b, reduced modulo r.
+// This is ToScalar in the protocol specification.
+func scalar(b []byte) *big.Int {
+	x := leInt(b)
+	return x.Mod(x, r)
+}
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,120 @@
+package sapling
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// urs is the uniform random string that is hashed before the message in the
+// group hash of Sapling.
+const urs = "096b36a5804bfacef1691e173c366a47ff5ba84a44f26ddd7e8d9f79d5b42df0"
+
+// pedersenChunksPerSegment is the number of 3-bit chunks that are hashed with
+// each generator of the Pedersen hash.
+const pedersenChunksPerSegment = 63
+
+// noteCommitBases is the number of Pedersen hash generators needed by the
+// note commitment, which hashes 582 bits.
+const noteCommitBases = 4
+
+var (
+	// pedersenBases are the generators of the Pedersen hash.
+	pedersenBases = func() []point {
+		bases := make([]point, noteCommitBases)
+		for i := range bases {
+			var m [4]byte
+			binary.LittleEndian.PutUint32(m[:], uint32(i))
+			bases[i] = mustFindGroupHash("Zcash_PH", m[:])
+		}
+		return bases
+	}()
+
+	// pedersenRandomnessBase is the generator used for the randomness of the
+	// windowed Pedersen commitment.
+	pedersenRandomnessBase = mustFindGroupHash("Zcash_PH", []byte("r"))
+)
+
+// groupHash returns the point of the prime-order subgroup of Jubjub that the
+// message hashes to, with the 8-byte personalization. It returns false if the
+// hash is not a point, or is a point of small order.
+func groupHash(personalization string, m []byte) (point, bool) {
+	var person [8]byte
+	copy(person[:], personalization)
+	p, ok := decodePoint(blake2s256(person, append([]byte(urs), m...)))
+	if !ok {
+		return point{}, false
+	}
+	p = p.mulByCofactor()
+	if p.isIdentity() {
+		return point{}, false
+	}
+	return p, true
+}
+
+// mustFindGroupHash returns the group hash of the message with the first
+// suffix byte that hashes to a point. It panics if there is no such byte,
+// which does not happen for the constant messages it is used with.
+func mustFindGroupHash(personalization string, m []byte) point {
+	for i := 0; i < 256; i++ {
+		if p, ok := groupHash(personalization, append(append([]byte(nil), m...), byte(i))); ok {
+			return p
+		}
+	}
+	panic(fmt.Sprintf("no group hash for %q", m))
+}
+
+// diversifyHash returns the diversified base g_d of the diversifier. It returns
+// false if the diversifier is not valid.
+func diversifyHash(diversifier [11]byte) (point, bool) {
+	return groupHash("Zcash_gd", diversifier[:])
+}
+
+// pedersenHashToPoint returns the Pedersen hash of the bits, as a point. Each
+// element of the bits is either 0 or 1. This is PedersenHashToPoint with the
+// "Zcash_PH" personalization, and it supports at most noteCommitBases
+// segments.
+func pedersenHashToPoint(bits []byte) point {
+	for len(bits)%3 != 0 {
+		bits = append(bits, 0)
+	}
+
+	result := identity()
+	for i := 0; len(bits) > 0; i++ {
+		n := len(bits)
+		if n > 3*pedersenChunksPerSegment {
+			n = 3 * pedersenChunksPerSegment
+		}
+		segment := bits[:n]
+		bits = bits[n:]
+
+		// Each chunk is encoded as (1 - 2*s2) * (1 + s0 + 2*s1), and weighted
+		// by 2^(4*j).
+		sum := new(big.Int)
+		for j := 0; j < len(segment); j += 3 {
+			chunk := int64(1 + segment[j] + 2*segment[j+1])
+			if segment[j+2] == 1 {
+				chunk = -chunk
+			}
+			sum.Add(sum, new(big.Int).Lsh(big.NewInt(chunk), uint(4*(j/3))))
+		}
+		result = result.add(pedersenBases[i].mul(sum.Mod(sum, r)))
+	}
+	return result
+}
+
+// noteCommitment returns the windowed Pedersen commitment to the note, as a
+// point. The u coordinate of the point is the cmu of the output.
+func noteCommitment(rcm *big.Int, gd, pkd [32]byte, value uint64) point {
+	bits := make([]byte, 0, 6+64+2*256)
+	bits = append(bits, 1, 1, 1, 1, 1, 1)
+	for i := 0; i < 64; i++ {
+		bits = append(bits, byte(value>>i)&1)
+	}
+	for _, b := range append(gd[:], pkd[:]...) {
+		for i := 0; i < 8; i++ {
+			bits = append(bits, (b>>i)&1)
+		}
+	}
+	return pedersenHashToPoint(bits).add(pedersenRandomnessBase.mul(rcm))
+}
@@ -0,0 +1,275 @@
+// Package sapling implements trial decryption of Sapling shielded outputs with
+// an incoming viewing key. It is written in pure Go, so shielded deposits can
+// be detected from the raw outputs of a transaction (or from compact blocks)
+// without using the wallet of zcashd.
+//
+// The curve arithmetic uses math/big, so it is not constant-time: the time
+// taken to decrypt an output depends on the incoming viewing key. It must not
+// be used where an attacker can measure the time taken to scan outputs.
+package sapling
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	blake2 "github.com/dchest/blake2b"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/chain/zcash/rpcclient"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// EncCiphertextSize is the size (in bytes) of the encrypted note of a
+	// Sapling output, including the memo and the authentication tag.
+	EncCiphertextSize = notePlaintextSize + chacha20poly1305.Overhead
+	// CompactCiphertextSize is the size (in bytes) of the encrypted note of a
+	// compact output, as defined by ZIP-307. It does not include the memo, or
+	// the authentication tag.
+	CompactCiphertextSize = 52
+
+	// notePlaintextSize is the size (in bytes) of the note plaintext: the
+	// lead byte, diversifier, value, rseed and memo.
+	notePlaintextSize = 1 + 11 + 8 + 32 + zcash.MemoSize
+
+	// leadBytePreZIP212 is the lead byte of a note plaintext whose rseed is
+	// the rcm of the note.
+	leadBytePreZIP212 = 0x01
+	// leadByteZIP212 is the lead byte of a note plaintext whose rseed is
+	// used to derive the rcm and esk of the note, as defined by ZIP-212.
+	leadByteZIP212 = 0x02
+
+	// zip212GracePeriod is the number of blocks, after the activation of
+	// Canopy, in which note plaintexts with either lead byte are accepted (as
+	// defined by ZIP-212).
+	zip212GracePeriod = 32256
+
+	kdfPersonalization    = "Zcash_SaplingKDF"
+	expandPersonalization = "Zcash_ExpandSeed"
+)
+
+// IncomingViewingKey is the ivk of a Sapling address. It is a scalar, encoded
+// in little-endian, that is less than 2^251.
+type IncomingViewingKey [32]byte
+
+// NewIncomingViewingKey returns the incoming viewing key with the given
+// little-endian bytes. An error is returned if the bytes are not a valid ivk.
+func NewIncomingViewingKey(b []byte) (IncomingViewingKey, error) {
+	var ivk IncomingViewingKey
+	if len(b) != len(ivk) {
+		return ivk, fmt.Errorf("bad ivk: expected %v bytes, got %v bytes", len(ivk), len(b))
+	}
+	if b[31]&0xf8 != 0 {
+		return ivk, fmt.Errorf("bad ivk: expected less than 2^251")
+	}
+	copy(ivk[:], b)
+	return ivk, nil
+}
+
+// Output is a Sapling output of a transaction, or a compact output of a
+// compact block. Only the fields needed to decrypt the note are included.
+type Output struct {
+	// CMU is the u coordinate of the note commitment, in little-endian.
+	CMU [32]byte
+	// EphemeralKey is the encoding of the ephemeral public key.
+	EphemeralKey [32]byte
+	// EncCiphertext is the encrypted note. It has EncCiphertextSize bytes for
+	// full outputs, or CompactCiphertextSize bytes for compact outputs.
+	EncCiphertext []byte
+}
+
+// ParseRPCOutput returns the output from an element of the `vShieldedOutput`
+// of a verbose transaction returned by zcashd. The cmu and ephemeralKey are
+// encoded by zcashd in reverse byte order, like hashes.
+func ParseRPCOutput(output rpcclient.ShieldedOutput) (Output, error) {
+	var parsed Output
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   []byte
+	}{
+		{"cmu", output.CMU, parsed.CMU[:]},
+		{"ephemeralKey", output.EphemeralKey, parsed.EphemeralKey[:]},
+	} {
+		b, err := hex.DecodeString(field.value)
+		if err != nil {
+			return Output{}, fmt.Errorf("bad %v: %v", field.name, err)
+		}
+		if len(b) != len(field.dst) {
+			return Output{}, fmt.Errorf("bad %v: expected %v bytes, got %v bytes", field.name, len(field.dst), len(b))
+		}
+		reverse(b)
+		copy(field.dst, b)
+	}
+	encCiphertext, err := hex.DecodeString(output.EncCiphertext)
+	if err != nil {
+		return Output{}, fmt.Errorf("bad encCiphertext: %v", err)
+	}
+	if len(encCiphertext) != EncCiphertextSize {
+		return Output{}, fmt.Errorf("bad encCiphertext: expected %v bytes, got %v bytes", EncCiphertextSize, len(encCiphertext))
+	}
+	parsed.EncCiphertext = encCiphertext
+	return parsed, nil
+}
+
+// Note is a Sapling note that has been decrypted with an incoming viewing key.
+type Note struct {
+	// Diversifier is the diversifier of the address that received the note.
+	Diversifier [11]byte
+	// PkD is the diversified transmission key of the address that received
+	// the note.
+	PkD [32]byte
+	// Value is the value of the note, in zatoshi.
+	Value uint64
+	// Rcm is the commitment randomness of the note, in little-endian.
+	Rcm [32]byte
+	// Memo is the memo of the note. It is nil for compact outputs, which do
+	// not include the memo.
+	Memo *zcash.Memo
+}
+
+// canopyBranchID is the consensus branch ID of Canopy, in little-endian byte
+// order, which activates ZIP-212.
+var canopyBranchID = []byte{0xA6, 0x75, 0xff, 0xe9}
+
+// TryDecrypt decrypts the output, which was mined at the given height of the
+// network, with the incoming viewing key. It returns false if the output was
+// not sent to an address of the key. The note commitment of the output is
+// checked, so a returned note is always the note committed to by the output.
+//
+// The lead byte of the note plaintext is checked as defined by ZIP-212. Before
+// Canopy, only notes with lead byte 0x01 are accepted. After Canopy, notes
+// with lead byte 0x02 are accepted, and notes with lead byte 0x01 are only
+// accepted during the grace period of 32256 blocks.
+//
+// Compact outputs are decrypted without their authentication tag, so they are
+// only authenticated by the note commitment.
+func (ivk IncomingViewingKey) TryDecrypt(output Output, params *zcash.Params, height uint32) (Note, bool) {
+	epk, ok := decodePoint(output.EphemeralKey)
+	if !ok {
+		return Note{}, false
+	}
+	ivkScalar := leInt(ivk[:])
+	sharedSecret := epk.mulByCofactor().mul(ivkScalar).bytes()
+	key := kdf(sharedSecret, output.EphemeralKey)
+
+	var plaintext []byte
+	switch len(output.EncCiphertext) {
+	case EncCiphertextSize:
+		aead, err := chacha20poly1305.New(key[:])
+		if err != nil {
+			return Note{}, false
+		}
+		if plaintext, err = aead.Open(nil, make([]byte, aead.NonceSize()), output.EncCiphertext, nil); err != nil {
+			return Note{}, false
+		}
+	case CompactCiphertextSize:
+		// The first block of the keystream is used by Poly1305, so the
+		// plaintext starts at the second block.
+		cipher, err := chacha20.NewUnauthenticatedCipher(key[:], make([]byte, chacha20.NonceSize))
+		if err != nil {
+			return Note{}, false
+		}
+		cipher.SetCounter(1)
+		plaintext = make([]byte, CompactCiphertextSize)
+		cipher.XORKeyStream(plaintext, output.EncCiphertext)
+	default:
+		return Note{}, false
+	}
+
+	var note Note
+	copy(note.Diversifier[:], plaintext[1:12])
+	note.Value = binary.LittleEndian.Uint64(plaintext[12:20])
+	rseed := plaintext[20:52]
+	if len(plaintext) == notePlaintextSize {
+		memo, err := zcash.ParseMemo(plaintext[52:])
+		if err != nil {
+			return Note{}, false
+		}
+		note.Memo = &memo
+	}
+
+	gd, ok := diversifyHash(note.Diversifier)
+	if !ok {
+		return Note{}, false
+	}
+	if !leadByteAllowed(plaintext[0], params, height) {
+		return Note{}, false
+	}
+	var rcm *big.Int
+	switch plaintext[0] {
+	case leadBytePreZIP212:
+		if rcm = leInt(rseed); rcm.Cmp(r) >= 0 {
+			return Note{}, false
+		}
+	case leadByteZIP212:
+		rcm = scalar(prfExpand(rseed, 4))
+		// The ephemeral key is derived from the rseed, so it can be checked
+		// by the recipient.
+		esk := scalar(prfExpand(rseed, 5))
+		if gd.mul(esk).bytes() != output.EphemeralKey {
+			return Note{}, false
+		}
+	default:
+		return Note{}, false
+	}
+	rcmBytes := make([]byte, 32)
+	rcm.FillBytes(rcmBytes)
+	reverse(rcmBytes)
+	copy(note.Rcm[:], rcmBytes)
+
+	note.PkD = gd.mul(ivkScalar).bytes()
+	if noteCommitment(rcm, gd.bytes(), note.PkD, note.Value).uBytes() != output.CMU {
+		return Note{}, false
+	}
+	return note, true
+}
+
+// leadByteAllowed returns true if notes with the lead byte can be mined at the
+// height of the network (as defined by ZIP-212). Networks without Canopy only
+// allow the lead byte 0x01.
+func leadByteAllowed(leadByte byte, params *zcash.Params, height uint32) bool {
+	for _, upgrade := range params.Upgrades {
+		if !bytes.Equal(upgrade.BranchID, canopyBranchID) {
+			continue
+		}
+		switch leadByte {
+		case leadBytePreZIP212:
+			return uint64(height) < uint64(upgrade.ActivationHeight)+zip212GracePeriod
+		case leadByteZIP212:
+			return height >= upgrade.ActivationHeight
+		default:
+			return false
+		}
+	}
+	return leadByte == leadBytePreZIP212
+}
+
+// kdf returns the symmetric key used to encrypt the note, which is KDF^Sapling
+// in the protocol specification.
+func kdf(sharedSecret, ephemeralKey [32]byte) [32]byte {
+	hash, err := blake2.New(&blake2.Config{Person: []byte(kdfPersonalization), Size: 32})
+	if err != nil {
+		panic(err)
+	}
+	hash.Write(sharedSecret[:])
+	hash.Write(ephemeralKey[:])
+	var key [32]byte
+	copy(key[:], hash.Sum(nil))
+	return key
+}
+
+// prfExpand returns PRF^expand of the seed, with the single domain separation
+// byte.
+func prfExpand(seed []byte, t byte) []byte {
+	hash, err := blake2.New(&blake2.Config{Person: []byte(expandPersonalization), Size: 64})
+	if err != nil {
+		panic(err)
+	}
+	hash.Write(seed)
+	hash.Write([]byte{t})
+	return hash.Sum(nil)
+}
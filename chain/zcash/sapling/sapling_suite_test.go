@@ -0,0 +1,13 @@
+package sapling_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSapling(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sapling Suite")
+}
@@ -0,0 +1,322 @@
+package sapling_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/chain/zcash/rpcclient"
+	"github.com/pranav292gpt/zecutil/chain/zcash/sapling"
+	"golang.org/x/crypto/blake2s"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// noteEncryptionVector is a Sapling note encryption test vector from
+// zcash-test-vectors (sapling/note_encryption.py). Only the columns needed to
+// decrypt the note are loaded.
+type noteEncryptionVector struct {
+	ivk           []byte
+	diversifier   []byte
+	pkd           []byte
+	value         uint64
+	rcm           []byte
+	memo          []byte
+	cmu           []byte
+	epk           []byte
+	encCiphertext []byte
+}
+
+// loadVectors returns the rows of a test vector file, as hex strings and
+// numbers. The first two rows, which are the source and the names of the
+// columns, are skipped.
+func loadVectors(filename string) [][]json.RawMessage {
+	data, err := os.ReadFile(filename)
+	Expect(err).ToNot(HaveOccurred())
+	var rows [][]json.RawMessage
+	Expect(json.Unmarshal(data, &rows)).To(Succeed())
+	return rows[2:]
+}
+
+func decodeHex(raw json.RawMessage) []byte {
+	var str string
+	Expect(json.Unmarshal(raw, &str)).To(Succeed())
+	data, err := hex.DecodeString(str)
+	Expect(err).ToNot(HaveOccurred())
+	return data
+}
+
+func loadNoteEncryptionVectors() []noteEncryptionVector {
+	rows := loadVectors("testdata/sapling_note_encryption.json")
+	vectors := make([]noteEncryptionVector, 0, len(rows))
+	for _, row := range rows {
+		vector := noteEncryptionVector{
+			ivk:           decodeHex(row[1]),
+			diversifier:   decodeHex(row[2]),
+			pkd:           decodeHex(row[3]),
+			rcm:           decodeHex(row[5]),
+			memo:          decodeHex(row[6]),
+			cmu:           decodeHex(row[8]),
+			epk:           decodeHex(row[10]),
+			encCiphertext: decodeHex(row[14]),
+		}
+		Expect(json.Unmarshal(row[4], &vector.value)).To(Succeed())
+		vectors = append(vectors, vector)
+	}
+	return vectors
+}
+
+func (vector noteEncryptionVector) output() sapling.Output {
+	output := sapling.Output{EncCiphertext: vector.encCiphertext}
+	copy(output.CMU[:], vector.cmu)
+	copy(output.EphemeralKey[:], vector.epk)
+	return output
+}
+
+func (vector noteEncryptionVector) incomingViewingKey() sapling.IncomingViewingKey {
+	ivk, err := sapling.NewIncomingViewingKey(vector.ivk)
+	Expect(err).ToNot(HaveOccurred())
+	return ivk
+}
+
+var _ = Describe("Sapling", func() {
+	// The test vectors are pre-ZIP-212 notes, so they are decrypted at a
+	// height before the activation of Canopy on mainnet.
+	params := &zcash.MainNetParams
+	const canopy = 1046400
+	const preCanopy, postGrace = canopy - 1, canopy + 32256
+
+	Context("when hashing with BLAKE2s", func() {
+		It("should match the unpersonalized hash", func() {
+			for _, n := range []int{0, 1, 63, 64, 65, 128, 200} {
+				data := make([]byte, n)
+				for i := range data {
+					data[i] = byte(i)
+				}
+				Expect(sapling.Blake2s256([8]byte{}, data)).To(Equal(blake2s.Sum256(data)), "%v bytes", n)
+			}
+		})
+
+		It("should match the personalized hash", func() {
+			data := make([]byte, 200)
+			for i := range data {
+				data[i] = byte(i)
+			}
+			for _, test := range []struct {
+				person string
+				data   []byte
+				hash   string
+			}{
+				{"Zcash_gd", []byte("abc"), "4248bc4911ea95f052d0b1d1f3c408c6c8ce9cbb5e2702a319e272b9110de2df"},
+				{"Zcash_PH", data, "e717e5c76cb72699e4bd25a0cd4ff0a4d06494658a8f6e5dc500e9b6e21ef0bc"},
+			} {
+				var person [8]byte
+				copy(person[:], test.person)
+				hash := sapling.Blake2s256(person, test.data)
+				Expect(hex.EncodeToString(hash[:])).To(Equal(test.hash))
+			}
+		})
+	})
+
+	Context("when deriving the generators", func() {
+		It("should match the test vectors", func() {
+			rows := loadVectors("testdata/sapling_generators.json")
+			Expect(rows).To(HaveLen(1))
+			row := rows[0]
+
+			randomnessBase := sapling.PedersenRandomnessBase()
+			Expect(randomnessBase[:]).To(Equal(decodeHex(row[3])))
+			for i, base := range sapling.PedersenBases() {
+				Expect(base[:]).To(Equal(decodeHex(row[6+i])), "base %v", i)
+			}
+		})
+	})
+
+	Context("when creating incoming viewing keys", func() {
+		It("should return an error for bad keys", func() {
+			_, err := sapling.NewIncomingViewingKey(make([]byte, 31))
+			Expect(err).To(HaveOccurred())
+			b := make([]byte, 32)
+			b[31] = 0x08
+			_, err = sapling.NewIncomingViewingKey(b)
+			Expect(err).To(HaveOccurred())
+			b[31] = 0x07
+			_, err = sapling.NewIncomingViewingKey(b)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when decrypting outputs", func() {
+		It("should decrypt the test vectors", func() {
+			vectors := loadNoteEncryptionVectors()
+			Expect(vectors).To(HaveLen(10))
+			for i, vector := range vectors {
+				note, ok := vector.incomingViewingKey().TryDecrypt(vector.output(), params, preCanopy)
+				Expect(ok).To(BeTrue(), "vector %v", i)
+				Expect(note.Diversifier[:]).To(Equal(vector.diversifier))
+				Expect(note.PkD[:]).To(Equal(vector.pkd))
+				Expect(note.Value).To(Equal(vector.value))
+				Expect(note.Rcm[:]).To(Equal(vector.rcm))
+				Expect(note.Memo).ToNot(BeNil())
+				Expect(note.Memo[:]).To(Equal(vector.memo))
+				Expect(note.Memo.Format()).To(Equal(zcash.MemoEmpty))
+			}
+		})
+
+		It("should decrypt compact outputs without the memo", func() {
+			for i, vector := range loadNoteEncryptionVectors() {
+				output := vector.output()
+				output.EncCiphertext = output.EncCiphertext[:sapling.CompactCiphertextSize]
+				note, ok := vector.incomingViewingKey().TryDecrypt(output, params, preCanopy)
+				Expect(ok).To(BeTrue(), "vector %v", i)
+				Expect(note.Diversifier[:]).To(Equal(vector.diversifier))
+				Expect(note.Value).To(Equal(vector.value))
+				Expect(note.Rcm[:]).To(Equal(vector.rcm))
+				Expect(note.Memo).To(BeNil())
+			}
+		})
+
+		It("should not decrypt outputs sent to other keys", func() {
+			vectors := loadNoteEncryptionVectors()
+			for i, vector := range vectors {
+				other := vectors[(i+1)%len(vectors)].incomingViewingKey()
+				_, ok := other.TryDecrypt(vector.output(), params, preCanopy)
+				Expect(ok).To(BeFalse(), "vector %v", i)
+
+				output := vector.output()
+				output.EncCiphertext = output.EncCiphertext[:sapling.CompactCiphertextSize]
+				_, ok = other.TryDecrypt(output, params, preCanopy)
+				Expect(ok).To(BeFalse(), "vector %v", i)
+			}
+		})
+
+		It("should not decrypt tampered outputs", func() {
+			vector := loadNoteEncryptionVectors()[0]
+			ivk := vector.incomingViewingKey()
+
+			output := vector.output()
+			output.EncCiphertext = append([]byte(nil), output.EncCiphertext...)
+			output.EncCiphertext[len(output.EncCiphertext)-1] ^= 1
+			_, ok := ivk.TryDecrypt(output, params, preCanopy)
+			Expect(ok).To(BeFalse())
+
+			// Compact outputs are not authenticated by a tag, but changing the
+			// value changes the note commitment.
+			output = vector.output()
+			output.EncCiphertext = append([]byte(nil), output.EncCiphertext[:sapling.CompactCiphertextSize]...)
+			output.EncCiphertext[12] ^= 1
+			_, ok = ivk.TryDecrypt(output, params, preCanopy)
+			Expect(ok).To(BeFalse())
+
+			output = vector.output()
+			output.CMU[0] ^= 1
+			_, ok = ivk.TryDecrypt(output, params, preCanopy)
+			Expect(ok).To(BeFalse())
+
+			output = vector.output()
+			output.EncCiphertext = output.EncCiphertext[:100]
+			_, ok = ivk.TryDecrypt(output, params, preCanopy)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should decrypt ZIP-212 notes", func() {
+			vector := loadNoteEncryptionVectors()[1]
+			ivk := vector.incomingViewingKey()
+			var diversifier [11]byte
+			copy(diversifier[:], vector.diversifier)
+			var rseed [32]byte
+			for i := range rseed {
+				rseed[i] = byte(i)
+			}
+			memo, err := zcash.NewTextMemo("ZIP-212")
+			Expect(err).ToNot(HaveOccurred())
+
+			output := sapling.EncryptNote(ivk, diversifier, 12345, rseed, memo)
+			note, ok := ivk.TryDecrypt(output, params, postGrace)
+			Expect(ok).To(BeTrue())
+			Expect(note.Diversifier).To(Equal(diversifier))
+			Expect(note.PkD[:]).To(Equal(vector.pkd))
+			Expect(note.Value).To(Equal(uint64(12345)))
+			Expect(note.Rcm).To(Equal(sapling.Rcm(rseed)))
+			Expect(*note.Memo).To(Equal(memo))
+
+			output.EncCiphertext = output.EncCiphertext[:sapling.CompactCiphertextSize]
+			note, ok = ivk.TryDecrypt(output, params, postGrace)
+			Expect(ok).To(BeTrue())
+			Expect(note.Value).To(Equal(uint64(12345)))
+
+			// The ephemeral key must be derived from the rseed.
+			output.EphemeralKey = vector.output().EphemeralKey
+			_, ok = ivk.TryDecrypt(output, params, postGrace)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should only decrypt notes with the lead bytes of the height", func() {
+			vector := loadNoteEncryptionVectors()[1]
+			ivk := vector.incomingViewingKey()
+			var diversifier [11]byte
+			copy(diversifier[:], vector.diversifier)
+			output := sapling.EncryptNote(ivk, diversifier, 12345, [32]byte{1}, zcash.NoMemo())
+
+			for _, test := range []struct {
+				params            *zcash.Params
+				height            uint32
+				preZIP212, zip212 bool
+			}{
+				{params, preCanopy, true, false},
+				{params, canopy, true, true},
+				{params, postGrace - 1, true, true},
+				{params, postGrace, false, true},
+				{&zcash.TestNet3Params, 1028500 + 32256, false, true},
+				{&zcash.TestNet3Params, 1028500 - 1, true, false},
+				// Networks without Canopy only allow pre-ZIP-212 notes.
+				{&zcash.Params{}, postGrace, true, false},
+			} {
+				_, ok := ivk.TryDecrypt(vector.output(), test.params, test.height)
+				Expect(ok).To(Equal(test.preZIP212), "height %v", test.height)
+				_, ok = ivk.TryDecrypt(output, test.params, test.height)
+				Expect(ok).To(Equal(test.zip212), "height %v", test.height)
+			}
+		})
+	})
+
+	Context("when parsing RPC outputs", func() {
+		It("should reverse the cmu and ephemeral key", func() {
+			vector := loadNoteEncryptionVectors()[0]
+			reversed := func(b []byte) string {
+				r := make([]byte, len(b))
+				for i := range b {
+					r[len(b)-1-i] = b[i]
+				}
+				return hex.EncodeToString(r)
+			}
+			output, err := sapling.ParseRPCOutput(rpcclient.ShieldedOutput{
+				CMU:           reversed(vector.cmu),
+				EphemeralKey:  reversed(vector.epk),
+				EncCiphertext: hex.EncodeToString(vector.encCiphertext),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output).To(Equal(vector.output()))
+			_, ok := vector.incomingViewingKey().TryDecrypt(output, params, preCanopy)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should return an error for bad fields", func() {
+			vector := loadNoteEncryptionVectors()[0]
+			for _, value := range []string{"", "zz", "0011"} {
+				for i := 0; i < 3; i++ {
+					output := rpcclient.ShieldedOutput{
+						CMU:           hex.EncodeToString(vector.cmu),
+						EphemeralKey:  hex.EncodeToString(vector.epk),
+						EncCiphertext: hex.EncodeToString(vector.encCiphertext),
+					}
+					*[]*string{&output.CMU, &output.EphemeralKey, &output.EncCiphertext}[i] = value
+					_, err := sapling.ParseRPCOutput(output)
+					Expect(err).To(HaveOccurred(), "field %v: %q", i, value)
+				}
+			}
+		})
+	})
+})
@@ -0,0 +1,597 @@
+package zcash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	// maxScriptElementSize is the maximum number of bytes that can be pushed
+	// onto the stack.
+	maxScriptElementSize = 520
+	// maxScriptNumSize is the maximum number of bytes in a script number,
+	// except for OP_CHECKLOCKTIMEVERIFY which allows five.
+	maxScriptNumSize = 4
+)
+
+var (
+	// errScriptFalse is returned when a script executes successfully, but
+	// leaves a false value on the top of the stack.
+	errScriptFalse = errors.New("script evaluated to false")
+
+	// halfOrder is half of the order of the secp256k1 curve. Signatures with
+	// an S value greater than this are non-standard.
+	halfOrder = new(big.Int).Rsh(btcec.S256().N, 1)
+)
+
+// A scriptOp is a parsed opcode, and any data that it pushes.
+type scriptOp struct {
+	opcode byte
+	data   []byte
+	// end is the offset in the script immediately after the opcode, and is
+	// used to compute the script code after an OP_CODESEPARATOR.
+	end int
+}
+
+// parseScript splits a script into opcodes, and the data that they push.
+func parseScript(script []byte) ([]scriptOp, error) {
+	ops := []scriptOp{}
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+
+		n := 0
+		switch {
+		case opcode >= txscript.OP_DATA_1 && opcode <= txscript.OP_DATA_75:
+			n = int(opcode)
+		case opcode == txscript.OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, fmt.Errorf("malformed push at offset %v", i-1)
+			}
+			n = int(script[i])
+			i++
+		case opcode == txscript.OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, fmt.Errorf("malformed push at offset %v", i-1)
+			}
+			n = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case opcode == txscript.OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, fmt.Errorf("malformed push at offset %v", i-1)
+			}
+			n = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		}
+		if n < 0 || n > len(script)-i {
+			return nil, fmt.Errorf("malformed push at offset %v: expected %v bytes", i-1, n)
+		}
+
+		var data []byte
+		if opcode <= txscript.OP_PUSHDATA4 {
+			data = script[i : i+n]
+		}
+		i += n
+		ops = append(ops, scriptOp{opcode: opcode, data: data, end: i})
+	}
+	return ops, nil
+}
+
+// isPushOnly returns true if the script only contains opcodes that push data
+// onto the stack.
+func isPushOnly(ops []scriptOp) bool {
+	for _, op := range ops {
+		if op.opcode > txscript.OP_16 {
+			return false
+		}
+	}
+	return true
+}
+
+// A scriptEngine executes the transparent scripts of a Zcash transaction. It
+// supports the subset of opcodes needed by standard scripts (P2PK, P2PKH, P2SH,
+// multisig, and hash/time locked contracts), and uses the Zcash sighash in place
+// of the Bitcoin sighash when checking signatures.
+type scriptEngine struct {
+	tx     *Tx
+	idx    int
+	amount int64
+
+	stack [][]byte
+}
+
+// verifyInput executes the signature script of the given input against the
+// pubkey script of the output that it spends.
+func (tx *Tx) verifyInput(idx int) error {
+	if idx >= len(tx.inputs) || idx >= len(tx.msgTx.TxIn) {
+		return fmt.Errorf("bad input %v: out of range", idx)
+	}
+	amount, err := NewAmountFromU256(tx.inputs[idx].Value)
+	if err != nil {
+		return err
+	}
+	engine := scriptEngine{tx: tx, idx: idx, amount: int64(amount)}
+
+	sigScript, err := parseScript(tx.msgTx.TxIn[idx].SignatureScript)
+	if err != nil {
+		return fmt.Errorf("parsing signature script: %v", err)
+	}
+	if !isPushOnly(sigScript) {
+		return fmt.Errorf("signature script is not push only")
+	}
+	pubKeyScript, err := parseScript(tx.inputs[idx].PubKeyScript)
+	if err != nil {
+		return fmt.Errorf("parsing pubkey script: %v", err)
+	}
+
+	if err := engine.execute(tx.msgTx.TxIn[idx].SignatureScript, sigScript); err != nil {
+		return fmt.Errorf("executing signature script: %v", err)
+	}
+	stack := make([][]byte, len(engine.stack))
+	copy(stack, engine.stack)
+	if err := engine.execute(tx.inputs[idx].PubKeyScript, pubKeyScript); err != nil {
+		return fmt.Errorf("executing pubkey script: %v", err)
+	}
+	if err := engine.checkTop(); err != nil {
+		return fmt.Errorf("executing pubkey script: %v", err)
+	}
+
+	// Pay-to-script-hash outputs also require the redeem script, pushed last
+	// by the signature script, to execute successfully.
+	if txscript.IsPayToScriptHash(tx.inputs[idx].PubKeyScript) {
+		if len(stack) == 0 {
+			return fmt.Errorf("executing redeem script: missing redeem script")
+		}
+		redeemScript := stack[len(stack)-1]
+		engine.stack = stack[:len(stack)-1]
+		ops, err := parseScript(redeemScript)
+		if err != nil {
+			return fmt.Errorf("parsing redeem script: %v", err)
+		}
+		if err := engine.execute(redeemScript, ops); err != nil {
+			return fmt.Errorf("executing redeem script: %v", err)
+		}
+		if err := engine.checkTop(); err != nil {
+			return fmt.Errorf("executing redeem script: %v", err)
+		}
+	}
+	return nil
+}
+
+func (engine *scriptEngine) checkTop() error {
+	if len(engine.stack) == 0 || !asBool(engine.stack[len(engine.stack)-1]) {
+		return errScriptFalse
+	}
+	return nil
+}
+
+func (engine *scriptEngine) push(data []byte) {
+	engine.stack = append(engine.stack, data)
+}
+
+func (engine *scriptEngine) pop() ([]byte, error) {
+	if len(engine.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	data := engine.stack[len(engine.stack)-1]
+	engine.stack = engine.stack[:len(engine.stack)-1]
+	return data, nil
+}
+
+func (engine *scriptEngine) peek() ([]byte, error) {
+	if len(engine.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	return engine.stack[len(engine.stack)-1], nil
+}
+
+func (engine *scriptEngine) popInt(maxSize int) (int64, error) {
+	data, err := engine.pop()
+	if err != nil {
+		return 0, err
+	}
+	return decodeScriptNum(data, maxSize)
+}
+
+func (engine *scriptEngine) popBool() (bool, error) {
+	data, err := engine.pop()
+	if err != nil {
+		return false, err
+	}
+	return asBool(data), nil
+}
+
+// execute the parsed opcodes of a script against the current stack.
+func (engine *scriptEngine) execute(script []byte, ops []scriptOp) error {
+	// The condition stack tracks nested OP_IF branches. Opcodes are only
+	// executed when every branch on the condition stack is true.
+	conds := []bool{}
+	executing := func() bool {
+		for _, cond := range conds {
+			if !cond {
+				return false
+			}
+		}
+		return true
+	}
+	codeSeparator := 0
+
+	for _, op := range ops {
+		if len(op.data) > maxScriptElementSize {
+			return fmt.Errorf("push of %v bytes exceeds max element size", len(op.data))
+		}
+
+		exec := executing()
+		switch op.opcode {
+		case txscript.OP_IF, txscript.OP_NOTIF:
+			cond := false
+			if exec {
+				var err error
+				if cond, err = engine.popBool(); err != nil {
+					return err
+				}
+				if op.opcode == txscript.OP_NOTIF {
+					cond = !cond
+				}
+			}
+			conds = append(conds, cond)
+			continue
+		case txscript.OP_ELSE:
+			if len(conds) == 0 {
+				return fmt.Errorf("OP_ELSE without OP_IF")
+			}
+			conds[len(conds)-1] = !conds[len(conds)-1]
+			continue
+		case txscript.OP_ENDIF:
+			if len(conds) == 0 {
+				return fmt.Errorf("OP_ENDIF without OP_IF")
+			}
+			conds = conds[:len(conds)-1]
+			continue
+		}
+		if !exec {
+			continue
+		}
+
+		switch {
+		case op.opcode <= txscript.OP_PUSHDATA4:
+			engine.push(op.data)
+			continue
+		case op.opcode == txscript.OP_1NEGATE:
+			engine.push(encodeScriptNum(-1))
+			continue
+		case op.opcode >= txscript.OP_1 && op.opcode <= txscript.OP_16:
+			engine.push(encodeScriptNum(int64(op.opcode - (txscript.OP_1 - 1))))
+			continue
+		}
+
+		switch op.opcode {
+		case txscript.OP_NOP, txscript.OP_NOP1, txscript.OP_NOP3, txscript.OP_NOP4, txscript.OP_NOP5,
+			txscript.OP_NOP6, txscript.OP_NOP7, txscript.OP_NOP8, txscript.OP_NOP9, txscript.OP_NOP10:
+		case txscript.OP_VERIFY:
+			ok, err := engine.popBool()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("OP_VERIFY failed")
+			}
+		case txscript.OP_RETURN:
+			return fmt.Errorf("OP_RETURN executed")
+		case txscript.OP_DROP:
+			if _, err := engine.pop(); err != nil {
+				return err
+			}
+		case txscript.OP_2DROP:
+			if _, err := engine.pop(); err != nil {
+				return err
+			}
+			if _, err := engine.pop(); err != nil {
+				return err
+			}
+		case txscript.OP_DUP:
+			data, err := engine.peek()
+			if err != nil {
+				return err
+			}
+			engine.push(data)
+		case txscript.OP_SWAP:
+			a, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			b, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			engine.push(a)
+			engine.push(b)
+		case txscript.OP_SIZE:
+			data, err := engine.peek()
+			if err != nil {
+				return err
+			}
+			engine.push(encodeScriptNum(int64(len(data))))
+		case txscript.OP_EQUAL, txscript.OP_EQUALVERIFY:
+			a, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			b, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			equal := bytes.Equal(a, b)
+			if op.opcode == txscript.OP_EQUALVERIFY {
+				if !equal {
+					return fmt.Errorf("OP_EQUALVERIFY failed")
+				}
+				continue
+			}
+			engine.push(fromBool(equal))
+		case txscript.OP_RIPEMD160, txscript.OP_SHA256, txscript.OP_HASH160, txscript.OP_HASH256:
+			data, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			engine.push(hashOp(op.opcode, data))
+		case txscript.OP_CODESEPARATOR:
+			codeSeparator = op.end
+		case txscript.OP_CHECKSIG, txscript.OP_CHECKSIGVERIFY:
+			pubKey, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			sig, err := engine.pop()
+			if err != nil {
+				return err
+			}
+			ok, err := engine.checkSig(sig, pubKey, script[codeSeparator:])
+			if err != nil {
+				return err
+			}
+			if op.opcode == txscript.OP_CHECKSIGVERIFY {
+				if !ok {
+					return fmt.Errorf("OP_CHECKSIGVERIFY failed")
+				}
+				continue
+			}
+			engine.push(fromBool(ok))
+		case txscript.OP_CHECKMULTISIG, txscript.OP_CHECKMULTISIGVERIFY:
+			ok, err := engine.checkMultiSig(script[codeSeparator:])
+			if err != nil {
+				return err
+			}
+			if op.opcode == txscript.OP_CHECKMULTISIGVERIFY {
+				if !ok {
+					return fmt.Errorf("OP_CHECKMULTISIGVERIFY failed")
+				}
+				continue
+			}
+			engine.push(fromBool(ok))
+		case txscript.OP_CHECKLOCKTIMEVERIFY:
+			if err := engine.checkLockTime(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported opcode 0x%02x", op.opcode)
+		}
+	}
+	if len(conds) != 0 {
+		return fmt.Errorf("unbalanced conditional")
+	}
+	return nil
+}
+
+// checkSig returns true if the signature is a valid signature, by the public
+// key, of the sighash of the transaction. An error is returned if the
+// signature or the public key are not strictly encoded.
+func (engine *scriptEngine) checkSig(sig, pubKey, scriptCode []byte) (bool, error) {
+	if len(sig) == 0 {
+		return false, nil
+	}
+	hashType := txscript.SigHashType(sig[len(sig)-1])
+	switch hashType &^ txscript.SigHashAnyOneCanPay {
+	case txscript.SigHashAll, txscript.SigHashNone, txscript.SigHashSingle:
+	default:
+		return false, fmt.Errorf("invalid sighash type 0x%02x", byte(hashType))
+	}
+	signature, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+	if err != nil {
+		return false, fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	if signature.S.Cmp(halfOrder) > 0 {
+		return false, fmt.Errorf("invalid signature: S is not low")
+	}
+	key, err := btcec.ParsePubKey(pubKey, btcec.S256())
+	if err != nil {
+		return false, fmt.Errorf("invalid pubkey encoding: %v", err)
+	}
+	hash, err := calculateSighash(engine.tx.params, scriptCode, hashType, engine.tx.msgTx, engine.idx, engine.amount, engine.tx.expiryHeight, shieldedDigests{})
+	if err != nil {
+		return false, err
+	}
+	return signature.Verify(hash, key), nil
+}
+
+// checkMultiSig pops the public keys, signatures, and the extra dummy element
+// used by OP_CHECKMULTISIG off the stack, and returns true if every signature
+// is valid for one of the public keys (in order).
+func (engine *scriptEngine) checkMultiSig(scriptCode []byte) (bool, error) {
+	numPubKeys, err := engine.popInt(maxScriptNumSize)
+	if err != nil {
+		return false, err
+	}
+	if numPubKeys < 0 || numPubKeys > txscript.MaxPubKeysPerMultiSig {
+		return false, fmt.Errorf("invalid number of pubkeys %v", numPubKeys)
+	}
+	pubKeys := make([][]byte, numPubKeys)
+	for i := range pubKeys {
+		if pubKeys[i], err = engine.pop(); err != nil {
+			return false, err
+		}
+	}
+	numSigs, err := engine.popInt(maxScriptNumSize)
+	if err != nil {
+		return false, err
+	}
+	if numSigs < 0 || numSigs > numPubKeys {
+		return false, fmt.Errorf("invalid number of signatures %v", numSigs)
+	}
+	sigs := make([][]byte, numSigs)
+	for i := range sigs {
+		if sigs[i], err = engine.pop(); err != nil {
+			return false, err
+		}
+	}
+	dummy, err := engine.pop()
+	if err != nil {
+		return false, err
+	}
+	if len(dummy) != 0 {
+		return false, fmt.Errorf("multisig dummy argument is not empty")
+	}
+
+	// Signatures and public keys were pushed in order, so they were popped in
+	// reverse order. Iterate in reverse to restore the original order.
+	sigIdx, pubKeyIdx := len(sigs)-1, len(pubKeys)-1
+	for sigIdx >= 0 {
+		if pubKeyIdx < sigIdx {
+			return false, nil
+		}
+		ok, err := engine.checkSig(sigs[sigIdx], pubKeys[pubKeyIdx], scriptCode)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			sigIdx--
+		}
+		pubKeyIdx--
+	}
+	return true, nil
+}
+
+// checkLockTime implements OP_CHECKLOCKTIMEVERIFY as defined by BIP-65.
+func (engine *scriptEngine) checkLockTime() error {
+	data, err := engine.peek()
+	if err != nil {
+		return err
+	}
+	lockTime, err := decodeScriptNum(data, 5)
+	if err != nil {
+		return err
+	}
+	if lockTime < 0 {
+		return fmt.Errorf("negative lock time %v", lockTime)
+	}
+	txLockTime := int64(engine.tx.msgTx.LockTime)
+	if (txLockTime < txscript.LockTimeThreshold) != (lockTime < txscript.LockTimeThreshold) {
+		return fmt.Errorf("mismatched lock time types: tx lock time %v, script lock time %v", txLockTime, lockTime)
+	}
+	if lockTime > txLockTime {
+		return fmt.Errorf("lock time %v has not been reached: tx lock time %v", lockTime, txLockTime)
+	}
+	if engine.tx.msgTx.TxIn[engine.idx].Sequence == wire.MaxTxInSequenceNum {
+		return fmt.Errorf("input is finalized: lock time is disabled")
+	}
+	return nil
+}
+
+func hashOp(opcode byte, data []byte) []byte {
+	switch opcode {
+	case txscript.OP_RIPEMD160:
+		h := ripemd160.New()
+		h.Write(data)
+		return h.Sum(nil)
+	case txscript.OP_SHA256:
+		h := sha256.Sum256(data)
+		return h[:]
+	case txscript.OP_HASH160:
+		return btcutil.Hash160(data)
+	default:
+		h := sha256.Sum256(data)
+		h = sha256.Sum256(h[:])
+		return h[:]
+	}
+}
+
+func asBool(data []byte) bool {
+	for i := range data {
+		if data[i] != 0 {
+			// Negative zero is also false.
+			if i == len(data)-1 && data[i] == 0x80 {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func fromBool(b bool) []byte {
+	if b {
+		return []byte{1}
+	}
+	return nil
+}
+
+// encodeScriptNum encodes an integer as a minimally encoded, little endian,
+// sign-magnitude script number.
+func encodeScriptNum(n int64) []byte {
+	if n == 0 {
+		return nil
+	}
+	neg := n < 0
+	abs := uint64(n)
+	if neg {
+		abs = uint64(-n)
+	}
+	result := []byte{}
+	for abs > 0 {
+		result = append(result, byte(abs&0xff))
+		abs >>= 8
+	}
+	if result[len(result)-1]&0x80 != 0 {
+		extra := byte(0x00)
+		if neg {
+			extra = 0x80
+		}
+		result = append(result, extra)
+	} else if neg {
+		result[len(result)-1] |= 0x80
+	}
+	return result
+}
+
+// decodeScriptNum decodes a minimally encoded, little endian, sign-magnitude
+// script number of at most maxSize bytes.
+func decodeScriptNum(data []byte, maxSize int) (int64, error) {
+	if len(data) > maxSize {
+		return 0, fmt.Errorf("script number of %v bytes exceeds max size %v", len(data), maxSize)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	if data[len(data)-1]&0x7f == 0 && (len(data) == 1 || data[len(data)-2]&0x80 == 0) {
+		return 0, fmt.Errorf("script number is not minimally encoded")
+	}
+	n := int64(0)
+	for i, b := range data {
+		n |= int64(b) << uint(8*i)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		n &= ^(int64(0x80) << uint(8*(len(data)-1)))
+		return -n, nil
+	}
+	return n, nil
+}
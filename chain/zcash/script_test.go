@@ -0,0 +1,192 @@
+package zcash_test
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Script verification", func() {
+	params := &zcash.RegressionNetParams
+	builder := zcash.NewTxBuilder(params, 1000000)
+
+	newKey := func() *btcec.PrivateKey {
+		return (*btcec.PrivateKey)(id.NewPrivKey())
+	}
+	p2pkhScript := func(key *btcec.PrivateKey) []byte {
+		addr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), params)
+		Expect(err).ToNot(HaveOccurred())
+		script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+		Expect(err).ToNot(HaveOccurred())
+		return script
+	}
+	p2shScript := func(redeemScript []byte) []byte {
+		addr, err := zcash.NewAddressScriptHash(redeemScript, params)
+		Expect(err).ToNot(HaveOccurred())
+		script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+		Expect(err).ToNot(HaveOccurred())
+		return script
+	}
+	sign := func(key *btcec.PrivateKey, sighash pack.Bytes32) []byte {
+		signature, err := key.Sign(sighash[:])
+		Expect(err).ToNot(HaveOccurred())
+		return append(signature.Serialize(), byte(txscript.SigHashAll))
+	}
+	buildTx := func(inputs []utxo.Input) *zcash.Tx {
+		recipient := newKey()
+		addr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(recipient.PubKey().SerializeCompressed()), params)
+		Expect(err).ToNot(HaveOccurred())
+		tx, err := builder.BuildTx(inputs, []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}})
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*zcash.Tx)
+	}
+	input := func(index uint32, pubKeyScript, sigScript []byte) utxo.Input {
+		return utxo.Input{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(index)},
+				Value:        pack.NewU256FromUint64(50000),
+				PubKeyScript: pubKeyScript,
+			},
+			SigScript: sigScript,
+		}
+	}
+
+	Context("when verifying P2PKH inputs", func() {
+		It("should accept valid signatures and reject invalid ones", func() {
+			key, otherKey := newKey(), newKey()
+			tx := buildTx([]utxo.Input{input(0, p2pkhScript(key), nil), input(1, p2pkhScript(key), nil)})
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			signatures := make([]pack.Bytes65, len(sighashes))
+			for i := range sighashes {
+				signer := key
+				if i == 1 {
+					signer = otherKey
+				}
+				hash := id.Hash(sighashes[i])
+				signature, err := (*id.PrivKey)(signer).Sign(&hash)
+				Expect(err).ToNot(HaveOccurred())
+				signatures[i] = pack.NewBytes65(signature)
+			}
+			Expect(tx.Sign(signatures, pack.NewBytes(key.PubKey().SerializeCompressed()))).To(Succeed())
+
+			results, err := tx.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect(results[0]).ToNot(HaveOccurred())
+			Expect(results[1]).To(HaveOccurred())
+		})
+
+		It("should reject unsigned inputs", func() {
+			key := newKey()
+			tx := buildTx([]utxo.Input{input(0, p2pkhScript(key), nil), input(1, p2pkhScript(key), nil)})
+			results, err := tx.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(results[0]).To(HaveOccurred())
+			Expect(results[1]).To(HaveOccurred())
+		})
+
+		It("should reject signatures over the wrong amount", func() {
+			key := newKey()
+			tx := buildTx([]utxo.Input{input(0, p2pkhScript(key), nil), input(1, p2pkhScript(key), nil)})
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			for i := range sighashes {
+				sigScript, err := txscript.NewScriptBuilder().AddData(sign(key, sighashes[i])).AddData(key.PubKey().SerializeCompressed()).Script()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.SetSignatureScript(i, sigScript)).To(Succeed())
+			}
+			_, err = tx.Verify()
+			Expect(err).ToNot(HaveOccurred())
+
+			// The sighash commits to the value of the spent output, so the
+			// signature is no longer valid if the value is different.
+			inputs, err := tx.Inputs()
+			Expect(err).ToNot(HaveOccurred())
+			inputs[1].Value = pack.NewU256FromUint64(50001)
+			results, err := tx.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(results[0]).ToNot(HaveOccurred())
+			Expect(results[1]).To(MatchError(ContainSubstring("false")))
+		})
+	})
+
+	Context("when verifying P2SH multisig inputs", func() {
+		It("should accept valid signatures in order", func() {
+			keys := []*btcec.PrivateKey{newKey(), newKey(), newKey()}
+			builder := txscript.NewScriptBuilder().AddOp(txscript.OP_2)
+			for _, key := range keys {
+				builder.AddData(key.PubKey().SerializeCompressed())
+			}
+			redeemScript, err := builder.AddOp(txscript.OP_3).AddOp(txscript.OP_CHECKMULTISIG).Script()
+			Expect(err).ToNot(HaveOccurred())
+
+			tx := buildTx([]utxo.Input{input(0, p2shScript(redeemScript), redeemScript), input(1, p2shScript(redeemScript), redeemScript)})
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+
+			// Valid signatures from the first and third keys.
+			sigScript, err := txscript.NewScriptBuilder().
+				AddOp(txscript.OP_0).
+				AddData(sign(keys[0], sighashes[0])).
+				AddData(sign(keys[2], sighashes[0])).
+				AddData(redeemScript).
+				Script()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+
+			// Signatures in the wrong order.
+			sigScript, err = txscript.NewScriptBuilder().
+				AddOp(txscript.OP_0).
+				AddData(sign(keys[2], sighashes[1])).
+				AddData(sign(keys[0], sighashes[1])).
+				AddData(redeemScript).
+				Script()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.SetSignatureScript(1, sigScript)).To(Succeed())
+
+			results, err := tx.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(results[0]).ToNot(HaveOccurred())
+			Expect(results[1]).To(HaveOccurred())
+		})
+	})
+
+	Context("when verifying CLTV inputs", func() {
+		It("should reject spends before the lock time", func() {
+			key := newKey()
+			redeemScript, err := txscript.NewScriptBuilder().
+				AddInt64(100).
+				AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+				AddOp(txscript.OP_DROP).
+				AddData(key.PubKey().SerializeCompressed()).
+				AddOp(txscript.OP_CHECKSIG).
+				Script()
+			Expect(err).ToNot(HaveOccurred())
+
+			tx := buildTx([]utxo.Input{input(0, p2shScript(redeemScript), redeemScript), input(1, p2pkhScript(key), nil)})
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			sigScript, err := txscript.NewScriptBuilder().AddData(sign(key, sighashes[0])).AddData(redeemScript).Script()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.SetSignatureScript(0, sigScript)).To(Succeed())
+			sigScript, err = txscript.NewScriptBuilder().AddData(sign(key, sighashes[1])).AddData(key.PubKey().SerializeCompressed()).Script()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.SetSignatureScript(1, sigScript)).To(Succeed())
+
+			results, err := tx.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(results[0]).To(MatchError(ContainSubstring("lock time")))
+			Expect(results[1]).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -43,19 +43,74 @@ var NewClient = bitcoin.NewClient
 type TxBuilder struct {
 	params       *Params
 	expiryHeight uint32
+	feePolicy    FeePolicy
+	lockTime     uint32
+	sequence     uint32
+	sequences    []uint32
 }
 
 // NewTxBuilder returns an implementation the transaction builder interface from
 // the Bitcoin Compat API, and exposes the functionality to build simple Zcash
 // transactions.
 func NewTxBuilder(params *Params, expiryHeight uint32) utxo.TxBuilder {
-	return TxBuilder{params: params, expiryHeight: expiryHeight}
+	return NewConfigurableTxBuilder(params, expiryHeight)
+}
+
+// NewConfigurableTxBuilder returns the same transaction builder as
+// NewTxBuilder, as a TxBuilder whose fee policy, lock time and sequences can
+// be changed with its With methods.
+func NewConfigurableTxBuilder(params *Params, expiryHeight uint32) TxBuilder {
+	return TxBuilder{params: params, expiryHeight: expiryHeight, feePolicy: DefaultFeePolicy(), sequence: SequenceFinal}
+}
+
+// WithFeePolicy returns a copy of the transaction builder that checks built
+// transactions against the given fee policy.
+func (txBuilder TxBuilder) WithFeePolicy(feePolicy FeePolicy) TxBuilder {
+	txBuilder.feePolicy = feePolicy
+	return txBuilder
+}
+
+// WithExpiryHeight returns a copy of the transaction builder that sets the
+// expiry height (nExpiryHeight) of built transactions. Transactions that have
+// not been mined by the expiry height are invalid. An expiry height of zero
+// disables expiry.
+func (txBuilder TxBuilder) WithExpiryHeight(expiryHeight uint32) TxBuilder {
+	txBuilder.expiryHeight = expiryHeight
+	return txBuilder
+}
+
+// WithLockTime returns a copy of the transaction builder that sets the lock
+// time (nLockTime) of built transactions. The lock time is either a block
+// height or a UNIX timestamp (see LockTimeFromHeight and LockTimeFromTime). It
+// is only enforced if at least one input has a non-final sequence number (see
+// WithSequence), and it must be set when refunding an HTLC.
+func (txBuilder TxBuilder) WithLockTime(lockTime uint32) TxBuilder {
+	txBuilder.lockTime = lockTime
+	return txBuilder
+}
+
+// WithSequence returns a copy of the transaction builder that sets the
+// sequence number (nSequence) of all inputs of built transactions. By default,
+// inputs have the final sequence number (see SequenceFinal).
+func (txBuilder TxBuilder) WithSequence(sequence uint32) TxBuilder {
+	txBuilder.sequence = sequence
+	return txBuilder
+}
+
+// WithInputSequences returns a copy of the transaction builder that sets the
+// sequence number (nSequence) of each input of built transactions
+// individually. The number of sequence numbers must match the number of inputs
+// passed to BuildTx. This overrides WithSequence.
+func (txBuilder TxBuilder) WithInputSequences(sequences []uint32) TxBuilder {
+	txBuilder.sequences = append([]uint32{}, sequences...)
+	return txBuilder
 }
 
 // BuildTx returns a simple Zcash transaction that consumes the funds from the
 // given outputs, and sends the to the given recipients. The difference in the
 // sum value of the inputs and the sum value of the recipients is paid as a fee
-// to the Zcash network.
+// to the Zcash network. This fee is checked against the fee policy of the
+// builder, and an error is returned if it is out of bounds.
 //
 // It is assumed that the required signature scripts require the SIGHASH_ALL
 // signatures and the serialized public key:
@@ -65,42 +120,69 @@ func NewTxBuilder(params *Params, expiryHeight uint32) utxo.TxBuilder {
 //  builder.AddData(serializedPubKey)
 //
 // Outputs produced for recipients will use P2PKH, or P2SH scripts as the pubkey
-// script, based on the format of the recipient address.
+// script, based on the format of the recipient address. Recipients can instead
+// specify a standard pubkey script, or data to be committed to by a null-data
+// output (see RecipientScript).
 func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipient) (utxo.Tx, error) {
 	msgTx := wire.NewMsgTx(Version)
 
-	// Address encoder-decoder
-	addrEncodeDecoder := NewAddressEncodeDecoder(txBuilder.params)
+	if txBuilder.expiryHeight > MaxExpiryHeight {
+		return nil, fmt.Errorf("bad expiry height: expected <= %v, got %v", MaxExpiryHeight, txBuilder.expiryHeight)
+	}
 
 	// Inputs
-	for _, input := range inputs {
+	if txBuilder.sequences != nil && len(txBuilder.sequences) != len(inputs) {
+		return nil, fmt.Errorf("expected %v sequences, got %v sequences", len(inputs), len(txBuilder.sequences))
+	}
+	for i, input := range inputs {
+		if _, err := NewAmountFromU256(input.Value); err != nil {
+			return nil, fmt.Errorf("bad input %v: %v", i, err)
+		}
 		hash := chainhash.Hash{}
 		copy(hash[:], input.Hash)
 		index := input.Output.Outpoint.Index.Uint32()
-		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&hash, index), nil, nil))
+		txIn := wire.NewTxIn(wire.NewOutPoint(&hash, index), nil, nil)
+		txIn.Sequence = txBuilder.sequence
+		if txBuilder.sequences != nil {
+			txIn.Sequence = txBuilder.sequences[i]
+		}
+		msgTx.AddTxIn(txIn)
 	}
+	msgTx.LockTime = txBuilder.lockTime
 
 	// Outputs
-	for _, recipient := range recipients {
-		addrBytes, err := addrEncodeDecoder.DecodeAddress(recipient.To)
+	total := Amount(0)
+	for i, recipient := range recipients {
+		script, err := RecipientScript(recipient, txBuilder.params)
 		if err != nil {
-			return &Tx{}, err
+			return nil, fmt.Errorf("bad recipient %v: %v", i, err)
 		}
-		addr, err := addressFromRawBytes(addrBytes, txBuilder.params)
+		value, err := NewAmountFromU256(recipient.Value)
 		if err != nil {
-			return &Tx{}, err
+			return nil, fmt.Errorf("bad recipient %v: %v", i, err)
 		}
-		script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
-		if err != nil {
-			return &Tx{}, err
+		if total, err = total.Add(value); err != nil {
+			return nil, fmt.Errorf("bad recipient %v: %v", i, err)
 		}
-		value := recipient.Value.Int().Int64()
-		if value < 0 {
-			return nil, fmt.Errorf("expected value >= 0, got value = %v", value)
+		if err := total.Validate(); err != nil {
+			return nil, fmt.Errorf("bad recipients: total value %v", err)
 		}
-		msgTx.AddTxOut(wire.NewTxOut(value, script))
+		msgTx.AddTxOut(wire.NewTxOut(int64(value), script))
+	}
+	if err := CheckNullDataOutputs(msgTx.TxOut); err != nil {
+		return nil, err
+	}
+	tx := &Tx{inputs: inputs, recipients: recipients, msgTx: msgTx, params: txBuilder.params, expiryHeight: txBuilder.expiryHeight, signed: false}
+	size, err := tx.EstimateSize()
+	if err != nil {
+		return nil, err
+	}
+	fee, err := txBuilder.feePolicy.Check(inputs, msgTx.TxOut, size)
+	if err != nil {
+		return nil, err
 	}
-	return &Tx{inputs: inputs, recipients: recipients, msgTx: msgTx, params: txBuilder.params, expiryHeight: txBuilder.expiryHeight, signed: false}, nil
+	tx.fee = fee
+	return tx, nil
 }
 
 // Tx represents a simple Zcash transaction that implements the Bitcoin Compat
@@ -112,6 +194,7 @@ type Tx struct {
 	msgTx        *wire.MsgTx
 	params       *Params
 	expiryHeight uint32
+	fee          pack.U256
 
 	signed bool
 }
@@ -126,6 +209,32 @@ func (tx *Tx) Hash() (pack.Bytes, error) {
 	return pack.NewBytes(txhash[:]), nil
 }
 
+// Fee returns the implicit fee of the transaction: the difference in the sum
+// value of the inputs and the sum value of the outputs.
+func (tx *Tx) Fee() pack.U256 {
+	return tx.fee
+}
+
+// EstimateSize returns the estimated size of the transaction (in bytes) once
+// all of its inputs have been signed. If the transaction has already been
+// signed, then its actual size is returned.
+func (tx *Tx) EstimateSize() (int, error) {
+	serial, err := tx.Serialize()
+	if err != nil {
+		return 0, err
+	}
+	size := len(serial)
+	if tx.signed {
+		return size, nil
+	}
+	for i, input := range tx.inputs {
+		sigScriptSize, _ := bitcoin.EstimateInputSize(input)
+		current := len(tx.msgTx.TxIn[i].SignatureScript)
+		size += sigScriptSize - current + wire.VarIntSerializeSize(uint64(sigScriptSize)) - wire.VarIntSerializeSize(uint64(current))
+	}
+	return size, nil
+}
+
 // Inputs returns the UTXO inputs in the underlying transaction.
 func (tx *Tx) Inputs() ([]utxo.Input, error) {
 	return tx.inputs, nil
@@ -167,9 +276,9 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 		var hash []byte
 		var err error
 		if sigScript == nil {
-			hash, err = calculateSighash(tx.params, pubKeyScript, txscript.SigHashAll, tx.msgTx, i, value, tx.expiryHeight)
+			hash, err = calculateSighash(tx.params, pubKeyScript, txscript.SigHashAll, tx.msgTx, i, value, tx.expiryHeight, shieldedDigests{})
 		} else {
-			hash, err = calculateSighash(tx.params, sigScript, txscript.SigHashAll, tx.msgTx, i, value, tx.expiryHeight)
+			hash, err = calculateSighash(tx.params, sigScript, txscript.SigHashAll, tx.msgTx, i, value, tx.expiryHeight, shieldedDigests{})
 		}
 		if err != nil {
 			return []pack.Bytes32{}, err
@@ -216,6 +325,41 @@ func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	return nil
 }
 
+// SetSignatureScript sets the signature script of an input directly. This can
+// be used to spend outputs that cannot be signed by Sign, such as multisig
+// outputs, using signatures over the sighashes returned by Sighashes.
+func (tx *Tx) SetSignatureScript(idx int, sigScript []byte) error {
+	if idx < 0 || idx >= len(tx.msgTx.TxIn) {
+		return fmt.Errorf("bad input %v: expected index < %v", idx, len(tx.msgTx.TxIn))
+	}
+	tx.msgTx.TxIn[idx].SignatureScript = sigScript
+	return nil
+}
+
+// Verify executes the signature script of each input against the pubkey
+// script of the output that it spends, using the Zcash sighash to check
+// signatures. It returns one result for each input, which is nil if the input
+// is valid, and an error if any of the inputs are invalid. This allows signing
+// bugs to be detected before the transaction is submitted.
+func (tx *Tx) Verify() ([]error, error) {
+	results := make([]error, len(tx.msgTx.TxIn))
+	invalid := 0
+	var first error
+	for i := range results {
+		results[i] = tx.verifyInput(i)
+		if results[i] != nil {
+			if invalid == 0 {
+				first = fmt.Errorf("bad input %v: %v", i, results[i])
+			}
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return results, fmt.Errorf("%v of %v inputs are invalid, %v", invalid, len(results), first)
+	}
+	return results, nil
+}
+
 // Serialize serializes the UTXO transaction to bytes.
 func (tx *Tx) Serialize() (pack.Bytes, error) {
 	w := new(bytes.Buffer)
@@ -326,6 +470,7 @@ func calculateSighash(
 	idx int,
 	amt int64,
 	expiryHeight uint32,
+	shielded shieldedDigests,
 ) ([]byte, error) {
 	sigHashes, err := txSighashes(tx)
 	if err != nil {
@@ -333,8 +478,9 @@ func calculateSighash(
 	}
 
 	// As a sanity check, ensure the passed input index for the transaction
-	// is valid.
-	if idx > len(tx.TxIn)-1 {
+	// is valid. The index is notAnInput for the sighash that is signed by the
+	// shielded components, which commits to no input.
+	if idx != notAnInput && (idx < 0 || idx > len(tx.TxIn)-1) {
 		return nil, fmt.Errorf("blake2bSignatureHash error: idx %d but %d txins", idx, len(tx.TxIn))
 	}
 
@@ -392,7 +538,7 @@ func calculateSighash(
 	// pre-image.
 	if hashType&sighashMask != txscript.SigHashSingle && hashType&sighashMask != txscript.SigHashNone {
 		sigHash.Write(sigHashes.HashOutputs[:])
-	} else if hashType&sighashMask == txscript.SigHashSingle && idx < len(tx.TxOut) {
+	} else if hashType&sighashMask == txscript.SigHashSingle && idx != notAnInput && idx < len(tx.TxOut) {
 		var (
 			b bytes.Buffer
 			h chainhash.Hash
@@ -411,16 +557,16 @@ func calculateSighash(
 	}
 
 	// << hashJoinSplits
-	sigHash.Write(zeroHash[:])
+	sigHash.Write(shielded.hashJoinSplits[:])
 
 	// << hashShieldedSpends
 	if tx.Version == versionSapling {
-		sigHash.Write(zeroHash[:])
+		sigHash.Write(shielded.hashShieldedSpends[:])
 	}
 
 	// << hashShieldedOutputs
 	if tx.Version == versionSapling {
-		sigHash.Write(zeroHash[:])
+		sigHash.Write(shielded.hashShieldedOutputs[:])
 	}
 
 	// << nLockTime
@@ -436,7 +582,7 @@ func calculateSighash(
 	// << valueBalance
 	if tx.Version == versionSapling {
 		var valueBalance [8]byte
-		binary.LittleEndian.PutUint64(valueBalance[:], 0)
+		binary.LittleEndian.PutUint64(valueBalance[:], uint64(shielded.valueBalance))
 		sigHash.Write(valueBalance[:])
 	}
 
@@ -445,7 +591,7 @@ func calculateSighash(
 	binary.LittleEndian.PutUint32(bHashType[:], uint32(hashType))
 	sigHash.Write(bHashType[:])
 
-	if idx != math.MaxUint32 {
+	if idx != notAnInput {
 		// << prevout
 		// Next, write the outpoint being spent.
 		sigHash.Write(tx.TxIn[idx].PreviousOutPoint.Hash[:])
@@ -496,15 +642,27 @@ func blake2b(data, key []byte) (h chainhash.Hash, err error) {
 	return h, err
 }
 
+// sighashKey returns the personalization of the sighash, which commits to the
+// consensus branch that is active at the given height (see branchID).
 func sighashKey(activationHeight uint32, network *Params) []byte {
+	return append([]byte(blake2BSighash), branchID(activationHeight, network)...)
+}
+
+// branchID returns the consensus branch ID, in little-endian byte order, that
+// is active at the given height. A height of zero (which is the expiry height
+// of transactions that do not expire) selects the latest consensus branch.
+func branchID(activationHeight uint32, network *Params) []byte {
 	var i int
 	upgradeParams := network.Upgrades
+	if activationHeight == 0 {
+		return upgradeParams[len(upgradeParams)-1].BranchID
+	}
 	for i = len(upgradeParams) - 1; i >= 0; i-- {
 		if activationHeight >= upgradeParams[i].ActivationHeight {
 			break
 		}
 	}
-	return append([]byte(blake2BSighash), upgradeParams[i].BranchID...)
+	return upgradeParams[i].BranchID
 }
 
 // txSighashes computes, and returns the cached sighashes of the given
@@ -646,7 +804,7 @@ func writeVarInt(w io.Writer, pver uint32, val uint64) error {
 	}
 
 	if val <= math.MaxUint16 {
-		err := binary.Write(w, binary.LittleEndian, 0xfd)
+		err := binary.Write(w, binary.LittleEndian, uint8(0xfd))
 		if err != nil {
 			return err
 		}
@@ -654,14 +812,14 @@ func writeVarInt(w io.Writer, pver uint32, val uint64) error {
 	}
 
 	if val <= math.MaxUint32 {
-		err := binary.Write(w, binary.LittleEndian, 0xfe)
+		err := binary.Write(w, binary.LittleEndian, uint8(0xfe))
 		if err != nil {
 			return err
 		}
 		return binary.Write(w, binary.LittleEndian, uint32(val))
 	}
 
-	if err := binary.Write(w, binary.LittleEndian, 0xff); err != nil {
+	if err := binary.Write(w, binary.LittleEndian, uint8(0xff)); err != nil {
 		return err
 	}
 	return binary.Write(w, binary.LittleEndian, val)
@@ -25,6 +25,18 @@ type Params struct {
 	P2SHPrefix  []byte
 	P2PKHPrefix []byte
 	Upgrades    []ParamsUpgrade
+
+	// SaplingHRP, UnifiedHRP, and TEXHRP are the human readable parts of
+	// Sapling payment addresses (as defined by ZIP-173), unified addresses (as
+	// defined by ZIP-316), and TEX addresses (as defined by ZIP-320).
+	SaplingHRP string
+	UnifiedHRP string
+	TEXHRP     string
+
+	// HDCoinType is the BIP44 coin type used when deriving hierarchical
+	// deterministic keys. It shadows the coin type of the embedded chaincfg
+	// params, which belongs to Bitcoin.
+	HDCoinType uint32
 }
 
 // ParamsUpgrade ...
@@ -42,6 +54,10 @@ var (
 
 		P2PKHPrefix: []byte{0x1C, 0xB8},
 		P2SHPrefix:  []byte{0x1C, 0xBD},
+		SaplingHRP:  "zs",
+		UnifiedHRP:  "u",
+		TEXHRP:      "tex",
+		HDCoinType:  133,
 		Upgrades: []ParamsUpgrade{
 			{0, []byte{0x00, 0x00, 0x00, 0x00}},
 			{347500, []byte{0x19, 0x1B, 0xA8, 0x5B}},
@@ -58,6 +74,10 @@ var (
 
 		P2PKHPrefix: []byte{0x1D, 0x25},
 		P2SHPrefix:  []byte{0x1C, 0xBA},
+		SaplingHRP:  "ztestsapling",
+		UnifiedHRP:  "utest",
+		TEXHRP:      "textest",
+		HDCoinType:  1,
 		Upgrades: []ParamsUpgrade{
 			{0, []byte{0x00, 0x00, 0x00, 0x00}},
 			{207500, []byte{0x19, 0x1B, 0xA8, 0x5B}},
@@ -74,6 +94,10 @@ var (
 
 		P2PKHPrefix: []byte{0x1D, 0x25},
 		P2SHPrefix:  []byte{0x1C, 0xBA},
+		SaplingHRP:  "zregtestsapling",
+		UnifiedHRP:  "uregtest",
+		TEXHRP:      "texregtest",
+		HDCoinType:  1,
 		Upgrades: []ParamsUpgrade{
 			{0, []byte{0x00, 0x00, 0x00, 0x00}},
 			{10, []byte{0x19, 0x1B, 0xA8, 0x5B}},
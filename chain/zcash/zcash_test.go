@@ -2,15 +2,16 @@ package zcash_test
 
 import (
 	"context"
-	"log"
-	"os"
 	"reflect"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcutil"
 	"github.com/pranav292gpt/zecutil/address"
 	"github.com/pranav292gpt/zecutil/api/utxo"
 	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/testutil"
 	"github.com/renproject/id"
 	"github.com/renproject/pack"
 
@@ -20,31 +21,34 @@ import (
 
 var _ = Describe("Zcash", func() {
 	Context("when submitting transactions", func() {
+		var server *testutil.Server
+
+		BeforeEach(func() {
+			server = testutil.NewServer(testutil.ZcashChain(&zcash.RegressionNetParams))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
 		Context("when sending ZEC to multiple addresses", func() {
 			It("should work", func() {
-				// Load private key, and assume that the associated address has
-				// funds to spend. You can do this by setting ZCASH_PK to
-				// the value specified in the `./multichaindeploy/.env` file.
-				pkEnv := os.Getenv("ZCASH_PK")
-				if pkEnv == "" {
-					panic("ZCASH_PK is undefined")
-				}
-				wif, err := btcutil.DecodeWIF(pkEnv)
+				// Generate a private key, and fund the associated address.
+				privKey := id.NewPrivKey()
+				pubKey := (*btcec.PrivateKey)(privKey).PubKey()
+				pkhAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), &zcash.RegressionNetParams)
 				Expect(err).ToNot(HaveOccurred())
-
-				// PKH
-				pkhAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(wif.PrivKey.PubKey().SerializeCompressed()), &zcash.RegressionNetParams)
+				pkhAddrUncompressed, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeUncompressed()), &zcash.RegressionNetParams)
 				Expect(err).ToNot(HaveOccurred())
-				pkhAddrUncompressed, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(wif.PrivKey.PubKey().SerializeUncompressed()), &zcash.RegressionNetParams)
+				funded, err := server.Fund(pkhAddr.EncodeAddress(), 100000000)
 				Expect(err).ToNot(HaveOccurred())
-				log.Printf("PKH                %v", pkhAddr.EncodeAddress())
-				log.Printf("PKH (uncompressed) %v", pkhAddrUncompressed.EncodeAddress())
+				server.Mine(1)
 
 				// Setup the client and load the unspent transaction outputs.
-				client := zcash.NewClient(zcash.DefaultClientOptions())
+				client := zcash.NewClient(server.ClientOptions())
 				outputs, err := client.UnspentOutputs(context.Background(), 0, 999999999, address.Address(pkhAddr.EncodeAddress()))
 				Expect(err).ToNot(HaveOccurred())
-				Expect(len(outputs)).To(BeNumerically(">", 0))
+				Expect(outputs).To(Equal([]utxo.Output{funded}))
 				output := outputs[0]
 
 				// Check that we can load the output and that it is equal.
@@ -53,6 +57,10 @@ var _ = Describe("Zcash", func() {
 				output2, _, err := client.Output(context.Background(), output.Outpoint)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(reflect.DeepEqual(output, output2)).To(BeTrue())
+				output2, confs, err := client.UnspentOutput(context.Background(), output.Outpoint)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reflect.DeepEqual(output, output2)).To(BeTrue())
+				Expect(confs).To(Equal(pack.NewU64(1)))
 
 				// Build the transaction by consuming the outputs and spending
 				// them to a set of recipients.
@@ -62,11 +70,11 @@ var _ = Describe("Zcash", func() {
 				recipients := []utxo.Recipient{
 					{
 						To:    address.Address(pkhAddr.EncodeAddress()),
-						Value: pack.NewU256FromU64(pack.NewU64((output.Value.Int().Uint64() - 1000) / 2)),
+						Value: pack.NewU256FromU64(pack.NewU64((output.Value.Int().Uint64() - 10000) / 2)),
 					},
 					{
 						To:    address.Address(pkhAddrUncompressed.EncodeAddress()),
-						Value: pack.NewU256FromU64(pack.NewU64((output.Value.Int().Uint64() - 1000) / 2)),
+						Value: pack.NewU256FromU64(pack.NewU64((output.Value.Int().Uint64() - 10000) / 2)),
 					},
 				}
 				tx, err := zcash.NewTxBuilder(&zcash.RegressionNetParams, 1000000).BuildTx(inputs, recipients)
@@ -77,43 +85,45 @@ var _ = Describe("Zcash", func() {
 				// MPC algorithm, but for the purposes of this test, using an
 				// explicit privkey is ok.
 				sighashes, err := tx.Sighashes()
-				signatures := make([]pack.Bytes65, len(sighashes))
 				Expect(err).ToNot(HaveOccurred())
+				signatures := make([]pack.Bytes65, len(sighashes))
 				for i := range sighashes {
 					hash := id.Hash(sighashes[i])
-					privKey := (*id.PrivKey)(wif.PrivKey)
 					signature, err := privKey.Sign(&hash)
 					Expect(err).ToNot(HaveOccurred())
 					signatures[i] = pack.NewBytes65(signature)
 				}
-				Expect(tx.Sign(signatures, pack.NewBytes(wif.SerializePubKey()))).To(Succeed())
+				Expect(tx.Sign(signatures, pack.NewBytes(pubKey.SerializeCompressed()))).To(Succeed())
 
-				// Submit the transaction to the Bitcoin Cash node. Again, this
-				// should be running a la `./multichaindeploy`.
+				// Submit the transaction, and check that it is in the mempool.
 				txHash, err := tx.Hash()
 				Expect(err).ToNot(HaveOccurred())
-				err = client.SubmitTx(context.Background(), tx)
+				Expect(client.SubmitTx(context.Background(), tx)).To(Succeed())
+				var txID chainhash.Hash
+				copy(txID[:], txHash)
+				Expect(server.Mempool()).To(Equal([]chainhash.Hash{txID}))
+				confs2, err := client.Confirmations(context.Background(), txHash)
 				Expect(err).ToNot(HaveOccurred())
-				log.Printf("TXID               %v", txHash)
+				Expect(confs2).To(Equal(int64(0)))
 
-				// Zcash nodes are a little slow, so we wait for 1000 ms before
-				// beginning to check confirmations.
-				time.Sleep(1 * time.Second)
+				// Mine the transaction, and check that it is confirmed.
+				server.Mine(3)
+				confs2, err = client.Confirmations(context.Background(), txHash)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(confs2).To(Equal(int64(3)))
+				outputs, err = client.UnspentOutputs(context.Background(), 0, 999999999, address.Address(pkhAddrUncompressed.EncodeAddress()))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(outputs).To(HaveLen(1))
+				Expect(outputs[0].Value).To(Equal(recipients[1].Value))
 
-				for {
-					// Loop until the transaction has at least a few
-					// confirmations. This implies that the transaction is
-					// definitely valid, and the test has passed. We were
-					// successfully able to use the multichain to construct and
-					// submit a Bitcoin Cash transaction!
-					confs, err := client.Confirmations(context.Background(), txHash)
-					Expect(err).ToNot(HaveOccurred())
-					log.Printf("                   %v/3 confirmations", confs)
-					if confs >= 3 {
-						break
-					}
-					time.Sleep(10 * time.Second)
-				}
+				// The spent output can no longer be loaded as unspent, and the
+				// transaction cannot be submitted again. The client retries
+				// until the context is done.
+				ctxWithTimeout, cancelCtxWithTimeout := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancelCtxWithTimeout()
+				_, _, err = client.UnspentOutput(ctxWithTimeout, output.Outpoint)
+				Expect(err).To(HaveOccurred())
+				Expect(client.SubmitTx(ctxWithTimeout, tx)).ToNot(Succeed())
 
 				// Check that we can load the output and that it is equal.
 				// Otherwise, something strange is happening with the RPC
@@ -123,5 +133,34 @@ var _ = Describe("Zcash", func() {
 				Expect(reflect.DeepEqual(output, output2)).To(BeTrue())
 			})
 		})
+
+		Context("when the transaction is not signed by the owner of the inputs", func() {
+			It("should be rejected", func() {
+				pubKey := (*btcec.PrivateKey)(id.NewPrivKey()).PubKey()
+				pkhAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), &zcash.RegressionNetParams)
+				Expect(err).ToNot(HaveOccurred())
+				output, err := server.Fund(pkhAddr.EncodeAddress(), 100000)
+				Expect(err).ToNot(HaveOccurred())
+
+				tx, err := zcash.NewTxBuilder(&zcash.RegressionNetParams, 1000000).BuildTx(
+					[]utxo.Input{{Output: output}},
+					[]utxo.Recipient{{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				sighashes, err := tx.Sighashes()
+				Expect(err).ToNot(HaveOccurred())
+				otherKey := id.NewPrivKey()
+				hash := id.Hash(sighashes[0])
+				signature, err := otherKey.Sign(&hash)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tx.Sign([]pack.Bytes65{pack.NewBytes65(signature)}, pack.NewBytes(pubKey.SerializeCompressed()))).To(Succeed())
+
+				client := zcash.NewClient(server.ClientOptions())
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				Expect(client.SubmitTx(ctx, tx)).ToNot(Succeed())
+				Expect(server.Mempool()).To(HaveLen(1))
+			})
+		})
 	})
 })
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
@@ -87,6 +88,12 @@ func (encoder AddressEncoder) EncodeAddress(rawAddr address.RawAddress) (address
 
 // DecodeAddress implements the address.Decoder interface
 func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	// The base58 decoder panics on characters that are not ASCII.
+	for _, c := range []byte(addr) {
+		if c >= utf8.RuneSelf {
+			return nil, fmt.Errorf("validating address: unexpected character %q", c)
+		}
+	}
 	var decoded = base58.Decode(string(addr))
 	var addrType uint8
 	var err error
@@ -264,6 +271,12 @@ func addressFromRawBytes(addrBytes []byte, params *Params) (Address, error) {
 		return nil, fmt.Errorf("parsing address type: %v", err)
 	}
 
+	var cksum [4]byte
+	copy(cksum[:], addrBytes[len(addrBytes)-4:])
+	if checksum(addrBytes[:len(addrBytes)-4]) != cksum {
+		return nil, fmt.Errorf("validating checksum: %v", base58.ErrChecksum)
+	}
+
 	switch addrType {
 	case 0: // P2PKH
 		return NewAddressPubKeyHash(hash[:], params)
@@ -276,7 +289,7 @@ func addressFromRawBytes(addrBytes []byte, params *Params) (Address, error) {
 
 func encodeAddress(hash, prefix []byte) string {
 	var (
-		body  = append(prefix, hash...)
+		body  = append(append([]byte{}, prefix...), hash...)
 		chk   = checksum(body)
 		cksum [4]byte
 	)
@@ -308,10 +321,11 @@ func ExtractPkScriptAddrs(pkScript []byte, chainParams *Params) (btcutil.Address
 	return nil, errors.New("unknown script type")
 }
 
+// DecodeAddress decodes a P2PKH or P2SH address for the network.
 func DecodeAddress(addr string, defaultNet *Params) (btcutil.Address, error) {
 	rawAddr, err := NewAddressDecoder(defaultNet).DecodeAddress(address.Address(addr))
 	if err != nil {
 		return nil, err
 	}
-	return NewAddressPubKeyHash(rawAddr, defaultNet)
+	return addressFromRawBytes(rawAddr, defaultNet)
 }
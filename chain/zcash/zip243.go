@@ -0,0 +1,81 @@
+package zcash
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// Personalizations of the ZIP-143 and ZIP-243 digests of the shielded
+// components.
+const (
+	joinSplitsHashPersonalization      = "ZcashJSplitsHash"
+	shieldedSpendsHashPersonalization  = "ZcashSSpendsHash"
+	shieldedOutputsHashPersonalization = "ZcashSOutputHash"
+)
+
+// notAnInput is the index that is passed to calculateSighash for the sighash
+// that is signed by the shielded components of a transaction, which commits to
+// no input. It is NOT_AN_INPUT in zcashd.
+const notAnInput = -1
+
+// shieldedDigests are the digests of the shielded components of a version 3
+// or 4 transaction that are committed to by its ZIP-143 and ZIP-243 sighashes.
+// They are zero for transactions with no shielded components.
+type shieldedDigests struct {
+	hashJoinSplits      chainhash.Hash
+	hashShieldedSpends  chainhash.Hash
+	hashShieldedOutputs chainhash.Hash
+	valueBalance        int64
+}
+
+// shieldedDigests returns the digests of the JoinSplits, and the Sapling spends
+// and outputs, of the transaction. Spend authorization signatures are not
+// committed to.
+func (tx *DecodedTx) shieldedDigests() (shieldedDigests, error) {
+	digests := shieldedDigests{valueBalance: tx.SaplingValueBalance}
+	var err error
+	if len(tx.JoinSplits) > 0 {
+		data := new(bytes.Buffer)
+		for _, joinSplit := range tx.JoinSplits {
+			var values [16]byte
+			binary.LittleEndian.PutUint64(values[:8], joinSplit.VPubOld)
+			binary.LittleEndian.PutUint64(values[8:], joinSplit.VPubNew)
+			data.Write(values[:])
+			data.Write(joinSplit.Data)
+		}
+		data.Write(tx.JoinSplitPubKey[:])
+		if digests.hashJoinSplits, err = blake2b(data.Bytes(), []byte(joinSplitsHashPersonalization)); err != nil {
+			return shieldedDigests{}, err
+		}
+	}
+	if len(tx.SaplingSpends) > 0 {
+		data := new(bytes.Buffer)
+		for _, spend := range tx.SaplingSpends {
+			data.Write(spend.CV[:])
+			data.Write(spend.Anchor[:])
+			data.Write(spend.Nullifier[:])
+			data.Write(spend.RK[:])
+			data.Write(spend.Proof[:])
+		}
+		if digests.hashShieldedSpends, err = blake2b(data.Bytes(), []byte(shieldedSpendsHashPersonalization)); err != nil {
+			return shieldedDigests{}, err
+		}
+	}
+	if len(tx.SaplingOutputs) > 0 {
+		data := new(bytes.Buffer)
+		for _, output := range tx.SaplingOutputs {
+			data.Write(output.CV[:])
+			data.Write(output.CMU[:])
+			data.Write(output.EphemeralKey[:])
+			data.Write(output.EncCiphertext[:])
+			data.Write(output.OutCiphertext[:])
+			data.Write(output.Proof[:])
+		}
+		if digests.hashShieldedOutputs, err = blake2b(data.Bytes(), []byte(shieldedOutputsHashPersonalization)); err != nil {
+			return shieldedDigests{}, err
+		}
+	}
+	return digests, nil
+}
@@ -0,0 +1,150 @@
+package zcash_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// sighashVector is a ZIP-143 or ZIP-243 test vector from zcash-test-vectors
+// (zip_0143.py and zip_0243.py).
+type sighashVector struct {
+	tx               []byte
+	scriptCode       []byte
+	transparentInput int
+	hashType         txscript.SigHashType
+	amount           int64
+	params           *zcash.Params
+	sighash          []byte
+}
+
+// loadSighashVectors loads the test vectors. The transparent input is -1 for
+// the sighash that commits to no input. The params have the consensus branch
+// of the vector active at all heights.
+func loadSighashVectors(path string) []sighashVector {
+	data, err := os.ReadFile(path)
+	Expect(err).ToNot(HaveOccurred())
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var rows [][]interface{}
+	Expect(decoder.Decode(&rows)).To(Succeed())
+
+	// The first two rows are the source, and the names of the columns.
+	vectors := make([]sighashVector, 0, len(rows)-2)
+	for _, row := range rows[2:] {
+		bytes := func(i int) []byte {
+			data, err := hex.DecodeString(row[i].(string))
+			Expect(err).ToNot(HaveOccurred())
+			return data
+		}
+		number := func(i int) int64 {
+			n, err := row[i].(json.Number).Int64()
+			Expect(err).ToNot(HaveOccurred())
+			return n
+		}
+		branchID := make([]byte, 4)
+		binary.LittleEndian.PutUint32(branchID, uint32(number(5)))
+		vectors = append(vectors, sighashVector{
+			tx:               bytes(0),
+			scriptCode:       bytes(1),
+			transparentInput: int(number(2)),
+			hashType:         txscript.SigHashType(number(3)),
+			amount:           number(4),
+			params: &zcash.Params{
+				Params:   &chaincfg.MainNetParams,
+				Upgrades: []zcash.ParamsUpgrade{{ActivationHeight: 0, BranchID: branchID}},
+			},
+			sighash: bytes(6),
+		})
+	}
+	return vectors
+}
+
+var _ = Describe("ZIP-143 and ZIP-243", func() {
+	for _, test := range []struct {
+		path    string
+		version int32
+	}{
+		{"testdata/zip_0143.json", 3},
+		{"testdata/zip_0243.json", 4},
+	} {
+		test := test
+
+		Context("when computing the sighashes of "+test.path, func() {
+			It("should match the test vectors", func() {
+				vectors := loadSighashVectors(test.path)
+				Expect(vectors).To(HaveLen(10))
+				for i, vector := range vectors {
+					tx, err := zcash.DecodeTx(vector.tx)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(tx.MsgTx.Version).To(Equal(test.version))
+					sighash, err := tx.SignatureHash(vector.params, vector.scriptCode, vector.hashType, vector.transparentInput, vector.amount)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sighash).To(Equal(vector.sighash), "vector %v", i)
+				}
+			})
+
+			It("should match the SIGHASH_ALL test vectors of transactions with shielded components", func() {
+				n := 0
+				for _, vector := range loadSighashVectors(test.path) {
+					if vector.transparentInput < 0 || vector.hashType != txscript.SigHashAll {
+						continue
+					}
+					tx, err := zcash.DecodeTx(vector.tx)
+					Expect(err).ToNot(HaveOccurred())
+					inputs := make([]utxo.Input, len(tx.MsgTx.TxIn))
+					for i, txIn := range tx.MsgTx.TxIn {
+						inputs[i] = utxo.Input{Output: utxo.Output{
+							Outpoint: utxo.Outpoint{Hash: pack.NewBytes(txIn.PreviousOutPoint.Hash[:]), Index: pack.NewU32(txIn.PreviousOutPoint.Index)},
+							Value:    pack.NewU256FromUint64(0),
+						}}
+					}
+					inputs[vector.transparentInput].Value = pack.NewU256FromUint64(uint64(vector.amount))
+					inputs[vector.transparentInput].SigScript = vector.scriptCode
+					sighashes, err := tx.Sighashes(inputs, vector.params)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sighashes[vector.transparentInput][:]).To(Equal(vector.sighash))
+					n++
+				}
+				// Only the version 3 test vectors have SIGHASH_ALL sighashes
+				// of transparent inputs.
+				if test.version == 3 {
+					Expect(n).To(BeNumerically(">", 0))
+				}
+			})
+		})
+
+		Context("when serializing the transparent transactions of "+test.path, func() {
+			It("should match the test vectors", func() {
+				n := 0
+				for _, vector := range loadSighashVectors(test.path) {
+					tx, err := zcash.DecodeTx(vector.tx)
+					Expect(err).ToNot(HaveOccurred())
+					if !tx.IsTransparent() {
+						continue
+					}
+					serialized, err := tx.Tx(vector.params).Serialize()
+					Expect(err).ToNot(HaveOccurred())
+					Expect([]byte(serialized)).To(Equal(vector.tx))
+					n++
+				}
+				// Only the version 3 test vectors have transactions with no
+				// shielded components.
+				if test.version == 3 {
+					Expect(n).To(BeNumerically(">", 0))
+				}
+			})
+		})
+	}
+})
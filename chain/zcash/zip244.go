@@ -0,0 +1,319 @@
+package zcash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+)
+
+// Personalizations of the ZIP-244 digests.
+const (
+	zip244TxHash            = "ZcashTxHash_"
+	zip244HeadersHash       = "ZTxIdHeadersHash"
+	zip244TransparentHash   = "ZTxIdTranspaHash"
+	zip244PrevoutsHash      = "ZTxIdPrevoutHash"
+	zip244SequenceHash      = "ZTxIdSequencHash"
+	zip244OutputsHash       = "ZTxIdOutputsHash"
+	zip244AmountsHash       = "ZTxTrAmountsHash"
+	zip244ScriptsHash       = "ZTxTrScriptsHash"
+	zip244TxInHash          = "Zcash___TxInHash"
+	zip244SaplingHash       = "ZTxIdSaplingHash"
+	zip244SaplingSpendsHash = "ZTxIdSSpendsHash"
+	zip244SaplingSpendCHash = "ZTxIdSSpendCHash"
+	zip244SaplingSpendNHash = "ZTxIdSSpendNHash"
+	zip244SaplingOutputHash = "ZTxIdSOutputHash"
+	zip244SaplingOutCHash   = "ZTxIdSOutC__Hash"
+	zip244SaplingOutMHash   = "ZTxIdSOutM__Hash"
+	zip244SaplingOutNHash   = "ZTxIdSOutN__Hash"
+	zip244OrchardHash       = "ZTxIdOrchardHash"
+	zip244OrchardActCHash   = "ZTxIdOrcActCHash"
+	zip244OrchardActMHash   = "ZTxIdOrcActMHash"
+	zip244OrchardActNHash   = "ZTxIdOrcActNHash"
+)
+
+// Offsets into the note ciphertext that separate the compact part, the memo,
+// and the rest of the ciphertext.
+const (
+	compactNoteSize = 52
+	memoEnd         = compactNoteSize + 512
+)
+
+// txIDDigest returns the ZIP-244 transaction digest of a version 5
+// transaction.
+func (tx *DecodedTx) txIDDigest() (chainhash.Hash, error) {
+	transparentDigest, err := tx.transparentDigest()
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return tx.digest(transparentDigest)
+}
+
+// signatureDigest returns the ZIP-244 signature digest of a transparent input
+// of a version 5 transaction, using SIGHASH_ALL. The inputs must be the inputs
+// of the transaction, with the outputs that they spend. The sig script of each
+// input is the redeem script, if the input spends a P2SH output.
+func (tx *DecodedTx) signatureDigest(inputs []utxo.Input, idx int) (chainhash.Hash, error) {
+	if idx < 0 || idx >= len(tx.MsgTx.TxIn) || len(inputs) != len(tx.MsgTx.TxIn) {
+		return chainhash.Hash{}, fmt.Errorf("bad input %v: expected %v inputs", idx, len(tx.MsgTx.TxIn))
+	}
+
+	prevoutsDigest, sequenceDigest, outputsDigest, err := transparentDigests(tx.MsgTx)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	amounts := new(bytes.Buffer)
+	scripts := new(bytes.Buffer)
+	for _, input := range inputs {
+		if err := binary.Write(amounts, binary.LittleEndian, input.Value.Int().Int64()); err != nil {
+			return chainhash.Hash{}, err
+		}
+		if err := wire.WriteVarBytes(scripts, 0, input.PubKeyScript); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+	amountsDigest, err := blake2b(amounts.Bytes(), []byte(zip244AmountsHash))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	scriptsDigest, err := blake2b(scripts.Bytes(), []byte(zip244ScriptsHash))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	input := inputs[idx]
+	scriptCode := []byte(input.PubKeyScript)
+	if input.SigScript != nil {
+		scriptCode = input.SigScript
+	}
+	txIn := new(bytes.Buffer)
+	txIn.Write(tx.MsgTx.TxIn[idx].PreviousOutPoint.Hash[:])
+	writeUint32(txIn, tx.MsgTx.TxIn[idx].PreviousOutPoint.Index)
+	if err := binary.Write(txIn, binary.LittleEndian, input.Value.Int().Int64()); err != nil {
+		return chainhash.Hash{}, err
+	}
+	if err := wire.WriteVarBytes(txIn, 0, scriptCode); err != nil {
+		return chainhash.Hash{}, err
+	}
+	writeUint32(txIn, tx.MsgTx.TxIn[idx].Sequence)
+	txInDigest, err := blake2b(txIn.Bytes(), []byte(zip244TxInHash))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	transparent := new(bytes.Buffer)
+	transparent.WriteByte(byte(txscript.SigHashAll))
+	transparent.Write(prevoutsDigest[:])
+	transparent.Write(amountsDigest[:])
+	transparent.Write(scriptsDigest[:])
+	transparent.Write(sequenceDigest[:])
+	transparent.Write(outputsDigest[:])
+	transparent.Write(txInDigest[:])
+	transparentDigest, err := blake2b(transparent.Bytes(), []byte(zip244TransparentHash))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return tx.digest(transparentDigest)
+}
+
+// digest returns the top-level ZIP-244 digest, which commits to the header,
+// the given transparent digest, and the shielded bundles.
+func (tx *DecodedTx) digest(transparentDigest chainhash.Hash) (chainhash.Hash, error) {
+	header := new(bytes.Buffer)
+	writeUint32(header, uint32(tx.MsgTx.Version)|(1<<31))
+	writeUint32(header, tx.VersionGroupID)
+	writeUint32(header, tx.ConsensusBranchID)
+	writeUint32(header, tx.MsgTx.LockTime)
+	writeUint32(header, tx.ExpiryHeight)
+	headerDigest, err := blake2b(header.Bytes(), []byte(zip244HeadersHash))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	saplingDigest, err := tx.saplingDigest()
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	orchardDigest, err := tx.orchardDigest()
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	var branchID [4]byte
+	binary.LittleEndian.PutUint32(branchID[:], tx.ConsensusBranchID)
+	data := make([]byte, 0, 4*chainhash.HashSize)
+	data = append(data, headerDigest[:]...)
+	data = append(data, transparentDigest[:]...)
+	data = append(data, saplingDigest[:]...)
+	data = append(data, orchardDigest[:]...)
+	return blake2b(data, append([]byte(zip244TxHash), branchID[:]...))
+}
+
+func (tx *DecodedTx) transparentDigest() (chainhash.Hash, error) {
+	if len(tx.MsgTx.TxIn) == 0 && len(tx.MsgTx.TxOut) == 0 {
+		return blake2b(nil, []byte(zip244TransparentHash))
+	}
+	prevoutsDigest, sequenceDigest, outputsDigest, err := transparentDigests(tx.MsgTx)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	data := make([]byte, 0, 3*chainhash.HashSize)
+	data = append(data, prevoutsDigest[:]...)
+	data = append(data, sequenceDigest[:]...)
+	data = append(data, outputsDigest[:]...)
+	return blake2b(data, []byte(zip244TransparentHash))
+}
+
+// transparentDigests returns the digests of the prevouts, the sequence
+// numbers, and the outputs of the transaction.
+func transparentDigests(msgTx *wire.MsgTx) (prevoutsDigest, sequenceDigest, outputsDigest chainhash.Hash, err error) {
+	prevouts := new(bytes.Buffer)
+	sequences := new(bytes.Buffer)
+	for _, txIn := range msgTx.TxIn {
+		prevouts.Write(txIn.PreviousOutPoint.Hash[:])
+		writeUint32(prevouts, txIn.PreviousOutPoint.Index)
+		writeUint32(sequences, txIn.Sequence)
+	}
+	outputs := new(bytes.Buffer)
+	for _, txOut := range msgTx.TxOut {
+		if err = wire.WriteTxOut(outputs, 0, 0, txOut); err != nil {
+			return
+		}
+	}
+	if prevoutsDigest, err = blake2b(prevouts.Bytes(), []byte(zip244PrevoutsHash)); err != nil {
+		return
+	}
+	if sequenceDigest, err = blake2b(sequences.Bytes(), []byte(zip244SequenceHash)); err != nil {
+		return
+	}
+	outputsDigest, err = blake2b(outputs.Bytes(), []byte(zip244OutputsHash))
+	return
+}
+
+func (tx *DecodedTx) saplingDigest() (chainhash.Hash, error) {
+	if len(tx.SaplingSpends) == 0 && len(tx.SaplingOutputs) == 0 {
+		return blake2b(nil, []byte(zip244SaplingHash))
+	}
+
+	spendsDigest, err := blake2b(nil, []byte(zip244SaplingSpendsHash))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	if len(tx.SaplingSpends) > 0 {
+		compact := new(bytes.Buffer)
+		noncompact := new(bytes.Buffer)
+		for _, spend := range tx.SaplingSpends {
+			compact.Write(spend.Nullifier[:])
+			noncompact.Write(spend.CV[:])
+			noncompact.Write(spend.Anchor[:])
+			noncompact.Write(spend.RK[:])
+		}
+		if spendsDigest, err = digestOfDigests(zip244SaplingSpendsHash, digestPart{compact.Bytes(), zip244SaplingSpendCHash}, digestPart{noncompact.Bytes(), zip244SaplingSpendNHash}); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+
+	outputsDigest, err := blake2b(nil, []byte(zip244SaplingOutputHash))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	if len(tx.SaplingOutputs) > 0 {
+		compact := new(bytes.Buffer)
+		memos := new(bytes.Buffer)
+		noncompact := new(bytes.Buffer)
+		for _, output := range tx.SaplingOutputs {
+			compact.Write(output.CMU[:])
+			compact.Write(output.EphemeralKey[:])
+			compact.Write(output.EncCiphertext[:compactNoteSize])
+			memos.Write(output.EncCiphertext[compactNoteSize:memoEnd])
+			noncompact.Write(output.CV[:])
+			noncompact.Write(output.EncCiphertext[memoEnd:])
+			noncompact.Write(output.OutCiphertext[:])
+		}
+		if outputsDigest, err = digestOfDigests(zip244SaplingOutputHash, digestPart{compact.Bytes(), zip244SaplingOutCHash}, digestPart{memos.Bytes(), zip244SaplingOutMHash}, digestPart{noncompact.Bytes(), zip244SaplingOutNHash}); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+
+	data := new(bytes.Buffer)
+	data.Write(spendsDigest[:])
+	data.Write(outputsDigest[:])
+	if err := binary.Write(data, binary.LittleEndian, tx.SaplingValueBalance); err != nil {
+		return chainhash.Hash{}, err
+	}
+	return blake2b(data.Bytes(), []byte(zip244SaplingHash))
+}
+
+func (tx *DecodedTx) orchardDigest() (chainhash.Hash, error) {
+	if len(tx.OrchardActions) == 0 {
+		return blake2b(nil, []byte(zip244OrchardHash))
+	}
+
+	compact := new(bytes.Buffer)
+	memos := new(bytes.Buffer)
+	noncompact := new(bytes.Buffer)
+	for _, action := range tx.OrchardActions {
+		compact.Write(action.Nullifier[:])
+		compact.Write(action.CMX[:])
+		compact.Write(action.EphemeralKey[:])
+		compact.Write(action.EncCiphertext[:compactNoteSize])
+		memos.Write(action.EncCiphertext[compactNoteSize:memoEnd])
+		noncompact.Write(action.CV[:])
+		noncompact.Write(action.RK[:])
+		noncompact.Write(action.EncCiphertext[memoEnd:])
+		noncompact.Write(action.OutCiphertext[:])
+	}
+	actionsDigests, err := concatDigests(digestPart{compact.Bytes(), zip244OrchardActCHash}, digestPart{memos.Bytes(), zip244OrchardActMHash}, digestPart{noncompact.Bytes(), zip244OrchardActNHash})
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	data := new(bytes.Buffer)
+	data.Write(actionsDigests)
+	data.WriteByte(tx.OrchardFlags)
+	if err := binary.Write(data, binary.LittleEndian, tx.OrchardValueBalance); err != nil {
+		return chainhash.Hash{}, err
+	}
+	data.Write(tx.OrchardAnchor[:])
+	return blake2b(data.Bytes(), []byte(zip244OrchardHash))
+}
+
+// A digestPart is data that is hashed with a personalization, before its
+// digest is hashed together with the digests of other parts.
+type digestPart struct {
+	data            []byte
+	personalization string
+}
+
+// concatDigests hashes each of the parts, and returns the concatenation of
+// their digests.
+func concatDigests(parts ...digestPart) ([]byte, error) {
+	data := new(bytes.Buffer)
+	for _, part := range parts {
+		digest, err := blake2b(part.data, []byte(part.personalization))
+		if err != nil {
+			return nil, err
+		}
+		data.Write(digest[:])
+	}
+	return data.Bytes(), nil
+}
+
+// digestOfDigests hashes the concatenation of the digests of the parts with
+// the given personalization.
+func digestOfDigests(personalization string, parts ...digestPart) (chainhash.Hash, error) {
+	data, err := concatDigests(parts...)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return blake2b(data, []byte(personalization))
+}
+
+func writeUint32(buf *bytes.Buffer, value uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], value)
+	buf.Write(b[:])
+}
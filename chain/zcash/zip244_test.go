@@ -0,0 +1,117 @@
+package zcash_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// zip244Vector is a ZIP-244 test vector from zcash-test-vectors
+// (zip_0244.py). The transparent input is -1 if the transaction has no
+// transparent inputs.
+type zip244Vector struct {
+	tx               []byte
+	txID             []byte
+	amounts          []int64
+	scriptPubKeys    [][]byte
+	transparentInput int
+	sighashShielded  []byte
+	sighashAll       []byte
+}
+
+func loadZIP244Vectors() []zip244Vector {
+	data, err := os.ReadFile("testdata/zip_0244.json")
+	Expect(err).ToNot(HaveOccurred())
+	var rows [][]json.RawMessage
+	Expect(json.Unmarshal(data, &rows)).To(Succeed())
+
+	// The first two rows are the source, and the names of the columns.
+	vectors := make([]zip244Vector, 0, len(rows)-2)
+	for _, row := range rows[2:] {
+		bytes := func(i int) []byte {
+			var str *string
+			Expect(json.Unmarshal(row[i], &str)).To(Succeed())
+			if str == nil {
+				return nil
+			}
+			data, err := hex.DecodeString(*str)
+			Expect(err).ToNot(HaveOccurred())
+			return data
+		}
+		vector := zip244Vector{
+			tx:               bytes(0),
+			txID:             bytes(1),
+			transparentInput: -1,
+			sighashShielded:  bytes(6),
+			sighashAll:       bytes(7),
+		}
+		var scriptPubKeys []string
+		Expect(json.Unmarshal(row[3], &vector.amounts)).To(Succeed())
+		Expect(json.Unmarshal(row[4], &scriptPubKeys)).To(Succeed())
+		for _, script := range scriptPubKeys {
+			data, err := hex.DecodeString(script)
+			Expect(err).ToNot(HaveOccurred())
+			vector.scriptPubKeys = append(vector.scriptPubKeys, data)
+		}
+		if string(row[5]) != "null" {
+			Expect(json.Unmarshal(row[5], &vector.transparentInput)).To(Succeed())
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors
+}
+
+var _ = Describe("ZIP-244", func() {
+	Context("when computing the txids", func() {
+		It("should match the test vectors", func() {
+			vectors := loadZIP244Vectors()
+			Expect(vectors).To(HaveLen(10))
+			for i, vector := range vectors {
+				tx, err := zcash.DecodeTx(vector.tx)
+				Expect(err).ToNot(HaveOccurred())
+				txID, err := tx.TxID()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(txID[:]).To(Equal(vector.txID), "vector %v", i)
+
+				// Without transparent inputs, the shielded sighash is the
+				// txid.
+				if len(tx.MsgTx.TxIn) == 0 {
+					Expect(txID[:]).To(Equal(vector.sighashShielded), "vector %v", i)
+				}
+			}
+		})
+	})
+
+	Context("when computing the sighashes", func() {
+		It("should match the SIGHASH_ALL test vectors", func() {
+			n := 0
+			for i, vector := range loadZIP244Vectors() {
+				if vector.transparentInput < 0 {
+					continue
+				}
+				tx, err := zcash.DecodeTx(vector.tx)
+				Expect(err).ToNot(HaveOccurred())
+				inputs := make([]utxo.Input, len(tx.MsgTx.TxIn))
+				for i, txIn := range tx.MsgTx.TxIn {
+					inputs[i] = utxo.Input{Output: utxo.Output{
+						Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(txIn.PreviousOutPoint.Hash[:]), Index: pack.NewU32(txIn.PreviousOutPoint.Index)},
+						Value:        pack.NewU256FromUint64(uint64(vector.amounts[i])),
+						PubKeyScript: vector.scriptPubKeys[i],
+					}}
+				}
+				sighashes, err := tx.Sighashes(inputs, &zcash.MainNetParams)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sighashes[vector.transparentInput][:]).To(Equal(vector.sighashAll), "vector %v", i)
+				n++
+			}
+			Expect(n).To(BeNumerically(">", 0))
+		})
+	})
+})
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+)
+
+// addressInfo is the output of the decode-address and encode-address commands.
+type addressInfo struct {
+	Address      string `json:"address"`
+	Network      string `json:"network"`
+	Type         string `json:"type"`
+	Raw          string `json:"raw"`
+	Hash         string `json:"hash"`
+	PubKeyScript string `json:"pubKeyScript"`
+}
+
+func decodeAddress(e *env, args []string) error {
+	flags := e.newFlagSet()
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	info, err := newAddressInfo(e, address.Address(flags.Arg(0)))
+	if err != nil {
+		return err
+	}
+	return e.print(info, info.print)
+}
+
+func encodeAddress(e *env, args []string) error {
+	flags := e.newFlagSet()
+	addrType := flags.String("type", "p2pkh", "type of the address if the input is a 20-byte hash: p2pkh or p2sh")
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	data, err := hex.DecodeString(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("bad hex: %v", err)
+	}
+
+	var addr address.Address
+	if len(data) == 20 {
+		var zaddr zcash.Address
+		switch *addrType {
+		case "p2pkh":
+			zaddr, err = zcash.NewAddressPubKeyHash(data, e.params)
+		case "p2sh":
+			zaddr, err = zcash.NewAddressScriptHashFromHash(data, e.params)
+		default:
+			return fmt.Errorf("bad type %q: expected p2pkh or p2sh", *addrType)
+		}
+		if err != nil {
+			return fmt.Errorf("bad hash: %v", err)
+		}
+		addr = address.Address(zaddr.EncodeAddress())
+	} else {
+		if addr, err = zcash.NewAddressEncoder(e.params).EncodeAddress(address.RawAddress(data)); err != nil {
+			return fmt.Errorf("bad raw address: %v", err)
+		}
+		// The encoder recomputes the checksum, so make sure that the raw
+		// address was not corrupted.
+		raw, err := zcash.NewAddressDecoder(e.params).DecodeAddress(addr)
+		if err != nil || !bytes.Equal(raw, data) {
+			return fmt.Errorf("bad raw address: bad checksum")
+		}
+	}
+
+	info, err := newAddressInfo(e, addr)
+	if err != nil {
+		return err
+	}
+	return e.print(info, info.print)
+}
+
+// newAddressInfo decodes the address for the network of the environment.
+func newAddressInfo(e *env, addr address.Address) (addressInfo, error) {
+	raw, err := zcash.NewAddressDecoder(e.params).DecodeAddress(addr)
+	if err != nil {
+		return addressInfo{}, fmt.Errorf("bad address %q for %v: %v", addr, e.network, err)
+	}
+	script, err := zcash.RecipientScript(utxo.Recipient{To: addr}, e.params)
+	if err != nil {
+		return addressInfo{}, fmt.Errorf("bad address %q: %v", addr, err)
+	}
+	addrType := "p2pkh"
+	if bytes.HasPrefix(raw, e.params.P2SHPrefix) {
+		addrType = "p2sh"
+	}
+	prefixLen := len(e.params.P2PKHPrefix)
+	return addressInfo{
+		Address:      string(addr),
+		Network:      e.network,
+		Type:         addrType,
+		Raw:          hex.EncodeToString(raw),
+		Hash:         hex.EncodeToString(raw[prefixLen : prefixLen+20]),
+		PubKeyScript: hex.EncodeToString(script),
+	}, nil
+}
+
+func (info addressInfo) print(w io.Writer) {
+	fmt.Fprintf(w, "address:      %v\n", info.Address)
+	fmt.Fprintf(w, "network:      %v\n", info.Network)
+	fmt.Fprintf(w, "type:         %v\n", info.Type)
+	fmt.Fprintf(w, "raw:          %v\n", info.Raw)
+	fmt.Fprintf(w, "hash:         %v\n", info.Hash)
+	fmt.Fprintf(w, "pubkeyscript: %v\n", info.PubKeyScript)
+}
@@ -0,0 +1,203 @@
+// Command zecutil is a command-line tool for working with Zcash addresses and
+// transparent transactions. It can validate and convert addresses, list the
+// unspent outputs of an address, build unsigned transactions, sign them with a
+// WIF key, decode raw transactions, estimate fees, and broadcast transactions
+// to a node.
+//
+// Usage:
+//
+//	zecutil [global flags] <command> [flags] [args]
+//
+// Run `zecutil help` for the list of commands and global flags. Commands that
+// talk to a node use the JSON-RPC interface of zcashd, configured using the
+// -rpc-host, -rpc-user, and -rpc-password global flags (or the
+// ZECUTIL_RPC_PASSWORD environment variable).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+)
+
+// newClient returns the client that is used by commands that talk to a node.
+// It is a variable so that tests can replace the client.
+var newClient = zcash.NewClient
+
+// networks maps the values of the -network flag to the chain parameters.
+var networks = map[string]*zcash.Params{
+	"mainnet": &zcash.MainNetParams,
+	"testnet": &zcash.TestNet3Params,
+	"regtest": &zcash.RegressionNetParams,
+}
+
+// A command is a subcommand of zecutil.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(env *env, args []string) error
+}
+
+var commands = []command{
+	{"decode-address", "<address>", "validate an address and print its type, raw bytes, and pubkey script", decodeAddress},
+	{"encode-address", "[-type p2pkh|p2sh] <hex>", "encode a raw address, or a 20-byte hash, as an address", encodeAddress},
+	{"utxos", "[-min-conf n] [-max-conf n] <address>", "list the unspent outputs of an address", listUnspentOutputs},
+	{"build", "-to <address>=<zec> [flags]", "build an unsigned transaction and write it to a file", buildTx},
+	{"sighashes", "<tx file>", "print the sighashes of an unsigned transaction", printSighashes},
+	{"sign", "-wif <key> [-out file] <tx file>", "sign a transaction with a WIF private key", signTx},
+	{"decode", "<hex | tx file | raw tx file>", "decode a transaction", decodeTx},
+	{"estimate-fee", "[-blocks n] [-fallback zats]", "estimate the fee rate in zatoshis-per-byte", estimateFee},
+	{"broadcast", "<hex | tx file | raw tx file>", "submit a signed transaction to the node", broadcastTx},
+}
+
+// env holds the global options of a command.
+type env struct {
+	network       string
+	params        *zcash.Params
+	json          bool
+	clientOptions zcash.ClientOptions
+	command       command
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "zecutil: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the global flags, and runs the command.
+func run(args []string, stdout, stderr io.Writer) error {
+	defaults := zcash.DefaultClientOptions()
+	flags := flag.NewFlagSet("zecutil", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	network := flags.String("network", "mainnet", "network: mainnet, testnet, or regtest")
+	output := flags.String("output", "text", "output format: text or json")
+	host := flags.String("rpc-host", defaults.Host, "URL of the zcashd JSON-RPC interface")
+	user := flags.String("rpc-user", defaults.User, "JSON-RPC username")
+	password := flags.String("rpc-password", defaults.Password, "JSON-RPC password (or set ZECUTIL_RPC_PASSWORD)")
+	timeout := flags.Duration("rpc-timeout", defaults.Timeout, "timeout of JSON-RPC requests")
+	flags.Usage = func() { usage(flags, stderr) }
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	params, ok := networks[*network]
+	if !ok {
+		return fmt.Errorf("bad network %q: expected mainnet, testnet, or regtest", *network)
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("bad output %q: expected text or json", *output)
+	}
+	if env := os.Getenv("ZECUTIL_RPC_PASSWORD"); env != "" && !isFlagSet(flags, "rpc-password") {
+		*password = env
+	}
+	e := &env{
+		network: *network,
+		params:  params,
+		json:    *output == "json",
+		clientOptions: defaults.
+			WithHost(*host).
+			WithUser(*user).
+			WithPassword(*password),
+		stdout: stdout,
+		stderr: stderr,
+	}
+	e.clientOptions.Timeout = *timeout
+
+	if flags.NArg() == 0 || flags.Arg(0) == "help" {
+		usage(flags, stdout)
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == flags.Arg(0) {
+			e.command = cmd
+			return cmd.run(e, flags.Args()[1:])
+		}
+	}
+	return fmt.Errorf("unknown command %q, see \"zecutil help\"", flags.Arg(0))
+}
+
+func usage(flags *flag.FlagSet, w io.Writer) {
+	fmt.Fprintf(w, "Usage: zecutil [global flags] <command> [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-15s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+// newFlagSet returns the flag set of the command that is being run. Errors are
+// returned, instead of exiting the process.
+func (e *env) newFlagSet() *flag.FlagSet {
+	cmd := e.command
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: zecutil %v %v\n\n%v.\n", cmd.name, cmd.args, strings.ToUpper(cmd.summary[:1])+cmd.summary[1:])
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses the flags of a command, and checks the number of positional
+// arguments.
+func parseArgs(flags *flag.FlagSet, args []string, numArgs int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != numArgs {
+		flags.Usage()
+		return fmt.Errorf("%v: expected %v arguments, got %v", flags.Name(), numArgs, flags.NArg())
+	}
+	return nil
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// client returns a client for the node, and a context that is cancelled when
+// the request times out, or when the process is interrupted.
+func (e *env) client() (zcash.Client, context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.clientOptions.Timeout+time.Second)
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	return newClient(e.clientOptions), ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// print writes the value as indented JSON if the output format is JSON, and
+// otherwise writes the text.
+func (e *env) print(v interface{}, text func(w io.Writer)) error {
+	if !e.json {
+		text(e.stdout)
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.stdout, "%s\n", data)
+	return err
+}
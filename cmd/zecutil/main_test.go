@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeClient is a client that serves unspent outputs and fee estimates from
+// memory, and records submitted transactions.
+type fakeClient struct {
+	zcash.Client
+	outputs   []utxo.Output
+	height    uint64
+	feeRate   float64
+	feeErr    error
+	submitted [][]byte
+}
+
+func (client *fakeClient) UnspentOutputs(ctx context.Context, minConf, maxConf int64, addr address.Address) ([]utxo.Output, error) {
+	return client.outputs, nil
+}
+
+func (client *fakeClient) LatestBlock(ctx context.Context) (pack.U64, error) {
+	return pack.NewU64(client.height), nil
+}
+
+func (client *fakeClient) EstimateFeeLegacy(ctx context.Context, numBlocks int64) (float64, error) {
+	return client.feeRate, client.feeErr
+}
+
+func (client *fakeClient) SubmitTx(ctx context.Context, tx utxo.Tx) error {
+	serialized, err := tx.Serialize()
+	if err != nil {
+		return err
+	}
+	client.submitted = append(client.submitted, serialized)
+	return nil
+}
+
+var _ = Describe("Zecutil", func() {
+	params := &zcash.RegressionNetParams
+	privKey := id.NewPrivKey()
+	pubKey := (*btcec.PrivateKey)(privKey).PubKey().SerializeCompressed()
+	pkhAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey), params)
+	if err != nil {
+		panic(err)
+	}
+	addr := pkhAddr.EncodeAddress()
+	pubKeyScript, err := txscript.PayToAddrScript(pkhAddr.BitcoinAddress())
+	if err != nil {
+		panic(err)
+	}
+	wif, err := btcutil.NewWIF((*btcec.PrivateKey)(privKey), params.Params, true)
+	if err != nil {
+		panic(err)
+	}
+	outputs := []utxo.Output{
+		{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(bytes.Repeat([]byte{1}, 32)), Index: pack.NewU32(0)}, Value: pack.NewU256FromUint64(30000), PubKeyScript: pubKeyScript},
+		{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(bytes.Repeat([]byte{2}, 32)), Index: pack.NewU32(1)}, Value: pack.NewU256FromUint64(100000), PubKeyScript: pubKeyScript},
+		{Outpoint: utxo.Outpoint{Hash: pack.NewBytes(bytes.Repeat([]byte{3}, 32)), Index: pack.NewU32(2)}, Value: pack.NewU256FromUint64(50000), PubKeyScript: pubKeyScript},
+	}
+
+	var client *fakeClient
+	var dir string
+	BeforeEach(func() {
+		client = &fakeClient{outputs: outputs, height: 1000, feeRate: 0.0001}
+		newClient = func(zcash.ClientOptions) zcash.Client { return client }
+		dir, err = os.MkdirTemp("", "zecutil")
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		newClient = zcash.NewClient
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	zecutil := func(args ...string) (string, error) {
+		stdout := new(bytes.Buffer)
+		err := run(append([]string{"-network", "regtest"}, args...), stdout, new(bytes.Buffer))
+		return stdout.String(), err
+	}
+	zecutilJSON := func(v interface{}, args ...string) {
+		stdout, err := zecutil(append([]string{"-output", "json"}, args...)...)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal([]byte(stdout), v)).To(Succeed())
+	}
+	writeUTXOs := func() string {
+		stdout, err := zecutil("-output", "json", "utxos", addr)
+		Expect(err).ToNot(HaveOccurred())
+		path := filepath.Join(dir, "utxos.json")
+		Expect(os.WriteFile(path, []byte(stdout), 0600)).To(Succeed())
+		return path
+	}
+
+	Context("when decoding and encoding addresses", func() {
+		It("should convert between addresses, raw addresses, and hashes", func() {
+			var info addressInfo
+			zecutilJSON(&info, "decode-address", addr)
+			Expect(info.Address).To(Equal(addr))
+			Expect(info.Network).To(Equal("regtest"))
+			Expect(info.Type).To(Equal("p2pkh"))
+			Expect(info.Hash).To(Equal(hex.EncodeToString(btcutil.Hash160(pubKey))))
+			Expect(info.PubKeyScript).To(Equal(hex.EncodeToString(pubKeyScript)))
+
+			var encoded addressInfo
+			zecutilJSON(&encoded, "encode-address", info.Raw)
+			Expect(encoded).To(Equal(info))
+			zecutilJSON(&encoded, "encode-address", info.Hash)
+			Expect(encoded).To(Equal(info))
+
+			zecutilJSON(&encoded, "encode-address", "-type", "p2sh", info.Hash)
+			Expect(encoded.Type).To(Equal("p2sh"))
+			Expect(encoded.Address).ToNot(Equal(addr))
+		})
+
+		It("should print addresses as text", func() {
+			stdout, err := zecutil("decode-address", addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stdout).To(ContainSubstring("type:         p2pkh\n"))
+		})
+
+		It("should reject addresses for other networks", func() {
+			mainnetAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey), &zcash.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = zecutil("decode-address", mainnetAddr.EncodeAddress())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject raw addresses with bad checksums", func() {
+			var info addressInfo
+			zecutilJSON(&info, "decode-address", addr)
+			raw, err := hex.DecodeString(info.Raw)
+			Expect(err).ToNot(HaveOccurred())
+			raw[len(raw)-1] ^= 1
+			_, err = zecutil("encode-address", hex.EncodeToString(raw))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building, signing, and broadcasting transactions", func() {
+		It("should build, sign, decode, and broadcast offline transactions", func() {
+			utxosPath := writeUTXOs()
+			unsignedPath := filepath.Join(dir, "unsigned.json")
+			signedPath := filepath.Join(dir, "signed.json")
+
+			_, err := zecutil("build", "-utxos", utxosPath, "-to", addr+"=0.001", "-change", addr, "-expiry-height", "2000", "-out", unsignedPath)
+			Expect(err).ToNot(HaveOccurred())
+			var unsigned txFile
+			Expect(readJSON(unsignedPath, &unsigned)).To(Succeed())
+			Expect(unsigned.Network).To(Equal("regtest"))
+			Expect(unsigned.ExpiryHeight).To(Equal(uint32(2000)))
+			Expect(unsigned.Fee).To(Equal(int64(zcash.MarginalFee * zcash.GraceActions)))
+			// The largest output is spent first.
+			Expect(unsigned.Inputs).To(HaveLen(2))
+			Expect(unsigned.Inputs[0].Value).To(Equal(int64(100000)))
+			Expect(unsigned.Recipients).To(Equal([]recipientJSON{{addr, 100000}, {addr, 40000}}))
+
+			var sighashes []string
+			zecutilJSON(&sighashes, "sighashes", unsignedPath)
+			Expect(sighashes).To(HaveLen(2))
+
+			_, err = zecutil("sign", "-wif", wif.String(), "-out", signedPath, unsignedPath)
+			Expect(err).ToNot(HaveOccurred())
+			var signed txFile
+			Expect(readJSON(signedPath, &signed)).To(Succeed())
+			Expect(signed.SignedTx).ToNot(BeEmpty())
+			Expect(signed.TxID).ToNot(Equal(unsigned.TxID))
+			_, err = zecutil("sign", "-wif", wif.String(), signedPath)
+			Expect(err).To(HaveOccurred())
+
+			var explanation zcash.Explanation
+			zecutilJSON(&explanation, "decode", signedPath)
+			Expect(explanation.TxID).To(Equal(signed.TxID))
+			Expect(*explanation.Fee).To(Equal(int64(zcash.MarginalFee * zcash.GraceActions)))
+			Expect(explanation.Inputs[0].Sighash).To(Equal(sighashes[0]))
+			Expect(explanation.Inputs[0].ScriptSig.Hex).ToNot(BeEmpty())
+			Expect(explanation.Outputs[1].ScriptPubKey.Address).To(Equal(addr))
+
+			stdout, err := zecutil("decode", signed.SignedTx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stdout).To(ContainSubstring(signed.TxID))
+
+			_, err = zecutil("broadcast", unsignedPath)
+			Expect(err).To(HaveOccurred())
+			stdout, err = zecutil("broadcast", signedPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stdout).To(Equal(signed.TxID + "\n"))
+			Expect(client.submitted).To(HaveLen(1))
+			Expect(hex.EncodeToString(client.submitted[0])).To(Equal(signed.SignedTx))
+		})
+
+		It("should fetch unspent outputs and the expiry height from the node", func() {
+			var f txFile
+			zecutilJSON(&f, "build", "-from", addr, "-to", addr+"=0.0005", "-fee-rate", "20")
+			Expect(f.ExpiryHeight).To(Equal(uint32(1000 + zcash.DefaultExpiryDelta)))
+			Expect(f.Inputs).To(HaveLen(1))
+
+			tx, err := f.rebuild(&env{network: "regtest", params: params})
+			Expect(err).ToNot(HaveOccurred())
+			size, err := tx.EstimateSize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.Fee).To(Equal(int64(size) * 20))
+		})
+
+		It("should add change below the dust threshold to the fee", func() {
+			utxosPath := writeUTXOs()
+			var f txFile
+			zecutilJSON(&f, "build", "-utxos", utxosPath, "-to", addr+"=0.0008996", "-change", addr)
+			Expect(f.Recipients).To(HaveLen(1))
+			Expect(f.Fee).To(Equal(int64(zcash.MarginalFee*zcash.GraceActions + 40)))
+		})
+
+		It("should pay the conventional fee of the selected inputs", func() {
+			outputs := make([]outputJSON, 4)
+			for i := range outputs {
+				outputs[i] = outputJSON{
+					TxID:         hex.EncodeToString(bytes.Repeat([]byte{byte(i + 1)}, 32)),
+					Value:        30000,
+					PubKeyScript: hex.EncodeToString(pubKeyScript),
+				}
+			}
+			data, err := json.Marshal(outputs)
+			Expect(err).ToNot(HaveOccurred())
+			utxosPath := filepath.Join(dir, "utxos.json")
+			Expect(os.WriteFile(utxosPath, data, 0600)).To(Succeed())
+
+			// Four inputs are four logical actions, so the fee is more than the
+			// fee of the two grace actions.
+			var f txFile
+			zecutilJSON(&f, "build", "-utxos", utxosPath, "-to", addr+"=0.001", "-change", addr)
+			Expect(f.Inputs).To(HaveLen(4))
+			Expect(f.Recipients).To(Equal([]recipientJSON{{addr, 100000}}))
+			Expect(f.Fee).To(Equal(int64(4 * zcash.MarginalFee)))
+
+			zecutilJSON(&f, "build", "-utxos", utxosPath, "-to", addr+"=0.001", "-change", addr, "-fee", "1000")
+			Expect(f.Inputs).To(HaveLen(4))
+			Expect(f.Recipients).To(Equal([]recipientJSON{{addr, 100000}, {addr, 19000}}))
+			Expect(f.Fee).To(Equal(int64(1000)))
+		})
+
+		It("should reject transactions with insufficient funds", func() {
+			utxosPath := writeUTXOs()
+			_, err := zecutil("build", "-utxos", utxosPath, "-to", addr+"=0.0018", "-change", addr)
+			Expect(err).To(MatchError(ContainSubstring("insufficient funds")))
+		})
+
+		It("should reject files that do not match their inputs and recipients", func() {
+			utxosPath := writeUTXOs()
+			var f txFile
+			zecutilJSON(&f, "build", "-utxos", utxosPath, "-to", addr+"=0.001", "-change", addr)
+			f.Recipients[0].Value++
+			data, err := json.Marshal(f)
+			Expect(err).ToNot(HaveOccurred())
+			path := filepath.Join(dir, "tampered.json")
+			Expect(os.WriteFile(path, data, 0600)).To(Succeed())
+
+			_, err = zecutil("sighashes", path)
+			Expect(err).To(MatchError(ContainSubstring("does not match")))
+			_, err = zecutil("sign", "-wif", wif.String(), path)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject keys that cannot sign the inputs", func() {
+			utxosPath := writeUTXOs()
+			path := filepath.Join(dir, "unsigned.json")
+			_, err := zecutil("build", "-utxos", utxosPath, "-to", addr+"=0.001", "-change", addr, "-out", path)
+			Expect(err).ToNot(HaveOccurred())
+
+			otherWIF, err := btcutil.NewWIF((*btcec.PrivateKey)(id.NewPrivKey()), params.Params, true)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = zecutil("sign", "-wif", otherWIF.String(), path)
+			Expect(err).To(MatchError(ContainSubstring("bad signatures")))
+
+			mainnetWIF, err := btcutil.NewWIF((*btcec.PrivateKey)(privKey), zcash.MainNetParams.Params, true)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = zecutil("sign", "-wif", mainnetWIF.String(), path)
+			Expect(err).To(MatchError(ContainSubstring("not for regtest")))
+		})
+	})
+
+	Context("when estimating fees", func() {
+		It("should convert the estimate to zatoshis-per-byte", func() {
+			stdout, err := zecutil("estimate-fee")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stdout).To(Equal("10 zatoshis-per-byte\n"))
+		})
+
+		It("should use the fallback if the node cannot estimate the fee", func() {
+			client.feeErr = fmt.Errorf("insufficient data")
+			var result struct {
+				FeeRate  uint64 `json:"feeRate"`
+				Fallback bool   `json:"fallback"`
+			}
+			zecutilJSON(&result, "estimate-fee", "-fallback", "25")
+			Expect(result.FeeRate).To(Equal(uint64(25)))
+			Expect(result.Fallback).To(BeTrue())
+		})
+	})
+
+	Context("when parsing global flags", func() {
+		It("should reject unknown networks, outputs, and commands", func() {
+			Expect(run([]string{"-network", "foo", "help"}, new(bytes.Buffer), new(bytes.Buffer))).ToNot(Succeed())
+			Expect(run([]string{"-output", "yaml", "help"}, new(bytes.Buffer), new(bytes.Buffer))).ToNot(Succeed())
+			_, err := zecutil("foo")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should print the usage", func() {
+			stdout, err := zecutil("help")
+			Expect(err).ToNot(HaveOccurred())
+			for _, cmd := range commands {
+				Expect(stdout).To(ContainSubstring(cmd.name))
+			}
+		})
+	})
+})
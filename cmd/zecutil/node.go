@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/pack"
+)
+
+func listUnspentOutputs(e *env, args []string) error {
+	flags := e.newFlagSet()
+	minConf := flags.Int64("min-conf", 1, "minimum number of confirmations")
+	maxConf := flags.Int64("max-conf", 9999999, "maximum number of confirmations")
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	addr := address.Address(flags.Arg(0))
+	if _, err := newAddressInfo(e, addr); err != nil {
+		return err
+	}
+
+	client, ctx, cancel := e.client()
+	defer cancel()
+	outputs, err := client.UnspentOutputs(ctx, *minConf, *maxConf, addr)
+	if err != nil {
+		return err
+	}
+	outputsJSON := make([]outputJSON, len(outputs))
+	total := int64(0)
+	for i, output := range outputs {
+		outputsJSON[i] = newOutputJSON(output)
+		total += outputsJSON[i].Value
+	}
+	return e.print(outputsJSON, func(w io.Writer) {
+		for _, output := range outputsJSON {
+			fmt.Fprintf(w, "%v:%v %v\n", output.TxID, output.Vout, zcash.Amount(output.Value))
+		}
+		fmt.Fprintf(w, "total: %v in %v outputs\n", zcash.Amount(total), len(outputsJSON))
+	})
+}
+
+func estimateFee(e *env, args []string) error {
+	flags := e.newFlagSet()
+	numBlocks := flags.Int64("blocks", 1, "target number of blocks within which the transaction is confirmed")
+	fallback := flags.Uint64("fallback", 10, "fee rate in zatoshis-per-byte that is used if the node cannot estimate the fee rate")
+	if err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	client, ctx, cancel := e.client()
+	defer cancel()
+	feeRate, _, err := zcash.NewGasEstimator(client, *numBlocks, pack.NewU256FromUint64(*fallback)).EstimateGas(ctx)
+	result := struct {
+		FeeRate  uint64 `json:"feeRate"`
+		Fallback bool   `json:"fallback"`
+	}{feeRate.Int().Uint64(), err != nil}
+	if err != nil {
+		fmt.Fprintf(e.stderr, "zecutil: using the fallback fee rate: %v\n", err)
+	}
+	return e.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%v zatoshis-per-byte\n", result.FeeRate)
+	})
+}
+
+func broadcastTx(e *env, args []string) error {
+	flags := e.newFlagSet()
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	data, f, err := readTx(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		if f.Network != e.network {
+			return fmt.Errorf("bad network: expected %v, got %v", e.network, f.Network)
+		}
+		if f.SignedTx == "" {
+			return fmt.Errorf("bad tx: not signed")
+		}
+	}
+	decoded, err := zcash.DecodeTx(data)
+	if err != nil {
+		return err
+	}
+	txID, err := decoded.TxID()
+	if err != nil {
+		return fmt.Errorf("bad txid: %v", err)
+	}
+
+	client, ctx, cancel := e.client()
+	defer cancel()
+	if err := client.SubmitTx(ctx, rawTx{decoded}); err != nil {
+		return err
+	}
+	result := struct {
+		TxID string `json:"txid"`
+	}{txID.String()}
+	return e.print(result, func(w io.Writer) {
+		fmt.Fprintln(w, result.TxID)
+	})
+}
+
+// rawTx is a utxo.Tx for a decoded transaction. It can only be serialized, so
+// that it can be submitted using the client.
+type rawTx struct {
+	*zcash.DecodedTx
+}
+
+// Hash implements the utxo.Tx interface.
+func (tx rawTx) Hash() (pack.Bytes, error) {
+	txID, err := tx.TxID()
+	if err != nil {
+		return nil, err
+	}
+	return pack.NewBytes(txID[:]), nil
+}
+
+// Inputs implements the utxo.Tx interface.
+func (tx rawTx) Inputs() ([]utxo.Input, error) {
+	return nil, fmt.Errorf("not supported for decoded transactions")
+}
+
+// Outputs implements the utxo.Tx interface.
+func (tx rawTx) Outputs() ([]utxo.Output, error) {
+	return nil, fmt.Errorf("not supported for decoded transactions")
+}
+
+// Sighashes implements the utxo.Tx interface.
+func (tx rawTx) Sighashes() ([]pack.Bytes32, error) {
+	return nil, fmt.Errorf("not supported for decoded transactions")
+}
+
+// Sign implements the utxo.Tx interface.
+func (tx rawTx) Sign([]pack.Bytes65, pack.Bytes) error {
+	return fmt.Errorf("not supported for decoded transactions")
+}
+
+// Serialize implements the utxo.Tx interface.
+func (tx rawTx) Serialize() (pack.Bytes, error) {
+	return pack.Bytes(tx.DecodedTx.Serialize()), nil
+}
@@ -0,0 +1,572 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/pack"
+)
+
+// maxFeeIterations bounds the number of times that the build command re-selects
+// inputs when paying a fee rate, since the fee depends on the size of the
+// transaction, which depends on the number of inputs.
+const maxFeeIterations = 10
+
+// A txFile holds a transaction, and everything that is needed to rebuild it, so
+// that its sighashes can be checked before it is signed. It is written by the
+// build command, and read by the sighashes, sign, decode, and broadcast
+// commands. Values are in zatoshis.
+type txFile struct {
+	Network      string          `json:"network"`
+	ExpiryHeight uint32          `json:"expiryHeight"`
+	LockTime     uint32          `json:"lockTime"`
+	Inputs       []outputJSON    `json:"inputs"`
+	Recipients   []recipientJSON `json:"recipients"`
+	Fee          int64           `json:"fee"`
+	TxID         string          `json:"txid"`
+	Tx           string          `json:"tx"`
+	SignedTx     string          `json:"signedTx,omitempty"`
+}
+
+// An outputJSON is an unspent output, with its txid in the byte order used by
+// zcashd. It is the format of the utxos command, and of the inputs of a txFile.
+type outputJSON struct {
+	TxID         string `json:"txid"`
+	Vout         uint32 `json:"vout"`
+	Value        int64  `json:"value"`
+	PubKeyScript string `json:"pubKeyScript"`
+}
+
+// A recipientJSON is a recipient of a txFile.
+type recipientJSON struct {
+	Address string `json:"address"`
+	Value   int64  `json:"value"`
+}
+
+func newOutputJSON(output utxo.Output) outputJSON {
+	var hash chainhash.Hash
+	copy(hash[:], output.Outpoint.Hash)
+	return outputJSON{
+		TxID:         hash.String(),
+		Vout:         output.Outpoint.Index.Uint32(),
+		Value:        output.Value.Int().Int64(),
+		PubKeyScript: hex.EncodeToString(output.PubKeyScript),
+	}
+}
+
+func (output outputJSON) output() (utxo.Output, error) {
+	hash, err := chainhash.NewHashFromStr(output.TxID)
+	if err != nil {
+		return utxo.Output{}, fmt.Errorf("bad txid: %v", err)
+	}
+	if output.Value < 0 {
+		return utxo.Output{}, fmt.Errorf("bad value: expected value >= 0, got value = %v", output.Value)
+	}
+	script, err := hex.DecodeString(output.PubKeyScript)
+	if err != nil {
+		return utxo.Output{}, fmt.Errorf("bad pubkey script: %v", err)
+	}
+	return utxo.Output{
+		Outpoint: utxo.Outpoint{
+			Hash:  pack.NewBytes(hash[:]),
+			Index: pack.NewU32(output.Vout),
+		},
+		Value:        pack.NewU256FromUint64(uint64(output.Value)),
+		PubKeyScript: pack.NewBytes(script),
+	}, nil
+}
+
+// recipientsFlag is a repeated flag of the form <address>=<zec>.
+type recipientsFlag []recipientJSON
+
+func (recipients *recipientsFlag) String() string {
+	parts := make([]string, len(*recipients))
+	for i, recipient := range *recipients {
+		parts[i] = fmt.Sprintf("%v=%v", recipient.Address, zcash.Amount(recipient.Value))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (recipients *recipientsFlag) Set(value string) error {
+	i := strings.LastIndex(value, "=")
+	if i < 0 {
+		return fmt.Errorf("expected <address>=<zec>, got %q", value)
+	}
+	amount, err := zcash.ParseAmount(value[i+1:])
+	if err != nil {
+		return fmt.Errorf("bad amount: %v", err)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("bad amount: expected amount > 0, got %v", amount)
+	}
+	*recipients = append(*recipients, recipientJSON{Address: value[:i], Value: int64(amount)})
+	return nil
+}
+
+func buildTx(e *env, args []string) error {
+	flags := e.newFlagSet()
+	var to recipientsFlag
+	flags.Var(&to, "to", "recipient of the form <address>=<zec> (can be repeated)")
+	from := flags.String("from", "", "address that spends its unspent outputs, fetched from the node")
+	utxosPath := flags.String("utxos", "", "file with the unspent outputs that can be spent, as written by the utxos command (instead of -from)")
+	minConf := flags.Int64("min-conf", 1, "minimum number of confirmations of unspent outputs fetched from the node")
+	change := flags.String("change", "", "address that receives the change (defaults to -from)")
+	fee := flags.Int64("fee", -1, "absolute fee in zatoshis (defaults to the ZIP-317 conventional fee)")
+	feeRate := flags.Int64("fee-rate", 0, "fee rate in zatoshis-per-byte (overrides -fee)")
+	expiryHeight := flags.Int64("expiry-height", -1, "expiry height (defaults to the latest block plus 40 if -from is set, and no expiry otherwise)")
+	lockTime := flags.Uint("lock-time", 0, "lock time")
+	out := flags.String("out", "", "file to which the transaction is written (defaults to standard output)")
+	if err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("expected at least one -to recipient")
+	}
+	if (*from == "") == (*utxosPath == "") {
+		return fmt.Errorf("expected exactly one of -from or -utxos")
+	}
+	if *change == "" {
+		*change = *from
+	}
+	if *change == "" {
+		return fmt.Errorf("expected -change when spending -utxos")
+	}
+	if *fee < -1 || *feeRate < 0 {
+		return fmt.Errorf("bad fee: expected fee >= 0")
+	}
+	if *lockTime > uint(^uint32(0)) {
+		return fmt.Errorf("bad lock time: expected lock time <= %v, got %v", ^uint32(0), *lockTime)
+	}
+
+	var outputs []utxo.Output
+	if *from != "" {
+		if _, err := newAddressInfo(e, address.Address(*from)); err != nil {
+			return err
+		}
+		client, ctx, cancel := e.client()
+		defer cancel()
+		var err error
+		if outputs, err = client.UnspentOutputs(ctx, *minConf, 9999999, address.Address(*from)); err != nil {
+			return err
+		}
+		if *expiryHeight < 0 {
+			height, err := client.LatestBlock(ctx)
+			if err != nil {
+				return err
+			}
+			*expiryHeight = int64(zcash.ExpiryHeightFromHeight(uint32(height.Uint64())))
+		}
+	} else {
+		var outputsJSON []outputJSON
+		if err := readJSON(*utxosPath, &outputsJSON); err != nil {
+			return err
+		}
+		for i, outputJSON := range outputsJSON {
+			output, err := outputJSON.output()
+			if err != nil {
+				return fmt.Errorf("bad utxo %v: %v", i, err)
+			}
+			outputs = append(outputs, output)
+		}
+	}
+	if *expiryHeight < 0 {
+		*expiryHeight = 0
+	}
+	if *expiryHeight > zcash.MaxExpiryHeight {
+		return fmt.Errorf("bad expiry height: expected expiry height <= %v, got %v", zcash.MaxExpiryHeight, *expiryHeight)
+	}
+
+	f := txFile{
+		Network:      e.network,
+		ExpiryHeight: uint32(*expiryHeight),
+		LockTime:     uint32(*lockTime),
+	}
+	tx, err := f.fund(e.params, outputs, to, address.Address(*change), *fee, *feeRate)
+	if err != nil {
+		return err
+	}
+	if err := f.setTx(tx); err != nil {
+		return err
+	}
+	return e.writeTxFile(*out, f)
+}
+
+// fund selects the inputs and the change of the transaction, largest outputs
+// first, so that the transaction pays the recipients and the fee. If the fee
+// rate is not zero, the fee is the fee rate multiplied by the estimated size of
+// the signed transaction. Otherwise, if the fee is negative, the fee is the
+// ZIP-317 conventional fee of the selected inputs and outputs. Change that is
+// below the dust threshold is added to the fee.
+func (f *txFile) fund(params *zcash.Params, outputs []utxo.Output, to []recipientJSON, change address.Address, fee, feeRate int64) (*zcash.Tx, error) {
+	outputs = append([]utxo.Output{}, outputs...)
+	sort.SliceStable(outputs, func(i, j int) bool {
+		return outputs[j].Value.LessThan(outputs[i].Value)
+	})
+	value := int64(0)
+	for _, recipient := range to {
+		value += recipient.Value
+	}
+	conventional := fee < 0 && feeRate == 0
+	if feeRate > 0 || conventional {
+		fee = 0
+	}
+
+	for i := 0; i < maxFeeIterations; i++ {
+		f.Inputs = f.Inputs[:0]
+		total := int64(0)
+		for _, output := range outputs {
+			if total >= value+fee {
+				break
+			}
+			f.Inputs = append(f.Inputs, newOutputJSON(output))
+			total += output.Value.Int().Int64()
+		}
+		if total < value+fee {
+			return nil, fmt.Errorf("insufficient funds: expected %v, got %v", zcash.Amount(value+fee), zcash.Amount(total))
+		}
+		f.Recipients = append([]recipientJSON{}, to...)
+		if total-value-fee >= zcash.DefaultDustThreshold {
+			f.Recipients = append(f.Recipients, recipientJSON{Address: string(change), Value: total - value - fee})
+		}
+
+		tx, err := f.build(params)
+		if err != nil {
+			return nil, err
+		}
+		var required int64
+		switch {
+		case feeRate > 0:
+			size, err := tx.EstimateSize()
+			if err != nil {
+				return nil, fmt.Errorf("bad size: %v", err)
+			}
+			required = int64(size) * feeRate
+		case conventional:
+			logicalActions := zcash.LogicalActions(zcash.P2PKHStandardInputSize*len(f.Inputs), zcash.P2PKHStandardOutputSize*len(f.Recipients), 0)
+			required = zcash.ConventionalFee(logicalActions).Int().Int64()
+		default:
+			return tx, nil
+		}
+		if required <= fee {
+			return tx, nil
+		}
+		fee = required
+	}
+	return nil, fmt.Errorf("bad fee: did not converge after %v iterations", maxFeeIterations)
+}
+
+// build builds the unsigned transaction that is described by the file.
+func (f *txFile) build(params *zcash.Params) (*zcash.Tx, error) {
+	inputs := make([]utxo.Input, len(f.Inputs))
+	for i, inputJSON := range f.Inputs {
+		output, err := inputJSON.output()
+		if err != nil {
+			return nil, fmt.Errorf("bad input %v: %v", i, err)
+		}
+		inputs[i] = utxo.Input{Output: output}
+	}
+	recipients := make([]utxo.Recipient, len(f.Recipients))
+	for i, recipient := range f.Recipients {
+		if recipient.Value < 0 {
+			return nil, fmt.Errorf("bad recipient %v: expected value >= 0, got value = %v", i, recipient.Value)
+		}
+		recipients[i] = utxo.Recipient{
+			To:    address.Address(recipient.Address),
+			Value: pack.NewU256FromUint64(uint64(recipient.Value)),
+		}
+	}
+	tx, err := zcash.NewConfigurableTxBuilder(params, f.ExpiryHeight).
+		WithLockTime(f.LockTime).
+		BuildTx(inputs, recipients)
+	if err != nil {
+		return nil, err
+	}
+	return tx.(*zcash.Tx), nil
+}
+
+// rebuild builds the unsigned transaction that is described by the file, and
+// checks that it is the transaction in the file, so that the sighashes of the
+// transaction are known to spend the inputs in the file.
+func (f *txFile) rebuild(e *env) (*zcash.Tx, error) {
+	if f.Network != e.network {
+		return nil, fmt.Errorf("bad network: expected %v, got %v", e.network, f.Network)
+	}
+	tx, err := f.build(e.params)
+	if err != nil {
+		return nil, err
+	}
+	serialized, err := tx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	if !equalHex(hex.EncodeToString(serialized), f.Tx) {
+		return nil, fmt.Errorf("bad tx: does not match its inputs and recipients")
+	}
+	return tx, nil
+}
+
+// setTx sets the unsigned transaction, its txid, and its fee.
+func (f *txFile) setTx(tx *zcash.Tx) error {
+	serialized, err := tx.Serialize()
+	if err != nil {
+		return err
+	}
+	txID, err := txIDOf(serialized)
+	if err != nil {
+		return err
+	}
+	f.Tx = hex.EncodeToString(serialized)
+	f.TxID = txID
+	f.Fee = tx.Fee().Int().Int64()
+	return nil
+}
+
+func printSighashes(e *env, args []string) error {
+	flags := e.newFlagSet()
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	var f txFile
+	if err := readJSON(flags.Arg(0), &f); err != nil {
+		return err
+	}
+	tx, err := f.rebuild(e)
+	if err != nil {
+		return err
+	}
+	sighashes, err := tx.Sighashes()
+	if err != nil {
+		return fmt.Errorf("bad sighashes: %v", err)
+	}
+	hexes := make([]string, len(sighashes))
+	for i, sighash := range sighashes {
+		hexes[i] = hex.EncodeToString(sighash[:])
+	}
+	return e.print(hexes, func(w io.Writer) {
+		for i, sighash := range hexes {
+			fmt.Fprintf(w, "%v %v\n", i, sighash)
+		}
+	})
+}
+
+func signTx(e *env, args []string) error {
+	flags := e.newFlagSet()
+	wifFlag := flags.String("wif", "", "private key in WIF (or set ZECUTIL_WIF)")
+	out := flags.String("out", "", "file to which the signed transaction is written (defaults to standard output)")
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	if *wifFlag == "" {
+		*wifFlag = os.Getenv("ZECUTIL_WIF")
+	}
+	if *wifFlag == "" {
+		return fmt.Errorf("expected -wif")
+	}
+	wif, err := btcutil.DecodeWIF(*wifFlag)
+	if err != nil {
+		return fmt.Errorf("bad wif: %v", err)
+	}
+	if !wif.IsForNet(e.params.Params) {
+		return fmt.Errorf("bad wif: not for %v", e.network)
+	}
+
+	var f txFile
+	if err := readJSON(flags.Arg(0), &f); err != nil {
+		return err
+	}
+	if f.SignedTx != "" {
+		return fmt.Errorf("bad tx: already signed")
+	}
+	tx, err := f.rebuild(e)
+	if err != nil {
+		return err
+	}
+	if err := signer.SignTx(context.Background(), signer.NewKeySignerFromWIF(wif), tx); err != nil {
+		return err
+	}
+	if _, err := tx.Verify(); err != nil {
+		return fmt.Errorf("bad signatures: %v", err)
+	}
+	serialized, err := tx.Serialize()
+	if err != nil {
+		return err
+	}
+	if f.TxID, err = txIDOf(serialized); err != nil {
+		return err
+	}
+	f.SignedTx = hex.EncodeToString(serialized)
+	return e.writeTxFile(*out, f)
+}
+
+func decodeTx(e *env, args []string) error {
+	flags := e.newFlagSet()
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	data, f, err := readTx(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var inputs []utxo.Input
+	if f != nil {
+		if f.Network != e.network {
+			return fmt.Errorf("bad network: expected %v, got %v", e.network, f.Network)
+		}
+		for i, inputJSON := range f.Inputs {
+			output, err := inputJSON.output()
+			if err != nil {
+				return fmt.Errorf("bad input %v: %v", i, err)
+			}
+			inputs = append(inputs, utxo.Input{Output: output})
+		}
+	}
+	explanation, err := zcash.ExplainRawTx(data, inputs, e.params)
+	if err != nil {
+		return err
+	}
+	return e.print(explanation, func(w io.Writer) { printExplanation(w, explanation) })
+}
+
+func printExplanation(w io.Writer, explanation zcash.Explanation) {
+	fmt.Fprintf(w, "txid:     %v\n", explanation.TxID)
+	fmt.Fprintf(w, "version:  %v (%v)\n", explanation.Version, explanation.VersionGroupID)
+	fmt.Fprintf(w, "branch:   %v\n", explanation.ConsensusBranchID)
+	fmt.Fprintf(w, "size:     %v bytes\n", explanation.Size)
+	fmt.Fprintf(w, "locktime: %v\n", explanation.LockTime)
+	fmt.Fprintf(w, "expiry:   %v\n", explanation.ExpiryHeight)
+	if explanation.Fee != nil {
+		fmt.Fprintf(w, "fee:      %v\n", zcash.Amount(*explanation.Fee))
+	}
+	fmt.Fprintf(w, "inputs:\n")
+	for i, input := range explanation.Inputs {
+		fmt.Fprintf(w, "  %v: %v:%v", i, input.TxID, input.Vout)
+		if input.Value != nil {
+			fmt.Fprintf(w, " %v", zcash.Amount(*input.Value))
+		}
+		if input.ScriptSig.Hex == "" {
+			fmt.Fprintf(w, " (unsigned)")
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "outputs:\n")
+	for _, output := range explanation.Outputs {
+		to := output.ScriptPubKey.Address
+		if to == "" {
+			to = output.ScriptPubKey.Type
+		}
+		fmt.Fprintf(w, "  %v: %v %v\n", output.N, to, zcash.Amount(output.Value))
+	}
+	if explanation.SaplingSpends+explanation.SaplingOutputs+explanation.JoinSplits+explanation.OrchardActions > 0 {
+		fmt.Fprintf(w, "shielded: %v sapling spends, %v sapling outputs, %v joinsplits, %v orchard actions\n",
+			explanation.SaplingSpends, explanation.SaplingOutputs, explanation.JoinSplits, explanation.OrchardActions)
+	}
+}
+
+// readTx reads a serialized transaction from a hex string, a file with a hex
+// string, or a txFile. The txFile is returned if there is one, and the signed
+// transaction is preferred over the unsigned transaction.
+func readTx(arg string) ([]byte, *txFile, error) {
+	text := arg
+	if _, err := hex.DecodeString(arg); err != nil {
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		text = string(data)
+	}
+	text = strings.TrimSpace(text)
+
+	var f *txFile
+	if strings.HasPrefix(text, "{") {
+		f = new(txFile)
+		if err := json.Unmarshal([]byte(text), f); err != nil {
+			return nil, nil, fmt.Errorf("bad tx file: %v", err)
+		}
+		text = f.Tx
+		if f.SignedTx != "" {
+			text = f.SignedTx
+		}
+	}
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad tx: %v", err)
+	}
+	return data, f, nil
+}
+
+// txIDOf returns the txid of a serialized transaction, in the byte order used
+// by zcashd.
+func txIDOf(serialized []byte) (string, error) {
+	decoded, err := zcash.DecodeTx(serialized)
+	if err != nil {
+		return "", err
+	}
+	txID, err := decoded.TxID()
+	if err != nil {
+		return "", fmt.Errorf("bad txid: %v", err)
+	}
+	return txID.String(), nil
+}
+
+// writeTxFile writes the file as indented JSON to the path, or to standard
+// output if the path is empty. If it is written to the path, a summary of the
+// transaction is written to standard output.
+func (e *env) writeTxFile(path string, f txFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err := e.stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	summary := struct {
+		File   string `json:"file"`
+		TxID   string `json:"txid"`
+		Fee    int64  `json:"fee"`
+		Signed bool   `json:"signed"`
+	}{path, f.TxID, f.Fee, f.SignedTx != ""}
+	return e.print(summary, func(w io.Writer) {
+		state := "unsigned"
+		if summary.Signed {
+			state = "signed"
+		}
+		fmt.Fprintf(w, "wrote %v tx %v to %v (fee %v)\n", state, summary.TxID, path, zcash.Amount(summary.Fee))
+	})
+}
+
+// readJSON decodes the JSON in the file at the path.
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("bad %v: %v", path, err)
+	}
+	return nil
+}
+
+// equalHex returns true if the hex strings encode the same bytes.
+func equalHex(a, b string) bool {
+	x, errX := hex.DecodeString(a)
+	y, errY := hex.DecodeString(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
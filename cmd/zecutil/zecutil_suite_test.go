@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestZecutil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Zecutil Suite")
+}
@@ -0,0 +1,492 @@
+// Package consolidate sweeps the unspent outputs of a set of addresses into a
+// single destination. Addresses that receive many small deposits accumulate
+// outputs that make later transactions large, and expensive. Sweeping those
+// outputs while fees are low means that they are only paid for once, at the
+// low fee rate.
+//
+// Each sweep spends the outputs of one source, since all of the sighashes of a
+// transaction are signed by the same signer. Sweeps are capped by the maximum
+// standard size of a transaction, and outputs that are worth less than the fee
+// needed to spend them are never swept.
+//
+// Sweeping only saves fees on chains where the fee is paid at a fee rate that
+// changes over time. The ZIP-317 conventional fee of Zcash (the default) does
+// not depend on a fee rate: each swept output pays the marginal fee, which is
+// at least what it would pay if it were spent later, and the swept output pays
+// it again when it is spent. So Zcash sweeps save nothing, and the maximum fee
+// rate does not bound what they pay. They are only useful to keep later
+// transactions small, so that they need fewer signatures and fit within the
+// maximum size.
+package consolidate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/gas"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/keys"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultMaxSize is the maximum size (in bytes) of a sweep. This is the
+	// maximum standard size of a transaction for zcashd, and the maximum
+	// standard virtual size of a transaction for bitcoind.
+	DefaultMaxSize = 100000
+	// DefaultMinInputs is the minimum number of outputs that are swept by a
+	// transaction. Sweeping a single output into its own address only pays a
+	// fee.
+	DefaultMinInputs = 2
+	// DefaultMinConf is the number of confirmations that an output needs
+	// before it is swept.
+	DefaultMinConf = 1
+	// DefaultInterval is the interval at which Run checks whether fees are
+	// low enough to sweep.
+	DefaultInterval = 10 * time.Minute
+	// DefaultRPCTimeout is the timeout of each call to the client and the gas
+	// estimator.
+	DefaultRPCTimeout = 10 * time.Second
+	// DefaultDustThreshold is the default minimum value (in zatoshi) of a
+	// swept output. It is the ZIP-317 marginal fee, since an output worth less
+	// than that costs more to spend than it is worth.
+	DefaultDustThreshold = zcash.MarginalFee
+)
+
+// ErrFeeRateTooHigh is returned when the estimated fee rate is above the
+// maximum fee rate at which outputs are swept.
+var ErrFeeRateTooHigh = errors.New("fee rate too high")
+
+// The Client interface defines the functionality required to list the outputs
+// of an address, and to submit sweeps. It is implemented by the bitcoin and
+// zcash clients.
+type Client interface {
+	UnspentOutputs(ctx context.Context, minConf, maxConf int64, address address.Address) ([]utxo.Output, error)
+	SubmitTx(ctx context.Context, tx utxo.Tx) error
+}
+
+// A Source is an address whose outputs are swept, and the signer that can sign
+// for them.
+type Source struct {
+	Address address.Address
+	Signer  signer.Signer
+}
+
+// KeySource returns the Source for the address of a private key, as encoded by
+// the address function.
+func KeySource(privKey *btcec.PrivateKey, encode keys.AddressFunc) (Source, error) {
+	addr, err := encode(privKey.PubKey())
+	if err != nil {
+		return Source{}, fmt.Errorf("bad address: %v", err)
+	}
+	return Source{Address: addr, Signer: signer.NewKeySigner(privKey, true)}, nil
+}
+
+// FeeFunc re-exports bitcoin.FeeFunc.
+type FeeFunc = bitcoin.FeeFunc
+
+// LinearFee re-exports bitcoin.LinearFee.
+func LinearFee(feeRate pack.U256, numInputs, numOutputs int) pack.U256 {
+	return bitcoin.LinearFee(feeRate, numInputs, numOutputs)
+}
+
+// ConventionalFee re-exports zcash.P2PKHConventionalFee.
+func ConventionalFee(feeRate pack.U256, numInputs, numOutputs int) pack.U256 {
+	return zcash.P2PKHConventionalFee(feeRate, numInputs, numOutputs)
+}
+
+// Options are used to parameterise the behaviour of the Consolidator.
+type Options struct {
+	// MaxSize is the maximum size of a sweep, and MaxInputs is the maximum
+	// number of outputs that it spends. A MaxInputs that is not positive
+	// leaves the number of outputs bounded only by the size.
+	MaxSize   int
+	MaxInputs int
+	MinInputs int
+	MinConf   int64
+	// MaxFeeRate is the fee rate above which outputs are not swept. It has no
+	// default, since the fee rate that counts as low depends on the chain.
+	// The ZIP-317 conventional fee ignores the fee rate, so for Zcash it only
+	// gates sweeps on the fee rate estimated by the node, and does not bound
+	// their fees.
+	MaxFeeRate pack.U256
+	// ReferenceFeeRate is the fee rate at which the outputs would be spent if
+	// they were not swept, and is used to report the fee saved by sweeping. A
+	// zero ReferenceFeeRate uses the current fee rate. Nothing is saved under
+	// the ZIP-317 conventional fee, whatever the reference fee rate.
+	ReferenceFeeRate pack.U256
+	Fee              FeeFunc
+	DustThreshold    pack.U256
+	Interval         time.Duration
+	RPCTimeout       time.Duration
+}
+
+// DefaultOptions returns Options with the default settings for Zcash, which
+// pay the ZIP-317 conventional fee, and so save no fees. The maximum fee rate
+// must be set. Bitcoin-like chains should use the LinearFee, and a dust threshold suitable
+// for the chain.
+func DefaultOptions() Options {
+	return Options{
+		MaxSize:       DefaultMaxSize,
+		MinInputs:     DefaultMinInputs,
+		MinConf:       DefaultMinConf,
+		MaxFeeRate:    pack.NewU256FromUint64(0),
+		Fee:           ConventionalFee,
+		DustThreshold: pack.NewU256FromUint64(DefaultDustThreshold),
+		Interval:      DefaultInterval,
+		RPCTimeout:    DefaultRPCTimeout,
+	}
+}
+
+// WithMaxSize sets the maximum size of a sweep.
+func (opts Options) WithMaxSize(maxSize int) Options {
+	opts.MaxSize = maxSize
+	return opts
+}
+
+// WithMaxInputs sets the maximum number of outputs that are spent by a sweep.
+func (opts Options) WithMaxInputs(maxInputs int) Options {
+	opts.MaxInputs = maxInputs
+	return opts
+}
+
+// WithMinInputs sets the minimum number of outputs that are spent by a sweep.
+func (opts Options) WithMinInputs(minInputs int) Options {
+	opts.MinInputs = minInputs
+	return opts
+}
+
+// WithMinConf sets the number of confirmations that an output needs before it
+// is swept.
+func (opts Options) WithMinConf(minConf int64) Options {
+	opts.MinConf = minConf
+	return opts
+}
+
+// WithMaxFeeRate sets the fee rate above which outputs are not swept.
+func (opts Options) WithMaxFeeRate(maxFeeRate pack.U256) Options {
+	opts.MaxFeeRate = maxFeeRate
+	return opts
+}
+
+// WithReferenceFeeRate sets the fee rate against which the fee saved by a sweep
+// is reported.
+func (opts Options) WithReferenceFeeRate(referenceFeeRate pack.U256) Options {
+	opts.ReferenceFeeRate = referenceFeeRate
+	return opts
+}
+
+// WithFee sets the function used to compute the fee of sweeps.
+func (opts Options) WithFee(fee FeeFunc) Options {
+	opts.Fee = fee
+	return opts
+}
+
+// WithDustThreshold sets the minimum value of a swept output.
+func (opts Options) WithDustThreshold(dustThreshold pack.U256) Options {
+	opts.DustThreshold = dustThreshold
+	return opts
+}
+
+// WithInterval sets the interval at which Run checks whether fees are low
+// enough to sweep.
+func (opts Options) WithInterval(interval time.Duration) Options {
+	opts.Interval = interval
+	return opts
+}
+
+// WithRPCTimeout sets the timeout of each call to the client and the gas
+// estimator.
+func (opts Options) WithRPCTimeout(rpcTimeout time.Duration) Options {
+	opts.RPCTimeout = rpcTimeout
+	return opts
+}
+
+// A Sweep is a transaction that spends outputs of a source, and pays their
+// value (less the fee) into the destination.
+type Sweep struct {
+	Source address.Address
+	Inputs []utxo.Output
+	// Value is the value paid into the destination.
+	Value pack.U256
+	Fee   pack.U256
+	// Saved is the fee that would be paid for spending the inputs at the
+	// reference fee rate, less the fee of the sweep and the fee of spending
+	// its output at the reference fee rate. It is zero if sweeping saves
+	// nothing, which is always the case under the ZIP-317 conventional fee
+	// (see the package documentation).
+	Saved  pack.U256
+	Tx     utxo.Tx
+	TxHash pack.Bytes
+}
+
+// A Report describes the sweeps that were planned, or submitted.
+type Report struct {
+	FeeRate pack.U256
+	Sweeps  []Sweep
+	// Skipped are the outputs that are not swept, either because they are
+	// worth less than the fee needed to spend them, or because there are too
+	// few of them.
+	Skipped []utxo.Output
+}
+
+// Fee returns the total fee of the sweeps.
+func (report Report) Fee() pack.U256 {
+	fee := pack.NewU256FromUint64(0)
+	for _, sweep := range report.Sweeps {
+		fee = fee.Add(sweep.Fee)
+	}
+	return fee
+}
+
+// Saved returns the total fee saved by the sweeps.
+func (report Report) Saved() pack.U256 {
+	saved := pack.NewU256FromUint64(0)
+	for _, sweep := range report.Sweeps {
+		saved = saved.Add(sweep.Saved)
+	}
+	return saved
+}
+
+// A Consolidator sweeps the outputs of sources into a destination, using
+// transactions built by the transaction builder, and fee rates estimated by
+// the gas estimator.
+type Consolidator struct {
+	opts         Options
+	client       Client
+	txBuilder    utxo.TxBuilder
+	gasEstimator gas.Estimator
+	maxInputs    int
+}
+
+// New returns a Consolidator. The maximum number of outputs spent by a sweep
+// is the smaller of the maximum number of inputs, and the number of P2PKH
+// inputs that fit in the maximum size.
+func New(client Client, txBuilder utxo.TxBuilder, gasEstimator gas.Estimator, opts Options) (Consolidator, error) {
+	maxInputs := (opts.MaxSize - bitcoin.EstimateP2PKHSize(0, 1)) / bitcoin.P2PKHInputSize
+	if opts.MaxInputs > 0 && opts.MaxInputs < maxInputs {
+		maxInputs = opts.MaxInputs
+	}
+	if maxInputs <= 0 {
+		return Consolidator{}, fmt.Errorf("bad max size: expected room for at least one input, got %v bytes", opts.MaxSize)
+	}
+	if opts.MinInputs <= 0 || opts.MinInputs > maxInputs {
+		return Consolidator{}, fmt.Errorf("bad min inputs: expected between 1 and %v, got %v", maxInputs, opts.MinInputs)
+	}
+	if opts.Fee == nil {
+		return Consolidator{}, fmt.Errorf("bad fee: expected non-nil")
+	}
+	if opts.MaxFeeRate.Equal(pack.NewU256FromUint64(0)) {
+		return Consolidator{}, fmt.Errorf("bad max fee rate: expected greater than zero")
+	}
+	if opts.Interval <= 0 || opts.RPCTimeout <= 0 {
+		return Consolidator{}, fmt.Errorf("bad intervals: expected greater than zero")
+	}
+	return Consolidator{
+		opts:         opts,
+		client:       client,
+		txBuilder:    txBuilder,
+		gasEstimator: gasEstimator,
+		maxInputs:    maxInputs,
+	}, nil
+}
+
+// MarginalFee returns the fee of adding one more input to a full sweep, at the
+// fee rate. Outputs that are not worth more than this are not swept.
+func (consolidator Consolidator) MarginalFee(feeRate pack.U256) pack.U256 {
+	fee := consolidator.opts.Fee
+	return fee(feeRate, consolidator.maxInputs, 1).Sub(fee(feeRate, consolidator.maxInputs-1, 1))
+}
+
+// Run sweeps the sources into the destination at every interval, until the
+// context is done. Sweeps are only submitted while the estimated fee rate is
+// not above the maximum fee rate, so consolidation happens when fees are low
+// (on chains whose fees depend on the fee rate).
+func (consolidator Consolidator) Run(ctx context.Context, destination address.Address, sources ...Source) {
+	ticker := time.NewTicker(consolidator.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := consolidator.Sweep(ctx, destination, sources...)
+		for _, sweep := range report.Sweeps {
+			log.Printf("swept %v outputs from %v in %v: value %v, fee %v, saved %v", len(sweep.Inputs), sweep.Source, sweep.TxHash, sweep.Value, sweep.Fee, sweep.Saved)
+		}
+		if err != nil && !errors.Is(err, ErrFeeRateTooHigh) {
+			log.Printf("sweeping outputs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep plans the sweeps of the sources into the destination, and signs and
+// submits them. If the estimated fee rate is above the maximum fee rate,
+// ErrFeeRateTooHigh is returned and nothing is swept. If a sweep cannot be
+// submitted, the report of the sweeps that were submitted is returned with the
+// error.
+func (consolidator Consolidator) Sweep(ctx context.Context, destination address.Address, sources ...Source) (Report, error) {
+	feeRate, err := consolidator.estimateFeeRate(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	if feeRate.GreaterThan(consolidator.opts.MaxFeeRate) {
+		return Report{FeeRate: feeRate}, fmt.Errorf("%w: expected at most %v, got %v", ErrFeeRateTooHigh, consolidator.opts.MaxFeeRate, feeRate)
+	}
+	planned, err := consolidator.plan(ctx, feeRate, destination, sources)
+	if err != nil {
+		return Report{FeeRate: feeRate}, err
+	}
+
+	signers := make(map[address.Address]signer.Signer, len(sources))
+	for _, source := range sources {
+		signers[source.Address] = source.Signer
+	}
+	report := Report{FeeRate: feeRate, Skipped: planned.Skipped}
+	for _, sweep := range planned.Sweeps {
+		if err := signer.SignTx(ctx, signers[sweep.Source], sweep.Tx); err != nil {
+			return report, fmt.Errorf("signing sweep of %v: %v", sweep.Source, err)
+		}
+		txHash, err := sweep.Tx.Hash()
+		if err != nil {
+			return report, fmt.Errorf("bad tx hash: %v", err)
+		}
+		rpcCtx, cancel := context.WithTimeout(ctx, consolidator.opts.RPCTimeout)
+		err = consolidator.client.SubmitTx(rpcCtx, sweep.Tx)
+		cancel()
+		if err != nil {
+			return report, fmt.Errorf("submitting sweep of %v: %v", sweep.Source, err)
+		}
+		sweep.TxHash = txHash
+		report.Sweeps = append(report.Sweeps, sweep)
+	}
+	return report, nil
+}
+
+// Plan returns the sweeps of the sources into the destination at the
+// estimated fee rate, without signing or submitting them. It ignores the
+// maximum fee rate, so it can be used to preview the sweeps.
+func (consolidator Consolidator) Plan(ctx context.Context, destination address.Address, sources ...Source) (Report, error) {
+	feeRate, err := consolidator.estimateFeeRate(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	return consolidator.plan(ctx, feeRate, destination, sources)
+}
+
+func (consolidator Consolidator) estimateFeeRate(ctx context.Context) (pack.U256, error) {
+	rpcCtx, cancel := context.WithTimeout(ctx, consolidator.opts.RPCTimeout)
+	defer cancel()
+	feeRate, _, err := consolidator.gasEstimator.EstimateGas(rpcCtx)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("estimating fee rate: %v", err)
+	}
+	return feeRate, nil
+}
+
+func (consolidator Consolidator) plan(ctx context.Context, feeRate pack.U256, destination address.Address, sources []Source) (Report, error) {
+	report := Report{FeeRate: feeRate}
+	marginalFee := consolidator.MarginalFee(feeRate)
+	for _, source := range sources {
+		rpcCtx, cancel := context.WithTimeout(ctx, consolidator.opts.RPCTimeout)
+		outputs, err := consolidator.client.UnspentOutputs(rpcCtx, consolidator.opts.MinConf, math.MaxInt32, source.Address)
+		cancel()
+		if err != nil {
+			return report, fmt.Errorf("listing outputs of %v: %v", source.Address, err)
+		}
+
+		// Outputs that are not worth more than the marginal fee would cost
+		// more to sweep than they add to the destination.
+		economical := make([]utxo.Output, 0, len(outputs))
+		for _, output := range outputs {
+			if output.Value.GreaterThan(marginalFee) {
+				economical = append(economical, output)
+			} else {
+				report.Skipped = append(report.Skipped, output)
+			}
+		}
+		sort.SliceStable(economical, func(i, j int) bool {
+			return economical[i].Value.GreaterThan(economical[j].Value)
+		})
+
+		for len(economical) > 0 {
+			n := len(economical)
+			if n > consolidator.maxInputs {
+				n = consolidator.maxInputs
+			}
+			inputs := economical[:n]
+			economical = economical[n:]
+			if len(inputs) < consolidator.opts.MinInputs {
+				report.Skipped = append(report.Skipped, inputs...)
+				continue
+			}
+			sweep, ok, err := consolidator.sweep(feeRate, destination, source.Address, inputs)
+			if err != nil {
+				return report, err
+			}
+			if !ok {
+				report.Skipped = append(report.Skipped, inputs...)
+				continue
+			}
+			report.Sweeps = append(report.Sweeps, sweep)
+		}
+	}
+	return report, nil
+}
+
+// sweep builds the sweep of the inputs into the destination. It returns false
+// if the swept value would be dust.
+func (consolidator Consolidator) sweep(feeRate pack.U256, destination, source address.Address, inputs []utxo.Output) (Sweep, bool, error) {
+	value := pack.NewU256FromUint64(0)
+	txInputs := make([]utxo.Input, len(inputs))
+	for i, input := range inputs {
+		value = value.Add(input.Value)
+		txInputs[i] = utxo.Input{Output: input}
+	}
+	fee := consolidator.opts.Fee(feeRate, len(inputs), 1)
+	if !value.GreaterThan(fee) || value.Sub(fee).LessThan(consolidator.opts.DustThreshold) {
+		return Sweep{}, false, nil
+	}
+	value = value.Sub(fee)
+
+	tx, err := consolidator.txBuilder.BuildTx(txInputs, []utxo.Recipient{{To: destination, Value: value}})
+	if err != nil {
+		return Sweep{}, false, fmt.Errorf("building sweep of %v: %v", source, err)
+	}
+	return Sweep{
+		Source: source,
+		Inputs: append([]utxo.Output(nil), inputs...),
+		Value:  value,
+		Fee:    fee,
+		Saved:  consolidator.saved(feeRate, len(inputs), fee),
+		Tx:     tx,
+	}, true, nil
+}
+
+// saved returns the fee saved by sweeping the number of inputs with the fee,
+// compared to spending them at the reference fee rate.
+func (consolidator Consolidator) saved(feeRate pack.U256, numInputs int, fee pack.U256) pack.U256 {
+	referenceFeeRate := consolidator.opts.ReferenceFeeRate
+	if referenceFeeRate.Equal(pack.NewU256FromUint64(0)) {
+		referenceFeeRate = feeRate
+	}
+	without := consolidator.opts.Fee(referenceFeeRate, numInputs, 1)
+	with := fee.Add(consolidator.opts.Fee(referenceFeeRate, 1, 1))
+	if !without.GreaterThan(with) {
+		return pack.NewU256FromUint64(0)
+	}
+	return without.Sub(with)
+}
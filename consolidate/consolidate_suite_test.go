@@ -0,0 +1,13 @@
+package consolidate_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestConsolidate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Consolidate Suite")
+}
@@ -0,0 +1,344 @@
+package consolidate_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/consolidate"
+	"github.com/pranav292gpt/zecutil/keys"
+	"github.com/pranav292gpt/zecutil/testutil"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Consolidator", func() {
+	params := &chaincfg.RegressionNetParams
+
+	newSource := func() consolidate.Source {
+		privKey, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			panic(err)
+		}
+		source, err := consolidate.KeySource(privKey, keys.BitcoinP2PKH(params))
+		if err != nil {
+			panic(err)
+		}
+		return source
+	}
+	destinationAddr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	if err != nil {
+		panic(err)
+	}
+	destination := address.Address(destinationAddr.EncodeAddress())
+
+	var server *testutil.Server
+	var client bitcoin.Client
+
+	BeforeEach(func() {
+		server = testutil.NewServer(testutil.BitcoinChain(params))
+		client = bitcoin.NewClient(server.ClientOptions())
+		// 10 SATs-per-byte.
+		server.SetFeeRate(1, 0.0001)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	fund := func(source consolidate.Source, values ...int64) {
+		for _, value := range values {
+			_, err := server.Fund(string(source.Address), value)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		server.Mine(1)
+	}
+
+	newConsolidator := func(opts consolidate.Options) consolidate.Consolidator {
+		gasEstimator := bitcoin.NewGasEstimator(client, 1, pack.NewU256FromUint64(1))
+		consolidator, err := consolidate.New(client, bitcoin.NewTxBuilder(params), gasEstimator, opts.WithRPCTimeout(time.Second))
+		Expect(err).ToNot(HaveOccurred())
+		return consolidator
+	}
+
+	swept := func() []utxo.Output {
+		outputs, err := client.UnspentOutputs(context.Background(), 0, math.MaxInt32, destination)
+		Expect(err).ToNot(HaveOccurred())
+		return outputs
+	}
+
+	// options are the options for Bitcoin, which pay 10 SATs-per-byte for the
+	// size of each sweep.
+	options := func() consolidate.Options {
+		return consolidate.DefaultOptions().
+			WithFee(consolidate.LinearFee).
+			WithDustThreshold(pack.NewU256FromUint64(bitcoin.DefaultDustThreshold)).
+			WithMaxFeeRate(pack.NewU256FromUint64(100))
+	}
+
+	fee := func(numInputs int) pack.U256 {
+		return pack.NewU256FromUint64(uint64(10 * bitcoin.EstimateP2PKHSize(numInputs, 1)))
+	}
+
+	Context("when sweeping", func() {
+		It("should sweep each source into the destination", func() {
+			source1, source2 := newSource(), newSource()
+			fund(source1, 10000, 20000, 30000)
+			fund(source2, 40000, 50000)
+			consolidator := newConsolidator(options())
+
+			report, err := consolidator.Sweep(context.Background(), destination, source1, source2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.FeeRate).To(Equal(pack.NewU256FromUint64(10)))
+			Expect(report.Skipped).To(BeEmpty())
+			Expect(report.Sweeps).To(HaveLen(2))
+			Expect(report.Sweeps[0].Source).To(Equal(source1.Address))
+			Expect(report.Sweeps[0].Inputs).To(HaveLen(3))
+			Expect(report.Sweeps[0].Fee).To(Equal(fee(3)))
+			Expect(report.Sweeps[0].Value).To(Equal(pack.NewU256FromUint64(60000).Sub(fee(3))))
+			Expect(report.Sweeps[1].Source).To(Equal(source2.Address))
+			Expect(report.Sweeps[1].Inputs).To(HaveLen(2))
+			Expect(report.Fee()).To(Equal(fee(3).Add(fee(2))))
+			Expect(server.Mempool()).To(HaveLen(2))
+			for _, sweep := range report.Sweeps {
+				Expect(sweep.TxHash).ToNot(BeEmpty())
+			}
+
+			server.Mine(1)
+			Expect(swept()).To(HaveLen(2))
+			outputs, err := client.UnspentOutputs(context.Background(), 0, math.MaxInt32, source1.Address)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(BeEmpty())
+		})
+
+		It("should skip outputs that are not worth more than the marginal fee", func() {
+			source := newSource()
+			marginalFee := int64(10 * bitcoin.P2PKHInputSize)
+			fund(source, 1000, marginalFee, marginalFee+1, 20000, 30000)
+			consolidator := newConsolidator(options())
+			Expect(consolidator.MarginalFee(pack.NewU256FromUint64(10))).To(Equal(pack.NewU256FromUint64(uint64(marginalFee))))
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Skipped).To(HaveLen(2))
+			Expect(report.Sweeps).To(HaveLen(1))
+			Expect(report.Sweeps[0].Inputs).To(HaveLen(3))
+			Expect(report.Sweeps[0].Value).To(Equal(pack.NewU256FromUint64(uint64(50001 + marginalFee)).Sub(fee(3))))
+		})
+
+		It("should cap the number of inputs of each sweep", func() {
+			source := newSource()
+			fund(source, 10000, 20000, 30000, 40000, 50000, 60000, 70000)
+			consolidator := newConsolidator(options().WithMaxInputs(3))
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(HaveLen(2))
+			Expect(report.Sweeps[0].Inputs).To(HaveLen(3))
+			Expect(report.Sweeps[0].Inputs[0].Value).To(Equal(pack.NewU256FromUint64(70000)))
+			Expect(report.Sweeps[1].Inputs).To(HaveLen(3))
+			// The last output is left alone, since sweeping it by itself only
+			// pays a fee.
+			Expect(report.Skipped).To(HaveLen(1))
+			Expect(report.Skipped[0].Value).To(Equal(pack.NewU256FromUint64(10000)))
+			Expect(server.Mempool()).To(HaveLen(2))
+		})
+
+		It("should cap the size of each sweep", func() {
+			source := newSource()
+			fund(source, 10000, 20000, 30000, 40000, 50000)
+			consolidator := newConsolidator(options().WithMaxSize(bitcoin.EstimateP2PKHSize(2, 1)))
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(HaveLen(2))
+			for _, sweep := range report.Sweeps {
+				Expect(sweep.Inputs).To(HaveLen(2))
+				Expect(sweep.Tx.(*bitcoin.Tx).EstimateSize()).To(BeNumerically("<=", bitcoin.EstimateP2PKHSize(2, 1)))
+			}
+		})
+
+		It("should not sweep when the fee rate is too high", func() {
+			source := newSource()
+			fund(source, 10000, 20000)
+			consolidator := newConsolidator(options().WithMaxFeeRate(pack.NewU256FromUint64(5)))
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(errors.Is(err, consolidate.ErrFeeRateTooHigh)).To(BeTrue())
+			Expect(report.FeeRate).To(Equal(pack.NewU256FromUint64(10)))
+			Expect(report.Sweeps).To(BeEmpty())
+			Expect(server.Mempool()).To(BeEmpty())
+
+			server.SetFeeRate(1, 0.00005)
+			report, err = consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(HaveLen(1))
+		})
+
+		It("should report the fee saved against the reference fee rate", func() {
+			source := newSource()
+			fund(source, 10000, 20000, 30000, 40000)
+			consolidator := newConsolidator(options().WithReferenceFeeRate(pack.NewU256FromUint64(50)))
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(HaveLen(1))
+			// Spending the four outputs at 50 SATs-per-byte, less sweeping
+			// them at 10 SATs-per-byte and spending the swept output at 50
+			// SATs-per-byte.
+			saved := 50*bitcoin.EstimateP2PKHSize(4, 1) - 10*bitcoin.EstimateP2PKHSize(4, 1) - 50*bitcoin.EstimateP2PKHSize(1, 1)
+			Expect(report.Sweeps[0].Saved).To(Equal(pack.NewU256FromUint64(uint64(saved))))
+			Expect(report.Saved()).To(Equal(pack.NewU256FromUint64(uint64(saved))))
+		})
+
+		It("should report no fee saved when the fee rate does not change", func() {
+			source := newSource()
+			fund(source, 10000, 20000)
+			consolidator := newConsolidator(options())
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Saved()).To(Equal(pack.NewU256FromUint64(0)))
+		})
+
+		It("should pay the ZIP-317 conventional fee by default", func() {
+			source := newSource()
+			fund(source, 4000, 5000, 5001, 20000, 30000)
+			consolidator := newConsolidator(consolidate.DefaultOptions().WithMaxFeeRate(pack.NewU256FromUint64(100)))
+			Expect(consolidator.MarginalFee(pack.NewU256FromUint64(10))).To(Equal(pack.NewU256FromUint64(5000)))
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Skipped).To(HaveLen(2))
+			Expect(report.Sweeps).To(HaveLen(1))
+			Expect(report.Sweeps[0].Fee).To(Equal(pack.NewU256FromUint64(15000)))
+			Expect(report.Sweeps[0].Value).To(Equal(pack.NewU256FromUint64(55001 - 15000)))
+		})
+
+		It("should report no fee saved by default", func() {
+			source := newSource()
+			fund(source, 10000, 20000, 30000, 40000)
+			consolidator := newConsolidator(consolidate.DefaultOptions().
+				WithMaxFeeRate(pack.NewU256FromUint64(100)).
+				WithReferenceFeeRate(pack.NewU256FromUint64(1000)))
+
+			// Spending the four outputs later pays the marginal fee for each of
+			// them, which the sweep pays now, and spending the swept output
+			// pays it again.
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(HaveLen(1))
+			Expect(report.Sweeps[0].Fee).To(Equal(pack.NewU256FromUint64(20000)))
+			Expect(report.Sweeps[0].Saved).To(Equal(pack.NewU256FromUint64(0)))
+			Expect(report.Saved()).To(Equal(pack.NewU256FromUint64(0)))
+		})
+
+		It("should not sweep outputs into less than the marginal fee by default", func() {
+			source := newSource()
+			fund(source, 5001, 5002)
+			consolidator := newConsolidator(consolidate.DefaultOptions().WithMaxFeeRate(pack.NewU256FromUint64(100)))
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(BeEmpty())
+			Expect(report.Skipped).To(HaveLen(2))
+			Expect(server.Mempool()).To(BeEmpty())
+		})
+
+		It("should not sweep outputs into dust", func() {
+			source := newSource()
+			fund(source, 1500, 1600)
+			consolidator := newConsolidator(options())
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(BeEmpty())
+			Expect(report.Skipped).To(HaveLen(2))
+			Expect(server.Mempool()).To(BeEmpty())
+		})
+
+		It("should not sweep unconfirmed outputs", func() {
+			source := newSource()
+			_, err := server.Fund(string(source.Address), 10000)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = server.Fund(string(source.Address), 20000)
+			Expect(err).ToNot(HaveOccurred())
+			consolidator := newConsolidator(options())
+
+			report, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(BeEmpty())
+		})
+
+		It("should return an error when the fee rate cannot be estimated", func() {
+			source := newSource()
+			fund(source, 10000, 20000)
+			server.SetFeeRate(1, 0)
+			consolidator := newConsolidator(options())
+
+			_, err := consolidator.Sweep(context.Background(), destination, source)
+			Expect(err).To(HaveOccurred())
+			Expect(server.Mempool()).To(BeEmpty())
+		})
+	})
+
+	Context("when planning", func() {
+		It("should not submit the sweeps", func() {
+			source := newSource()
+			fund(source, 10000, 20000)
+			consolidator := newConsolidator(options().WithMaxFeeRate(pack.NewU256FromUint64(5)))
+
+			report, err := consolidator.Plan(context.Background(), destination, source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Sweeps).To(HaveLen(1))
+			Expect(report.Sweeps[0].TxHash).To(BeEmpty())
+			Expect(server.Mempool()).To(BeEmpty())
+		})
+	})
+
+	Context("when running", func() {
+		It("should sweep once the fee rate is low enough", func() {
+			source := newSource()
+			fund(source, 10000, 20000)
+			consolidator := newConsolidator(options().
+				WithMaxFeeRate(pack.NewU256FromUint64(5)).
+				WithInterval(10 * time.Millisecond))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go consolidator.Run(ctx, destination, source)
+
+			Consistently(server.Mempool, 100*time.Millisecond).Should(BeEmpty())
+			server.SetFeeRate(1, 0.00005)
+			Eventually(server.Mempool).Should(HaveLen(1))
+			Consistently(server.Mempool, 100*time.Millisecond).Should(HaveLen(1))
+		})
+	})
+
+	Context("when creating a consolidator", func() {
+		gasEstimator := bitcoin.NewGasEstimator(nil, 1, pack.NewU256FromUint64(1))
+
+		It("should return an error for bad options", func() {
+			for _, opts := range []consolidate.Options{
+				options().WithMaxSize(100),
+				options().WithMinInputs(0),
+				options().WithMaxInputs(2).WithMinInputs(3),
+				options().WithFee(nil),
+				options().WithInterval(0),
+				consolidate.DefaultOptions(),
+			} {
+				_, err := consolidate.New(nil, bitcoin.NewTxBuilder(params), gasEstimator, opts)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+})
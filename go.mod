@@ -12,6 +12,7 @@ require (
 	github.com/renproject/pack v0.2.5
 	github.com/ybbus/jsonrpc v2.1.2+incompatible
 	golang.org/x/crypto v0.0.0-20211202192323-5770296d904e
+	golang.org/x/text v0.3.6
 )
 
 require (
@@ -22,7 +23,6 @@ require (
 	github.com/renproject/surge v1.2.5 // indirect
 	golang.org/x/net v0.0.0-20211112202133-69e39bad7dc2 // indirect
 	golang.org/x/sys v0.0.0-20210903071746-97244b99971b // indirect
-	golang.org/x/text v0.3.6 // indirect
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
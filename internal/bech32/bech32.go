@@ -0,0 +1,116 @@
+// Package bech32 encodes and decodes bech32 (as defined by BIP173) and bech32m
+// (as defined by BIP350) strings. Unlike btcutil/bech32, it supports bech32m,
+// and strings longer than 90 characters, which are used by Zcash for Sapling
+// addresses on some networks (as defined by ZIP-173) and for unified addresses
+// (as defined by ZIP-316). It is used by the bitcoin and zcash packages.
+package bech32
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Variant is the constant that is XORed into the checksum of a string. It
+// distinguishes bech32 strings from bech32m strings.
+type Variant uint32
+
+const (
+	// Bech32 is the variant defined by BIP173.
+	Bech32 = Variant(1)
+	// Bech32m is the variant defined by BIP350.
+	Bech32m = Variant(0x2bc830a3)
+)
+
+// String returns the name of the variant.
+func (variant Variant) String() string {
+	switch variant {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	default:
+		return fmt.Sprintf("Variant(%#x)", uint32(variant))
+	}
+}
+
+// charset is the character set of the data part of strings.
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// polymod returns the checksum of the values, for the human readable part.
+func polymod(hrp string, values []byte) uint32 {
+	chk := uint32(1)
+	step := func(v byte) {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] >> 5)
+	}
+	step(0)
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] & 31)
+	}
+	for _, v := range values {
+		step(v)
+	}
+	return chk
+}
+
+// Encode the 5-bit values, and the human readable part, as a string of the
+// variant.
+func Encode(hrp string, values []byte, variant Variant) string {
+	hrp = strings.ToLower(hrp)
+	chk := polymod(hrp, append(append([]byte{}, values...), 0, 0, 0, 0, 0, 0)) ^ uint32(variant)
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(charset[(chk>>uint(5*(5-i)))&31])
+	}
+	return b.String()
+}
+
+// Decode a string of the variant into its human readable part, and its 5-bit
+// values (without the checksum). Strings longer than the maximum length are
+// rejected, unless the maximum length is not positive.
+func Decode(s string, variant Variant, maxLength int) (string, []byte, error) {
+	if maxLength > 0 && len(s) > maxLength {
+		return "", nil, fmt.Errorf("bad %v: expected <= %v characters, got %v characters", variant, maxLength, len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 {
+			return "", nil, fmt.Errorf("bad %v: invalid character %q", variant, s[i])
+		}
+	}
+	lower := strings.ToLower(s)
+	if lower != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("bad %v: mixed case", variant)
+	}
+	sep := strings.LastIndexByte(lower, '1')
+	if sep < 1 || sep+7 > len(lower) {
+		return "", nil, fmt.Errorf("bad %v: invalid separator index %v", variant, sep)
+	}
+	hrp := lower[:sep]
+	values := make([]byte, 0, len(lower)-sep-1)
+	for i := sep + 1; i < len(lower); i++ {
+		v := strings.IndexByte(charset, lower[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("bad %v: invalid character %q", variant, lower[i])
+		}
+		values = append(values, byte(v))
+	}
+	if polymod(hrp, values) != uint32(variant) {
+		return "", nil, fmt.Errorf("bad %v: invalid checksum", variant)
+	}
+	return hrp, values[:len(values)-6], nil
+}
@@ -0,0 +1,13 @@
+package bech32_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBech32(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bech32 Suite")
+}
@@ -0,0 +1,78 @@
+package bech32_test
+
+import (
+	"strings"
+
+	"github.com/pranav292gpt/zecutil/internal/bech32"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bech32", func() {
+	// The valid strings of BIP173 and BIP350.
+	valid := map[bech32.Variant][]string{
+		bech32.Bech32: {
+			"A12UEL5L",
+			"a12uel5l",
+			"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+			"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+			"?1ezyfcl",
+		},
+		bech32.Bech32m: {
+			"A1LQFN3A",
+			"a1lqfn3a",
+			"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+			"split1checkupstagehandshakeupstreamerranterredcaperredlc445v",
+			"?1v759aa",
+		},
+	}
+
+	It("should decode and encode valid strings", func() {
+		for variant, strs := range valid {
+			for _, s := range strs {
+				hrp, values, err := bech32.Decode(s, variant, 90)
+				Expect(err).ToNot(HaveOccurred(), "%v", s)
+				Expect(bech32.Encode(hrp, values, variant)).To(Equal(strings.ToLower(s)))
+			}
+		}
+	})
+
+	It("should not decode strings of the other variant", func() {
+		for _, s := range valid[bech32.Bech32] {
+			_, _, err := bech32.Decode(s, bech32.Bech32m, 90)
+			Expect(err).To(HaveOccurred(), "%v", s)
+		}
+		for _, s := range valid[bech32.Bech32m] {
+			_, _, err := bech32.Decode(s, bech32.Bech32, 90)
+			Expect(err).To(HaveOccurred(), "%v", s)
+		}
+	})
+
+	It("should return an error for invalid strings", func() {
+		for _, s := range []string{
+			"\x201nwldj5",
+			"pzry9x0s0muk",
+			"1pzry9x0s0muk",
+			"x1b4n0q5v",
+			"li1dgmt3",
+			"A1G7SGD8",
+			"10a06t8",
+			"1qzzfhee",
+			"a12UEL5L",
+		} {
+			_, _, err := bech32.Decode(s, bech32.Bech32, 90)
+			Expect(err).To(HaveOccurred(), "%q", s)
+		}
+	})
+
+	It("should only limit the length when there is a maximum length", func() {
+		s := bech32.Encode("a", make([]byte, 100), bech32.Bech32m)
+		_, _, err := bech32.Decode(s, bech32.Bech32m, 90)
+		Expect(err).To(HaveOccurred())
+		hrp, values, err := bech32.Decode(s, bech32.Bech32m, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hrp).To(Equal("a"))
+		Expect(values).To(Equal(make([]byte, 100)))
+	})
+})
@@ -0,0 +1,57 @@
+// Package jsonfile persists values as JSON files. Files are replaced by
+// writing a temporary file in the same directory and renaming it, so a crash
+// leaves either the old or the new contents, and never a partial write. It is
+// used by the file storages of the wallet and payout packages.
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Read decodes the JSON in the file at the path into the value. It returns
+// false, and leaves the value unchanged, if the file does not exist.
+func Read(path string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("reading %v: %v", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("bad contents of %v: %v", path, err)
+	}
+	return true, nil
+}
+
+// Write encodes the value as indented JSON, and replaces the file at the path
+// with it. The file is left unchanged if an error is returned.
+func Write(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %v: %v", path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %v: %v", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing %v: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing %v: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming %v: %v", tmp.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package jsonfile_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestJSONFile(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "JSON File Suite")
+}
@@ -0,0 +1,74 @@
+package jsonfile_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pranav292gpt/zecutil/internal/jsonfile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON files", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "jsonfile")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	type contents struct {
+		Keys []string `json:"keys"`
+	}
+
+	It("should read what was written", func() {
+		path := filepath.Join(dir, "file.json")
+		Expect(jsonfile.Write(path, contents{Keys: []string{"a"}})).To(Succeed())
+		Expect(jsonfile.Write(path, contents{Keys: []string{"a", "b"}})).To(Succeed())
+
+		var read contents
+		ok, err := jsonfile.Read(path, &read)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(read).To(Equal(contents{Keys: []string{"a", "b"}}))
+
+		// Temporary files are removed.
+		files, err := os.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+	})
+
+	It("should not read files that do not exist", func() {
+		read := contents{Keys: []string{"a"}}
+		ok, err := jsonfile.Read(filepath.Join(dir, "file.json"), &read)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(read).To(Equal(contents{Keys: []string{"a"}}))
+	})
+
+	It("should return an error for malformed files", func() {
+		path := filepath.Join(dir, "file.json")
+		Expect(os.WriteFile(path, []byte("{"), 0600)).To(Succeed())
+		var read contents
+		_, err := jsonfile.Read(path, &read)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should leave the file unchanged when it cannot be written", func() {
+		path := filepath.Join(dir, "file.json")
+		Expect(jsonfile.Write(path, contents{Keys: []string{"a"}})).To(Succeed())
+		Expect(jsonfile.Write(path, func() {})).ToNot(Succeed())
+		Expect(jsonfile.Write(filepath.Join(dir, "missing", "file.json"), contents{})).ToNot(Succeed())
+
+		var read contents
+		_, err := jsonfile.Read(path, &read)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read).To(Equal(contents{Keys: []string{"a"}}))
+	})
+})
@@ -0,0 +1,102 @@
+package keys
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+)
+
+// An AddressFunc encodes a public key as an address.
+type AddressFunc func(pubKey *btcec.PublicKey) (address.Address, error)
+
+// ZcashP2PKH returns an AddressFunc that encodes public keys as transparent
+// Zcash P2PKH addresses.
+func ZcashP2PKH(params *zcash.Params) AddressFunc {
+	return func(pubKey *btcec.PublicKey) (address.Address, error) {
+		addr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+		if err != nil {
+			return address.Address(""), err
+		}
+		return address.Address(addr.EncodeAddress()), nil
+	}
+}
+
+// BitcoinP2PKH returns an AddressFunc that encodes public keys as Bitcoin P2PKH
+// addresses.
+func BitcoinP2PKH(params *chaincfg.Params) AddressFunc {
+	return func(pubKey *btcec.PublicKey) (address.Address, error) {
+		addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+		if err != nil {
+			return address.Address(""), err
+		}
+		return address.Address(addr.EncodeAddress()), nil
+	}
+}
+
+// BitcoinP2WPKH returns an AddressFunc that encodes public keys as Bitcoin
+// P2WPKH addresses.
+func BitcoinP2WPKH(params *chaincfg.Params) AddressFunc {
+	return func(pubKey *btcec.PublicKey) (address.Address, error) {
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+		if err != nil {
+			return address.Address(""), err
+		}
+		return address.Address(addr.EncodeAddress()), nil
+	}
+}
+
+// ZcashAddress derives the transparent Zcash P2PKH address at the given index
+// of the given chain.
+func (account Account) ZcashAddress(chain, index uint32, params *zcash.Params) (zcash.AddressPubKeyHash, error) {
+	pubKey, err := account.PubKey(chain, index)
+	if err != nil {
+		return zcash.AddressPubKeyHash{}, err
+	}
+	return zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+}
+
+// BitcoinAddress derives the Bitcoin P2PKH address at the given index of the
+// given chain.
+func (account Account) BitcoinAddress(chain, index uint32, params *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
+	pubKey, err := account.PubKey(chain, index)
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+}
+
+// BitcoinWitnessAddress derives the Bitcoin P2WPKH address at the given index
+// of the given chain.
+func (account Account) BitcoinWitnessAddress(chain, index uint32, params *chaincfg.Params) (*btcutil.AddressWitnessPubKeyHash, error) {
+	pubKey, err := account.PubKey(chain, index)
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+}
+
+// Address derives the address at the given index of the given chain, encoded
+// using the AddressFunc.
+func (account Account) Address(chain, index uint32, encode AddressFunc) (address.Address, error) {
+	pubKey, err := account.PubKey(chain, index)
+	if err != nil {
+		return address.Address(""), err
+	}
+	return encode(pubKey)
+}
+
+// Addresses derives count consecutive addresses of the given chain, starting
+// at the given index, encoded using the AddressFunc.
+func (account Account) Addresses(chain, start, count uint32, encode AddressFunc) ([]address.Address, error) {
+	addrs := make([]address.Address, count)
+	for i := uint32(0); i < count; i++ {
+		addr, err := account.Address(chain, start+i, encode)
+		if err != nil {
+			return nil, err
+		}
+		addrs[i] = addr
+	}
+	return addrs, nil
+}
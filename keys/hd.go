@@ -0,0 +1,188 @@
+// Package keys derives hierarchical deterministic keys (BIP32), from BIP39
+// mnemonics, and generates the receive and change addresses of BIP44 accounts
+// for transparent Zcash and Bitcoin addresses.
+package keys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key.
+	HardenedKeyStart = hdkeychain.HardenedKeyStart
+
+	// PurposeBIP44 is the purpose used to derive accounts for P2PKH
+	// addresses.
+	PurposeBIP44 = 44
+	// PurposeBIP84 is the purpose used to derive accounts for P2WPKH
+	// addresses.
+	PurposeBIP84 = 84
+
+	// ReceiveChain is the chain of addresses that are given out to receive
+	// payments (also known as the external chain).
+	ReceiveChain = 0
+	// ChangeChain is the chain of addresses that are used to receive change
+	// (also known as the internal chain).
+	ChangeChain = 1
+)
+
+// Hardened returns the hardened form of the given child index.
+func Hardened(index uint32) uint32 {
+	return index + HardenedKeyStart
+}
+
+// NewMasterKey returns the BIP32 master key for the seed. The version bytes of
+// the key, which determine the xprv/tprv prefix, are taken from the params.
+func NewMasterKey(seed []byte, params *chaincfg.Params) (*hdkeychain.ExtendedKey, error) {
+	key, err := hdkeychain.NewMaster(seed, params)
+	if err != nil {
+		return nil, fmt.Errorf("bad seed: %v", err)
+	}
+	return key, nil
+}
+
+// ParsePath parses a BIP32 derivation path of the form "m/44'/133'/0'/0/1".
+// Hardened indices can be marked with either ' or h (or H).
+func ParsePath(path string) ([]uint32, error) {
+	components := strings.Split(strings.TrimSpace(path), "/")
+	if components[0] != "m" {
+		return nil, fmt.Errorf("bad path %q: expected prefix m", path)
+	}
+	indices := make([]uint32, 0, len(components)-1)
+	for _, component := range components[1:] {
+		hardened := false
+		if strings.HasSuffix(component, "'") || strings.HasSuffix(component, "h") || strings.HasSuffix(component, "H") {
+			hardened = true
+			component = component[:len(component)-1]
+		}
+		index, err := strconv.ParseUint(component, 10, 32)
+		if err != nil || index >= HardenedKeyStart {
+			return nil, fmt.Errorf("bad path %q: bad index %q", path, component)
+		}
+		if hardened {
+			index += HardenedKeyStart
+		}
+		indices = append(indices, uint32(index))
+	}
+	return indices, nil
+}
+
+// DerivePath derives the descendant of the key at the given path, relative to
+// the key.
+func DerivePath(key *hdkeychain.ExtendedKey, path []uint32) (*hdkeychain.ExtendedKey, error) {
+	for _, index := range path {
+		child, err := key.Derive(index)
+		if err != nil {
+			return nil, fmt.Errorf("bad child %v: %v", index, err)
+		}
+		key = child
+	}
+	return key, nil
+}
+
+// An Account is a BIP44 account key, at the path m/purpose'/coin_type'/account'.
+// Addresses are derived from the account on the receive and change chains. An
+// account can be neutered, so that it can derive addresses but not private
+// keys (for example, on a deposit server).
+type Account struct {
+	key *hdkeychain.ExtendedKey
+}
+
+// NewAccount derives the account at m/purpose'/coinType'/index' from the master
+// key.
+func NewAccount(master *hdkeychain.ExtendedKey, purpose, coinType, index uint32) (Account, error) {
+	key, err := DerivePath(master, []uint32{Hardened(purpose), Hardened(coinType), Hardened(index)})
+	if err != nil {
+		return Account{}, err
+	}
+	return Account{key: key}, nil
+}
+
+// NewZcashAccount derives a BIP44 account for transparent Zcash addresses from
+// the seed, using the coin type of the params.
+func NewZcashAccount(seed []byte, params *zcash.Params, index uint32) (Account, error) {
+	master, err := NewMasterKey(seed, params.Params)
+	if err != nil {
+		return Account{}, err
+	}
+	return NewAccount(master, PurposeBIP44, params.HDCoinType, index)
+}
+
+// NewBitcoinAccount derives an account for Bitcoin addresses from the seed,
+// using the coin type of the params. The purpose should be PurposeBIP44 for
+// P2PKH addresses, and PurposeBIP84 for P2WPKH addresses.
+func NewBitcoinAccount(seed []byte, params *chaincfg.Params, purpose, index uint32) (Account, error) {
+	master, err := NewMasterKey(seed, params)
+	if err != nil {
+		return Account{}, err
+	}
+	return NewAccount(master, purpose, params.HDCoinType, index)
+}
+
+// ParseAccount parses a serialized account key (an xprv or xpub).
+func ParseAccount(key string) (Account, error) {
+	extendedKey, err := hdkeychain.NewKeyFromString(key)
+	if err != nil {
+		return Account{}, fmt.Errorf("bad account key: %v", err)
+	}
+	return Account{key: extendedKey}, nil
+}
+
+// ExtendedKey returns the extended key of the account.
+func (account Account) ExtendedKey() *hdkeychain.ExtendedKey {
+	return account.key
+}
+
+// String returns the serialized account key (an xprv or xpub).
+func (account Account) String() string {
+	return account.key.String()
+}
+
+// IsPrivate returns true if the account can derive private keys.
+func (account Account) IsPrivate() bool {
+	return account.key.IsPrivate()
+}
+
+// Neuter returns the public version of the account, which can derive public
+// keys and addresses, but not private keys.
+func (account Account) Neuter() (Account, error) {
+	key, err := account.key.Neuter()
+	if err != nil {
+		return Account{}, err
+	}
+	return Account{key: key}, nil
+}
+
+// Key derives the extended key at the given index of the given chain.
+func (account Account) Key(chain, index uint32) (*hdkeychain.ExtendedKey, error) {
+	if index >= HardenedKeyStart {
+		return nil, fmt.Errorf("bad index %v: expected non-hardened index", index)
+	}
+	return DerivePath(account.key, []uint32{chain, index})
+}
+
+// PubKey derives the public key at the given index of the given chain.
+func (account Account) PubKey(chain, index uint32) (*btcec.PublicKey, error) {
+	key, err := account.Key(chain, index)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPubKey()
+}
+
+// PrivKey derives the private key at the given index of the given chain. An
+// error is returned if the account has been neutered.
+func (account Account) PrivKey(chain, index uint32) (*btcec.PrivateKey, error) {
+	key, err := account.Key(chain, index)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPrivKey()
+}
@@ -0,0 +1,268 @@
+package keys_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/keys"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// loadVectors loads test vectors in the format used by
+// https://github.com/zcash/zcash-test-vectors, where the first two rows are a
+// source and a header.
+func loadVectors(filename string) []map[string]interface{} {
+	data, err := os.ReadFile(filename)
+	Expect(err).ToNot(HaveOccurred())
+	rows := [][]interface{}{}
+	Expect(json.Unmarshal(data, &rows)).To(Succeed())
+	Expect(len(rows)).To(BeNumerically(">", 2))
+
+	header := strings.Split(rows[1][0].(string), ", ")
+	vectors := make([]map[string]interface{}, 0, len(rows)-2)
+	for _, row := range rows[2:] {
+		vector := map[string]interface{}{}
+		for i, name := range header {
+			vector[name] = row[i]
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors
+}
+
+func decodeHex(s string) []byte {
+	data, err := hex.DecodeString(s)
+	Expect(err).ToNot(HaveOccurred())
+	return data
+}
+
+var _ = Describe("HD keys", func() {
+	zcashSeed := make([]byte, 32)
+	for i := range zcashSeed {
+		zcashSeed[i] = byte(i)
+	}
+
+	Context("when deriving BIP32 keys", func() {
+		// Test vectors from
+		// https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki.
+		vectors := []struct {
+			seed string
+			path string
+			xpub string
+			xprv string
+		}{
+			{
+				"000102030405060708090a0b0c0d0e0f",
+				"m",
+				"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+				"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+			},
+			{
+				"000102030405060708090a0b0c0d0e0f",
+				"m/0H/1",
+				"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+				"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+			},
+			{
+				"000102030405060708090a0b0c0d0e0f",
+				"m/0'/1/2'/2/1000000000",
+				"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+				"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+			},
+			{
+				"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+				"m/0/2147483647H/1/2147483646H/2",
+				"xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+				"xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j",
+			},
+			{
+				// Retention of leading zeros (btcsuite/btcutil#172).
+				"3ddd5602285899a946114506157c7997e5444528f3003f6134712147db19b678",
+				"m/0H/1H",
+				"xpub6BJA1jSqiukeaesWfxe6sNK9CCGaujFFSJLomWHprUL9DePQ4JDkM5d88n49sMGJxrhpjazuXYWdMf17C9T5XnxkopaeS7jGk1GyyVziaMt",
+				"xprv9xJocDuwtYCMNAo3Zw76WENQeAS6WGXQ55RCy7tDJ8oALr4FWkuVoHJeHVAcAqiZLE7Je3vZJHxspZdFHfnBEjHqU5hG1Jaj32dVoS6XLT1",
+			},
+		}
+
+		It("should match the test vectors", func() {
+			for _, vector := range vectors {
+				master, err := keys.NewMasterKey(decodeHex(vector.seed), &chaincfg.MainNetParams)
+				Expect(err).ToNot(HaveOccurred())
+				path, err := keys.ParsePath(vector.path)
+				Expect(err).ToNot(HaveOccurred())
+				key, err := keys.DerivePath(master, path)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(key.String()).To(Equal(vector.xprv))
+				pub, err := key.Neuter()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pub.String()).To(Equal(vector.xpub))
+			}
+		})
+
+		It("should parse paths", func() {
+			path, err := keys.ParsePath("m/44'/133h/0H/1/2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal([]uint32{keys.Hardened(44), keys.Hardened(133), keys.Hardened(0), 1, 2}))
+
+			path, err = keys.ParsePath("m")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(BeEmpty())
+
+			for _, bad := range []string{"", "44'/0", "m/", "m/x", "m/-1", "m/2147483648", "m/0''"} {
+				_, err := keys.ParsePath(bad)
+				Expect(err).To(HaveOccurred(), bad)
+			}
+		})
+	})
+
+	Context("when deriving Zcash accounts", func() {
+		It("should match the transparent account test vectors", func() {
+			for _, vector := range loadVectors("testdata/bip_0032.json") {
+				account, err := keys.NewZcashAccount(zcashSeed, &zcash.MainNetParams, uint32(vector["account"].(float64)))
+				Expect(err).ToNot(HaveOccurred())
+				key := account.ExtendedKey()
+				Expect(hex.EncodeToString(key.ChainCode())).To(Equal(vector["c"]))
+				pubKey, err := key.ECPubKey()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(pubKey.SerializeCompressed())).To(Equal(vector["pk"]))
+				Expect(hex.EncodeToString(btcutil.Hash160(pubKey.SerializeCompressed()))).To(Equal(vector["address"]))
+			}
+		})
+
+		It("should match the P2PKH receivers of the unified address test vectors", func() {
+			n := 0
+			for _, vector := range loadVectors("testdata/unified_address.json") {
+				pkh, ok := vector["p2pkh_bytes"].(string)
+				if !ok {
+					continue
+				}
+				Expect(vector["root_seed"]).To(Equal(hex.EncodeToString(zcashSeed)))
+				account, err := keys.NewZcashAccount(zcashSeed, &zcash.MainNetParams, uint32(vector["account"].(float64)))
+				Expect(err).ToNot(HaveOccurred())
+				addr, err := account.ZcashAddress(keys.ReceiveChain, uint32(vector["diversifier_index"].(float64)), &zcash.MainNetParams)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(addr.ScriptAddress())).To(Equal(pkh))
+				Expect(addr.EncodeAddress()).To(HavePrefix("t1"))
+				n++
+			}
+			Expect(n).To(BeNumerically(">", 0))
+		})
+
+		It("should use the coin type and version bytes of the params", func() {
+			account, err := keys.NewZcashAccount(zcashSeed, &zcash.TestNet3Params, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(account.String()).To(HavePrefix("tprv"))
+			Expect(account.ExtendedKey().Depth()).To(Equal(uint8(3)))
+
+			master, err := keys.NewMasterKey(zcashSeed, zcash.TestNet3Params.Params)
+			Expect(err).ToNot(HaveOccurred())
+			expected, err := keys.NewAccount(master, keys.PurposeBIP44, 1, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(account.String()).To(Equal(expected.String()))
+
+			addr, err := account.ZcashAddress(keys.ReceiveChain, 0, &zcash.TestNet3Params)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.EncodeAddress()).To(HavePrefix("tm"))
+		})
+
+		It("should derive the same addresses from a neutered account", func() {
+			account, err := keys.NewZcashAccount(zcashSeed, &zcash.MainNetParams, 0)
+			Expect(err).ToNot(HaveOccurred())
+			neutered, err := account.Neuter()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(neutered.IsPrivate()).To(BeFalse())
+			Expect(neutered.String()).To(HavePrefix("xpub"))
+
+			parsed, err := keys.ParseAccount(neutered.String())
+			Expect(err).ToNot(HaveOccurred())
+			for _, chain := range []uint32{keys.ReceiveChain, keys.ChangeChain} {
+				expected, err := account.Addresses(chain, 0, 5, keys.ZcashP2PKH(&zcash.MainNetParams))
+				Expect(err).ToNot(HaveOccurred())
+				actual, err := parsed.Addresses(chain, 0, 5, keys.ZcashP2PKH(&zcash.MainNetParams))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(actual).To(Equal(expected))
+			}
+
+			receive, err := account.Address(keys.ReceiveChain, 0, keys.ZcashP2PKH(&zcash.MainNetParams))
+			Expect(err).ToNot(HaveOccurred())
+			change, err := account.Address(keys.ChangeChain, 0, keys.ZcashP2PKH(&zcash.MainNetParams))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receive).ToNot(Equal(change))
+
+			_, err = neutered.PrivKey(keys.ReceiveChain, 0)
+			Expect(err).To(HaveOccurred())
+			privKey, err := account.PrivKey(keys.ReceiveChain, 0)
+			Expect(err).ToNot(HaveOccurred())
+			pubKey, err := neutered.PubKey(keys.ReceiveChain, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(privKey.PubKey().IsEqual(pubKey)).To(BeTrue())
+		})
+
+		It("should reject hardened address indices", func() {
+			account, err := keys.NewZcashAccount(zcashSeed, &zcash.MainNetParams, 0)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = account.ZcashAddress(keys.ReceiveChain, keys.Hardened(0), &zcash.MainNetParams)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when deriving Bitcoin accounts", func() {
+		// Test vectors from
+		// https://github.com/bitcoin/bips/blob/master/bip-0084.mediawiki.
+		mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+		It("should match the BIP84 test vectors", func() {
+			seed, err := keys.NewSeed(mnemonic, "")
+			Expect(err).ToNot(HaveOccurred())
+			account, err := keys.NewBitcoinAccount(seed, &chaincfg.MainNetParams, keys.PurposeBIP84, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			// BIP84 serializes account keys with the zprv/zpub version bytes.
+			zprv, err := account.ExtendedKey().CloneWithVersion([]byte{0x04, 0xb2, 0x43, 0x0c})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(zprv.String()).To(Equal("zprvAdG4iTXWBoARxkkzNpNh8r6Qag3irQB8PzEMkAFeTRXxHpbF9z4QgEvBRmfvqWvGp42t42nvgGpNgYSJA9iefm1yYNZKEm7z6qUWCroSQnE"))
+
+			addrs, err := account.Addresses(keys.ReceiveChain, 0, 2, keys.BitcoinP2WPKH(&chaincfg.MainNetParams))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addrs[0]).To(BeEquivalentTo("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"))
+			Expect(addrs[1]).To(BeEquivalentTo("bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"))
+
+			change, err := account.BitcoinWitnessAddress(keys.ChangeChain, 0, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(change.EncodeAddress()).To(Equal("bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el"))
+
+			privKey, err := account.PrivKey(keys.ReceiveChain, 0)
+			Expect(err).ToNot(HaveOccurred())
+			wif, err := btcutil.NewWIF(privKey, &chaincfg.MainNetParams, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(wif.String()).To(Equal("KyZpNDKnfs94vbrwhJneDi77V6jF64PWPF8x5cdJb8ifgg2DUc9d"))
+		})
+
+		It("should derive BIP44 P2PKH addresses", func() {
+			seed, err := keys.NewSeed(mnemonic, "")
+			Expect(err).ToNot(HaveOccurred())
+			account, err := keys.NewBitcoinAccount(seed, &chaincfg.MainNetParams, keys.PurposeBIP44, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			addr, err := account.BitcoinAddress(keys.ReceiveChain, 0, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.EncodeAddress()).To(Equal("1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"))
+
+			encoded, err := account.Address(keys.ReceiveChain, 0, keys.BitcoinP2PKH(&chaincfg.MainNetParams))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoded).To(BeEquivalentTo(addr.EncodeAddress()))
+		})
+
+		It("should reject a malformed account key", func() {
+			_, err := keys.ParseAccount("xpub")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,148 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed" // Required to embed the wordlist.
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MinEntropyBits is the minimum number of bits of entropy that can be
+	// encoded in a mnemonic (12 words).
+	MinEntropyBits = 128
+	// MaxEntropyBits is the maximum number of bits of entropy that can be
+	// encoded in a mnemonic (24 words).
+	MaxEntropyBits = 256
+
+	// seedIterations is the number of PBKDF2 iterations used to stretch a
+	// mnemonic into a seed.
+	seedIterations = 2048
+	// seedSize is the size of the seed produced from a mnemonic.
+	seedSize = 64
+	// bitsPerWord is the number of bits encoded by each word of a mnemonic.
+	bitsPerWord = 11
+)
+
+//go:embed english.txt
+var englishWordlist string
+
+var (
+	// wordlist is the BIP39 English wordlist.
+	wordlist = strings.Fields(englishWordlist)
+	// wordIndices maps each word in the wordlist to its index.
+	wordIndices = func() map[string]int {
+		indices := make(map[string]int, len(wordlist))
+		for i, word := range wordlist {
+			indices[word] = i
+		}
+		return indices
+	}()
+)
+
+// NewEntropy returns random entropy of the given number of bits, that can be
+// used to generate a new mnemonic. The number of bits must be a multiple of 32
+// between MinEntropyBits and MaxEntropyBits.
+func NewEntropy(bits int) ([]byte, error) {
+	if err := validateEntropyBits(bits); err != nil {
+		return nil, err
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, fmt.Errorf("bad entropy: %v", err)
+	}
+	return entropy, nil
+}
+
+// NewMnemonic encodes entropy as a BIP39 mnemonic using the English wordlist.
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if err := validateEntropyBits(bits); err != nil {
+		return "", err
+	}
+	checksumBits := bits / 32
+
+	// Append the checksum to the entropy, and split the result into groups of
+	// 11 bits, each of which selects a word.
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	numWords := (bits + checksumBits) / bitsPerWord
+	words := make([]string, numWords)
+	mask := big.NewInt(1<<bitsPerWord - 1)
+	index := new(big.Int)
+	for i := numWords - 1; i >= 0; i-- {
+		index.And(data, mask)
+		words[i] = wordlist[index.Int64()]
+		data.Rsh(data, bitsPerWord)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// EntropyFromMnemonic decodes a BIP39 mnemonic into the entropy that it
+// encodes. An error is returned if the mnemonic contains unknown words, has
+// an invalid number of words, or has an invalid checksum.
+func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	numWords := len(words)
+	if numWords%3 != 0 || numWords < 12 || numWords > 24 {
+		return nil, fmt.Errorf("bad mnemonic: expected 12, 15, 18, 21, or 24 words, got %v words", numWords)
+	}
+
+	data := new(big.Int)
+	for i, word := range words {
+		index, ok := wordIndices[word]
+		if !ok {
+			return nil, fmt.Errorf("bad mnemonic: unknown word %v %q", i, word)
+		}
+		data.Lsh(data, bitsPerWord)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksumBits := numWords * bitsPerWord / 33
+	bits := numWords*bitsPerWord - checksumBits
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	data.Rsh(data, uint(checksumBits))
+
+	entropy := make([]byte, bits/8)
+	data.FillBytes(entropy)
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return nil, fmt.Errorf("bad mnemonic: invalid checksum")
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic returns an error if the mnemonic is not a valid BIP39
+// mnemonic.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := EntropyFromMnemonic(mnemonic)
+	return err
+}
+
+// NewSeed validates a BIP39 mnemonic and stretches it, together with an
+// optional passphrase, into a 64 byte seed that can be used to create a master
+// key.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalisedMnemonic := strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(normalisedMnemonic), []byte(salt), seedIterations, seedSize, sha512.New), nil
+}
+
+func validateEntropyBits(bits int) error {
+	if bits%32 != 0 || bits < MinEntropyBits || bits > MaxEntropyBits {
+		return fmt.Errorf("bad entropy: expected a multiple of 32 bits between %v and %v, got %v bits", MinEntropyBits, MaxEntropyBits, bits)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package keys_test
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/pranav292gpt/zecutil/keys"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mnemonics", func() {
+	// Test vectors from https://github.com/trezor/python-mnemonic, using the
+	// passphrase "TREZOR".
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"80808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+		},
+		{
+			"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			"000000000000000000000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+			"035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa",
+		},
+		{
+			"808080808080808080808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always",
+			"107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+			"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			"dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+	}
+
+	Context("when encoding entropy", func() {
+		It("should match the test vectors", func() {
+			for _, vector := range vectors {
+				entropy, err := hex.DecodeString(vector.entropy)
+				Expect(err).ToNot(HaveOccurred())
+				mnemonic, err := keys.NewMnemonic(entropy)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mnemonic).To(Equal(vector.mnemonic))
+			}
+		})
+
+		It("should reject entropy of the wrong length", func() {
+			for _, n := range []int{0, 12, 15, 17, 33, 36} {
+				_, err := keys.NewMnemonic(make([]byte, n))
+				Expect(err).To(HaveOccurred())
+			}
+			_, err := keys.NewEntropy(100)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should round-trip random entropy", func() {
+			for _, bits := range []int{128, 160, 192, 224, 256} {
+				entropy, err := keys.NewEntropy(bits)
+				Expect(err).ToNot(HaveOccurred())
+				mnemonic, err := keys.NewMnemonic(entropy)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(strings.Fields(mnemonic)).To(HaveLen(bits * 33 / 32 / 11))
+				decoded, err := keys.EntropyFromMnemonic(mnemonic)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decoded).To(Equal(entropy))
+			}
+		})
+	})
+
+	Context("when decoding a mnemonic", func() {
+		It("should match the test vectors", func() {
+			for _, vector := range vectors {
+				entropy, err := keys.EntropyFromMnemonic(vector.mnemonic)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(entropy)).To(Equal(vector.entropy))
+
+				seed, err := keys.NewSeed(vector.mnemonic, "TREZOR")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(seed)).To(Equal(vector.seed))
+			}
+		})
+
+		It("should ignore extra whitespace", func() {
+			seed, err := keys.NewSeed("  "+strings.ReplaceAll(vectors[0].mnemonic, " ", "  \t")+"\n", "TREZOR")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hex.EncodeToString(seed)).To(Equal(vectors[0].seed))
+		})
+
+		It("should reject an invalid checksum", func() {
+			Expect(keys.ValidateMnemonic(strings.Replace(vectors[0].mnemonic, "about", "abandon", 1))).ToNot(Succeed())
+			_, err := keys.NewSeed("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo", "")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject unknown words", func() {
+			Expect(keys.ValidateMnemonic(strings.Replace(vectors[0].mnemonic, "about", "aboot", 1))).ToNot(Succeed())
+		})
+
+		It("should reject the wrong number of words", func() {
+			Expect(keys.ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon about")).ToNot(Succeed())
+			Expect(keys.ValidateMnemonic("")).ToNot(Succeed())
+		})
+	})
+})
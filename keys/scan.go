@@ -0,0 +1,62 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+)
+
+// DefaultGapLimit is the number of consecutive unused addresses after which a
+// scan stops, as recommended by BIP44.
+const DefaultGapLimit = 20
+
+// The UnspentOutputsClient interface defines the functionality required to
+// scan for used addresses. It is implemented by the bitcoin and zcash
+// clients.
+type UnspentOutputsClient interface {
+	UnspentOutputs(ctx context.Context, minConf, maxConf int64, address address.Address) ([]utxo.Output, error)
+}
+
+// A UsedAddress is an address that was found to have unspent outputs during a
+// scan.
+type UsedAddress struct {
+	Index   uint32
+	Address address.Address
+	Outputs []utxo.Output
+}
+
+// Scan the given chain of the account for addresses that have unspent outputs
+// (including unconfirmed outputs). Addresses are checked in order, starting at
+// index zero, until gapLimit consecutive addresses have no unspent outputs.
+// The used addresses are returned, along with the index of the first address
+// after the last used address (which is the next address that should be given
+// out). Addresses whose outputs have all been spent cannot be distinguished
+// from addresses that were never used.
+func (account Account) Scan(ctx context.Context, client UnspentOutputsClient, chain, gapLimit uint32, encode AddressFunc) ([]UsedAddress, uint32, error) {
+	if gapLimit == 0 {
+		return nil, 0, fmt.Errorf("bad gap limit: expected greater than zero")
+	}
+	used := []UsedAddress{}
+	next := uint32(0)
+	for index := uint32(0); index-next < gapLimit; index++ {
+		if index >= HardenedKeyStart {
+			return nil, 0, fmt.Errorf("bad index %v: exhausted non-hardened indices", index)
+		}
+		addr, err := account.Address(chain, index, encode)
+		if err != nil {
+			return nil, 0, fmt.Errorf("bad address %v: %v", index, err)
+		}
+		outputs, err := client.UnspentOutputs(ctx, 0, math.MaxInt32, addr)
+		if err != nil {
+			return nil, 0, fmt.Errorf("bad unspent outputs for %v: %v", addr, err)
+		}
+		if len(outputs) > 0 {
+			used = append(used, UsedAddress{Index: index, Address: addr, Outputs: outputs})
+			next = index + 1
+		}
+	}
+	return used, next, nil
+}
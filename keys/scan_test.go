@@ -0,0 +1,116 @@
+package keys_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/keys"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The bitcoin (and zcash) clients can be used to scan.
+var _ keys.UnspentOutputsClient = bitcoin.Client(nil)
+
+// mockClient returns one unspent output for each of the funded addresses, and
+// records the addresses that it was asked about.
+type mockClient struct {
+	funded  map[address.Address]bool
+	queried []address.Address
+	err     error
+}
+
+func (client *mockClient) UnspentOutputs(ctx context.Context, minConf, maxConf int64, addr address.Address) ([]utxo.Output, error) {
+	client.queried = append(client.queried, addr)
+	if client.err != nil {
+		return nil, client.err
+	}
+	if !client.funded[addr] {
+		return []utxo.Output{}, nil
+	}
+	return []utxo.Output{{Value: pack.NewU256FromUint64(1000)}}, nil
+}
+
+var _ = Describe("Scanning", func() {
+	params := &zcash.MainNetParams
+	encode := keys.ZcashP2PKH(params)
+
+	newAccount := func() keys.Account {
+		entropy, err := keys.NewEntropy(keys.MinEntropyBits)
+		Expect(err).ToNot(HaveOccurred())
+		mnemonic, err := keys.NewMnemonic(entropy)
+		Expect(err).ToNot(HaveOccurred())
+		seed, err := keys.NewSeed(mnemonic, "")
+		Expect(err).ToNot(HaveOccurred())
+		account, err := keys.NewZcashAccount(seed, params, 0)
+		Expect(err).ToNot(HaveOccurred())
+		return account
+	}
+
+	It("should stop after the gap limit", func() {
+		account := newAccount()
+		addrs, err := account.Addresses(keys.ReceiveChain, 0, 20, encode)
+		Expect(err).ToNot(HaveOccurred())
+		client := &mockClient{funded: map[address.Address]bool{addrs[1]: true, addrs[4]: true, addrs[9]: true}}
+
+		used, next, err := account.Scan(context.Background(), client, keys.ReceiveChain, 5, encode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(next).To(Equal(uint32(10)))
+		Expect(used).To(HaveLen(3))
+		for i, index := range []uint32{1, 4, 9} {
+			Expect(used[i].Index).To(Equal(index))
+			Expect(used[i].Address).To(Equal(addrs[index]))
+			Expect(used[i].Outputs).To(HaveLen(1))
+		}
+		// Addresses 10 to 14 are unused, so the scan should not look beyond
+		// them.
+		Expect(client.queried).To(Equal(addrs[:15]))
+	})
+
+	It("should not find addresses beyond the gap limit", func() {
+		account := newAccount()
+		addrs, err := account.Addresses(keys.ReceiveChain, 0, 10, encode)
+		Expect(err).ToNot(HaveOccurred())
+		client := &mockClient{funded: map[address.Address]bool{addrs[6]: true}}
+
+		used, next, err := account.Scan(context.Background(), client, keys.ReceiveChain, 5, encode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(used).To(BeEmpty())
+		Expect(next).To(Equal(uint32(0)))
+		Expect(client.queried).To(HaveLen(5))
+	})
+
+	It("should scan the change chain independently", func() {
+		account := newAccount()
+		change, err := account.Address(keys.ChangeChain, 2, encode)
+		Expect(err).ToNot(HaveOccurred())
+		client := &mockClient{funded: map[address.Address]bool{change: true}}
+
+		used, next, err := account.Scan(context.Background(), client, keys.ReceiveChain, keys.DefaultGapLimit, encode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(used).To(BeEmpty())
+		Expect(next).To(Equal(uint32(0)))
+
+		used, next, err = account.Scan(context.Background(), client, keys.ChangeChain, keys.DefaultGapLimit, encode)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(used).To(HaveLen(1))
+		Expect(next).To(Equal(uint32(3)))
+	})
+
+	It("should return client errors", func() {
+		client := &mockClient{err: fmt.Errorf("unavailable")}
+		_, _, err := newAccount().Scan(context.Background(), client, keys.ReceiveChain, keys.DefaultGapLimit, encode)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject a zero gap limit", func() {
+		_, _, err := newAccount().Scan(context.Background(), &mockClient{}, keys.ReceiveChain, 0, encode)
+		Expect(err).To(HaveOccurred())
+	})
+})
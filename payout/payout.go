@@ -0,0 +1,780 @@
+// Package payout batches payment requests into transactions. Requests are
+// queued with an idempotency key, and are flushed into a single transaction
+// when enough requests are queued, or when a timer fires. Each transaction
+// spends outputs reserved from a wallet.Wallet, returns change to the wallet,
+// and is signed through a signer.Signer before it is submitted. Batches are
+// tracked until they are confirmed, and are re-broadcast if they are dropped
+// by the node.
+//
+// A request is never paid twice. Once a request has been included in a signed
+// transaction, that transaction is persisted before it is broadcast, and it is
+// the only transaction that will ever pay the request: retries re-broadcast
+// the same transaction, and never move its requests into another batch. The
+// inputs of a batch stay reserved while it is retried. If its reservation
+// expires anyway, the inputs may be spent by another transaction, so the batch
+// fails, and its requests must be resolved by the operator.
+package payout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/pranav292gpt/zecutil/wallet"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultMaxBatchSize is the maximum number of requests that are paid by a
+	// batch. A flush is triggered as soon as this many requests are queued.
+	DefaultMaxBatchSize = 100
+	// DefaultFlushInterval is the interval at which queued requests are
+	// flushed, and batches are polled, by Run.
+	DefaultFlushInterval = time.Minute
+	// DefaultRetryInterval is the time after which a batch that is not known
+	// by the node is broadcast again. It is half of the default reservation
+	// TTL of the wallet, so a batch is retried at least once before its
+	// reservation would expire if it were not renewed.
+	DefaultRetryInterval = wallet.DefaultReservationTTL / 2
+	// DefaultMinConf is the number of confirmations after which a batch is
+	// confirmed.
+	DefaultMinConf = 1
+	// DefaultRPCTimeout is the timeout of each call to the client. Clients
+	// retry failed calls until their context is done, so the timeout bounds
+	// the time spent on batches that are not known by the node.
+	DefaultRPCTimeout = 10 * time.Second
+	// DefaultFeeRate is the default fee rate (per byte) of batches. It is only
+	// used by fees that depend on the size of the batch, such as LinearFee.
+	DefaultFeeRate = 10
+	// DefaultDustThreshold is the default minimum value (in zatoshi) of a
+	// change output. Smaller change is added to the fee. It is the ZIP-317
+	// marginal fee, since change worth less than that costs more to spend
+	// than it is worth.
+	DefaultDustThreshold = zcash.MarginalFee
+)
+
+var (
+	// ErrKeyConflict is returned when a request is submitted with the key of
+	// a request that has a different recipient.
+	ErrKeyConflict = errors.New("key conflict")
+	// ErrUnknownRequest is returned when there is no request with a key.
+	ErrUnknownRequest = errors.New("unknown request")
+	// ErrUnknownBatch is returned when there is no batch with an ID.
+	ErrUnknownBatch = errors.New("unknown batch")
+)
+
+// The Client interface defines the functionality required to submit batches,
+// and to track their confirmations. It is implemented by the bitcoin and zcash
+// clients.
+type Client interface {
+	SubmitTx(ctx context.Context, tx utxo.Tx) error
+	Confirmations(ctx context.Context, txHash pack.Bytes) (int64, error)
+}
+
+// Status of a request or batch.
+type Status string
+
+const (
+	// StatusQueued is the status of requests that are waiting to be batched.
+	StatusQueued = Status("queued")
+	// StatusSigned is the status of batches, and their requests, that have
+	// been signed, but have not been accepted by the node.
+	StatusSigned = Status("signed")
+	// StatusSubmitted is the status of batches, and their requests, that have
+	// been accepted by the node, but have not been confirmed.
+	StatusSubmitted = Status("submitted")
+	// StatusConfirmed is the status of batches, and their requests, that have
+	// the required number of confirmations.
+	StatusConfirmed = Status("confirmed")
+	// StatusFailed is the status of requests that cannot be paid, because a
+	// transaction cannot be built for their recipient. It is also the status
+	// of batches, and their requests, whose reservation expired before their
+	// transaction was accepted by the node.
+	StatusFailed = Status("failed")
+)
+
+// A Request is a payment to a recipient.
+type Request struct {
+	Key       string         `json:"key"`
+	Recipient utxo.Recipient `json:"recipient"`
+	Status    Status         `json:"status"`
+	// Batch is the ID of the batch that pays the request, if it has been
+	// batched.
+	Batch string `json:"batch,omitempty"`
+	// Error is the reason that the request failed.
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (request Request) clone() Request {
+	request.Recipient.Script = append(pack.Bytes(nil), request.Recipient.Script...)
+	request.Recipient.Data = append(pack.Bytes(nil), request.Recipient.Data...)
+	return request
+}
+
+// A Batch is a signed transaction that pays a set of requests.
+type Batch struct {
+	ID     string   `json:"id"`
+	Status Status   `json:"status"`
+	Keys   []string `json:"keys"`
+	// Reservation is the ID of the wallet reservation of the inputs of the
+	// transaction. It is committed when the transaction is first accepted.
+	Reservation string `json:"reservation"`
+	Committed   bool   `json:"committed"`
+	// Tx is the signed transaction, and Outputs are its outputs.
+	Tx            pack.Bytes    `json:"tx"`
+	TxHash        pack.Bytes    `json:"txHash"`
+	Outputs       []utxo.Output `json:"outputs"`
+	Fee           pack.U256     `json:"fee"`
+	Confirmations int64         `json:"confirmations"`
+	// Broadcasts is the number of times that the transaction was accepted by
+	// the node, and BroadcastAt is the time of the last attempt.
+	Broadcasts  int       `json:"broadcasts"`
+	CreatedAt   time.Time `json:"createdAt"`
+	BroadcastAt time.Time `json:"broadcastAt"`
+	// Error is the error of the last attempt to broadcast, or confirm, the
+	// transaction.
+	Error string `json:"error,omitempty"`
+}
+
+func (batch Batch) clone() Batch {
+	batch.Keys = append([]string(nil), batch.Keys...)
+	batch.Tx = append(pack.Bytes(nil), batch.Tx...)
+	batch.TxHash = append(pack.Bytes(nil), batch.TxHash...)
+	batch.Outputs = append([]utxo.Output(nil), batch.Outputs...)
+	return batch
+}
+
+// FeeFunc re-exports bitcoin.FeeFunc.
+type FeeFunc = bitcoin.FeeFunc
+
+// LinearFee re-exports bitcoin.LinearFee.
+func LinearFee(feeRate pack.U256, numInputs, numOutputs int) pack.U256 {
+	return bitcoin.LinearFee(feeRate, numInputs, numOutputs)
+}
+
+// ConventionalFee re-exports zcash.P2PKHConventionalFee.
+func ConventionalFee(feeRate pack.U256, numInputs, numOutputs int) pack.U256 {
+	return zcash.P2PKHConventionalFee(feeRate, numInputs, numOutputs)
+}
+
+// Options are used to parameterise the behaviour of the Batcher.
+type Options struct {
+	// ChangeAddress receives the change of batches. It should be an address
+	// that is synced by the wallet.
+	ChangeAddress address.Address
+	MaxBatchSize  int
+	// FlushInterval is also the interval at which the reservations of batches
+	// are renewed, so it must be shorter than the reservation TTL of the
+	// wallet.
+	FlushInterval time.Duration
+	RetryInterval time.Duration
+	MinConf       int64
+	RPCTimeout    time.Duration
+	// Fee computes the fee of batches at the FeeRate.
+	Fee           FeeFunc
+	FeeRate       pack.U256
+	DustThreshold pack.U256
+	// Now returns the current time.
+	Now func() time.Time
+}
+
+// DefaultOptions returns Options with the default settings for Zcash, which
+// pay the ZIP-317 conventional fee. The change address must be set.
+// Bitcoin-like chains should use the LinearFee, and a dust threshold suitable
+// for the chain.
+func DefaultOptions() Options {
+	return Options{
+		MaxBatchSize:  DefaultMaxBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		RetryInterval: DefaultRetryInterval,
+		MinConf:       DefaultMinConf,
+		RPCTimeout:    DefaultRPCTimeout,
+		Fee:           ConventionalFee,
+		FeeRate:       pack.NewU256FromUint64(DefaultFeeRate),
+		DustThreshold: pack.NewU256FromUint64(DefaultDustThreshold),
+		Now:           time.Now,
+	}
+}
+
+// WithChangeAddress sets the address that receives the change of batches.
+func (opts Options) WithChangeAddress(changeAddress address.Address) Options {
+	opts.ChangeAddress = changeAddress
+	return opts
+}
+
+// WithMaxBatchSize sets the maximum number of requests that are paid by a
+// batch.
+func (opts Options) WithMaxBatchSize(maxBatchSize int) Options {
+	opts.MaxBatchSize = maxBatchSize
+	return opts
+}
+
+// WithFlushInterval sets the interval at which requests are flushed, and
+// batches are polled, by Run.
+func (opts Options) WithFlushInterval(flushInterval time.Duration) Options {
+	opts.FlushInterval = flushInterval
+	return opts
+}
+
+// WithRetryInterval sets the time after which a batch that is not known by the
+// node is broadcast again.
+func (opts Options) WithRetryInterval(retryInterval time.Duration) Options {
+	opts.RetryInterval = retryInterval
+	return opts
+}
+
+// WithMinConf sets the number of confirmations after which a batch is
+// confirmed.
+func (opts Options) WithMinConf(minConf int64) Options {
+	opts.MinConf = minConf
+	return opts
+}
+
+// WithRPCTimeout sets the timeout of each call to the client.
+func (opts Options) WithRPCTimeout(rpcTimeout time.Duration) Options {
+	opts.RPCTimeout = rpcTimeout
+	return opts
+}
+
+// WithFee sets the function used to compute the fee of batches.
+func (opts Options) WithFee(fee FeeFunc) Options {
+	opts.Fee = fee
+	return opts
+}
+
+// WithFeeRate sets the fee rate (per byte) at which the fee of batches is
+// computed.
+func (opts Options) WithFeeRate(feeRate pack.U256) Options {
+	opts.FeeRate = feeRate
+	return opts
+}
+
+// WithDustThreshold sets the minimum value of a change output.
+func (opts Options) WithDustThreshold(dustThreshold pack.U256) Options {
+	opts.DustThreshold = dustThreshold
+	return opts
+}
+
+// WithNow sets the function used to get the current time.
+func (opts Options) WithNow(now func() time.Time) Options {
+	opts.Now = now
+	return opts
+}
+
+// A Batcher queues payment requests, and pays them in batches. It is safe for
+// concurrent use. The wallet must be synced by the caller.
+type Batcher struct {
+	opts      Options
+	client    Client
+	wallet    *wallet.Wallet
+	txBuilder utxo.TxBuilder
+	signer    signer.Signer
+	storage   Storage
+
+	// flushMu serializes flushing and polling, and mu guards the requests and
+	// batches.
+	flushMu  sync.Mutex
+	mu       sync.Mutex
+	requests map[string]Request
+	batches  map[string]Batch
+	flush    chan struct{}
+}
+
+// New returns a Batcher that pays requests using outputs reserved from the
+// wallet, and transactions built by the transaction builder and signed by the
+// signer. The requests and batches in the storage are loaded, so batches that
+// were signed before a restart are tracked, and re-broadcast, as usual.
+func New(client Client, w *wallet.Wallet, txBuilder utxo.TxBuilder, s signer.Signer, storage Storage, opts Options) (*Batcher, error) {
+	if opts.ChangeAddress == "" {
+		return nil, fmt.Errorf("bad change address: expected non-empty")
+	}
+	if opts.MaxBatchSize <= 0 {
+		return nil, fmt.Errorf("bad max batch size: expected greater than zero, got %v", opts.MaxBatchSize)
+	}
+	if opts.FlushInterval <= 0 || opts.RetryInterval <= 0 || opts.RPCTimeout <= 0 {
+		return nil, fmt.Errorf("bad intervals: expected greater than zero")
+	}
+	if opts.Fee == nil {
+		return nil, fmt.Errorf("bad fee: expected non-nil")
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	requests, batches, err := storage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading requests: %v", err)
+	}
+	batcher := &Batcher{
+		opts:      opts,
+		client:    client,
+		wallet:    w,
+		txBuilder: txBuilder,
+		signer:    s,
+		storage:   storage,
+		requests:  make(map[string]Request, len(requests)),
+		batches:   make(map[string]Batch, len(batches)),
+		flush:     make(chan struct{}, 1),
+	}
+	for _, request := range requests {
+		batcher.requests[request.Key] = request
+	}
+	for _, batch := range batches {
+		batcher.batches[batch.ID] = batch
+	}
+	return batcher, nil
+}
+
+// Submit queues a payment to the recipient. The key identifies the request:
+// submitting a request with the key of an existing request, and the same
+// recipient, returns the existing request instead of paying the recipient
+// again. Submitting a different recipient with the same key is an error.
+func (batcher *Batcher) Submit(key string, recipient utxo.Recipient) (Request, error) {
+	if key == "" {
+		return Request{}, fmt.Errorf("bad key: expected non-empty")
+	}
+
+	batcher.mu.Lock()
+	defer batcher.mu.Unlock()
+
+	if request, ok := batcher.requests[key]; ok {
+		if !sameRecipient(request.Recipient, recipient) {
+			return Request{}, fmt.Errorf("%w: %v", ErrKeyConflict, key)
+		}
+		return request.clone(), nil
+	}
+	request := Request{Key: key, Recipient: recipient, Status: StatusQueued, CreatedAt: batcher.opts.Now()}
+	if err := batcher.put(nil, []Request{request}); err != nil {
+		return Request{}, err
+	}
+	if len(batcher.queued()) >= batcher.opts.MaxBatchSize {
+		select {
+		case batcher.flush <- struct{}{}:
+		default:
+		}
+	}
+	return request.clone(), nil
+}
+
+// Request returns the request with the key.
+func (batcher *Batcher) Request(key string) (Request, error) {
+	batcher.mu.Lock()
+	defer batcher.mu.Unlock()
+
+	request, ok := batcher.requests[key]
+	if !ok {
+		return Request{}, fmt.Errorf("%w: %v", ErrUnknownRequest, key)
+	}
+	return request.clone(), nil
+}
+
+// Batch returns the batch with the ID.
+func (batcher *Batcher) Batch(id string) (Batch, error) {
+	batcher.mu.Lock()
+	defer batcher.mu.Unlock()
+
+	batch, ok := batcher.batches[id]
+	if !ok {
+		return Batch{}, fmt.Errorf("%w: %v", ErrUnknownBatch, id)
+	}
+	return batch.clone(), nil
+}
+
+// Run flushes the queue, and polls the batches, whenever the flush interval
+// elapses or the queue reaches the maximum batch size, until the context is
+// done. Errors are logged.
+func (batcher *Batcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(batcher.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-batcher.flush:
+		}
+		if err := batcher.Flush(ctx); err != nil {
+			log.Printf("flushing payouts: %v", err)
+		}
+		if err := batcher.Poll(ctx); err != nil {
+			log.Printf("polling payouts: %v", err)
+		}
+	}
+}
+
+// Flush builds, signs, and submits batches until the queue is empty. Requests
+// are batched in the order in which they were queued. Requests whose
+// recipients cannot be paid fail, and do not stop the other requests from
+// being paid. If there are not enough funds, the requests remain queued.
+func (batcher *Batcher) Flush(ctx context.Context) error {
+	batcher.flushMu.Lock()
+	defer batcher.flushMu.Unlock()
+
+	for {
+		batcher.mu.Lock()
+		requests := batcher.queued()
+		batcher.mu.Unlock()
+		if len(requests) == 0 {
+			return nil
+		}
+		if len(requests) > batcher.opts.MaxBatchSize {
+			requests = requests[:batcher.opts.MaxBatchSize]
+		}
+		if err := batcher.flushBatch(ctx, requests); err != nil {
+			return err
+		}
+	}
+}
+
+// Poll the confirmations of the batches that are not confirmed. Batches that
+// are not known by the node are broadcast again once the retry interval has
+// elapsed since the last attempt, and the reservations of their inputs are
+// renewed on every poll until they are accepted. Batches that are known, but
+// unconfirmed, are left alone. Failed batches are polled, but never broadcast
+// again, in case they were accepted before they failed.
+func (batcher *Batcher) Poll(ctx context.Context) error {
+	batcher.flushMu.Lock()
+	defer batcher.flushMu.Unlock()
+
+	batcher.mu.Lock()
+	batches := make([]Batch, 0, len(batcher.batches))
+	for _, batch := range batcher.batches {
+		if batch.Status != StatusConfirmed {
+			batches = append(batches, batch.clone())
+		}
+	}
+	batcher.mu.Unlock()
+	sort.Slice(batches, func(i, j int) bool { return batches[i].CreatedAt.Before(batches[j].CreatedAt) })
+
+	for _, batch := range batches {
+		rpcCtx, cancel := context.WithTimeout(ctx, batcher.opts.RPCTimeout)
+		confirmations, err := batcher.client.Confirmations(rpcCtx, batch.TxHash)
+		cancel()
+		tx := rawTx{hash: batch.TxHash, serialized: batch.Tx, outputs: batch.Outputs}
+		if err == nil {
+			batch.Confirmations = confirmations
+			batch.Error = ""
+			if batch.Status == StatusSigned || batch.Status == StatusFailed {
+				// The transaction was accepted, even though the broadcast
+				// failed (or the process stopped before it returned).
+				batcher.accepted(&batch, tx)
+			}
+			if confirmations >= batcher.opts.MinConf {
+				batch.Status = StatusConfirmed
+			}
+		} else {
+			batcher.retry(ctx, &batch, tx, err)
+		}
+		if err := batcher.putBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushBatch pays the requests with a single transaction. The flush mutex must
+// be held.
+func (batcher *Batcher) flushBatch(ctx context.Context, requests []Request) error {
+	total := pack.NewU256FromUint64(0)
+	recipients := make([]utxo.Recipient, len(requests))
+	for i, request := range requests {
+		total = total.Add(request.Recipient.Value)
+		recipients[i] = request.Recipient
+	}
+	reservation, fee, err := batcher.reserve(total, len(recipients)+1)
+	if err != nil {
+		return err
+	}
+	release := func(err error) error {
+		if releaseErr := batcher.wallet.Release(reservation.ID); releaseErr != nil {
+			return fmt.Errorf("%v (%v)", err, releaseErr)
+		}
+		return err
+	}
+
+	change := reservation.Value.Sub(total).Sub(fee)
+	if change.GreaterThanEqual(batcher.opts.DustThreshold) {
+		recipients = append(recipients, utxo.Recipient{To: batcher.opts.ChangeAddress, Value: change})
+	} else {
+		fee = fee.Add(change)
+	}
+	tx, err := batcher.txBuilder.BuildTx(reservation.Inputs(), recipients)
+	if err != nil {
+		failed := batcher.isolate(reservation, requests)
+		if len(failed) == 0 {
+			return release(fmt.Errorf("building tx: %v", err))
+		}
+		if err := release(nil); err != nil {
+			return err
+		}
+		return batcher.putRequests(failed)
+	}
+	if err := signer.SignTx(ctx, batcher.signer, tx); err != nil {
+		return release(fmt.Errorf("signing tx: %v", err))
+	}
+	hash, err := tx.Hash()
+	if err != nil {
+		return release(fmt.Errorf("bad tx hash: %v", err))
+	}
+	serialized, err := tx.Serialize()
+	if err != nil {
+		return release(fmt.Errorf("bad tx: %v", err))
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return release(fmt.Errorf("bad outputs: %v", err))
+	}
+	id, err := newBatchID()
+	if err != nil {
+		return release(err)
+	}
+
+	batch := Batch{
+		ID:          id,
+		Status:      StatusSigned,
+		Keys:        make([]string, len(requests)),
+		Reservation: reservation.ID,
+		Tx:          serialized,
+		TxHash:      hash,
+		Outputs:     outputs,
+		Fee:         fee,
+		CreatedAt:   batcher.opts.Now(),
+	}
+	for i := range requests {
+		batch.Keys[i] = requests[i].Key
+		requests[i].Status = StatusSigned
+		requests[i].Batch = batch.ID
+	}
+
+	// The batch must be stored before it is broadcast, so that its requests
+	// are never batched again.
+	batcher.mu.Lock()
+	err = batcher.put([]Batch{batch}, requests)
+	batcher.mu.Unlock()
+	if err != nil {
+		return release(err)
+	}
+	batcher.broadcast(ctx, &batch, tx)
+	return batcher.putBatch(batch)
+}
+
+// reserve outputs with enough value to pay the total, and the fee of a
+// transaction that spends them and has the given number of outputs.
+func (batcher *Batcher) reserve(total pack.U256, numOutputs int) (wallet.Reservation, pack.U256, error) {
+	numInputs := 1
+	for {
+		reservation, err := batcher.wallet.Reserve(total.Add(batcher.opts.Fee(batcher.opts.FeeRate, numInputs, numOutputs)))
+		if err != nil {
+			return wallet.Reservation{}, pack.U256{}, err
+		}
+		fee := batcher.opts.Fee(batcher.opts.FeeRate, len(reservation.Outputs), numOutputs)
+		if reservation.Value.GreaterThanEqual(total.Add(fee)) {
+			return reservation, fee, nil
+		}
+		// More inputs were needed than were paid for. Fees grow with the
+		// number of inputs, so this terminates once the wallet runs out of
+		// outputs.
+		if err := batcher.wallet.Release(reservation.ID); err != nil {
+			return wallet.Reservation{}, pack.U256{}, err
+		}
+		numInputs = len(reservation.Outputs)
+	}
+}
+
+// isolate returns the requests whose recipients cannot be paid, with their
+// status set to failed. Each request is checked by building a transaction
+// that pays only its recipient, and the change.
+func (batcher *Batcher) isolate(reservation wallet.Reservation, requests []Request) []Request {
+	fee := batcher.opts.Fee(batcher.opts.FeeRate, len(reservation.Outputs), 2)
+	failed := []Request{}
+	for _, request := range requests {
+		recipients := []utxo.Recipient{request.Recipient}
+		if reservation.Value.GreaterThanEqual(request.Recipient.Value.Add(fee)) {
+			change := reservation.Value.Sub(request.Recipient.Value).Sub(fee)
+			if change.GreaterThanEqual(batcher.opts.DustThreshold) {
+				recipients = append(recipients, utxo.Recipient{To: batcher.opts.ChangeAddress, Value: change})
+			}
+		}
+		if _, err := batcher.txBuilder.BuildTx(reservation.Inputs(), recipients); err != nil {
+			request.Status = StatusFailed
+			request.Error = err.Error()
+			failed = append(failed, request)
+		}
+	}
+	return failed
+}
+
+// retry a batch that is not known by the node. The reservation of a batch that
+// has not been committed is renewed first, so that its inputs cannot be
+// reserved by another batch. If the reservation has expired, the inputs may
+// already be spent by another batch, so the batch fails instead of being
+// broadcast again. Other errors are recorded, and renewal is retried by the
+// next poll.
+func (batcher *Batcher) retry(ctx context.Context, batch *Batch, tx utxo.Tx, err error) {
+	if batch.Status == StatusFailed {
+		return
+	}
+	if !batch.Committed {
+		if _, renewErr := batcher.wallet.Renew(batch.Reservation); renewErr != nil {
+			if errors.Is(renewErr, wallet.ErrUnknownReservation) {
+				batch.Status = StatusFailed
+			}
+			batch.Error = fmt.Sprintf("renewing reservation: %v", renewErr)
+			return
+		}
+	}
+	if batcher.opts.Now().Sub(batch.BroadcastAt) >= batcher.opts.RetryInterval {
+		batcher.broadcast(ctx, batch, tx)
+		return
+	}
+	batch.Error = fmt.Sprintf("confirming tx: %v", err)
+}
+
+// broadcast submits the transaction of the batch, and records the outcome in
+// the batch.
+func (batcher *Batcher) broadcast(ctx context.Context, batch *Batch, tx utxo.Tx) {
+	rpcCtx, cancel := context.WithTimeout(ctx, batcher.opts.RPCTimeout)
+	defer cancel()
+
+	batch.BroadcastAt = batcher.opts.Now()
+	if err := batcher.client.SubmitTx(rpcCtx, tx); err != nil {
+		batch.Error = fmt.Sprintf("submitting tx: %v", err)
+		return
+	}
+	batch.Broadcasts++
+	batch.Error = ""
+	batcher.accepted(batch, tx)
+}
+
+// accepted marks the batch as submitted, and commits its reservation, after
+// its transaction has been accepted by the node.
+func (batcher *Batcher) accepted(batch *Batch, tx utxo.Tx) {
+	if batch.Status == StatusSigned || batch.Status == StatusFailed {
+		batch.Status = StatusSubmitted
+	}
+	if batch.Committed {
+		return
+	}
+	if err := batcher.wallet.Commit(batch.Reservation, tx); err != nil && !errors.Is(err, wallet.ErrUnknownReservation) {
+		batch.Error = fmt.Sprintf("committing reservation: %v", err)
+		return
+	}
+	batch.Committed = true
+}
+
+// putBatch stores the batch, and updates the status of its requests to match.
+// The requests of a failed batch fail with the error of the batch.
+func (batcher *Batcher) putBatch(batch Batch) error {
+	batcher.mu.Lock()
+	defer batcher.mu.Unlock()
+
+	requests := []Request{}
+	for _, key := range batch.Keys {
+		request := batcher.requests[key]
+		if request.Status != batch.Status {
+			request.Status = batch.Status
+			request.Error = ""
+			if batch.Status == StatusFailed {
+				request.Error = batch.Error
+			}
+			requests = append(requests, request)
+		}
+	}
+	return batcher.put([]Batch{batch}, requests)
+}
+
+func (batcher *Batcher) putRequests(requests []Request) error {
+	batcher.mu.Lock()
+	defer batcher.mu.Unlock()
+
+	return batcher.put(nil, requests)
+}
+
+// put persists the batches and requests, and then stores them in memory. The
+// mutex must be held.
+func (batcher *Batcher) put(batches []Batch, requests []Request) error {
+	if err := batcher.storage.Put(batches, requests); err != nil {
+		return fmt.Errorf("storing requests: %v", err)
+	}
+	for _, batch := range batches {
+		batcher.batches[batch.ID] = batch.clone()
+	}
+	for _, request := range requests {
+		batcher.requests[request.Key] = request.clone()
+	}
+	return nil
+}
+
+// queued returns the queued requests, in the order in which they were queued.
+// The mutex must be held.
+func (batcher *Batcher) queued() []Request {
+	queued := []Request{}
+	for _, request := range batcher.requests {
+		if request.Status == StatusQueued {
+			queued = append(queued, request.clone())
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		if !queued[i].CreatedAt.Equal(queued[j].CreatedAt) {
+			return queued[i].CreatedAt.Before(queued[j].CreatedAt)
+		}
+		return queued[i].Key < queued[j].Key
+	})
+	return queued
+}
+
+func sameRecipient(a, b utxo.Recipient) bool {
+	return a.To == b.To && a.Value.Equal(b.Value) && a.Script.Equal(b.Script) && a.Data.Equal(b.Data)
+}
+
+func newBatchID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("generating batch id: %v", err)
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+// rawTx is a signed transaction that has been serialized. It can be submitted
+// and committed, but not signed again.
+type rawTx struct {
+	hash       pack.Bytes
+	serialized pack.Bytes
+	outputs    []utxo.Output
+}
+
+func (tx rawTx) Hash() (pack.Bytes, error) {
+	return tx.hash, nil
+}
+
+func (tx rawTx) Inputs() ([]utxo.Input, error) {
+	return nil, fmt.Errorf("inputs are not available")
+}
+
+func (tx rawTx) Outputs() ([]utxo.Output, error) {
+	return tx.outputs, nil
+}
+
+func (tx rawTx) Sighashes() ([]pack.Bytes32, error) {
+	return nil, fmt.Errorf("tx is already signed")
+}
+
+func (tx rawTx) Sign([]pack.Bytes65, pack.Bytes) error {
+	return fmt.Errorf("tx is already signed")
+}
+
+func (tx rawTx) Serialize() (pack.Bytes, error) {
+	return tx.serialized, nil
+}
@@ -0,0 +1,13 @@
+package payout_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPayout(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Payout Suite")
+}
@@ -0,0 +1,393 @@
+package payout_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/payout"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/pranav292gpt/zecutil/testutil"
+	"github.com/pranav292gpt/zecutil/wallet"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batcher", func() {
+	params := &chaincfg.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		panic(err)
+	}
+	pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(privKey.PubKey().SerializeCompressed()), params)
+	if err != nil {
+		panic(err)
+	}
+	addr := address.Address(pkhAddr.EncodeAddress())
+
+	var server *testutil.Server
+	var client bitcoin.Client
+	var w *wallet.Wallet
+	var now time.Time
+	var opts payout.Options
+
+	BeforeEach(func() {
+		server = testutil.NewServer(testutil.BitcoinChain(params))
+		client = bitcoin.NewClient(server.ClientOptions())
+		now = time.Unix(1600000000, 0).UTC()
+		clock := func() time.Time { return now }
+		w, err = wallet.New(client, wallet.NewMemoryStorage(), wallet.DefaultOptions().WithNow(clock))
+		Expect(err).ToNot(HaveOccurred())
+		// The options for Bitcoin, which pay 10 SATs-per-byte for the size of
+		// each batch.
+		opts = payout.DefaultOptions().
+			WithChangeAddress(addr).
+			WithFee(payout.LinearFee).
+			WithDustThreshold(pack.NewU256FromUint64(bitcoin.DefaultDustThreshold)).
+			WithRPCTimeout(100 * time.Millisecond).
+			WithNow(clock)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	fund := func(values ...int64) {
+		for _, value := range values {
+			_, err := server.Fund(string(addr), value)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		server.Mine(1)
+		Expect(w.Sync(context.Background(), addr)).To(Succeed())
+	}
+
+	newBatcher := func(storage payout.Storage, opts payout.Options) *payout.Batcher {
+		batcher, err := payout.New(client, w, bitcoin.NewTxBuilder(params), signer.NewKeySigner(privKey, true), storage, opts)
+		Expect(err).ToNot(HaveOccurred())
+		return batcher
+	}
+
+	pay := func(i byte, value uint64) utxo.Recipient {
+		hash := make([]byte, 20)
+		hash[0] = i
+		to, err := btcutil.NewAddressPubKeyHash(hash, params)
+		Expect(err).ToNot(HaveOccurred())
+		return utxo.Recipient{To: address.Address(to.EncodeAddress()), Value: pack.NewU256FromUint64(value)}
+	}
+
+	txID := func(batch payout.Batch) chainhash.Hash {
+		var hash chainhash.Hash
+		copy(hash[:], batch.TxHash)
+		return hash
+	}
+
+	batchOf := func(batcher *payout.Batcher, key string) payout.Batch {
+		request, err := batcher.Request(key)
+		Expect(err).ToNot(HaveOccurred())
+		batch, err := batcher.Batch(request.Batch)
+		Expect(err).ToNot(HaveOccurred())
+		return batch
+	}
+
+	Context("when flushing requests", func() {
+		It("should pay them with a single transaction, and track them until they are confirmed", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts)
+			for i, key := range []string{"a", "b", "c"} {
+				request, err := batcher.Submit(key, pay(byte(i), 10000))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(request.Status).To(Equal(payout.StatusQueued))
+			}
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+
+			batch := batchOf(batcher, "a")
+			Expect(batch.Status).To(Equal(payout.StatusSubmitted))
+			Expect(batch.Keys).To(Equal([]string{"a", "b", "c"}))
+			Expect(batch.Broadcasts).To(Equal(1))
+			Expect(batch.Fee).To(Equal(payout.LinearFee(pack.NewU256FromUint64(payout.DefaultFeeRate), 1, 4)))
+			Expect(batch.Outputs).To(HaveLen(4))
+			Expect(batch.Outputs[3].Value).To(Equal(pack.NewU256FromUint64(70000).Sub(batch.Fee)))
+			Expect(server.Mempool()).To(Equal([]chainhash.Hash{txID(batch)}))
+			for _, key := range []string{"a", "b", "c"} {
+				request, err := batcher.Request(key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(request.Status).To(Equal(payout.StatusSubmitted))
+				Expect(request.Batch).To(Equal(batch.ID))
+			}
+
+			// The change is tracked by the wallet.
+			_, pending := w.Balance()
+			Expect(pending).To(Equal(batch.Outputs[3].Value))
+
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusSubmitted))
+			server.Mine(1)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			batch = batchOf(batcher, "a")
+			Expect(batch.Status).To(Equal(payout.StatusConfirmed))
+			Expect(batch.Confirmations).To(Equal(int64(1)))
+			request, err := batcher.Request("c")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusConfirmed))
+		})
+
+		It("should split the queue into batches of the max size", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts.WithMaxBatchSize(2))
+			keys := []string{"a", "b", "c", "d", "e"}
+			for i, key := range keys {
+				_, err := batcher.Submit(key, pay(byte(i), 10000))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+
+			// Later batches spend the pending change of earlier batches.
+			Expect(server.Mempool()).To(HaveLen(3))
+			Expect(batchOf(batcher, "a").ID).To(Equal(batchOf(batcher, "b").ID))
+			Expect(batchOf(batcher, "c").ID).To(Equal(batchOf(batcher, "d").ID))
+			Expect(batchOf(batcher, "e").Keys).To(Equal([]string{"e"}))
+			Expect(batchOf(batcher, "a").ID).ToNot(Equal(batchOf(batcher, "c").ID))
+		})
+
+		It("should pay the ZIP-317 conventional fee by default", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts.
+				WithFee(payout.DefaultOptions().Fee).
+				WithDustThreshold(pack.NewU256FromUint64(payout.DefaultDustThreshold)))
+			for i, key := range []string{"a", "b", "c"} {
+				_, err := batcher.Submit(key, pay(byte(i), 10000))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+
+			// Four outputs are four logical actions.
+			batch := batchOf(batcher, "a")
+			Expect(batch.Fee).To(Equal(pack.NewU256FromUint64(20000)))
+			Expect(batch.Outputs).To(HaveLen(4))
+			Expect(batch.Outputs[3].Value).To(Equal(pack.NewU256FromUint64(50000)))
+
+			// Change worth less than the marginal fee is added to the fee.
+			_, err := batcher.Submit("d", pay(3, 36000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			batch = batchOf(batcher, "d")
+			Expect(batch.Fee).To(Equal(pack.NewU256FromUint64(14000)))
+			Expect(batch.Outputs).To(HaveLen(1))
+		})
+
+		It("should keep requests queued when there are not enough funds", func() {
+			fund(10000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts)
+			_, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(errors.Is(batcher.Flush(context.Background()), wallet.ErrInsufficientFunds)).To(BeTrue())
+			request, err := batcher.Request("a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusQueued))
+
+			fund(10000)
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusSubmitted))
+		})
+
+		It("should fail requests whose recipients cannot be paid", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts)
+			_, err := batcher.Submit("bad", utxo.Recipient{To: "bad", Value: pack.NewU256FromUint64(10000)})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = batcher.Submit("good", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+
+			request, err := batcher.Request("bad")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusFailed))
+			Expect(request.Error).ToNot(BeEmpty())
+			Expect(request.Batch).To(BeEmpty())
+			Expect(batchOf(batcher, "good").Keys).To(Equal([]string{"good"}))
+			Expect(server.Mempool()).To(HaveLen(1))
+		})
+	})
+
+	Context("when submitting requests", func() {
+		It("should not pay the same key twice", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts)
+			_, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			request, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusQueued))
+			_, err = batcher.Submit("a", pay(0, 20000))
+			Expect(errors.Is(err, payout.ErrKeyConflict)).To(BeTrue())
+			_, err = batcher.Submit("", pay(0, 10000))
+			Expect(err).To(HaveOccurred())
+
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			request, err = batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusSubmitted))
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			Expect(server.Mempool()).To(HaveLen(1))
+
+			_, err = batcher.Request("b")
+			Expect(errors.Is(err, payout.ErrUnknownRequest)).To(BeTrue())
+			_, err = batcher.Batch("b")
+			Expect(errors.Is(err, payout.ErrUnknownBatch)).To(BeTrue())
+		})
+
+		It("should flush when the queue reaches the max batch size", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts.WithMaxBatchSize(2).WithFlushInterval(time.Hour))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go batcher.Run(ctx)
+
+			_, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(server.Mempool, 50*time.Millisecond).Should(BeEmpty())
+			_, err = batcher.Submit("b", pay(1, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(server.Mempool).Should(HaveLen(1))
+		})
+	})
+
+	Context("when retrying batches", func() {
+		It("should broadcast evicted batches again after the retry interval", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts)
+			_, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			batch := batchOf(batcher, "a")
+			Expect(server.Evict(txID(batch))).To(BeTrue())
+
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(server.Mempool()).To(BeEmpty())
+			Expect(batchOf(batcher, "a").Error).ToNot(BeEmpty())
+
+			now = now.Add(payout.DefaultRetryInterval)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(server.Mempool()).To(Equal([]chainhash.Hash{txID(batch)}))
+			Expect(batchOf(batcher, "a").Broadcasts).To(Equal(2))
+
+			server.Mine(1)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusConfirmed))
+		})
+
+		It("should not batch requests again after a restart", func() {
+			fund(100000)
+			dir, err := os.MkdirTemp("", "payout")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "payout.json")
+			storage, err := payout.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			batcher := newBatcher(storage, opts)
+			_, err = batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+
+			// The node rejects the batch, so it is signed but not submitted.
+			server.InjectError("sendrawtransaction", 0, btcjson.NewRPCError(btcjson.ErrRPCMisc, "unavailable"))
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			batch := batchOf(batcher, "a")
+			Expect(batch.Status).To(Equal(payout.StatusSigned))
+			Expect(batch.Error).ToNot(BeEmpty())
+
+			storage, err = payout.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			batcher = newBatcher(storage, opts)
+			request, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusSigned))
+			Expect(request.Batch).To(Equal(batch.ID))
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+
+			server.ClearErrors()
+			now = now.Add(payout.DefaultRetryInterval)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(server.Mempool()).To(Equal([]chainhash.Hash{txID(batch)}))
+			request, err = batcher.Request("a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusSubmitted))
+			server.Mine(1)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusConfirmed))
+		})
+		It("should keep the inputs of batches reserved until they are accepted", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts)
+			_, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			server.InjectError("sendrawtransaction", 0, btcjson.NewRPCError(btcjson.ErrRPCMisc, "unavailable"))
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			batch := batchOf(batcher, "a")
+
+			// The reservation is renewed by every poll, so it outlives its TTL.
+			for elapsed := time.Duration(0); elapsed <= 2*wallet.DefaultReservationTTL; elapsed += payout.DefaultFlushInterval {
+				now = now.Add(payout.DefaultFlushInterval)
+				Expect(batcher.Poll(context.Background())).To(Succeed())
+				Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusSigned))
+			}
+			_, err = w.Reserve(pack.NewU256FromUint64(1))
+			Expect(err).To(HaveOccurred())
+
+			server.ClearErrors()
+			now = now.Add(payout.DefaultRetryInterval)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(server.Mempool()).To(Equal([]chainhash.Hash{txID(batch)}))
+			Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusSubmitted))
+		})
+
+		It("should fail batches whose reservation expired before they were accepted", func() {
+			fund(100000)
+			batcher := newBatcher(payout.NewMemoryStorage(), opts)
+			_, err := batcher.Submit("a", pay(0, 10000))
+			Expect(err).ToNot(HaveOccurred())
+			server.InjectError("sendrawtransaction", 0, btcjson.NewRPCError(btcjson.ErrRPCMisc, "unavailable"))
+			Expect(batcher.Flush(context.Background())).To(Succeed())
+			server.ClearErrors()
+
+			now = now.Add(wallet.DefaultReservationTTL)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusFailed))
+			request, err := batcher.Request("a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.Status).To(Equal(payout.StatusFailed))
+			Expect(request.Error).To(ContainSubstring("renewing reservation"))
+
+			// Failed batches are never broadcast again.
+			now = now.Add(payout.DefaultRetryInterval)
+			Expect(batcher.Poll(context.Background())).To(Succeed())
+			Expect(server.Mempool()).To(BeEmpty())
+			Expect(batchOf(batcher, "a").Status).To(Equal(payout.StatusFailed))
+		})
+	})
+
+	Context("when creating a batcher", func() {
+		It("should return an error for bad options", func() {
+			newWithOpts := func(opts payout.Options) error {
+				_, err := payout.New(client, w, bitcoin.NewTxBuilder(params), signer.NewKeySigner(privKey, true), payout.NewMemoryStorage(), opts)
+				return err
+			}
+			Expect(newWithOpts(opts)).To(Succeed())
+			Expect(newWithOpts(opts.WithChangeAddress(""))).ToNot(Succeed())
+			Expect(newWithOpts(opts.WithMaxBatchSize(0))).ToNot(Succeed())
+			Expect(newWithOpts(opts.WithRetryInterval(0))).ToNot(Succeed())
+			Expect(newWithOpts(opts.WithFee(nil))).ToNot(Succeed())
+		})
+	})
+})
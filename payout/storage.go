@@ -0,0 +1,162 @@
+package payout
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pranav292gpt/zecutil/internal/jsonfile"
+)
+
+// The Storage interface defines the functionality required to persist the
+// requests and batches of a Batcher. Requests are identified by their keys,
+// and batches by their IDs. A batch, and the requests that it pays, must be
+// stored atomically, so that a request is never lost between the queue and a
+// batch.
+type Storage interface {
+	// Load returns all of the stored requests and batches.
+	Load() ([]Request, []Batch, error)
+	// Put stores the batches and requests, replacing any stored batches and
+	// requests with the same IDs and keys.
+	Put(batches []Batch, requests []Request) error
+}
+
+// MemoryStorage is a Storage that keeps requests and batches in memory. They
+// are lost when the process exits.
+type MemoryStorage struct {
+	mu    *sync.Mutex
+	state *state
+}
+
+// NewMemoryStorage returns an empty MemoryStorage.
+func NewMemoryStorage() MemoryStorage {
+	return MemoryStorage{mu: new(sync.Mutex), state: newState()}
+}
+
+// Load returns all of the stored requests and batches.
+func (storage MemoryStorage) Load() ([]Request, []Batch, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	requests, batches := storage.state.sorted()
+	return requests, batches, nil
+}
+
+// Put stores the batches and requests in memory.
+func (storage MemoryStorage) Put(batches []Batch, requests []Request) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	storage.state.put(batches, requests)
+	return nil
+}
+
+// FileStorage is a Storage that keeps requests and batches in a JSON file. The
+// file is replaced on every change, so a crash leaves either the old or the new
+// contents, and never a partial write.
+type FileStorage struct {
+	path  string
+	mu    *sync.Mutex
+	state *state
+}
+
+// fileContents is the format of the file of a FileStorage.
+type fileContents struct {
+	Requests []Request `json:"requests"`
+	Batches  []Batch   `json:"batches"`
+}
+
+// NewFileStorage returns a FileStorage that keeps requests and batches in the
+// file at the given path. The file is loaded if it exists, and is created by
+// the first change if it does not.
+func NewFileStorage(path string) (FileStorage, error) {
+	storage := FileStorage{path: path, mu: new(sync.Mutex), state: newState()}
+	contents := fileContents{}
+	if _, err := jsonfile.Read(path, &contents); err != nil {
+		return FileStorage{}, err
+	}
+	storage.state.put(contents.Batches, contents.Requests)
+	return storage, nil
+}
+
+// Load returns all of the stored requests and batches.
+func (storage FileStorage) Load() ([]Request, []Batch, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	requests, batches := storage.state.sorted()
+	return requests, batches, nil
+}
+
+// Put stores the batches and requests, and replaces the file. Nothing is
+// stored if the file cannot be replaced.
+func (storage FileStorage) Put(batches []Batch, requests []Request) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	next := storage.state.clone()
+	next.put(batches, requests)
+	contents := fileContents{}
+	contents.Requests, contents.Batches = next.sorted()
+	if err := jsonfile.Write(storage.path, contents); err != nil {
+		return err
+	}
+	*storage.state = *next
+	return nil
+}
+
+// state is the set of requests and batches held by a Storage.
+type state struct {
+	requests map[string]Request
+	batches  map[string]Batch
+}
+
+func newState() *state {
+	return &state{requests: map[string]Request{}, batches: map[string]Batch{}}
+}
+
+func (s *state) put(batches []Batch, requests []Request) {
+	for _, batch := range batches {
+		s.batches[batch.ID] = batch.clone()
+	}
+	for _, request := range requests {
+		s.requests[request.Key] = request.clone()
+	}
+}
+
+func (s *state) clone() *state {
+	next := newState()
+	for id, batch := range s.batches {
+		next.batches[id] = batch
+	}
+	for key, request := range s.requests {
+		next.requests[key] = request
+	}
+	return next
+}
+
+// sorted returns copies of the requests, ordered by the time at which they
+// were queued, and of the batches, ordered by the time at which they were
+// created.
+func (s *state) sorted() ([]Request, []Batch) {
+	requests := make([]Request, 0, len(s.requests))
+	for _, request := range s.requests {
+		requests = append(requests, request.clone())
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if !requests[i].CreatedAt.Equal(requests[j].CreatedAt) {
+			return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+		}
+		return requests[i].Key < requests[j].Key
+	})
+	batches := make([]Batch, 0, len(s.batches))
+	for _, batch := range s.batches {
+		batches = append(batches, batch.clone())
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		if !batches[i].CreatedAt.Equal(batches[j].CreatedAt) {
+			return batches[i].CreatedAt.Before(batches[j].CreatedAt)
+		}
+		return batches[i].ID < batches[j].ID
+	})
+	return requests, batches
+}
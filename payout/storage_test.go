@@ -0,0 +1,120 @@
+package payout_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/payout"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "payout")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	createdAt := time.Unix(1600000000, 0).UTC()
+
+	request := func(key string, status payout.Status) payout.Request {
+		return payout.Request{
+			Key:       key,
+			Recipient: utxo.Recipient{To: address.Address("addr"), Value: pack.NewU256FromUint64(1000)},
+			Status:    status,
+			CreatedAt: createdAt,
+		}
+	}
+
+	batch := func(id string, keys ...string) payout.Batch {
+		return payout.Batch{
+			ID:        id,
+			Status:    payout.StatusSigned,
+			Keys:      keys,
+			Tx:        pack.Bytes{1, 2, 3},
+			TxHash:    pack.Bytes{4, 5, 6},
+			Fee:       pack.NewU256FromUint64(100),
+			CreatedAt: createdAt,
+		}
+	}
+
+	testStorage := func(storage payout.Storage) {
+		requests, batches, err := storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(BeEmpty())
+		Expect(batches).To(BeEmpty())
+
+		Expect(storage.Put(nil, []payout.Request{request("b", payout.StatusQueued), request("a", payout.StatusQueued)})).To(Succeed())
+		Expect(storage.Put([]payout.Batch{batch("x", "a")}, []payout.Request{request("a", payout.StatusSigned)})).To(Succeed())
+		requests, batches, err = storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(Equal([]payout.Request{request("a", payout.StatusSigned), request("b", payout.StatusQueued)}))
+		Expect(batches).To(Equal([]payout.Batch{batch("x", "a")}))
+
+		// Changing the loaded batches does not change the stored batches.
+		batches[0].Keys[0] = "b"
+		_, batches, err = storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(batches).To(Equal([]payout.Batch{batch("x", "a")}))
+	}
+
+	Context("when storing requests in memory", func() {
+		It("should put and load requests and batches", func() {
+			testStorage(payout.NewMemoryStorage())
+		})
+	})
+
+	Context("when storing requests in a file", func() {
+		It("should put and load requests and batches", func() {
+			storage, err := payout.NewFileStorage(filepath.Join(dir, "payout.json"))
+			Expect(err).ToNot(HaveOccurred())
+			testStorage(storage)
+		})
+
+		It("should load the requests and batches when the file is opened again", func() {
+			path := filepath.Join(dir, "payout.json")
+			storage, err := payout.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storage.Put([]payout.Batch{batch("x", "a")}, []payout.Request{request("a", payout.StatusSigned)})).To(Succeed())
+
+			storage, err = payout.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			requests, batches, err := storage.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]payout.Request{request("a", payout.StatusSigned)}))
+			Expect(batches).To(Equal([]payout.Batch{batch("x", "a")}))
+		})
+
+		It("should not store anything when the file cannot be written", func() {
+			storage, err := payout.NewFileStorage(filepath.Join(dir, "payout.json"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storage.Put(nil, []payout.Request{request("a", payout.StatusQueued)})).To(Succeed())
+
+			Expect(os.RemoveAll(dir)).To(Succeed())
+			Expect(storage.Put([]payout.Batch{batch("x", "a")}, []payout.Request{request("a", payout.StatusSigned)})).ToNot(Succeed())
+			requests, batches, err := storage.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal([]payout.Request{request("a", payout.StatusQueued)}))
+			Expect(batches).To(BeEmpty())
+		})
+
+		It("should return an error for a malformed file", func() {
+			path := filepath.Join(dir, "payout.json")
+			Expect(os.WriteFile(path, []byte("{"), 0600)).To(Succeed())
+			_, err := payout.NewFileStorage(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
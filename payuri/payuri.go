@@ -0,0 +1,469 @@
+// Package payuri parses and builds payment request URIs. Bitcoin-like chains
+// use BIP-21 URIs, which request a single payment:
+//
+//	bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=20.3&label=Luke-Jr
+//
+// Zcash uses ZIP-321 URIs, which can request several payments by suffixing
+// the parameters of all but the first payment with an index:
+//
+//	zcash:?address=tmEZh...&amount=1&address.1=zs1...&amount.1=2&memo.1=SGk
+//
+// Addresses are validated by the address decoder of the chain, and amounts are
+// parsed exactly, without going through a floating point representation. The
+// addresses of ZIP-321 URIs can be transparent, TEX, Sapling, or unified
+// addresses, and memos can only be sent to Sapling and unified addresses.
+package payuri
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/pack"
+)
+
+// maxIndex is the largest payment index of a ZIP-321 URI. Indices have at most
+// four digits.
+const maxIndex = 9999
+
+// A Payment is one of the payments requested by a URI.
+type Payment struct {
+	Address address.Address
+	// Amount is the requested amount. A zero amount is left to the payer, and
+	// is omitted from built URIs.
+	Amount zcash.Amount
+	// Memo is the ZIP-302 memo of a payment to a shielded address, or nil.
+	Memo    *zcash.Memo
+	Label   string
+	Message string
+	// Other holds the parameters that are not understood, and are not
+	// required.
+	Other map[string]string
+}
+
+// A Scheme parses and builds the payment request URIs of a chain.
+type Scheme struct {
+	name    string
+	decoder address.Decoder
+	zip321  bool
+	// kinds reports the kinds of the addresses of ZIP-321 URIs, so that
+	// memos are only sent to shielded addresses.
+	kinds  zcash.PaymentAddressDecoder
+	params *zcash.Params
+}
+
+// BIP21 returns the Scheme for BIP-21 URIs with the given scheme name, such as
+// "bitcoin".
+func BIP21(name string, decoder address.Decoder) Scheme {
+	return Scheme{name: name, decoder: decoder}
+}
+
+// ZIP321 returns the Scheme for ZIP-321 "zcash:" URIs on the network.
+func ZIP321(params *zcash.Params) Scheme {
+	decoder := zcash.NewPaymentAddressDecoder(params)
+	return Scheme{name: "zcash", decoder: decoder, zip321: true, kinds: decoder, params: params}
+}
+
+// Parse the payments requested by a URI. An error is returned if the URI is
+// malformed, if an address cannot be decoded, if an amount is invalid, or if
+// the URI has a required parameter ("req-") that is not understood.
+func (scheme Scheme) Parse(uri string) ([]Payment, error) {
+	i := strings.IndexByte(uri, ':')
+	if i < 0 || !strings.EqualFold(uri[:i], scheme.name) {
+		return nil, fmt.Errorf("bad scheme: expected %v", scheme.name)
+	}
+	path, query := uri[i+1:], ""
+	if j := strings.IndexByte(path, '?'); j >= 0 {
+		path, query = path[:j], path[j+1:]
+	}
+
+	payments := map[int]*Payment{}
+	seen := map[string]bool{}
+	payment := func(index int) *Payment {
+		if payments[index] == nil {
+			payments[index] = &Payment{}
+		}
+		return payments[index]
+	}
+	if path != "" {
+		if !isAlphaNum(path) {
+			return nil, fmt.Errorf("bad address %q: unexpected character", path)
+		}
+		payment(0).Address = address.Address(path)
+		seen["address"] = true
+	} else if !scheme.zip321 {
+		return nil, fmt.Errorf("bad address: expected non-empty")
+	}
+
+	if query != "" {
+		for _, param := range strings.Split(query, "&") {
+			// Every parameter is optional in the grammars of both BIP-21
+			// and ZIP-321, so empty parameters are allowed.
+			if param == "" {
+				continue
+			}
+			if err := scheme.parseParam(param, payment, seen); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	indices := make([]int, 0, len(payments))
+	for index := range payments {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	result := make([]Payment, 0, len(indices))
+	total := zcash.Amount(0)
+	for _, index := range indices {
+		p := *payments[index]
+		if p.Address == "" {
+			return nil, fmt.Errorf("bad payment %v: expected address", index)
+		}
+		if err := scheme.checkAddress(index, p); err != nil {
+			return nil, err
+		}
+		var err error
+		if total, err = total.Add(p.Amount); err != nil {
+			return nil, fmt.Errorf("bad total amount: %v", err)
+		}
+		if err := total.Validate(); err != nil {
+			return nil, fmt.Errorf("bad total amount: %v", err)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// parseParam parses a "name=value" parameter into the payment with its index.
+func (scheme Scheme) parseParam(param string, payment func(int) *Payment, seen map[string]bool) error {
+	name, value := param, ""
+	if i := strings.IndexByte(param, '='); i >= 0 {
+		name, value = param[:i], param[i+1:]
+	}
+	if !isQChars(value) {
+		return fmt.Errorf("bad parameter %q: unexpected character", name)
+	}
+	key, index := name, 0
+	if scheme.zip321 {
+		var err error
+		if key, index, err = parseIndex(name); err != nil {
+			return err
+		}
+	}
+	if seen[name] {
+		return fmt.Errorf("bad parameter %q: duplicated", name)
+	}
+	seen[name] = true
+
+	switch {
+	case key == "address" && scheme.zip321:
+		if !isAlphaNum(value) || value == "" {
+			return fmt.Errorf("bad address %q: unexpected character", value)
+		}
+		payment(index).Address = address.Address(value)
+	case key == "amount":
+		amount, err := parseAmount(value)
+		if err != nil {
+			return err
+		}
+		payment(index).Amount = amount
+	case key == "memo" && scheme.zip321:
+		b, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("bad memo: %v", err)
+		}
+		memo, err := zcash.ParseMemo(b)
+		if err != nil {
+			return err
+		}
+		payment(index).Memo = &memo
+	case key == "label" || key == "message":
+		text, err := url.PathUnescape(value)
+		if err != nil {
+			return fmt.Errorf("bad %v: %v", key, err)
+		}
+		if key == "label" {
+			payment(index).Label = text
+		} else {
+			payment(index).Message = text
+		}
+	case strings.HasPrefix(key, "req-"):
+		return fmt.Errorf("bad parameter %q: required parameter is not supported", name)
+	default:
+		text, err := url.PathUnescape(value)
+		if err != nil {
+			return fmt.Errorf("bad parameter %q: %v", name, err)
+		}
+		p := payment(index)
+		if p.Other == nil {
+			p.Other = map[string]string{}
+		}
+		p.Other[key] = text
+	}
+	return nil
+}
+
+// parseIndex splits a ZIP-321 parameter name into its key and payment index.
+// Indices do not have leading zeros, so the first payment has no index.
+func parseIndex(name string) (string, int, error) {
+	i := strings.IndexByte(name, '.')
+	if i < 0 {
+		return name, 0, nil
+	}
+	digits := name[i+1:]
+	if len(digits) == 0 || len(digits) > 4 || digits[0] == '0' {
+		return "", 0, fmt.Errorf("bad parameter %q: bad index", name)
+	}
+	index, err := strconv.Atoi(digits)
+	if err != nil || index > maxIndex {
+		return "", 0, fmt.Errorf("bad parameter %q: bad index", name)
+	}
+	return name[:i], index, nil
+}
+
+// parseAmount parses a decimal amount with at most eight decimal places. Unlike
+// zcash.ParseAmount, signs and missing digits are not allowed.
+func parseAmount(value string) (zcash.Amount, error) {
+	whole, frac := value, ""
+	hasFrac := false
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		whole, frac, hasFrac = value[:i], value[i+1:], true
+	}
+	if whole == "" || (hasFrac && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("bad amount %q: expected decimal digits", value)
+	}
+	amount, err := zcash.ParseAmount(value)
+	if err != nil {
+		return 0, fmt.Errorf("bad amount: %v", err)
+	}
+	return amount, nil
+}
+
+// Build a URI that requests the payments. BIP-21 URIs can only request one
+// payment, and cannot have memos. A single payment is requested using the path
+// of the URI, and several payments are requested using indexed parameters.
+func (scheme Scheme) Build(payments ...Payment) (string, error) {
+	if len(payments) == 0 {
+		return "", fmt.Errorf("bad payments: expected at least one payment")
+	}
+	if !scheme.zip321 && len(payments) > 1 {
+		return "", fmt.Errorf("bad payments: expected one payment, got %v", len(payments))
+	}
+	if len(payments) > maxIndex+1 {
+		return "", fmt.Errorf("bad payments: expected at most %v payments, got %v", maxIndex+1, len(payments))
+	}
+
+	var params []string
+	path := ""
+	total := zcash.Amount(0)
+	for index, p := range payments {
+		if p.Address == "" || !isAlphaNum(string(p.Address)) {
+			return "", fmt.Errorf("bad address %q: unexpected character", p.Address)
+		}
+		if p.Memo != nil && !scheme.zip321 {
+			return "", fmt.Errorf("bad payment %v: memos are not supported by %v URIs", index, scheme.name)
+		}
+		if err := scheme.checkAddress(index, p); err != nil {
+			return "", err
+		}
+		if err := p.Amount.Validate(); err != nil {
+			return "", fmt.Errorf("bad amount: %v", err)
+		}
+		var err error
+		if total, err = total.Add(p.Amount); err != nil || total.Validate() != nil {
+			return "", fmt.Errorf("bad total amount: expected at most %v", zcash.Amount(zcash.MaxZatoshi))
+		}
+
+		suffix := ""
+		if index > 0 {
+			suffix = "." + strconv.Itoa(index)
+		}
+		if len(payments) == 1 {
+			path = string(p.Address)
+		} else {
+			params = append(params, "address"+suffix+"="+string(p.Address))
+		}
+		if p.Amount != 0 {
+			params = append(params, "amount"+suffix+"="+formatAmount(p.Amount))
+		}
+		if p.Memo != nil {
+			params = append(params, "memo"+suffix+"="+base64.RawURLEncoding.EncodeToString(p.Memo.Bytes()))
+		}
+		if p.Label != "" {
+			params = append(params, "label"+suffix+"="+escape(p.Label))
+		}
+		if p.Message != "" {
+			params = append(params, "message"+suffix+"="+escape(p.Message))
+		}
+		keys := make([]string, 0, len(p.Other))
+		for key := range p.Other {
+			if !isParamName(key) || strings.HasPrefix(key, "req-") {
+				return "", fmt.Errorf("bad parameter %q: expected optional parameter name", key)
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			params = append(params, key+suffix+"="+escape(p.Other[key]))
+		}
+	}
+
+	uri := scheme.name + ":" + path
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+	return uri, nil
+}
+
+// Recipients returns the recipients that pay the payments, for use with
+// utxo.TxBuilder. Payments to TEX addresses are paid to the P2PKH script of
+// their pubkey hash, since TEX addresses cannot be decoded by transaction
+// builders. An error is returned if a payment has no amount, has a memo, or is
+// to a Sapling or unified address, since transparent transactions can only pay
+// transparent and TEX addresses.
+func (scheme Scheme) Recipients(payments []Payment) ([]utxo.Recipient, error) {
+	recipients := make([]utxo.Recipient, len(payments))
+	for i, p := range payments {
+		if p.Amount <= 0 {
+			return nil, fmt.Errorf("bad payment %v: expected amount", i)
+		}
+		if p.Memo != nil {
+			return nil, fmt.Errorf("bad payment %v: memos cannot be paid by transparent transactions", i)
+		}
+		recipient, err := scheme.recipient(p)
+		if err != nil {
+			return nil, fmt.Errorf("bad payment %v: %v", i, err)
+		}
+		recipients[i] = recipient
+	}
+	return recipients, nil
+}
+
+// recipient returns the recipient that pays the payment.
+func (scheme Scheme) recipient(p Payment) (utxo.Recipient, error) {
+	value := pack.NewU256FromUint64(uint64(p.Amount))
+	if !scheme.zip321 {
+		return utxo.Recipient{To: p.Address, Value: value}, nil
+	}
+	kind, err := scheme.kinds.AddressKind(p.Address)
+	if err != nil {
+		return utxo.Recipient{}, fmt.Errorf("bad address %v: %v", p.Address, err)
+	}
+	switch kind {
+	case zcash.AddressTransparent:
+		return utxo.Recipient{To: p.Address, Value: value}, nil
+	case zcash.AddressTEX:
+		pubKeyHash, err := scheme.kinds.DecodeAddress(p.Address)
+		if err != nil {
+			return utxo.Recipient{}, fmt.Errorf("bad address %v: %v", p.Address, err)
+		}
+		addr, err := zcash.NewAddressPubKeyHash(pubKeyHash, scheme.params)
+		if err != nil {
+			return utxo.Recipient{}, fmt.Errorf("bad address %v: %v", p.Address, err)
+		}
+		script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+		if err != nil {
+			return utxo.Recipient{}, fmt.Errorf("bad address %v: %v", p.Address, err)
+		}
+		return utxo.Recipient{Script: script, Value: value}, nil
+	default:
+		return utxo.Recipient{}, fmt.Errorf("%v address %v cannot be paid by transparent transactions", kind, p.Address)
+	}
+}
+
+// formatAmount formats an amount as a decimal without trailing zeros.
+func formatAmount(amount zcash.Amount) string {
+	value := strings.TrimSuffix(amount.Format(zcash.AmountZEC), " "+zcash.AmountZEC.String())
+	value = strings.TrimRight(value, "0")
+	return strings.TrimSuffix(value, ".")
+}
+
+// checkAddress returns an error if the address of the payment cannot be
+// decoded, or if the payment has a memo and its address is not shielded.
+func (scheme Scheme) checkAddress(index int, p Payment) error {
+	if !scheme.zip321 {
+		if _, err := scheme.decoder.DecodeAddress(p.Address); err != nil {
+			return fmt.Errorf("bad address %v: %v", p.Address, err)
+		}
+		return nil
+	}
+	kind, err := scheme.kinds.AddressKind(p.Address)
+	if err != nil {
+		return fmt.Errorf("bad address %v: %v", p.Address, err)
+	}
+	if p.Memo != nil && !kind.IsShielded() {
+		return fmt.Errorf("bad payment %v: memo cannot be sent to %v address %v", index, kind, p.Address)
+	}
+	return nil
+}
+
+// escape percent-encodes the characters that are not qchars.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreserved(c) || strings.IndexByte("!$'()*+,;:@", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// isQChars returns true if the string only has qchars, which are unreserved
+// characters, percent-encodings, and the delimiters allowed in values.
+func isQChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isUnreserved(c) && strings.IndexByte("!$'()*+,;:@%", c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isUnreserved(c byte) bool {
+	return isAlphaNum(string(c)) || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isAlphaNum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isParamName returns true if the string is a parameter name, which starts
+// with a letter, and has letters, digits, "+", and "-".
+func isParamName(s string) bool {
+	if s == "" || isDigits(s[:1]) || !isAlphaNum(s[:1]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isAlphaNum(s[i:i+1]) && s[i] != '+' && s[i] != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,13 @@
+package payuri_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPayuri(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Payuri Suite")
+}
@@ -0,0 +1,296 @@
+package payuri_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/payuri"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Payment URIs", func() {
+	const (
+		tAddr = "tmEZhbWHTpdKMw5it8YDspUXSMGQyFwovpU"
+		zAddr = "ztestsapling10yy2ex5dcqkclhc7z7yrnjq2z6feyjad56ptwlfgmy77dmaqqrl9gyhprdx59qgmsnyfska2kez"
+	)
+	textMemo := func(text string) *zcash.Memo {
+		memo, err := zcash.NewTextMemo(text)
+		if err != nil {
+			panic(err)
+		}
+		return &memo
+	}
+	noMemo := zcash.NoMemo()
+	dataMemo, err := zcash.NewDataMemo([]byte{0, 1, 2})
+	if err != nil {
+		panic(err)
+	}
+
+	zip321 := payuri.ZIP321(&zcash.TestNet3Params)
+	mainnetZIP321 := payuri.ZIP321(&zcash.MainNetParams)
+	// uAddr is a mainnet unified address, and texAddr is a mainnet TEX
+	// address.
+	const (
+		uAddr   = "u1pg2aaph7jp8rpf6yhsza25722sg5fcn3vaca6ze27hqjw7jvvhhuxkpcg0ge9xh6drsgdkda8qjq5chpehkcpxf87rnjryjqwymdheptpvnljqqrjqzjwkc2ma6hcq666kgwfytxwac8eyex6ndgr6ezte66706e3vaqrd25dzvzkc69kw0jgywtd0cmq52q5lkw6uh7hyvzjse8ksx"
+		texAddr = "tex1s2rt77ggv6q989lr49rkgzmh5slsksa9khdgte"
+	)
+	bip21 := payuri.BIP21("bitcoin", bitcoin.NewAddressDecoder(&chaincfg.MainNetParams))
+
+	Context("when parsing ZIP-321 URIs", func() {
+		It("should parse a single payment", func() {
+			payments, err := zip321.Parse("zcash:" + zAddr + "?amount=1&memo=VGhpcyBpcyBhIHNpbXBsZSBtZW1vLg&message=Thank%20you%20for%20your%20purchase")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payments).To(Equal([]payuri.Payment{{
+				Address: zAddr,
+				Amount:  zcash.Amount(100000000),
+				Memo:    textMemo("This is a simple memo."),
+				Message: "Thank you for your purchase",
+			}}))
+		})
+
+		It("should parse several payments", func() {
+			payments, err := zip321.Parse("zcash:?address=" + tAddr + "&amount=123.456&address.1=" + zAddr + "&amount.1=0.789&memo.1=VGhpcyBpcyBhIHVuaWNvZGUgbWVtbyDinKjwn6aE8J-PhvCfjok")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payments).To(Equal([]payuri.Payment{
+				{Address: tAddr, Amount: zcash.Amount(12345600000)},
+				{Address: zAddr, Amount: zcash.Amount(78900000), Memo: textMemo("This is a unicode memo ✨🦄🏆🎉")},
+			}))
+		})
+
+		It("should order payments by index", func() {
+			payments, err := zip321.Parse("zcash:?amount.5=2&address.5=" + tAddr + "&address.2=" + zAddr + "&label.2=Two&&other.2=x")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payments).To(HaveLen(2))
+			Expect(payments[0].Address).To(Equal(address.Address(zAddr)))
+			Expect(payments[0].Label).To(Equal("Two"))
+			Expect(payments[0].Other).To(Equal(map[string]string{"other": "x"}))
+			Expect(payments[1].Address).To(Equal(address.Address(tAddr)))
+			Expect(payments[1].Amount).To(Equal(zcash.Amount(200000000)))
+		})
+
+		It("should keep parameters that are not understood", func() {
+			payments, err := zip321.Parse("zcash:" + tAddr + "?amount=1&foo=b%26r&bar")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payments[0].Other).To(Equal(map[string]string{"foo": "b&r", "bar": ""}))
+		})
+
+		It("should accept the scheme in any case", func() {
+			_, err := zip321.Parse("ZCash:" + tAddr)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		DescribeTable("should reject invalid URIs",
+			func(uri string) {
+				_, err := zip321.Parse(uri)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("wrong scheme", "bitcoin:"+tAddr),
+			Entry("no address", "zcash:?amount=1"),
+			Entry("payment without address", "zcash:"+tAddr+"?amount.1=1"),
+			Entry("undecodable address", "zcash:tmEZhbWHTpdKMw5it8YDspUXSMGQyFwovpV"),
+			Entry("address in path and parameter", "zcash:"+tAddr+"?address="+tAddr),
+			Entry("duplicate amount", "zcash:"+tAddr+"?amount=1&amount=2"),
+			Entry("duplicate indexed address", "zcash:?address.1="+tAddr+"&address.1="+zAddr),
+			Entry("zero index", "zcash:?address.0="+tAddr),
+			Entry("leading zero index", "zcash:?address.01="+tAddr),
+			Entry("long index", "zcash:?address.10000="+tAddr),
+			Entry("empty index", "zcash:?address.="+tAddr),
+			Entry("negative amount", "zcash:"+tAddr+"?amount=-1"),
+			Entry("too many decimals", "zcash:"+tAddr+"?amount=1.000000001"),
+			Entry("two decimal points", "zcash:"+tAddr+"?amount=1.2.3"),
+			Entry("trailing decimal point", "zcash:"+tAddr+"?amount=1."),
+			Entry("leading decimal point", "zcash:"+tAddr+"?amount=.1"),
+			Entry("amount above the maximum", "zcash:"+tAddr+"?amount=21000000.00000001"),
+			Entry("total above the maximum", "zcash:?address="+tAddr+"&amount=3491405.05201255&address.1="+zAddr+"&amount.1=17540296.87793245"),
+			Entry("memo to a transparent address", "zcash:"+tAddr+"?memo=SGk"),
+			Entry("padded memo", "zcash:"+zAddr+"?memo=SGk="),
+			Entry("oversized memo", "zcash:"+zAddr+"?memo="+strings.Repeat("A", 684)),
+			Entry("unknown required parameter", "zcash:"+tAddr+"?req-foo=bar"),
+			Entry("bad percent-encoding", "zcash:"+tAddr+"?message=%zz"),
+			Entry("bad character", "zcash:"+tAddr+"?message=a b"),
+		)
+	})
+
+	Context("when parsing BIP-21 URIs", func() {
+		It("should parse a payment", func() {
+			payments, err := bip21.Parse("bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=20.3&label=Luke-Jr&message=Donation%20for%20project%20xyz")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payments).To(Equal([]payuri.Payment{{
+				Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+				Amount:  zcash.Amount(2030000000),
+				Label:   "Luke-Jr",
+				Message: "Donation for project xyz",
+			}}))
+		})
+
+		It("should not treat indexed parameters as payments", func() {
+			payments, err := bip21.Parse("bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount.1=20.3&memo=SGk")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payments).To(HaveLen(1))
+			Expect(payments[0].Amount).To(Equal(zcash.Amount(0)))
+			Expect(payments[0].Memo).To(BeNil())
+			Expect(payments[0].Other).To(Equal(map[string]string{"amount.1": "20.3", "memo": "SGk"}))
+		})
+
+		DescribeTable("should reject invalid URIs",
+			func(uri string) {
+				_, err := bip21.Parse(uri)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("no address", "bitcoin:?amount=1"),
+			Entry("undecodable address", "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3"),
+			Entry("unknown required parameter", "bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?req-somethingyoudontunderstand=50"),
+		)
+	})
+
+	Context("when building URIs", func() {
+		It("should build a single payment in the path", func() {
+			uri, err := zip321.Build(payuri.Payment{
+				Address: zAddr,
+				Amount:  zcash.Amount(100000000),
+				Memo:    textMemo("This is a simple memo."),
+				Message: "Thank you for your purchase",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uri).To(Equal("zcash:" + zAddr + "?amount=1&memo=VGhpcyBpcyBhIHNpbXBsZSBtZW1vLg&message=Thank%20you%20for%20your%20purchase"))
+		})
+
+		It("should build several payments with indexed parameters", func() {
+			uri, err := zip321.Build(
+				payuri.Payment{Address: tAddr, Amount: zcash.Amount(12345600000)},
+				payuri.Payment{Address: zAddr, Amount: zcash.Amount(78900000), Label: "a&b=c", Other: map[string]string{"foo": "bar"}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uri).To(Equal("zcash:?address=" + tAddr + "&amount=123.456&address.1=" + zAddr + "&amount.1=0.789&label.1=a%26b%3Dc&foo.1=bar"))
+		})
+
+		It("should round-trip payments", func() {
+			payments := []payuri.Payment{
+				{Address: tAddr, Amount: zcash.Amount(1), Message: "100% paid, thanks!"},
+				{Address: zAddr, Memo: &noMemo, Label: "Ünïcode ✨"},
+				{Address: zAddr, Memo: &dataMemo, Amount: zcash.Amount(2)},
+				{Address: tAddr, Amount: zcash.Amount(zcash.MaxZatoshi - 3)},
+			}
+			uri, err := zip321.Build(payments...)
+			Expect(err).ToNot(HaveOccurred())
+			parsed, err := zip321.Parse(uri)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(payments))
+		})
+
+		It("should send memos to unified addresses", func() {
+			payments := []payuri.Payment{
+				{Address: uAddr, Amount: zcash.Amount(1), Memo: textMemo("hi")},
+				{Address: texAddr, Amount: zcash.Amount(2)},
+			}
+			uri, err := mainnetZIP321.Build(payments...)
+			Expect(err).ToNot(HaveOccurred())
+			parsed, err := mainnetZIP321.Parse(uri)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(payments))
+		})
+
+		It("should build BIP-21 URIs", func() {
+			uri, err := bip21.Build(payuri.Payment{Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", Amount: zcash.Amount(2030000000), Label: "Luke-Jr"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uri).To(Equal("bitcoin:1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2?amount=20.3&label=Luke-Jr"))
+		})
+
+		DescribeTable("should reject invalid payments",
+			func(scheme payuri.Scheme, payments ...payuri.Payment) {
+				_, err := scheme.Build(payments...)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("no payments", zip321),
+			Entry("undecodable address", zip321, payuri.Payment{Address: "tmEZhbWHTpdKMw5it8YDspUXSMGQyFwovpV"}),
+			Entry("negative amount", zip321, payuri.Payment{Address: tAddr, Amount: -1}),
+			Entry("total above the maximum", zip321, payuri.Payment{Address: tAddr, Amount: zcash.MaxZatoshi}, payuri.Payment{Address: tAddr, Amount: 1}),
+			Entry("memo to a transparent address", zip321, payuri.Payment{Address: tAddr, Memo: textMemo("hi")}),
+			Entry("memo to a TEX address", mainnetZIP321, payuri.Payment{Address: texAddr, Memo: textMemo("hi")}),
+			Entry("address of another network", zip321, payuri.Payment{Address: uAddr}),
+			Entry("required parameter", zip321, payuri.Payment{Address: tAddr, Other: map[string]string{"req-foo": "bar"}}),
+			Entry("bad parameter name", zip321, payuri.Payment{Address: tAddr, Other: map[string]string{"a=b": "c"}}),
+			Entry("several BIP-21 payments", bip21, payuri.Payment{Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"}, payuri.Payment{Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"}),
+			Entry("BIP-21 memo", bip21, payuri.Payment{Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", Memo: textMemo("hi")}),
+		)
+	})
+
+	Context("when converting payments to recipients", func() {
+		It("should return a recipient for each payment", func() {
+			payments, err := zip321.Parse(fmt.Sprintf("zcash:?address=%v&amount=1.5&address.1=%v&amount.1=0.00000001", tAddr, tAddr))
+			Expect(err).ToNot(HaveOccurred())
+			recipients, err := zip321.Recipients(payments)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipients).To(Equal([]utxo.Recipient{
+				{To: tAddr, Value: pack.NewU256FromUint64(150000000)},
+				{To: tAddr, Value: pack.NewU256FromUint64(1)},
+			}))
+		})
+
+		It("should return an error for payments without amounts", func() {
+			_, err := zip321.Recipients([]payuri.Payment{{Address: tAddr}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return an error for payments with memos", func() {
+			_, err := zip321.Recipients([]payuri.Payment{{Address: zAddr, Amount: 1, Memo: textMemo("hi")}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should pay TEX addresses to their P2PKH script", func() {
+			params := &zcash.MainNetParams
+			pubKeyHash, err := zcash.NewPaymentAddressDecoder(params).DecodeAddress(texAddr)
+			Expect(err).ToNot(HaveOccurred())
+			addr, err := zcash.NewAddressPubKeyHash(pubKeyHash, params)
+			Expect(err).ToNot(HaveOccurred())
+			script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			// The TEX address pays the same script as the transparent address
+			// of its pubkey hash.
+			payments := []payuri.Payment{
+				{Address: address.Address(addr.EncodeAddress()), Amount: 40000},
+				{Address: texAddr, Amount: 50000},
+			}
+			recipients, err := mainnetZIP321.Recipients(payments)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipients).To(Equal([]utxo.Recipient{
+				{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(40000)},
+				{Script: script, Value: pack.NewU256FromUint64(50000)},
+			}))
+
+			// The recipients can be paid by the transaction builder.
+			inputs := []utxo.Input{{Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)},
+				Value:        pack.NewU256FromUint64(100000),
+				PubKeyScript: script,
+			}}}
+			tx, err := zcash.NewTxBuilder(params, 1000000).BuildTx(inputs, recipients)
+			Expect(err).ToNot(HaveOccurred())
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(2))
+			Expect([]byte(outputs[0].PubKeyScript)).To(Equal(script))
+			Expect([]byte(outputs[1].PubKeyScript)).To(Equal(script))
+			Expect(outputs[1].Value).To(Equal(pack.NewU256FromUint64(50000)))
+		})
+
+		It("should return an error for payments to shielded addresses", func() {
+			_, err := zip321.Recipients([]payuri.Payment{{Address: zAddr, Amount: 1}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("sapling address"))
+			_, err = mainnetZIP321.Recipients([]payuri.Payment{{Address: uAddr, Amount: 1}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unified address"))
+		})
+	})
+})
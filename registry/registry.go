@@ -0,0 +1,145 @@
+// Package registry maps chain identifiers and networks to the implementations
+// of the APIs for that chain. Chain packages register themselves when they are
+// imported, so services that support many chains can import the chain
+// packages that they need (usually for their side effects only), and then
+// choose between them at runtime using Lookup:
+//
+//	import (
+//	    _ "github.com/pranav292gpt/zecutil/chain/bitcoin"
+//	    _ "github.com/pranav292gpt/zecutil/chain/zcash"
+//	)
+//
+//	bundle, err := registry.Lookup(registry.Zcash, registry.Mainnet)
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/gas"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// A Chain identifies a chain, independently of its network.
+type Chain string
+
+// Chains that are implemented by this module.
+const (
+	Bitcoin     = Chain("bitcoin")
+	BitcoinCash = Chain("bitcoincash")
+	Dogecoin    = Chain("dogecoin")
+	Litecoin    = Chain("litecoin")
+	Zcash       = Chain("zcash")
+)
+
+// A Network identifies a network of a chain.
+type Network string
+
+// Networks that are supported by the chains implemented by this module.
+const (
+	Mainnet = Network("mainnet")
+	Testnet = Network("testnet")
+	Regtest = Network("regtest")
+)
+
+// ClientOptions are used to parameterise the behaviour of the client that is
+// returned by a bundle. They have the same fields as the client options of the
+// chain packages, so they can be converted directly.
+type ClientOptions struct {
+	Timeout      time.Duration
+	TimeoutRetry time.Duration
+	Host         string
+	User         string
+	Password     string
+}
+
+// A Bundle groups together the implementations of the APIs for a chain, and
+// one of its networks.
+type Bundle struct {
+	Chain   Chain
+	Network Network
+
+	// AddressEncodeDecoder encodes and decodes addresses for the network.
+	AddressEncodeDecoder address.EncodeDecoder
+	// TxBuilder builds transactions for the network, using the default
+	// settings of the chain package.
+	TxBuilder utxo.TxBuilder
+	// DefaultClientOptions are the client options for a node of the network
+	// running locally with its default RPC port. In production, the host,
+	// user, and password should be changed.
+	DefaultClientOptions ClientOptions
+	// NewClient returns a client that interacts with a node of the network.
+	NewClient func(ClientOptions) utxo.Client
+	// NewGasEstimator returns a gas estimator that targets confirmation
+	// within the given number of blocks, and returns the fallback gas if
+	// estimation fails. The client must have been returned by NewClient.
+	NewGasEstimator func(client utxo.Client, numBlocks int64, fallbackGas pack.U256) (gas.Estimator, error)
+}
+
+type key struct {
+	chain   Chain
+	network Network
+}
+
+var (
+	bundlesMu sync.RWMutex
+	bundles   = map[key]Bundle{}
+)
+
+// Register makes a bundle available by its chain and network. It is expected
+// to be called from the init function of chain packages. It panics if a bundle
+// is already registered for the same chain and network, or if the bundle is
+// missing any of its implementations.
+func Register(bundle Bundle) {
+	if bundle.Chain == "" || bundle.Network == "" {
+		panic("registry: bundle is missing chain or network")
+	}
+	if bundle.AddressEncodeDecoder == nil || bundle.TxBuilder == nil || bundle.NewClient == nil || bundle.NewGasEstimator == nil {
+		panic(fmt.Sprintf("registry: bundle for %v %v is missing an implementation", bundle.Chain, bundle.Network))
+	}
+
+	bundlesMu.Lock()
+	defer bundlesMu.Unlock()
+
+	k := key{chain: bundle.Chain, network: bundle.Network}
+	if _, ok := bundles[k]; ok {
+		panic(fmt.Sprintf("registry: bundle for %v %v is already registered", bundle.Chain, bundle.Network))
+	}
+	bundles[k] = bundle
+}
+
+// Lookup returns the bundle that is registered for the chain and network. An
+// error is returned if no bundle is registered, which usually means that the
+// chain package has not been imported.
+func Lookup(chain Chain, network Network) (Bundle, error) {
+	bundlesMu.RLock()
+	defer bundlesMu.RUnlock()
+
+	bundle, ok := bundles[key{chain: chain, network: network}]
+	if !ok {
+		return Bundle{}, fmt.Errorf("unknown chain %v on network %v", chain, network)
+	}
+	return bundle, nil
+}
+
+// Bundles returns all registered bundles, sorted by chain and then by network.
+func Bundles() []Bundle {
+	bundlesMu.RLock()
+	defer bundlesMu.RUnlock()
+
+	registered := make([]Bundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		registered = append(registered, bundle)
+	}
+	sort.Slice(registered, func(i, j int) bool {
+		if registered[i].Chain != registered[j].Chain {
+			return registered[i].Chain < registered[j].Chain
+		}
+		return registered[i].Network < registered[j].Network
+	})
+	return registered
+}
@@ -0,0 +1,125 @@
+package registry_test
+
+import (
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/registry"
+	"github.com/renproject/pack"
+
+	_ "github.com/pranav292gpt/zecutil/chain/bitcoin"
+	_ "github.com/pranav292gpt/zecutil/chain/bitcoincash"
+	_ "github.com/pranav292gpt/zecutil/chain/dogecoin"
+	_ "github.com/pranav292gpt/zecutil/chain/litecoin"
+	_ "github.com/pranav292gpt/zecutil/chain/zcash"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeClient is a client that does not implement the chain specific methods
+// needed for gas estimation.
+type fakeClient struct {
+	utxo.Client
+}
+
+var _ = Describe("Registry", func() {
+	chains := []registry.Chain{registry.Bitcoin, registry.BitcoinCash, registry.Dogecoin, registry.Litecoin, registry.Zcash}
+	networks := []registry.Network{registry.Mainnet, registry.Testnet, registry.Regtest}
+
+	Context("when looking up bundles", func() {
+		for _, chain := range chains {
+			for _, network := range networks {
+				chain, network := chain, network
+				It("should return a bundle for "+string(chain)+" "+string(network), func() {
+					bundle, err := registry.Lookup(chain, network)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(bundle.Chain).To(Equal(chain))
+					Expect(bundle.Network).To(Equal(network))
+					Expect(bundle.DefaultClientOptions.Host).ToNot(BeEmpty())
+
+					// Build a transaction that spends a P2PKH output back to itself.
+					script, err := txscript.NewScriptBuilder().
+						AddOp(txscript.OP_DUP).
+						AddOp(txscript.OP_HASH160).
+						AddData(btcutil.Hash160([]byte("pubkey"))).
+						AddOp(txscript.OP_EQUALVERIFY).
+						AddOp(txscript.OP_CHECKSIG).
+						Script()
+					Expect(err).ToNot(HaveOccurred())
+					input := utxo.Input{Output: utxo.Output{
+						Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)},
+						Value:        pack.NewU256FromUint64(100000000),
+						PubKeyScript: script,
+					}}
+					recipients := []utxo.Recipient{{Script: script, Value: pack.NewU256FromUint64(99990000)}}
+					tx, err := bundle.TxBuilder.BuildTx([]utxo.Input{input}, recipients)
+					Expect(err).ToNot(HaveOccurred())
+					sighashes, err := tx.Sighashes()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sighashes).To(HaveLen(1))
+
+					// Clients returned by the bundle can be used to construct gas
+					// estimators, but other clients cannot.
+					client := bundle.NewClient(bundle.DefaultClientOptions)
+					Expect(client).ToNot(BeNil())
+					gasEstimator, err := bundle.NewGasEstimator(client, 1, pack.NewU256FromUint64(1))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(gasEstimator).ToNot(BeNil())
+					_, err = bundle.NewGasEstimator(fakeClient{}, 1, pack.NewU256FromUint64(1))
+					Expect(err).To(HaveOccurred())
+				})
+			}
+		}
+
+		It("should use the RPC port of each network", func() {
+			for _, chain := range chains {
+				mainnet, err := registry.Lookup(chain, registry.Mainnet)
+				Expect(err).ToNot(HaveOccurred())
+				testnet, err := registry.Lookup(chain, registry.Testnet)
+				Expect(err).ToNot(HaveOccurred())
+				regtest, err := registry.Lookup(chain, registry.Regtest)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mainnet.DefaultClientOptions.Host).ToNot(Equal(testnet.DefaultClientOptions.Host), "%v", chain)
+				Expect(mainnet.DefaultClientOptions.Host).ToNot(Equal(regtest.DefaultClientOptions.Host), "%v", chain)
+			}
+			bundle, err := registry.Lookup(registry.Zcash, registry.Mainnet)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bundle.DefaultClientOptions.Host).To(Equal("http://127.0.0.1:8232"))
+		})
+
+		It("should return the bundles in order", func() {
+			bundles := registry.Bundles()
+			Expect(bundles).To(HaveLen(len(chains) * len(networks)))
+			Expect(bundles[0].Chain).To(Equal(registry.Bitcoin))
+			Expect(bundles[0].Network).To(Equal(registry.Mainnet))
+			Expect(bundles[len(bundles)-1].Chain).To(Equal(registry.Zcash))
+			Expect(bundles[len(bundles)-1].Network).To(Equal(registry.Testnet))
+		})
+
+		It("should return an error for unknown chains and networks", func() {
+			_, err := registry.Lookup(registry.Chain("ethereum"), registry.Mainnet)
+			Expect(err).To(HaveOccurred())
+			_, err = registry.Lookup(registry.Zcash, registry.Network("devnet"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when registering bundles", func() {
+		It("should panic for duplicate bundles", func() {
+			bundle, err := registry.Lookup(registry.Bitcoin, registry.Regtest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(func() { registry.Register(bundle) }).To(Panic())
+		})
+
+		It("should panic for incomplete bundles", func() {
+			bundle, err := registry.Lookup(registry.Bitcoin, registry.Regtest)
+			Expect(err).ToNot(HaveOccurred())
+			bundle.Chain = registry.Chain("incomplete")
+			bundle.NewGasEstimator = nil
+			Expect(func() { registry.Register(bundle) }).To(Panic())
+			_, err = registry.Lookup(bundle.Chain, registry.Regtest)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
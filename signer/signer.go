@@ -0,0 +1,179 @@
+// Package signer defines the Signer interface, which is used to produce the
+// signatures required by a utxo-based transaction, and implementations of the
+// interface. Signers work from the sighashes returned by Tx.Sighashes, and the
+// signatures that they return can be passed directly to Tx.Sign.
+package signer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// halfOrder is used to check that signatures have a low S value.
+var halfOrder = new(big.Int).Rsh(btcec.S256().N, 1)
+
+// The Signer interface defines the functionality required to sign the
+// sighashes of a transaction. Signatures are returned in the 65-byte [R || S ||
+// V] format, where V is the recovery id of the signature, and are returned in
+// the same order as the sighashes. The serialized public key that can be used
+// to verify the signatures is also returned.
+type Signer interface {
+	Sign(ctx context.Context, sighashes []pack.Bytes32) ([]pack.Bytes65, pack.Bytes, error)
+}
+
+// SignTx uses the signer to sign all of the sighashes of a transaction, and
+// injects the resulting signatures into the transaction.
+func SignTx(ctx context.Context, signer Signer, tx utxo.Tx) error {
+	sighashes, err := tx.Sighashes()
+	if err != nil {
+		return fmt.Errorf("bad sighashes: %v", err)
+	}
+	signatures, pubKey, err := signer.Sign(ctx, sighashes)
+	if err != nil {
+		return fmt.Errorf("bad signatures: %v", err)
+	}
+	if len(signatures) != len(sighashes) {
+		return fmt.Errorf("expected %v signatures, got %v signatures", len(sighashes), len(signatures))
+	}
+	return tx.Sign(signatures, pubKey)
+}
+
+// KeySigner is a Signer that holds a private key in memory. Nonces are
+// generated deterministically (as defined by RFC6979), and signatures are
+// always normalised to have a low S value.
+type KeySigner struct {
+	privKey    *btcec.PrivateKey
+	compressed bool
+}
+
+// NewKeySigner returns a Signer that signs using the given private key. The
+// compressed flag controls the serialization of the public key returned by the
+// signer.
+func NewKeySigner(privKey *btcec.PrivateKey, compressed bool) KeySigner {
+	return KeySigner{privKey: privKey, compressed: compressed}
+}
+
+// NewKeySignerFromWIF returns a Signer that signs using the private key encoded
+// in the given WIF.
+func NewKeySignerFromWIF(wif *btcutil.WIF) KeySigner {
+	return NewKeySigner(wif.PrivKey, wif.CompressPubKey)
+}
+
+// PubKey returns the serialized public key of the signer.
+func (signer KeySigner) PubKey() pack.Bytes {
+	if signer.compressed {
+		return pack.Bytes(signer.privKey.PubKey().SerializeCompressed())
+	}
+	return pack.Bytes(signer.privKey.PubKey().SerializeUncompressed())
+}
+
+// Sign implements the Signer interface.
+func (signer KeySigner) Sign(ctx context.Context, sighashes []pack.Bytes32) ([]pack.Bytes65, pack.Bytes, error) {
+	signatures := make([]pack.Bytes65, len(sighashes))
+	for i, sighash := range sighashes {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		// SignCompact uses RFC6979 nonces, and produces a low S value. It
+		// returns the signature in the [V || R || S] format, where V is offset
+		// by 27 (and a further 4 for compressed public keys).
+		compact, err := btcec.SignCompact(btcec.S256(), signer.privKey, sighash[:], signer.compressed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad sighash %v: %v", i, err)
+		}
+		copy(signatures[i][:64], compact[1:])
+		signatures[i][64] = (compact[0] - 27) & 0x03
+	}
+	return signatures, signer.PubKey(), nil
+}
+
+// Normalise a signature so that it has a low S value. If the S value is high,
+// it is replaced by N - S, and the recovery id is flipped so that it still
+// recovers the same public key.
+func Normalise(signature pack.Bytes65) pack.Bytes65 {
+	s := new(big.Int).SetBytes(signature[32:64])
+	if s.Cmp(halfOrder) <= 0 {
+		return signature
+	}
+	s.Sub(btcec.S256().N, s)
+	normalised := signature
+	copy(normalised[32:64], make([]byte, 32))
+	sBytes := s.Bytes()
+	copy(normalised[64-len(sBytes):64], sBytes)
+	normalised[64] ^= 0x01
+	return normalised
+}
+
+// Recover the public key that produced the signature over the sighash, using
+// the recovery id of the signature.
+func Recover(sighash pack.Bytes32, signature pack.Bytes65) (*btcec.PublicKey, error) {
+	if signature[64] > 3 {
+		return nil, fmt.Errorf("bad recovery id: expected 0-3, got %v", signature[64])
+	}
+	compact := make([]byte, 65)
+	compact[0] = 27 + signature[64]
+	copy(compact[1:], signature[:64])
+	pubKey, _, err := btcec.RecoverCompact(btcec.S256(), compact, sighash[:])
+	if err != nil {
+		return nil, err
+	}
+	return pubKey, nil
+}
+
+// VerifyingSigner wraps another Signer, and checks every signature returned by
+// it before the signature is used. Signatures are normalised to have a low S
+// value, and the recovery id of each signature is used to check that it was
+// produced by the expected public key. This is useful when signing is done
+// remotely (e.g. by an HSM or an MPC network) and the signatures cannot be
+// trusted.
+type VerifyingSigner struct {
+	signer Signer
+	pubKey *btcec.PublicKey
+}
+
+// NewVerifyingSigner returns a Signer that checks that all signatures returned
+// by the given signer were produced by the expected public key.
+func NewVerifyingSigner(signer Signer, expectedPubKey pack.Bytes) (VerifyingSigner, error) {
+	pubKey, err := btcec.ParsePubKey(expectedPubKey, btcec.S256())
+	if err != nil {
+		return VerifyingSigner{}, fmt.Errorf("bad pubkey: %v", err)
+	}
+	return VerifyingSigner{signer: signer, pubKey: pubKey}, nil
+}
+
+// Sign implements the Signer interface.
+func (signer VerifyingSigner) Sign(ctx context.Context, sighashes []pack.Bytes32) ([]pack.Bytes65, pack.Bytes, error) {
+	signatures, pubKey, err := signer.signer.Sign(ctx, sighashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(signatures) != len(sighashes) {
+		return nil, nil, fmt.Errorf("expected %v signatures, got %v signatures", len(sighashes), len(signatures))
+	}
+	parsedPubKey, err := btcec.ParsePubKey(pubKey, btcec.S256())
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad pubkey: %v", err)
+	}
+	if !parsedPubKey.IsEqual(signer.pubKey) {
+		return nil, nil, fmt.Errorf("bad pubkey: expected %x, got %x", signer.pubKey.SerializeCompressed(), parsedPubKey.SerializeCompressed())
+	}
+
+	normalised := make([]pack.Bytes65, len(signatures))
+	for i, signature := range signatures {
+		normalised[i] = Normalise(signature)
+		recovered, err := Recover(sighashes[i], normalised[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad signature %v: %v", i, err)
+		}
+		if !recovered.IsEqual(signer.pubKey) {
+			return nil, nil, fmt.Errorf("bad signature %v: expected pubkey %x, got pubkey %x", i, signer.pubKey.SerializeCompressed(), recovered.SerializeCompressed())
+		}
+	}
+	return normalised, pubKey, nil
+}
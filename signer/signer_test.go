@@ -0,0 +1,245 @@
+package signer_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// badSigner returns signatures produced by a different key, while claiming to
+// have the expected public key.
+type badSigner struct {
+	signer signer.KeySigner
+	pubKey pack.Bytes
+}
+
+func (s badSigner) Sign(ctx context.Context, sighashes []pack.Bytes32) ([]pack.Bytes65, pack.Bytes, error) {
+	signatures, _, err := s.signer.Sign(ctx, sighashes)
+	return signatures, s.pubKey, err
+}
+
+// highSSigner returns signatures with a high S value.
+type highSSigner struct {
+	signer signer.KeySigner
+}
+
+func (s highSSigner) Sign(ctx context.Context, sighashes []pack.Bytes32) ([]pack.Bytes65, pack.Bytes, error) {
+	signatures, pubKey, err := s.signer.Sign(ctx, sighashes)
+	for i := range signatures {
+		signatures[i] = flipS(signatures[i])
+	}
+	return signatures, pubKey, err
+}
+
+func flipS(signature pack.Bytes65) pack.Bytes65 {
+	sig := signature
+	s := new(big.Int).Sub(btcec.S256().N, new(big.Int).SetBytes(signature[32:64]))
+	copy(sig[32:64], make([]byte, 32))
+	copy(sig[64-len(s.Bytes()):64], s.Bytes())
+	sig[64] ^= 0x01
+	return sig
+}
+
+var _ = Describe("Signer", func() {
+	halfOrder := new(big.Int).Rsh(btcec.S256().N, 1)
+
+	newKey := func() *btcec.PrivateKey {
+		return (*btcec.PrivateKey)(id.NewPrivKey())
+	}
+
+	Context("when signing with a local key", func() {
+		It("should produce deterministic RFC6979 signatures", func() {
+			// Test vectors from Trezor and CoreBitcoin. The second vector
+			// produces a high S value before normalisation.
+			vectors := []struct {
+				key       string
+				msg       string
+				signature string
+			}{
+				{
+					"cca9fbcc1b41e5a95d369eaa6ddcff73b61a4efaa279cfc6567e8daa39cbaf50",
+					"sample",
+					"af340daf02cc15c8d5d08d7735dfe6b98a474ed373bdb5fbecf7571be52b38425009fb27f37034a9b24b707b7c6b79ca23ddef9e25f7282e8a797efe53a8f124",
+				},
+				{
+					"0000000000000000000000000000000000000000000000000000000000000001",
+					"Satoshi Nakamoto",
+					"934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d82442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+				},
+				{
+					"f8b8af8ce3c7cca5e300d33939540c10d45ce001b8f252bfbc57ba0342904181",
+					"Alan Turing",
+					"7063ae83e7f62bbb171798131b4a0564b956930092b33b07b395615d9ec7e15c58dfcc1e00a35e1572f366ffe34ba0fc47db1e7189759b9fb233c5b05ab388ea",
+				},
+			}
+			for _, vector := range vectors {
+				keyBytes, err := hex.DecodeString(vector.key)
+				Expect(err).ToNot(HaveOccurred())
+				privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), keyBytes)
+				sighash := pack.Bytes32(sha256.Sum256([]byte(vector.msg)))
+
+				signatures, pubKey, err := signer.NewKeySigner(privKey, true).Sign(context.Background(), []pack.Bytes32{sighash})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hex.EncodeToString(signatures[0][:64])).To(Equal(vector.signature))
+				Expect(pubKey).To(Equal(pack.Bytes(privKey.PubKey().SerializeCompressed())))
+
+				recovered, err := signer.Recover(sighash, signatures[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recovered.IsEqual(privKey.PubKey())).To(BeTrue())
+			}
+		})
+
+		It("should produce signatures with a low S value", func() {
+			privKey := newKey()
+			sighashes := make([]pack.Bytes32, 32)
+			for i := range sighashes {
+				sighashes[i] = sha256.Sum256([]byte(fmt.Sprintf("sighash %v", i)))
+			}
+			signatures, _, err := signer.NewKeySigner(privKey, true).Sign(context.Background(), sighashes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signatures).To(HaveLen(len(sighashes)))
+			for i, signature := range signatures {
+				Expect(new(big.Int).SetBytes(signature[32:64]).Cmp(halfOrder)).To(BeNumerically("<=", 0))
+				Expect(signer.Normalise(signature)).To(Equal(signature))
+				recovered, err := signer.Recover(sighashes[i], signature)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recovered.IsEqual(privKey.PubKey())).To(BeTrue())
+			}
+		})
+
+		It("should return the uncompressed pubkey when requested", func() {
+			privKey := newKey()
+			_, pubKey, err := signer.NewKeySigner(privKey, false).Sign(context.Background(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pubKey).To(Equal(pack.Bytes(privKey.PubKey().SerializeUncompressed())))
+		})
+
+		It("should stop when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, _, err := signer.NewKeySigner(newKey(), true).Sign(ctx, []pack.Bytes32{{}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when normalising a signature", func() {
+		It("should lower a high S value and keep the recovered pubkey", func() {
+			privKey := newKey()
+			sighash := pack.Bytes32(sha256.Sum256([]byte("sighash")))
+			signatures, _, err := signer.NewKeySigner(privKey, true).Sign(context.Background(), []pack.Bytes32{sighash})
+			Expect(err).ToNot(HaveOccurred())
+
+			high := flipS(signatures[0])
+			Expect(new(big.Int).SetBytes(high[32:64]).Cmp(halfOrder)).To(Equal(1))
+			recovered, err := signer.Recover(sighash, high)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recovered.IsEqual(privKey.PubKey())).To(BeTrue())
+			Expect(signer.Normalise(high)).To(Equal(signatures[0]))
+		})
+	})
+
+	Context("when verifying signatures", func() {
+		It("should accept and normalise signatures from the expected pubkey", func() {
+			privKey := newKey()
+			local := signer.NewKeySigner(privKey, true)
+			verifying, err := signer.NewVerifyingSigner(highSSigner{signer: local}, local.PubKey())
+			Expect(err).ToNot(HaveOccurred())
+
+			sighashes := []pack.Bytes32{sha256.Sum256([]byte("a")), sha256.Sum256([]byte("b"))}
+			expected, _, err := local.Sign(context.Background(), sighashes)
+			Expect(err).ToNot(HaveOccurred())
+			signatures, pubKey, err := verifying.Sign(context.Background(), sighashes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signatures).To(Equal(expected))
+			Expect(pubKey).To(Equal(local.PubKey()))
+		})
+
+		It("should reject signatures from another key", func() {
+			expected := signer.NewKeySigner(newKey(), true)
+			verifying, err := signer.NewVerifyingSigner(badSigner{signer: signer.NewKeySigner(newKey(), true), pubKey: expected.PubKey()}, expected.PubKey())
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = verifying.Sign(context.Background(), []pack.Bytes32{sha256.Sum256([]byte("a"))})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject signatures with the wrong recovery id", func() {
+			local := signer.NewKeySigner(newKey(), true)
+			sighash := pack.Bytes32(sha256.Sum256([]byte("a")))
+			signatures, _, err := local.Sign(context.Background(), []pack.Bytes32{sighash})
+			Expect(err).ToNot(HaveOccurred())
+
+			signature := signatures[0]
+			signature[64] ^= 0x01
+			recovered, err := signer.Recover(sighash, signature)
+			if err == nil {
+				Expect(recovered.IsEqual(mustParse(local.PubKey()))).To(BeFalse())
+			}
+			signature[64] = 4
+			_, err = signer.Recover(sighash, signature)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject an unexpected pubkey", func() {
+			verifying, err := signer.NewVerifyingSigner(signer.NewKeySigner(newKey(), true), signer.NewKeySigner(newKey(), true).PubKey())
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = verifying.Sign(context.Background(), []pack.Bytes32{sha256.Sum256([]byte("a"))})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject a malformed expected pubkey", func() {
+			_, err := signer.NewVerifyingSigner(signer.NewKeySigner(newKey(), true), pack.Bytes{0x02, 0x01})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when signing a transaction", func() {
+		It("should produce a transaction that passes verification", func() {
+			params := &zcash.RegressionNetParams
+			privKey := newKey()
+			local := signer.NewKeySigner(privKey, true)
+			verifying, err := signer.NewVerifyingSigner(local, local.PubKey())
+			Expect(err).ToNot(HaveOccurred())
+
+			addr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(local.PubKey()), params)
+			Expect(err).ToNot(HaveOccurred())
+			pubKeyScript, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+			Expect(err).ToNot(HaveOccurred())
+			inputs := make([]utxo.Input, 2)
+			for i := range inputs {
+				inputs[i] = utxo.Input{Output: utxo.Output{
+					Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(uint32(i))},
+					Value:        pack.NewU256FromUint64(50000),
+					PubKeyScript: pubKeyScript,
+				}}
+			}
+			tx, err := zcash.NewTxBuilder(params, 1000000).BuildTx(inputs, []utxo.Recipient{{To: address.Address(addr.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(signer.SignTx(context.Background(), verifying, tx)).To(Succeed())
+			results, err := tx.(*zcash.Tx).Verify()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]error{nil, nil}))
+		})
+	})
+})
+
+func mustParse(pubKey pack.Bytes) *btcec.PublicKey {
+	parsed, err := btcec.ParsePubKey(pubKey, btcec.S256())
+	Expect(err).ToNot(HaveOccurred())
+	return parsed
+}
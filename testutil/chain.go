@@ -0,0 +1,163 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/renproject/pack"
+)
+
+// A Chain defines how a Server decodes and verifies the transactions, and the
+// addresses, of a chain.
+type Chain struct {
+	// DecodeTx deserializes a transaction, and returns its transparent
+	// component and its txid.
+	DecodeTx func(data []byte) (*wire.MsgTx, chainhash.Hash, error)
+	// VerifyTx executes the signature scripts of a deserialized transaction.
+	// The previous outputs are the outputs spent by the inputs of the
+	// transaction, in order.
+	VerifyTx func(data []byte, prevOuts []*wire.TxOut) error
+	// AddressScript returns the pubkey script that pays an address.
+	AddressScript func(addr string) ([]byte, error)
+	// ScriptAddress returns the address that is paid by a pubkey script. It
+	// returns false if the script does not pay an address.
+	ScriptAddress func(script []byte) (string, bool)
+	// PaymentAddressKind returns the kind of a Zcash payment address. It is
+	// nil for chains without shielded addresses, whose servers do not
+	// implement the shielded calls.
+	PaymentAddressKind func(addr string) (zcash.AddressKind, error)
+}
+
+// BitcoinChain returns the Chain for Bitcoin. Transactions are verified using
+// the standard script flags, except that spends of witness programs of
+// unknown versions (such as Taproot) are not verified.
+func BitcoinChain(params *chaincfg.Params) Chain {
+	encoder := bitcoin.NewAddressEncoder(params)
+	return Chain{
+		DecodeTx: func(data []byte) (*wire.MsgTx, chainhash.Hash, error) {
+			msgTx, err := bitcoin.DecodeTx(data)
+			if err != nil {
+				return nil, chainhash.Hash{}, err
+			}
+			return msgTx, msgTx.TxHash(), nil
+		},
+		VerifyTx: func(data []byte, prevOuts []*wire.TxOut) error {
+			msgTx, err := bitcoin.DecodeTx(data)
+			if err != nil {
+				return err
+			}
+			flags := txscript.StandardVerifyFlags &^ txscript.ScriptVerifyDiscourageUpgradeableWitnessProgram
+			sigHashes := txscript.NewTxSigHashes(msgTx)
+			for i, prevOut := range prevOuts {
+				engine, err := txscript.NewEngine(prevOut.PkScript, msgTx, i, flags, nil, sigHashes, prevOut.Value)
+				if err != nil {
+					return fmt.Errorf("bad input %v: %v", i, err)
+				}
+				if err := engine.Execute(); err != nil {
+					return fmt.Errorf("bad input %v: %v", i, err)
+				}
+			}
+			return nil
+		},
+		AddressScript: func(addr string) ([]byte, error) {
+			decoded, err := bitcoin.DecodeAddressForNet(addr, params)
+			if err != nil {
+				return nil, err
+			}
+			return bitcoin.PayToAddrScript(decoded)
+		},
+		ScriptAddress: func(script []byte) (string, bool) {
+			rawAddr, ok := bitcoin.RawAddressFromScript(script, params)
+			if !ok {
+				return "", false
+			}
+			addr, err := encoder.EncodeAddress(rawAddr)
+			return string(addr), err == nil
+		},
+	}
+}
+
+// ZcashChain returns the Chain for Zcash. Version 4 and version 5 transactions
+// are accepted, but only the signatures of version 4 transactions with no
+// shielded components are verified.
+func ZcashChain(params *zcash.Params) Chain {
+	encoder := zcash.NewAddressEncoder(params)
+	paymentDecoder := zcash.NewPaymentAddressDecoder(params)
+	return Chain{
+		DecodeTx: func(data []byte) (*wire.MsgTx, chainhash.Hash, error) {
+			decoded, err := zcash.DecodeTx(data)
+			if err != nil {
+				return nil, chainhash.Hash{}, err
+			}
+			txID, err := decoded.TxID()
+			if err != nil {
+				return nil, chainhash.Hash{}, err
+			}
+			return decoded.MsgTx, txID, nil
+		},
+		VerifyTx: func(data []byte, prevOuts []*wire.TxOut) error {
+			decoded, err := zcash.DecodeTx(data)
+			if err != nil {
+				return err
+			}
+			if decoded.MsgTx.Version != zcash.Version || !decoded.IsTransparent() {
+				return nil
+			}
+			inputs := make([]utxo.Input, len(prevOuts))
+			for i, prevOut := range prevOuts {
+				outpoint := decoded.MsgTx.TxIn[i].PreviousOutPoint
+				inputs[i] = utxo.Input{Output: utxo.Output{
+					Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(outpoint.Hash[:]), Index: pack.NewU32(outpoint.Index)},
+					Value:        pack.NewU256FromUint64(uint64(prevOut.Value)),
+					PubKeyScript: pack.NewBytes(prevOut.PkScript),
+				}}
+			}
+			_, err = decoded.Verify(inputs, params)
+			return err
+		},
+		AddressScript: func(addr string) ([]byte, error) {
+			return zcash.RecipientScript(utxo.Recipient{To: address.Address(addr)}, params)
+		},
+		ScriptAddress: func(script []byte) (string, bool) {
+			rawAddr, ok := zcash.RawAddressFromScript(script, params)
+			if !ok {
+				return "", false
+			}
+			addr, err := encoder.EncodeAddress(rawAddr)
+			return string(addr), err == nil
+		},
+		PaymentAddressKind: func(addr string) (zcash.AddressKind, error) {
+			return paymentDecoder.AddressKind(address.Address(addr))
+		},
+	}
+}
+
+// scriptPubKey returns the JSON view of a pubkey script, as returned by the
+// node.
+func (chain Chain) scriptPubKey(script []byte) btcjson.ScriptPubKeyResult {
+	result := btcjson.ScriptPubKeyResult{
+		Asm:  bitcoin.ExplainScript(script).Asm,
+		Hex:  hex.EncodeToString(script),
+		Type: bitcoin.ScriptType(script),
+	}
+	if addr, ok := chain.ScriptAddress(script); ok {
+		result.Addresses = []string{addr}
+	}
+	return result
+}
+
+// isNullOutPoint returns true if the outpoint is the outpoint spent by funding
+// transactions, which have no previous output.
+func isNullOutPoint(outpoint wire.OutPoint) bool {
+	return outpoint.Index == wire.MaxPrevOutIndex && bytes.Equal(outpoint.Hash[:], make([]byte, chainhash.HashSize))
+}
@@ -0,0 +1,766 @@
+// Package testutil provides an in-process fake of the JSON-RPC interface of
+// zcashd and bitcoind, so that clients can build, sign, submit, and confirm
+// transactions in tests without a live node.
+//
+// The Server models a single chain with a UTXO set, a mempool, and blocks that
+// are mined on demand. It implements the RPC calls used by the clients of this
+// module:
+//
+//	getblockcount, getbestblockhash, getrawtransaction, gettxout,
+//	sendrawtransaction, listunspent, gettransaction, getrawmempool,
+//	estimatefee, estimatesmartfee, generate
+//
+// Servers of Zcash chains also implement z_sendmany, z_listreceivedbyaddress,
+// and z_getoperationstatus. The amounts sent by z_sendmany are not taken from
+// any outputs: each operation succeeds immediately, and adds a note for each
+// recipient, which is confirmed by the next block.
+//
+// The node has no wallet, so listunspent returns all unspent outputs, and
+// gettransaction returns any known transaction. Outputs are funded using
+// Server.Fund, fee estimates are set using Server.SetFeeRate, transactions are
+// dropped from the mempool using Server.Evict, and errors are injected using
+// Server.InjectError and Server.InjectLostResponse.
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultTimeoutRetry is the retry interval of the ClientOptions returned
+	// by Server.ClientOptions. Clients retry failed requests until their
+	// context is done, so it is short to keep tests fast.
+	DefaultTimeoutRetry = 10 * time.Millisecond
+	// DefaultMaxConf is the default maximum number of confirmations of the
+	// outputs returned by listunspent.
+	DefaultMaxConf = 9999999
+)
+
+// A Server is a fake zcashd or bitcoind node that serves the JSON-RPC
+// interface over HTTP. It is safe for concurrent use.
+type Server struct {
+	chain  Chain
+	server *httptest.Server
+
+	mu       sync.Mutex
+	blocks   []chainhash.Hash
+	txs      map[chainhash.Hash]*txEntry
+	order    []chainhash.Hash
+	mempool  []chainhash.Hash
+	spentBy  map[wire.OutPoint]chainhash.Hash
+	feeRates map[int64]float64
+	errors   map[string]*injectedError
+	calls    map[string]int
+	nonce    uint32
+
+	notes      []*note
+	operations []*operation
+}
+
+// A txEntry is a transaction that is known by the server. The height is -1 if
+// the transaction is in the mempool.
+type txEntry struct {
+	msgTx      *wire.MsgTx
+	txID       chainhash.Hash
+	serialized []byte
+	height     int64
+}
+
+// An injectedError is returned by the next calls to a method. If times is not
+// positive, it is returned by all calls. If lost is set, the calls are handled
+// before the error is returned in place of their response.
+type injectedError struct {
+	err   *btcjson.RPCError
+	times int
+	lost  bool
+}
+
+// NewServer starts a server for the chain. The chain starts with a genesis
+// block, and no outputs. The server must be closed when it is no longer used.
+func NewServer(chain Chain) *Server {
+	server := &Server{
+		chain:    chain,
+		blocks:   []chainhash.Hash{chainhash.DoubleHashH([]byte("genesis"))},
+		txs:      map[chainhash.Hash]*txEntry{},
+		spentBy:  map[wire.OutPoint]chainhash.Hash{},
+		feeRates: map[int64]float64{},
+		errors:   map[string]*injectedError{},
+		calls:    map[string]int{},
+	}
+	server.server = httptest.NewServer(server)
+	return server
+}
+
+// URL returns the URL of the JSON-RPC interface.
+func (server *Server) URL() string {
+	return server.server.URL
+}
+
+// ClientOptions returns the options of a client that connects to the server.
+func (server *Server) ClientOptions() bitcoin.ClientOptions {
+	opts := bitcoin.DefaultClientOptions().WithHost(server.URL())
+	opts.TimeoutRetry = DefaultTimeoutRetry
+	return opts
+}
+
+// Close shuts down the server.
+func (server *Server) Close() {
+	server.server.Close()
+}
+
+// Fund adds a transaction to the mempool that pays the value to the address,
+// as if it was sent by the wallet of the node, and returns the output. The
+// transaction is a Bitcoin-encoded transaction with no previous outputs, so it
+// is not a valid transaction of the chain. It is confirmed by Mine.
+func (server *Server) Fund(addr string, value int64) (utxo.Output, error) {
+	script, err := server.chain.AddressScript(addr)
+	if err != nil {
+		return utxo.Output{}, fmt.Errorf("bad address: %v", err)
+	}
+	if value < 0 || value > btcutil.MaxSatoshi {
+		return utxo.Output{}, fmt.Errorf("bad value: %v", value)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	nonce := make([]byte, 4)
+	binary.LittleEndian.PutUint32(nonce, server.nonce)
+	server.nonce++
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), nonce, nil))
+	msgTx.AddTxOut(wire.NewTxOut(value, script))
+	buf := new(bytes.Buffer)
+	if err := msgTx.Serialize(buf); err != nil {
+		return utxo.Output{}, err
+	}
+	serialized := buf.Bytes()
+	txID := msgTx.TxHash()
+	server.add(&txEntry{msgTx: msgTx, txID: txID, serialized: serialized, height: -1})
+
+	return utxo.Output{
+		Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(txID[:]), Index: pack.NewU32(0)},
+		Value:        pack.NewU256FromUint64(uint64(value)),
+		PubKeyScript: pack.NewBytes(script),
+	}, nil
+}
+
+// Mine mines blocks that include all transactions in the mempool, and returns
+// the hashes of the blocks.
+func (server *Server) Mine(numBlocks int) []chainhash.Hash {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.mine(numBlocks)
+}
+
+// Height returns the height of the latest block.
+func (server *Server) Height() int64 {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.height()
+}
+
+// Mempool returns the txids of the transactions in the mempool, in the order
+// in which they were accepted.
+func (server *Server) Mempool() []chainhash.Hash {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]chainhash.Hash{}, server.mempool...)
+}
+
+// Evict removes a transaction from the mempool, as if it had expired or been
+// replaced, along with any transactions in the mempool that spend its outputs.
+// The transaction is forgotten, so it can be submitted again. It returns false
+// if the transaction is not in the mempool.
+func (server *Server) Evict(txID chainhash.Hash) bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	entry, ok := server.txs[txID]
+	if !ok || entry.height >= 0 {
+		return false
+	}
+	server.evict(entry)
+	return true
+}
+
+// Confirmations returns the number of confirmations of a transaction, and
+// false if the transaction is not known.
+func (server *Server) Confirmations(txID chainhash.Hash) (int64, bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	entry, ok := server.txs[txID]
+	if !ok {
+		return 0, false
+	}
+	return server.confirmations(entry), true
+}
+
+// SetFeeRate sets the fee rate (in coins-per-kilobyte) that is estimated for
+// transactions to be confirmed within the number of blocks. Estimates for
+// other targets use the rate of the largest target that is not greater, and
+// fail if there is no such target. A fee rate that is not positive removes the
+// target.
+func (server *Server) SetFeeRate(numBlocks int64, feeRate float64) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if feeRate <= 0 {
+		delete(server.feeRates, numBlocks)
+		return
+	}
+	server.feeRates[numBlocks] = feeRate
+}
+
+// InjectError makes the next calls to the method return the error, instead of
+// being handled. If times is not positive, all calls return the error until
+// ClearErrors is called.
+func (server *Server) InjectError(method string, times int, err *btcjson.RPCError) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.errors[method] = &injectedError{err: err, times: times}
+}
+
+// InjectLostResponse makes the next calls to the method return the error,
+// after being handled, as if their response had been lost. If times is not
+// positive, all calls return the error until ClearErrors is called.
+func (server *Server) InjectLostResponse(method string, times int, err *btcjson.RPCError) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.errors[method] = &injectedError{err: err, times: times, lost: true}
+}
+
+// ClearErrors removes all injected errors.
+func (server *Server) ClearErrors() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.errors = map[string]*injectedError{}
+}
+
+// Calls returns the number of times that the method has been called.
+func (server *Server) Calls(method string) int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.calls[method]
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		ID     interface{}       `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}{}
+	var result interface{}
+	var rpcErr *btcjson.RPCError
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		rpcErr = btcjson.NewRPCError(btcjson.ErrRPCParse.Code, fmt.Sprintf("Parse error: %v", err))
+	} else {
+		result, rpcErr = server.handle(req.Method, req.Params)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case rpcErr == nil:
+	case rpcErr.Code == btcjson.ErrRPCMethodNotFound.Code:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(struct {
+		Result interface{}       `json:"result"`
+		Error  *btcjson.RPCError `json:"error"`
+		ID     interface{}       `json:"id"`
+	}{result, rpcErr, req.ID})
+}
+
+func (server *Server) handle(method string, params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.calls[method]++
+	if injected, ok := server.errors[method]; ok {
+		if injected.times > 0 {
+			injected.times--
+			if injected.times == 0 {
+				delete(server.errors, method)
+			}
+		}
+		if injected.lost {
+			server.call(method, params)
+		}
+		return nil, injected.err
+	}
+	return server.call(method, params)
+}
+
+func (server *Server) call(method string, params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	switch method {
+	case "getblockcount":
+		return server.height(), parseParams(params, 0)
+	case "getbestblockhash":
+		return server.blocks[len(server.blocks)-1].String(), parseParams(params, 0)
+	case "getrawtransaction":
+		return server.getRawTransaction(params)
+	case "gettxout":
+		return server.getTxOut(params)
+	case "sendrawtransaction":
+		return server.sendRawTransaction(params)
+	case "listunspent":
+		return server.listUnspent(params)
+	case "gettransaction":
+		return server.getTransaction(params)
+	case "getrawmempool":
+		txIDs := make([]string, len(server.mempool))
+		for i, txID := range server.mempool {
+			txIDs[i] = txID.String()
+		}
+		return txIDs, parseParams(params, 0)
+	case "estimatefee":
+		return server.estimateFee(params)
+	case "estimatesmartfee":
+		return server.estimateSmartFee(params)
+	case "z_sendmany", "z_listreceivedbyaddress", "z_getoperationstatus":
+		if server.chain.PaymentAddressKind == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCMethodNotFound.Code, "Method not found")
+		}
+		switch method {
+		case "z_sendmany":
+			return server.zSendMany(params)
+		case "z_listreceivedbyaddress":
+			return server.zListReceivedByAddress(params)
+		default:
+			return server.zGetOperationStatus(params)
+		}
+	case "generate":
+		numBlocks := 0
+		if err := parseParams(params, 1, &numBlocks); err != nil {
+			return nil, err
+		}
+		if numBlocks < 0 {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid number of blocks")
+		}
+		hashes := server.mine(numBlocks)
+		result := make([]string, len(hashes))
+		for i, hash := range hashes {
+			result[i] = hash.String()
+		}
+		return result, nil
+	default:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMethodNotFound.Code, "Method not found")
+	}
+}
+
+func (server *Server) getRawTransaction(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	txIDStr := ""
+	verbose := json.RawMessage("false")
+	if err := parseParams(params, 1, &txIDStr, &verbose); err != nil {
+		return nil, err
+	}
+	entry, err := server.lookup(txIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "No such mempool or blockchain transaction. Use gettransaction for wallet transactions.")
+	}
+	if string(verbose) == "false" || string(verbose) == "0" {
+		return hex.EncodeToString(entry.serialized), nil
+	}
+
+	result := btcjson.TxRawResult{
+		Hex:           hex.EncodeToString(entry.serialized),
+		Txid:          entry.txID.String(),
+		Size:          int32(len(entry.serialized)),
+		Version:       uint32(entry.msgTx.Version),
+		LockTime:      entry.msgTx.LockTime,
+		Vin:           make([]btcjson.Vin, len(entry.msgTx.TxIn)),
+		Vout:          make([]btcjson.Vout, len(entry.msgTx.TxOut)),
+		BlockHash:     server.blockHash(entry),
+		Confirmations: uint64(server.confirmations(entry)),
+	}
+	for i, txIn := range entry.msgTx.TxIn {
+		vin := btcjson.Vin{Sequence: txIn.Sequence}
+		if isNullOutPoint(txIn.PreviousOutPoint) {
+			vin.Coinbase = hex.EncodeToString(txIn.SignatureScript)
+		} else {
+			vin.Txid = txIn.PreviousOutPoint.Hash.String()
+			vin.Vout = txIn.PreviousOutPoint.Index
+			vin.ScriptSig = &btcjson.ScriptSig{
+				Asm: bitcoin.ExplainScript(txIn.SignatureScript).Asm,
+				Hex: hex.EncodeToString(txIn.SignatureScript),
+			}
+		}
+		for _, item := range txIn.Witness {
+			vin.Witness = append(vin.Witness, hex.EncodeToString(item))
+		}
+		result.Vin[i] = vin
+	}
+	for i, txOut := range entry.msgTx.TxOut {
+		result.Vout[i] = btcjson.Vout{
+			Value:        toCoins(txOut.Value),
+			N:            uint32(i),
+			ScriptPubKey: server.chain.scriptPubKey(txOut.PkScript),
+		}
+	}
+	return result, nil
+}
+
+func (server *Server) getTxOut(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	txIDStr := ""
+	index := uint32(0)
+	includeMempool := true
+	if err := parseParams(params, 2, &txIDStr, &index, &includeMempool); err != nil {
+		return nil, err
+	}
+	entry, err := server.lookup(txIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown, and spent, outputs are returned as null.
+	if entry == nil || index >= uint32(len(entry.msgTx.TxOut)) {
+		return nil, nil
+	}
+	if entry.height < 0 && !includeMempool {
+		return nil, nil
+	}
+	if spender, ok := server.spentBy[wire.OutPoint{Hash: entry.txID, Index: index}]; ok {
+		if server.txs[spender].height >= 0 || includeMempool {
+			return nil, nil
+		}
+	}
+	txOut := entry.msgTx.TxOut[index]
+	return btcjson.GetTxOutResult{
+		BestBlock:     server.blocks[len(server.blocks)-1].String(),
+		Confirmations: server.confirmations(entry),
+		Value:         toCoins(txOut.Value),
+		ScriptPubKey:  server.chain.scriptPubKey(txOut.PkScript),
+	}, nil
+}
+
+// sendRawTransaction accepts a transaction into the mempool if it spends known
+// outputs that are not spent by other transactions, if its outputs do not
+// exceed its inputs, and if its signature scripts are valid.
+func (server *Server) sendRawTransaction(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	txHex := ""
+	var allowHighFees json.RawMessage
+	if err := parseParams(params, 1, &txHex, &allowHighFees); err != nil {
+		return nil, err
+	}
+	serialized, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDeserialization, "TX decode failed")
+	}
+	msgTx, txID, err := server.chain.DecodeTx(serialized)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCDeserialization, fmt.Sprintf("TX decode failed: %v", err))
+	}
+	if entry, ok := server.txs[txID]; ok {
+		if entry.height >= 0 {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyAlreadyInChain, "transaction already in block chain")
+		}
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "txn-already-in-mempool")
+	}
+	if len(msgTx.TxIn) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "bad-txns-vin-empty")
+	}
+	if len(msgTx.TxOut) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "bad-txns-vout-empty")
+	}
+
+	valueIn := int64(0)
+	prevOuts := make([]*wire.TxOut, len(msgTx.TxIn))
+	seen := map[wire.OutPoint]bool{}
+	for i, txIn := range msgTx.TxIn {
+		outpoint := txIn.PreviousOutPoint
+		if isNullOutPoint(outpoint) {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "coinbase")
+		}
+		if seen[outpoint] {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "bad-txns-inputs-duplicate")
+		}
+		seen[outpoint] = true
+		prev, ok := server.txs[outpoint.Hash]
+		if !ok || outpoint.Index >= uint32(len(prev.msgTx.TxOut)) {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCVerify, "bad-txns-inputs-missingorspent")
+		}
+		if spender, ok := server.spentBy[outpoint]; ok {
+			if server.txs[spender].height >= 0 {
+				return nil, btcjson.NewRPCError(btcjson.ErrRPCVerify, "bad-txns-inputs-missingorspent")
+			}
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "txn-mempool-conflict")
+		}
+		prevOuts[i] = prev.msgTx.TxOut[outpoint.Index]
+		valueIn += prevOuts[i].Value
+	}
+	valueOut := int64(0)
+	for _, txOut := range msgTx.TxOut {
+		if txOut.Value < 0 || txOut.Value > btcutil.MaxSatoshi {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "bad-txns-vout-negative")
+		}
+		valueOut += txOut.Value
+	}
+	if valueOut > valueIn {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, "bad-txns-in-belowout")
+	}
+	if err := server.chain.VerifyTx(serialized, prevOuts); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, fmt.Sprintf("mandatory-script-verify-flag-failed (%v)", err))
+	}
+
+	server.add(&txEntry{msgTx: msgTx, txID: txID, serialized: serialized, height: -1})
+	return txID.String(), nil
+}
+
+func (server *Server) listUnspent(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	minConf := int64(1)
+	maxConf := int64(DefaultMaxConf)
+	var addrs []string
+	if err := parseParams(params, 0, &minConf, &maxConf, &addrs); err != nil {
+		return nil, err
+	}
+	scripts := map[string]bool{}
+	for _, addr := range addrs {
+		script, err := server.chain.AddressScript(addr)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, fmt.Sprintf("Invalid address: %v", addr))
+		}
+		scripts[string(script)] = true
+	}
+
+	result := []btcjson.ListUnspentResult{}
+	for _, txID := range server.order {
+		entry := server.txs[txID]
+		confirmations := server.confirmations(entry)
+		if confirmations < minConf || confirmations > maxConf {
+			continue
+		}
+		for i, txOut := range entry.msgTx.TxOut {
+			if _, ok := server.spentBy[wire.OutPoint{Hash: txID, Index: uint32(i)}]; ok {
+				continue
+			}
+			if bitcoin.IsNullData(txOut.PkScript) || (len(scripts) > 0 && !scripts[string(txOut.PkScript)]) {
+				continue
+			}
+			addr, _ := server.chain.ScriptAddress(txOut.PkScript)
+			result = append(result, btcjson.ListUnspentResult{
+				TxID:          txID.String(),
+				Vout:          uint32(i),
+				Address:       addr,
+				ScriptPubKey:  hex.EncodeToString(txOut.PkScript),
+				Amount:        toCoins(txOut.Value),
+				Confirmations: confirmations,
+				Spendable:     true,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (server *Server) getTransaction(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	txIDStr := ""
+	var includeWatchOnly json.RawMessage
+	if err := parseParams(params, 1, &txIDStr, &includeWatchOnly); err != nil {
+		return nil, err
+	}
+	entry, err := server.lookup(txIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid or non-wallet transaction id")
+	}
+	amount := int64(0)
+	for _, txOut := range entry.msgTx.TxOut {
+		amount += txOut.Value
+	}
+	blockIndex := int64(0)
+	if entry.height >= 0 {
+		for i, txID := range server.order {
+			if txID == entry.txID {
+				break
+			}
+			if server.txs[txID].height == entry.height {
+				blockIndex = int64(i)
+			}
+		}
+	}
+	return btcjson.GetTransactionResult{
+		Amount:          toCoins(amount),
+		Confirmations:   server.confirmations(entry),
+		BlockHash:       server.blockHash(entry),
+		BlockIndex:      blockIndex,
+		TxID:            entry.txID.String(),
+		WalletConflicts: []string{},
+		Details:         []btcjson.GetTransactionDetailsResult{},
+		Hex:             hex.EncodeToString(entry.serialized),
+	}, nil
+}
+
+// estimateFee returns the fee rate, or -1 if there is not enough data, as
+// zcashd does.
+func (server *Server) estimateFee(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	numBlocks := int64(1)
+	if err := parseParams(params, 0, &numBlocks); err != nil {
+		return nil, err
+	}
+	feeRate, _, ok := server.feeRate(numBlocks)
+	if !ok {
+		return -1.0, nil
+	}
+	return feeRate, nil
+}
+
+func (server *Server) estimateSmartFee(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	numBlocks := int64(0)
+	mode := ""
+	if err := parseParams(params, 1, &numBlocks, &mode); err != nil {
+		return nil, err
+	}
+	feeRate, target, ok := server.feeRate(numBlocks)
+	if !ok {
+		return btcjson.EstimateSmartFeeResult{Errors: []string{"Insufficient data or no feerate found"}}, nil
+	}
+	return btcjson.EstimateSmartFeeResult{FeeRate: &feeRate, Blocks: target}, nil
+}
+
+// feeRate returns the fee rate of the largest target that is not greater than
+// the number of blocks.
+func (server *Server) feeRate(numBlocks int64) (float64, int64, bool) {
+	targets := make([]int64, 0, len(server.feeRates))
+	for target := range server.feeRates {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] > targets[j] })
+	for _, target := range targets {
+		if target <= numBlocks {
+			return server.feeRates[target], target, true
+		}
+	}
+	return 0, 0, false
+}
+
+func (server *Server) add(entry *txEntry) {
+	server.txs[entry.txID] = entry
+	server.order = append(server.order, entry.txID)
+	server.mempool = append(server.mempool, entry.txID)
+	for _, txIn := range entry.msgTx.TxIn {
+		if !isNullOutPoint(txIn.PreviousOutPoint) {
+			server.spentBy[txIn.PreviousOutPoint] = entry.txID
+		}
+	}
+}
+
+func (server *Server) evict(entry *txEntry) {
+	for i := range entry.msgTx.TxOut {
+		if spender, ok := server.spentBy[wire.OutPoint{Hash: entry.txID, Index: uint32(i)}]; ok {
+			server.evict(server.txs[spender])
+		}
+	}
+	for _, txIn := range entry.msgTx.TxIn {
+		if server.spentBy[txIn.PreviousOutPoint] == entry.txID {
+			delete(server.spentBy, txIn.PreviousOutPoint)
+		}
+	}
+	delete(server.txs, entry.txID)
+	server.order = removeHash(server.order, entry.txID)
+	server.mempool = removeHash(server.mempool, entry.txID)
+}
+
+func removeHash(hashes []chainhash.Hash, hash chainhash.Hash) []chainhash.Hash {
+	for i := range hashes {
+		if hashes[i] == hash {
+			return append(hashes[:i:i], hashes[i+1:]...)
+		}
+	}
+	return hashes
+}
+
+func (server *Server) mine(numBlocks int) []chainhash.Hash {
+	hashes := make([]chainhash.Hash, numBlocks)
+	for i := range hashes {
+		height := int64(len(server.blocks))
+		header := make([]byte, 0, chainhash.HashSize*(len(server.mempool)+1)+8)
+		header = append(header, server.blocks[height-1][:]...)
+		header = append(header, byte(height), byte(height>>8), byte(height>>16), byte(height>>24))
+		for _, txID := range server.mempool {
+			header = append(header, txID[:]...)
+			server.txs[txID].height = height
+		}
+		server.mempool = nil
+		for _, note := range server.notes {
+			if note.height < 0 {
+				note.height = height
+			}
+		}
+		hashes[i] = chainhash.DoubleHashH(header)
+		server.blocks = append(server.blocks, hashes[i])
+	}
+	return hashes
+}
+
+// lookup returns the transaction with the txid, or nil if it is not known.
+func (server *Server) lookup(txIDStr string) (*txEntry, *btcjson.RPCError) {
+	txID, err := chainhash.NewHashFromStr(txIDStr)
+	if err != nil || len(txIDStr) != 2*chainhash.HashSize {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "txid must be of length 64")
+	}
+	return server.txs[*txID], nil
+}
+
+func (server *Server) height() int64 {
+	return int64(len(server.blocks)) - 1
+}
+
+func (server *Server) confirmations(entry *txEntry) int64 {
+	if entry.height < 0 {
+		return 0
+	}
+	return server.height() - entry.height + 1
+}
+
+func (server *Server) blockHash(entry *txEntry) string {
+	if entry.height < 0 {
+		return ""
+	}
+	return server.blocks[entry.height].String()
+}
+
+// parseParams decodes the positional parameters into the values. There must be
+// at least the required number of parameters. Values of missing, or null,
+// parameters are not changed, so they should be initialised to their defaults.
+func parseParams(params []json.RawMessage, required int, values ...interface{}) *btcjson.RPCError {
+	if len(params) < required || len(params) > len(values) {
+		return btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, fmt.Sprintf("expected %v to %v params, got %v", required, len(values), len(params)))
+	}
+	for i, param := range params {
+		if string(param) == "null" {
+			continue
+		}
+		if err := json.Unmarshal(param, values[i]); err != nil {
+			return btcjson.NewRPCError(btcjson.ErrRPCType, fmt.Sprintf("bad param %v: %v", i, err))
+		}
+	}
+	return nil
+}
+
+// toCoins converts an amount from the smallest unit to coins, which is the unit
+// of amounts in the JSON-RPC interface.
+func toCoins(value int64) float64 {
+	return btcutil.Amount(value).ToBTC()
+}
@@ -0,0 +1,494 @@
+package testutil_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+	"github.com/pranav292gpt/zecutil/testutil"
+	"github.com/renproject/id"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	params := &chaincfg.RegressionNetParams
+	privKey := id.NewPrivKey()
+	pubKey := (*btcec.PrivateKey)(privKey).PubKey().SerializeCompressed()
+	pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), params)
+	if err != nil {
+		panic(err)
+	}
+	addr := pkhAddr.EncodeAddress()
+
+	var server *testutil.Server
+
+	BeforeEach(func() {
+		server = testutil.NewServer(testutil.BitcoinChain(params))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// call sends a JSON-RPC request to the server, and returns the result, the
+	// error, and the HTTP status code.
+	call := func(method string, params ...interface{}) (json.RawMessage, *btcjson.RPCError, int) {
+		data, err := json.Marshal(map[string]interface{}{"jsonrpc": "1.0", "id": 1, "method": method, "params": params})
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := http.Post(server.URL(), "application/json", bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		body := struct {
+			Result json.RawMessage   `json:"result"`
+			Error  *btcjson.RPCError `json:"error"`
+			ID     int               `json:"id"`
+		}{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		Expect(body.ID).To(Equal(1))
+		return body.Result, body.Error, resp.StatusCode
+	}
+
+	// spend builds and signs a transaction that spends the outputs, and
+	// returns it in hex.
+	spend := func(outputs []utxo.Output, recipients ...utxo.Recipient) (string, chainhash.Hash) {
+		inputs := make([]utxo.Input, len(outputs))
+		for i := range outputs {
+			inputs[i] = utxo.Input{Output: outputs[i]}
+		}
+		tx, err := bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		signatures := make([]pack.Bytes65, len(sighashes))
+		for i := range sighashes {
+			hash := id.Hash(sighashes[i])
+			signature, err := privKey.Sign(&hash)
+			Expect(err).ToNot(HaveOccurred())
+			signatures[i] = pack.NewBytes65(signature)
+		}
+		Expect(tx.Sign(signatures, pack.NewBytes(pubKey))).To(Succeed())
+		serialized, err := tx.Serialize()
+		Expect(err).ToNot(HaveOccurred())
+		hash, err := tx.Hash()
+		Expect(err).ToNot(HaveOccurred())
+		var txID chainhash.Hash
+		copy(txID[:], hash)
+		return hex.EncodeToString(serialized), txID
+	}
+
+	pay := func(value uint64) utxo.Recipient {
+		return utxo.Recipient{To: address.Address(addr), Value: pack.NewU256FromUint64(value)}
+	}
+
+	Context("when mining blocks", func() {
+		It("should confirm the transactions in the mempool", func() {
+			Expect(server.Height()).To(Equal(int64(0)))
+			output, err := server.Fund(addr, 100000)
+			Expect(err).ToNot(HaveOccurred())
+			var txID chainhash.Hash
+			copy(txID[:], output.Outpoint.Hash)
+			Expect(server.Mempool()).To(Equal([]chainhash.Hash{txID}))
+			confs, ok := server.Confirmations(txID)
+			Expect(ok).To(BeTrue())
+			Expect(confs).To(Equal(int64(0)))
+
+			hashes := server.Mine(2)
+			Expect(hashes).To(HaveLen(2))
+			Expect(hashes[0]).ToNot(Equal(hashes[1]))
+			Expect(server.Height()).To(Equal(int64(2)))
+			Expect(server.Mempool()).To(BeEmpty())
+			confs, _ = server.Confirmations(txID)
+			Expect(confs).To(Equal(int64(2)))
+
+			result, rpcErr, _ := call("generate", 3)
+			Expect(rpcErr).To(BeNil())
+			var generated []string
+			Expect(json.Unmarshal(result, &generated)).To(Succeed())
+			Expect(generated).To(HaveLen(3))
+			result, _, _ = call("getblockcount")
+			Expect(string(result)).To(Equal("5"))
+			result, _, _ = call("getbestblockhash")
+			Expect(string(result)).To(Equal(`"` + generated[2] + `"`))
+
+			_, ok = server.Confirmations(chainhash.Hash{})
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("when loading transactions and outputs", func() {
+		It("should return them in the format of the node", func() {
+			output, err := server.Fund(addr, 100000)
+			Expect(err).ToNot(HaveOccurred())
+			var txID chainhash.Hash
+			copy(txID[:], output.Outpoint.Hash)
+
+			// Unconfirmed outputs are only returned from the mempool.
+			result, rpcErr, _ := call("gettxout", txID.String(), 0, false)
+			Expect(rpcErr).To(BeNil())
+			Expect(string(result)).To(Equal("null"))
+			result, _, _ = call("gettxout", txID.String(), 0)
+			txOut := btcjson.GetTxOutResult{}
+			Expect(json.Unmarshal(result, &txOut)).To(Succeed())
+			Expect(txOut.Value).To(Equal(0.001))
+			Expect(txOut.Confirmations).To(Equal(int64(0)))
+			Expect(txOut.ScriptPubKey.Hex).To(Equal(hex.EncodeToString(output.PubKeyScript)))
+			Expect(txOut.ScriptPubKey.Addresses).To(Equal([]string{addr}))
+			Expect(txOut.ScriptPubKey.Type).To(Equal("pubkeyhash"))
+
+			server.Mine(1)
+			result, _, _ = call("getrawtransaction", txID.String(), 1)
+			rawTx := btcjson.TxRawResult{}
+			Expect(json.Unmarshal(result, &rawTx)).To(Succeed())
+			Expect(rawTx.Txid).To(Equal(txID.String()))
+			Expect(rawTx.Confirmations).To(Equal(uint64(1)))
+			Expect(rawTx.Vout).To(HaveLen(1))
+			Expect(rawTx.Vout[0].Value).To(Equal(0.001))
+			Expect(rawTx.Vin[0].Coinbase).ToNot(BeEmpty())
+			result, _, _ = call("getrawtransaction", txID.String())
+			Expect(string(result)).To(Equal(`"` + rawTx.Hex + `"`))
+
+			result, _, _ = call("gettransaction", txID.String())
+			walletTx := btcjson.GetTransactionResult{}
+			Expect(json.Unmarshal(result, &walletTx)).To(Succeed())
+			Expect(walletTx.Confirmations).To(Equal(int64(1)))
+			Expect(walletTx.BlockHash).To(Equal(rawTx.BlockHash))
+			Expect(walletTx.Hex).To(Equal(rawTx.Hex))
+
+			// Unknown transactions are errors, but unknown outputs are null.
+			_, rpcErr, status := call("getrawtransaction", chainhash.Hash{}.String(), 1)
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCInvalidAddressOrKey))
+			Expect(status).To(Equal(http.StatusInternalServerError))
+			_, rpcErr, _ = call("gettransaction", chainhash.Hash{}.String())
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCInvalidAddressOrKey))
+			_, rpcErr, _ = call("gettransaction", "00")
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCInvalidParameter))
+			result, rpcErr, _ = call("gettxout", txID.String(), 1)
+			Expect(rpcErr).To(BeNil())
+			Expect(string(result)).To(Equal("null"))
+		})
+
+		It("should list the unspent outputs", func() {
+			other, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+			Expect(err).ToNot(HaveOccurred())
+			output, err := server.Fund(addr, 100000)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = server.Fund(other.EncodeAddress(), 200000)
+			Expect(err).ToNot(HaveOccurred())
+			server.Mine(1)
+			_, err = server.Fund(addr, 300000)
+			Expect(err).ToNot(HaveOccurred())
+
+			unspent := func(params ...interface{}) []btcjson.ListUnspentResult {
+				result, rpcErr, _ := call("listunspent", params...)
+				Expect(rpcErr).To(BeNil())
+				results := []btcjson.ListUnspentResult{}
+				Expect(json.Unmarshal(result, &results)).To(Succeed())
+				return results
+			}
+			Expect(unspent()).To(HaveLen(2))
+			Expect(unspent(0)).To(HaveLen(3))
+			Expect(unspent(0, 0)).To(HaveLen(1))
+			Expect(unspent(0, 9999999, []string{addr})).To(HaveLen(2))
+			results := unspent(1, 9999999, []string{addr})
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Address).To(Equal(addr))
+			Expect(results[0].Amount).To(Equal(0.001))
+			Expect(results[0].Confirmations).To(Equal(int64(1)))
+
+			// Outputs that are spent in the mempool are not listed.
+			txHex, _ := spend([]utxo.Output{output}, pay(90000))
+			_, rpcErr, _ := call("sendrawtransaction", txHex)
+			Expect(rpcErr).To(BeNil())
+			Expect(unspent(0, 9999999, []string{addr})).To(HaveLen(2))
+			Expect(unspent(1, 9999999, []string{addr})).To(BeEmpty())
+
+			_, rpcErr, _ = call("listunspent", 0, 9999999, []string{"bad"})
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCInvalidAddressOrKey))
+		})
+	})
+
+	Context("when submitting transactions", func() {
+		It("should accept valid transactions, including chains of unconfirmed transactions", func() {
+			output, err := server.Fund(addr, 100000)
+			Expect(err).ToNot(HaveOccurred())
+			txHex, txID := spend([]utxo.Output{output}, pay(90000))
+			result, rpcErr, _ := call("sendrawtransaction", txHex)
+			Expect(rpcErr).To(BeNil())
+			Expect(string(result)).To(Equal(`"` + txID.String() + `"`))
+
+			child := utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(txID[:]), Index: pack.NewU32(0)},
+				Value:        pack.NewU256FromUint64(90000),
+				PubKeyScript: output.PubKeyScript,
+			}
+			childHex, childID := spend([]utxo.Output{child}, pay(80000))
+			_, rpcErr, _ = call("sendrawtransaction", childHex)
+			Expect(rpcErr).To(BeNil())
+			result, _, _ = call("getrawmempool")
+			var mempool []string
+			Expect(json.Unmarshal(result, &mempool)).To(Succeed())
+			Expect(mempool).To(HaveLen(3))
+			Expect(mempool[1:]).To(Equal([]string{txID.String(), childID.String()}))
+
+			_, rpcErr, _ = call("sendrawtransaction", childHex)
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCVerifyRejected))
+			server.Mine(1)
+			_, rpcErr, _ = call("sendrawtransaction", childHex)
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCVerifyAlreadyInChain))
+		})
+
+		It("should evict transactions and their descendants from the mempool", func() {
+			output, err := server.Fund(addr, 100000)
+			Expect(err).ToNot(HaveOccurred())
+			server.Mine(1)
+			txHex, txID := spend([]utxo.Output{output}, pay(90000))
+			_, rpcErr, _ := call("sendrawtransaction", txHex)
+			Expect(rpcErr).To(BeNil())
+			child := utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(txID[:]), Index: pack.NewU32(0)},
+				Value:        pack.NewU256FromUint64(90000),
+				PubKeyScript: output.PubKeyScript,
+			}
+			childHex, childID := spend([]utxo.Output{child}, pay(80000))
+			_, rpcErr, _ = call("sendrawtransaction", childHex)
+			Expect(rpcErr).To(BeNil())
+
+			Expect(server.Evict(txID)).To(BeTrue())
+			Expect(server.Mempool()).To(BeEmpty())
+			_, ok := server.Confirmations(childID)
+			Expect(ok).To(BeFalse())
+			Expect(server.Evict(txID)).To(BeFalse())
+
+			// The output can be spent again, and the evicted transaction can
+			// be submitted again.
+			result, rpcErr, _ := call("listunspent")
+			Expect(rpcErr).To(BeNil())
+			Expect(string(result)).To(ContainSubstring(hex.EncodeToString(output.PubKeyScript)))
+			_, rpcErr, _ = call("sendrawtransaction", txHex)
+			Expect(rpcErr).To(BeNil())
+			server.Mine(1)
+			Expect(server.Evict(txID)).To(BeFalse())
+		})
+
+		It("should reject invalid transactions", func() {
+			output, err := server.Fund(addr, 100000)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, rpcErr, _ := call("sendrawtransaction", "00")
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCDeserialization))
+
+			// Outputs that exceed the inputs. The transaction is built with a
+			// higher value for the input than it has.
+			moreValue := output
+			moreValue.Value = pack.NewU256FromUint64(200000)
+			txHex, _ := spend([]utxo.Output{moreValue}, pay(150000))
+			_, rpcErr, _ = call("sendrawtransaction", txHex)
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCVerifyRejected))
+			Expect(rpcErr.Message).To(Equal("bad-txns-in-belowout"))
+
+			// Signatures that commit to the wrong pubkey script.
+			wrongScript := output
+			wrongScript.PubKeyScript = append(pack.Bytes{}, output.PubKeyScript...)
+			wrongScript.PubKeyScript[3] ^= 1
+			txHex, _ = spend([]utxo.Output{wrongScript}, pay(90000))
+			_, rpcErr, _ = call("sendrawtransaction", txHex)
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCVerifyRejected))
+			Expect(rpcErr.Message).To(HavePrefix("mandatory-script-verify-flag-failed"))
+
+			// Missing inputs.
+			missing := output
+			missing.Outpoint.Index = pack.NewU32(1)
+			txHex, _ = spend([]utxo.Output{missing}, pay(90000))
+			_, rpcErr, _ = call("sendrawtransaction", txHex)
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCVerify))
+
+			// Duplicate inputs. The builder rejects them, so the input is
+			// duplicated after signing.
+			txHex, _ = spend([]utxo.Output{output}, pay(90000))
+			serialized, err := hex.DecodeString(txHex)
+			Expect(err).ToNot(HaveOccurred())
+			msgTx, err := bitcoin.DecodeTx(serialized)
+			Expect(err).ToNot(HaveOccurred())
+			msgTx.AddTxIn(msgTx.TxIn[0])
+			buf := new(bytes.Buffer)
+			Expect(msgTx.Serialize(buf)).To(Succeed())
+			txHex = hex.EncodeToString(buf.Bytes())
+			_, rpcErr, _ = call("sendrawtransaction", txHex)
+			Expect(rpcErr.Message).To(Equal("bad-txns-inputs-duplicate"))
+
+			// Conflicts with the mempool, and double-spends of the chain.
+			txHex, _ = spend([]utxo.Output{output}, pay(90000))
+			_, rpcErr, _ = call("sendrawtransaction", txHex)
+			Expect(rpcErr).To(BeNil())
+			conflictHex, _ := spend([]utxo.Output{output}, pay(80000))
+			_, rpcErr, _ = call("sendrawtransaction", conflictHex)
+			Expect(rpcErr.Message).To(Equal("txn-mempool-conflict"))
+			server.Mine(1)
+			_, rpcErr, _ = call("sendrawtransaction", conflictHex)
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCVerify))
+			Expect(server.Mempool()).To(BeEmpty())
+		})
+
+		It("should accept Zcash transactions", func() {
+			zcashServer := testutil.NewServer(testutil.ZcashChain(&zcash.RegressionNetParams))
+			defer zcashServer.Close()
+			zcashAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey), &zcash.RegressionNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			output, err := zcashServer.Fund(zcashAddr.EncodeAddress(), 100000)
+			Expect(err).ToNot(HaveOccurred())
+
+			tx, err := zcash.NewTxBuilder(&zcash.RegressionNetParams, 1000).BuildTx(
+				[]utxo.Input{{Output: output}},
+				[]utxo.Recipient{{To: address.Address(zcashAddr.EncodeAddress()), Value: pack.NewU256FromUint64(90000)}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			hash := id.Hash(sighashes[0])
+			signature, err := privKey.Sign(&hash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.Sign([]pack.Bytes65{pack.NewBytes65(signature)}, pack.NewBytes(pubKey))).To(Succeed())
+
+			client := zcash.NewClient(zcashServer.ClientOptions())
+			Expect(client.SubmitTx(context.Background(), tx)).To(Succeed())
+			zcashServer.Mine(1)
+			txHash, err := tx.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			confs, err := client.Confirmations(context.Background(), txHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(int64(1)))
+		})
+	})
+
+	Context("when sending shielded amounts", func() {
+		It("should only implement the shielded calls for Zcash", func() {
+			_, rpcErr, status := call("z_getoperationstatus")
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCMethodNotFound.Code))
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		It("should reject the params that zcashd rejects", func() {
+			server.Close()
+			server = testutil.NewServer(testutil.ZcashChain(&zcash.RegressionNetParams))
+			zcashAddr, err := zcash.NewAddressPubKeyHash(btcutil.Hash160(pubKey), &zcash.RegressionNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			taddr := zcashAddr.EncodeAddress()
+			memo := zcash.NoMemo()
+
+			for _, amount := range []interface{}{
+				// Amounts must be exact numbers.
+				map[string]interface{}{"address": taddr, "amount": "0.1"},
+				map[string]interface{}{"address": taddr, "amount": json.Number("1e-08")},
+				map[string]interface{}{"address": taddr, "amount": json.Number("0.000000001")},
+				// Amounts must not be negative.
+				map[string]interface{}{"address": taddr, "amount": json.Number("-0.1")},
+				// Memos can only be sent to shielded addresses.
+				map[string]interface{}{"address": taddr, "amount": json.Number("0.1"), "memo": memo.Hex()},
+				// Unknown keys are rejected.
+				map[string]interface{}{"address": taddr, "amount": json.Number("0.1"), "fee": 1},
+			} {
+				_, rpcErr, _ := call("z_sendmany", taddr, []interface{}{amount})
+				Expect(rpcErr).ToNot(BeNil(), "%v", amount)
+			}
+			result, rpcErr, _ := call("z_sendmany", taddr, []interface{}{map[string]interface{}{"address": taddr, "amount": json.Number("0.1")}})
+			Expect(rpcErr).To(BeNil())
+			Expect(string(result)).To(Equal(`"opid-1"`))
+
+			// The IDs must be an array.
+			_, rpcErr, _ = call("z_getoperationstatus", "opid-1")
+			Expect(rpcErr).ToNot(BeNil())
+			result, rpcErr, _ = call("z_getoperationstatus", []string{"opid-1"})
+			Expect(rpcErr).To(BeNil())
+			Expect(string(result)).To(ContainSubstring(`"status":"success"`))
+		})
+	})
+
+	Context("when estimating fees", func() {
+		It("should use the largest target that is not greater", func() {
+			result, rpcErr, _ := call("estimatefee", 1)
+			Expect(rpcErr).To(BeNil())
+			Expect(string(result)).To(Equal("-1"))
+			result, _, _ = call("estimatesmartfee", 1)
+			smartFee := btcjson.EstimateSmartFeeResult{}
+			Expect(json.Unmarshal(result, &smartFee)).To(Succeed())
+			Expect(smartFee.FeeRate).To(BeNil())
+			Expect(smartFee.Errors).ToNot(BeEmpty())
+
+			server.SetFeeRate(2, 0.0002)
+			server.SetFeeRate(6, 0.0001)
+			result, _, _ = call("estimatefee", 1)
+			Expect(string(result)).To(Equal("-1"))
+			result, _, _ = call("estimatefee", 5)
+			Expect(string(result)).To(Equal("0.0002"))
+			result, _, _ = call("estimatesmartfee", 10, "CONSERVATIVE")
+			smartFee = btcjson.EstimateSmartFeeResult{}
+			Expect(json.Unmarshal(result, &smartFee)).To(Succeed())
+			Expect(*smartFee.FeeRate).To(Equal(0.0001))
+			Expect(smartFee.Blocks).To(Equal(int64(6)))
+
+			server.SetFeeRate(6, 0)
+			result, _, _ = call("estimatefee", 10)
+			Expect(string(result)).To(Equal("0.0002"))
+		})
+	})
+
+	Context("when injecting errors", func() {
+		It("should return the error the given number of times", func() {
+			injected := btcjson.NewRPCError(btcjson.ErrRPCMisc, "injected")
+			server.InjectError("getblockcount", 2, injected)
+			for i := 0; i < 2; i++ {
+				_, rpcErr, status := call("getblockcount")
+				Expect(rpcErr).To(Equal(injected))
+				Expect(status).To(Equal(http.StatusInternalServerError))
+			}
+			_, rpcErr, _ := call("getblockcount")
+			Expect(rpcErr).To(BeNil())
+			Expect(server.Calls("getblockcount")).To(Equal(3))
+
+			// The client retries until the error is cleared.
+			server.InjectError("getblockcount", 0, injected)
+			for i := 0; i < 3; i++ {
+				_, rpcErr, _ := call("getblockcount")
+				Expect(rpcErr).To(Equal(injected))
+			}
+			server.ClearErrors()
+			server.InjectError("getblockcount", 3, injected)
+			client := bitcoin.NewClient(server.ClientOptions())
+			height, err := client.LatestBlock(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(pack.NewU64(0)))
+			Expect(server.Calls("getblockcount")).To(Equal(10))
+		})
+
+		It("should handle the call before losing the response", func() {
+			injected := btcjson.NewRPCError(btcjson.ErrRPCMisc, "injected")
+			server.InjectLostResponse("generate", 1, injected)
+			_, rpcErr, _ := call("generate", 3)
+			Expect(rpcErr).To(Equal(injected))
+			Expect(server.Height()).To(Equal(int64(3)))
+			_, rpcErr, _ = call("generate", 1)
+			Expect(rpcErr).To(BeNil())
+			Expect(server.Height()).To(Equal(int64(4)))
+		})
+
+		It("should return not found for unknown methods", func() {
+			_, rpcErr, status := call("getblockchaininfo")
+			Expect(rpcErr.Code).To(Equal(btcjson.ErrRPCMethodNotFound.Code))
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+	})
+})
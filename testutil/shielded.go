@@ -0,0 +1,207 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/pranav292gpt/zecutil/chain/zcash"
+)
+
+// A note is an amount received by an address from z_sendmany. The height is -1
+// until the next block is mined.
+type note struct {
+	txID    chainhash.Hash
+	address string
+	pool    string
+	amount  zcash.Amount
+	memo    *zcash.Memo
+	index   int
+	height  int64
+}
+
+// An operation is a z_sendmany operation. Operations succeed as soon as they
+// are created.
+type operation struct {
+	id      string
+	created int64
+	txID    chainhash.Hash
+}
+
+// zSendManyAmount is a recipient of z_sendmany. The amount is kept as a raw
+// number, so that it is parsed exactly and amounts encoded as strings are
+// rejected, as zcashd does.
+type zSendManyAmount struct {
+	Address string          `json:"address"`
+	Amount  json.RawMessage `json:"amount"`
+	Memo    *string         `json:"memo"`
+}
+
+func (server *Server) zSendMany(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	from := ""
+	var rawAmounts json.RawMessage
+	minConf := int64(1)
+	var fee json.RawMessage
+	if err := parseParams(params, 2, &from, &rawAmounts, &minConf, &fee); err != nil {
+		return nil, err
+	}
+	if _, err := server.chain.PaymentAddressKind(from); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, fmt.Sprintf("Invalid from address: %v", from))
+	}
+	amounts := []zSendManyAmount{}
+	decoder := json.NewDecoder(bytes.NewReader(rawAmounts))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&amounts); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCType, fmt.Sprintf("bad param 1: %v", err))
+	}
+	if len(amounts) == 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid parameter, amounts array is empty.")
+	}
+
+	// Only the amounts sent to shielded addresses are recorded as notes.
+	notes := []*note{}
+	for i, recipient := range amounts {
+		kind, err := server.chain.PaymentAddressKind(recipient.Address)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, fmt.Sprintf("Invalid parameter, unknown address format: %v", recipient.Address))
+		}
+		if len(recipient.Amount) == 0 || recipient.Amount[0] == '"' {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCType, "Amount is not a number")
+		}
+		amount, err := zcash.ParseAmount(string(recipient.Amount))
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCType, fmt.Sprintf("Invalid amount: %v", err))
+		}
+		if amount < 0 {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid parameter, amount must be positive")
+		}
+		var memo *zcash.Memo
+		if recipient.Memo != nil {
+			if !kind.IsShielded() {
+				return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Memo cannot be used with a taddr. It can only be used with a zaddr.")
+			}
+			decoded, err := zcash.DecodeMemoHex(*recipient.Memo)
+			if err != nil {
+				return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, fmt.Sprintf("Invalid parameter, bad memo: %v", err))
+			}
+			memo = &decoded
+		}
+		switch kind {
+		case zcash.AddressSapling:
+			notes = append(notes, &note{address: recipient.Address, pool: "sapling", amount: amount, memo: memo, index: i, height: -1})
+		case zcash.AddressUnified:
+			notes = append(notes, &note{address: recipient.Address, pool: "orchard", amount: amount, memo: memo, index: i, height: -1})
+		}
+	}
+
+	nonce := make([]byte, 4)
+	binary.LittleEndian.PutUint32(nonce, server.nonce)
+	server.nonce++
+	txID := chainhash.DoubleHashH(append([]byte("z_sendmany"), nonce...))
+	for _, note := range notes {
+		note.txID = txID
+	}
+	server.notes = append(server.notes, notes...)
+	op := &operation{
+		id:      fmt.Sprintf("opid-%d", len(server.operations)+1),
+		created: time.Now().Unix(),
+		txID:    txID,
+	}
+	server.operations = append(server.operations, op)
+	return op.id, nil
+}
+
+// zReceived is a note, in the format of z_listreceivedbyaddress.
+type zReceived struct {
+	TxID          string      `json:"txid"`
+	Pool          string      `json:"pool"`
+	Amount        json.Number `json:"amount"`
+	AmountZat     int64       `json:"amountZat"`
+	Memo          string      `json:"memo"`
+	OutIndex      int         `json:"outindex"`
+	Confirmations int64       `json:"confirmations"`
+	BlockHeight   int64       `json:"blockheight,omitempty"`
+	Change        bool        `json:"change"`
+}
+
+func (server *Server) zListReceivedByAddress(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	addr := ""
+	minConf := int64(1)
+	if err := parseParams(params, 1, &addr, &minConf); err != nil {
+		return nil, err
+	}
+	kind, err := server.chain.PaymentAddressKind(addr)
+	if err != nil || !kind.IsShielded() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid zaddr.")
+	}
+
+	result := []zReceived{}
+	for _, note := range server.notes {
+		if note.address != addr {
+			continue
+		}
+		confirmations := int64(0)
+		if note.height >= 0 {
+			confirmations = server.height() - note.height + 1
+		}
+		if confirmations < minConf {
+			continue
+		}
+		memo := zcash.NoMemo()
+		if note.memo != nil {
+			memo = *note.memo
+		}
+		amount, _ := note.amount.MarshalJSON()
+		received := zReceived{
+			TxID:          note.txID.String(),
+			Pool:          note.pool,
+			Amount:        json.Number(amount),
+			AmountZat:     int64(note.amount),
+			Memo:          memo.Hex(),
+			OutIndex:      note.index,
+			Confirmations: confirmations,
+		}
+		if note.height >= 0 {
+			received.BlockHeight = note.height
+		}
+		result = append(result, received)
+	}
+	return result, nil
+}
+
+// zOperationStatus is an operation, in the format of z_getoperationstatus.
+type zOperationStatus struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	CreationTime int64  `json:"creation_time"`
+	Method       string `json:"method"`
+	Result       struct {
+		TxID string `json:"txid"`
+	} `json:"result"`
+}
+
+func (server *Server) zGetOperationStatus(params []json.RawMessage) (interface{}, *btcjson.RPCError) {
+	var ids []string
+	if err := parseParams(params, 0, &ids); err != nil {
+		return nil, err
+	}
+	filter := map[string]bool{}
+	for _, id := range ids {
+		filter[id] = true
+	}
+
+	result := []zOperationStatus{}
+	for _, op := range server.operations {
+		if len(filter) > 0 && !filter[op.id] {
+			continue
+		}
+		status := zOperationStatus{ID: op.id, Status: "success", CreationTime: op.created, Method: "z_sendmany"}
+		status.Result.TxID = op.txID.String()
+		result = append(result, status)
+	}
+	return result, nil
+}
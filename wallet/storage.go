@@ -0,0 +1,168 @@
+package wallet
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/internal/jsonfile"
+)
+
+// The Storage interface defines the functionality required to persist the
+// entries of a Wallet. Entries are identified by their outpoints. A Storage
+// does not need to be safe for concurrent use, because the Wallet serializes
+// all access to it, but it must not share entries with its caller.
+type Storage interface {
+	// Load returns all of the stored entries.
+	Load() ([]Entry, error)
+	// Put stores the entries, replacing any stored entries with the same
+	// outpoints.
+	Put(entries ...Entry) error
+	// Delete removes the entries with the given outpoints. Outpoints that are
+	// not stored are ignored.
+	Delete(outpoints ...utxo.Outpoint) error
+}
+
+// MemoryStorage is a Storage that keeps entries in memory. Entries are lost
+// when the process exits.
+type MemoryStorage struct {
+	mu      *sync.Mutex
+	entries map[string]Entry
+}
+
+// NewMemoryStorage returns an empty MemoryStorage.
+func NewMemoryStorage() MemoryStorage {
+	return MemoryStorage{mu: new(sync.Mutex), entries: map[string]Entry{}}
+}
+
+// Load returns all of the stored entries, ordered by outpoint.
+func (storage MemoryStorage) Load() ([]Entry, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	return sortedEntries(storage.entries), nil
+}
+
+// Put stores the entries in memory.
+func (storage MemoryStorage) Put(entries ...Entry) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	for _, entry := range entries {
+		storage.entries[outpointKey(entry.Output.Outpoint)] = entry.clone()
+	}
+	return nil
+}
+
+// Delete removes the entries from memory.
+func (storage MemoryStorage) Delete(outpoints ...utxo.Outpoint) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	for _, outpoint := range outpoints {
+		delete(storage.entries, outpointKey(outpoint))
+	}
+	return nil
+}
+
+// FileStorage is a Storage that keeps entries in a JSON file. Every change
+// rewrites the file, by writing a temporary file in the same directory and
+// renaming it, so the file always holds a complete set of entries. It is
+// intended for wallets with a moderate number of outputs; larger wallets
+// should implement the Storage interface with an embedded database.
+type FileStorage struct {
+	path    string
+	mu      *sync.Mutex
+	entries map[string]Entry
+}
+
+// NewFileStorage returns a FileStorage that keeps entries in the file at the
+// given path. The entries in the file are loaded if it exists, and the file is
+// created by the first change if it does not.
+func NewFileStorage(path string) (FileStorage, error) {
+	storage := FileStorage{path: path, mu: new(sync.Mutex), entries: map[string]Entry{}}
+	entries := []Entry{}
+	if _, err := jsonfile.Read(path, &entries); err != nil {
+		return FileStorage{}, err
+	}
+	for _, entry := range entries {
+		storage.entries[outpointKey(entry.Output.Outpoint)] = entry
+	}
+	return storage, nil
+}
+
+// Load returns all of the stored entries, ordered by outpoint.
+func (storage FileStorage) Load() ([]Entry, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	return sortedEntries(storage.entries), nil
+}
+
+// Put stores the entries, and rewrites the file. Nothing is stored if the file
+// cannot be rewritten.
+func (storage FileStorage) Put(entries ...Entry) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	next := storage.clone()
+	for _, entry := range entries {
+		next[outpointKey(entry.Output.Outpoint)] = entry.clone()
+	}
+	return storage.replace(next)
+}
+
+// Delete removes the entries, and rewrites the file. Nothing is removed if the
+// file cannot be rewritten.
+func (storage FileStorage) Delete(outpoints ...utxo.Outpoint) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	next := storage.clone()
+	for _, outpoint := range outpoints {
+		delete(next, outpointKey(outpoint))
+	}
+	if len(next) == len(storage.entries) {
+		return nil
+	}
+	return storage.replace(next)
+}
+
+// clone returns a copy of the stored entries, to which a change can be applied
+// before it is written.
+func (storage FileStorage) clone() map[string]Entry {
+	next := make(map[string]Entry, len(storage.entries))
+	for key, entry := range storage.entries {
+		next[key] = entry
+	}
+	return next
+}
+
+// replace writes the entries to the file, and then stores them in memory.
+func (storage FileStorage) replace(next map[string]Entry) error {
+	if err := jsonfile.Write(storage.path, sortedEntries(next)); err != nil {
+		return err
+	}
+	for key := range storage.entries {
+		if _, ok := next[key]; !ok {
+			delete(storage.entries, key)
+		}
+	}
+	for key, entry := range next {
+		storage.entries[key] = entry
+	}
+	return nil
+}
+
+func sortedEntries(entries map[string]Entry) []Entry {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sorted := make([]Entry, len(keys))
+	for i, key := range keys {
+		sorted[i] = entries[key].clone()
+	}
+	return sorted
+}
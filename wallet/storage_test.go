@@ -0,0 +1,121 @@
+package wallet_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/wallet"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "wallet")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	entry := func(index uint32, value uint64) wallet.Entry {
+		return wallet.Entry{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes{1, 2, 3}, Index: pack.NewU32(index)},
+				Value:        pack.NewU256FromUint64(value),
+				PubKeyScript: pack.Bytes{0x76, 0xa9},
+			},
+			Address: address.Address("addr"),
+			Status:  wallet.StatusConfirmed,
+		}
+	}
+
+	testStorage := func(storage wallet.Storage) {
+		entries, err := storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+
+		Expect(storage.Put(entry(1, 100), entry(0, 200))).To(Succeed())
+		entries, err = storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal([]wallet.Entry{entry(0, 200), entry(1, 100)}))
+
+		// Entries with the same outpoint are replaced.
+		reserved := entry(0, 200)
+		reserved.Reservation = "id"
+		expiry := time.Unix(1600000000, 0).UTC()
+		reserved.Expiry = &expiry
+		Expect(storage.Put(reserved)).To(Succeed())
+		entries, err = storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal([]wallet.Entry{reserved, entry(1, 100)}))
+
+		// Changing the loaded entries does not change the stored entries.
+		entries[1].Output.PubKeyScript[0] = 0
+		entries, err = storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries[1]).To(Equal(entry(1, 100)))
+
+		Expect(storage.Delete(reserved.Output.Outpoint, entry(2, 0).Output.Outpoint)).To(Succeed())
+		entries, err = storage.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal([]wallet.Entry{entry(1, 100)}))
+	}
+
+	Context("when storing entries in memory", func() {
+		It("should put, load, and delete entries", func() {
+			testStorage(wallet.NewMemoryStorage())
+		})
+	})
+
+	Context("when storing entries in a file", func() {
+		It("should put, load, and delete entries", func() {
+			storage, err := wallet.NewFileStorage(filepath.Join(dir, "wallet.json"))
+			Expect(err).ToNot(HaveOccurred())
+			testStorage(storage)
+		})
+
+		It("should load the entries when the file is opened again", func() {
+			path := filepath.Join(dir, "wallet.json")
+			storage, err := wallet.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storage.Put(entry(0, 100), entry(1, 200))).To(Succeed())
+			Expect(storage.Delete(entry(0, 100).Output.Outpoint)).To(Succeed())
+
+			storage, err = wallet.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			entries, err := storage.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]wallet.Entry{entry(1, 200)}))
+		})
+
+		It("should not change the entries when the file cannot be written", func() {
+			storage, err := wallet.NewFileStorage(filepath.Join(dir, "wallet.json"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storage.Put(entry(0, 100))).To(Succeed())
+
+			Expect(os.RemoveAll(dir)).To(Succeed())
+			Expect(storage.Put(entry(0, 200), entry(1, 100))).ToNot(Succeed())
+			Expect(storage.Delete(entry(0, 100).Output.Outpoint)).ToNot(Succeed())
+			entries, err := storage.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]wallet.Entry{entry(0, 100)}))
+		})
+
+		It("should return an error for a malformed file", func() {
+			path := filepath.Join(dir, "wallet.json")
+			Expect(os.WriteFile(path, []byte("{"), 0600)).To(Succeed())
+			_, err := wallet.NewFileStorage(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
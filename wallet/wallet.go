@@ -0,0 +1,549 @@
+// Package wallet keeps track of the unspent outputs of a set of addresses, so
+// that concurrent transaction builders do not spend the same outputs. Outputs
+// are synced from a utxo.Client, reserved for an in-flight transaction until a
+// deadline, and then either released (when the transaction is abandoned or
+// rejected) or committed (when the transaction is accepted). Change that is
+// returned to a tracked address by a committed transaction can be spent before
+// it confirms. If a committed transaction is not mined before a deadline, its
+// inputs are returned to the wallet and its change is dropped. The state of
+// the wallet is persisted through a Storage.
+package wallet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultReservationTTL is the time for which outputs are reserved, after
+	// which they can be reserved again by another transaction.
+	DefaultReservationTTL = 10 * time.Minute
+	// DefaultCommitTTL is the time after which a committed transaction that
+	// has not been mined is assumed to have been evicted. It is longer than
+	// the default expiry delta of Zcash transactions (40 blocks, or about 50
+	// minutes), after which the transaction can no longer be mined.
+	DefaultCommitTTL = time.Hour
+	// DefaultMinConf is the number of confirmations required for synced
+	// outputs to be spendable.
+	DefaultMinConf = 1
+)
+
+var (
+	// ErrInsufficientFunds is returned when there are not enough spendable
+	// outputs to reserve the requested value.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrUnknownReservation is returned when a reservation does not exist, or
+	// has expired and its outputs have been reserved again.
+	ErrUnknownReservation = errors.New("unknown reservation")
+)
+
+// The Client interface defines the functionality required to sync and submit
+// transactions. It is implemented by the bitcoin and zcash clients.
+type Client interface {
+	UnspentOutputs(ctx context.Context, minConf, maxConf int64, address address.Address) ([]utxo.Output, error)
+	SubmitTx(ctx context.Context, tx utxo.Tx) error
+	Confirmations(ctx context.Context, txHash pack.Bytes) (int64, error)
+}
+
+// Status of an entry.
+type Status string
+
+const (
+	// StatusConfirmed is the status of outputs that have been synced from the
+	// client with the required number of confirmations.
+	StatusConfirmed = Status("confirmed")
+	// StatusPending is the status of change outputs produced by committed
+	// transactions that have not yet been synced from the client. They are
+	// spendable, but unconfirmed. They are removed if they have not been
+	// synced after the commit TTL.
+	StatusPending = Status("pending")
+	// StatusSpent is the status of outputs spent by committed transactions
+	// that are still returned by the client. They are removed by the first
+	// sync that does not return them, and become confirmed again if they are
+	// still returned after the commit TTL.
+	StatusSpent = Status("spent")
+)
+
+// An Entry is an output tracked by the wallet.
+type Entry struct {
+	Output  utxo.Output     `json:"output"`
+	Address address.Address `json:"address"`
+	Status  Status          `json:"status"`
+	// Reservation is the ID of the reservation that holds the output, and
+	// Expiry is the time at which it expires. They are empty when the output
+	// is not reserved.
+	Reservation string     `json:"reservation,omitempty"`
+	Expiry      *time.Time `json:"expiry,omitempty"`
+	// Committed is the time at which the transaction that spent the output
+	// (for spent outputs), or that produced it (for pending change), was
+	// committed. It is empty for confirmed outputs.
+	Committed *time.Time `json:"committed,omitempty"`
+}
+
+// Reserved returns whether the entry is held by a reservation that has not
+// expired at the given time.
+func (entry Entry) Reserved(now time.Time) bool {
+	return entry.Reservation != "" && entry.Expiry != nil && now.Before(*entry.Expiry)
+}
+
+func (entry Entry) clone() Entry {
+	entry.Output.Outpoint.Hash = append(pack.Bytes{}, entry.Output.Outpoint.Hash...)
+	entry.Output.PubKeyScript = append(pack.Bytes{}, entry.Output.PubKeyScript...)
+	if entry.Expiry != nil {
+		expiry := *entry.Expiry
+		entry.Expiry = &expiry
+	}
+	if entry.Committed != nil {
+		committed := *entry.Committed
+		entry.Committed = &committed
+	}
+	return entry
+}
+
+// A Reservation holds outputs for an in-flight transaction.
+type Reservation struct {
+	ID      string
+	Outputs []utxo.Output
+	// Value is the total value of the outputs.
+	Value  pack.U256
+	Expiry time.Time
+}
+
+// Inputs returns the reserved outputs as inputs, to be passed to a TxBuilder.
+func (reservation Reservation) Inputs() []utxo.Input {
+	inputs := make([]utxo.Input, len(reservation.Outputs))
+	for i, output := range reservation.Outputs {
+		inputs[i] = utxo.Input{Output: output}
+	}
+	return inputs
+}
+
+// Options are used to parameterise the behaviour of the Wallet.
+type Options struct {
+	// ReservationTTL is the time for which outputs are reserved.
+	ReservationTTL time.Duration
+	// CommitTTL is the time after which a committed transaction that has not
+	// been mined is assumed to have been evicted.
+	CommitTTL time.Duration
+	// MinConf is the number of confirmations required for synced outputs to
+	// be spendable.
+	MinConf int64
+	// SpendPending allows change that has not been confirmed to be reserved.
+	SpendPending bool
+	// Now returns the current time.
+	Now func() time.Time
+}
+
+// DefaultOptions returns Options with the default settings. Pending change can
+// be spent.
+func DefaultOptions() Options {
+	return Options{
+		ReservationTTL: DefaultReservationTTL,
+		CommitTTL:      DefaultCommitTTL,
+		MinConf:        DefaultMinConf,
+		SpendPending:   true,
+		Now:            time.Now,
+	}
+}
+
+// WithReservationTTL sets the time for which outputs are reserved.
+func (opts Options) WithReservationTTL(ttl time.Duration) Options {
+	opts.ReservationTTL = ttl
+	return opts
+}
+
+// WithCommitTTL sets the time after which a committed transaction that has not
+// been mined is assumed to have been evicted.
+func (opts Options) WithCommitTTL(ttl time.Duration) Options {
+	opts.CommitTTL = ttl
+	return opts
+}
+
+// WithMinConf sets the number of confirmations required for synced outputs to
+// be spendable.
+func (opts Options) WithMinConf(minConf int64) Options {
+	opts.MinConf = minConf
+	return opts
+}
+
+// WithSpendPending sets whether change that has not been confirmed can be
+// reserved.
+func (opts Options) WithSpendPending(spendPending bool) Options {
+	opts.SpendPending = spendPending
+	return opts
+}
+
+// WithNow sets the function used to get the current time.
+func (opts Options) WithNow(now func() time.Time) Options {
+	opts.Now = now
+	return opts
+}
+
+// A Wallet tracks the unspent outputs of a set of addresses. It is safe for
+// concurrent use.
+type Wallet struct {
+	opts    Options
+	client  Client
+	storage Storage
+
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// New returns a Wallet that syncs outputs from the client, and persists its
+// state to the storage. The entries in the storage are loaded, including any
+// reservations that have not expired.
+func New(client Client, storage Storage, opts Options) (*Wallet, error) {
+	if opts.ReservationTTL <= 0 {
+		return nil, fmt.Errorf("bad reservation ttl: expected greater than zero, got %v", opts.ReservationTTL)
+	}
+	if opts.CommitTTL <= 0 {
+		return nil, fmt.Errorf("bad commit ttl: expected greater than zero, got %v", opts.CommitTTL)
+	}
+	if opts.MinConf < 0 {
+		return nil, fmt.Errorf("bad min conf: expected non-negative, got %v", opts.MinConf)
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	entries, err := storage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading entries: %v", err)
+	}
+	wallet := &Wallet{opts: opts, client: client, storage: storage, entries: make(map[string]Entry, len(entries))}
+	for _, entry := range entries {
+		wallet.entries[outpointKey(entry.Output.Outpoint)] = entry
+	}
+	return wallet, nil
+}
+
+// Sync the outputs of the addresses from the client. Outputs with the required
+// number of confirmations are added, and pending change becomes confirmed once
+// it is returned by the client. Outputs of the addresses that are no longer
+// returned by the client, because they have been spent by another transaction,
+// are removed, even if they are reserved. Pending change is kept until it is
+// confirmed, because the client does not return unconfirmed outputs.
+//
+// If the transaction that spent or produced an output was committed more than
+// the commit TTL ago, and has not been mined, it is assumed to have been
+// evicted: its spent outputs that are still returned by the client become
+// confirmed again, and its pending change is removed.
+func (wallet *Wallet) Sync(ctx context.Context, addrs ...address.Address) error {
+	synced := make(map[address.Address]map[string]utxo.Output, len(addrs))
+	for _, addr := range addrs {
+		outputs, err := wallet.client.UnspentOutputs(ctx, wallet.opts.MinConf, math.MaxInt32, addr)
+		if err != nil {
+			return fmt.Errorf("bad unspent outputs for %v: %v", addr, err)
+		}
+		synced[addr] = make(map[string]utxo.Output, len(outputs))
+		for _, output := range outputs {
+			synced[addr][outpointKey(output.Outpoint)] = output
+		}
+	}
+
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	now := wallet.opts.Now()
+	put := []Entry{}
+	del := []utxo.Outpoint{}
+	for key, entry := range wallet.entries {
+		outputs, ok := synced[entry.Address]
+		if !ok {
+			continue
+		}
+		evicted := entry.Committed != nil && !now.Before(entry.Committed.Add(wallet.opts.CommitTTL))
+		if _, ok := outputs[key]; ok {
+			if entry.Status == StatusPending || (entry.Status == StatusSpent && evicted) {
+				entry.Status = StatusConfirmed
+				entry.Committed = nil
+				put = append(put, entry)
+			}
+			continue
+		}
+		if entry.Status != StatusPending || evicted {
+			del = append(del, entry.Output.Outpoint)
+		}
+	}
+	for addr, outputs := range synced {
+		for key, output := range outputs {
+			if _, ok := wallet.entries[key]; !ok {
+				put = append(put, Entry{Output: output, Address: addr, Status: StatusConfirmed})
+			}
+		}
+	}
+	return wallet.apply(put, del)
+}
+
+// Reserve spendable outputs with a total value of at least the given value.
+// The largest outputs are reserved first, and confirmed outputs are preferred
+// over pending change of the same value. Outputs that are held by a
+// reservation that has expired can be reserved again.
+func (wallet *Wallet) Reserve(value pack.U256) (Reservation, error) {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	now := wallet.opts.Now()
+	available := []Entry{}
+	for _, entry := range wallet.entries {
+		if entry.Reserved(now) || !wallet.spendable(entry) {
+			continue
+		}
+		available = append(available, entry)
+	}
+	sort.Slice(available, func(i, j int) bool {
+		if !available[i].Output.Value.Equal(available[j].Output.Value) {
+			return available[i].Output.Value.GreaterThan(available[j].Output.Value)
+		}
+		if available[i].Status != available[j].Status {
+			return available[i].Status == StatusConfirmed
+		}
+		return outpointKey(available[i].Output.Outpoint) < outpointKey(available[j].Output.Outpoint)
+	})
+
+	id, err := newReservationID()
+	if err != nil {
+		return Reservation{}, err
+	}
+	reservation := Reservation{ID: id, Outputs: []utxo.Output{}, Value: pack.NewU256FromUint64(0), Expiry: now.Add(wallet.opts.ReservationTTL)}
+	reserved := []Entry{}
+	for _, entry := range available {
+		if reservation.Value.GreaterThanEqual(value) && len(reserved) > 0 {
+			break
+		}
+		entry.Reservation = reservation.ID
+		entry.Expiry = &reservation.Expiry
+		reserved = append(reserved, entry)
+		reservation.Outputs = append(reservation.Outputs, entry.clone().Output)
+		reservation.Value = reservation.Value.Add(entry.Output.Value)
+	}
+	if len(reserved) == 0 || reservation.Value.LessThan(value) {
+		return Reservation{}, fmt.Errorf("%w: %v available, expected at least %v", ErrInsufficientFunds, reservation.Value, value)
+	}
+	if err := wallet.apply(reserved, nil); err != nil {
+		return Reservation{}, err
+	}
+	return reservation, nil
+}
+
+// Release the outputs of a reservation, so that they can be reserved by
+// another transaction.
+func (wallet *Wallet) Release(id string) error {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	reserved := wallet.reserved(id)
+	if len(reserved) == 0 {
+		return fmt.Errorf("%w: %v", ErrUnknownReservation, id)
+	}
+	for i := range reserved {
+		reserved[i].Reservation = ""
+		reserved[i].Expiry = nil
+	}
+	return wallet.apply(reserved, nil)
+}
+
+// Renew a reservation that has not expired, so that it expires after the
+// reservation TTL from now. The new expiry is returned.
+func (wallet *Wallet) Renew(id string) (time.Time, error) {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	reserved := wallet.reserved(id)
+	if len(reserved) == 0 {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrUnknownReservation, id)
+	}
+	expiry := wallet.opts.Now().Add(wallet.opts.ReservationTTL)
+	for i := range reserved {
+		reserved[i].Expiry = &expiry
+	}
+	if err := wallet.apply(reserved, nil); err != nil {
+		return time.Time{}, err
+	}
+	return expiry, nil
+}
+
+// Commit a reservation after the transaction that spends its outputs has been
+// accepted. The outputs are marked as spent, and outputs of the transaction
+// that pay to a tracked address are added as pending change, until the
+// transaction is mined or the commit TTL passes (see Sync). The reservation
+// must not have expired.
+func (wallet *Wallet) Commit(id string, tx utxo.Tx) error {
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return fmt.Errorf("bad outputs: %v", err)
+	}
+
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	reserved := wallet.reserved(id)
+	if len(reserved) == 0 {
+		return fmt.Errorf("%w: %v", ErrUnknownReservation, id)
+	}
+	scripts := map[string]address.Address{}
+	for _, entry := range wallet.entries {
+		scripts[string(entry.Output.PubKeyScript)] = entry.Address
+	}
+	committed := wallet.opts.Now()
+	put := make([]Entry, 0, len(reserved)+len(outputs))
+	for _, entry := range reserved {
+		entry.Status = StatusSpent
+		entry.Reservation = ""
+		entry.Expiry = nil
+		entry.Committed = &committed
+		put = append(put, entry)
+	}
+	for _, output := range outputs {
+		addr, ok := scripts[string(output.PubKeyScript)]
+		if !ok {
+			continue
+		}
+		put = append(put, Entry{Output: output, Address: addr, Status: StatusPending, Committed: &committed})
+	}
+	return wallet.apply(put, nil)
+}
+
+// SubmitTx submits a transaction that spends the outputs of a reservation. The
+// reservation is committed if the transaction is accepted by the client. If
+// submitting fails, the transaction may still have been accepted (for example,
+// if the response was lost), so the client is asked for its confirmations, and
+// the reservation is committed if it is known. Otherwise, the reservation is
+// released if the transaction was definitely rejected (see Rejected), and kept
+// until it expires if it was not, so that its outputs are not spent by another
+// transaction while this one can still be mined. The client is asked once the
+// context is done, so the context should have a deadline.
+func (wallet *Wallet) SubmitTx(ctx context.Context, id string, tx utxo.Tx) error {
+	err := wallet.client.SubmitTx(ctx, tx)
+	if err == nil {
+		return wallet.Commit(id, tx)
+	}
+	if txHash, hashErr := tx.Hash(); hashErr == nil {
+		if _, confErr := wallet.client.Confirmations(ctx, txHash); confErr == nil {
+			return wallet.Commit(id, tx)
+		}
+	}
+	if !Rejected(err) {
+		return fmt.Errorf("submitting tx: %v", err)
+	}
+	if releaseErr := wallet.Release(id); releaseErr != nil {
+		return fmt.Errorf("submitting tx: %v (%v)", err, releaseErr)
+	}
+	return fmt.Errorf("submitting tx: %v", err)
+}
+
+// Rejected returns whether the error wraps a *btcjson.RPCError with a code
+// that is used by bitcoind and zcashd when a transaction is definitely
+// rejected: it cannot be decoded, is invalid, or is not accepted by the
+// mempool. Transactions that are already known are rejected with the same
+// codes, so the node must be asked about the transaction before its inputs are
+// assumed to be unspent.
+func Rejected(err error) bool {
+	rpcErr := new(btcjson.RPCError)
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	switch rpcErr.Code {
+	case btcjson.ErrRPCDeserialization, btcjson.ErrRPCVerify, btcjson.ErrRPCVerifyRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// Entries returns all of the entries tracked by the wallet, ordered by
+// outpoint.
+func (wallet *Wallet) Entries() []Entry {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	return sortedEntries(wallet.entries)
+}
+
+// Balance returns the total value of the confirmed outputs, and of the pending
+// change, that are not spent. Reserved outputs are included.
+func (wallet *Wallet) Balance() (confirmed, pending pack.U256) {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	confirmed, pending = pack.NewU256FromUint64(0), pack.NewU256FromUint64(0)
+	for _, entry := range wallet.entries {
+		switch entry.Status {
+		case StatusConfirmed:
+			confirmed = confirmed.Add(entry.Output.Value)
+		case StatusPending:
+			pending = pending.Add(entry.Output.Value)
+		}
+	}
+	return confirmed, pending
+}
+
+func (wallet *Wallet) spendable(entry Entry) bool {
+	switch entry.Status {
+	case StatusConfirmed:
+		return true
+	case StatusPending:
+		return wallet.opts.SpendPending
+	default:
+		return false
+	}
+}
+
+// reserved returns the entries held by the reservation, if it has not expired.
+func (wallet *Wallet) reserved(id string) []Entry {
+	now := wallet.opts.Now()
+	reserved := []Entry{}
+	for _, entry := range wallet.entries {
+		if entry.Reservation == id && entry.Reserved(now) {
+			reserved = append(reserved, entry)
+		}
+	}
+	return reserved
+}
+
+// apply persists the changes to the entries, and then applies them in memory.
+// The mutex must be held.
+func (wallet *Wallet) apply(put []Entry, del []utxo.Outpoint) error {
+	if len(put) > 0 {
+		if err := wallet.storage.Put(put...); err != nil {
+			return fmt.Errorf("storing entries: %v", err)
+		}
+	}
+	if len(del) > 0 {
+		if err := wallet.storage.Delete(del...); err != nil {
+			return fmt.Errorf("deleting entries: %v", err)
+		}
+	}
+	for _, entry := range put {
+		wallet.entries[outpointKey(entry.Output.Outpoint)] = entry.clone()
+	}
+	for _, outpoint := range del {
+		delete(wallet.entries, outpointKey(outpoint))
+	}
+	return nil
+}
+
+func newReservationID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("generating reservation id: %v", err)
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+func outpointKey(outpoint utxo.Outpoint) string {
+	return hex.EncodeToString(outpoint.Hash) + ":" + strconv.FormatUint(uint64(outpoint.Index), 10)
+}
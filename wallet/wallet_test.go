@@ -0,0 +1,459 @@
+package wallet_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/pranav292gpt/zecutil/address"
+	"github.com/pranav292gpt/zecutil/api/utxo"
+	"github.com/pranav292gpt/zecutil/chain/bitcoin"
+	"github.com/pranav292gpt/zecutil/signer"
+	"github.com/pranav292gpt/zecutil/testutil"
+	"github.com/pranav292gpt/zecutil/wallet"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Wallet", func() {
+	params := &chaincfg.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		panic(err)
+	}
+	pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(privKey.PubKey().SerializeCompressed()), params)
+	if err != nil {
+		panic(err)
+	}
+	addr := address.Address(pkhAddr.EncodeAddress())
+	recipientAddr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	if err != nil {
+		panic(err)
+	}
+	recipient := address.Address(recipientAddr.EncodeAddress())
+
+	var server *testutil.Server
+	var client bitcoin.Client
+	var now time.Time
+	var clock func() time.Time
+
+	BeforeEach(func() {
+		server = testutil.NewServer(testutil.BitcoinChain(params))
+		client = bitcoin.NewClient(server.ClientOptions())
+		now = time.Unix(1600000000, 0).UTC()
+		clock = func() time.Time { return now }
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	fund := func(values ...int64) {
+		for _, value := range values {
+			_, err := server.Fund(string(addr), value)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		server.Mine(1)
+	}
+
+	newWallet := func(storage wallet.Storage) *wallet.Wallet {
+		w, err := wallet.New(client, storage, wallet.DefaultOptions().WithNow(clock))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Sync(context.Background(), addr)).To(Succeed())
+		return w
+	}
+
+	// spend builds and signs a transaction that spends the reserved outputs,
+	// pays the value to the recipient, and returns the change to the wallet.
+	spend := func(reservation wallet.Reservation, value uint64) utxo.Tx {
+		change := reservation.Value.Sub(pack.NewU256FromUint64(value + 1000))
+		tx, err := bitcoin.NewTxBuilder(params).BuildTx(reservation.Inputs(), []utxo.Recipient{
+			{To: recipient, Value: pack.NewU256FromUint64(value)},
+			{To: addr, Value: change},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer.SignTx(context.Background(), signer.NewKeySigner(privKey, true), tx)).To(Succeed())
+		return tx
+	}
+
+	Context("when syncing", func() {
+		It("should track confirmed outputs", func() {
+			fund(10000, 20000)
+			_, err := server.Fund(string(addr), 40000)
+			Expect(err).ToNot(HaveOccurred())
+
+			w := newWallet(wallet.NewMemoryStorage())
+			Expect(w.Entries()).To(HaveLen(2))
+			for _, entry := range w.Entries() {
+				Expect(entry.Address).To(Equal(addr))
+				Expect(entry.Status).To(Equal(wallet.StatusConfirmed))
+			}
+			confirmed, pending := w.Balance()
+			Expect(confirmed).To(Equal(pack.NewU256FromUint64(30000)))
+			Expect(pending).To(Equal(pack.NewU256FromUint64(0)))
+
+			server.Mine(1)
+			Expect(w.Sync(context.Background(), addr)).To(Succeed())
+			Expect(w.Entries()).To(HaveLen(3))
+		})
+
+		It("should remove outputs that are spent by other transactions", func() {
+			fund(10000, 20000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+
+			// Spend the outputs without going through the wallet.
+			Expect(client.SubmitTx(context.Background(), spend(reservation, 5000))).To(Succeed())
+			server.Mine(1)
+			Expect(w.Sync(context.Background(), addr)).To(Succeed())
+			Expect(w.Entries()).To(HaveLen(2))
+			for _, entry := range w.Entries() {
+				Expect(entry.Output.Value).ToNot(Equal(reservation.Outputs[0].Value))
+			}
+		})
+
+		It("should return an error when the client fails", func() {
+			server.InjectError("listunspent", 100, btcjson.NewRPCError(btcjson.ErrRPCMisc, "unavailable"))
+			w, err := wallet.New(client, wallet.NewMemoryStorage(), wallet.DefaultOptions())
+			Expect(err).ToNot(HaveOccurred())
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			Expect(w.Sync(ctx, addr)).ToNot(Succeed())
+		})
+	})
+
+	Context("when reserving outputs", func() {
+		It("should reserve the largest outputs first", func() {
+			fund(10000, 30000, 20000)
+			w := newWallet(wallet.NewMemoryStorage())
+
+			reservation, err := w.Reserve(pack.NewU256FromUint64(45000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reservation.Outputs).To(HaveLen(2))
+			Expect(reservation.Outputs[0].Value).To(Equal(pack.NewU256FromUint64(30000)))
+			Expect(reservation.Outputs[1].Value).To(Equal(pack.NewU256FromUint64(20000)))
+			Expect(reservation.Value).To(Equal(pack.NewU256FromUint64(50000)))
+			Expect(reservation.Expiry).To(Equal(now.Add(wallet.DefaultReservationTTL)))
+			Expect(reservation.Inputs()).To(HaveLen(2))
+		})
+
+		It("should not reserve the same outputs twice", func() {
+			fund(10000, 20000)
+			w := newWallet(wallet.NewMemoryStorage())
+
+			first, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+			second, err := w.Reserve(pack.NewU256FromUint64(5000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first.ID).ToNot(Equal(second.ID))
+			Expect(second.Outputs[0].Outpoint).ToNot(Equal(first.Outputs[0].Outpoint))
+
+			_, err = w.Reserve(pack.NewU256FromUint64(1))
+			Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+		})
+
+		It("should not reserve outputs concurrently", func() {
+			values := make([]int64, 20)
+			for i := range values {
+				values[i] = 10000
+			}
+			fund(values...)
+			w := newWallet(wallet.NewMemoryStorage())
+
+			mu := new(sync.Mutex)
+			reserved := map[string]bool{}
+			wg := new(sync.WaitGroup)
+			for i := 0; i < 2*len(values); i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					reservation, err := w.Reserve(pack.NewU256FromUint64(10000))
+					if err != nil {
+						Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+						return
+					}
+					Expect(reservation.Outputs).To(HaveLen(1))
+					mu.Lock()
+					defer mu.Unlock()
+					key := reservation.Outputs[0].Outpoint.Hash.String()
+					Expect(reserved[key]).To(BeFalse())
+					reserved[key] = true
+				}()
+			}
+			wg.Wait()
+			Expect(reserved).To(HaveLen(len(values)))
+		})
+
+		It("should return an error when there are not enough funds", func() {
+			fund(10000)
+			w := newWallet(wallet.NewMemoryStorage())
+			_, err := w.Reserve(pack.NewU256FromUint64(10001))
+			Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+
+			// The outputs must still be available.
+			_, err = w.Reserve(pack.NewU256FromUint64(10000))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should reserve outputs again after the reservation expires", func() {
+			fund(10000)
+			w := newWallet(wallet.NewMemoryStorage())
+			first, err := w.Reserve(pack.NewU256FromUint64(10000))
+			Expect(err).ToNot(HaveOccurred())
+
+			now = now.Add(wallet.DefaultReservationTTL)
+			second, err := w.Reserve(pack.NewU256FromUint64(10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second.Outputs).To(Equal(first.Outputs))
+
+			Expect(errors.Is(w.Release(first.ID), wallet.ErrUnknownReservation)).To(BeTrue())
+			Expect(errors.Is(w.Commit(first.ID, spend(first, 5000)), wallet.ErrUnknownReservation)).To(BeTrue())
+		})
+
+		It("should renew reservations that have not expired", func() {
+			fund(10000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(10000))
+			Expect(err).ToNot(HaveOccurred())
+
+			now = now.Add(wallet.DefaultReservationTTL / 2)
+			expiry, err := w.Renew(reservation.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expiry).To(Equal(now.Add(wallet.DefaultReservationTTL)))
+			now = now.Add(wallet.DefaultReservationTTL / 2)
+			_, err = w.Reserve(pack.NewU256FromUint64(10000))
+			Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+
+			now = expiry
+			_, err = w.Renew(reservation.ID)
+			Expect(errors.Is(err, wallet.ErrUnknownReservation)).To(BeTrue())
+		})
+
+		It("should reserve released outputs", func() {
+			fund(10000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(10000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.Release(reservation.ID)).To(Succeed())
+			Expect(errors.Is(w.Release(reservation.ID), wallet.ErrUnknownReservation)).To(BeTrue())
+
+			_, err = w.Reserve(pack.NewU256FromUint64(10000))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when submitting transactions", func() {
+		It("should commit the reservation and track the change", func() {
+			fund(10000, 20000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+			tx := spend(reservation, 5000)
+			Expect(w.SubmitTx(context.Background(), reservation.ID, tx)).To(Succeed())
+
+			confirmed, pending := w.Balance()
+			Expect(confirmed).To(Equal(pack.NewU256FromUint64(10000)))
+			Expect(pending).To(Equal(pack.NewU256FromUint64(14000)))
+			statuses := map[wallet.Status]int{}
+			for _, entry := range w.Entries() {
+				statuses[entry.Status]++
+			}
+			Expect(statuses).To(Equal(map[wallet.Status]int{wallet.StatusConfirmed: 1, wallet.StatusPending: 1, wallet.StatusSpent: 1}))
+
+			// The pending change can be spent before it confirms.
+			next, err := w.Reserve(pack.NewU256FromUint64(24000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(next.Outputs).To(HaveLen(2))
+			Expect(w.Release(next.ID)).To(Succeed())
+
+			// Once the transaction confirms, the change is confirmed and the
+			// spent output is removed.
+			server.Mine(1)
+			Expect(w.Sync(context.Background(), addr)).To(Succeed())
+			confirmed, pending = w.Balance()
+			Expect(confirmed).To(Equal(pack.NewU256FromUint64(24000)))
+			Expect(pending).To(Equal(pack.NewU256FromUint64(0)))
+			Expect(w.Entries()).To(HaveLen(2))
+		})
+
+		It("should return the inputs and drop the change when the transaction is evicted", func() {
+			fund(10000, 20000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+			tx := spend(reservation, 5000)
+			Expect(w.SubmitTx(context.Background(), reservation.ID, tx)).To(Succeed())
+			hash, err := tx.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			var txID chainhash.Hash
+			copy(txID[:], hash)
+			Expect(server.Evict(txID)).To(BeTrue())
+
+			// Until the commit TTL passes, the transaction might still be
+			// mined, so the spent output and the change are kept.
+			now = now.Add(wallet.DefaultCommitTTL - time.Second)
+			Expect(w.Sync(context.Background(), addr)).To(Succeed())
+			confirmed, pending := w.Balance()
+			Expect(confirmed).To(Equal(pack.NewU256FromUint64(10000)))
+			Expect(pending).To(Equal(pack.NewU256FromUint64(14000)))
+
+			now = now.Add(time.Second)
+			Expect(w.Sync(context.Background(), addr)).To(Succeed())
+			confirmed, pending = w.Balance()
+			Expect(confirmed).To(Equal(pack.NewU256FromUint64(30000)))
+			Expect(pending).To(Equal(pack.NewU256FromUint64(0)))
+			Expect(w.Entries()).To(HaveLen(2))
+			for _, entry := range w.Entries() {
+				Expect(entry.Status).To(Equal(wallet.StatusConfirmed))
+				Expect(entry.Committed).To(BeNil())
+			}
+			_, err = w.Reserve(pack.NewU256FromUint64(30000))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should keep the change of transactions that are mined late", func() {
+			fund(20000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.SubmitTx(context.Background(), reservation.ID, spend(reservation, 5000))).To(Succeed())
+
+			now = now.Add(wallet.DefaultCommitTTL)
+			server.Mine(1)
+			Expect(w.Sync(context.Background(), addr)).To(Succeed())
+			confirmed, pending := w.Balance()
+			Expect(confirmed).To(Equal(pack.NewU256FromUint64(14000)))
+			Expect(pending).To(Equal(pack.NewU256FromUint64(0)))
+			Expect(w.Entries()).To(HaveLen(1))
+		})
+
+		It("should not spend pending change when it is disabled", func() {
+			fund(20000)
+			w, err := wallet.New(client, wallet.NewMemoryStorage(), wallet.DefaultOptions().WithSpendPending(false))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.Sync(context.Background(), addr)).To(Succeed())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.SubmitTx(context.Background(), reservation.ID, spend(reservation, 5000))).To(Succeed())
+
+			_, err = w.Reserve(pack.NewU256FromUint64(1))
+			Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+		})
+
+		It("should release the reservation when the transaction is rejected", func() {
+			fund(20000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+
+			server.InjectError("sendrawtransaction", 100, btcjson.NewRPCError(btcjson.ErrRPCVerify, "rejected"))
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			Expect(w.SubmitTx(ctx, reservation.ID, spend(reservation, 5000))).ToNot(Succeed())
+
+			for _, entry := range w.Entries() {
+				Expect(entry.Status).To(Equal(wallet.StatusConfirmed))
+				Expect(entry.Reserved(now)).To(BeFalse())
+			}
+			_, err = w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should commit the reservation when the response is lost", func() {
+			fund(20000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+
+			// The transaction is accepted, and then submitted again by the
+			// client, which is rejected because it is already in the mempool.
+			server.InjectLostResponse("sendrawtransaction", 1, btcjson.NewRPCError(btcjson.ErrRPCMisc, "unavailable"))
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			Expect(w.SubmitTx(ctx, reservation.ID, spend(reservation, 5000))).To(Succeed())
+			Expect(server.Mempool()).To(HaveLen(1))
+
+			confirmed, pending := w.Balance()
+			Expect(confirmed).To(Equal(pack.NewU256FromUint64(0)))
+			Expect(pending).To(Equal(pack.NewU256FromUint64(14000)))
+			_, err = w.Reserve(pack.NewU256FromUint64(14001))
+			Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+		})
+
+		It("should keep the reservation when the transaction is not definitely rejected", func() {
+			fund(20000)
+			w := newWallet(wallet.NewMemoryStorage())
+			reservation, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+
+			server.InjectError("sendrawtransaction", 0, btcjson.NewRPCError(btcjson.ErrRPCMisc, "unavailable"))
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err = w.SubmitTx(ctx, reservation.ID, spend(reservation, 5000))
+			Expect(err).To(HaveOccurred())
+			Expect(wallet.Rejected(err)).To(BeFalse())
+
+			_, err = w.Reserve(pack.NewU256FromUint64(1))
+			Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+			Expect(w.Release(reservation.ID)).To(Succeed())
+			_, err = w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when persisting to a file", func() {
+		It("should restore reservations and pending change", func() {
+			fund(10000, 20000)
+			dir, err := os.MkdirTemp("", "wallet")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "wallet.json")
+			storage, err := wallet.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			w := newWallet(storage)
+			first, err := w.Reserve(pack.NewU256FromUint64(15000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(w.SubmitTx(context.Background(), first.ID, spend(first, 5000))).To(Succeed())
+			second, err := w.Reserve(pack.NewU256FromUint64(5000))
+			Expect(err).ToNot(HaveOccurred())
+
+			storage, err = wallet.NewFileStorage(path)
+			Expect(err).ToNot(HaveOccurred())
+			restored, err := wallet.New(client, storage, wallet.DefaultOptions().WithNow(clock))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(restored.Entries()).To(Equal(w.Entries()))
+
+			// The outputs of the second reservation are still reserved, so only
+			// the pending change is available.
+			reservation, err := restored.Reserve(pack.NewU256FromUint64(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reservation.Outputs).To(HaveLen(1))
+			Expect(reservation.Outputs[0].Outpoint).ToNot(Equal(second.Outputs[0].Outpoint))
+			_, err = restored.Reserve(pack.NewU256FromUint64(1))
+			Expect(errors.Is(err, wallet.ErrInsufficientFunds)).To(BeTrue())
+		})
+	})
+
+	Context("when creating a wallet", func() {
+		It("should return an error for bad options", func() {
+			_, err := wallet.New(client, wallet.NewMemoryStorage(), wallet.DefaultOptions().WithReservationTTL(0))
+			Expect(err).To(HaveOccurred())
+			_, err = wallet.New(client, wallet.NewMemoryStorage(), wallet.DefaultOptions().WithCommitTTL(0))
+			Expect(err).To(HaveOccurred())
+			_, err = wallet.New(client, wallet.NewMemoryStorage(), wallet.DefaultOptions().WithMinConf(-1))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})